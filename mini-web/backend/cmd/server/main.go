@@ -2,14 +2,19 @@ package main
 
 import (
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"gitee.com/await29/mini-web/internal/api"
 	"gitee.com/await29/mini-web/internal/config"
 	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
 	"gitee.com/await29/mini-web/internal/model/sqlite"
 	"gitee.com/await29/mini-web/internal/service"
 	"github.com/gorilla/mux"
@@ -39,24 +44,159 @@ func main() {
 	configRepo := sqlite.NewSystemConfigRepository(sqlite.DB)
 	logRepo := sqlite.NewSystemLogRepository(sqlite.DB)
 	activityRepo := sqlite.NewUserActivityRepository(sqlite.DB)
+	sshCertRepo := sqlite.NewSSHCertificateRepository(sqlite.DB)
+	sshKeyRepo := sqlite.NewSSHKeyRepository(sqlite.DB)
+	credentialRepo := sqlite.NewCredentialRepository(sqlite.DB)
+	teamRepo := sqlite.NewTeamRepository(sqlite.DB)
+	folderRepo := sqlite.NewConnectionFolderRepository(sqlite.DB)
+	shareRepo := sqlite.NewConnectionShareRepository(sqlite.DB)
+	roleRepo := sqlite.NewRoleRepository(sqlite.DB)
+	refreshTokenRepo := sqlite.NewRefreshTokenRepository(sqlite.DB)
+	revokedTokenRepo := sqlite.NewRevokedTokenRepository(sqlite.DB)
+	passwordHistoryRepo := sqlite.NewPasswordHistoryRepository(sqlite.DB)
+	passwordResetRepo := sqlite.NewPasswordResetRepository(sqlite.DB)
+	emailRepo := sqlite.NewEmailRepository(sqlite.DB)
+	emailQueueRepo := sqlite.NewEmailQueueRepository(sqlite.DB)
+	loginSecurityRepo := sqlite.NewLoginSecurityRepository(sqlite.DB)
+	notificationChannelRepo := sqlite.NewNotificationChannelRepository(sqlite.DB)
+	notificationDeliveryRepo := sqlite.NewNotificationDeliveryRepository(sqlite.DB)
+	hostKeyRepo := sqlite.NewSSHHostKeyRepository(sqlite.DB)
+	batchJobRepo := sqlite.NewBatchJobRepository(sqlite.DB)
+	scheduledJobRepo := sqlite.NewScheduledJobRepository(sqlite.DB)
+	snippetRepo := sqlite.NewSnippetRepository(sqlite.DB)
+
+	// 初始化敏感数据加密器
+	secretBox, err := service.NewSecretBox(cfg.Security.SecretKeyPath)
+	if err != nil {
+		log.Fatalf("初始化数据加密失败: %v", err)
+	}
 
 	// 创建服务
-	authService := service.NewAuthService(userRepo)
-	userService := service.NewUserService(userRepo)
-	connService := service.NewConnectionService(connRepo, sessionRepo)
-	systemService := service.NewSystemService(configRepo, logRepo)
+	roleService := service.NewRoleService(roleRepo)
+	jwtKeySet, err := service.InitJWTKeySet(cfg.JWT.Algorithm, cfg.JWT.Secret, cfg.JWT.KeyPath, cfg.JWT.VerifyKeyPaths)
+	if err != nil {
+		log.Fatalf("加载JWT签名密钥失败: %v", err)
+	}
+	tokenDenylist := service.InitTokenDenylist(revokedTokenRepo)
+	passwordService := service.NewPasswordService(configRepo, userRepo, passwordHistoryRepo)
+	loginGuard := service.NewLoginGuard(loginSecurityRepo, configRepo)
+	authService := service.NewAuthService(userRepo, roleService, refreshTokenRepo, tokenDenylist, jwtKeySet, passwordService, loginGuard,
+		time.Duration(cfg.JWT.AccessExpireMinute)*time.Minute, time.Duration(cfg.JWT.ExpireHour)*time.Hour)
+	userProvisioner := service.NewUserProvisioner(userRepo, teamRepo, roleService)
+	oidcService := service.NewOIDCService(configRepo, authService, userProvisioner)
+	if cfg.LDAP.Enabled {
+		ldapProvider, err := service.NewLDAPProvider(cfg.LDAP)
+		if err != nil {
+			log.Fatalf("初始化LDAP认证失败: %v", err)
+		}
+		authService.EnableLDAP(ldapProvider, userProvisioner)
+		log.Printf("已启用LDAP认证: %s", cfg.LDAP.URL)
+	}
+	emailService := service.NewEmailService(emailRepo, emailQueueRepo, configRepo, secretBox)
+	emailService.StartQueue()
+	notificationService := service.InitNotificationService(notificationChannelRepo, notificationDeliveryRepo, userRepo, configRepo, emailService, secretBox)
+	hostKeyService := service.InitHostKeyService(hostKeyRepo)
+	passwordResetService := service.NewPasswordResetService(userRepo, passwordResetRepo, configRepo, passwordService, authService, emailService)
+	go func() {
+		// 定期清理过期的刷新令牌、找回密码令牌、已发送的邮件和已投递的通知
+		for range time.Tick(time.Hour) {
+			if err := authService.CleanupExpiredTokens(); err != nil {
+				log.Printf("清理过期刷新令牌失败: %v", err)
+			}
+			if err := passwordResetService.CleanupExpiredTokens(); err != nil {
+				log.Printf("清理过期找回密码令牌失败: %v", err)
+			}
+			if err := emailService.CleanupSentMessages(); err != nil {
+				log.Printf("清理已发送邮件失败: %v", err)
+			}
+			if err := notificationService.CleanupDeliveries(); err != nil {
+				log.Printf("清理通知投递记录失败: %v", err)
+			}
+		}
+	}()
+	userService := service.NewUserService(userRepo, roleService, passwordService, loginGuard, authService)
+	sshCAService := service.NewSSHCAService(sshCertRepo, userRepo, cfg.SSHCA.KeyPath, time.Duration(cfg.SSHCA.CertTTL)*time.Minute, cfg.SSHCA.RolePrefix, cfg.SSHCA.RolePrincipals)
+	teamService := service.NewTeamService(teamRepo, userRepo, roleService)
+	accessService := service.NewAccessService(connRepo, folderRepo, shareRepo, teamService)
+	credentialService := service.NewCredentialService(credentialRepo, connRepo, accessService, secretBox, teamService)
+	sshKeyService := service.NewSSHKeyService(sshKeyRepo, connRepo, accessService, secretBox, credentialService)
+	connService := service.NewConnectionService(connRepo, sessionRepo, accessService, sshCAService, sshKeyService, credentialService)
+	batchJobService := service.NewBatchJobService(batchJobRepo, connService, accessService)
+	scheduledJobService := service.NewScheduledJobService(scheduledJobRepo, batchJobService, connService, configRepo)
+	scheduledJobService.Start()
+	snippetService := service.NewSnippetService(snippetRepo, userRepo, roleService, teamService, batchJobService)
+	systemService := service.NewSystemService(configRepo, logRepo, emailService)
 	dashboardService := service.NewDashboardService(userRepo, connRepo, sessionRepo, systemService)
+	service.InitInternalCA(cfg.SSLCA.CertPath, cfg.SSLCA.KeyPath, cfg.SSLCA.CommonName)
+	// 证书存储需先于ACME续期等后台任务初始化，新证书写入后才能立即生效
+	if cfg.Server.TLSEnabled {
+		service.InitCertificateStore()
+	}
+	sslService := service.NewSSLService()
+	acmeService, err := service.NewACMEService(cfg.ACME, sslService, secretBox)
+	if err != nil {
+		log.Fatalf("初始化ACME服务失败: %v", err)
+	}
+	acmeService.Start()
+	go func() {
+		// 每分钟检查资源使用率，每天检查一次即将过期的SSL证书并续期内部CA签发的证书
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		lastSSLCheck := time.Time{}
+		for range ticker.C {
+			systemService.CheckPerformanceAlerts()
+			if time.Since(lastSSLCheck) >= 24*time.Hour {
+				lastSSLCheck = time.Now()
+				if renewed, err := sslService.RenewExpiringInternalCertificates(); err != nil {
+					log.Printf("续期内部CA证书失败: %v", err)
+				} else if renewed > 0 {
+					if err := service.GetCertificateStore().Reload(); err != nil {
+						log.Printf("重新加载SSL证书失败: %v", err)
+					}
+				}
+				if err := sslService.NotifyExpiringCertificates(); err != nil {
+					log.Printf("检查SSL证书有效期失败: %v", err)
+				}
+			}
+		}
+	}()
 
 	// 创建处理器
 	authHandler := api.NewAuthHandler(authService)
 	userHandler := api.NewUserHandler(userService, activityRepo)
-	connHandler := api.NewConnectionHandler(connService)
+	connHandler := api.NewConnectionHandler(connService, snippetService)
 	systemHandler := api.NewSystemHandler(systemService)
+	emailHandler := api.NewEmailHandler(emailService)
+	notificationHandler := api.NewNotificationHandler(notificationService)
+	systemNotificationHandler := api.NewSystemNotificationHandler(notificationService)
+	hostKeyHandler := api.NewHostKeyHandler(hostKeyService)
 	dashboardHandler := api.NewDashboardHandler(dashboardService)
-	terminalSessionHandler := api.NewTerminalSessionHandler(connService)
+	terminalSessionHandler := api.NewTerminalSessionHandler(connService, roleService)
+	sshCAHandler := api.NewSSHCAHandler(sshCAService)
+	sshKeyHandler := api.NewSSHKeyHandler(sshKeyService)
+	credentialHandler := api.NewCredentialHandler(credentialService)
+	teamHandler := api.NewTeamHandler(teamService)
+	accessHandler := api.NewAccessHandler(accessService)
+	roleHandler := api.NewRoleHandler(roleService)
+	oidcHandler := api.NewOIDCHandler(oidcService)
+	passwordResetHandler := api.NewPasswordResetHandler(passwordResetService)
+	batchJobHandler := api.NewBatchJobHandler(batchJobService)
+	scheduledJobHandler := api.NewScheduledJobHandler(scheduledJobService)
+	snippetHandler := api.NewSnippetHandler(snippetService)
+
+	// WebSocket连接使用一次性票据认证，并只接受白名单中的页面来源
+	wsTicketStore := service.InitWSTicketStore(time.Duration(cfg.WebSocket.TicketTTLSecond) * time.Second)
+	wsTicketHandler := api.NewWSTicketHandler(wsTicketStore)
+	api.ConfigureWebSocketOrigins(cfg.WebSocket.AllowedOrigins)
+	service.ConfigureTrustedProxies(cfg.Server.TrustedProxies)
 
 	// 创建中间件
-	authMiddleware := middleware.NewAuthMiddleware(authService)
+	authMiddleware := middleware.NewAuthMiddleware(authService, roleService)
+
+	// requirePermission 为单个路由附加权限检查
+	requirePermission := func(handler http.HandlerFunc, permissions ...string) http.Handler {
+		return authMiddleware.RequirePermission(permissions...)(handler)
+	}
 
 	// 创建路由
 	router := mux.NewRouter()
@@ -123,6 +263,15 @@ func main() {
 	publicRouter := router.PathPrefix("/api").Subrouter()
 	publicRouter.HandleFunc("/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
 	publicRouter.HandleFunc("/auth/register", authHandler.Register).Methods("POST", "OPTIONS")
+	publicRouter.HandleFunc("/auth/refresh", authHandler.RefreshToken).Methods("POST", "OPTIONS")
+	publicRouter.HandleFunc("/auth/jwks", authHandler.GetJWKS).Methods("GET", "OPTIONS")
+	publicRouter.HandleFunc("/auth/password-policy", authHandler.GetPasswordPolicy).Methods("GET", "OPTIONS")
+	publicRouter.HandleFunc("/auth/forgot-password", passwordResetHandler.ForgotPassword).Methods("POST", "OPTIONS")
+	publicRouter.HandleFunc("/auth/reset-password", passwordResetHandler.ResetPassword).Methods("POST", "OPTIONS")
+	publicRouter.HandleFunc("/auth/oidc", oidcHandler.GetInfo).Methods("GET", "OPTIONS")
+	publicRouter.HandleFunc("/auth/oidc/login", oidcHandler.Login).Methods("GET")
+	publicRouter.HandleFunc("/auth/oidc/callback", oidcHandler.Callback).Methods("GET")
+	router.HandleFunc("/.well-known/jwks.json", authHandler.GetJWKS).Methods("GET")
 
 	// 受保护的路由
 	protectedRouter := router.PathPrefix("/api").Subrouter()
@@ -132,63 +281,199 @@ func main() {
 	protectedRouter.HandleFunc("/user/profile", authHandler.GetUserInfo).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/user/profile", authHandler.UpdateUserInfo).Methods("PUT", "OPTIONS")
 	protectedRouter.HandleFunc("/user/password", authHandler.UpdatePassword).Methods("PUT", "OPTIONS")
-	protectedRouter.HandleFunc("/auth/refresh", authHandler.RefreshToken).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/auth/logout-all", authHandler.LogoutAll).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/auth/sessions", authHandler.GetLoginSessions).Methods("GET", "OPTIONS")
 
 	// 连接相关路由
 	protectedRouter.HandleFunc("/connections", connHandler.GetUserConnections).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/connections", connHandler.CreateConnection).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/connections", requirePermission(connHandler.CreateConnection, model.PermConnectionsCreate)).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/connections/{id}", connHandler.GetConnection).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/connections/{id}", connHandler.UpdateConnection).Methods("PUT", "OPTIONS")
 	protectedRouter.HandleFunc("/connections/{id}", connHandler.DeleteConnection).Methods("DELETE", "OPTIONS")
 	protectedRouter.HandleFunc("/connections/test", connHandler.TestConnection).Methods("POST", "OPTIONS")
 	
+	// SSH密钥管理路由
+	protectedRouter.Handle("/ssh-keys", requirePermission(sshKeyHandler.GetKeys, model.PermSSHKeysManage)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/ssh-keys", requirePermission(sshKeyHandler.GenerateKey, model.PermSSHKeysManage)).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/ssh-keys/{id}", requirePermission(sshKeyHandler.GetKey, model.PermSSHKeysManage)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/ssh-keys/{id}", requirePermission(sshKeyHandler.DeleteKey, model.PermSSHKeysManage)).Methods("DELETE", "OPTIONS")
+	protectedRouter.Handle("/ssh-keys/{id}/connections", requirePermission(sshKeyHandler.GetLinkedConnections, model.PermSSHKeysManage)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/ssh-keys/{id}/deploy", requirePermission(sshKeyHandler.DeployKey, model.PermSSHKeysManage)).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/ssh-keys/{id}/rotate", requirePermission(sshKeyHandler.RotateKey, model.PermSSHKeysManage)).Methods("POST", "OPTIONS")
+
+	// 凭据管理路由
+	protectedRouter.Handle("/credentials", requirePermission(credentialHandler.GetCredentials, model.PermCredentialsManage)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/credentials", requirePermission(credentialHandler.CreateCredential, model.PermCredentialsManage)).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/credentials/{id}", requirePermission(credentialHandler.GetCredential, model.PermCredentialsManage)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/credentials/{id}", requirePermission(credentialHandler.UpdateCredential, model.PermCredentialsManage)).Methods("PUT", "OPTIONS")
+	protectedRouter.Handle("/credentials/{id}", requirePermission(credentialHandler.DeleteCredential, model.PermCredentialsManage)).Methods("DELETE", "OPTIONS")
+	protectedRouter.Handle("/credentials/{id}/connections", requirePermission(credentialHandler.GetCredentialConnections, model.PermCredentialsManage)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/credentials/{id}/rotate", requirePermission(credentialHandler.RotateCredential, model.PermCredentialsManage)).Methods("POST", "OPTIONS")
+
+	// 团队管理路由
+	protectedRouter.HandleFunc("/teams", teamHandler.GetTeams).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/teams", requirePermission(teamHandler.CreateTeam, model.PermTeamsCreate)).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/teams/{id}", teamHandler.GetTeam).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/teams/{id}", teamHandler.UpdateTeam).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/teams/{id}", teamHandler.DeleteTeam).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/teams/{id}/members", teamHandler.GetMembers).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/teams/{id}/members", teamHandler.AddMember).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/teams/{id}/members/{userId}", teamHandler.RemoveMember).Methods("DELETE", "OPTIONS")
+
+	// 连接文件夹与共享授权路由
+	protectedRouter.HandleFunc("/connection-folders", accessHandler.GetFolders).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/connection-folders", accessHandler.CreateFolder).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/connection-folders/{id}", accessHandler.UpdateFolder).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/connection-folders/{id}", accessHandler.DeleteFolder).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/connection-folders/{id}/shares", accessHandler.GetFolderShares).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/connection-folders/{id}/shares", requirePermission(accessHandler.ShareFolder, model.PermConnectionsShare)).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/connections/{id}/shares", accessHandler.GetConnectionShares).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/connections/{id}/shares", requirePermission(accessHandler.ShareConnection, model.PermConnectionsShare)).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/connection-shares/{id}", accessHandler.RevokeShare).Methods("DELETE", "OPTIONS")
+
+	// 个人通知渠道路由
+	protectedRouter.HandleFunc("/notifications/events", notificationHandler.GetEventTypes).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/channels", notificationHandler.GetChannels).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/channels", notificationHandler.CreateChannel).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/channels/{id}", notificationHandler.UpdateChannel).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/channels/{id}", notificationHandler.DeleteChannel).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/channels/{id}/test", notificationHandler.TestChannel).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/channels/{id}/deliveries", notificationHandler.GetDeliveries).Methods("GET", "OPTIONS")
+
 	// 会话相关路由
 	protectedRouter.HandleFunc("/sessions", connHandler.GetUserSessions).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/sessions/active", connHandler.GetActiveSessions).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/connections/{id}/sessions", connHandler.CreateSession).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/connections/{id}/sessions", requirePermission(connHandler.CreateSession, model.PermSessionsCreate)).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/sessions/{id}", connHandler.CloseSession).Methods("DELETE", "OPTIONS")
+
+	// 批量命令执行路由，执行命令等同于发起会话，每台目标主机还需要connect权限
+	protectedRouter.Handle("/batch-jobs", requirePermission(batchJobHandler.CreateJob, model.PermSessionsCreate)).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/batch-jobs", batchJobHandler.GetJobs).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/batch-jobs/{id}", batchJobHandler.GetJob).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/batch-jobs/{id}/events", batchJobHandler.StreamEvents).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/batch-jobs/{id}/rerun", requirePermission(batchJobHandler.RerunJob, model.PermSessionsCreate)).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/batch-jobs/{id}/cancel", batchJobHandler.CancelJob).Methods("POST", "OPTIONS")
+
+	// 定时任务路由，执行记录和输出通过批量任务路由查看
+	protectedRouter.Handle("/scheduled-jobs", requirePermission(scheduledJobHandler.CreateJob, model.PermScheduledJobsManage)).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/scheduled-jobs", scheduledJobHandler.GetJobs).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/scheduled-jobs/{id}", scheduledJobHandler.GetJob).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/scheduled-jobs/{id}", requirePermission(scheduledJobHandler.UpdateJob, model.PermScheduledJobsManage)).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/scheduled-jobs/{id}", scheduledJobHandler.DeleteJob).Methods("DELETE", "OPTIONS")
+	protectedRouter.Handle("/scheduled-jobs/{id}/run", requirePermission(scheduledJobHandler.RunJob, model.PermScheduledJobsManage)).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/scheduled-jobs/{id}/runs", scheduledJobHandler.GetRuns).Methods("GET", "OPTIONS")
+
+	// 命令片段路由，GET /snippets?q=全文搜索；作为批量任务执行等同于发起会话
+	protectedRouter.HandleFunc("/snippets", snippetHandler.GetSnippets).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/snippets", snippetHandler.CreateSnippet).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/snippets/{id}", snippetHandler.GetSnippet).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/snippets/{id}", snippetHandler.UpdateSnippet).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/snippets/{id}", snippetHandler.DeleteSnippet).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/snippets/{id}/expand", snippetHandler.ExpandSnippet).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/snippets/{id}/batch-jobs", requirePermission(snippetHandler.RunSnippet, model.PermSessionsCreate)).Methods("POST", "OPTIONS")
 	
-	// WebSocket终端连接 - 移到公开路由，移除认证要求，便于调试
+	// WebSocket连接票据，浏览器无法为WebSocket设置认证头，先用访问令牌换取一次性票据
+	protectedRouter.HandleFunc("/ws-ticket", wsTicketHandler.IssueTicket).Methods("POST", "OPTIONS")
+
+	// WebSocket终端连接 - 不经过认证中间件，由处理器校验URL中的票据
 	log.Println("注册WebSocket终端路由: /ws/{protocol}/{sessionId}")
 	router.HandleFunc("/ws/{protocol}/{sessionId}", connHandler.HandleTerminalWebSocket)
 
+	// 多路复用WebSocket：一个连接承载多个终端和文件管理通道
+	log.Println("注册多路复用WebSocket路由: /ws/mux")
+	router.HandleFunc("/ws/mux", connHandler.HandleMultiplexWebSocket)
+
 	// 管理员路由
 	adminRouter := protectedRouter.PathPrefix("/admin").Subrouter()
-	adminRouter.Use(authMiddleware.RoleAuth("admin"))
-	adminRouter.HandleFunc("/users", userHandler.GetUsers).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/users/{id}", userHandler.GetUserByID).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/users", requirePermission(userHandler.GetUsers, model.PermUsersRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/users/{id}", requirePermission(userHandler.GetUserByID, model.PermUsersRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/users", requirePermission(userHandler.CreateUser, model.PermUsersWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/users/{id}", requirePermission(userHandler.UpdateUser, model.PermUsersWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/users/{id}", requirePermission(userHandler.DeleteUser, model.PermUsersWrite)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/users/{id}/revoke-sessions", requirePermission(authHandler.RevokeUserSessions, model.PermUsersWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/users/{id}/require-password-change", requirePermission(authHandler.RequirePasswordChange, model.PermUsersWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/users/{id}/unlock", requirePermission(authHandler.UnlockUser, model.PermUsersWrite)).Methods("POST", "OPTIONS")
+
+	// SSH主机公钥记录路由
+	adminRouter.Handle("/ssh-host-keys", requirePermission(hostKeyHandler.GetHostKeys, model.PermConnectionsManageAll)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/ssh-host-keys/{id}", requirePermission(hostKeyHandler.DeleteHostKey, model.PermConnectionsManageAll)).Methods("DELETE", "OPTIONS")
+
+	// 角色与权限管理路由
+	adminRouter.Handle("/permissions", requirePermission(roleHandler.GetPermissions, model.PermRolesRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/roles", requirePermission(roleHandler.GetRoles, model.PermRolesRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/roles", requirePermission(roleHandler.CreateRole, model.PermRolesWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/roles/{id}", requirePermission(roleHandler.GetRole, model.PermRolesRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/roles/{id}", requirePermission(roleHandler.UpdateRole, model.PermRolesWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/roles/{id}", requirePermission(roleHandler.DeleteRole, model.PermRolesWrite)).Methods("DELETE", "OPTIONS")
 
 	// 系统配置路由
-	adminRouter.HandleFunc("/system/configs", systemHandler.GetAllConfigs).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/system/configs", systemHandler.CreateConfig).Methods("POST", "OPTIONS")
-	adminRouter.HandleFunc("/system/configs/batch", systemHandler.BatchUpdateConfigs).Methods("PUT", "OPTIONS")
-
-	// 邮件配置路由 (暂时注释，使用系统处理器中的邮件测试功能)
-	// emailHandler := api.NewEmailHandler()
-	// adminRouter.HandleFunc("/system/email/config", emailHandler.GetEmailConfig).Methods("GET", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/config", emailHandler.UpdateEmailConfig).Methods("PUT", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/test-connection", emailHandler.TestEmailConnection).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/test-send", emailHandler.SendTestEmail).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/templates", emailHandler.GetEmailTemplates).Methods("GET", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/templates", emailHandler.CreateEmailTemplate).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/templates/{id}", emailHandler.UpdateEmailTemplate).Methods("PUT", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/templates/{id}", emailHandler.DeleteEmailTemplate).Methods("DELETE", "OPTIONS")
-	// adminRouter.HandleFunc("/system/email/variables", emailHandler.GetEmailTemplateVariables).Methods("GET", "OPTIONS")
-
-	// SSL证书配置路由 (暂时注释)
-	// sslHandler := api.NewSSLHandler()
-	// adminRouter.HandleFunc("/system/ssl/configs", sslHandler.GetSSLConfigs).Methods("GET", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/configs", sslHandler.CreateSSLConfig).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/configs/{id}", sslHandler.GetSSLConfig).Methods("GET", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/configs/{id}", sslHandler.UpdateSSLConfig).Methods("PUT", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/configs/{id}", sslHandler.DeleteSSLConfig).Methods("DELETE", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/configs/{id}/enable", sslHandler.EnableSSLConfig).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/configs/{id}/disable", sslHandler.DisableSSLConfig).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/configs/{id}/default", sslHandler.SetDefaultSSLConfig).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/test-connection", sslHandler.TestSSLConnection).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/parse-certificate", sslHandler.ParseCertificate).Methods("POST", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/expiring", sslHandler.GetExpiringCertificates).Methods("GET", "OPTIONS")
-	// adminRouter.HandleFunc("/system/ssl/status", sslHandler.GetSSLStatus).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/configs", requirePermission(systemHandler.GetAllConfigs, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/configs", requirePermission(systemHandler.CreateConfig, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/configs/batch", requirePermission(systemHandler.BatchUpdateConfigs, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+
+	// SSH证书颁发机构路由
+	adminRouter.Handle("/ssh-ca/public-key", requirePermission(sshCAHandler.GetPublicKey, model.PermSSHCARead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/ssh-ca/certificates", requirePermission(sshCAHandler.GetCertificates, model.PermSSHCARead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/ssh-ca/certificates/{serial}", requirePermission(sshCAHandler.GetCertificate, model.PermSSHCARead)).Methods("GET", "OPTIONS")
+
+	// 邮件配置、模板和发信队列路由
+	adminRouter.Handle("/system/email/config", requirePermission(emailHandler.GetEmailConfig, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/email/config", requirePermission(emailHandler.UpdateEmailConfig, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/system/email/test-connection", requirePermission(emailHandler.TestEmailConnection, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/email/test-send", requirePermission(emailHandler.SendTestEmail, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/email/templates", requirePermission(emailHandler.GetEmailTemplates, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/email/templates", requirePermission(emailHandler.CreateEmailTemplate, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/email/templates/{id}", requirePermission(emailHandler.UpdateEmailTemplate, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/system/email/templates/{id}", requirePermission(emailHandler.DeleteEmailTemplate, model.PermSystemConfigsWrite)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/system/email/variables", requirePermission(emailHandler.GetEmailTemplateVariables, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/email/queue", requirePermission(emailHandler.GetEmailQueue, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/email/queue/{id}/retry", requirePermission(emailHandler.RetryEmail, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+
+	// 系统通知渠道路由
+	adminRouter.Handle("/notifications/channels", requirePermission(systemNotificationHandler.GetChannels, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/notifications/channels", requirePermission(systemNotificationHandler.CreateChannel, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/notifications/channels/{id}", requirePermission(systemNotificationHandler.UpdateChannel, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/notifications/channels/{id}", requirePermission(systemNotificationHandler.DeleteChannel, model.PermSystemConfigsWrite)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/notifications/channels/{id}/test", requirePermission(systemNotificationHandler.TestChannel, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/notifications/channels/{id}/deliveries", requirePermission(systemNotificationHandler.GetDeliveries, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+
+	// SSL证书配置路由，变更后HTTPS证书即时重新加载
+	sslHandler := api.NewSSLHandler()
+	adminRouter.Handle("/system/ssl/configs", requirePermission(sslHandler.GetSSLConfigs, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs", requirePermission(sslHandler.CreateSSLConfig, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs/{id}", requirePermission(sslHandler.GetSSLConfig, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs/{id}", requirePermission(sslHandler.UpdateSSLConfig, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs/{id}", requirePermission(sslHandler.DeleteSSLConfig, model.PermSystemConfigsWrite)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs/{id}/enable", requirePermission(sslHandler.EnableSSLConfig, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs/{id}/disable", requirePermission(sslHandler.DisableSSLConfig, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs/{id}/default", requirePermission(sslHandler.SetDefaultSSLConfig, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/test-connection", requirePermission(sslHandler.TestSSLConnection, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/parse-certificate", requirePermission(sslHandler.ParseCertificate, model.PermSystemConfigsRead)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/expiring", requirePermission(sslHandler.GetExpiringCertificates, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/status", requirePermission(sslHandler.GetSSLStatus, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/configs/{id}/renew", requirePermission(sslHandler.RenewSSLConfig, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/self-signed", requirePermission(sslHandler.GenerateSelfSigned, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/ca", requirePermission(sslHandler.GetInternalCA, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/ca/issue", requirePermission(sslHandler.IssueInternal, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/csrs", requirePermission(sslHandler.GetCSRs, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/csrs", requirePermission(sslHandler.CreateCSR, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/csrs/{id}", requirePermission(sslHandler.DeleteCSR, model.PermSystemConfigsWrite)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/system/ssl/csrs/{id}/complete", requirePermission(sslHandler.CompleteCSR, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+
+	// 定时任务全局暂停
+	adminRouter.Handle("/scheduled-jobs/pause", requirePermission(scheduledJobHandler.GetPauseState, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/scheduled-jobs/pause", requirePermission(scheduledJobHandler.SetPauseState, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+
+	// ACME自动证书路由
+	acmeHandler := api.NewACMEHandler(acmeService)
+	adminRouter.Handle("/system/ssl/acme/dns-providers", requirePermission(acmeHandler.GetDNSProviders, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/acme/certificates", requirePermission(acmeHandler.GetCertificates, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/ssl/acme/certificates", requirePermission(acmeHandler.CreateCertificate, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/acme/certificates/{id}", requirePermission(acmeHandler.UpdateCertificate, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/system/ssl/acme/certificates/{id}", requirePermission(acmeHandler.DeleteCertificate, model.PermSystemConfigsWrite)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/system/ssl/acme/certificates/{id}/renew", requirePermission(acmeHandler.RenewCertificate, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/ssl/acme/orders", requirePermission(acmeHandler.GetOrders, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
 
 	// API访问控制路由 (暂时注释)
 	// apiControlHandler := api.NewAPIControlHandler()
@@ -208,32 +493,32 @@ func main() {
 	// adminRouter.HandleFunc("/system/api/statistics", apiControlHandler.GetAccessStatistics).Methods("GET", "OPTIONS")
 	// adminRouter.HandleFunc("/system/api/rate-limit/status", apiControlHandler.GetRateLimitStatus).Methods("GET", "OPTIONS")
 	// adminRouter.HandleFunc("/system/api/cleanup", apiControlHandler.CleanupExpiredEntries).Methods("POST", "OPTIONS")
-	adminRouter.HandleFunc("/system/configs/category/{category}", systemHandler.GetConfigsByCategory).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/system/configs/{key}", systemHandler.GetConfig).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/system/configs/{key}", systemHandler.UpdateConfig).Methods("PUT", "OPTIONS")
-	adminRouter.HandleFunc("/system/configs/{key}", systemHandler.DeleteConfig).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/system/configs/category/{category}", requirePermission(systemHandler.GetConfigsByCategory, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/configs/{key}", requirePermission(systemHandler.GetConfig, model.PermSystemConfigsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/configs/{key}", requirePermission(systemHandler.UpdateConfig, model.PermSystemConfigsWrite)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/system/configs/{key}", requirePermission(systemHandler.DeleteConfig, model.PermSystemConfigsWrite)).Methods("DELETE", "OPTIONS")
 
 	// 系统日志路由
-	adminRouter.HandleFunc("/system/logs", systemHandler.GetLogs).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/system/logs/stats", systemHandler.GetLogStats).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/system/logs/clear", systemHandler.ClearLogs).Methods("POST", "OPTIONS")
-	adminRouter.HandleFunc("/system/logs/{id}", systemHandler.DeleteLog).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/system/logs", requirePermission(systemHandler.GetLogs, model.PermLogsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/logs/stats", requirePermission(systemHandler.GetLogStats, model.PermLogsRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/logs/clear", requirePermission(systemHandler.ClearLogs, model.PermLogsWrite)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/logs/{id}", requirePermission(systemHandler.DeleteLog, model.PermLogsWrite)).Methods("DELETE", "OPTIONS")
 
 	// 系统信息和性能监控路由
-	adminRouter.HandleFunc("/system/info", systemHandler.GetSystemInfo).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/system/performance", systemHandler.GetPerformanceMetrics).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/system/email/test", systemHandler.TestEmailConfig).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/system/info", requirePermission(systemHandler.GetSystemInfo, model.PermSystemInfoRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/performance", requirePermission(systemHandler.GetPerformanceMetrics, model.PermSystemInfoRead)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/system/email/test", requirePermission(systemHandler.TestEmailConfig, model.PermSystemConfigsWrite)).Methods("POST", "OPTIONS")
 
 	// Dashboard路由
-	protectedRouter.HandleFunc("/dashboard/stats", dashboardHandler.GetDashboardStats).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/dashboard/system-status", dashboardHandler.GetSystemStatus).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/dashboard/activities", dashboardHandler.GetRecentActivities).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/dashboard/connections", dashboardHandler.GetConnectionStats).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/dashboard/users", dashboardHandler.GetUserStats).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/dashboard/sessions", dashboardHandler.GetSessionStats).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/dashboard/stats", requirePermission(dashboardHandler.GetDashboardStats, model.PermDashboardRead)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/dashboard/system-status", requirePermission(dashboardHandler.GetSystemStatus, model.PermDashboardRead)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/dashboard/activities", requirePermission(dashboardHandler.GetRecentActivities, model.PermDashboardRead)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/dashboard/connections", requirePermission(dashboardHandler.GetConnectionStats, model.PermDashboardRead)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/dashboard/users", requirePermission(dashboardHandler.GetUserStats, model.PermDashboardRead)).Methods("GET", "OPTIONS")
+	protectedRouter.Handle("/dashboard/sessions", requirePermission(dashboardHandler.GetSessionStats, model.PermDashboardRead)).Methods("GET", "OPTIONS")
 
 	// 终端会话管理路由
-	protectedRouter.HandleFunc("/terminal/sessions", terminalSessionHandler.CreateTerminalSession).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/terminal/sessions", requirePermission(terminalSessionHandler.CreateTerminalSession, model.PermSessionsCreate)).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/terminal/sessions", terminalSessionHandler.GetUserTerminalSessions).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/terminal/sessions/{id}", terminalSessionHandler.GetTerminalSession).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/terminal/sessions/{id}", terminalSessionHandler.CloseTerminalSession).Methods("DELETE", "OPTIONS")
@@ -242,26 +527,82 @@ func main() {
 	// 新的WebSocket终端连接（支持会话恢复）
 	router.HandleFunc("/ws/terminal/{sessionId}", terminalSessionHandler.HandleTerminalWebSocketWithSession)
 
-	// 设置服务器
+	// 设置服务器，ACME HTTP-01验证请求在路由之前处理
 	server := &http.Server{
 		Addr:    cfg.GetServerAddr(),
-		Handler: router,
+		Handler: acmeService.HTTP01Handler(router),
+	}
+	servers := []*http.Server{server}
+
+	// 启用HTTPS时始终以TLS方式监听，证书按SNI从ssl_configs中选择。
+	// 暂无可用证书时握手失败，新增或签发证书后立即生效，无需重启
+	useTLS := cfg.Server.TLSEnabled
+	if useTLS {
+		certStore := service.GetCertificateStore()
+		server.TLSConfig = certStore.TLSConfig()
+		if !certStore.HasCertificate() {
+			log.Printf("警告: 已启用HTTPS但暂无可用的SSL证书，添加证书前HTTPS握手将失败")
+		}
 	}
 
 	// 启动服务器
 	go func() {
-		log.Printf("服务器启动在 %s\n", cfg.GetServerAddr())
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if useTLS {
+			log.Printf("HTTPS服务器启动在 %s\n", cfg.GetServerAddr())
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("服务器启动在 %s\n", cfg.GetServerAddr())
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("服务器启动失败: %v", err)
 		}
 	}()
 
+	// HTTP到HTTPS的重定向，同时响应ACME HTTP-01验证
+	if useTLS && cfg.Server.HTTPRedirectPort > 0 {
+		redirectServer := &http.Server{
+			Addr:    net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.HTTPRedirectPort)),
+			Handler: acmeService.HTTP01Handler(httpsRedirectHandler(cfg.Server.Port)),
+		}
+		servers = append(servers, redirectServer)
+		go func() {
+			log.Printf("HTTP重定向服务启动在 %s\n", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("HTTP重定向服务启动失败: %v", err)
+			}
+		}()
+	}
+
 	// 优雅关闭
-	gracefulShutdown(server)
+	gracefulShutdown(servers...)
+}
+
+// httpsRedirectHandler 将HTTP请求重定向到相同主机的HTTPS端口
+func httpsRedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		// 非GET请求使用308，保留请求方法和请求体
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
 }
 
 // gracefulShutdown 优雅关闭服务器
-func gracefulShutdown(server *http.Server) {
+func gracefulShutdown(servers ...*http.Server) {
 	// 监听信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -270,8 +611,10 @@ func gracefulShutdown(server *http.Server) {
 	log.Println("正在关闭服务器...")
 
 	// 关闭服务器
-	if err := server.Close(); err != nil {
-		log.Fatalf("服务器关闭失败: %v", err)
+	for _, server := range servers {
+		if err := server.Close(); err != nil {
+			log.Fatalf("服务器关闭失败: %v", err)
+		}
 	}
 
 	log.Println("服务器已关闭")
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// AccessHandler 连接文件夹与共享授权处理器
+type AccessHandler struct {
+	accessService *service.AccessService
+}
+
+// NewAccessHandler 创建连接文件夹与共享授权处理器
+func NewAccessHandler(accessService *service.AccessService) *AccessHandler {
+	return &AccessHandler{accessService: accessService}
+}
+
+// GetFolders 获取当前用户可见的文件夹
+func (h *AccessHandler) GetFolders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	folders, err := h.accessService.GetUserFolders(userID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if folders == nil {
+		folders = []*model.ConnectionFolder{}
+	}
+
+	sendSuccessResponse(w, "获取文件夹列表成功", folders)
+}
+
+// CreateFolder 创建文件夹
+func (h *AccessHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.ConnectionFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	folder, err := h.accessService.CreateFolder(userID, &req)
+	if err != nil {
+		sendAccessError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "创建文件夹成功", folder)
+}
+
+// UpdateFolder 更新文件夹
+func (h *AccessHandler) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的文件夹ID")
+	if !ok {
+		return
+	}
+
+	var req model.ConnectionFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	folder, err := h.accessService.UpdateFolder(userID, id, &req)
+	if err != nil {
+		sendAccessError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "更新文件夹成功", folder)
+}
+
+// DeleteFolder 删除文件夹
+func (h *AccessHandler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的文件夹ID")
+	if !ok {
+		return
+	}
+
+	if err := h.accessService.DeleteFolder(userID, id); err != nil {
+		sendAccessError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "删除文件夹成功", nil)
+}
+
+// GetConnectionShares 获取连接的共享授权
+func (h *AccessHandler) GetConnectionShares(w http.ResponseWriter, r *http.Request) {
+	h.getShares(w, r, model.ShareResourceConnection, "无效的连接ID")
+}
+
+// ShareConnection 共享连接给用户或团队
+func (h *AccessHandler) ShareConnection(w http.ResponseWriter, r *http.Request) {
+	h.share(w, r, model.ShareResourceConnection, "无效的连接ID")
+}
+
+// GetFolderShares 获取文件夹的共享授权
+func (h *AccessHandler) GetFolderShares(w http.ResponseWriter, r *http.Request) {
+	h.getShares(w, r, model.ShareResourceFolder, "无效的文件夹ID")
+}
+
+// ShareFolder 共享文件夹给用户或团队
+func (h *AccessHandler) ShareFolder(w http.ResponseWriter, r *http.Request) {
+	h.share(w, r, model.ShareResourceFolder, "无效的文件夹ID")
+}
+
+// RevokeShare 撤销共享授权
+func (h *AccessHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的共享授权ID")
+	if !ok {
+		return
+	}
+
+	if err := h.accessService.RevokeShare(userID, id); err != nil {
+		sendAccessError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "撤销共享授权成功", nil)
+}
+
+// getShares 获取资源的共享授权列表
+func (h *AccessHandler) getShares(w http.ResponseWriter, r *http.Request, resourceType string, invalidMessage string) {
+	userID, id, ok := parseIDRequest(w, r, "id", invalidMessage)
+	if !ok {
+		return
+	}
+
+	shares, err := h.accessService.GetShares(userID, resourceType, id)
+	if err != nil {
+		sendAccessError(w, err)
+		return
+	}
+	if shares == nil {
+		shares = []*model.ConnectionShare{}
+	}
+
+	sendSuccessResponse(w, "获取共享授权成功", shares)
+}
+
+// share 为资源添加或更新共享授权
+func (h *AccessHandler) share(w http.ResponseWriter, r *http.Request, resourceType string, invalidMessage string) {
+	userID, id, ok := parseIDRequest(w, r, "id", invalidMessage)
+	if !ok {
+		return
+	}
+
+	var req model.ConnectionShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	share, err := h.accessService.Share(userID, resourceType, id, &req)
+	if err != nil {
+		sendAccessError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "共享授权成功", share)
+}
+
+// sendAccessError 将访问控制服务错误转换为HTTP响应
+func sendAccessError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrConnectionNotFound), errors.Is(err, service.ErrFolderNotFound),
+		errors.Is(err, service.ErrShareNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrConnectionForbidden), errors.Is(err, service.ErrFolderForbidden):
+		sendErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidPermission), errors.Is(err, service.ErrInvalidShareSubject),
+		errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrTeamNotFound):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// ACMEHandler ACME自动证书处理器
+type ACMEHandler struct {
+	acmeService *service.ACMEService
+}
+
+// NewACMEHandler 创建ACME处理器
+func NewACMEHandler(acmeService *service.ACMEService) *ACMEHandler {
+	return &ACMEHandler{acmeService: acmeService}
+}
+
+// sendACMEError 按错误类型返回ACME相关的错误响应
+func sendACMEError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidACMERequest):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrACMECertificateNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrACMEInProgress):
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
+
+// GetDNSProviders 获取支持的DNS服务商
+func (h *ACMEHandler) GetDNSProviders(w http.ResponseWriter, r *http.Request) {
+	sendSuccessResponse(w, "获取DNS服务商成功", service.DNSProviderNames())
+}
+
+// GetCertificates 获取ACME证书列表
+func (h *ACMEHandler) GetCertificates(w http.ResponseWriter, r *http.Request) {
+	certs, err := h.acmeService.GetCertificates()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取ACME证书失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取ACME证书成功", certs)
+}
+
+// CreateCertificate 创建ACME证书，签发在后台进行，结果见签发记录
+func (h *ACMEHandler) CreateCertificate(w http.ResponseWriter, r *http.Request) {
+	var req service.ACMECertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+
+	cert, err := h.acmeService.CreateCertificate(&req)
+	if err != nil {
+		sendACMEError(w, "创建ACME证书失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "证书已开始签发", cert)
+}
+
+// UpdateCertificate 修改ACME证书
+func (h *ACMEHandler) UpdateCertificate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSSLID(w, r)
+	if !ok {
+		return
+	}
+	var req service.ACMECertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+
+	cert, err := h.acmeService.UpdateCertificate(id, &req)
+	if err != nil {
+		sendACMEError(w, "修改ACME证书失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "ACME证书已修改", cert)
+}
+
+// DeleteCertificate 删除ACME证书，已签发的SSL配置保留
+func (h *ACMEHandler) DeleteCertificate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSSLID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.acmeService.DeleteCertificate(id); err != nil {
+		sendACMEError(w, "删除ACME证书失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "ACME证书已删除", nil)
+}
+
+// RenewCertificate 立即重新签发证书
+func (h *ACMEHandler) RenewCertificate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSSLID(w, r)
+	if !ok {
+		return
+	}
+
+	cert, err := h.acmeService.Renew(id)
+	if err != nil {
+		sendACMEError(w, "续期ACME证书失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "证书已开始续期", cert)
+}
+
+// GetOrders 获取签发记录，可用certificate_id筛选
+func (h *ACMEHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
+	certificateID := 0
+	if value := r.URL.Query().Get("certificate_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			sendErrorResponse(w, http.StatusBadRequest, "无效的证书ID")
+			return
+		}
+		certificateID = id
+	}
+
+	orders, err := h.acmeService.GetOrders(certificateID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取签发记录失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取签发记录成功", orders)
+}
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strings"
+	"strconv"
 
+	"gitee.com/await29/mini-web/internal/middleware"
 	"gitee.com/await29/mini-web/internal/model"
 	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
 )
 
 // AuthHandler 认证处理器
@@ -36,12 +38,20 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 调用服务进行登录
-	response, err := h.authService.Login(req.Username, req.Password)
+	response, err := h.authService.Login(req.Username, req.Password, r.UserAgent(), getClientIP(r))
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			sendErrorResponse(w, http.StatusUnauthorized, "用户名或密码错误")
 			return
 		}
+		if errors.Is(err, service.ErrAccountLocked) {
+			sendErrorResponse(w, http.StatusLocked, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrLDAPUnavailable) {
+			sendErrorResponse(w, http.StatusServiceUnavailable, "目录服务暂不可用，请稍后重试")
+			return
+		}
 		// 其他错误
 		sendErrorResponse(w, http.StatusInternalServerError, "登录失败: "+err.Error())
 		return
@@ -73,6 +83,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 			sendErrorResponse(w, http.StatusConflict, "用户名或邮箱已存在")
 			return
 		}
+		if errors.Is(err, service.ErrWeakPassword) {
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		// 其他错误
 		sendErrorResponse(w, http.StatusInternalServerError, "注册失败: "+err.Error())
 		return
@@ -161,46 +175,171 @@ func (h *AuthHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 调用服务更新密码
-	if err := h.authService.UpdatePassword(userID, &req); err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
+	// 调用服务更新密码，成功后其他设备需要重新登录
+	pair, err := h.authService.UpdatePassword(userID, &req, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
 			sendErrorResponse(w, http.StatusNotFound, "用户不存在")
-			return
+		case errors.Is(err, service.ErrOldPasswordIncorrect),
+			errors.Is(err, service.ErrWeakPassword),
+			errors.Is(err, service.ErrPasswordReused),
+			errors.Is(err, service.ErrExternalAccountPassword):
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			sendErrorResponse(w, http.StatusInternalServerError, "更新密码失败: "+err.Error())
 		}
-		// 其他错误
-		sendErrorResponse(w, http.StatusInternalServerError, "更新密码失败: "+err.Error())
 		return
 	}
 
-	// 返回更新成功消息
-	sendSuccessResponse(w, "更新密码成功", nil)
+	// 返回新的令牌对
+	sendSuccessResponse(w, "更新密码成功", pair)
+}
+
+// GetPasswordPolicy 获取密码策略，供注册和修改密码页面提示
+func (h *AuthHandler) GetPasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.authService.GetPasswordPolicy()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendSuccessResponse(w, "获取密码策略成功", policy)
 }
 
-// RefreshToken 刷新令牌
+// RefreshToken 使用刷新令牌换取新的令牌对
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
-	// 从上下文中获取用户ID
+	var req model.TokenRefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	pair, err := h.authService.RefreshToken(req.RefreshToken, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			sendErrorResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusInternalServerError, "刷新令牌失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "刷新令牌成功", pair)
+}
+
+// Logout 退出当前登录会话
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetTokenClaims(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	if err := h.authService.Logout(claims); err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "退出登录失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "退出登录成功", nil)
+}
+
+// LogoutAll 退出所有设备
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r)
+	if err != nil {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	if err := h.authService.RevokeUserTokens(userID); err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "退出所有设备失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "已退出所有设备", nil)
+}
+
+// GetLoginSessions 获取当前用户已登录的设备
+func (h *AuthHandler) GetLoginSessions(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromContext(r)
 	if err != nil {
 		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
 		return
 	}
 
-	// 调用服务刷新令牌
-	token, expireTime, err := h.authService.RefreshToken(userID)
+	sessions, err := h.authService.GetLoginSessions(userID)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sessions == nil {
+		sessions = []*model.RefreshToken{}
+	}
+
+	sendSuccessResponse(w, "获取登录设备成功", sessions)
+}
+
+// RevokeUserSessions 管理员强制指定用户下线
+func (h *AuthHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.authService.RevokeUserTokens(uint(id)); err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "吊销用户会话失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "已吊销用户全部登录会话", nil)
+}
+
+// UnlockUser 管理员解除因连续登录失败导致的账号锁定
+func (h *AuthHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.authService.UnlockUser(uint(id)); err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "解除账号锁定失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "账号已解除锁定", nil)
+}
+
+// RequirePasswordChange 管理员要求用户下次登录时修改密码，用户现有会话随即失效
+func (h *AuthHandler) RequirePasswordChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.authService.RequirePasswordChange(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
 			sendErrorResponse(w, http.StatusNotFound, "用户不存在")
-			return
+		case errors.Is(err, service.ErrExternalAccountPassword):
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			sendErrorResponse(w, http.StatusInternalServerError, "设置修改密码要求失败: "+err.Error())
 		}
-		// 其他错误
-		sendErrorResponse(w, http.StatusInternalServerError, "刷新令牌失败: "+err.Error())
 		return
 	}
 
-	// 返回新令牌
-	sendSuccessResponse(w, "刷新令牌成功", map[string]interface{}{
-		"token":  token,
-		"expire": expireTime.Unix(),
+	sendSuccessResponse(w, "已要求用户下次登录时修改密码", nil)
+}
+
+// GetJWKS 以标准JWKS格式公开令牌验证公钥，供其他内部服务校验mini-web签发的令牌
+func (h *AuthHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": h.authService.GetJWKS(),
 	})
 }
 
@@ -208,25 +347,12 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 // getUserIDFromContext 从请求上下文中获取用户ID
 func getUserIDFromContext(r *http.Request) (uint, error) {
-	// 在实际应用中，这个函数应该从JWT中间件设置的上下文中获取用户ID
-	// 这里简单模拟一下
-	// 实际实现需要根据你的认证中间件来调整
-	
-	// 从Authorization头获取令牌
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return 0, errors.New("缺少授权头")
+	// 用户ID由JWT认证中间件写入上下文
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		return 0, errors.New("无法获取用户ID")
 	}
-
-	// 期望格式: "Bearer {token}"
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return 0, errors.New("授权头格式无效")
-	}
-
-	// 这里简单返回1，实际应用中应该从令牌中解析用户ID
-	// 真实实现应该使用JWT令牌解析
-	return 1, nil
+	return userID, nil
 }
 
 // sendSuccessResponse 发送成功响应
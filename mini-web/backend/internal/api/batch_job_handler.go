@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// batchJobHeartbeatInterval 事件流的心跳间隔，避免代理关闭空闲连接
+const batchJobHeartbeatInterval = 15 * time.Second
+
+// BatchJobHandler 批量命令执行处理器
+type BatchJobHandler struct {
+	batchJobService *service.BatchJobService
+}
+
+// NewBatchJobHandler 创建批量命令执行处理器
+func NewBatchJobHandler(batchJobService *service.BatchJobService) *BatchJobHandler {
+	return &BatchJobHandler{batchJobService: batchJobService}
+}
+
+// CreateJob 创建批量任务并开始执行
+func (h *BatchJobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.BatchJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	job, err := h.batchJobService.CreateJob(userID, &req)
+	if err != nil {
+		sendBatchJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "批量任务已开始执行", job)
+}
+
+// GetJobs 获取当前用户最近的批量任务
+func (h *BatchJobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	jobs, err := h.batchJobService.ListJobs(userID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取批量任务列表成功", jobs)
+}
+
+// GetJob 获取批量任务及各主机的执行结果
+func (h *BatchJobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.batchJobService.GetJob(userID, jobID)
+	if err != nil {
+		sendBatchJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取批量任务成功", job)
+}
+
+// RerunJob 重新执行批量任务，默认只在失败的主机上执行
+func (h *BatchJobHandler) RerunJob(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.BatchJobRerunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+			return
+		}
+	}
+	failedOnly := req.FailedOnly == nil || *req.FailedOnly
+
+	job, err := h.batchJobService.RerunJob(userID, jobID, failedOnly)
+	if err != nil {
+		sendBatchJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "批量任务已重新执行", job)
+}
+
+// CancelJob 取消正在执行的批量任务
+func (h *BatchJobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.batchJobService.CancelJob(userID, jobID); err != nil {
+		sendBatchJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "批量任务已取消", nil)
+}
+
+// StreamEvents 以Server-Sent Events推送任务的实时输出和结果，先补发已发生的事件。
+// 任务结束时发送job_done后关闭；没有收到job_done就断开时客户端可重新订阅
+func (h *BatchJobHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendErrorResponse(w, http.StatusInternalServerError, "服务器不支持流式响应")
+		return
+	}
+
+	history, events, unsubscribe, err := h.batchJobService.Subscribe(userID, jobID)
+	if err != nil {
+		sendBatchJobError(w, err)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// 禁止nginx缓冲事件流
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range history {
+		if err := writeBatchJobEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(batchJobHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeBatchJobEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeBatchJobEvent 按SSE格式写出一个事件
+func writeBatchJobEvent(w http.ResponseWriter, event *model.BatchJobEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("序列化批量任务事件失败: %v", err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// parseRequest 获取当前用户ID和路径中的任务ID
+func (h *BatchJobHandler) parseRequest(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的任务ID")
+		return 0, 0, false
+	}
+
+	return userID, uint(id), true
+}
+
+// sendBatchJobError 将批量任务服务错误转换为HTTP响应
+func sendBatchJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrBatchJobNotFound), errors.Is(err, service.ErrConnectionNotFound),
+		errors.Is(err, service.ErrFolderNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrConnectionForbidden), errors.Is(err, service.ErrFolderForbidden):
+		sendErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrBatchJobRunning), errors.Is(err, service.ErrBatchJobNotRunning):
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrBatchJobNoTargets), errors.Is(err, service.ErrBatchJobTooManyHosts),
+		errors.Is(err, service.ErrBatchJobCommandRequired), errors.Is(err, service.ErrBatchJobNotSSH),
+		errors.Is(err, service.ErrInvalidSudoUser):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
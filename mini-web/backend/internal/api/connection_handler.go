@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gitee.com/await29/mini-web/internal/middleware"
@@ -21,14 +22,11 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// 添加WebSocket升级器
+// 添加WebSocket升级器，来源白名单由ConfigureWebSocketOrigins设置
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  2 * 1024 * 1024, // 2MB
 	WriteBufferSize: 2 * 1024 * 1024, // 2MB
-	CheckOrigin: func(r *http.Request) bool {
-		// 在生产环境中应该检查来源
-		return true
-	},
+	CheckOrigin:     checkWebSocketOrigin,
 }
 
 // SafeWebSocketConn 线程安全的WebSocket连接包装器
@@ -103,6 +101,7 @@ func safeSetWriteDeadline(wsConn *websocket.Conn, t time.Time, mutex *sync.Mutex
 // ConnectionHandler 连接处理器
 type ConnectionHandler struct {
 	connService          *service.ConnectionService
+	snippetService       *service.SnippetService // 终端中展开命令片段
 	binaryProtocol       *service.BinaryProtocolHandler
 	specialDetector      *service.SpecialCommandDetector
 	wsWriteMutex         sync.Mutex      // WebSocket写入互斥锁
@@ -111,15 +110,57 @@ type ConnectionHandler struct {
 }
 
 // NewConnectionHandler 创建连接处理器实例
-func NewConnectionHandler(connService *service.ConnectionService) *ConnectionHandler {
+func NewConnectionHandler(connService *service.ConnectionService, snippetService *service.SnippetService) *ConnectionHandler {
 	return &ConnectionHandler{
 		connService:       connService,
+		snippetService:    snippetService,
 		binaryProtocol:    service.NewBinaryProtocolHandler(),
 		specialDetector:   service.NewSpecialCommandDetector(),
 		cancelledRequests: make(map[string]bool),
 	}
 }
 
+// newConnectionResponse 构建连接响应，不包含密码、私钥等敏感信息；
+// 仅有view或connect权限时同时隐藏凭据和SSH密钥的引用
+func newConnectionResponse(conn *model.Connection) model.ConnectionResponse {
+	response := model.ConnectionResponse{
+		ID:             conn.ID,
+		Name:           conn.Name,
+		Protocol:       conn.Protocol,
+		Host:           conn.Host,
+		Port:           conn.Port,
+		Username:       conn.Username,
+		AuthMethods:    conn.AuthMethods,
+		CertPrincipals: conn.CertPrincipals,
+		SSHKeyID:       conn.SSHKeyID,
+		CredentialID:   conn.CredentialID,
+		FolderID:       conn.FolderID,
+		Group:          conn.Group,
+		Tags:           conn.Tags,
+		Description:    conn.Description,
+		LastUsed:       conn.LastUsed,
+		CreatedBy:      conn.CreatedBy,
+		CreatedAt:      conn.CreatedAt,
+		UpdatedAt:      conn.UpdatedAt,
+		Permission:     conn.Permission,
+	}
+
+	if conn.Permission == model.PermissionView || conn.Permission == model.PermissionConnect {
+		response.SSHKeyID = 0
+		response.CredentialID = 0
+	}
+	return response
+}
+
+// newConnectionResponses 批量构建连接响应
+func newConnectionResponses(connections []*model.Connection) []model.ConnectionResponse {
+	responses := make([]model.ConnectionResponse, 0, len(connections))
+	for _, conn := range connections {
+		responses = append(responses, newConnectionResponse(conn))
+	}
+	return responses
+}
+
 // CreateConnection 创建连接
 func (h *ConnectionHandler) CreateConnection(w http.ResponseWriter, r *http.Request) {
 	// 获取用户ID
@@ -149,25 +190,21 @@ func (h *ConnectionHandler) CreateConnection(w http.ResponseWriter, r *http.Requ
 			sendErrorResponse(w, http.StatusBadRequest, "无效的协议类型")
 			return
 		}
+		if errors.Is(err, service.ErrInvalidAuthMethod) || errors.Is(err, service.ErrSSHKeyNotFound) ||
+			errors.Is(err, service.ErrCredentialNotFound) || errors.Is(err, service.ErrFolderNotFound) {
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrConnectionForbidden) || errors.Is(err, service.ErrFolderForbidden) {
+			sendErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		sendErrorResponse(w, http.StatusInternalServerError, "创建连接失败: "+err.Error())
 		return
 	}
 
 	// 构建响应
-	response := model.ConnectionResponse{
-		ID:          conn.ID,
-		Name:        conn.Name,
-		Protocol:    conn.Protocol,
-		Host:        conn.Host,
-		Port:        conn.Port,
-		Username:    conn.Username,
-		Group:       conn.Group,
-		Description: conn.Description,
-		LastUsed:    conn.LastUsed,
-		CreatedBy:   conn.CreatedBy,
-		CreatedAt:   conn.CreatedAt,
-		UpdatedAt:   conn.UpdatedAt,
-	}
+	response := newConnectionResponse(conn)
 
 	sendSuccessResponse(w, "创建连接成功", response)
 }
@@ -214,25 +251,21 @@ func (h *ConnectionHandler) UpdateConnection(w http.ResponseWriter, r *http.Requ
 			sendErrorResponse(w, http.StatusBadRequest, "无效的协议类型")
 			return
 		}
+		if errors.Is(err, service.ErrInvalidAuthMethod) || errors.Is(err, service.ErrSSHKeyNotFound) ||
+			errors.Is(err, service.ErrCredentialNotFound) || errors.Is(err, service.ErrFolderNotFound) {
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrConnectionForbidden) || errors.Is(err, service.ErrFolderForbidden) {
+			sendErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		sendErrorResponse(w, http.StatusInternalServerError, "更新连接失败: "+err.Error())
 		return
 	}
 
 	// 构建响应
-	response := model.ConnectionResponse{
-		ID:          conn.ID,
-		Name:        conn.Name,
-		Protocol:    conn.Protocol,
-		Host:        conn.Host,
-		Port:        conn.Port,
-		Username:    conn.Username,
-		Group:       conn.Group,
-		Description: conn.Description,
-		LastUsed:    conn.LastUsed,
-		CreatedBy:   conn.CreatedBy,
-		CreatedAt:   conn.CreatedAt,
-		UpdatedAt:   conn.UpdatedAt,
-	}
+	response := newConnectionResponse(conn)
 
 	sendSuccessResponse(w, "更新连接成功", response)
 }
@@ -261,6 +294,10 @@ func (h *ConnectionHandler) DeleteConnection(w http.ResponseWriter, r *http.Requ
 			sendErrorResponse(w, http.StatusNotFound, "连接不存在")
 			return
 		}
+		if errors.Is(err, service.ErrConnectionForbidden) {
+			sendErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		sendErrorResponse(w, http.StatusInternalServerError, "删除连接失败: "+err.Error())
 		return
 	}
@@ -293,25 +330,16 @@ func (h *ConnectionHandler) GetConnection(w http.ResponseWriter, r *http.Request
 			sendErrorResponse(w, http.StatusNotFound, "连接不存在")
 			return
 		}
+		if errors.Is(err, service.ErrConnectionForbidden) {
+			sendErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		sendErrorResponse(w, http.StatusInternalServerError, "获取连接失败: "+err.Error())
 		return
 	}
 
 	// 构建响应
-	response := model.ConnectionResponse{
-		ID:          conn.ID,
-		Name:        conn.Name,
-		Protocol:    conn.Protocol,
-		Host:        conn.Host,
-		Port:        conn.Port,
-		Username:    conn.Username,
-		Group:       conn.Group,
-		Description: conn.Description,
-		LastUsed:    conn.LastUsed,
-		CreatedBy:   conn.CreatedBy,
-		CreatedAt:   conn.CreatedAt,
-		UpdatedAt:   conn.UpdatedAt,
-	}
+	response := newConnectionResponse(conn)
 
 	sendSuccessResponse(w, "获取连接成功", response)
 }
@@ -335,20 +363,7 @@ func (h *ConnectionHandler) GetUserConnections(w http.ResponseWriter, r *http.Re
 	// 构建响应
 	var connectionResponses []model.ConnectionResponse
 	for _, conn := range connections {
-		response := model.ConnectionResponse{
-			ID:          conn.ID,
-			Name:        conn.Name,
-			Protocol:    conn.Protocol,
-			Host:        conn.Host,
-			Port:        conn.Port,
-			Username:    conn.Username,
-			Group:       conn.Group,
-			Description: conn.Description,
-			LastUsed:    conn.LastUsed,
-			CreatedBy:   conn.CreatedBy,
-			CreatedAt:   conn.CreatedAt,
-			UpdatedAt:   conn.UpdatedAt,
-		}
+		response := newConnectionResponse(conn)
 		connectionResponses = append(connectionResponses, response)
 	}
 
@@ -414,10 +429,7 @@ func (h *ConnectionHandler) CreateSession(w http.ResponseWriter, r *http.Request
 	}
 
 	// 获取客户端IP
-	clientIP := r.RemoteAddr
-	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
-		clientIP = forwardedFor
-	}
+	clientIP := getClientIP(r)
 
 	// 创建会话
 	session, err := h.connService.CreateSession(userID, uint(id), clientIP)
@@ -426,6 +438,10 @@ func (h *ConnectionHandler) CreateSession(w http.ResponseWriter, r *http.Request
 			sendErrorResponse(w, http.StatusNotFound, "连接不存在")
 			return
 		}
+		if errors.Is(err, service.ErrConnectionForbidden) {
+			sendErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		sendErrorResponse(w, http.StatusInternalServerError, "创建会话失败: "+err.Error())
 		return
 	}
@@ -510,61 +526,21 @@ func (h *ConnectionHandler) GetActiveSessions(w http.ResponseWriter, r *http.Req
 
 // HandleTerminalWebSocket 处理终端WebSocket连接
 func (h *ConnectionHandler) HandleTerminalWebSocket(w http.ResponseWriter, r *http.Request) {
-	// 添加非常详细的日志
-	log.Printf("==========================================")
-	log.Printf("收到终端WebSocket连接请求: %s %s%s", r.Method, r.URL.Path, r.URL.RawQuery)
-	log.Printf("请求头:")
-	for name, values := range r.Header {
-		for _, value := range values {
-			log.Printf("  %s: %s", name, value)
-		}
-	}
-	log.Printf("==========================================")
-
-	// 设置CORS头
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Sec-WebSocket-Key, Sec-WebSocket-Extensions, Sec-WebSocket-Version")
-	w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-	// 使用全局定义的upgrader，不再定义本地upgrader
-
-	// 简化逻辑：直接尝试升级WebSocket连接
-	log.Printf("尝试直接升级WebSocket连接...")
-
-	// 首先检查URL查询参数中的令牌
-	urlToken := r.URL.Query().Get("token")
-	var userID uint
-	var ok bool
-
-	if urlToken != "" {
-		log.Printf("从URL查询参数中获取到令牌")
-		// 验证URL中的令牌
-		claims, err := middleware.ValidateToken(urlToken)
-		if err == nil && claims.UserID > 0 {
-			userID = claims.UserID
-			ok = true
-			log.Printf("URL令牌验证成功, 用户ID: %d", userID)
-		} else if err != nil {
-			log.Printf("URL令牌验证失败: %v", err)
-		}
-	}
-
-	// 如果URL中没有有效令牌，尝试从请求头获取
-	if !ok {
-		userID, ok = middleware.GetUserID(r)
-		if !ok {
-			log.Printf("WebSocket连接认证失败：用户ID未找到")
-			sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
-			return
-		}
-	}
+	log.Printf("收到终端WebSocket连接请求: %s %s", r.Method, r.URL.Path)
 
 	// 获取参数
 	vars := mux.Vars(r)
 	protocol := vars["protocol"]
 	sessionIDStr := vars["sessionId"]
 
+	// 使用一次性票据认证，票据必须签发给该会话
+	userID, ok := authenticateWebSocket(r, sessionIDStr)
+	if !ok {
+		log.Printf("WebSocket连接认证失败：票据无效")
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
 	log.Printf("收到WebSocket连接请求: 协议=%s, 会话ID=%s, 用户ID=%d", protocol, sessionIDStr, userID)
 
 	// 验证协议
@@ -592,10 +568,14 @@ func (h *ConnectionHandler) HandleTerminalWebSocket(w http.ResponseWriter, r *ht
 
 	log.Printf("会话验证成功: ID=%d, 连接ID=%d", session.ID, session.ConnectionID)
 
-	// 获取连接信息
-	connectionInfo, err := h.connService.GetConnection(userID, session.ConnectionID)
+	// 获取连接信息，发起终端会话需要connect权限
+	connectionInfo, err := h.connService.AuthorizeConnection(userID, session.ConnectionID, model.PermissionConnect)
 	if err != nil {
 		log.Printf("获取连接信息失败: 连接ID=%d, 错误: %v", session.ConnectionID, err)
+		if errors.Is(err, service.ErrConnectionForbidden) {
+			sendErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		sendErrorResponse(w, http.StatusNotFound, "连接信息不存在")
 		return
 	}
@@ -610,24 +590,6 @@ func (h *ConnectionHandler) HandleTerminalWebSocket(w http.ResponseWriter, r *ht
 	// 升级HTTP连接为WebSocket
 	log.Printf("尝试升级HTTP连接为WebSocket...")
 
-	// 设置响应头，确保CORS支持
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-	// 打印请求头信息以便调试
-	log.Printf("请求头信息:")
-	for name, values := range r.Header {
-		log.Printf("  %s: %v", name, values)
-	}
-
-	// 配置升级器，明确允许所有来源
-	upgrader.CheckOrigin = func(r *http.Request) bool {
-		log.Printf("WebSocket CheckOrigin被调用，来源: %s", r.Header.Get("Origin"))
-		return true // 允许所有来源
-	}
-
 	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("升级WebSocket连接失败: %v", err)
@@ -639,18 +601,57 @@ func (h *ConnectionHandler) HandleTerminalWebSocket(w http.ResponseWriter, r *ht
 
 	log.Printf("WebSocket连接升级成功")
 
+	// 携带续传令牌重连时接管断开前的终端，令牌失效则新建终端
+	var resumable *service.ResumableTerminal
+	var lastSeq uint64
+	if resumeToken := r.URL.Query().Get("resume_token"); resumeToken != "" {
+		lastSeq, _ = strconv.ParseUint(r.URL.Query().Get("last_seq"), 10, 64)
+		resumable, err = service.ResumeTerminal(resumeToken, userID, sessionIDStr)
+		if err != nil {
+			log.Printf("续传终端失败，将新建终端: 会话ID=%s, 错误: %v", sessionIDStr, err)
+		} else {
+			log.Printf("续传终端: 会话ID=%s, 客户端最后序号=%d, 服务端最后序号=%d", sessionIDStr, lastSeq, resumable.LastSeq())
+			generation := resumable.Attach(func() { wsConn.Close() })
+			defer resumable.Detach(generation)
+			h.handleTerminalSession(wsConn, userID, resumable.Terminal, sessionIDStr, &terminalResume{
+				terminal:   resumable,
+				generation: generation,
+				lastSeq:    lastSeq,
+				resumed:    true,
+			})
+			return
+		}
+	}
+
 	// 创建终端会话 - 使用数据库中的实际协议
 	log.Printf("尝试创建终端会话: 协议=%s", actualProtocol)
-	terminal, err := h.connService.CreateTerminalSession(actualProtocol, connectionInfo)
+	// SSH的keyboard-interactive和私钥口令提示通过当前WebSocket转发给用户
+	authOptions, err := h.connService.PrepareSessionAuth(userID, connectionInfo, newWSAuthPrompter(wsConn, h.binaryProtocol))
+	if err != nil {
+		log.Printf("准备会话认证失败: 连接ID=%d, 错误: %v", connectionInfo.ID, err)
+		wsConn.WriteMessage(websocket.TextMessage, []byte("创建终端会话失败: "+err.Error()))
+		return
+	}
+	terminal, err := h.connService.CreateTerminalSessionWithAuth(actualProtocol, connectionInfo, authOptions)
 	if err != nil {
 		log.Printf("创建终端会话失败: 协议=%s, 错误: %v", actualProtocol, err)
 		wsConn.WriteMessage(websocket.TextMessage, []byte("创建终端会话失败: "+err.Error()))
 		return
 	}
-	defer terminal.Close()
 
 	log.Printf("终端会话创建成功，开始处理WebSocket通信")
 
+	// 文本终端支持断线续传，连接断开后终端在宽限期内保留
+	var resume *terminalResume
+	if actualProtocol == model.ProtocolSSH || actualProtocol == model.ProtocolTelnet {
+		resumable = service.NewResumableTerminal(userID, sessionIDStr, terminal)
+		generation := resumable.Attach(func() { wsConn.Close() })
+		defer resumable.Detach(generation)
+		resume = &terminalResume{terminal: resumable, generation: generation}
+	} else {
+		defer terminal.Close()
+	}
+
 	// 如果是RDP会话，需要设置WebSocket连接并启动连接
 	if actualProtocol == "rdp" {
 		if rdpSession, ok := terminal.(*service.RDPSessionSimple); ok {
@@ -669,15 +670,25 @@ func (h *ConnectionHandler) HandleTerminalWebSocket(w http.ResponseWriter, r *ht
 	}
 
 	// 处理WebSocket连接
-	h.handleTerminalSession(wsConn, terminal)
+	h.handleTerminalSession(wsConn, userID, terminal, sessionIDStr, resume)
+}
+
+// terminalResume 可续传终端在当前连接上的状态
+type terminalResume struct {
+	terminal   *service.ResumableTerminal
+	generation uint64
+	lastSeq    uint64 // 客户端收到的最后序号
+	resumed    bool   // 是否为重连接管
 }
 
-// handleTerminalSession 处理终端会话的WebSocket通信
-func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, terminal service.TerminalSession) {
+// handleTerminalSession 处理终端会话的WebSocket通信，resume为nil表示终端不支持续传
+func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, userID uint, terminal service.TerminalSession, sessionID string, resume *terminalResume) {
 	var once sync.Once
 	done := make(chan struct{})
 	errChan := make(chan error, 2)       // 用于传递错误
 	activeChan := make(chan struct{}, 2) // 用于活跃性检测
+	// 监控循环因错误或超时返回时也要通知读写协程退出，避免等待流控信用的协程泄漏
+	defer once.Do(func() { close(done) })
 
 	log.Printf("开始处理终端会话WebSocket通信")
 
@@ -710,8 +721,11 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 		}
 	}
 
-	// 对于非RDP协议或RDP连接已建立，尝试读取初始响应
-	if !isRDP {
+	// 对于非RDP协议或RDP连接已建立，尝试读取初始响应。续传的终端已在运行，不再探测
+	if resume != nil && resume.resumed {
+		log.Printf("续传终端，跳过初始响应读取")
+		buf = make([]byte, 512)
+	} else if !isRDP {
 		// 向终端发送一条测试消息
 		log.Printf("向终端发送测试消息")
 		terminal.Write([]byte{0}) // 发送空消息
@@ -781,6 +795,13 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 	timeout := 3 * time.Minute // 3分钟无活动则超时
 	lastActivity := time.Now()
 
+	// 协议协商选定的压缩方式，协商前不压缩
+	var outputCompression atomic.Uint32
+
+	// 终端输出流控，客户端在协商中声明支持后启用
+	flow := service.NewFlowControl(sessionID)
+	defer flow.Release()
+
 	// 活动检测计时器
 	activityTimer := time.NewTicker(10 * time.Second)
 	defer activityTimer.Stop()
@@ -856,6 +877,10 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 										// 设置消息类型为协议协商
 										responseData[4] = service.MessageTypeProtocolNegotiation
 										wsConn.WriteMessage(websocket.BinaryMessage, responseData)
+										outputCompression.Store(uint32(serverNegotiation.Compression))
+										if serverNegotiation.FlowControlWindow > 0 {
+											flow.Enable(int(serverNegotiation.FlowControlWindow))
+										}
 										log.Printf("发送协议协商响应成功")
 									}
 								}
@@ -865,6 +890,17 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 					continue
 				}
 
+				// 处理流控确认
+				if protocolMsg.Header.MessageType == service.MessageTypeFlowControl {
+					if ackData, err := json.Marshal(protocolMsg.JSONData); err == nil {
+						var ack service.FlowControlAck
+						if err := json.Unmarshal(ackData, &ack); err == nil {
+							flow.Ack(int(ack.Bytes))
+						}
+					}
+					continue
+				}
+
 				// 处理心跳消息
 				if protocolMsg.Header.MessageType == service.MessageTypeHeartbeat {
 					// 更新活动时间
@@ -1137,6 +1173,25 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 							} else {
 								log.Printf("解析文件列表请求数据失败: %v", err)
 							}
+						case "snippet":
+							// 展开命令片段并写入终端输入，execute为true时直接回车执行
+							var snippetData terminalSnippetRequest
+							err := json.Unmarshal(cmd.Data, &snippetData)
+							if err == nil {
+								log.Printf("收到命令片段请求: 片段ID=%d, 执行=%v, 请求ID=%s", snippetData.ID, snippetData.Execute, snippetData.RequestID)
+								var input []byte
+								if input, err = h.expandTerminalSnippet(userID, &snippetData); err == nil {
+									_, err = terminal.Write(input)
+								}
+							} else {
+								err = errors.New("请求数据格式错误")
+							}
+							if err != nil {
+								log.Printf("处理命令片段请求失败: 片段ID=%d, 错误: %v", snippetData.ID, err)
+							}
+							if responseBytes, marshalErr := json.Marshal(terminalSnippetResponse(&snippetData, err)); marshalErr == nil {
+								safeWriteMessage(wsConn, websocket.TextMessage, responseBytes, &h.wsWriteMutex)
+							}
 						case "resize":
 							// 支持两种不同格式的调整大小命令
 							// 1. {type: "resize", width: X, height: Y}
@@ -1625,6 +1680,16 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 
 		log.Printf("启动终端读取协程（非RDP协议）")
 
+		// 可续传终端先告知续传令牌，重连时补发客户端缺失的输出帧
+		if resume != nil {
+			if err := h.replayTerminalOutput(wsConn, resume, uint8(outputCompression.Load()), flow); err != nil {
+				if !errors.Is(err, service.ErrTerminalDetached) {
+					errChan <- err
+				}
+				return
+			}
+		}
+
 		buf := make([]byte, 1024*1024*2) // 增加缓冲区大小到2MB以处理大型图形数据
 		for {
 			// 设置读取截止时间
@@ -1633,8 +1698,25 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 				log.Printf("设置WebSocket写入超时失败: %v", err)
 			}
 
-			n, err := terminal.Read(buf)
+			// 信用耗尽时停止读取终端，直到客户端确认已消费的输出
+			limit, ok := flow.Acquire(len(buf), done)
+			if !ok {
+				return
+			}
+
+			var n int
+			var seq uint64
+			var err error
+			if resume != nil {
+				n, seq, err = resume.terminal.Read(resume.generation, buf[:limit], done)
+			} else {
+				n, err = terminal.Read(buf[:limit])
+			}
 			if err != nil {
+				if errors.Is(err, service.ErrTerminalDetached) {
+					log.Printf("终端已与当前连接分离，停止读取")
+					return
+				}
 				if err != io.EOF {
 					log.Printf("读取终端输出错误: %v", err)
 					errChan <- err
@@ -1724,6 +1806,9 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 				"type": "terminal-output",
 				"size": n,
 			}
+			if seq > 0 {
+				metadata["seq"] = seq
+			}
 
 			// 检查是否为图形协议
 			if n > 4 {
@@ -1747,7 +1832,7 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 			}
 
 			// 尝试使用二进制协议编码
-			if encodedData, err := h.binaryProtocol.EncodeMessage(metadata, buf[:n], service.CompressionNone); err == nil {
+			if encodedData, err := h.binaryProtocol.EncodeMessage(metadata, buf[:n], uint8(outputCompression.Load())); err == nil {
 				finalData = encodedData
 				finalMsgType = websocket.BinaryMessage
 				protocolUsed = true
@@ -1774,6 +1859,8 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 			}
 
 			if protocolUsed {
+				// 只有二进制协议消息由客户端确认
+				flow.Consume(n)
 				log.Printf("成功发送 %d 字节二进制协议数据到WebSocket客户端（原始数据 %d 字节）", len(finalData), n)
 			} else {
 				log.Printf("成功发送 %d 字节数据到WebSocket客户端", n)
@@ -1842,6 +1929,61 @@ func (h *ConnectionHandler) handleTerminalSession(wsConn *websocket.Conn, termin
 	}
 }
 
+// replayTerminalOutput 发送续传信息，重连时补发序号大于客户端最后序号的输出帧
+func (h *ConnectionHandler) replayTerminalOutput(wsConn *websocket.Conn, resume *terminalResume, compression uint8, flow *service.FlowControl) error {
+	var frames []service.ReplayFrame
+	complete := true
+	if resume.resumed {
+		var err error
+		frames, complete, err = resume.terminal.Replay(resume.generation, resume.lastSeq)
+		if err != nil {
+			return err
+		}
+	}
+
+	info := service.ResumeInfo{
+		Type:     "resume",
+		Token:    resume.terminal.Token,
+		Resumed:  resume.resumed,
+		LastSeq:  resume.terminal.LastSeq(),
+		Replayed: len(frames),
+		Gap:      !complete,
+	}
+	if len(frames) > 0 {
+		info.LastSeq = frames[len(frames)-1].Seq
+	}
+	infoData, err := h.binaryProtocol.EncodeMessage(info, nil, service.CompressionNone)
+	if err != nil {
+		return fmt.Errorf("编码续传信息失败: %w", err)
+	}
+	wsConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err := wsConn.WriteMessage(websocket.BinaryMessage, infoData); err != nil {
+		return err
+	}
+
+	if resume.resumed {
+		log.Printf("补发终端输出: 帧数=%d, 完整=%v", len(frames), complete)
+	}
+	for _, frame := range frames {
+		metadata := map[string]interface{}{
+			"type":   "terminal-output",
+			"size":   len(frame.Data),
+			"seq":    frame.Seq,
+			"replay": true,
+		}
+		encoded, err := h.binaryProtocol.EncodeMessage(metadata, frame.Data, compression)
+		if err != nil {
+			return fmt.Errorf("编码补发输出失败: %w", err)
+		}
+		wsConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := wsConn.WriteMessage(websocket.BinaryMessage, encoded); err != nil {
+			return err
+		}
+		flow.Consume(len(frame.Data))
+	}
+	return nil
+}
+
 // sendSegmentedResponse 发送分段响应
 func (h *ConnectionHandler) sendSegmentedResponse(wsConn *websocket.Conn, data []byte, requestId string) error {
 	const segmentSize = 16384 // 16KB 每段，更保守的大小
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// CredentialHandler 凭据处理器
+type CredentialHandler struct {
+	credentialService *service.CredentialService
+}
+
+// NewCredentialHandler 创建凭据处理器
+func NewCredentialHandler(credentialService *service.CredentialService) *CredentialHandler {
+	return &CredentialHandler{credentialService: credentialService}
+}
+
+// GetCredentials 获取当前用户可用的凭据列表
+func (h *CredentialHandler) GetCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	creds, err := h.credentialService.GetUserCredentials(userID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if creds == nil {
+		creds = []*model.Credential{}
+	}
+
+	sendSuccessResponse(w, "获取凭据列表成功", creds)
+}
+
+// CreateCredential 创建凭据
+func (h *CredentialHandler) CreateCredential(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.CredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	cred, err := h.credentialService.CreateCredential(userID, &req)
+	if err != nil {
+		sendCredentialError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "创建凭据成功", cred)
+}
+
+// GetCredential 获取凭据详情（不包含敏感信息）
+func (h *CredentialHandler) GetCredential(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的凭据ID")
+	if !ok {
+		return
+	}
+
+	cred, err := h.credentialService.GetCredential(userID, id)
+	if err != nil {
+		sendCredentialError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取凭据成功", cred)
+}
+
+// UpdateCredential 更新凭据
+func (h *CredentialHandler) UpdateCredential(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的凭据ID")
+	if !ok {
+		return
+	}
+
+	var req model.CredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	cred, err := h.credentialService.UpdateCredential(userID, id, &req)
+	if err != nil {
+		sendCredentialError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "更新凭据成功", cred)
+}
+
+// DeleteCredential 删除凭据
+func (h *CredentialHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的凭据ID")
+	if !ok {
+		return
+	}
+
+	if err := h.credentialService.DeleteCredential(userID, id); err != nil {
+		sendCredentialError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "删除凭据成功", nil)
+}
+
+// GetCredentialConnections 获取引用了该凭据的连接
+func (h *CredentialHandler) GetCredentialConnections(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的凭据ID")
+	if !ok {
+		return
+	}
+
+	connections, err := h.credentialService.GetCredentialConnections(userID, id)
+	if err != nil {
+		sendCredentialError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取引用凭据的连接成功", newConnectionResponses(connections))
+}
+
+// RotateCredential 轮换凭据，并返回受影响的连接
+func (h *CredentialHandler) RotateCredential(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的凭据ID")
+	if !ok {
+		return
+	}
+
+	var req model.CredentialRotateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	cred, connections, err := h.credentialService.RotateCredential(userID, id, &req)
+	if err != nil {
+		sendCredentialError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "凭据轮换成功", map[string]interface{}{
+		"credential":  cred,
+		"connections": newConnectionResponses(connections),
+	})
+}
+
+// sendCredentialError 将凭据服务错误转换为HTTP响应
+func sendCredentialError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrCredentialNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCredentialInUse):
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrCredentialNotOwner):
+		sendErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidCredentialType), errors.Is(err, service.ErrCredentialSecretRequired),
+		errors.Is(err, service.ErrTeamNotFound):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
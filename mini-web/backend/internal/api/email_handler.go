@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// EmailHandler 邮件配置、模板和发信队列处理器
+type EmailHandler struct {
+	emailService *service.EmailService
+}
+
+// NewEmailHandler 创建邮件处理器
+func NewEmailHandler(emailService *service.EmailService) *EmailHandler {
+	return &EmailHandler{emailService: emailService}
+}
+
+// GetEmailConfig 获取邮件配置，不返回SMTP密码
+func (h *EmailHandler) GetEmailConfig(w http.ResponseWriter, r *http.Request) {
+	config, err := h.emailService.GetConfig()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取邮件配置失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取邮件配置成功", config)
+}
+
+// UpdateEmailConfig 更新邮件配置，密码留空时保留原密码
+func (h *EmailHandler) UpdateEmailConfig(w http.ResponseWriter, r *http.Request) {
+	var config model.EmailConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := h.emailService.SaveConfig(&config); err != nil {
+		if errors.Is(err, service.ErrInvalidEmailConfig) {
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusInternalServerError, "保存邮件配置失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "邮件配置保存成功", config)
+}
+
+// TestEmailConnection 使用表单中的配置测试SMTP连接和认证
+func (h *EmailHandler) TestEmailConnection(w http.ResponseWriter, r *http.Request) {
+	var config model.EmailConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := h.emailService.TestConnection(&config); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "邮件连接测试失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "邮件连接测试成功", nil)
+}
+
+// SendTestEmail 使用表单中的配置直接发送测试邮件
+func (h *EmailHandler) SendTestEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Config model.EmailConfig `json:"config"`
+		Email  string            `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+	if req.Email == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "收件人邮箱不能为空")
+		return
+	}
+
+	if err := h.emailService.SendTest(&req.Config, req.Email); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "测试邮件发送失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "测试邮件发送成功", nil)
+}
+
+// GetEmailTemplates 获取邮件模板列表
+func (h *EmailHandler) GetEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.emailService.GetTemplates()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取邮件模板失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取邮件模板成功", templates)
+}
+
+// CreateEmailTemplate 创建邮件模板
+func (h *EmailHandler) CreateEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	var template model.EmailTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := h.emailService.CreateTemplate(&template); err != nil {
+		h.sendTemplateError(w, "创建邮件模板失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "邮件模板创建成功", template)
+}
+
+// UpdateEmailTemplate 更新邮件模板
+func (h *EmailHandler) UpdateEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的模板ID")
+		return
+	}
+
+	var template model.EmailTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+	template.ID = uint(id)
+
+	if err := h.emailService.UpdateTemplate(&template); err != nil {
+		h.sendTemplateError(w, "更新邮件模板失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "邮件模板更新成功", template)
+}
+
+// DeleteEmailTemplate 删除邮件模板
+func (h *EmailHandler) DeleteEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的模板ID")
+		return
+	}
+
+	if err := h.emailService.DeleteTemplate(uint(id)); err != nil {
+		h.sendTemplateError(w, "删除邮件模板失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "邮件模板删除成功", nil)
+}
+
+// GetEmailTemplateVariables 获取模板可用变量及说明
+func (h *EmailHandler) GetEmailTemplateVariables(w http.ResponseWriter, r *http.Request) {
+	variables, descriptions := h.emailService.TemplateVariables()
+
+	sendSuccessResponse(w, "获取模板变量成功", map[string]interface{}{
+		"variables":    variables,
+		"descriptions": descriptions,
+		"usage":        "在模板中使用 {{variable_name}} 的格式来使用变量，HTML正文中的变量值会自动转义",
+	})
+}
+
+// GetEmailQueue 分页查询发信队列，可按status过滤
+func (h *EmailHandler) GetEmailQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	messages, total, err := h.emailService.GetQueue(query.Get("status"), limit, offset)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取发信队列失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取发信队列成功", map[string]interface{}{
+		"list":   messages,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// RetryEmail 重新发送已放弃的邮件
+func (h *EmailHandler) RetryEmail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的邮件ID")
+		return
+	}
+
+	if err := h.emailService.RetryMessage(uint(id)); err != nil {
+		if errors.Is(err, service.ErrEmailMessageNotFound) {
+			sendErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusInternalServerError, "重新发送邮件失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "邮件已重新加入发送队列", nil)
+}
+
+// sendTemplateError 按错误类型返回模板操作的错误响应
+func (h *EmailHandler) sendTemplateError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidEmailTemplate):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrEmailTemplateNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// HostKeyHandler SSH主机公钥记录处理器
+type HostKeyHandler struct {
+	hostKeyService *service.HostKeyService
+}
+
+// NewHostKeyHandler 创建SSH主机公钥处理器
+func NewHostKeyHandler(hostKeyService *service.HostKeyService) *HostKeyHandler {
+	return &HostKeyHandler{hostKeyService: hostKeyService}
+}
+
+// GetHostKeys 获取已记录的SSH主机公钥
+func (h *HostKeyHandler) GetHostKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.hostKeyService.GetAll()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取主机公钥失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取主机公钥成功", keys)
+}
+
+// DeleteHostKey 删除主机公钥记录，主机更换密钥后下次连接时重新记录
+func (h *HostKeyHandler) DeleteHostKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的主机公钥ID")
+		return
+	}
+
+	if err := h.hostKeyService.Delete(uint(id)); err != nil {
+		if errors.Is(err, service.ErrHostKeyNotFound) {
+			sendErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusInternalServerError, "删除主机公钥失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "主机公钥记录已删除", nil)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// NotificationHandler 通知渠道处理器。个人渠道属于当前用户，
+// 系统渠道（所属用户为0）由管理员维护并接收全部事件
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+	system              bool
+}
+
+// NewNotificationHandler 创建个人通知渠道处理器
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// NewSystemNotificationHandler 创建系统通知渠道处理器
+func NewSystemNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService, system: true}
+}
+
+// owner 获取渠道所属用户ID，系统渠道为0
+func (h *NotificationHandler) owner(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	if h.system {
+		return 0, true
+	}
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return 0, false
+	}
+	return userID, true
+}
+
+// ownerAndChannelID 获取渠道所属用户ID和路径中的渠道ID
+func (h *NotificationHandler) ownerAndChannelID(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	ownerID, ok := h.owner(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的渠道ID")
+		return 0, 0, false
+	}
+	return ownerID, uint(id), true
+}
+
+// GetEventTypes 获取可订阅的事件类型
+func (h *NotificationHandler) GetEventTypes(w http.ResponseWriter, r *http.Request) {
+	sendSuccessResponse(w, "获取事件类型成功", h.notificationService.EventTypes())
+}
+
+// GetChannels 获取通知渠道列表
+func (h *NotificationHandler) GetChannels(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	channels, err := h.notificationService.ListChannels(ownerID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取通知渠道失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取通知渠道成功", channels)
+}
+
+// CreateChannel 创建通知渠道，自动生成的签名密钥只在此次响应中返回
+func (h *NotificationHandler) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.NotificationChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	channel, err := h.notificationService.CreateChannel(ownerID, &req)
+	if err != nil {
+		h.sendChannelError(w, "创建通知渠道失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "通知渠道创建成功", channel)
+}
+
+// UpdateChannel 更新通知渠道，密钥留空时保留原密钥
+func (h *NotificationHandler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
+	ownerID, id, ok := h.ownerAndChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.NotificationChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	channel, err := h.notificationService.UpdateChannel(ownerID, id, &req)
+	if err != nil {
+		h.sendChannelError(w, "更新通知渠道失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "通知渠道更新成功", channel)
+}
+
+// DeleteChannel 删除通知渠道
+func (h *NotificationHandler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	ownerID, id, ok := h.ownerAndChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.notificationService.DeleteChannel(ownerID, id); err != nil {
+		h.sendChannelError(w, "删除通知渠道失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "通知渠道删除成功", nil)
+}
+
+// TestChannel 向通知渠道发送测试消息
+func (h *NotificationHandler) TestChannel(w http.ResponseWriter, r *http.Request) {
+	ownerID, id, ok := h.ownerAndChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.notificationService.TestChannel(ownerID, id); err != nil {
+		if errors.Is(err, service.ErrEmailDisabled) {
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrNotificationChannelNotFound) {
+			sendErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusBadGateway, "测试通知发送失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "测试通知已发送", nil)
+}
+
+// GetDeliveries 获取通知渠道最近的投递记录
+func (h *NotificationHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	ownerID, id, ok := h.ownerAndChannelID(w, r)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	deliveries, err := h.notificationService.GetDeliveries(ownerID, id, limit)
+	if err != nil {
+		h.sendChannelError(w, "获取投递记录失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取投递记录成功", deliveries)
+}
+
+// sendChannelError 将通知服务错误转换为HTTP响应
+func (h *NotificationHandler) sendChannelError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotificationChannelNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidNotificationChannel):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, prefix+": "+err.Error())
+	}
+}
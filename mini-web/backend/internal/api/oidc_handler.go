@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// OIDCHandler OpenID Connect单点登录处理器
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+// NewOIDCHandler 创建单点登录处理器
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService}
+}
+
+// GetInfo 获取登录页需要的单点登录信息
+func (h *OIDCHandler) GetInfo(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.oidcService.LoadSettings()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取单点登录信息成功", map[string]interface{}{
+		"enabled":       settings.Enabled,
+		"provider_name": settings.ProviderName,
+		"login_url":     "/api/auth/oidc/login",
+	})
+}
+
+// Login 跳转到身份提供者的授权页面
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.oidcService.BeginLogin(r.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			sendErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusBadGateway, "发起单点登录失败: "+err.Error())
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback 处理身份提供者回调，登录结果通过URL片段交给前端，令牌不会出现在服务端日志中
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.oidcService.LoadSettings()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	fragment := url.Values{}
+
+	if providerError := query.Get("error"); providerError != "" {
+		message := query.Get("error_description")
+		if message == "" {
+			message = providerError
+		}
+		fragment.Set("error", message)
+	} else {
+		response, err := h.oidcService.CompleteLogin(r.Context(), query.Get("state"), query.Get("code"), r.UserAgent(), getClientIP(r))
+		if err != nil {
+			fragment.Set("error", err.Error())
+		} else {
+			fragment.Set("token", response.Token)
+			fragment.Set("expire", strconv.FormatInt(response.Expire, 10))
+			fragment.Set("refresh_token", response.RefreshToken)
+			fragment.Set("refresh_expire", strconv.FormatInt(response.RefreshExpire, 10))
+		}
+	}
+
+	http.Redirect(w, r, settings.FrontendURL+"#"+fragment.Encode(), http.StatusFound)
+}
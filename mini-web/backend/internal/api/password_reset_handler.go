@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// PasswordResetHandler 自助找回密码处理器
+type PasswordResetHandler struct {
+	resetService *service.PasswordResetService
+}
+
+// NewPasswordResetHandler 创建找回密码处理器
+func NewPasswordResetHandler(resetService *service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{resetService: resetService}
+}
+
+// ForgotPassword 申请找回密码，无论邮箱是否注册都返回相同的响应
+func (h *PasswordResetHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := h.resetService.RequestReset(req.Email, getClientIP(r)); err != nil {
+		if errors.Is(err, service.ErrTooManyResetRequests) {
+			sendErrorResponse(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusInternalServerError, "申请找回密码失败")
+		return
+	}
+
+	sendSuccessResponse(w, "如果该邮箱已注册，重置密码的链接将发送到该邮箱", nil)
+}
+
+// ResetPassword 使用邮件中的令牌设置新密码
+func (h *PasswordResetHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+	if req.Token == "" || req.NewPassword == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "令牌和新密码不能为空")
+		return
+	}
+
+	if err := h.resetService.ResetPassword(req.Token, req.NewPassword, getClientIP(r)); err != nil {
+		switch {
+		case errors.Is(err, service.ErrTooManyResetRequests):
+			sendErrorResponse(w, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, service.ErrInvalidResetToken),
+			errors.Is(err, service.ErrWeakPassword),
+			errors.Is(err, service.ErrPasswordReused):
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			sendErrorResponse(w, http.StatusInternalServerError, "重置密码失败: "+err.Error())
+		}
+		return
+	}
+
+	sendSuccessResponse(w, "密码已重置，请使用新密码登录", nil)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// RoleHandler 角色与权限处理器
+type RoleHandler struct {
+	roleService *service.RoleService
+}
+
+// NewRoleHandler 创建角色与权限处理器
+func NewRoleHandler(roleService *service.RoleService) *RoleHandler {
+	return &RoleHandler{roleService: roleService}
+}
+
+// GetPermissions 获取全部可分配的权限
+func (h *RoleHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	sendSuccessResponse(w, "获取权限列表成功", h.roleService.GetPermissionCatalog())
+}
+
+// GetRoles 获取全部角色
+func (h *RoleHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.GetRoles()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if roles == nil {
+		roles = []*model.Role{}
+	}
+
+	sendSuccessResponse(w, "获取角色列表成功", roles)
+}
+
+// GetRole 获取角色详情
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	_, id, ok := parseIDRequest(w, r, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	role, err := h.roleService.GetRole(id)
+	if err != nil {
+		sendRoleError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取角色成功", role)
+}
+
+// CreateRole 创建自定义角色
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req model.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	role, err := h.roleService.CreateRole(&req)
+	if err != nil {
+		sendRoleError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "创建角色成功", role)
+}
+
+// UpdateRole 更新角色描述和权限
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	_, id, ok := parseIDRequest(w, r, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	var req model.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(id, &req)
+	if err != nil {
+		sendRoleError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "更新角色成功", role)
+}
+
+// DeleteRole 删除自定义角色
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	_, id, ok := parseIDRequest(w, r, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	if err := h.roleService.DeleteRole(id); err != nil {
+		sendRoleError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "删除角色成功", nil)
+}
+
+// sendRoleError 将角色服务错误转换为HTTP响应
+func sendRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrRoleExists), errors.Is(err, service.ErrRoleInUse):
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrRoleBuiltIn):
+		sendErrorResponse(w, http.StatusForbidden, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	}
+}
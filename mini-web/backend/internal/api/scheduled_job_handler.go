@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// ScheduledJobHandler 定时任务处理器
+type ScheduledJobHandler struct {
+	scheduledJobService *service.ScheduledJobService
+}
+
+// NewScheduledJobHandler 创建定时任务处理器
+func NewScheduledJobHandler(scheduledJobService *service.ScheduledJobService) *ScheduledJobHandler {
+	return &ScheduledJobHandler{scheduledJobService: scheduledJobService}
+}
+
+// CreateJob 创建定时任务
+func (h *ScheduledJobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.ScheduledJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	job, err := h.scheduledJobService.CreateJob(userID, &req)
+	if err != nil {
+		sendScheduledJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "创建定时任务成功", job)
+}
+
+// GetJobs 获取当前用户的定时任务
+func (h *ScheduledJobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	jobs, err := h.scheduledJobService.ListJobs(userID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取定时任务列表成功", jobs)
+}
+
+// GetJob 获取定时任务
+func (h *ScheduledJobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.scheduledJobService.GetJob(userID, jobID)
+	if err != nil {
+		sendScheduledJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取定时任务成功", job)
+}
+
+// UpdateJob 修改定时任务
+func (h *ScheduledJobHandler) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.ScheduledJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	job, err := h.scheduledJobService.UpdateJob(userID, jobID, &req)
+	if err != nil {
+		sendScheduledJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "更新定时任务成功", job)
+}
+
+// DeleteJob 删除定时任务及其执行记录
+func (h *ScheduledJobHandler) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.scheduledJobService.DeleteJob(userID, jobID); err != nil {
+		sendScheduledJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "删除定时任务成功", nil)
+}
+
+// RunJob 立即执行一次定时任务
+func (h *ScheduledJobHandler) RunJob(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.scheduledJobService.RunJob(userID, jobID); err != nil {
+		sendScheduledJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "定时任务已开始执行", nil)
+}
+
+// GetRuns 获取定时任务的执行记录，输出通过批量任务接口查看
+func (h *ScheduledJobHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	userID, jobID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := h.scheduledJobService.GetRuns(userID, jobID)
+	if err != nil {
+		sendScheduledJobError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取执行记录成功", runs)
+}
+
+// GetPauseState 获取定时任务全局暂停状态
+func (h *ScheduledJobHandler) GetPauseState(w http.ResponseWriter, r *http.Request) {
+	sendSuccessResponse(w, "获取定时任务暂停状态成功", &model.ScheduledJobPauseState{
+		Paused: h.scheduledJobService.IsPaused(),
+	})
+}
+
+// SetPauseState 全局暂停或恢复定时任务
+func (h *ScheduledJobHandler) SetPauseState(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.ScheduledJobPauseState
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := h.scheduledJobService.SetPaused(userID, req.Paused); err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	message := "定时任务已恢复"
+	if req.Paused {
+		message = "定时任务已全局暂停"
+	}
+	sendSuccessResponse(w, message, &req)
+}
+
+// parseRequest 获取当前用户ID和路径中的定时任务ID
+func (h *ScheduledJobHandler) parseRequest(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的定时任务ID")
+		return 0, 0, false
+	}
+
+	return userID, uint(id), true
+}
+
+// sendScheduledJobError 将定时任务服务错误转换为HTTP响应，其余错误按批量任务处理
+func sendScheduledJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrScheduledJobNotFound), errors.Is(err, service.ErrCredentialNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrScheduledJobRunning), errors.Is(err, service.ErrScheduledJobsPaused):
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidCronExpr), errors.Is(err, service.ErrScheduledJobCredentialRequired),
+		errors.Is(err, service.ErrInvalidOverlapPolicy):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		sendBatchJobError(w, err)
+	}
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// SnippetHandler 命令片段处理器
+type SnippetHandler struct {
+	snippetService *service.SnippetService
+}
+
+// NewSnippetHandler 创建命令片段处理器
+func NewSnippetHandler(snippetService *service.SnippetService) *SnippetHandler {
+	return &SnippetHandler{snippetService: snippetService}
+}
+
+// GetSnippets 获取当前用户可见的命令片段，带q参数时全文搜索
+func (h *SnippetHandler) GetSnippets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	snippets, err := h.snippetService.ListSnippets(userID, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取命令片段列表成功", snippets)
+}
+
+// CreateSnippet 创建命令片段
+func (h *SnippetHandler) CreateSnippet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.SnippetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	snippet, err := h.snippetService.CreateSnippet(userID, &req)
+	if err != nil {
+		sendSnippetError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "创建命令片段成功", snippet)
+}
+
+// GetSnippet 获取命令片段
+func (h *SnippetHandler) GetSnippet(w http.ResponseWriter, r *http.Request) {
+	userID, snippetID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, err := h.snippetService.GetSnippet(userID, snippetID)
+	if err != nil {
+		sendSnippetError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取命令片段成功", snippet)
+}
+
+// UpdateSnippet 更新命令片段
+func (h *SnippetHandler) UpdateSnippet(w http.ResponseWriter, r *http.Request) {
+	userID, snippetID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.SnippetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	snippet, err := h.snippetService.UpdateSnippet(userID, snippetID, &req)
+	if err != nil {
+		sendSnippetError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "更新命令片段成功", snippet)
+}
+
+// DeleteSnippet 删除命令片段
+func (h *SnippetHandler) DeleteSnippet(w http.ResponseWriter, r *http.Request) {
+	userID, snippetID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.snippetService.DeleteSnippet(userID, snippetID); err != nil {
+		sendSnippetError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "删除命令片段成功", nil)
+}
+
+// ExpandSnippet 按参数展开命令片段，仅用于预览，不计入使用次数
+func (h *SnippetHandler) ExpandSnippet(w http.ResponseWriter, r *http.Request) {
+	userID, snippetID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.SnippetExpandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	expansion, err := h.snippetService.ExpandSnippet(userID, snippetID, req.Args)
+	if err != nil {
+		sendSnippetError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "展开命令片段成功", expansion)
+}
+
+// RunSnippet 将命令片段作为批量任务在多台主机上执行
+func (h *SnippetHandler) RunSnippet(w http.ResponseWriter, r *http.Request) {
+	userID, snippetID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.SnippetBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	detail, err := h.snippetService.RunSnippet(userID, snippetID, &req)
+	if err != nil {
+		sendSnippetError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "命令片段已开始批量执行", detail)
+}
+
+// parseRequest 获取当前用户ID和路径中的命令片段ID
+func (h *SnippetHandler) parseRequest(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的命令片段ID")
+		return 0, 0, false
+	}
+
+	return userID, uint(id), true
+}
+
+// sendSnippetError 将命令片段服务错误转换为HTTP响应，其余错误按批量任务处理
+func sendSnippetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrSnippetNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrSnippetForbidden):
+		sendErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidSnippet), errors.Is(err, service.ErrInvalidSnippetScope),
+		errors.Is(err, service.ErrInvalidSnippetParameter), errors.Is(err, service.ErrInvalidSnippetArgument),
+		errors.Is(err, service.ErrTeamNotFound):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		sendBatchJobError(w, err)
+	}
+}
+
+// terminalSnippetRequest 终端WebSocket上的snippet消息：展开命令片段并写入终端输入
+type terminalSnippetRequest struct {
+	ID        uint                   `json:"id"`
+	Args      map[string]interface{} `json:"args"`
+	Execute   bool                   `json:"execute"` // 写入后回车执行，否则只填入命令行等待用户确认
+	RequestID string                 `json:"requestId,omitempty"`
+}
+
+// expandTerminalSnippet 展开命令片段并转换为终端输入，换行转换为回车，多行片段会逐行执行
+func (h *ConnectionHandler) expandTerminalSnippet(userID uint, req *terminalSnippetRequest) ([]byte, error) {
+	if h.snippetService == nil {
+		return nil, errors.New("命令片段功能不可用")
+	}
+
+	expansion, err := h.snippetService.UseSnippet(userID, req.ID, req.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	input := strings.ReplaceAll(expansion.Command, "\n", "\r")
+	if req.Execute {
+		input = strings.TrimRight(input, "\r") + "\r"
+	}
+	return []byte(input), nil
+}
+
+// terminalSnippetResponse 构造snippet_response消息
+func terminalSnippetResponse(req *terminalSnippetRequest, err error) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":        req.ID,
+		"requestId": req.RequestID,
+		"success":   err == nil,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	return map[string]interface{}{"type": "snippet_response", "data": data}
+}
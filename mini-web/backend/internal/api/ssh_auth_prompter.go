@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/websocket"
+)
+
+// wsAuthPrompter 通过终端WebSocket（二进制协议）向浏览器转发SSH认证提示
+// 仅在终端会话建立前使用，此时没有其他协程读写该连接
+type wsAuthPrompter struct {
+	wsConn         *websocket.Conn
+	binaryProtocol *service.BinaryProtocolHandler
+}
+
+// newWSAuthPrompter 创建二进制协议的认证提示转发器
+func newWSAuthPrompter(wsConn *websocket.Conn, binaryProtocol *service.BinaryProtocolHandler) *wsAuthPrompter {
+	return &wsAuthPrompter{
+		wsConn:         wsConn,
+		binaryProtocol: binaryProtocol,
+	}
+}
+
+// Prompt 以终端输出+特殊命令信息的形式发送提示，并等待用户输入一行
+func (p *wsAuthPrompter) Prompt(info *service.SpecialCommandInfo) (string, error) {
+	text := info.Prompt
+	if info.Context != "" {
+		text = info.Context + "\r\n" + text
+	}
+
+	// 与终端输出中的特殊命令提示格式保持一致，前端复用同一套提示交互
+	metadata := map[string]interface{}{
+		"type": "terminal-output",
+		"auth": true,
+		"special": map[string]interface{}{
+			"type":        string(info.Type),
+			"prompt":      info.Prompt,
+			"masked":      info.Masked,
+			"expectInput": info.ExpectInput,
+			"timeout":     info.Timeout,
+			"description": info.Description,
+		},
+	}
+	if err := p.writeOutput(metadata, []byte("\r\n"+text)); err != nil {
+		return "", fmt.Errorf("发送认证提示失败: %w", err)
+	}
+
+	log.Printf("已发送SSH认证提示: 类型=%s, 提示=%s, 掩码=%v", info.Type, info.Prompt, info.Masked)
+
+	deadline := time.Now().Add(time.Duration(info.Timeout) * time.Second)
+	defer p.wsConn.SetReadDeadline(time.Time{})
+	var input []rune
+	for {
+		p.wsConn.SetReadDeadline(deadline)
+		messageType, data, err := p.wsConn.ReadMessage()
+		if err != nil {
+			return "", fmt.Errorf("等待认证输入失败: %w", err)
+		}
+
+		chunk, ok := p.extractInput(messageType, data)
+		if !ok {
+			continue
+		}
+
+		var complete bool
+		input, complete, err = editPromptInput(input, chunk)
+		if err != nil {
+			return "", err
+		}
+		if complete {
+			// 输入完成，换行后继续后续认证流程
+			p.writeOutput(map[string]interface{}{"type": "terminal-output"}, []byte("\r\n"))
+			return string(input), nil
+		}
+	}
+}
+
+// editPromptInput 把终端按键应用到当前输入行，遇到回车时complete为true，Ctrl+C返回错误
+func editPromptInput(input []rune, chunk string) ([]rune, bool, error) {
+	for _, r := range chunk {
+		switch r {
+		case '\r', '\n':
+			return input, true, nil
+		case '\x7f', '\b':
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		case '\x03':
+			return input, false, fmt.Errorf("用户取消了认证")
+		default:
+			input = append(input, r)
+		}
+	}
+	return input, false, nil
+}
+
+// extractInput 从WebSocket消息中提取用户输入，心跳等控制消息返回false
+func (p *wsAuthPrompter) extractInput(messageType int, data []byte) (string, bool) {
+	if messageType == websocket.BinaryMessage && p.binaryProtocol.IsProtocolMessage(data) {
+		protocolMsg, err := p.binaryProtocol.DecodeMessage(data)
+		if err != nil {
+			return "", false
+		}
+
+		if protocolMsg.Header.MessageType == service.MessageTypeHeartbeat {
+			if heartbeatReply, err := p.binaryProtocol.CreateHeartbeatMessage(); err == nil {
+				p.wsConn.WriteMessage(websocket.BinaryMessage, heartbeatReply)
+			}
+			return "", false
+		}
+
+		if jsonMap, ok := protocolMsg.JSONData.(map[string]interface{}); ok && jsonMap["type"] == "command" {
+			if content, isString := jsonMap["content"].(string); isString {
+				return content, true
+			}
+		}
+		if protocolMsg.BinaryData != nil {
+			return string(protocolMsg.BinaryData), true
+		}
+		return "", false
+	}
+
+	// JSON控制消息（resize等）在认证阶段忽略
+	if messageType == websocket.TextMessage && strings.HasPrefix(string(data), "{") {
+		return "", false
+	}
+
+	return string(data), true
+}
+
+// writeOutput 使用二进制协议向终端写入输出
+func (p *wsAuthPrompter) writeOutput(metadata map[string]interface{}, data []byte) error {
+	encoded, err := p.binaryProtocol.EncodeMessage(metadata, data, service.CompressionNone)
+	if err != nil {
+		return err
+	}
+	p.wsConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return p.wsConn.WriteMessage(websocket.BinaryMessage, encoded)
+}
+
+// sessionAuthPrompter 通过会话管理器的JSON消息通道向浏览器转发SSH认证提示
+type sessionAuthPrompter struct {
+	wsConn *websocket.Conn
+}
+
+// Prompt 发送auth_prompt消息并等待客户端回复input消息
+func (p *sessionAuthPrompter) Prompt(info *service.SpecialCommandInfo) (string, error) {
+	prompt := map[string]interface{}{
+		"type":      "auth_prompt",
+		"content":   info.Prompt,
+		"special":   info,
+		"timestamp": time.Now(),
+	}
+	if err := p.wsConn.WriteJSON(prompt); err != nil {
+		return "", fmt.Errorf("发送认证提示失败: %w", err)
+	}
+
+	deadline := time.Now().Add(time.Duration(info.Timeout) * time.Second)
+	defer p.wsConn.SetReadDeadline(time.Time{})
+	for {
+		p.wsConn.SetReadDeadline(deadline)
+		_, data, err := p.wsConn.ReadMessage()
+		if err != nil {
+			return "", fmt.Errorf("等待认证输入失败: %w", err)
+		}
+
+		var msg struct {
+			Type    string `json:"type"`
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			// 非JSON格式当作普通输入
+			return strings.TrimRight(string(data), "\r\n"), nil
+		}
+
+		switch msg.Type {
+		case "input":
+			return strings.TrimRight(msg.Content, "\r\n"), nil
+		case "close":
+			return "", fmt.Errorf("用户取消了认证")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// SSHCAHandler SSH证书颁发机构处理器
+type SSHCAHandler struct {
+	sshCAService *service.SSHCAService
+}
+
+// NewSSHCAHandler 创建SSH证书颁发机构处理器
+func NewSSHCAHandler(sshCAService *service.SSHCAService) *SSHCAHandler {
+	return &SSHCAHandler{sshCAService: sshCAService}
+}
+
+// GetPublicKey 导出CA公钥，用于目标主机sshd的TrustedUserCAKeys配置
+// format=raw时直接返回authorized_keys格式文本，便于 curl > /etc/ssh/mini-web-ca.pub
+func (h *SSHCAHandler) GetPublicKey(w http.ResponseWriter, r *http.Request) {
+	publicKey, fingerprint, err := h.sshCAService.GetPublicKey()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取CA公钥失败: "+err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "raw" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=mini-web-ca.pub")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(publicKey + "\n"))
+		return
+	}
+
+	sendSuccessResponse(w, "获取CA公钥成功", map[string]interface{}{
+		"public_key":  publicKey,
+		"fingerprint": fingerprint,
+		"sshd_config": "TrustedUserCAKeys /etc/ssh/mini-web-ca.pub",
+	})
+}
+
+// GetCertificates 分页获取证书签发记录
+func (h *SSHCAHandler) GetCertificates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	certs, total, err := h.sshCAService.GetIssuanceLog(limit, offset)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取证书签发记录失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取证书签发记录成功", map[string]interface{}{
+		"list":   certs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetCertificate 根据序列号获取证书签发记录
+func (h *SSHCAHandler) GetCertificate(w http.ResponseWriter, r *http.Request) {
+	serial, err := strconv.ParseUint(mux.Vars(r)["serial"], 10, 64)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的证书序列号")
+		return
+	}
+
+	cert, err := h.sshCAService.GetCertificateBySerial(serial)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取证书签发记录失败: "+err.Error())
+		return
+	}
+	if cert == nil {
+		sendErrorResponse(w, http.StatusNotFound, "证书签发记录不存在")
+		return
+	}
+
+	sendSuccessResponse(w, "获取证书签发记录成功", cert)
+}
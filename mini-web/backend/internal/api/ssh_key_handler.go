@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// SSHKeyHandler SSH密钥管理处理器
+type SSHKeyHandler struct {
+	sshKeyService *service.SSHKeyService
+}
+
+// NewSSHKeyHandler 创建SSH密钥管理处理器
+func NewSSHKeyHandler(sshKeyService *service.SSHKeyService) *SSHKeyHandler {
+	return &SSHKeyHandler{sshKeyService: sshKeyService}
+}
+
+// GetKeys 获取当前用户的SSH密钥列表
+func (h *SSHKeyHandler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	keys, err := h.sshKeyService.GetUserKeys(userID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if keys == nil {
+		keys = []*model.SSHKey{}
+	}
+
+	sendSuccessResponse(w, "获取SSH密钥列表成功", keys)
+}
+
+// GenerateKey 在服务端生成SSH密钥对
+func (h *SSHKeyHandler) GenerateKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.SSHKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	key, err := h.sshKeyService.GenerateKey(userID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSSHKeyType) || errors.Is(err, service.ErrInvalidSSHKeyBits) {
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendErrorResponse(w, http.StatusInternalServerError, "生成SSH密钥失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "生成SSH密钥成功", key)
+}
+
+// GetKey 获取SSH密钥详情
+func (h *SSHKeyHandler) GetKey(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.sshKeyService.GetKey(userID, keyID)
+	if err != nil {
+		sendSSHKeyError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取SSH密钥成功", key)
+}
+
+// DeleteKey 删除SSH密钥
+func (h *SSHKeyHandler) DeleteKey(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.sshKeyService.DeleteKey(userID, keyID); err != nil {
+		sendSSHKeyError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "删除SSH密钥成功", nil)
+}
+
+// GetLinkedConnections 获取关联了该密钥的连接
+func (h *SSHKeyHandler) GetLinkedConnections(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	connections, err := h.sshKeyService.GetLinkedConnections(userID, keyID)
+	if err != nil {
+		sendSSHKeyError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取关联连接成功", newConnectionResponses(connections))
+}
+
+// DeployKey 将公钥部署到目标主机的authorized_keys
+func (h *SSHKeyHandler) DeployKey(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.SSHKeyDeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ConnectionID == 0 {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	result, err := h.sshKeyService.DeployKey(userID, keyID, &req)
+	if err != nil {
+		sendSSHKeyError(w, err)
+		return
+	}
+
+	message := "公钥部署成功"
+	if !result.Added {
+		message = "公钥已存在，无需重复部署"
+	}
+	sendSuccessResponse(w, message, result)
+}
+
+// RotateKey 轮换SSH密钥并重新部署到所有关联主机
+func (h *SSHKeyHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	key, results, err := h.sshKeyService.RotateKey(userID, keyID)
+	if err != nil {
+		if errors.Is(err, service.ErrSSHKeyRotationFailed) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(model.ResponseData{
+				Code:    http.StatusBadGateway,
+				Message: err.Error(),
+				Data:    map[string]interface{}{"results": results},
+			})
+			return
+		}
+		sendSSHKeyError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "SSH密钥轮换成功", map[string]interface{}{
+		"key":     key,
+		"results": results,
+	})
+}
+
+// parseRequest 获取当前用户ID和路径中的密钥ID
+func (h *SSHKeyHandler) parseRequest(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的密钥ID")
+		return 0, 0, false
+	}
+
+	return userID, uint(id), true
+}
+
+// sendSSHKeyError 将SSH密钥服务错误转换为HTTP响应
+func sendSSHKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrSSHKeyNotFound), errors.Is(err, service.ErrConnectionNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrConnectionForbidden):
+		sendErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrSSHKeyInUse):
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidProtocol):
+		sendErrorResponse(w, http.StatusBadRequest, "仅SSH连接支持部署公钥")
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// CompleteCSRRequest 导入外部CA签发证书的请求
+type CompleteCSRRequest struct {
+	CertContent string `json:"cert_content"`
+	IsEnabled   bool   `json:"is_enabled"`
+}
+
+// sendCertificateError 按错误类型返回证书生成相关的错误响应
+func sendCertificateError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCertificateRequest),
+		errors.Is(err, service.ErrCertificateKeyMismatch),
+		errors.Is(err, service.ErrNotInternalCertificate):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrCSRNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInternalCAUnavailable):
+		sendErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
+
+// decodeCertificateRequest 解析证书生成参数
+func decodeCertificateRequest(w http.ResponseWriter, r *http.Request) (*service.CertificateRequest, bool) {
+	var req service.CertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求数据")
+		return nil, false
+	}
+	return &req, true
+}
+
+// parseSSLID 解析路径中的ID
+func parseSSLID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// sendGeneratedConfig 返回生成的SSL配置，不包含私钥
+func (h *SSLHandler) sendGeneratedConfig(w http.ResponseWriter, message string, config *sqlite.SSLConfig) {
+	h.reloadCertificates()
+	config.KeyContent = ""
+	sendSuccessResponse(w, message, config)
+}
+
+// GenerateSelfSigned 生成自签名证书
+func (h *SSLHandler) GenerateSelfSigned(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCertificateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	config, err := h.sslService.CreateSelfSignedCertificate(req)
+	if err != nil {
+		sendCertificateError(w, "生成自签名证书失败", err)
+		return
+	}
+
+	h.sendGeneratedConfig(w, "自签名证书已生成", config)
+}
+
+// IssueInternal 由内部CA签发证书
+func (h *SSLHandler) IssueInternal(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCertificateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	config, err := h.sslService.IssueInternalCertificate(req)
+	if err != nil {
+		sendCertificateError(w, "签发证书失败", err)
+		return
+	}
+
+	h.sendGeneratedConfig(w, "证书已签发", config)
+}
+
+// RenewSSLConfig 续期内部CA签发的证书
+func (h *SSLHandler) RenewSSLConfig(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSSLID(w, r)
+	if !ok {
+		return
+	}
+
+	config, err := h.sslService.RenewInternalCertificate(id)
+	if err != nil {
+		sendCertificateError(w, "续期证书失败", err)
+		return
+	}
+
+	h.sendGeneratedConfig(w, "证书已续期", config)
+}
+
+// GetInternalCA 获取内部CA证书，?download=1时以文件形式下载
+func (h *SSLHandler) GetInternalCA(w http.ResponseWriter, r *http.Request) {
+	certPEM, err := h.sslService.GetInternalCACertificate()
+	if err != nil {
+		sendCertificateError(w, "获取内部CA证书失败", err)
+		return
+	}
+
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.Header().Set("Content-Disposition", `attachment; filename="mini-web-ca.crt"`)
+		w.Write([]byte(certPEM))
+		return
+	}
+
+	info, err := h.sslService.ParseCertificate(certPEM)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "解析内部CA证书失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取内部CA证书成功", map[string]interface{}{
+		"cert_content": certPEM,
+		"info":         info,
+	})
+}
+
+// GetCSRs 获取等待签发的证书请求
+func (h *SSLHandler) GetCSRs(w http.ResponseWriter, r *http.Request) {
+	csrs, err := h.sslService.GetCSRs()
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, "获取证书请求失败: "+err.Error())
+		return
+	}
+
+	sendSuccessResponse(w, "获取证书请求成功", csrs)
+}
+
+// CreateCSR 生成私钥和证书请求
+func (h *SSLHandler) CreateCSR(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCertificateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	csr, err := h.sslService.GenerateCSR(req)
+	if err != nil {
+		sendCertificateError(w, "生成证书请求失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "证书请求已生成", csr)
+}
+
+// DeleteCSR 删除证书请求
+func (h *SSLHandler) DeleteCSR(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSSLID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.sslService.DeleteCSR(id); err != nil {
+		sendCertificateError(w, "删除证书请求失败", err)
+		return
+	}
+
+	sendSuccessResponse(w, "证书请求已删除", nil)
+}
+
+// CompleteCSR 导入外部CA签发的证书，与证书请求的私钥组合为SSL配置
+func (h *SSLHandler) CompleteCSR(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSSLID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CompleteCSRRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	if strings.TrimSpace(req.CertContent) == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "证书内容不能为空")
+		return
+	}
+
+	config, err := h.sslService.CompleteCSR(id, req.CertContent, req.IsEnabled)
+	if err != nil {
+		sendCertificateError(w, "导入证书失败", err)
+		return
+	}
+
+	h.sendGeneratedConfig(w, "证书已导入", config)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -23,6 +24,13 @@ func NewSSLHandler() *SSLHandler {
 	}
 }
 
+// reloadCertificates 证书配置变更后重新加载HTTPS证书
+func (h *SSLHandler) reloadCertificates() {
+	if err := service.GetCertificateStore().Reload(); err != nil {
+		log.Printf("重新加载SSL证书失败: %v", err)
+	}
+}
+
 // GetSSLConfigs 获取SSL配置列表
 func (h *SSLHandler) GetSSLConfigs(w http.ResponseWriter, r *http.Request) {
 	configs, err := sqlite.GetSSLConfigs()
@@ -127,6 +135,7 @@ func (h *SSLHandler) CreateSSLConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "创建SSL配置失败: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.reloadCertificates()
 	
 	// 隐藏私钥内容
 	config.KeyContent = ""
@@ -222,6 +231,7 @@ func (h *SSLHandler) UpdateSSLConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "更新SSL配置失败: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.reloadCertificates()
 	
 	// 隐藏私钥内容
 	config.KeyContent = ""
@@ -252,6 +262,7 @@ func (h *SSLHandler) DeleteSSLConfig(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	h.reloadCertificates()
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -274,6 +285,7 @@ func (h *SSLHandler) SetDefaultSSLConfig(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "设置默认SSL配置失败: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.reloadCertificates()
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -296,6 +308,7 @@ func (h *SSLHandler) EnableSSLConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "启用SSL配置失败: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.reloadCertificates()
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -322,6 +335,7 @@ func (h *SSLHandler) DisableSSLConfig(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	h.reloadCertificates()
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
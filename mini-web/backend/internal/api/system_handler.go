@@ -420,22 +420,5 @@ func (h *SystemHandler) TestEmailConfig(w http.ResponseWriter, r *http.Request)
 
 // getClientIP 获取客户端IP地址
 func getClientIP(r *http.Request) string {
-	// 尝试从各种头部获取真实IP
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		return ip
-	}
-
-	ip = r.Header.Get("X-Real-IP")
-	if ip != "" {
-		return ip
-	}
-
-	ip = r.Header.Get("X-Client-IP")
-	if ip != "" {
-		return ip
-	}
-
-	// 回退到RemoteAddr
-	return r.RemoteAddr
+	return service.ClientIP(r)
 }
\ No newline at end of file
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/mux"
+)
+
+// TeamHandler 团队处理器
+type TeamHandler struct {
+	teamService *service.TeamService
+}
+
+// NewTeamHandler 创建团队处理器
+func NewTeamHandler(teamService *service.TeamService) *TeamHandler {
+	return &TeamHandler{teamService: teamService}
+}
+
+// GetTeams 获取当前用户所属的团队，管理员获取全部团队
+func (h *TeamHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	teams, err := h.teamService.GetTeams(userID)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if teams == nil {
+		teams = []*model.Team{}
+	}
+
+	sendSuccessResponse(w, "获取团队列表成功", teams)
+}
+
+// CreateTeam 创建团队
+func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req model.TeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	team, err := h.teamService.CreateTeam(userID, &req)
+	if err != nil {
+		sendTeamError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "创建团队成功", team)
+}
+
+// GetTeam 获取团队详情
+func (h *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的团队ID")
+	if !ok {
+		return
+	}
+
+	team, err := h.teamService.GetTeam(userID, id)
+	if err != nil {
+		sendTeamError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "获取团队成功", team)
+}
+
+// UpdateTeam 更新团队
+func (h *TeamHandler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的团队ID")
+	if !ok {
+		return
+	}
+
+	var req model.TeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	team, err := h.teamService.UpdateTeam(userID, id, &req)
+	if err != nil {
+		sendTeamError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "更新团队成功", team)
+}
+
+// DeleteTeam 删除团队
+func (h *TeamHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的团队ID")
+	if !ok {
+		return
+	}
+
+	if err := h.teamService.DeleteTeam(userID, id); err != nil {
+		sendTeamError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "删除团队成功", nil)
+}
+
+// GetMembers 获取团队成员
+func (h *TeamHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的团队ID")
+	if !ok {
+		return
+	}
+
+	members, err := h.teamService.GetMembers(userID, id)
+	if err != nil {
+		sendTeamError(w, err)
+		return
+	}
+	if members == nil {
+		members = []*model.TeamMember{}
+	}
+
+	sendSuccessResponse(w, "获取团队成员成功", members)
+}
+
+// AddMember 添加团队成员或修改成员角色
+func (h *TeamHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的团队ID")
+	if !ok {
+		return
+	}
+
+	var req model.TeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	member, err := h.teamService.AddMember(userID, id, &req)
+	if err != nil {
+		sendTeamError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "保存团队成员成功", member)
+}
+
+// RemoveMember 移除团队成员
+func (h *TeamHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseIDRequest(w, r, "id", "无效的团队ID")
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.ParseUint(mux.Vars(r)["userId"], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.teamService.RemoveMember(userID, id, uint(memberID)); err != nil {
+		sendTeamError(w, err)
+		return
+	}
+
+	sendSuccessResponse(w, "移除团队成员成功", nil)
+}
+
+// parseIDRequest 获取当前用户ID和路径中的资源ID
+func parseIDRequest(w http.ResponseWriter, r *http.Request, name string, invalidMessage string) (uint, uint, bool) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)[name], 10, 32)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, invalidMessage)
+		return 0, 0, false
+	}
+
+	return userID, uint(id), true
+}
+
+// sendTeamError 将团队服务错误转换为HTTP响应
+func sendTeamError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrTeamNotFound), errors.Is(err, service.ErrUserNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrTeamForbidden):
+		sendErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidTeamRole), errors.Is(err, service.ErrLastTeamOwner):
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -2,28 +2,33 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 
 	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/model"
 	"gitee.com/await29/mini-web/internal/service"
 )
 
 // TerminalSessionHandler 终端会话处理器
 type TerminalSessionHandler struct {
 	connService    *service.ConnectionService
+	roleService    *service.RoleService
 	sessionManager *service.TerminalSessionManager
 }
 
 // NewTerminalSessionHandler 创建终端会话处理器
-func NewTerminalSessionHandler(connService *service.ConnectionService) *TerminalSessionHandler {
+func NewTerminalSessionHandler(connService *service.ConnectionService, roleService *service.RoleService) *TerminalSessionHandler {
 	return &TerminalSessionHandler{
 		connService:    connService,
+		roleService:    roleService,
 		sessionManager: service.GetTerminalSessionManager(),
 	}
 }
@@ -48,9 +53,13 @@ func (h *TerminalSessionHandler) CreateTerminalSession(w http.ResponseWriter, r
 		return
 	}
 
-	// 验证连接是否存在且属于用户
-	connectionInfo, err := h.connService.GetConnection(userID, req.ConnectionID)
+	// 验证用户对连接具有connect权限
+	connectionInfo, err := h.connService.AuthorizeConnection(userID, req.ConnectionID, model.PermissionConnect)
 	if err != nil {
+		if errors.Is(err, service.ErrConnectionForbidden) {
+			sendErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		sendErrorResponse(w, http.StatusNotFound, "连接不存在")
 		return
 	}
@@ -67,6 +76,7 @@ func (h *TerminalSessionHandler) CreateTerminalSession(w http.ResponseWriter, r
 		sendErrorResponse(w, http.StatusInternalServerError, "创建会话失败: "+err.Error())
 		return
 	}
+	service.GetNotificationService().NotifySessionStarted(userID, connectionInfo, getClientIP(r))
 
 	// 返回会话信息
 	response := map[string]interface{}{
@@ -190,57 +200,36 @@ func (h *TerminalSessionHandler) CloseTerminalSession(w http.ResponseWriter, r *
 
 // HandleTerminalWebSocketWithSession 处理带会话管理的终端WebSocket连接
 func (h *TerminalSessionHandler) HandleTerminalWebSocketWithSession(w http.ResponseWriter, r *http.Request) {
-	log.Printf("==========================================")
-	log.Printf("收到终端WebSocket会话连接请求: %s %s%s", r.Method, r.URL.Path, r.URL.RawQuery)
-	log.Printf("==========================================")
-
-	// 设置CORS头
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-	// 获取用户ID
-	urlToken := r.URL.Query().Get("token")
-	var userID uint
-	var ok bool
-
-	if urlToken != "" {
-		// 验证URL中的令牌
-		claims, err := middleware.ValidateToken(urlToken)
-		if err == nil && claims.UserID > 0 {
-			userID = claims.UserID
-			ok = true
-			log.Printf("URL令牌验证成功, 用户ID: %d", userID)
-		} else {
-			log.Printf("URL令牌验证失败: %v", err)
-		}
-	}
-
-	if !ok {
-		userID, ok = middleware.GetUserID(r)
-		if !ok {
-			log.Printf("WebSocket连接认证失败：用户ID未找到")
-			sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
-			return
-		}
-	}
+	log.Printf("收到终端WebSocket会话连接请求: %s %s", r.Method, r.URL.Path)
 
 	// 获取参数
 	vars := mux.Vars(r)
 	sessionID := vars["sessionId"]
+
+	// 使用一次性票据认证，票据必须签发给该会话
+	userID, ok := authenticateWebSocket(r, sessionID)
+	if !ok {
+		log.Printf("WebSocket连接认证失败：票据无效")
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
 	
 	// 检查是否为恢复会话
 	resume := r.URL.Query().Get("resume") == "true"
 
-	log.Printf("WebSocket会话连接: 会话ID=%s, 用户ID=%d, 恢复模式=%v", sessionID, userID, resume)
+	// 断线续传：resume_token为上次连接收到的续传令牌，last_seq为收到的最后消息序号
+	resumeToken := r.URL.Query().Get("resume_token")
+	lastSeq, _ := strconv.ParseUint(r.URL.Query().Get("last_seq"), 10, 64)
 
-	// 升级到WebSocket连接
-	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+	// flow_control=true时启用输出流控，客户端按输出消息的size发送ack，flow_window可指定窗口字节数
+	flowWindow := -1
+	if r.URL.Query().Get("flow_control") == "true" {
+		flowWindow, _ = strconv.Atoi(r.URL.Query().Get("flow_window"))
 	}
 
+	log.Printf("WebSocket会话连接: 会话ID=%s, 用户ID=%d, 恢复模式=%v", sessionID, userID, resume)
+
+	// 升级到WebSocket连接
 	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("升级WebSocket连接失败: %v", err)
@@ -251,14 +240,15 @@ func (h *TerminalSessionHandler) HandleTerminalWebSocketWithSession(w http.Respo
 	log.Printf("WebSocket连接升级成功")
 
 	// 处理会话连接
-	if err := h.handleSessionWebSocket(sessionID, userID, wsConn, resume); err != nil {
+	if err := h.handleSessionWebSocket(sessionID, userID, wsConn, resume, flowWindow, resumeToken, lastSeq); err != nil {
 		log.Printf("处理会话WebSocket失败: %v", err)
 		wsConn.WriteMessage(websocket.TextMessage, []byte("连接失败: "+err.Error()))
 	}
 }
 
-// handleSessionWebSocket 处理会话WebSocket连接
-func (h *TerminalSessionHandler) handleSessionWebSocket(sessionID string, userID uint, wsConn *websocket.Conn, resume bool) error {
+// handleSessionWebSocket 处理会话WebSocket连接，flowWindow小于0表示不启用流控，
+// resumeToken非空时按lastSeq只补发缺失的消息
+func (h *TerminalSessionHandler) handleSessionWebSocket(sessionID string, userID uint, wsConn *websocket.Conn, resume bool, flowWindow int, resumeToken string, lastSeq uint64) error {
 	// 获取或创建会话
 	var session *service.PersistentTerminalSession
 	var err error
@@ -292,18 +282,32 @@ func (h *TerminalSessionHandler) handleSessionWebSocket(sessionID string, userID
 	}
 
 	// 将WebSocket连接添加到会话
-	if err := h.sessionManager.AddWebSocketConnection(sessionID, wsConn); err != nil {
+	if resumeToken != "" {
+		err = h.sessionManager.ResumeWebSocketConnection(sessionID, wsConn, resumeToken, lastSeq)
+	} else {
+		err = h.sessionManager.AddWebSocketConnection(sessionID, wsConn)
+	}
+	if err != nil {
 		return fmt.Errorf("添加WebSocket连接失败: %w", err)
 	}
 
 	// 如果会话还没有关联的终端进程，需要创建
 	if session.Status == "disconnected" || session.Status == "active" {
-		if err := h.ensureTerminalProcess(session); err != nil {
+		if err := h.ensureTerminalProcess(session, wsConn); err != nil {
 			log.Printf("确保终端进程失败: %v", err)
 			// 不返回错误，继续处理，可能是历史消息会话
 		}
 	}
 
+	if flowWindow >= 0 && session.TerminalProxy != nil {
+		window := session.TerminalProxy.EnableFlowControl(flowWindow)
+		if resumeToken != "" {
+			// 断开前未确认的输出不会再被确认
+			session.TerminalProxy.ResetFlowControl()
+		}
+		log.Printf("会话启用输出流控: 会话ID=%s, 窗口=%d字节", sessionID, window)
+	}
+
 	// 处理WebSocket消息
 	h.handleWebSocketMessages(session, wsConn)
 
@@ -313,21 +317,27 @@ func (h *TerminalSessionHandler) handleSessionWebSocket(sessionID string, userID
 	return nil
 }
 
-// ensureTerminalProcess 确保会话有关联的终端进程
-func (h *TerminalSessionHandler) ensureTerminalProcess(session *service.PersistentTerminalSession) error {
+// ensureTerminalProcess 确保会话有关联的终端进程，SSH交互式认证提示通过wsConn转发
+func (h *TerminalSessionHandler) ensureTerminalProcess(session *service.PersistentTerminalSession, wsConn *websocket.Conn) error {
 	// 如果已经有代理进程，跳过
 	if session.Status == "active" && session.TerminalProxy != nil {
 		return nil
 	}
 
-	// 获取连接信息
-	connectionInfo, err := h.connService.GetConnection(session.UserID, session.ConnectionID)
+	// 每次建立终端进程都重新检查connect权限，撤销共享后无法再恢复会话
+	connectionInfo, err := h.connService.AuthorizeConnection(session.UserID, session.ConnectionID, model.PermissionConnect)
 	if err != nil {
 		return fmt.Errorf("获取连接信息失败: %w", err)
 	}
 
+	// 解析凭据并准备SSH认证参数（交互式提示转发、短期证书）
+	authOptions, err := h.connService.PrepareSessionAuth(session.UserID, connectionInfo, &sessionAuthPrompter{wsConn: wsConn})
+	if err != nil {
+		return fmt.Errorf("准备会话认证失败: %w", err)
+	}
+
 	// 创建终端会话代理
-	proxy, err := service.NewTerminalSessionProxy(session, connectionInfo, h.sessionManager)
+	proxy, err := service.NewTerminalSessionProxy(session, connectionInfo, h.sessionManager, authOptions)
 	if err != nil {
 		return fmt.Errorf("创建终端会话代理失败: %w", err)
 	}
@@ -361,6 +371,7 @@ func (h *TerminalSessionHandler) handleWebSocketMessages(session *service.Persis
 				var msg struct {
 					Type    string `json:"type"`
 					Content string `json:"content"`
+					Bytes   int    `json:"bytes"` // ack消息确认的输出字节数
 				}
 
 				if err := json.Unmarshal(p, &msg); err != nil {
@@ -396,7 +407,12 @@ func (h *TerminalSessionHandler) handleWebSocketMessages(session *service.Persis
 							"time": fmt.Sprintf("%d", time.Now().Unix()),
 						}
 						if respData, err := json.Marshal(response); err == nil {
-							wsConn.WriteMessage(websocket.TextMessage, respData)
+							session.WriteMessage(wsConn, websocket.TextMessage, respData)
+						}
+					case "ack":
+						// 流控确认，恢复输出信用
+						if session.TerminalProxy != nil {
+							session.TerminalProxy.AckOutput(msg.Bytes)
 						}
 					case "close":
 						// 客户端请求关闭会话
@@ -418,12 +434,14 @@ func (h *TerminalSessionHandler) GetSessionStats(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// 管理员可以查看全局统计，普通用户只能查看自己的
+	// 拥有sessions.monitor权限可以查看全局统计，否则只能查看自己的
 	role, _ := middleware.GetUserRole(r)
+	monitor, _ := h.roleService.HasPermission(role, middleware.GetRoleVersion(r), model.PermSessionsMonitor)
 	
 	var stats map[string]interface{}
-	if role == "admin" {
+	if monitor {
 		stats = h.sessionManager.GetSessionStats()
+		stats["flow_control"] = service.GetFlowControlStats()
 	} else {
 		// 获取用户会话统计
 		userSessions, _ := h.sessionManager.GetUserSessions(userID)
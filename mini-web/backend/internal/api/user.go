@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -197,11 +198,16 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		Nickname: req.Nickname,
 		Role:     req.Role,
 		Status:   req.Status,
+		MustChangePassword: req.MustChangePassword,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
 	err := h.userService.CreateUser(user)
+	if errors.Is(err, service.ErrWeakPassword) {
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusConflict)
@@ -751,13 +757,7 @@ func (h *UserHandler) GetAllActivities(w http.ResponseWriter, r *http.Request) {
 // logUserActivity 记录用户活动（辅助方法）
 func (h *UserHandler) logUserActivity(userID uint, action, resource, details string, r *http.Request) {
 	// 获取IP地址
-	ipAddress := r.Header.Get("X-Forwarded-For")
-	if ipAddress == "" {
-		ipAddress = r.Header.Get("X-Real-IP")
-	}
-	if ipAddress == "" {
-		ipAddress = r.RemoteAddr
-	}
+	ipAddress := getClientIP(r)
 
 	// 获取User Agent
 	userAgent := r.Header.Get("User-Agent")
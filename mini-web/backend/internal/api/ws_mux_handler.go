@@ -0,0 +1,683 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/service"
+	"github.com/gorilla/websocket"
+)
+
+// 多路复用WebSocket（/ws/mux）：一个已认证的连接同时承载多个终端会话和文件管理请求。
+// 消息使用MWEB二进制协议，消息头的通道ID区分通道，0为控制通道（协议协商、心跳）。
+// 客户端选择未使用的通道ID发送ChannelOpen，服务端以ChannelOpen确认或以ChannelClose拒绝；
+// 之后双方在该通道上收发数据，任一方发送ChannelClose关闭通道。每个终端通道独立流控
+const (
+	maxMuxChannels     = 32
+	muxReadTimeout     = 90 * time.Second
+	muxWriteTimeout    = 10 * time.Second
+	muxChannelTerminal = "terminal"
+	muxChannelFiles    = "files"
+	muxTicketSessionID = "mux"
+)
+
+// muxConnection 一个多路复用WebSocket连接
+type muxConnection struct {
+	h           *ConnectionHandler
+	wsConn      *websocket.Conn
+	userID      uint
+	writeMutex  sync.Mutex
+	compression atomic.Uint32 // 协议协商选定的压缩方式
+
+	mutex    sync.Mutex
+	channels map[uint16]*muxChannel
+}
+
+// muxChannel 多路复用连接上的一个通道
+type muxChannel struct {
+	id        uint16
+	kind      string
+	attach    uint16 // files通道关联的终端通道
+	flow      *service.FlowControl
+	input     chan []byte // 终端输入，由单独的协程写入终端，避免阻塞其他通道
+	prompts   chan string // 认证阶段的用户输入
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mutex          sync.Mutex
+	terminal       service.TerminalSession // 终端通道打开后设置
+	commandHandler *service.SSHCommandHandler
+}
+
+// muxResize 终端通道的调整大小消息
+type muxResize struct {
+	Cols int `json:"cols"`
+	Rows int `json:"rows"`
+}
+
+// HandleMultiplexWebSocket 处理多路复用WebSocket连接
+func (h *ConnectionHandler) HandleMultiplexWebSocket(w http.ResponseWriter, r *http.Request) {
+	// 多路复用连接不属于单个会话，票据签发给固定的会话ID mux
+	userID, ok := authenticateWebSocket(r, muxTicketSessionID)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	wsConn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("升级多路复用WebSocket连接失败: %v", err)
+		return
+	}
+	defer wsConn.Close()
+
+	log.Printf("多路复用WebSocket连接建立: 用户ID=%d, 来源=%s", userID, r.RemoteAddr)
+
+	m := &muxConnection{
+		h:        h,
+		wsConn:   wsConn,
+		userID:   userID,
+		channels: make(map[uint16]*muxChannel),
+	}
+	m.serve()
+}
+
+// serve 读取并分发消息，连接断开时关闭所有通道
+func (m *muxConnection) serve() {
+	defer func() {
+		m.mutex.Lock()
+		ids := make([]uint16, 0, len(m.channels))
+		for id := range m.channels {
+			ids = append(ids, id)
+		}
+		m.mutex.Unlock()
+		for _, id := range ids {
+			m.closeChannel(id, "", false)
+		}
+		log.Printf("多路复用WebSocket连接结束: 用户ID=%d", m.userID)
+	}()
+
+	for {
+		m.wsConn.SetReadDeadline(time.Now().Add(muxReadTimeout))
+		messageType, data, err := m.wsConn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("读取多路复用WebSocket消息错误: %v", err)
+			}
+			return
+		}
+		if messageType != websocket.BinaryMessage || !m.h.binaryProtocol.IsProtocolMessage(data) {
+			log.Printf("多路复用连接只接受二进制协议消息，忽略: 类型=%d, 大小=%d", messageType, len(data))
+			continue
+		}
+
+		msg, err := m.h.binaryProtocol.DecodeMessage(data)
+		if err != nil {
+			log.Printf("解析多路复用消息失败: %v", err)
+			continue
+		}
+
+		channelID := msg.Header.ChannelID
+		if channelID == service.ControlChannel {
+			m.handleControl(msg)
+			continue
+		}
+
+		switch msg.Header.MessageType {
+		case service.MessageTypeChannelOpen:
+			m.openChannel(channelID, msg)
+		case service.MessageTypeChannelClose:
+			m.closeChannel(channelID, "", false)
+		default:
+			ch := m.channel(channelID)
+			if ch == nil {
+				m.sendClose(channelID, "通道不存在")
+				continue
+			}
+			m.handleChannelMessage(ch, msg)
+		}
+	}
+}
+
+// send 向通道发送消息，messageType为0时按内容确定类型
+func (m *muxConnection) send(channelID uint16, messageType uint8, jsonData interface{}, binaryData []byte, compression uint8) error {
+	encoded, err := m.h.binaryProtocol.EncodeChannelMessage(channelID, messageType, jsonData, binaryData, compression)
+	if err != nil {
+		return err
+	}
+
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+	m.wsConn.SetWriteDeadline(time.Now().Add(muxWriteTimeout))
+	return m.wsConn.WriteMessage(websocket.BinaryMessage, encoded)
+}
+
+// sendClose 通知客户端通道已关闭或打开失败
+func (m *muxConnection) sendClose(channelID uint16, reason string) {
+	if err := m.send(channelID, service.MessageTypeChannelClose, &service.ChannelClose{Reason: reason}, nil, service.CompressionNone); err != nil {
+		log.Printf("发送通道关闭消息失败: 通道=%d, 错误=%v", channelID, err)
+	}
+}
+
+// handleControl 处理控制通道上的协议协商和心跳
+func (m *muxConnection) handleControl(msg *service.ProtocolMessage) {
+	switch msg.Header.MessageType {
+	case service.MessageTypeHeartbeat:
+		if heartbeat, err := m.h.binaryProtocol.CreateHeartbeatMessage(); err == nil {
+			m.writeMutex.Lock()
+			m.wsConn.SetWriteDeadline(time.Now().Add(muxWriteTimeout))
+			m.wsConn.WriteMessage(websocket.BinaryMessage, heartbeat)
+			m.writeMutex.Unlock()
+		}
+	case service.MessageTypeProtocolNegotiation:
+		var clientNegotiation service.ProtocolNegotiation
+		if err := decodeProtocolJSON(msg.JSONData, &clientNegotiation); err != nil {
+			log.Printf("解析协议协商消息失败: %v", err)
+			return
+		}
+		serverNegotiation, err := m.h.binaryProtocol.HandleProtocolNegotiation(&clientNegotiation)
+		if err != nil {
+			log.Printf("处理协议协商失败: %v", err)
+			return
+		}
+		// 多路复用连接的流控窗口在打开终端通道时分别指定
+		serverNegotiation.FlowControlWindow = 0
+		serverNegotiation.Features = append(serverNegotiation.Features, service.FeatureMultiplex)
+		if err := m.send(service.ControlChannel, service.MessageTypeProtocolNegotiation, serverNegotiation, nil, service.CompressionNone); err != nil {
+			log.Printf("发送协议协商响应失败: %v", err)
+			return
+		}
+		m.compression.Store(uint32(serverNegotiation.Compression))
+	default:
+		log.Printf("控制通道不支持的消息类型: %d", msg.Header.MessageType)
+	}
+}
+
+// channel 获取已打开的通道
+func (m *muxConnection) channel(id uint16) *muxChannel {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.channels[id]
+}
+
+// openChannel 处理客户端的打开通道请求
+func (m *muxConnection) openChannel(id uint16, msg *service.ProtocolMessage) {
+	var req service.ChannelOpenRequest
+	if err := decodeProtocolJSON(msg.JSONData, &req); err != nil {
+		m.sendClose(id, "无效的打开通道请求")
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = muxChannelTerminal
+	}
+
+	ch := &muxChannel{
+		id:      id,
+		kind:    req.Kind,
+		attach:  req.Attach,
+		flow:    service.NewFlowControl(fmt.Sprintf("mux-%d-%d", m.userID, id)),
+		input:   make(chan []byte, 64),
+		prompts: make(chan string, 16),
+		done:    make(chan struct{}),
+	}
+
+	switch req.Kind {
+	case muxChannelTerminal:
+		if err := m.register(ch); err != nil {
+			m.sendClose(id, err.Error())
+			return
+		}
+		// 建立终端可能需要交互式认证，在单独的协程中进行，不阻塞其他通道
+		go m.openTerminal(ch, &req)
+	case muxChannelFiles:
+		terminalChannel := m.channel(req.Attach)
+		if terminalChannel == nil || terminalChannel.kind != muxChannelTerminal {
+			m.sendClose(id, "关联的终端通道不存在")
+			return
+		}
+		terminalChannel.mutex.Lock()
+		terminal := terminalChannel.terminal
+		terminalChannel.mutex.Unlock()
+		sshTerminal, ok := terminal.(*service.SSHTerminalSession)
+		if !ok || sshTerminal.GetCommandHandler() == nil {
+			m.sendClose(id, "仅已连接的SSH终端支持文件管理")
+			return
+		}
+		ch.commandHandler = sshTerminal.GetCommandHandler()
+		if err := m.register(ch); err != nil {
+			m.sendClose(id, err.Error())
+			return
+		}
+		m.send(id, service.MessageTypeChannelOpen, map[string]interface{}{"kind": muxChannelFiles, "attach": req.Attach}, nil, service.CompressionNone)
+		log.Printf("打开文件管理通道: 用户ID=%d, 通道=%d, 终端通道=%d", m.userID, id, req.Attach)
+	default:
+		m.sendClose(id, "不支持的通道类型: "+req.Kind)
+	}
+}
+
+// register 登记新通道
+func (m *muxConnection) register(ch *muxChannel) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.channels[ch.id]; exists {
+		return fmt.Errorf("通道%d已被使用", ch.id)
+	}
+	if len(m.channels) >= maxMuxChannels {
+		return fmt.Errorf("通道数超过上限%d", maxMuxChannels)
+	}
+	m.channels[ch.id] = ch
+	return nil
+}
+
+// openTerminal 校验会话和连接权限后建立终端，成功后确认通道并开始转发输出
+func (m *muxConnection) openTerminal(ch *muxChannel, req *service.ChannelOpenRequest) {
+	terminal, protocol, err := m.createTerminal(ch, req.SessionID)
+	if err != nil {
+		log.Printf("打开终端通道失败: 用户ID=%d, 通道=%d, 会话ID=%d, 错误=%v", m.userID, ch.id, req.SessionID, err)
+		m.closeChannel(ch.id, err.Error(), true)
+		return
+	}
+
+	ch.mutex.Lock()
+	select {
+	case <-ch.done:
+		// 建立过程中通道已被关闭
+		ch.mutex.Unlock()
+		terminal.Close()
+		return
+	default:
+	}
+	ch.terminal = terminal
+	ch.mutex.Unlock()
+
+	ack := map[string]interface{}{"kind": muxChannelTerminal, "sessionId": req.SessionID, "protocol": protocol}
+	if req.FlowControlWindow > 0 {
+		ack["flowControlWindow"] = ch.flow.Enable(int(req.FlowControlWindow))
+	}
+	if err := m.send(ch.id, service.MessageTypeChannelOpen, ack, nil, service.CompressionNone); err != nil {
+		m.closeChannel(ch.id, "", false)
+		return
+	}
+	log.Printf("打开终端通道: 用户ID=%d, 通道=%d, 会话ID=%d, 协议=%s", m.userID, ch.id, req.SessionID, protocol)
+
+	go m.writeInput(ch)
+	m.pumpOutput(ch)
+}
+
+// createTerminal 按会话建立终端，与/ws/{protocol}/{sessionId}使用相同的权限检查
+func (m *muxConnection) createTerminal(ch *muxChannel, sessionID uint) (service.TerminalSession, string, error) {
+	connService := m.h.connService
+	session, err := connService.GetSessionByID(m.userID, sessionID)
+	if err != nil {
+		return nil, "", errors.New("会话不存在或已结束")
+	}
+
+	connection, err := connService.AuthorizeConnection(m.userID, session.ConnectionID, model.PermissionConnect)
+	if err != nil {
+		if errors.Is(err, service.ErrConnectionForbidden) {
+			return nil, "", err
+		}
+		return nil, "", errors.New("连接信息不存在")
+	}
+
+	// 图形协议的代理需要独占WebSocket，仍使用单独的连接
+	if connection.Protocol == model.ProtocolRDP || connection.Protocol == model.ProtocolVNC {
+		return nil, "", fmt.Errorf("%s连接不支持多路复用", connection.Protocol)
+	}
+
+	authOptions, err := connService.PrepareSessionAuth(m.userID, connection, &muxAuthPrompter{m: m, ch: ch})
+	if err != nil {
+		return nil, "", fmt.Errorf("准备会话认证失败: %w", err)
+	}
+	terminal, err := connService.CreateTerminalSessionWithAuth(connection.Protocol, connection, authOptions)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建终端会话失败: %w", err)
+	}
+	return terminal, connection.Protocol, nil
+}
+
+// pumpOutput 把终端输出转发到通道，流控信用耗尽时暂停读取终端
+func (m *muxConnection) pumpOutput(ch *muxChannel) {
+	buf := make([]byte, 64*1024)
+	for {
+		limit, ok := ch.flow.Acquire(len(buf), ch.done)
+		if !ok {
+			return
+		}
+
+		n, err := ch.terminal.Read(buf[:limit])
+		if n > 0 {
+			metadata := map[string]interface{}{
+				"type": "terminal-output",
+				"size": n,
+			}
+			if special := m.h.specialDetector.DetectSpecialCommand(string(buf[:n])); special.Type != service.SpecialCommandNormal {
+				metadata["special"] = map[string]interface{}{
+					"type":        string(special.Type),
+					"prompt":      special.Prompt,
+					"masked":      special.Masked,
+					"expectInput": special.ExpectInput,
+					"timeout":     special.Timeout,
+					"description": special.Description,
+				}
+			}
+			if err := m.send(ch.id, 0, metadata, buf[:n], uint8(m.compression.Load())); err != nil {
+				log.Printf("发送终端输出失败: 通道=%d, 错误=%v", ch.id, err)
+				m.closeChannel(ch.id, "", false)
+				return
+			}
+			ch.flow.Consume(n)
+		}
+		if err != nil {
+			m.closeChannel(ch.id, "终端会话已结束", true)
+			return
+		}
+	}
+}
+
+// writeInput 把通道输入写入终端
+func (m *muxConnection) writeInput(ch *muxChannel) {
+	for {
+		select {
+		case <-ch.done:
+			return
+		case data := <-ch.input:
+			if _, err := ch.terminal.Write(data); err != nil {
+				log.Printf("写入终端失败: 通道=%d, 错误=%v", ch.id, err)
+				m.closeChannel(ch.id, "写入终端失败", true)
+				return
+			}
+		}
+	}
+}
+
+// closeChannel 关闭通道及其终端，关联的文件管理通道一并关闭。notify为true时通知客户端
+func (m *muxConnection) closeChannel(id uint16, reason string, notify bool) {
+	m.mutex.Lock()
+	ch := m.channels[id]
+	delete(m.channels, id)
+	attached := make([]uint16, 0)
+	if ch != nil && ch.kind == muxChannelTerminal {
+		for otherID, other := range m.channels {
+			if other.kind == muxChannelFiles && other.attach == id {
+				attached = append(attached, otherID)
+			}
+		}
+	}
+	m.mutex.Unlock()
+
+	if ch == nil {
+		return
+	}
+	ch.closeOnce.Do(func() {
+		ch.mutex.Lock()
+		close(ch.done)
+		terminal := ch.terminal
+		ch.mutex.Unlock()
+
+		if terminal != nil {
+			terminal.Close()
+		}
+		ch.flow.Release()
+		log.Printf("关闭多路复用通道: 用户ID=%d, 通道=%d, 原因=%s", m.userID, id, reason)
+	})
+	if notify {
+		m.sendClose(id, reason)
+	}
+
+	for _, otherID := range attached {
+		m.closeChannel(otherID, "关联的终端通道已关闭", true)
+	}
+}
+
+// handleChannelMessage 处理数据通道上的消息
+func (m *muxConnection) handleChannelMessage(ch *muxChannel, msg *service.ProtocolMessage) {
+	if msg.Header.MessageType == service.MessageTypeFlowControl {
+		var ack service.FlowControlAck
+		if err := decodeProtocolJSON(msg.JSONData, &ack); err == nil {
+			ch.flow.Ack(int(ack.Bytes))
+		}
+		return
+	}
+
+	if ch.kind == muxChannelFiles {
+		var request muxFileRequest
+		if err := decodeProtocolJSON(msg.JSONData, &request); err != nil || request.Type == "" {
+			log.Printf("无效的文件管理请求: 通道=%d", ch.id)
+			return
+		}
+		go m.handleFileRequest(ch, &request, msg.BinaryData)
+		return
+	}
+
+	var command struct {
+		Type    string `json:"type"`
+		Content string `json:"content"`
+		muxResize
+		terminalSnippetRequest
+	}
+	if msg.JSONData != nil {
+		if err := decodeProtocolJSON(msg.JSONData, &command); err != nil {
+			log.Printf("解析终端通道消息失败: 通道=%d, 错误=%v", ch.id, err)
+			return
+		}
+	}
+
+	ch.mutex.Lock()
+	terminal := ch.terminal
+	ch.mutex.Unlock()
+
+	var data []byte
+	switch {
+	case command.Type == "resize":
+		if terminal != nil && command.Cols > 0 && command.Rows > 0 {
+			terminal.WindowResize(uint16(command.Rows), uint16(command.Cols))
+		}
+		return
+	case command.Type == "command":
+		data = []byte(command.Content)
+	case command.Type == "snippet":
+		// 命令片段只能在终端建立后使用，不能用来回答认证提示
+		var err error
+		if terminal == nil {
+			err = errors.New("终端尚未就绪")
+		} else {
+			data, err = m.h.expandTerminalSnippet(m.userID, &command.terminalSnippetRequest)
+		}
+		if err != nil {
+			log.Printf("处理命令片段请求失败: 通道=%d, 片段ID=%d, 错误=%v", ch.id, command.ID, err)
+		}
+		if sendErr := m.send(ch.id, 0, terminalSnippetResponse(&command.terminalSnippetRequest, err), nil, service.CompressionNone); sendErr != nil {
+			log.Printf("发送命令片段响应失败: 通道=%d, 错误=%v", ch.id, sendErr)
+		}
+		if err != nil {
+			return
+		}
+	case msg.BinaryData != nil:
+		data = msg.BinaryData
+	default:
+		return
+	}
+
+	// 终端建立前的输入用于回答认证提示
+	if terminal == nil {
+		select {
+		case ch.prompts <- string(data):
+		default:
+		}
+		return
+	}
+
+	select {
+	case ch.input <- data:
+	case <-ch.done:
+	}
+}
+
+// muxAuthPrompter 通过终端通道转发SSH认证提示
+type muxAuthPrompter struct {
+	m  *muxConnection
+	ch *muxChannel
+}
+
+// Prompt 以带特殊命令信息的终端输出发送提示，并从通道读取用户输入的一行
+func (p *muxAuthPrompter) Prompt(info *service.SpecialCommandInfo) (string, error) {
+	text := info.Prompt
+	if info.Context != "" {
+		text = info.Context + "\r\n" + text
+	}
+	metadata := map[string]interface{}{
+		"type": "terminal-output",
+		"auth": true,
+		"special": map[string]interface{}{
+			"type":        string(info.Type),
+			"prompt":      info.Prompt,
+			"masked":      info.Masked,
+			"expectInput": info.ExpectInput,
+			"timeout":     info.Timeout,
+			"description": info.Description,
+		},
+	}
+	if err := p.m.send(p.ch.id, 0, metadata, []byte("\r\n"+text), service.CompressionNone); err != nil {
+		return "", fmt.Errorf("发送认证提示失败: %w", err)
+	}
+
+	timeout := time.NewTimer(time.Duration(info.Timeout) * time.Second)
+	defer timeout.Stop()
+	var input []rune
+	for {
+		select {
+		case chunk := <-p.ch.prompts:
+			var complete bool
+			var err error
+			input, complete, err = editPromptInput(input, chunk)
+			if err != nil {
+				return "", err
+			}
+			if complete {
+				p.m.send(p.ch.id, 0, map[string]interface{}{"type": "terminal-output"}, []byte("\r\n"), service.CompressionNone)
+				return string(input), nil
+			}
+		case <-timeout.C:
+			return "", errors.New("等待认证输入超时")
+		case <-p.ch.done:
+			return "", errors.New("通道已关闭")
+		}
+	}
+}
+
+// muxFileRequest 文件管理通道上的请求，data为各操作的参数，与终端WebSocket上的文件管理请求一致。
+// 上传时文件分片放在消息的二进制部分，不再需要base64编码
+type muxFileRequest struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"requestId"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// handleFileRequest 执行文件管理请求，响应类型为请求类型加_response
+func (m *muxConnection) handleFileRequest(ch *muxChannel, request *muxFileRequest, binaryData []byte) {
+	result, err := executeMuxFileRequest(ch.commandHandler, request, binaryData)
+
+	response := map[string]interface{}{
+		"requestId": request.RequestID,
+		"success":   err == nil,
+	}
+	if err != nil {
+		log.Printf("文件管理请求失败: 通道=%d, 类型=%s, 错误=%v", ch.id, request.Type, err)
+		response["error"] = err.Error()
+	}
+	for key, value := range result {
+		response[key] = value
+	}
+
+	message := map[string]interface{}{"type": request.Type + "_response", "data": response}
+	if err := m.send(ch.id, 0, message, nil, service.CompressionNone); err != nil {
+		log.Printf("发送文件管理响应失败: 通道=%d, 错误=%v", ch.id, err)
+	}
+}
+
+// executeMuxFileRequest 通过SSH命令处理器执行文件管理操作
+func executeMuxFileRequest(handler *service.SSHCommandHandler, request *muxFileRequest, binaryData []byte) (map[string]interface{}, error) {
+	var params struct {
+		Path        string `json:"path"`
+		Content     string `json:"content"`
+		Encoding    string `json:"encoding"`
+		FileType    string `json:"fileType"`
+		MaxSize     int64  `json:"maxSize"`
+		IsDirectory bool   `json:"isDirectory"`
+		OldPath     string `json:"oldPath"`
+		NewPath     string `json:"newPath"`
+		Permissions string `json:"permissions"`
+		FileName    string `json:"fileName"`
+		TotalSize   int64  `json:"totalSize"`
+		ChunkIndex  int    `json:"chunkIndex"`
+		TotalChunks int    `json:"totalChunks"`
+	}
+	if len(request.Data) > 0 {
+		if err := json.Unmarshal(request.Data, &params); err != nil {
+			return nil, errors.New("请求数据格式错误")
+		}
+	}
+
+	switch request.Type {
+	case "file_list":
+		resp, err := handler.ExecuteFileListCommand(params.Path)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"path": resp.Path, "files": resp.Files}, nil
+	case "file_view":
+		resp, err := handler.ExecuteFileViewCommand(params.Path, params.FileType, params.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"path": params.Path, "fileType": resp.FileType, "content": resp.Content,
+			"encoding": resp.Encoding, "mimeType": resp.MimeType}, nil
+	case "file_save":
+		return nil, handler.ExecuteFileSaveCommand(params.Path, params.Content, params.Encoding)
+	case "file_create":
+		return nil, handler.ExecuteFileCreateCommand(params.Path, params.Content)
+	case "folder_create":
+		return nil, handler.ExecuteFolderCreateCommand(params.Path)
+	case "file_delete":
+		return nil, handler.ExecuteFileDeleteCommand(params.Path, params.IsDirectory)
+	case "file_rename":
+		return nil, handler.ExecuteFileRenameCommand(params.OldPath, params.NewPath)
+	case "file_permissions":
+		return nil, handler.ExecuteFilePermissionsCommand(params.Path, params.Permissions)
+	case "file_upload":
+		content := binaryData
+		if content == nil && params.Content != "" {
+			decoded, err := base64.StdEncoding.DecodeString(params.Content)
+			if err != nil {
+				return nil, errors.New("文件内容解码失败")
+			}
+			content = decoded
+		}
+		err := handler.ExecuteFileUploadCommand(params.Path, content, params.FileName, params.TotalSize, params.ChunkIndex, params.TotalChunks)
+		return map[string]interface{}{"chunkIndex": params.ChunkIndex, "totalChunks": params.TotalChunks}, err
+	default:
+		return nil, fmt.Errorf("不支持的文件管理请求: %s", request.Type)
+	}
+}
+
+// decodeProtocolJSON 把二进制协议消息中解析出的JSON数据转换为结构体
+func decodeProtocolJSON(data interface{}, v interface{}) error {
+	if data == nil {
+		return errors.New("缺少JSON数据")
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
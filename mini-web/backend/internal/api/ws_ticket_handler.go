@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/middleware"
+	"gitee.com/await29/mini-web/internal/service"
+)
+
+// WSTicketHandler WebSocket连接票据处理器
+type WSTicketHandler struct {
+	tickets *service.WSTicketStore
+}
+
+// NewWSTicketHandler 创建WebSocket连接票据处理器
+func NewWSTicketHandler(tickets *service.WSTicketStore) *WSTicketHandler {
+	return &WSTicketHandler{tickets: tickets}
+}
+
+// wsTicketRequest 申请票据的请求
+type wsTicketRequest struct {
+	SessionID string `json:"session_id"` // 要连接的会话ID，多路复用连接使用mux
+}
+
+// IssueTicket 为当前用户签发连接指定会话的一次性票据
+func (h *WSTicketHandler) IssueTicket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "未授权访问")
+		return
+	}
+
+	var req wsTicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	req.SessionID = strings.TrimSpace(req.SessionID)
+	if req.SessionID == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "会话ID不能为空")
+		return
+	}
+
+	ticket, expiresAt, err := h.tickets.Issue(userID, req.SessionID, getClientIP(r))
+	if err != nil {
+		log.Printf("签发WebSocket票据失败: 用户ID=%d, 错误=%v", userID, err)
+		sendErrorResponse(w, http.StatusInternalServerError, "签发票据失败")
+		return
+	}
+
+	sendSuccessResponse(w, "签发票据成功", map[string]interface{}{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+		"expires_in": int(time.Until(expiresAt).Seconds()),
+	})
+}
+
+// authenticateWebSocket 使用URL中的ticket参数认证WebSocket连接，票据必须签发给该会话和客户端地址
+func authenticateWebSocket(r *http.Request, sessionID string) (uint, bool) {
+	userID, err := service.GetWSTicketStore().Redeem(r.URL.Query().Get("ticket"), sessionID, getClientIP(r))
+	if err != nil {
+		if errors.Is(err, service.ErrWSTicketMismatch) {
+			log.Printf("WebSocket票据不匹配: 会话ID=%s, 来源=%s", sessionID, getClientIP(r))
+		}
+		return 0, false
+	}
+	return userID, true
+}
+
+var (
+	wsOriginsMutex  sync.RWMutex
+	wsAllowedOrigin = map[string]bool{}
+	wsAnyOrigin     bool
+)
+
+// ConfigureWebSocketOrigins 设置允许发起WebSocket连接的页面来源，同源请求始终允许，*表示不限制
+func ConfigureWebSocketOrigins(origins []string) {
+	allowed := make(map[string]bool, len(origins))
+	anyOrigin := false
+	for _, origin := range origins {
+		origin = strings.TrimRight(strings.ToLower(strings.TrimSpace(origin)), "/")
+		if origin == "*" {
+			anyOrigin = true
+		} else if origin != "" {
+			allowed[origin] = true
+		}
+	}
+
+	wsOriginsMutex.Lock()
+	wsAllowedOrigin = allowed
+	wsAnyOrigin = anyOrigin
+	wsOriginsMutex.Unlock()
+
+	if anyOrigin {
+		log.Printf("警告: WebSocket允许任意来源连接")
+	}
+}
+
+// nonBrowserClientHeader 非浏览器客户端声明身份的请求头，浏览器的WebSocket API无法设置自定义请求头
+const nonBrowserClientHeader = "X-Mini-Web-Client"
+
+// checkWebSocketOrigin 校验WebSocket握手的Origin。
+// 缺少Origin的请求只有显式声明为非浏览器客户端时才放行，仍需票据认证
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		if r.Header.Get(nonBrowserClientHeader) != "" {
+			return true
+		}
+		log.Printf("拒绝缺少Origin的WebSocket连接: Host=%s", r.Host)
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	wsOriginsMutex.RLock()
+	defer wsOriginsMutex.RUnlock()
+	if wsAnyOrigin || wsAllowedOrigin[strings.ToLower(u.Scheme+"://"+u.Host)] {
+		return true
+	}
+	log.Printf("拒绝来源不在白名单中的WebSocket连接: Origin=%s, Host=%s", origin, r.Host)
+	return false
+}
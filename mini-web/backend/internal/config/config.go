@@ -4,19 +4,30 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	SSHCA     SSHCAConfig
+	SSLCA     SSLCAConfig
+	ACME      ACMEConfig
+	Security  SecurityConfig
+	LDAP      LDAPConfig
+	WebSocket WebSocketConfig
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port int
-	Host string
+	Port             int
+	Host             string
+	TLSEnabled       bool // 使用ssl_configs中已启用的证书提供HTTPS
+	HTTPRedirectPort int  // 大于0时在该端口监听HTTP并重定向到HTTPS
+	// TrustedProxies 可信反向代理的IP或CIDR，只有来自这些地址的请求才采信X-Forwarded-For
+	TrustedProxies []string
 }
 
 // DatabaseConfig 数据库配置
@@ -27,24 +38,145 @@ type DatabaseConfig struct {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret     string
-	ExpireHour int
+	Algorithm          string   // 签名算法：EdDSA、RS256或HS256
+	Secret             string   // HS256密钥，至少32字节，为空时使用KeyPath中的密钥
+	KeyPath            string   // 当前签名密钥文件路径，不存在时自动生成
+	VerifyKeyPaths     []string // 轮换前的旧密钥文件，仅用于验证尚未过期的令牌
+	ExpireHour         int      // 登录会话（刷新令牌）有效期（小时）
+	AccessExpireMinute int      // 访问令牌有效期（分钟）
+}
+
+// SSHCAConfig SSH证书颁发机构配置
+type SSHCAConfig struct {
+	KeyPath    string // CA私钥文件路径，不存在时自动生成
+	CertTTL    int    // 用户证书有效期（分钟）
+	RolePrefix string // 角色principal前缀，如 role-admin
+	// RolePrincipals 管理员配置的角色到目标主机登录名的映射，证书principals只来自这里，
+	// 连接上的用户名和principals无法扩大签发范围
+	RolePrincipals map[string][]string
+}
+
+// SSLCAConfig 内部证书颁发机构配置，用于为内网主机签发HTTPS证书
+type SSLCAConfig struct {
+	CertPath   string // CA证书文件路径
+	KeyPath    string // CA私钥文件路径，与证书均不存在时自动生成
+	CommonName string // 自动生成CA时使用的名称
+}
+
+// ACMEConfig ACME自动证书配置
+type ACMEConfig struct {
+	DirectoryURL       string // ACME目录地址，默认Let's Encrypt正式环境
+	Email              string // 账号联系邮箱，用于接收CA的到期提醒
+	AccountKeyPath     string // 账号私钥文件路径，不存在时自动生成
+	CACertPath         string // 自定义CA证书文件，用于信任测试环境ACME服务器的HTTPS证书
+	RenewIntervalHours int    // 检查到期证书的间隔（小时）
+}
+
+// SecurityConfig 安全相关配置
+type SecurityConfig struct {
+	SecretKeyPath string // 数据库敏感字段加密主密钥文件路径，不存在时自动生成
+}
+
+// WebSocketConfig WebSocket连接安全配置
+type WebSocketConfig struct {
+	AllowedOrigins  []string // 允许发起WebSocket连接的页面来源，如 https://web.example.com，同源请求始终允许，*表示不限制
+	TicketTTLSecond int      // 连接票据有效期（秒）
+}
+
+// LDAPConfig LDAP/AD认证配置
+type LDAPConfig struct {
+	Enabled            bool
+	URL                string // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool   // 在ldap://连接上升级为TLS
+	InsecureSkipVerify bool   // 跳过服务器证书校验，仅用于测试环境
+	CACertPath         string // 自定义CA证书文件
+	BindDN             string // 服务账号DN
+	BindPassword       string
+	BaseDN             string // 用户搜索起点
+	UserFilter         string // 用户搜索过滤器，{username}替换为登录名
+	EmailAttribute     string
+	NameAttribute      string
+	GroupAttribute     string // 用户条目上的组属性，如AD的memberOf
+	GroupBaseDN        string // 设置后额外按GroupFilter搜索组，用于没有memberOf的目录
+	GroupFilter        string // 组搜索过滤器，{dn}替换为用户DN，{username}替换为登录名
+	GroupRoleMap       []GroupMapping
+	GroupTeamMap       []GroupMapping
+	DefaultRole        string // 未匹配任何组时新用户的角色
+	TimeoutSecond      int
+}
+
+// GroupMapping 目录组到mini-web角色或团队的映射
+type GroupMapping struct {
+	Group  string // 组DN或CN，不区分大小写
+	Target string // 角色名或团队名
 }
 
 // LoadConfig 加载配置
 func LoadConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnvAsInt("SERVER_PORT", 8080),
-			Host: getEnv("SERVER_HOST", "localhost"),
+			Port:             getEnvAsInt("SERVER_PORT", 8080),
+			Host:             getEnv("SERVER_HOST", "localhost"),
+			TLSEnabled:       getEnvAsBool("SERVER_TLS_ENABLED", false),
+			HTTPRedirectPort: getEnvAsInt("SERVER_HTTP_REDIRECT_PORT", 0),
+			TrustedProxies:   getEnvAsListDefault("SERVER_TRUSTED_PROXIES", []string{"127.0.0.1/8", "::1/128"}),
 		},
 		Database: DatabaseConfig{
 			Type: getEnv("DB_TYPE", "sqlite"),
 			Path: getEnv("DB_PATH", "./data/mini-web.db"),
 		},
 		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "mini-web-secret-key"),
-			ExpireHour: getEnvAsInt("JWT_EXPIRE_HOUR", 24),
+			Algorithm:          getEnv("JWT_ALGORITHM", "EdDSA"),
+			Secret:             getEnv("JWT_SECRET", ""),
+			KeyPath:            getEnv("JWT_KEY_PATH", "./data/jwt_signing.key"),
+			VerifyKeyPaths:     getEnvAsList("JWT_VERIFY_KEY_PATHS"),
+			ExpireHour:         getEnvAsInt("JWT_EXPIRE_HOUR", 24),
+			AccessExpireMinute: getEnvAsInt("JWT_ACCESS_EXPIRE_MINUTE", 15),
+		},
+		SSHCA: SSHCAConfig{
+			KeyPath:        getEnv("SSH_CA_KEY_PATH", "./data/ssh_ca_key"),
+			CertTTL:        getEnvAsInt("SSH_CA_CERT_TTL_MINUTES", 5),
+			RolePrefix:     getEnv("SSH_CA_ROLE_PREFIX", "role-"),
+			RolePrincipals: getEnvAsRolePrincipals("SSH_CA_ROLE_PRINCIPALS"),
+		},
+		SSLCA: SSLCAConfig{
+			CertPath:   getEnv("SSL_CA_CERT_PATH", "./data/ssl_ca.crt"),
+			KeyPath:    getEnv("SSL_CA_KEY_PATH", "./data/ssl_ca.key"),
+			CommonName: getEnv("SSL_CA_COMMON_NAME", "Mini-Web Internal CA"),
+		},
+		ACME: ACMEConfig{
+			DirectoryURL:       getEnv("ACME_DIRECTORY_URL", "https://acme-v02.api.letsencrypt.org/directory"),
+			Email:              getEnv("ACME_EMAIL", ""),
+			AccountKeyPath:     getEnv("ACME_ACCOUNT_KEY_PATH", "./data/acme_account.key"),
+			CACertPath:         getEnv("ACME_CA_CERT", ""),
+			RenewIntervalHours: getEnvAsInt("ACME_RENEW_INTERVAL_HOURS", 12),
+		},
+		Security: SecurityConfig{
+			SecretKeyPath: getEnv("SECRET_KEY_PATH", "./data/secret.key"),
+		},
+		LDAP: LDAPConfig{
+			Enabled:            getEnvAsBool("LDAP_ENABLED", false),
+			URL:                getEnv("LDAP_URL", ""),
+			StartTLS:           getEnvAsBool("LDAP_START_TLS", false),
+			InsecureSkipVerify: getEnvAsBool("LDAP_INSECURE_SKIP_VERIFY", false),
+			CACertPath:         getEnv("LDAP_CA_CERT", ""),
+			BindDN:             getEnv("LDAP_BIND_DN", ""),
+			BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+			BaseDN:             getEnv("LDAP_BASE_DN", ""),
+			UserFilter:         getEnv("LDAP_USER_FILTER", "(&(objectClass=person)(|(sAMAccountName={username})(uid={username})))"),
+			EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			NameAttribute:      getEnv("LDAP_NAME_ATTRIBUTE", "displayName"),
+			GroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			GroupBaseDN:        getEnv("LDAP_GROUP_BASE_DN", ""),
+			GroupFilter:        getEnv("LDAP_GROUP_FILTER", "(|(member={dn})(uniqueMember={dn})(memberUid={username}))"),
+			GroupRoleMap:       getEnvAsGroupMappings("LDAP_GROUP_ROLE_MAP"),
+			GroupTeamMap:       getEnvAsGroupMappings("LDAP_GROUP_TEAM_MAP"),
+			DefaultRole:        getEnv("LDAP_DEFAULT_ROLE", "user"),
+			TimeoutSecond:      getEnvAsInt("LDAP_TIMEOUT_SECOND", 10),
+		},
+		WebSocket: WebSocketConfig{
+			AllowedOrigins:  getEnvAsList("WS_ALLOWED_ORIGINS"),
+			TicketTTLSecond: getEnvAsInt("WS_TICKET_TTL_SECONDS", 30),
 		},
 	}
 }
@@ -72,4 +204,82 @@ func getEnvAsInt(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvAsList 获取逗号分隔的环境变量列表，不存在时返回nil
+func getEnvAsList(key string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return nil
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsListDefault 获取逗号分隔的环境变量列表，不存在时返回默认值
+func getEnvAsListDefault(key string, defaultValue []string) []string {
+	if _, exists := os.LookupEnv(key); !exists {
+		return defaultValue
+	}
+	return getEnvAsList(key)
+}
+
+// getEnvAsBool 获取布尔类型的环境变量，如果不存在或转换失败则返回默认值
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsGroupMappings 解析组映射环境变量
+func getEnvAsGroupMappings(key string) []GroupMapping {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return nil
+	}
+	return ParseGroupMappings(value)
+}
+
+// getEnvAsRolePrincipals 解析角色principal映射环境变量，格式为 "角色=>登录名,登录名;角色=>登录名"
+func getEnvAsRolePrincipals(key string) map[string][]string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return nil
+	}
+
+	rolePrincipals := make(map[string][]string)
+	for _, mapping := range ParseGroupMappings(value) {
+		for _, principal := range strings.Split(mapping.Target, ",") {
+			if principal = strings.TrimSpace(principal); principal != "" {
+				rolePrincipals[mapping.Group] = append(rolePrincipals[mapping.Group], principal)
+			}
+		}
+	}
+	return rolePrincipals
+}
+
+// ParseGroupMappings 解析组映射，格式为 "组=>目标;组=>目标"。
+// 组DN本身包含逗号和等号，因此使用分号和=>分隔
+func ParseGroupMappings(value string) []GroupMapping {
+	var mappings []GroupMapping
+	for _, item := range strings.Split(value, ";") {
+		parts := strings.SplitN(item, "=>", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		group, target := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if group != "" && target != "" {
+			mappings = append(mappings, GroupMapping{Group: group, Target: target})
+		}
+	}
+	return mappings
+}
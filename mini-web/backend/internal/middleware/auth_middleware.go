@@ -14,14 +14,27 @@ type userIDKey struct{}
 // RoleKey 上下文中用户角色的键
 type roleKey struct{}
 
+// roleVersionKey 上下文中令牌角色版本的键
+type roleVersionKey struct{}
+
+// claimsKey 上下文中令牌声明的键
+type claimsKey struct{}
+
+// passwordChangeExemptPaths 必须修改密码时仍允许访问的接口
+var passwordChangeExemptPaths = map[string]bool{
+	"/api/user/password": true,
+	"/api/auth/logout":   true,
+}
+
 // AuthMiddleware 认证中间件
 type AuthMiddleware struct {
 	authService *service.AuthService
+	roleService *service.RoleService
 }
 
 // NewAuthMiddleware 创建认证中间件
-func NewAuthMiddleware(authService *service.AuthService) *AuthMiddleware {
-	return &AuthMiddleware{authService: authService}
+func NewAuthMiddleware(authService *service.AuthService, roleService *service.RoleService) *AuthMiddleware {
+	return &AuthMiddleware{authService: authService, roleService: roleService}
 }
 
 // JWTAuth JWT认证中间件
@@ -50,9 +63,17 @@ func (m *AuthMiddleware) JWTAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// 需要修改密码的用户只能访问修改密码和退出登录
+		if claims.MustChangePassword && !passwordChangeExemptPaths[r.URL.Path] {
+			sendForbiddenError(w, service.ErrPasswordChangeRequired.Error())
+			return
+		}
+
 		// 将用户信息添加到请求上下文
 		ctx := context.WithValue(r.Context(), userIDKey{}, claims.UserID)
 		ctx = context.WithValue(ctx, roleKey{}, claims.Role)
+		ctx = context.WithValue(ctx, roleVersionKey{}, claims.RoleVersion)
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
 
 		// 使用更新后的上下文继续处理请求
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -90,6 +111,41 @@ func (m *AuthMiddleware) RoleAuth(roles ...string) func(http.Handler) http.Handl
 	}
 }
 
+// RequirePermission 权限检查中间件，要求用户角色拥有全部指定权限。
+// 权限按角色当前配置判定，角色修改后无需等待令牌过期即可生效
+func (m *AuthMiddleware) RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := GetUserRole(r)
+			if !ok {
+				sendAuthError(w, "无法获取用户角色")
+				return
+			}
+			version := GetRoleVersion(r)
+
+			for _, permission := range permissions {
+				allowed, err := m.roleService.HasPermission(role, version, permission)
+				if err != nil {
+					sendForbiddenError(w, "权限检查失败")
+					return
+				}
+				if !allowed {
+					sendForbiddenError(w, "权限不足: 需要"+permission)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetTokenClaims 从请求上下文中获取当前访问令牌的声明
+func GetTokenClaims(r *http.Request) (*service.TokenClaims, bool) {
+	claims, ok := r.Context().Value(claimsKey{}).(*service.TokenClaims)
+	return claims, ok
+}
+
 // GetUserID 从请求上下文中获取用户ID
 func GetUserID(r *http.Request) (uint, bool) {
 	userID, ok := r.Context().Value(userIDKey{}).(uint)
@@ -102,9 +158,22 @@ func GetUserRole(r *http.Request) (string, bool) {
 	return role, ok
 }
 
+// GetRoleVersion 从请求上下文中获取令牌的角色版本
+func GetRoleVersion(r *http.Request) int {
+	version, _ := r.Context().Value(roleVersionKey{}).(int)
+	return version
+}
+
 // sendAuthError 发送认证错误响应
 func sendAuthError(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
 	w.Write([]byte(`{"code":401,"message":"` + message + `"}`))
-}
\ No newline at end of file
+}
+
+// sendForbiddenError 发送权限不足响应
+func sendForbiddenError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(`{"code":403,"message":"` + message + `"}`))
+}
@@ -16,31 +16,11 @@ func CORSMiddleware(next http.Handler) http.Handler {
 			origin = "*"
 		}
 		
-		// 记录CORS请求日志 - 增加查询参数
-		log.Printf("收到请求: %s %s%s, Origin: %s", r.Method, r.URL.Path, r.URL.RawQuery, origin)
+		// 记录请求日志，查询参数中可能带有WebSocket票据，不写入日志
+		log.Printf("收到请求: %s %s, Origin: %s", r.Method, r.URL.Path, origin)
 		
 		// 判断是否为WebSocket请求
-		isWebSocket := false
-		if strings.ToLower(r.Header.Get("Upgrade")) == "websocket" {
-			isWebSocket = true
-			log.Printf("检测到WebSocket请求: %s%s", r.URL.Path, r.URL.RawQuery)
-			
-			// 打印详细的WebSocket请求信息
-			if strings.HasPrefix(r.URL.Path, "/ws/") {
-				log.Printf("================================")
-				log.Printf("WebSocket详细信息:")
-				log.Printf("完整URL: %s", r.URL.String())
-				log.Printf("Query参数: %s", r.URL.RawQuery)
-				log.Printf("Token: %s", r.URL.Query().Get("token"))
-				log.Printf("请求头:")
-				for name, values := range r.Header {
-					for _, value := range values {
-						log.Printf("  %s: %s", name, value)
-					}
-				}
-				log.Printf("================================")
-			}
-		}
+		isWebSocket := strings.ToLower(r.Header.Get("Upgrade")) == "websocket"
 		
 		// 设置跨域头 - 为WebSocket请求特别处理
 		if isWebSocket {
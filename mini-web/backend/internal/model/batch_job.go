@@ -0,0 +1,128 @@
+package model
+
+import "time"
+
+// 批量任务状态
+const (
+	BatchJobStatusPending   = "pending"
+	BatchJobStatusRunning   = "running"
+	BatchJobStatusCompleted = "completed" // 全部主机执行成功
+	BatchJobStatusFailed    = "failed"    // 至少一台主机失败
+	BatchJobStatusCancelled = "cancelled"
+)
+
+// 单台主机的执行状态
+const (
+	BatchHostStatusPending   = "pending"
+	BatchHostStatusRunning   = "running"
+	BatchHostStatusSucceeded = "succeeded" // 退出码为0
+	BatchHostStatusFailed    = "failed"    // 退出码非0或连接失败
+	BatchHostStatusTimeout   = "timeout"
+	BatchHostStatusCancelled = "cancelled"
+)
+
+// 批量任务实时事件类型
+const (
+	BatchEventHostStart = "host_start" // 开始在主机上执行
+	BatchEventOutput    = "output"     // 主机输出，Stream为stdout或stderr
+	BatchEventHostDone  = "host_done"  // 主机执行结束
+	BatchEventJobDone   = "job_done"   // 任务结束
+)
+
+// BatchJob 在多台主机上执行同一命令或脚本的任务
+type BatchJob struct {
+	ID             uint       `json:"id"`
+	UserID         uint       `json:"user_id"`
+	Name           string     `json:"name"`
+	Command        string     `json:"command"`              // 单行命令，与Script二选一
+	Script         string     `json:"script"`               // 多行脚本，由/bin/sh执行
+	Sudo           bool       `json:"sudo"`                 // 是否通过sudo执行
+	SudoUser       string     `json:"sudo_user"`            // sudo目标用户，为空表示root
+	Parallelism    int        `json:"parallelism"`          // 同时执行的主机数
+	TimeoutSeconds int        `json:"timeout_seconds"`      // 单台主机的超时时间
+	SourceJobID    uint       `json:"source_job_id"`        // 重新执行时的原任务ID
+	ScheduledJobID uint       `json:"scheduled_job_id"`     // 由定时任务触发时的定时任务ID
+	CredentialID   uint       `json:"credential_id"`        // 使用的服务账号凭据，为0时使用创建者的连接认证信息
+	Attempt        int        `json:"attempt"`              // 定时任务的第几次尝试，首次执行为1，手动任务为0
+	Status         string     `json:"status"`               // 任务状态
+	TotalHosts     int        `json:"total_hosts"`          // 目标主机数
+	SucceededHosts int        `json:"succeeded_hosts"`      // 执行成功的主机数
+	FailedHosts    int        `json:"failed_hosts"`         // 失败、超时或取消的主机数
+	StartedAt      *time.Time `json:"started_at,omitempty"` // 开始执行时间
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// BatchJobHost 任务在单台主机上的执行结果
+type BatchJobHost struct {
+	ID           uint       `json:"id"`
+	JobID        uint       `json:"job_id"`
+	ConnectionID uint       `json:"connection_id"`
+	Name         string     `json:"name"` // 执行时的连接名称
+	Host         string     `json:"host"`
+	Status       string     `json:"status"`
+	ExitCode     *int       `json:"exit_code,omitempty"` // 远程命令的退出码，未取得时为空
+	Stdout       string     `json:"stdout"`
+	Stderr       string     `json:"stderr"`
+	Truncated    bool       `json:"truncated"` // 输出超出保存上限被截断
+	Error        string     `json:"error,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// Succeeded 主机是否执行成功
+func (h *BatchJobHost) Succeeded() bool {
+	return h.Status == BatchHostStatusSucceeded
+}
+
+// BatchJobRequest 创建批量任务请求。目标主机可以是连接ID列表、文件夹或标签，同时指定时取并集
+type BatchJobRequest struct {
+	Name           string `json:"name"`
+	ConnectionIDs  []uint `json:"connection_ids"`
+	FolderID       uint   `json:"folder_id"` // 文件夹中的全部SSH连接
+	Tag            string `json:"tag"`       // 带该标签的全部SSH连接
+	Command        string `json:"command"`
+	Script         string `json:"script"`
+	Sudo           bool   `json:"sudo"`
+	SudoUser       string `json:"sudo_user"`
+	Parallelism    int    `json:"parallelism"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// BatchJobRerunRequest 重新执行任务请求
+type BatchJobRerunRequest struct {
+	FailedOnly *bool `json:"failed_only,omitempty"` // 只在失败的主机上重新执行，默认true
+}
+
+// BatchJobDetail 任务及各主机的执行结果
+type BatchJobDetail struct {
+	*BatchJob
+	Hosts []*BatchJobHost `json:"hosts"`
+}
+
+// BatchJobEvent 任务执行过程中的实时事件
+type BatchJobEvent struct {
+	Type         string `json:"type"`
+	JobID        uint   `json:"job_id"`
+	ConnectionID uint   `json:"connection_id,omitempty"`
+	Stream       string `json:"stream,omitempty"` // stdout或stderr
+	Data         string `json:"data,omitempty"`
+	Status       string `json:"status,omitempty"` // 主机或任务的状态
+	ExitCode     *int   `json:"exit_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// BatchJobRepository 批量任务仓库接口
+type BatchJobRepository interface {
+	Create(job *BatchJob, hosts []*BatchJobHost) error
+	Update(job *BatchJob) error
+	GetByID(id uint) (*BatchJob, error)
+	GetByUserID(userID uint, limit int) ([]*BatchJob, error)
+	GetByScheduledJobID(scheduledJobID uint, limit int) ([]*BatchJob, error)
+	// DeleteByScheduledJobID 删除定时任务的执行记录，只保留最近keep条
+	DeleteByScheduledJobID(scheduledJobID uint, keep int) error
+	UpdateHost(host *BatchJobHost) error
+	GetHosts(jobID uint) ([]*BatchJobHost, error)
+	// FailUnfinished 将服务重启前未结束的任务和主机标记为失败
+	FailUnfinished(reason string) error
+}
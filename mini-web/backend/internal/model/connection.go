@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // 连接协议类型
 const (
@@ -10,51 +13,144 @@ const (
 	ProtocolTelnet = "telnet"
 )
 
+// SSH认证方式
+const (
+	AuthMethodPublicKey           = "publickey"
+	AuthMethodPassword            = "password"
+	AuthMethodKeyboardInteractive = "keyboard-interactive"
+	AuthMethodCertificate         = "certificate" // 使用内置SSH CA签发的短期证书
+)
+
+// DefaultAuthMethods 未配置时的默认认证顺序
+var DefaultAuthMethods = []string{AuthMethodPublicKey, AuthMethodPassword, AuthMethodKeyboardInteractive}
+
 // Connection 远程连接配置模型
 type Connection struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`         // 连接名称
-	Protocol    string    `json:"protocol"`     // 连接协议：rdp, ssh, vnc, telnet
-	Host        string    `json:"host"`         // 主机地址
-	Port        int       `json:"port"`         // 端口
-	Username    string    `json:"username"`     // 用户名
-	Password    string    `json:"-"`            // 密码，不在JSON中返回
-	PrivateKey  string    `json:"-"`            // SSH私钥，不在JSON中返回
-	Group       string    `json:"group"`        // 分组
-	Description string    `json:"description"`  // 描述
-	LastUsed    time.Time `json:"last_used"`    // 上次使用时间
-	CreatedBy   uint      `json:"created_by"`   // 创建者ID
-	CreatedAt   time.Time `json:"created_at"`   // 创建时间
-	UpdatedAt   time.Time `json:"updated_at"`   // 更新时间
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`            // 连接名称
+	Protocol       string    `json:"protocol"`        // 连接协议：rdp, ssh, vnc, telnet
+	Host           string    `json:"host"`            // 主机地址
+	Port           int       `json:"port"`            // 端口
+	Username       string    `json:"username"`        // 用户名
+	Password       string    `json:"-"`               // 密码，不在JSON中返回
+	PrivateKey     string    `json:"-"`               // SSH私钥，不在JSON中返回
+	KeyPassphrase  string    `json:"-"`               // SSH私钥口令，不在JSON中返回
+	AuthMethods    string    `json:"auth_methods"`    // SSH认证方式顺序，逗号分隔
+	CertPrincipals string    `json:"cert_principals"` // 限定证书principals，逗号分隔，只能收窄角色映射的登录名
+	SSHKeyID       uint      `json:"ssh_key_id"`      // 关联的服务端SSH密钥，为0时使用PrivateKey
+	CredentialID   uint      `json:"credential_id"`   // 引用的凭据，设置后会话创建时使用凭据中的密码/私钥
+	FolderID       uint      `json:"folder_id"`       // 所属文件夹，文件夹的共享授权对其中的连接生效
+	Group          string    `json:"group"`           // 分组
+	Tags           string    `json:"tags"`            // 标签，逗号分隔，如production
+	Description    string    `json:"description"`     // 描述
+	LastUsed       time.Time `json:"last_used"`       // 上次使用时间
+	CreatedBy      uint      `json:"created_by"`      // 创建者ID
+	CreatedAt      time.Time `json:"created_at"`      // 创建时间
+	UpdatedAt      time.Time `json:"updated_at"`      // 更新时间
+	Permission     string    `json:"permission"`      // 当前用户对连接的有效权限，不持久化
 }
 
 // ConnectionRequest 连接请求
 type ConnectionRequest struct {
-	Name        string `json:"name"`
-	Protocol    string `json:"protocol"`
-	Host        string `json:"host"`
-	Port        int    `json:"port"`
-	Username    string `json:"username"`
-	Password    string `json:"password,omitempty"`
-	PrivateKey  string `json:"private_key,omitempty"`
-	Group       string `json:"group"`
-	Description string `json:"description"`
+	Name           string `json:"name"`
+	Protocol       string `json:"protocol"`
+	Host           string `json:"host"`
+	Port           int    `json:"port"`
+	Username       string `json:"username"`
+	Password       string `json:"password,omitempty"`
+	PrivateKey     string `json:"private_key,omitempty"`
+	KeyPassphrase  string `json:"key_passphrase,omitempty"`
+	AuthMethods    string `json:"auth_methods"`
+	CertPrincipals string `json:"cert_principals"`
+	SSHKeyID       uint   `json:"ssh_key_id"`
+	CredentialID   uint   `json:"credential_id"`
+	FolderID       uint   `json:"folder_id"`
+	Group          string `json:"group"`
+	Tags           string `json:"tags"`
+	Description    string `json:"description"`
 }
 
 // ConnectionResponse 连接响应
 type ConnectionResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Protocol    string    `json:"protocol"`
-	Host        string    `json:"host"`
-	Port        int       `json:"port"`
-	Username    string    `json:"username"`
-	Group       string    `json:"group"`
-	Description string    `json:"description"`
-	LastUsed    time.Time `json:"last_used"`
-	CreatedBy   uint      `json:"created_by"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	Protocol       string    `json:"protocol"`
+	Host           string    `json:"host"`
+	Port           int       `json:"port"`
+	Username       string    `json:"username"`
+	AuthMethods    string    `json:"auth_methods"`
+	CertPrincipals string    `json:"cert_principals"`
+	SSHKeyID       uint      `json:"ssh_key_id"`
+	CredentialID   uint      `json:"credential_id"`
+	FolderID       uint      `json:"folder_id"`
+	Group          string    `json:"group"`
+	Tags           string    `json:"tags"`
+	Description    string    `json:"description"`
+	LastUsed       time.Time `json:"last_used"`
+	CreatedBy      uint      `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Permission     string    `json:"permission"`
+}
+
+// GetTags 获取标签列表
+func (c *Connection) GetTags() []string {
+	tags := make([]string, 0, 4)
+	for _, tag := range strings.Split(c.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// GetAuthMethods 获取有序的SSH认证方式列表
+func (c *Connection) GetAuthMethods() []string {
+	if strings.TrimSpace(c.AuthMethods) == "" {
+		return DefaultAuthMethods
+	}
+
+	methods := make([]string, 0, 4)
+	for _, method := range strings.Split(c.AuthMethods, ",") {
+		method = strings.TrimSpace(method)
+		if IsValidAuthMethod(method) {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return DefaultAuthMethods
+	}
+	return methods
+}
+
+// IsValidAuthMethod 检查认证方式是否有效
+func IsValidAuthMethod(method string) bool {
+	return method == AuthMethodPublicKey ||
+		method == AuthMethodPassword ||
+		method == AuthMethodKeyboardInteractive ||
+		method == AuthMethodCertificate
+}
+
+// HasAuthMethod 检查连接是否启用了指定的认证方式
+func (c *Connection) HasAuthMethod(method string) bool {
+	for _, m := range c.GetAuthMethods() {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCertPrincipals 获取连接限定的证书principals
+func (c *Connection) GetCertPrincipals() []string {
+	var principals []string
+	for _, principal := range strings.Split(c.CertPrincipals, ",") {
+		principal = strings.TrimSpace(principal)
+		if principal != "" {
+			principals = append(principals, principal)
+		}
+	}
+	return principals
 }
 
 // ConnectionListResponse 连接列表响应
@@ -70,11 +166,11 @@ type Session struct {
 	UserID       uint      `json:"user_id"`
 	StartTime    time.Time `json:"start_time"`
 	EndTime      time.Time `json:"end_time"`
-	Duration     int       `json:"duration"`    // 会话时长（秒）
-	Status       string    `json:"status"`      // 会话状态：active, closed
-	ClientIP     string    `json:"client_ip"`   // 客户端IP
-	ServerIP     string    `json:"server_ip"`   // 服务器IP
-	LogPath      string    `json:"log_path"`    // 会话日志路径
+	Duration     int       `json:"duration"`  // 会话时长（秒）
+	Status       string    `json:"status"`    // 会话状态：active, closed
+	ClientIP     string    `json:"client_ip"` // 客户端IP
+	ServerIP     string    `json:"server_ip"` // 服务器IP
+	LogPath      string    `json:"log_path"`  // 会话日志路径
 }
 
 // ConnectionRepository 连接数据仓库接口
@@ -86,6 +182,8 @@ type ConnectionRepository interface {
 	GetByUserID(userID uint) ([]*Connection, error)
 	GetAll() ([]*Connection, error)
 	UpdateLastUsed(id uint) error
+	GetBySSHKeyID(keyID uint) ([]*Connection, error)
+	GetByCredentialID(credentialID uint) ([]*Connection, error)
 }
 
 // SessionRepository 会话数据仓库接口
@@ -97,4 +195,4 @@ type SessionRepository interface {
 	GetActiveByUserID(userID uint) ([]*Session, error)
 	GetByConnectionID(connectionID uint) ([]*Session, error)
 	CloseSession(id uint) error
-}
\ No newline at end of file
+}
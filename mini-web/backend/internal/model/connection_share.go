@@ -0,0 +1,106 @@
+package model
+
+import "time"
+
+// 连接访问权限，级别依次递增，高级别包含低级别的全部能力
+const (
+	PermissionView    = "view"    // 查看连接信息
+	PermissionConnect = "connect" // 发起会话，不可查看凭据
+	PermissionEdit    = "edit"    // 修改连接配置
+	PermissionManage  = "manage"  // 删除连接、管理共享授权
+)
+
+// 共享资源类型
+const (
+	ShareResourceConnection = "connection"
+	ShareResourceFolder     = "folder"
+)
+
+// 共享对象类型
+const (
+	ShareSubjectUser = "user"
+	ShareSubjectTeam = "team"
+)
+
+// permissionLevels 权限级别，0表示无权限
+var permissionLevels = map[string]int{
+	PermissionView:    1,
+	PermissionConnect: 2,
+	PermissionEdit:    3,
+	PermissionManage:  4,
+}
+
+// IsValidPermission 检查权限名称是否有效
+func IsValidPermission(permission string) bool {
+	return permissionLevels[permission] > 0
+}
+
+// HasPermission 检查已授予的权限是否满足所需权限
+func HasPermission(granted, required string) bool {
+	level := permissionLevels[granted]
+	return level > 0 && level >= permissionLevels[required]
+}
+
+// MaxPermission 返回两个权限中级别较高者
+func MaxPermission(a, b string) string {
+	if permissionLevels[b] > permissionLevels[a] {
+		return b
+	}
+	return a
+}
+
+// ConnectionFolder 连接文件夹，共享文件夹即共享其中的全部连接
+type ConnectionFolder struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`        // 文件夹名称
+	Description string    `json:"description"` // 描述
+	OwnerID     uint      `json:"owner_id"`    // 所有者ID
+	Permission  string    `json:"permission"`  // 当前用户对文件夹的有效权限，不持久化
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ConnectionFolderRequest 创建/更新文件夹请求
+type ConnectionFolderRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ConnectionShare 连接或文件夹的共享授权
+type ConnectionShare struct {
+	ID           uint      `json:"id"`
+	ResourceType string    `json:"resource_type"` // 资源类型：connection, folder
+	ResourceID   uint      `json:"resource_id"`   // 连接ID或文件夹ID
+	SubjectType  string    `json:"subject_type"`  // 授权对象类型：user, team
+	SubjectID    uint      `json:"subject_id"`    // 用户ID或团队ID
+	SubjectName  string    `json:"subject_name"`  // 用户名或团队名称
+	Permission   string    `json:"permission"`    // 权限：view, connect, edit, manage
+	CreatedBy    uint      `json:"created_by"`    // 授权人ID
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ConnectionShareRequest 共享授权请求
+type ConnectionShareRequest struct {
+	SubjectType string `json:"subject_type"`
+	SubjectID   uint   `json:"subject_id"`
+	Permission  string `json:"permission"`
+}
+
+// ConnectionFolderRepository 连接文件夹仓库接口
+type ConnectionFolderRepository interface {
+	Create(folder *ConnectionFolder) error
+	Update(folder *ConnectionFolder) error
+	Delete(id uint) error
+	GetByID(id uint) (*ConnectionFolder, error)
+	GetAll() ([]*ConnectionFolder, error)
+}
+
+// ConnectionShareRepository 共享授权仓库接口
+type ConnectionShareRepository interface {
+	Save(share *ConnectionShare) error
+	Delete(id uint) error
+	GetByID(id uint) (*ConnectionShare, error)
+	GetByResource(resourceType string, resourceID uint) ([]*ConnectionShare, error)
+	GetBySubjects(userID uint, teamIDs []uint) ([]*ConnectionShare, error)
+	DeleteByResource(resourceType string, resourceID uint) error
+}
@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// 凭据类型
+const (
+	CredentialTypePassword      = "password"       // 密码
+	CredentialTypeKey           = "key"            // 私钥
+	CredentialTypeKeyPassphrase = "key_passphrase" // 加密私钥+口令
+)
+
+// Credential 可被多个连接引用的凭据，敏感字段在数据库中加密保存
+type Credential struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`        // 凭据名称
+	Type          string     `json:"type"`        // 凭据类型：password, key, key_passphrase
+	Username      string     `json:"username"`    // 登录用户名，为空时使用连接中的用户名
+	Password      string     `json:"-"`           // 密码，不在JSON中返回
+	PrivateKey    string     `json:"-"`           // 私钥，不在JSON中返回
+	Passphrase    string     `json:"-"`           // 私钥口令，不在JSON中返回
+	Description   string     `json:"description"` // 描述
+	OwnerID       uint       `json:"owner_id"`    // 所属用户
+	TeamID        uint       `json:"team_id"`     // 所属团队，为0表示个人凭据
+	LastRotatedAt *time.Time `json:"last_rotated_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CredentialRequest 创建/更新凭据请求
+type CredentialRequest struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Username    string `json:"username"`
+	Password    string `json:"password,omitempty"`
+	PrivateKey  string `json:"private_key,omitempty"`
+	Passphrase  string `json:"passphrase,omitempty"`
+	Description string `json:"description"`
+	TeamID      uint   `json:"team_id"`
+}
+
+// CredentialRotateRequest 轮换凭据请求
+type CredentialRotateRequest struct {
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// CredentialRepository 凭据仓库接口
+type CredentialRepository interface {
+	Create(cred *Credential) error
+	Update(cred *Credential) error
+	Delete(id uint) error
+	GetByID(id uint) (*Credential, error)
+	GetByOwnerID(ownerID uint) ([]*Credential, error)
+	GetByTeamIDs(teamIDs []uint) ([]*Credential, error)
+}
@@ -0,0 +1,88 @@
+package model
+
+import "time"
+
+// 邮件模板类型
+const (
+	EmailTemplateWelcome              = "welcome"
+	EmailTemplateResetPassword        = "reset_password"
+	EmailTemplateSecurityNotification = "security_notification"
+	EmailTemplateSystemNotification   = "system_notification"
+)
+
+// EmailConfig SMTP发信配置
+type EmailConfig struct {
+	ID        uint      `json:"id"`
+	SMTPHost  string    `json:"smtp_host"`
+	SMTPPort  int       `json:"smtp_port"`
+	Username  string    `json:"username"`
+	Password  string    `json:"password,omitempty"`
+	FromEmail string    `json:"from_email"`
+	FromName  string    `json:"from_name"`
+	EnableTLS bool      `json:"enable_tls"` // 明文连接后通过STARTTLS升级
+	EnableSSL bool      `json:"enable_ssl"` // 直接建立TLS连接（通常为465端口）
+	TestEmail string    `json:"test_email"`
+	IsEnabled bool      `json:"is_enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EmailTemplate 邮件模板，主题和正文中的{{变量}}在发送时替换。
+// HTMLBody非空时以multipart/alternative同时发送纯文本和HTML正文
+type EmailTemplate struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	HTMLBody  string    `json:"html_body"`
+	Type      string    `json:"type"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 发信队列状态
+const (
+	EmailStatusPending = "pending" // 等待发送或等待重试
+	EmailStatusSent    = "sent"    // 已发送
+	EmailStatusFailed  = "failed"  // 超过最大重试次数，放弃发送
+)
+
+// EmailMessage 发信队列中的一封邮件
+type EmailMessage struct {
+	ID            uint       `json:"id"`
+	To            string     `json:"to"`
+	Subject       string     `json:"subject"`
+	TextBody      string     `json:"text_body,omitempty"`
+	HTMLBody      string     `json:"html_body,omitempty"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// EmailRepository 邮件配置和模板仓库接口
+type EmailRepository interface {
+	GetConfig() (*EmailConfig, error)
+	SaveConfig(config *EmailConfig) error
+	GetTemplates() ([]*EmailTemplate, error)
+	GetTemplateByID(id uint) (*EmailTemplate, error)
+	GetDefaultTemplate(templateType string) (*EmailTemplate, error)
+	CreateTemplate(template *EmailTemplate) error
+	UpdateTemplate(template *EmailTemplate) error
+	DeleteTemplate(id uint) error
+}
+
+// EmailQueueRepository 发信队列仓库接口
+type EmailQueueRepository interface {
+	Enqueue(message *EmailMessage) error
+	GetDue(now time.Time, limit int) ([]*EmailMessage, error)
+	GetList(status string, limit, offset int) ([]*EmailMessage, int, error)
+	MarkSent(id uint) error
+	MarkRetry(id uint, lastError string, nextAttemptAt time.Time) error
+	MarkFailed(id uint, lastError string) error
+	Requeue(id uint) (bool, error)
+	DeleteSentBefore(before time.Time) error
+}
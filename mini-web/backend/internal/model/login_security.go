@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// LoginSecurityRepository 登录失败计数、账号锁定和常用登录IP仓库接口
+type LoginSecurityRepository interface {
+	// RecordFailure 记录一次登录失败，返回连续失败次数
+	RecordFailure(userID uint) (int, error)
+	// Lock 锁定账号到指定时间并清零失败次数
+	Lock(userID uint, until time.Time) error
+	// GetLockedUntil 获取账号锁定截止时间，未锁定时返回nil
+	GetLockedUntil(userID uint) (*time.Time, error)
+	// Reset 清零失败次数并解除锁定
+	Reset(userID uint) error
+	// RecordLoginIP 记录登录IP，返回该IP是否首次出现以及此前已知的IP数量
+	RecordLoginIP(userID uint, ip string) (bool, int, error)
+	// DeleteByUserID 删除用户的全部登录安全记录
+	DeleteByUserID(userID uint) error
+}
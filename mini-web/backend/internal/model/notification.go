@@ -0,0 +1,139 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 通知事件类型
+const (
+	EventLoginNewIP         = "login_new_ip"         // 从未使用过的IP登录
+	EventAccountLocked      = "account_locked"       // 连续登录失败导致账号锁定
+	EventProductionSession  = "production_session"   // 在带生产标签的连接上发起会话
+	EventHostKeyMismatch    = "host_key_mismatch"    // SSH主机密钥与记录不一致
+	EventSSLExpiring        = "ssl_expiring"         // SSL证书即将过期
+	EventACMEFailed         = "acme_failed"          // ACME证书签发或续期失败
+	EventPerformanceAlert   = "performance_alert"    // 系统资源使用率超过阈值
+	EventScheduledJobFailed = "scheduled_job_failed" // 定时任务重试后仍然失败
+)
+
+// NotificationEventTypes 全部事件类型及说明
+var NotificationEventTypes = map[string]string{
+	EventLoginNewIP:         "新IP登录",
+	EventAccountLocked:      "账号被锁定",
+	EventProductionSession:  "生产环境会话",
+	EventHostKeyMismatch:    "SSH主机密钥不匹配",
+	EventSSLExpiring:        "SSL证书即将过期",
+	EventACMEFailed:         "ACME证书签发失败",
+	EventPerformanceAlert:   "性能告警",
+	EventScheduledJobFailed: "定时任务执行失败",
+}
+
+// 事件级别
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// 通知渠道类型
+const (
+	NotificationChannelEmail    = "email"    // 发送邮件，Target为收件地址
+	NotificationChannelWebhook  = "webhook"  // 通用JSON Webhook，使用Secret签名
+	NotificationChannelSlack    = "slack"    // Slack兼容的Incoming Webhook
+	NotificationChannelDingTalk = "dingtalk" // 钉钉群机器人，Secret为加签密钥
+)
+
+// 通知投递状态
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// NotificationEvent 系统内部发生的需要通知的事件
+type NotificationEvent struct {
+	Type       string            `json:"type"`
+	Severity   string            `json:"severity"`
+	Title      string            `json:"title"`
+	Message    string            `json:"message"`
+	UserID     uint              `json:"user_id,omitempty"` // 事件相关的用户，系统级事件为0
+	Data       map[string]string `json:"data,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// NotificationChannel 通知渠道。UserID为0的系统渠道接收全部事件，
+// 个人渠道只接收与所属用户相关的事件
+type NotificationChannel struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Target    string    `json:"target"`           // 邮箱地址或Webhook地址
+	Secret    string    `json:"secret,omitempty"` // 签名密钥，加密存储，只在创建时返回
+	HasSecret bool      `json:"has_secret"`
+	Events    string    `json:"events"` // 订阅的事件类型，逗号分隔，为空表示全部
+	IsEnabled bool      `json:"is_enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribes 判断渠道是否订阅了指定事件
+func (c *NotificationChannel) Subscribes(eventType string) bool {
+	if strings.TrimSpace(c.Events) == "" {
+		return true
+	}
+	for _, item := range strings.Split(c.Events, ",") {
+		if strings.TrimSpace(item) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationChannelRequest 创建或更新通知渠道请求
+type NotificationChannelRequest struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Target    string   `json:"target"`
+	Secret    string   `json:"secret"` // 更新时为空表示保留原密钥
+	Events    []string `json:"events"`
+	IsEnabled bool     `json:"is_enabled"`
+}
+
+// NotificationDelivery Webhook类渠道的一次投递，失败后按退避策略重试
+type NotificationDelivery struct {
+	ID            uint       `json:"id"`
+	ChannelID     uint       `json:"channel_id"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NotificationChannelRepository 通知渠道仓库接口
+type NotificationChannelRepository interface {
+	Create(channel *NotificationChannel) error
+	Update(channel *NotificationChannel) error
+	Delete(id uint) error
+	GetByID(id uint) (*NotificationChannel, error)
+	GetByUserID(userID uint) ([]*NotificationChannel, error)
+	GetEnabledForUser(userID uint) ([]*NotificationChannel, error)
+	DeleteByUserID(userID uint) error
+}
+
+// NotificationDeliveryRepository 通知投递记录仓库接口
+type NotificationDeliveryRepository interface {
+	Create(delivery *NotificationDelivery) error
+	GetDue(now time.Time, limit int) ([]*NotificationDelivery, error)
+	GetByChannelID(channelID uint, limit int) ([]*NotificationDelivery, error)
+	MarkDelivered(id uint) error
+	MarkRetry(id uint, lastError string, nextAttemptAt time.Time) error
+	MarkFailed(id uint, lastError string) error
+	DeleteByChannelID(channelID uint) error
+	DeleteDeliveredBefore(before time.Time) error
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// PasswordHistory 用户用过的密码哈希，用于阻止重复使用最近的密码
+type PasswordHistory struct {
+	ID           uint      `json:"id"`
+	UserID       uint      `json:"user_id"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PasswordHistoryRepository 密码历史仓库接口
+type PasswordHistoryRepository interface {
+	Add(userID uint, passwordHash string) error
+	GetRecent(userID uint, limit int) ([]string, error)
+	Prune(userID uint, keep int) error
+	DeleteByUserID(userID uint) error
+}
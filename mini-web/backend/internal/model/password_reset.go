@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// PasswordResetToken 找回密码令牌，只保存令牌的哈希
+type PasswordResetToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	TokenHash string     `json:"-"`
+	IPAddress string     `json:"ip_address"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ForgotPasswordRequest 找回密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// PasswordResetRepository 找回密码令牌仓库接口
+type PasswordResetRepository interface {
+	Create(token *PasswordResetToken) error
+	GetByHash(tokenHash string) (*PasswordResetToken, error)
+	MarkUsed(id uint) (bool, error)
+	InvalidateByUserID(userID uint) error
+	DeleteExpired(before time.Time) error
+}
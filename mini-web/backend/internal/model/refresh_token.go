@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// RefreshToken 刷新令牌记录，仅保存令牌哈希。
+// 同一次登录轮换产生的令牌属于同一个家族（FamilyID），
+// 已使用的令牌再次出现时视为被盗用，整个家族随之吊销
+type RefreshToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-"`
+	AccessJTI string     `json:"-"`          // 与该刷新令牌一同签发的访问令牌ID
+	UserAgent string     `json:"user_agent"` // 登录客户端
+	IPAddress string     `json:"ip_address"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`    // 轮换时间，非空表示已被使用
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // 吊销时间
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsActive 判断刷新令牌是否仍可使用
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RevokedToken 已吊销的访问令牌，过期后可清理
+type RevokedToken struct {
+	JTI       string    `json:"jti"`
+	UserID    uint      `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenRefreshRequest 刷新令牌请求
+type TokenRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	Token         string `json:"token"`
+	Expire        int64  `json:"expire"`
+	RefreshToken  string `json:"refresh_token"`
+	RefreshExpire int64  `json:"refresh_expire"`
+}
+
+// RefreshTokenRepository 刷新令牌仓库接口
+type RefreshTokenRepository interface {
+	Create(token *RefreshToken) error
+	GetByHash(tokenHash string) (*RefreshToken, error)
+	MarkUsed(id uint) (bool, error)
+	RevokeFamily(familyID string) ([]*RefreshToken, error)
+	RevokeByUserID(userID uint) ([]*RefreshToken, error)
+	GetActiveByUserID(userID uint) ([]*RefreshToken, error)
+	DeleteExpired(before time.Time) error
+}
+
+// RevokedTokenRepository 访问令牌吊销名单仓库接口
+type RevokedTokenRepository interface {
+	Add(token *RevokedToken) error
+	GetActive(now time.Time) ([]*RevokedToken, error)
+	DeleteExpired(before time.Time) error
+}
@@ -0,0 +1,120 @@
+package model
+
+import "time"
+
+// 内置角色
+const (
+	RoleAdmin = "admin" // 管理员，拥有全部权限
+	RoleUser  = "user"  // 普通用户，注册用户的默认角色
+)
+
+// 系统权限
+const (
+	PermAll = "*" // 全部权限，仅内置管理员角色使用
+
+	PermConnectionsCreate    = "connections.create"     // 创建连接
+	PermConnectionsShare     = "connections.share"      // 共享连接和文件夹
+	PermConnectionsManageAll = "connections.manage_all" // 管理所有用户的连接和文件夹
+	PermSessionsCreate       = "sessions.create"        // 发起远程会话
+	PermSessionsMonitor      = "sessions.monitor"       // 查看全部会话统计
+	PermScheduledJobsManage  = "scheduled_jobs.manage"  // 创建使用服务账号执行的定时任务
+	PermSnippetsManageGlobal = "snippets.manage_global" // 维护所有用户可见的全局命令片段
+	PermSSHKeysManage        = "ssh_keys.manage"        // 管理服务端SSH密钥
+	PermCredentialsManage    = "credentials.manage"     // 管理凭据
+	PermTeamsCreate          = "teams.create"           // 创建团队
+	PermTeamsManageAll       = "teams.manage_all"       // 管理所有团队
+	PermUsersRead            = "users.read"             // 查看用户
+	PermUsersWrite           = "users.write"            // 管理用户
+	PermRolesRead            = "roles.read"             // 查看角色
+	PermRolesWrite           = "roles.write"            // 管理角色
+	PermSystemConfigsRead    = "system.configs.read"    // 查看系统配置
+	PermSystemConfigsWrite   = "system.configs.write"   // 修改系统配置
+	PermSystemInfoRead       = "system.info.read"       // 查看系统信息和性能监控
+	PermLogsRead             = "logs.read"              // 查看系统日志
+	PermLogsWrite            = "logs.write"             // 删除和清理系统日志
+	PermSSHCARead            = "ssh_ca.read"            // 查看SSH CA公钥和签发记录
+	PermDashboardRead        = "dashboard.read"         // 查看仪表盘
+)
+
+// PermissionInfo 权限说明
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PermissionCatalog 全部可分配的权限
+var PermissionCatalog = []PermissionInfo{
+	{PermConnectionsCreate, "创建连接"},
+	{PermConnectionsShare, "共享连接和文件夹"},
+	{PermConnectionsManageAll, "管理所有用户的连接和文件夹"},
+	{PermSessionsCreate, "发起远程会话"},
+	{PermSessionsMonitor, "查看全部会话统计"},
+	{PermScheduledJobsManage, "创建使用服务账号执行的定时任务"},
+	{PermSnippetsManageGlobal, "维护所有用户可见的全局命令片段"},
+	{PermSSHKeysManage, "管理服务端SSH密钥"},
+	{PermCredentialsManage, "管理凭据"},
+	{PermTeamsCreate, "创建团队"},
+	{PermTeamsManageAll, "管理所有团队"},
+	{PermUsersRead, "查看用户"},
+	{PermUsersWrite, "管理用户"},
+	{PermRolesRead, "查看角色"},
+	{PermRolesWrite, "管理角色"},
+	{PermSystemConfigsRead, "查看系统配置"},
+	{PermSystemConfigsWrite, "修改系统配置"},
+	{PermSystemInfoRead, "查看系统信息和性能监控"},
+	{PermLogsRead, "查看系统日志"},
+	{PermLogsWrite, "删除和清理系统日志"},
+	{PermSSHCARead, "查看SSH CA公钥和签发记录"},
+	{PermDashboardRead, "查看仪表盘"},
+}
+
+// DefaultUserPermissions 内置普通用户角色的初始权限
+var DefaultUserPermissions = []string{
+	PermConnectionsCreate,
+	PermConnectionsShare,
+	PermSessionsCreate,
+	PermSSHKeysManage,
+	PermCredentialsManage,
+	PermTeamsCreate,
+	PermDashboardRead,
+}
+
+// IsValidSystemPermission 检查权限是否在权限目录中
+func IsValidSystemPermission(permission string) bool {
+	for _, p := range PermissionCatalog {
+		if p.Name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Role 角色，通过权限列表授予系统功能
+type Role struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`        // 角色名称，对应User.Role
+	Description string    `json:"description"` // 描述
+	Permissions []string  `json:"permissions"` // 权限列表
+	BuiltIn     bool      `json:"built_in"`    // 是否内置角色，内置角色不可删除
+	Version     int       `json:"version"`     // 权限版本，每次修改权限递增
+	UserCount   int       `json:"user_count"`  // 使用该角色的用户数
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RoleRequest 创建/更新角色请求
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleRepository 角色仓库接口
+type RoleRepository interface {
+	Create(role *Role) error
+	Update(role *Role) error
+	Delete(id uint) error
+	GetByID(id uint) (*Role, error)
+	GetByName(name string) (*Role, error)
+	GetAll() ([]*Role, error)
+}
@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+// 定时任务上一次执行仍未结束时的处理策略
+const (
+	OverlapPolicySkip  = "skip"  // 跳过本次触发
+	OverlapPolicyQueue = "queue" // 上一次结束后立即补执行一次，多次触发合并为一次
+)
+
+// ScheduledJob 按cron表达式定时在主机上执行命令或脚本的任务，使用服务账号凭据登录，
+// 每次执行（包括失败重试）保存为一个关联的批量任务
+type ScheduledJob struct {
+	ID                uint       `json:"id"`
+	UserID            uint       `json:"user_id"` // 创建者，执行时仍按其连接权限检查目标主机
+	Name              string     `json:"name"`
+	CronExpr          string     `json:"cron_expr"`      // 5段cron表达式，按服务器本地时间
+	ConnectionIDs     []uint     `json:"connection_ids"` // 目标连接
+	Command           string     `json:"command"`
+	Script            string     `json:"script"`
+	Sudo              bool       `json:"sudo"`
+	SudoUser          string     `json:"sudo_user"`
+	CredentialID      uint       `json:"credential_id"` // 登录主机使用的服务账号凭据
+	Parallelism       int        `json:"parallelism"`
+	TimeoutSeconds    int        `json:"timeout_seconds"`     // 单台主机的超时时间
+	MaxRetries        int        `json:"max_retries"`         // 失败后在失败主机上重试的次数
+	RetryDelaySeconds int        `json:"retry_delay_seconds"` // 重试前等待的时间
+	OverlapPolicy     string     `json:"overlap_policy"`      // skip或queue
+	RetentionRuns     int        `json:"retention_runs"`      // 保留的执行记录数
+	NotifyOnFailure   bool       `json:"notify_on_failure"`   // 最终失败时发送通知
+	IsEnabled         bool       `json:"is_enabled"`
+	LastRunAt         *time.Time `json:"last_run_at,omitempty"`
+	LastStatus        string     `json:"last_status"` // 最近一次执行的最终状态
+	LastError         string     `json:"last_error,omitempty"`
+	LastBatchJobID    uint       `json:"last_batch_job_id"`     // 最近一次执行的最后一个批量任务
+	NextRunAt         *time.Time `json:"next_run_at,omitempty"` // 由cron表达式计算，不保存
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// ScheduledJobRequest 创建或更新定时任务请求
+type ScheduledJobRequest struct {
+	Name              string `json:"name"`
+	CronExpr          string `json:"cron_expr"`
+	ConnectionIDs     []uint `json:"connection_ids"`
+	Command           string `json:"command"`
+	Script            string `json:"script"`
+	Sudo              bool   `json:"sudo"`
+	SudoUser          string `json:"sudo_user"`
+	CredentialID      uint   `json:"credential_id"`
+	Parallelism       int    `json:"parallelism"`
+	TimeoutSeconds    int    `json:"timeout_seconds"`
+	MaxRetries        int    `json:"max_retries"`
+	RetryDelaySeconds int    `json:"retry_delay_seconds"`
+	OverlapPolicy     string `json:"overlap_policy"`
+	RetentionRuns     int    `json:"retention_runs"`
+	NotifyOnFailure   *bool  `json:"notify_on_failure,omitempty"` // 默认true
+	IsEnabled         *bool  `json:"is_enabled,omitempty"`        // 默认true
+}
+
+// ScheduledJobPauseState 定时任务全局暂停状态
+type ScheduledJobPauseState struct {
+	Paused bool `json:"paused"`
+}
+
+// ScheduledJobRepository 定时任务仓库接口
+type ScheduledJobRepository interface {
+	Create(job *ScheduledJob) error
+	Update(job *ScheduledJob) error
+	Delete(id uint) error
+	GetByID(id uint) (*ScheduledJob, error)
+	GetByUserID(userID uint) ([]*ScheduledJob, error)
+	GetEnabled() ([]*ScheduledJob, error)
+	// UpdateLastRun 保存最近一次执行结果，不覆盖用户同时修改的配置
+	UpdateLastRun(job *ScheduledJob) error
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 命令片段的可见范围
+const (
+	SnippetScopeUser   = "user"   // 仅创建者可见
+	SnippetScopeTeam   = "team"   // 团队成员可见
+	SnippetScopeGlobal = "global" // 所有用户可见，需要snippets.manage_global权限维护
+)
+
+// 命令片段参数类型
+const (
+	SnippetParamString  = "string"
+	SnippetParamNumber  = "number"
+	SnippetParamBoolean = "boolean" // 展开为true或false
+	SnippetParamEnum    = "enum"    // 取值必须在Options中
+)
+
+// SnippetParameter 命令片段中{{name}}占位符的定义
+type SnippetParameter struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Default     string   `json:"default"`           // 未传入参数时使用的值
+	Required    bool     `json:"required"`          // 必须传入参数或有默认值
+	Options     []string `json:"options,omitempty"` // enum类型的可选值
+}
+
+// Snippet 可复用的命令片段，内容中的{{name}}占位符在使用时按参数替换
+type Snippet struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Content     string             `json:"content"` // 命令内容，多行时逐行执行
+	Parameters  []SnippetParameter `json:"parameters"`
+	Scope       string             `json:"scope"`    // user, team, global
+	OwnerID     uint               `json:"owner_id"` // 创建者
+	TeamID      uint               `json:"team_id"`  // 团队片段所属团队，其他范围为0
+	Tags        string             `json:"tags"`     // 标签，逗号分隔
+	UsageCount  int                `json:"usage_count"`
+	LastUsedAt  *time.Time         `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// GetTags 获取标签列表
+func (s *Snippet) GetTags() []string {
+	tags := make([]string, 0, 4)
+	for _, tag := range strings.Split(s.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// SnippetRequest 创建/更新命令片段请求
+type SnippetRequest struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Content     string             `json:"content"`
+	Parameters  []SnippetParameter `json:"parameters"`
+	Scope       string             `json:"scope"` // 默认user
+	TeamID      uint               `json:"team_id"`
+	Tags        string             `json:"tags"`
+}
+
+// SnippetExpandRequest 展开命令片段请求，参数值可以是字符串、数字或布尔值
+type SnippetExpandRequest struct {
+	Args map[string]interface{} `json:"args"`
+}
+
+// SnippetExpansion 展开后的命令
+type SnippetExpansion struct {
+	SnippetID uint   `json:"snippet_id"`
+	Command   string `json:"command"`
+}
+
+// SnippetBatchRequest 以批量任务执行命令片段的请求，目标主机的指定方式与批量任务一致
+type SnippetBatchRequest struct {
+	Args           map[string]interface{} `json:"args"`
+	Name           string                 `json:"name"` // 为空时使用片段名称
+	ConnectionIDs  []uint                 `json:"connection_ids"`
+	FolderID       uint                   `json:"folder_id"`
+	Tag            string                 `json:"tag"`
+	Sudo           bool                   `json:"sudo"`
+	SudoUser       string                 `json:"sudo_user"`
+	Parallelism    int                    `json:"parallelism"`
+	TimeoutSeconds int                    `json:"timeout_seconds"`
+}
+
+// SnippetRepository 命令片段仓库接口
+type SnippetRepository interface {
+	Create(snippet *Snippet) error
+	Update(snippet *Snippet) error
+	Delete(id uint) error
+	GetByID(id uint) (*Snippet, error)
+	// GetVisible 获取用户可见的片段：个人片段、所属团队的片段和全局片段
+	GetVisible(userID uint, teamIDs []uint) ([]*Snippet, error)
+	// Search 在用户可见的片段中全文搜索名称、描述、内容和标签
+	Search(userID uint, teamIDs []uint, query string, limit int) ([]*Snippet, error)
+	// IncrementUsage 使用次数加一并记录使用时间
+	IncrementUsage(id uint) error
+}
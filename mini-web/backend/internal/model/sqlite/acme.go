@@ -0,0 +1,239 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ACME证书和签发记录状态
+const (
+	ACMEStatusPending    = "pending"    // 尚未签发
+	ACMEStatusProcessing = "processing" // 正在签发
+	ACMEStatusValid      = "valid"      // 签发成功
+	ACMEStatusFailed     = "failed"     // 签发失败
+)
+
+// ACMECertificate 由ACME自动签发和续期的证书，签发结果保存到ssl_configs
+type ACMECertificate struct {
+	ID            int    `json:"id"`
+	SSLConfigID   int    `json:"ssl_config_id"` // 首次签发成功前为0
+	Name          string `json:"name"`
+	Domains       string `json:"domains"`        // 逗号分隔
+	ChallengeType string `json:"challenge_type"` // http-01或dns-01
+	DNSProvider   string `json:"dns_provider"`
+	DNSConfig     string `json:"-"` // 加密后的DNS服务商配置
+	AutoRenew     bool   `json:"auto_renew"`
+	Status        string `json:"status"`
+	LastError     string `json:"last_error"`
+	LastIssuedAt  string `json:"last_issued_at"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// ACMEOrder 一次签发或续期尝试的记录
+type ACMEOrder struct {
+	ID            int    `json:"id"`
+	CertificateID int    `json:"certificate_id"`
+	Action        string `json:"action"` // issue或renew
+	Domains       string `json:"domains"`
+	Status        string `json:"status"`
+	Error         string `json:"error"`
+	OrderURL      string `json:"order_url"`
+	StartedAt     string `json:"started_at"`
+	FinishedAt    string `json:"finished_at"`
+}
+
+const acmeCertificateColumns = `id, ssl_config_id, name, domains, challenge_type, dns_provider, dns_config,
+	auto_renew, status, last_error, last_issued_at, created_at, updated_at`
+
+const acmeOrderColumns = `id, certificate_id, action, domains, status, error, order_url, started_at, finished_at`
+
+// scanACMECertificate 扫描一行ACME证书
+func scanACMECertificate(row rowScanner) (*ACMECertificate, error) {
+	cert := &ACMECertificate{}
+	err := row.Scan(&cert.ID, &cert.SSLConfigID, &cert.Name, &cert.Domains, &cert.ChallengeType, &cert.DNSProvider, &cert.DNSConfig,
+		&cert.AutoRenew, &cert.Status, &cert.LastError, &cert.LastIssuedAt, &cert.CreatedAt, &cert.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// queryACMECertificates 查询ACME证书列表
+func queryACMECertificates(query string, args ...interface{}) ([]*ACMECertificate, error) {
+	rows, err := DB.Query(`SELECT `+acmeCertificateColumns+` FROM acme_certificates `+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询ACME证书失败: %w", err)
+	}
+	defer rows.Close()
+
+	certs := make([]*ACMECertificate, 0)
+	for rows.Next() {
+		cert, err := scanACMECertificate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描ACME证书失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, rows.Err()
+}
+
+// getACMECertificate 查询单个ACME证书，不存在时返回nil
+func getACMECertificate(where string, arg interface{}) (*ACMECertificate, error) {
+	cert, err := scanACMECertificate(DB.QueryRow(`SELECT `+acmeCertificateColumns+` FROM acme_certificates WHERE `+where+` LIMIT 1`, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取ACME证书失败: %w", err)
+	}
+	return cert, nil
+}
+
+// CreateACMECertificate 创建ACME证书
+func CreateACMECertificate(cert *ACMECertificate) error {
+	result, err := DB.Exec(`
+		INSERT INTO acme_certificates (ssl_config_id, name, domains, challenge_type, dns_provider, dns_config, auto_renew, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, cert.SSLConfigID, cert.Name, cert.Domains, cert.ChallengeType, cert.DNSProvider, cert.DNSConfig, cert.AutoRenew, cert.Status)
+	if err != nil {
+		return fmt.Errorf("创建ACME证书失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取ACME证书ID失败: %w", err)
+	}
+	cert.ID = int(id)
+	return nil
+}
+
+// UpdateACMECertificate 更新ACME证书
+func UpdateACMECertificate(cert *ACMECertificate) error {
+	_, err := DB.Exec(`
+		UPDATE acme_certificates SET ssl_config_id = ?, name = ?, domains = ?, challenge_type = ?, dns_provider = ?, dns_config = ?,
+			auto_renew = ?, status = ?, last_error = ?, last_issued_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, cert.SSLConfigID, cert.Name, cert.Domains, cert.ChallengeType, cert.DNSProvider, cert.DNSConfig,
+		cert.AutoRenew, cert.Status, cert.LastError, cert.LastIssuedAt, cert.ID)
+	if err != nil {
+		return fmt.Errorf("更新ACME证书失败: %w", err)
+	}
+	return nil
+}
+
+// GetACMECertificates 获取全部ACME证书
+func GetACMECertificates() ([]*ACMECertificate, error) {
+	return queryACMECertificates(`ORDER BY id DESC`)
+}
+
+// GetACMECertificateByID 根据ID获取ACME证书，不存在时返回nil
+func GetACMECertificateByID(id int) (*ACMECertificate, error) {
+	return getACMECertificate("id = ?", id)
+}
+
+// GetACMECertificateBySSLConfigID 获取签发到指定SSL配置的ACME证书，不存在时返回nil
+func GetACMECertificateBySSLConfigID(sslConfigID int) (*ACMECertificate, error) {
+	return getACMECertificate("ssl_config_id = ?", sslConfigID)
+}
+
+// GetUnissuedACMECertificates 获取开启自动续期但尚未签发成功的ACME证书
+func GetUnissuedACMECertificates() ([]*ACMECertificate, error) {
+	return queryACMECertificates(`WHERE ssl_config_id = 0 AND auto_renew = 1 ORDER BY id`)
+}
+
+// DeleteACMECertificate 删除ACME证书及其签发记录，返回是否存在。已签发的SSL配置保留
+func DeleteACMECertificate(id int) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM acme_certificates WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("删除ACME证书失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("删除ACME证书失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM acme_orders WHERE certificate_id = ?", id); err != nil {
+		return false, fmt.Errorf("删除ACME签发记录失败: %w", err)
+	}
+	return affected > 0, tx.Commit()
+}
+
+// CreateACMEOrder 创建签发记录
+func CreateACMEOrder(order *ACMEOrder) error {
+	result, err := DB.Exec(`
+		INSERT INTO acme_orders (certificate_id, action, domains, status) VALUES (?, ?, ?, ?)
+	`, order.CertificateID, order.Action, order.Domains, order.Status)
+	if err != nil {
+		return fmt.Errorf("创建ACME签发记录失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取ACME签发记录ID失败: %w", err)
+	}
+	order.ID = int(id)
+	return nil
+}
+
+// FinishACMEOrder 记录签发结果
+func FinishACMEOrder(order *ACMEOrder) error {
+	_, err := DB.Exec(`
+		UPDATE acme_orders SET status = ?, error = ?, order_url = ?, finished_at = datetime('now') WHERE id = ?
+	`, order.Status, order.Error, order.OrderURL, order.ID)
+	if err != nil {
+		return fmt.Errorf("更新ACME签发记录失败: %w", err)
+	}
+	return nil
+}
+
+// GetACMEOrders 获取签发记录，certificateID为0时返回全部证书的记录
+func GetACMEOrders(certificateID, limit int) ([]*ACMEOrder, error) {
+	query := `SELECT ` + acmeOrderColumns + ` FROM acme_orders`
+	args := []interface{}{}
+	if certificateID > 0 {
+		query += ` WHERE certificate_id = ?`
+		args = append(args, certificateID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询ACME签发记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]*ACMEOrder, 0)
+	for rows.Next() {
+		order := &ACMEOrder{}
+		err := rows.Scan(&order.ID, &order.CertificateID, &order.Action, &order.Domains, &order.Status,
+			&order.Error, &order.OrderURL, &order.StartedAt, &order.FinishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("扫描ACME签发记录失败: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// FailInterruptedACMEOrders 把服务重启前未完成的签发标记为失败
+func FailInterruptedACMEOrders(message string) error {
+	_, err := DB.Exec(`
+		UPDATE acme_orders SET status = ?, error = ?, finished_at = datetime('now') WHERE status = ?
+	`, ACMEStatusFailed, message, ACMEStatusProcessing)
+	if err != nil {
+		return fmt.Errorf("更新ACME签发记录失败: %w", err)
+	}
+	_, err = DB.Exec(`
+		UPDATE acme_certificates SET status = ?, last_error = ?, updated_at = CURRENT_TIMESTAMP WHERE status = ?
+	`, ACMEStatusFailed, message, ACMEStatusProcessing)
+	if err != nil {
+		return fmt.Errorf("更新ACME证书状态失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// BatchJobRepository SQLite批量任务仓库实现
+type BatchJobRepository struct {
+	db *sql.DB
+}
+
+// NewBatchJobRepository 创建批量任务仓库实例
+func NewBatchJobRepository(db *sql.DB) model.BatchJobRepository {
+	return &BatchJobRepository{db: db}
+}
+
+const batchJobColumns = `id, user_id, name, COALESCE(command, ''), COALESCE(script, ''), sudo, COALESCE(sudo_user, ''),
+		   parallelism, timeout_seconds, COALESCE(source_job_id, 0), COALESCE(scheduled_job_id, 0),
+		   COALESCE(credential_id, 0), COALESCE(attempt, 0), status, total_hosts, succeeded_hosts,
+		   failed_hosts, started_at, finished_at, created_at`
+
+const batchJobHostColumns = `id, job_id, connection_id, name, host, status, exit_code, COALESCE(stdout, ''),
+		   COALESCE(stderr, ''), truncated, COALESCE(error, ''), started_at, finished_at`
+
+// scanBatchJob 扫描一行批量任务
+func scanBatchJob(row rowScanner) (*model.BatchJob, error) {
+	job := &model.BatchJob{}
+	var startedAt, finishedAt sql.NullTime
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.Name, &job.Command, &job.Script, &job.Sudo, &job.SudoUser,
+		&job.Parallelism, &job.TimeoutSeconds, &job.SourceJobID, &job.ScheduledJobID,
+		&job.CredentialID, &job.Attempt, &job.Status, &job.TotalHosts, &job.SucceededHosts,
+		&job.FailedHosts, &startedAt, &finishedAt, &job.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
+
+// scanBatchJobHost 扫描一行主机执行结果
+func scanBatchJobHost(row rowScanner) (*model.BatchJobHost, error) {
+	host := &model.BatchJobHost{}
+	var exitCode sql.NullInt64
+	var startedAt, finishedAt sql.NullTime
+	err := row.Scan(
+		&host.ID, &host.JobID, &host.ConnectionID, &host.Name, &host.Host, &host.Status, &exitCode, &host.Stdout,
+		&host.Stderr, &host.Truncated, &host.Error, &startedAt, &finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode.Valid {
+		code := int(exitCode.Int64)
+		host.ExitCode = &code
+	}
+	if startedAt.Valid {
+		host.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		host.FinishedAt = &finishedAt.Time
+	}
+	return host, nil
+}
+
+// Create 创建任务及其目标主机
+func (r *BatchJobRepository) Create(job *model.BatchJob, hosts []*model.BatchJobHost) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	job.CreatedAt = time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO batch_jobs (
+			user_id, name, command, script, sudo, sudo_user, parallelism, timeout_seconds,
+			source_job_id, scheduled_job_id, credential_id, attempt, status, total_hosts, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.UserID, job.Name, job.Command, job.Script, job.Sudo, job.SudoUser, job.Parallelism, job.TimeoutSeconds,
+		job.SourceJobID, job.ScheduledJobID, job.CredentialID, job.Attempt, job.Status, job.TotalHosts, job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("创建批量任务失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取批量任务ID失败: %w", err)
+	}
+	job.ID = uint(id)
+
+	for _, host := range hosts {
+		host.JobID = job.ID
+		result, err := tx.Exec(`
+			INSERT INTO batch_job_hosts (job_id, connection_id, name, host, status)
+			VALUES (?, ?, ?, ?, ?)
+		`, host.JobID, host.ConnectionID, host.Name, host.Host, host.Status)
+		if err != nil {
+			return fmt.Errorf("创建批量任务主机失败: %w", err)
+		}
+		hostID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("获取批量任务主机ID失败: %w", err)
+		}
+		host.ID = uint(hostID)
+	}
+
+	return tx.Commit()
+}
+
+// Update 更新任务状态和统计
+func (r *BatchJobRepository) Update(job *model.BatchJob) error {
+	_, err := r.db.Exec(`
+		UPDATE batch_jobs
+		SET status = ?, succeeded_hosts = ?, failed_hosts = ?, started_at = ?, finished_at = ?
+		WHERE id = ?
+	`, job.Status, job.SucceededHosts, job.FailedHosts, job.StartedAt, job.FinishedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("更新批量任务失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取任务，不存在时返回nil
+func (r *BatchJobRepository) GetByID(id uint) (*model.BatchJob, error) {
+	job, err := scanBatchJob(r.db.QueryRow(`SELECT `+batchJobColumns+` FROM batch_jobs WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取批量任务失败: %w", err)
+	}
+	return job, nil
+}
+
+// GetByUserID 获取用户最近的任务
+func (r *BatchJobRepository) GetByUserID(userID uint, limit int) ([]*model.BatchJob, error) {
+	return r.query(`
+		SELECT `+batchJobColumns+`
+		FROM batch_jobs
+		WHERE user_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, userID, limit)
+}
+
+// GetByScheduledJobID 获取定时任务最近的执行记录
+func (r *BatchJobRepository) GetByScheduledJobID(scheduledJobID uint, limit int) ([]*model.BatchJob, error) {
+	return r.query(`
+		SELECT `+batchJobColumns+`
+		FROM batch_jobs
+		WHERE scheduled_job_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, scheduledJobID, limit)
+}
+
+// DeleteByScheduledJobID 删除定时任务较早的执行记录及其主机结果，只保留最近keep条
+func (r *BatchJobRepository) DeleteByScheduledJobID(scheduledJobID uint, keep int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 未启用外键约束，主机结果需要单独删除
+	const expired = `SELECT id FROM batch_jobs WHERE scheduled_job_id = ? ORDER BY id DESC LIMIT -1 OFFSET ?`
+	if _, err := tx.Exec(`DELETE FROM batch_job_hosts WHERE job_id IN (`+expired+`)`, scheduledJobID, keep); err != nil {
+		return fmt.Errorf("删除定时任务执行记录失败: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM batch_jobs WHERE id IN (`+expired+`)`, scheduledJobID, keep); err != nil {
+		return fmt.Errorf("删除定时任务执行记录失败: %w", err)
+	}
+	return tx.Commit()
+}
+
+// query 查询任务列表
+func (r *BatchJobRepository) query(query string, args ...interface{}) ([]*model.BatchJob, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询批量任务失败: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*model.BatchJob, 0)
+	for rows.Next() {
+		job, err := scanBatchJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描批量任务失败: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateHost 保存主机的执行结果
+func (r *BatchJobRepository) UpdateHost(host *model.BatchJobHost) error {
+	_, err := r.db.Exec(`
+		UPDATE batch_job_hosts
+		SET status = ?, exit_code = ?, stdout = ?, stderr = ?, truncated = ?, error = ?, started_at = ?, finished_at = ?
+		WHERE id = ?
+	`, host.Status, host.ExitCode, host.Stdout, host.Stderr, host.Truncated, host.Error, host.StartedAt, host.FinishedAt, host.ID)
+	if err != nil {
+		return fmt.Errorf("更新批量任务主机失败: %w", err)
+	}
+	return nil
+}
+
+// GetHosts 获取任务的全部主机执行结果
+func (r *BatchJobRepository) GetHosts(jobID uint) ([]*model.BatchJobHost, error) {
+	rows, err := r.db.Query(`SELECT `+batchJobHostColumns+` FROM batch_job_hosts WHERE job_id = ? ORDER BY id`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("查询批量任务主机失败: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := make([]*model.BatchJobHost, 0)
+	for rows.Next() {
+		host, err := scanBatchJobHost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描批量任务主机失败: %w", err)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, rows.Err()
+}
+
+// FailUnfinished 将未结束的任务和主机标记为失败
+func (r *BatchJobRepository) FailUnfinished(reason string) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		UPDATE batch_job_hosts
+		SET status = ?, error = ?, finished_at = ?
+		WHERE status IN (?, ?)
+	`, model.BatchHostStatusFailed, reason, now, model.BatchHostStatusPending, model.BatchHostStatusRunning)
+	if err != nil {
+		return fmt.Errorf("更新未完成的批量任务主机失败: %w", err)
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE batch_jobs
+		SET status = ?, finished_at = ?,
+			succeeded_hosts = (SELECT COUNT(*) FROM batch_job_hosts WHERE job_id = batch_jobs.id AND status = ?),
+			failed_hosts = (SELECT COUNT(*) FROM batch_job_hosts WHERE job_id = batch_jobs.id AND status != ?)
+		WHERE status IN (?, ?)
+	`, model.BatchJobStatusFailed, now, model.BatchHostStatusSucceeded, model.BatchHostStatusSucceeded,
+		model.BatchJobStatusPending, model.BatchJobStatusRunning)
+	if err != nil {
+		return fmt.Errorf("更新未完成的批量任务失败: %w", err)
+	}
+	return nil
+}
@@ -31,6 +31,12 @@ func (r *ConnectionRepository) ensureTable() error {
 		username TEXT,
 		password TEXT,
 		private_key TEXT,
+		key_passphrase TEXT,
+		auth_methods TEXT,
+		cert_principals TEXT,
+		ssh_key_id INTEGER,
+		credential_id INTEGER,
+		folder_id INTEGER,
 		group_name TEXT,
 		description TEXT,
 		last_used TIMESTAMP,
@@ -72,9 +78,10 @@ func (r *ConnectionRepository) Create(conn *model.Connection) error {
 	query := `
 	INSERT INTO connections (
 		name, protocol, host, port, username, password, private_key, 
-		group_name, description, created_by
+		key_passphrase, auth_methods, cert_principals, ssh_key_id, credential_id,
+		folder_id, group_name, tags, description, created_by
 	)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := r.db.Exec(
@@ -86,7 +93,14 @@ func (r *ConnectionRepository) Create(conn *model.Connection) error {
 		conn.Username,
 		conn.Password,
 		conn.PrivateKey,
+		conn.KeyPassphrase,
+		conn.AuthMethods,
+		conn.CertPrincipals,
+		nullableID(conn.SSHKeyID),
+		nullableID(conn.CredentialID),
+		nullableID(conn.FolderID),
 		conn.Group,
+		conn.Tags,
 		conn.Description,
 		conn.CreatedBy,
 	)
@@ -115,7 +129,9 @@ func (r *ConnectionRepository) Update(conn *model.Connection) error {
 	SET name = ?, protocol = ?, host = ?, port = ?, username = ?, 
 		password = CASE WHEN ? != '' THEN ? ELSE password END,
 		private_key = CASE WHEN ? != '' THEN ? ELSE private_key END,
-		group_name = ?, description = ?, updated_at = CURRENT_TIMESTAMP
+		key_passphrase = CASE WHEN ? != '' THEN ? ELSE key_passphrase END,
+		auth_methods = ?, cert_principals = ?, ssh_key_id = ?, credential_id = ?,
+		folder_id = ?, group_name = ?, tags = ?, description = ?, updated_at = CURRENT_TIMESTAMP
 	WHERE id = ?
 	`
 
@@ -128,7 +144,14 @@ func (r *ConnectionRepository) Update(conn *model.Connection) error {
 		conn.Username,
 		conn.Password, conn.Password,
 		conn.PrivateKey, conn.PrivateKey,
+		conn.KeyPassphrase, conn.KeyPassphrase,
+		conn.AuthMethods,
+		conn.CertPrincipals,
+		nullableID(conn.SSHKeyID),
+		nullableID(conn.CredentialID),
+		nullableID(conn.FolderID),
 		conn.Group,
+		conn.Tags,
 		conn.Description,
 		conn.ID,
 	)
@@ -144,6 +167,12 @@ func (r *ConnectionRepository) Delete(id uint) error {
 		return err
 	}
 
+	// 删除连接的共享授权
+	_, err = r.db.Exec("DELETE FROM connection_shares WHERE resource_type = ? AND resource_id = ?", model.ShareResourceConnection, id)
+	if err != nil {
+		return err
+	}
+
 	// 再删除连接记录
 	_, err = r.db.Exec("DELETE FROM connections WHERE id = ?", id)
 	return err
@@ -151,18 +180,104 @@ func (r *ConnectionRepository) Delete(id uint) error {
 
 // GetByID 根据ID获取连接
 func (r *ConnectionRepository) GetByID(id uint) (*model.Connection, error) {
-	var conn model.Connection
-	var createdAt, updatedAt, lastUsed sql.NullString
-
-	query := `
-	SELECT id, name, protocol, host, port, username, password, private_key,
-		   group_name, description, last_used, created_by, created_at, updated_at
+	query := `SELECT ` + connectionColumns + `
 	FROM connections
 	WHERE id = ?
 	LIMIT 1
 	`
 
-	err := r.db.QueryRow(query, id).Scan(
+	conn, err := scanConnection(r.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // 连接不存在
+		}
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// GetByUserID 获取用户的所有连接
+func (r *ConnectionRepository) GetByUserID(userID uint) ([]*model.Connection, error) {
+	query := `SELECT ` + connectionColumns + `
+	FROM connections
+	WHERE created_by = ?
+	ORDER BY name
+	`
+
+	return r.queryConnections(query, userID)
+}
+
+// GetAll 获取所有连接
+func (r *ConnectionRepository) GetAll() ([]*model.Connection, error) {
+	query := `SELECT ` + connectionColumns + `
+	FROM connections
+	ORDER BY name
+	`
+
+	return r.queryConnections(query)
+}
+
+// UpdateLastUsed 更新连接最后使用时间
+func (r *ConnectionRepository) UpdateLastUsed(id uint) error {
+	query := `
+	UPDATE connections
+	SET last_used = CURRENT_TIMESTAMP
+	WHERE id = ?
+	`
+
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// connectionColumns 连接查询字段，对可能为NULL的字段做了兼容处理
+const connectionColumns = `id, name, protocol, host, port, COALESCE(username, ''),
+		   COALESCE(password, ''), COALESCE(private_key, ''),
+		   COALESCE(key_passphrase, ''), COALESCE(auth_methods, ''),
+		   COALESCE(cert_principals, ''), COALESCE(ssh_key_id, 0),
+		   COALESCE(credential_id, 0), COALESCE(folder_id, 0),
+		   COALESCE(group_name, ''), COALESCE(tags, ''), COALESCE(description, ''),
+		   last_used, created_by, created_at, updated_at`
+
+// GetBySSHKeyID 获取关联了指定SSH密钥的连接
+func (r *ConnectionRepository) GetBySSHKeyID(keyID uint) ([]*model.Connection, error) {
+	query := `SELECT ` + connectionColumns + `
+	FROM connections
+	WHERE ssh_key_id = ?
+	ORDER BY id`
+
+	return r.queryConnections(query, keyID)
+}
+
+// GetByCredentialID 获取引用了指定凭据的连接
+func (r *ConnectionRepository) GetByCredentialID(credentialID uint) ([]*model.Connection, error) {
+	query := `SELECT ` + connectionColumns + `
+	FROM connections
+	WHERE credential_id = ?
+	ORDER BY id`
+
+	return r.queryConnections(query, credentialID)
+}
+
+// nullableID 将0转换为NULL，用于可选的外键字段
+func nullableID(id uint) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// rowScanner 兼容sql.Row和sql.Rows的扫描接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanConnection 扫描一条连接记录
+func scanConnection(scanner rowScanner) (*model.Connection, error) {
+	var conn model.Connection
+	var createdAt, updatedAt, lastUsed sql.NullString
+
+	err := scanner.Scan(
 		&conn.ID,
 		&conn.Name,
 		&conn.Protocol,
@@ -171,18 +286,21 @@ func (r *ConnectionRepository) GetByID(id uint) (*model.Connection, error) {
 		&conn.Username,
 		&conn.Password,
 		&conn.PrivateKey,
+		&conn.KeyPassphrase,
+		&conn.AuthMethods,
+		&conn.CertPrincipals,
+		&conn.SSHKeyID,
+		&conn.CredentialID,
+		&conn.FolderID,
 		&conn.Group,
+		&conn.Tags,
 		&conn.Description,
 		&lastUsed,
 		&conn.CreatedBy,
 		&createdAt,
 		&updatedAt,
 	)
-
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil // 连接不存在
-		}
 		return nil, err
 	}
 
@@ -200,78 +318,9 @@ func (r *ConnectionRepository) GetByID(id uint) (*model.Connection, error) {
 	return &conn, nil
 }
 
-// GetByUserID 获取用户的所有连接
-func (r *ConnectionRepository) GetByUserID(userID uint) ([]*model.Connection, error) {
-	query := `
-	SELECT id, name, protocol, host, port, username, password, private_key,
-		   group_name, description, last_used, created_by, created_at, updated_at
-	FROM connections
-	WHERE created_by = ?
-	ORDER BY name
-	`
-
-	rows, err := r.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var connections []*model.Connection
-	for rows.Next() {
-		var conn model.Connection
-		var createdAt, updatedAt, lastUsed sql.NullString
-
-		err := rows.Scan(
-			&conn.ID,
-			&conn.Name,
-			&conn.Protocol,
-			&conn.Host,
-			&conn.Port,
-			&conn.Username,
-			&conn.Password,
-			&conn.PrivateKey,
-			&conn.Group,
-			&conn.Description,
-			&lastUsed,
-			&conn.CreatedBy,
-			&createdAt,
-			&updatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		// 解析时间
-		if createdAt.Valid {
-			conn.CreatedAt, _ = time.Parse(time.RFC3339, createdAt.String)
-		}
-		if updatedAt.Valid {
-			conn.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt.String)
-		}
-		if lastUsed.Valid {
-			conn.LastUsed, _ = time.Parse(time.RFC3339, lastUsed.String)
-		}
-
-		connections = append(connections, &conn)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return connections, nil
-}
-
-// GetAll 获取所有连接
-func (r *ConnectionRepository) GetAll() ([]*model.Connection, error) {
-	query := `
-	SELECT id, name, protocol, host, port, username, password, private_key,
-		   group_name, description, last_used, created_by, created_at, updated_at
-	FROM connections
-	ORDER BY name
-	`
-
-	rows, err := r.db.Query(query)
+// queryConnections 查询连接列表辅助函数
+func (r *ConnectionRepository) queryConnections(query string, args ...interface{}) ([]*model.Connection, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -279,41 +328,11 @@ func (r *ConnectionRepository) GetAll() ([]*model.Connection, error) {
 
 	var connections []*model.Connection
 	for rows.Next() {
-		var conn model.Connection
-		var createdAt, updatedAt, lastUsed sql.NullString
-
-		err := rows.Scan(
-			&conn.ID,
-			&conn.Name,
-			&conn.Protocol,
-			&conn.Host,
-			&conn.Port,
-			&conn.Username,
-			&conn.Password,
-			&conn.PrivateKey,
-			&conn.Group,
-			&conn.Description,
-			&lastUsed,
-			&conn.CreatedBy,
-			&createdAt,
-			&updatedAt,
-		)
+		conn, err := scanConnection(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		// 解析时间
-		if createdAt.Valid {
-			conn.CreatedAt, _ = time.Parse(time.RFC3339, createdAt.String)
-		}
-		if updatedAt.Valid {
-			conn.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt.String)
-		}
-		if lastUsed.Valid {
-			conn.LastUsed, _ = time.Parse(time.RFC3339, lastUsed.String)
-		}
-
-		connections = append(connections, &conn)
+		connections = append(connections, conn)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -323,18 +342,6 @@ func (r *ConnectionRepository) GetAll() ([]*model.Connection, error) {
 	return connections, nil
 }
 
-// UpdateLastUsed 更新连接最后使用时间
-func (r *ConnectionRepository) UpdateLastUsed(id uint) error {
-	query := `
-	UPDATE connections
-	SET last_used = CURRENT_TIMESTAMP
-	WHERE id = ?
-	`
-
-	_, err := r.db.Exec(query, id)
-	return err
-}
-
 // SessionRepository 会话数据仓库
 type SessionRepository struct {
 	db *sql.DB
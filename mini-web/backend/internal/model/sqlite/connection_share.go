@@ -0,0 +1,282 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// ConnectionFolderRepository SQLite连接文件夹仓库实现
+type ConnectionFolderRepository struct {
+	db *sql.DB
+}
+
+// NewConnectionFolderRepository 创建连接文件夹仓库实例
+func NewConnectionFolderRepository(db *sql.DB) model.ConnectionFolderRepository {
+	return &ConnectionFolderRepository{db: db}
+}
+
+const folderColumns = `id, name, COALESCE(description, ''), owner_id, created_at, updated_at`
+
+// Create 创建文件夹
+func (r *ConnectionFolderRepository) Create(folder *model.ConnectionFolder) error {
+	now := time.Now()
+	folder.CreatedAt = now
+	folder.UpdatedAt = now
+
+	result, err := r.db.Exec(
+		"INSERT INTO connection_folders (name, description, owner_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		folder.Name, folder.Description, folder.OwnerID, folder.CreatedAt, folder.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("创建文件夹失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+
+	folder.ID = uint(id)
+	return nil
+}
+
+// Update 更新文件夹
+func (r *ConnectionFolderRepository) Update(folder *model.ConnectionFolder) error {
+	folder.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(
+		"UPDATE connection_folders SET name = ?, description = ?, updated_at = ? WHERE id = ?",
+		folder.Name, folder.Description, folder.UpdatedAt, folder.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新文件夹失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除文件夹及其共享授权，其中的连接移出文件夹
+func (r *ConnectionFolderRepository) Delete(id uint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE connections SET folder_id = NULL WHERE folder_id = ?", []interface{}{id}},
+		{"DELETE FROM connection_shares WHERE resource_type = ? AND resource_id = ?", []interface{}{model.ShareResourceFolder, id}},
+		{"DELETE FROM connection_folders WHERE id = ?", []interface{}{id}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("删除文件夹失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByID 根据ID获取文件夹
+func (r *ConnectionFolderRepository) GetByID(id uint) (*model.ConnectionFolder, error) {
+	query := `SELECT ` + folderColumns + ` FROM connection_folders WHERE id = ?`
+
+	folder, err := scanFolder(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询文件夹失败: %w", err)
+	}
+	return folder, nil
+}
+
+// GetAll 获取全部文件夹
+func (r *ConnectionFolderRepository) GetAll() ([]*model.ConnectionFolder, error) {
+	rows, err := r.db.Query(`SELECT ` + folderColumns + ` FROM connection_folders ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件夹失败: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []*model.ConnectionFolder
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描文件夹失败: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, rows.Err()
+}
+
+// scanFolder 扫描一条文件夹记录
+func scanFolder(scanner rowScanner) (*model.ConnectionFolder, error) {
+	folder := &model.ConnectionFolder{}
+	err := scanner.Scan(
+		&folder.ID,
+		&folder.Name,
+		&folder.Description,
+		&folder.OwnerID,
+		&folder.CreatedAt,
+		&folder.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// ConnectionShareRepository SQLite共享授权仓库实现
+type ConnectionShareRepository struct {
+	db *sql.DB
+}
+
+// NewConnectionShareRepository 创建共享授权仓库实例
+func NewConnectionShareRepository(db *sql.DB) model.ConnectionShareRepository {
+	return &ConnectionShareRepository{db: db}
+}
+
+const shareSelect = `SELECT s.id, s.resource_type, s.resource_id, s.subject_type, s.subject_id,
+		   COALESCE(u.username, t.name, ''), s.permission, s.created_by, s.created_at
+		FROM connection_shares s
+		LEFT JOIN users u ON s.subject_type = 'user' AND u.id = s.subject_id
+		LEFT JOIN teams t ON s.subject_type = 'team' AND t.id = s.subject_id`
+
+// Save 保存共享授权，同一资源对同一对象重复授权时更新权限
+func (r *ConnectionShareRepository) Save(share *model.ConnectionShare) error {
+	query := `
+		INSERT INTO connection_shares (
+			resource_type, resource_id, subject_type, subject_id, permission, created_by, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(resource_type, resource_id, subject_type, subject_id)
+		DO UPDATE SET permission = excluded.permission, created_by = excluded.created_by
+	`
+
+	share.CreatedAt = time.Now()
+	_, err := r.db.Exec(query,
+		share.ResourceType,
+		share.ResourceID,
+		share.SubjectType,
+		share.SubjectID,
+		share.Permission,
+		share.CreatedBy,
+		share.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("保存共享授权失败: %w", err)
+	}
+
+	// 更新已有授权时LastInsertId不可靠，按唯一键回查ID
+	err = r.db.QueryRow(
+		"SELECT id FROM connection_shares WHERE resource_type = ? AND resource_id = ? AND subject_type = ? AND subject_id = ?",
+		share.ResourceType, share.ResourceID, share.SubjectType, share.SubjectID,
+	).Scan(&share.ID)
+	if err != nil {
+		return fmt.Errorf("获取共享授权ID失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除共享授权
+func (r *ConnectionShareRepository) Delete(id uint) error {
+	if _, err := r.db.Exec("DELETE FROM connection_shares WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除共享授权失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取共享授权
+func (r *ConnectionShareRepository) GetByID(id uint) (*model.ConnectionShare, error) {
+	share, err := scanShare(r.db.QueryRow(shareSelect+` WHERE s.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询共享授权失败: %w", err)
+	}
+	return share, nil
+}
+
+// GetByResource 获取连接或文件夹的全部共享授权
+func (r *ConnectionShareRepository) GetByResource(resourceType string, resourceID uint) ([]*model.ConnectionShare, error) {
+	query := shareSelect + `
+		WHERE s.resource_type = ? AND s.resource_id = ?
+		ORDER BY s.subject_type, s.subject_id`
+
+	return r.queryShares(query, resourceType, resourceID)
+}
+
+// GetBySubjects 获取授予指定用户本人及其所属团队的全部共享授权
+func (r *ConnectionShareRepository) GetBySubjects(userID uint, teamIDs []uint) ([]*model.ConnectionShare, error) {
+	query := shareSelect + ` WHERE (s.subject_type = ? AND s.subject_id = ?)`
+	args := []interface{}{model.ShareSubjectUser, userID}
+
+	if len(teamIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(teamIDs)), ",")
+		query += ` OR (s.subject_type = ? AND s.subject_id IN (` + placeholders + `))`
+		args = append(args, model.ShareSubjectTeam)
+		for _, id := range teamIDs {
+			args = append(args, id)
+		}
+	}
+
+	return r.queryShares(query, args...)
+}
+
+// DeleteByResource 删除连接或文件夹的全部共享授权
+func (r *ConnectionShareRepository) DeleteByResource(resourceType string, resourceID uint) error {
+	_, err := r.db.Exec("DELETE FROM connection_shares WHERE resource_type = ? AND resource_id = ?", resourceType, resourceID)
+	if err != nil {
+		return fmt.Errorf("删除共享授权失败: %w", err)
+	}
+	return nil
+}
+
+// queryShares 查询共享授权列表辅助函数
+func (r *ConnectionShareRepository) queryShares(query string, args ...interface{}) ([]*model.ConnectionShare, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询共享授权失败: %w", err)
+	}
+	defer rows.Close()
+
+	var shares []*model.ConnectionShare
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描共享授权失败: %w", err)
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// scanShare 扫描一条共享授权记录
+func scanShare(scanner rowScanner) (*model.ConnectionShare, error) {
+	share := &model.ConnectionShare{}
+	err := scanner.Scan(
+		&share.ID,
+		&share.ResourceType,
+		&share.ResourceID,
+		&share.SubjectType,
+		&share.SubjectID,
+		&share.SubjectName,
+		&share.Permission,
+		&share.CreatedBy,
+		&share.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return share, nil
+}
@@ -0,0 +1,201 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// CredentialRepository SQLite凭据仓库实现
+type CredentialRepository struct {
+	db *sql.DB
+}
+
+// NewCredentialRepository 创建凭据仓库实例
+func NewCredentialRepository(db *sql.DB) model.CredentialRepository {
+	return &CredentialRepository{db: db}
+}
+
+const credentialColumns = `id, name, type, COALESCE(username, ''), COALESCE(password, ''),
+		   COALESCE(private_key, ''), COALESCE(passphrase, ''), COALESCE(description, ''),
+		   owner_id, COALESCE(team_id, 0), last_rotated_at, created_at, updated_at`
+
+// Create 创建凭据，敏感字段需由调用方加密
+func (r *CredentialRepository) Create(cred *model.Credential) error {
+	query := `
+		INSERT INTO credentials (
+			name, type, username, password, private_key, passphrase,
+			description, owner_id, team_id, last_rotated_at, created_at, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	cred.CreatedAt = now
+	cred.UpdatedAt = now
+
+	result, err := r.db.Exec(query,
+		cred.Name,
+		cred.Type,
+		cred.Username,
+		cred.Password,
+		cred.PrivateKey,
+		cred.Passphrase,
+		cred.Description,
+		cred.OwnerID,
+		nullableID(cred.TeamID),
+		cred.LastRotatedAt,
+		cred.CreatedAt,
+		cred.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("创建凭据失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+
+	cred.ID = uint(id)
+	return nil
+}
+
+// Update 更新凭据
+func (r *CredentialRepository) Update(cred *model.Credential) error {
+	query := `
+		UPDATE credentials
+		SET name = ?, type = ?, username = ?, password = ?, private_key = ?, passphrase = ?,
+			description = ?, team_id = ?, last_rotated_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	cred.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(query,
+		cred.Name,
+		cred.Type,
+		cred.Username,
+		cred.Password,
+		cred.PrivateKey,
+		cred.Passphrase,
+		cred.Description,
+		nullableID(cred.TeamID),
+		cred.LastRotatedAt,
+		cred.UpdatedAt,
+		cred.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新凭据失败: %w", err)
+	}
+
+	return nil
+}
+
+// Delete 删除凭据
+func (r *CredentialRepository) Delete(id uint) error {
+	if _, err := r.db.Exec("DELETE FROM credentials WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除凭据失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取凭据
+func (r *CredentialRepository) GetByID(id uint) (*model.Credential, error) {
+	query := `SELECT ` + credentialColumns + `
+		FROM credentials
+		WHERE id = ?`
+
+	cred, err := scanCredential(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询凭据失败: %w", err)
+	}
+
+	return cred, nil
+}
+
+// GetByOwnerID 获取用户创建的凭据
+func (r *CredentialRepository) GetByOwnerID(ownerID uint) ([]*model.Credential, error) {
+	query := `SELECT ` + credentialColumns + `
+		FROM credentials
+		WHERE owner_id = ?
+		ORDER BY name`
+
+	return r.queryCredentials(query, ownerID)
+}
+
+// GetByTeamIDs 获取属于指定团队的凭据
+func (r *CredentialRepository) GetByTeamIDs(teamIDs []uint) ([]*model.Credential, error) {
+	if len(teamIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(teamIDs)), ",")
+	args := make([]interface{}, len(teamIDs))
+	for i, id := range teamIDs {
+		args[i] = id
+	}
+
+	query := `SELECT ` + credentialColumns + `
+		FROM credentials
+		WHERE team_id IN (` + placeholders + `)
+		ORDER BY name`
+
+	return r.queryCredentials(query, args...)
+}
+
+// queryCredentials 查询凭据列表辅助函数
+func (r *CredentialRepository) queryCredentials(query string, args ...interface{}) ([]*model.Credential, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询凭据失败: %w", err)
+	}
+	defer rows.Close()
+
+	var creds []*model.Credential
+	for rows.Next() {
+		cred, err := scanCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描凭据失败: %w", err)
+		}
+		creds = append(creds, cred)
+	}
+
+	return creds, rows.Err()
+}
+
+// scanCredential 扫描一条凭据记录
+func scanCredential(scanner rowScanner) (*model.Credential, error) {
+	cred := &model.Credential{}
+	var lastRotatedAt sql.NullTime
+
+	err := scanner.Scan(
+		&cred.ID,
+		&cred.Name,
+		&cred.Type,
+		&cred.Username,
+		&cred.Password,
+		&cred.PrivateKey,
+		&cred.Passphrase,
+		&cred.Description,
+		&cred.OwnerID,
+		&cred.TeamID,
+		&lastRotatedAt,
+		&cred.CreatedAt,
+		&cred.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastRotatedAt.Valid {
+		cred.LastRotatedAt = &lastRotatedAt.Time
+	}
+	return cred, nil
+}
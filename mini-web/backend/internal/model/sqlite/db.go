@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"gitee.com/await29/mini-web/internal/model"
 	_ "modernc.org/sqlite"
 )
 
@@ -24,8 +25,8 @@ func InitDB() error {
 	dbPath := filepath.Join(dataDir, "mini-web.db")
 	log.Printf("数据库路径: %s", dbPath)
 
-	// 打开数据库连接
-	db, err := sql.Open("sqlite", dbPath)
+	// 打开数据库连接，后台任务并发写入时等待锁释放而不是立即返回SQLITE_BUSY
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return fmt.Errorf("打开数据库连接失败: %w", err)
 	}
@@ -44,11 +45,26 @@ func InitDB() error {
 		return fmt.Errorf("创建表失败: %w", err)
 	}
 
+	// 为已有数据库补齐新增字段
+	if err := migrateTables(db); err != nil {
+		return fmt.Errorf("迁移表结构失败: %w", err)
+	}
+
+	// 确保内置角色存在
+	if err := seedRoles(db); err != nil {
+		return fmt.Errorf("初始化内置角色失败: %w", err)
+	}
+
 	// 初始化示例数据
 	if err := seedData(db); err != nil {
 		return fmt.Errorf("初始化数据失败: %w", err)
 	}
 
+	// 补齐新版本增加的系统配置项，需在默认配置初始化之后执行
+	if err := seedSystemConfigs(db); err != nil {
+		return fmt.Errorf("初始化系统配置失败: %w", err)
+	}
+
 	// 保存数据库连接
 	DB = db
 	log.Println("数据库初始化成功")
@@ -68,6 +84,9 @@ func createTables(db *sql.DB) error {
 		avatar TEXT,
 		role TEXT NOT NULL DEFAULT 'user',
 		status TEXT NOT NULL DEFAULT 'active',
+		auth_source TEXT NOT NULL DEFAULT 'local',
+		must_change_password INTEGER NOT NULL DEFAULT 0,
+		password_changed_at TIMESTAMP,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	)`)
@@ -86,7 +105,14 @@ func createTables(db *sql.DB) error {
 		username TEXT,
 		password TEXT,
 		private_key TEXT,
+		key_passphrase TEXT,
+		auth_methods TEXT,
+		cert_principals TEXT,
+		ssh_key_id INTEGER,
+		credential_id INTEGER,
+		folder_id INTEGER,
 		group_name TEXT,
+		tags TEXT,
 		description TEXT,
 		last_used TIMESTAMP,
 		created_by INTEGER NOT NULL,
@@ -196,6 +222,7 @@ func createTables(db *sql.DB) error {
 		name TEXT NOT NULL,
 		subject TEXT NOT NULL,
 		body TEXT NOT NULL,
+		html_body TEXT,
 		type TEXT NOT NULL,
 		is_default BOOLEAN NOT NULL DEFAULT 0,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -228,6 +255,64 @@ func createTables(db *sql.DB) error {
 		return fmt.Errorf("创建SSL配置表失败: %w", err)
 	}
 
+	// 等待外部签发的证书请求表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS ssl_csrs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		common_name TEXT NOT NULL,
+		dns_names TEXT NOT NULL DEFAULT '',
+		key_type TEXT NOT NULL,
+		csr_content TEXT NOT NULL,
+		key_content TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建证书请求表失败: %w", err)
+	}
+
+	// ACME自动签发的证书及签发记录表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS acme_certificates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ssl_config_id INTEGER NOT NULL DEFAULT 0,
+		name TEXT NOT NULL,
+		domains TEXT NOT NULL,
+		challenge_type TEXT NOT NULL,
+		dns_provider TEXT NOT NULL DEFAULT '',
+		dns_config TEXT NOT NULL DEFAULT '',
+		auto_renew BOOLEAN NOT NULL DEFAULT 1,
+		status TEXT NOT NULL DEFAULT 'pending',
+		last_error TEXT NOT NULL DEFAULT '',
+		last_issued_at TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建ACME证书表失败: %w", err)
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS acme_orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		certificate_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		domains TEXT NOT NULL,
+		status TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		order_url TEXT NOT NULL DEFAULT '',
+		started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		finished_at TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建ACME签发记录表失败: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_acme_orders_certificate ON acme_orders(certificate_id, id)`)
+	if err != nil {
+		return fmt.Errorf("创建ACME签发记录索引失败: %w", err)
+	}
+
 	// API访问配置表
 	_, err = db.Exec(`
 	CREATE TABLE IF NOT EXISTS api_configs (
@@ -322,10 +407,628 @@ func createTables(db *sql.DB) error {
 		return fmt.Errorf("创建API访问日志表失败: %w", err)
 	}
 
+	// SSH密钥表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS ssh_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		key_type TEXT NOT NULL,
+		bits INTEGER NOT NULL DEFAULT 0,
+		public_key TEXT NOT NULL,
+		private_key TEXT NOT NULL,
+		fingerprint TEXT NOT NULL,
+		comment TEXT,
+		created_by INTEGER NOT NULL,
+		rotated_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建SSH密钥表失败: %w", err)
+	}
+
+	// 凭据表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS credentials (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		username TEXT,
+		password TEXT,
+		private_key TEXT,
+		passphrase TEXT,
+		description TEXT,
+		owner_id INTEGER NOT NULL,
+		team_id INTEGER,
+		last_rotated_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (owner_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建凭据表失败: %w", err)
+	}
+
+	// SSH证书签发记录表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS ssh_certificates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		serial INTEGER NOT NULL UNIQUE,
+		key_id TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		username TEXT NOT NULL,
+		connection_id INTEGER NOT NULL,
+		principals TEXT NOT NULL,
+		fingerprint TEXT NOT NULL,
+		valid_after TIMESTAMP NOT NULL,
+		valid_before TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (connection_id) REFERENCES connections(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建SSH证书签发记录表失败: %w", err)
+	}
+
+	// 团队表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS teams (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		created_by INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建团队表失败: %w", err)
+	}
+
+	// 团队成员表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS team_members (
+		team_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		role TEXT NOT NULL DEFAULT 'member',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (team_id, user_id),
+		FOREIGN KEY (team_id) REFERENCES teams(id),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建团队成员表失败: %w", err)
+	}
+
+	// 连接文件夹表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS connection_folders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT,
+		owner_id INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (owner_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建连接文件夹表失败: %w", err)
+	}
+
+	// 连接共享授权表，resource为连接或文件夹，subject为用户或团队
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS connection_shares (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		resource_type TEXT NOT NULL,
+		resource_id INTEGER NOT NULL,
+		subject_type TEXT NOT NULL,
+		subject_id INTEGER NOT NULL,
+		permission TEXT NOT NULL,
+		created_by INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (resource_type, resource_id, subject_type, subject_id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建连接共享表失败: %w", err)
+	}
+
+	// 角色表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS roles (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		built_in INTEGER NOT NULL DEFAULT 0,
+		version INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建角色表失败: %w", err)
+	}
+
+	// 角色权限表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS role_permissions (
+		role_id INTEGER NOT NULL,
+		permission TEXT NOT NULL,
+		PRIMARY KEY (role_id, permission),
+		FOREIGN KEY (role_id) REFERENCES roles(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建角色权限表失败: %w", err)
+	}
+
+	// 刷新令牌表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		family_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		access_jti TEXT NOT NULL,
+		user_agent TEXT,
+		ip_address TEXT,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建刷新令牌表失败: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id)`)
+	if err != nil {
+		return fmt.Errorf("创建刷新令牌索引失败: %w", err)
+	}
+
+	// 访问令牌吊销名单表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建访问令牌吊销名单表失败: %w", err)
+	}
+
+	// 密码历史表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS password_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建密码历史表失败: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_password_history_user ON password_history(user_id)`)
+	if err != nil {
+		return fmt.Errorf("创建密码历史索引失败: %w", err)
+	}
+
+	// 找回密码令牌表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		ip_address TEXT,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建找回密码令牌表失败: %w", err)
+	}
+
+	// 发信队列表，发送失败的邮件按next_attempt_at重试
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS email_queue (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recipient TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		text_body TEXT NOT NULL,
+		html_body TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP NOT NULL,
+		sent_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建发信队列表失败: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_email_queue_due ON email_queue(status, next_attempt_at)`)
+	if err != nil {
+		return fmt.Errorf("创建发信队列索引失败: %w", err)
+	}
+
+	// 登录失败计数和账号锁定表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS login_lockouts (
+		user_id INTEGER PRIMARY KEY,
+		failed_attempts INTEGER NOT NULL DEFAULT 0,
+		locked_until TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建账号锁定表失败: %w", err)
+	}
+
+	// 用户登录过的IP表，用于识别新IP登录
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS user_login_ips (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		ip_address TEXT NOT NULL,
+		first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, ip_address),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建登录IP表失败: %w", err)
+	}
+
+	// 通知渠道表，user_id为0表示系统渠道
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_channels (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL DEFAULT 0,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		target TEXT NOT NULL,
+		secret TEXT,
+		events TEXT,
+		is_enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建通知渠道表失败: %w", err)
+	}
+
+	// 通知投递表，Webhook类渠道投递失败后按next_attempt_at重试
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		channel_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP NOT NULL,
+		delivered_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (channel_id) REFERENCES notification_channels(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建通知投递表失败: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due ON notification_deliveries(status, next_attempt_at)`)
+	if err != nil {
+		return fmt.Errorf("创建通知投递索引失败: %w", err)
+	}
+
+	// SSH主机公钥表，首次连接时记录
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS ssh_host_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		host TEXT NOT NULL,
+		port INTEGER NOT NULL,
+		key_type TEXT NOT NULL,
+		public_key TEXT NOT NULL,
+		fingerprint TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (host, port)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建SSH主机公钥表失败: %w", err)
+	}
+
+	// 批量命令任务表
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS batch_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		command TEXT,
+		script TEXT,
+		sudo BOOLEAN NOT NULL DEFAULT 0,
+		sudo_user TEXT,
+		parallelism INTEGER NOT NULL,
+		timeout_seconds INTEGER NOT NULL,
+		source_job_id INTEGER,
+		scheduled_job_id INTEGER,
+		credential_id INTEGER,
+		attempt INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL,
+		total_hosts INTEGER NOT NULL DEFAULT 0,
+		succeeded_hosts INTEGER NOT NULL DEFAULT 0,
+		failed_hosts INTEGER NOT NULL DEFAULT 0,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建批量任务表失败: %w", err)
+	}
+
+	// 批量任务各主机的执行结果
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS batch_job_hosts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_id INTEGER NOT NULL,
+		connection_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		host TEXT NOT NULL,
+		status TEXT NOT NULL,
+		exit_code INTEGER,
+		stdout TEXT,
+		stderr TEXT,
+		truncated BOOLEAN NOT NULL DEFAULT 0,
+		error TEXT,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP,
+		FOREIGN KEY (job_id) REFERENCES batch_jobs(id) ON DELETE CASCADE
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建批量任务主机表失败: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_batch_job_hosts_job ON batch_job_hosts(job_id)`)
+	if err != nil {
+		return fmt.Errorf("创建批量任务主机索引失败: %w", err)
+	}
+
+	// 定时任务表，每次执行保存为关联的批量任务
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS scheduled_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		cron_expr TEXT NOT NULL,
+		connection_ids TEXT,
+		command TEXT,
+		script TEXT,
+		sudo BOOLEAN NOT NULL DEFAULT 0,
+		sudo_user TEXT,
+		credential_id INTEGER NOT NULL,
+		parallelism INTEGER NOT NULL,
+		timeout_seconds INTEGER NOT NULL,
+		max_retries INTEGER NOT NULL DEFAULT 0,
+		retry_delay_seconds INTEGER NOT NULL DEFAULT 0,
+		overlap_policy TEXT NOT NULL DEFAULT 'skip',
+		retention_runs INTEGER NOT NULL,
+		notify_on_failure BOOLEAN NOT NULL DEFAULT 1,
+		is_enabled BOOLEAN NOT NULL DEFAULT 1,
+		last_run_at TIMESTAMP,
+		last_status TEXT,
+		last_error TEXT,
+		last_batch_job_id INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建定时任务表失败: %w", err)
+	}
+
+	// 命令片段表，parameters为JSON格式的参数定义
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS snippets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL,
+		parameters TEXT NOT NULL DEFAULT '[]',
+		scope TEXT NOT NULL DEFAULT 'user',
+		owner_id INTEGER NOT NULL,
+		team_id INTEGER,
+		tags TEXT NOT NULL DEFAULT '',
+		usage_count INTEGER NOT NULL DEFAULT 0,
+		last_used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (owner_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建命令片段表失败: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_snippets_owner ON snippets(owner_id)`)
+	if err != nil {
+		return fmt.Errorf("创建命令片段索引失败: %w", err)
+	}
+
+	// 命令片段全文索引，trigram分词同时支持中文和命令片段的子串搜索，由触发器与snippets表同步
+	_, err = db.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS snippets_fts USING fts5(
+		name, description, content, tags,
+		content='snippets', content_rowid='id', tokenize='trigram'
+	);
+	CREATE TRIGGER IF NOT EXISTS snippets_fts_insert AFTER INSERT ON snippets BEGIN
+		INSERT INTO snippets_fts(rowid, name, description, content, tags)
+		VALUES (new.id, new.name, new.description, new.content, new.tags);
+	END;
+	CREATE TRIGGER IF NOT EXISTS snippets_fts_delete AFTER DELETE ON snippets BEGIN
+		INSERT INTO snippets_fts(snippets_fts, rowid, name, description, content, tags)
+		VALUES ('delete', old.id, old.name, old.description, old.content, old.tags);
+	END;
+	CREATE TRIGGER IF NOT EXISTS snippets_fts_update AFTER UPDATE OF name, description, content, tags ON snippets BEGIN
+		INSERT INTO snippets_fts(snippets_fts, rowid, name, description, content, tags)
+		VALUES ('delete', old.id, old.name, old.description, old.content, old.tags);
+		INSERT INTO snippets_fts(rowid, name, description, content, tags)
+		VALUES (new.id, new.name, new.description, new.content, new.tags);
+	END`)
+	if err != nil {
+		return fmt.Errorf("创建命令片段全文索引失败: %w", err)
+	}
+
 	log.Println("表结构创建成功")
 	return nil
 }
 
+// migrateTables 为旧版本数据库补齐新增字段
+func migrateTables(db *sql.DB) error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"connections", "key_passphrase", "TEXT"},
+		{"connections", "auth_methods", "TEXT"},
+		{"connections", "cert_principals", "TEXT"},
+		{"connections", "ssh_key_id", "INTEGER"},
+		{"connections", "credential_id", "INTEGER"},
+		{"connections", "folder_id", "INTEGER"},
+		{"users", "auth_source", "TEXT NOT NULL DEFAULT 'local'"},
+		{"users", "must_change_password", "INTEGER NOT NULL DEFAULT 0"},
+		{"users", "password_changed_at", "TIMESTAMP"},
+		{"email_templates", "html_body", "TEXT"},
+		{"connections", "tags", "TEXT"},
+		{"batch_jobs", "scheduled_job_id", "INTEGER"},
+		{"batch_jobs", "credential_id", "INTEGER"},
+		{"batch_jobs", "attempt", "INTEGER NOT NULL DEFAULT 0"},
+	}
+
+	for _, c := range columns {
+		if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	// 依赖新增字段的索引在补齐字段后创建
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_batch_jobs_scheduled ON batch_jobs(scheduled_job_id)`); err != nil {
+		return fmt.Errorf("创建定时任务执行记录索引失败: %w", err)
+	}
+
+	return nil
+}
+
+// addColumnIfNotExists 当字段不存在时添加字段
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("查询表%s结构失败: %w", table, err)
+	}
+
+	exists := false
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取表%s结构失败: %w", table, err)
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	rows.Close()
+
+	if exists {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("为表%s添加字段%s失败: %w", table, column, err)
+	}
+	log.Printf("已为表%s添加字段%s", table, column)
+	return nil
+}
+
+// seedRoles 创建缺失的内置角色，已存在的角色保持不变
+func seedRoles(db *sql.DB) error {
+	builtIns := []*model.Role{
+		{Name: model.RoleAdmin, Description: "系统管理员，拥有全部权限", Permissions: []string{model.PermAll}, BuiltIn: true},
+		{Name: model.RoleUser, Description: "普通用户", Permissions: model.DefaultUserPermissions, BuiltIn: true},
+	}
+
+	repo := NewRoleRepository(db)
+	for _, role := range builtIns {
+		existing, err := repo.GetByName(role.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			continue
+		}
+		if err := repo.Create(role); err != nil {
+			return err
+		}
+		log.Printf("已创建内置角色: %s", role.Name)
+	}
+
+	return nil
+}
+
+// seedSystemConfigs 插入缺失的系统配置项，已存在的配置保持不变
+func seedSystemConfigs(db *sql.DB) error {
+	configs := []model.SystemConfig{
+		{Key: "oidc_enabled", Value: "false", Description: "启用OpenID Connect单点登录", Category: "oidc", Type: "boolean"},
+		{Key: "oidc_provider_name", Value: "SSO", Description: "登录页显示的身份提供者名称", Category: "oidc", Type: "string"},
+		{Key: "oidc_issuer", Value: "", Description: "身份提供者Issuer地址", Category: "oidc", Type: "string"},
+		{Key: "oidc_client_id", Value: "", Description: "客户端ID", Category: "oidc", Type: "string"},
+		{Key: "oidc_client_secret", Value: "", Description: "客户端密钥，公共客户端可留空", Category: "oidc", Type: "string"},
+		{Key: "oidc_redirect_url", Value: "", Description: "回调地址，如 https://host/api/auth/oidc/callback", Category: "oidc", Type: "string"},
+		{Key: "oidc_frontend_url", Value: "/login", Description: "登录完成后跳转的前端地址，令牌通过URL片段传递", Category: "oidc", Type: "string"},
+		{Key: "oidc_scopes", Value: "openid profile email", Description: "请求的scope，空格分隔", Category: "oidc", Type: "string"},
+		{Key: "oidc_username_claim", Value: "preferred_username", Description: "用户名对应的claim", Category: "oidc", Type: "string"},
+		{Key: "oidc_email_claim", Value: "email", Description: "邮箱对应的claim", Category: "oidc", Type: "string"},
+		{Key: "oidc_name_claim", Value: "name", Description: "显示名称对应的claim", Category: "oidc", Type: "string"},
+		{Key: "oidc_groups_claim", Value: "groups", Description: "用户组对应的claim", Category: "oidc", Type: "string"},
+		{Key: "oidc_group_role_map", Value: "", Description: "用户组到角色的映射，格式：组=>角色;组=>角色", Category: "oidc", Type: "string"},
+		{Key: "oidc_default_role", Value: "user", Description: "未匹配任何组时新用户的角色", Category: "oidc", Type: "string"},
+		{Key: "password_min_length", Value: "", Description: "密码最小长度，留空使用password_policy预设", Category: "security", Type: "number"},
+		{Key: "password_min_char_classes", Value: "", Description: "密码至少包含的字符种类数（小写、大写、数字、符号），留空使用预设", Category: "security", Type: "number"},
+		{Key: "password_check_blocklist", Value: "", Description: "禁止使用常见弱密码，留空使用预设", Category: "security", Type: "boolean"},
+		{Key: "password_disallow_username", Value: "", Description: "禁止密码包含用户名，留空使用预设", Category: "security", Type: "boolean"},
+		{Key: "password_history_count", Value: "", Description: "不能与最近几次的密码相同，0不限制，留空使用预设", Category: "security", Type: "number"},
+		{Key: "password_max_age_days", Value: "", Description: "密码有效天数，0永不过期，留空使用预设", Category: "security", Type: "number"},
+		{Key: "password_reset_url", Value: "http://localhost:5173/reset-password", Description: "找回密码邮件中的重置页面地址，令牌以token参数附加", Category: "security", Type: "string"},
+		{Key: "password_reset_ttl_minutes", Value: "30", Description: "找回密码链接有效时间（分钟）", Category: "security", Type: "number"},
+		{Key: "login_lockout_minutes", Value: "15", Description: "连续登录失败达到login_attempts次后锁定账号的时间（分钟）", Category: "security", Type: "number"},
+		{Key: "notification_production_tags", Value: "production,prod", Description: "视为生产环境的连接标签，逗号分隔，在这些连接上发起会话时发送通知", Category: "notification", Type: "string"},
+		{Key: "notification_allow_private_webhooks", Value: "false", Description: "允许Webhook投递到内网、本机等私有地址", Category: "notification", Type: "boolean"},
+		{Key: "notification_memory_threshold", Value: "90", Description: "内存使用率告警阈值（%），0表示不告警", Category: "notification", Type: "number"},
+		{Key: "notification_disk_threshold", Value: "90", Description: "磁盘使用率告警阈值（%），0表示不告警", Category: "notification", Type: "number"},
+		{Key: "scheduled_jobs_paused", Value: "false", Description: "暂停全部定时任务，暂停期间不触发新的执行", Category: "scheduled_jobs", Type: "boolean"},
+	}
+
+	for _, c := range configs {
+		_, err := db.Exec(`
+		INSERT INTO system_configs (key, value, description, category, type)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO NOTHING`,
+			c.Key, c.Value, c.Description, c.Category, c.Type,
+		)
+		if err != nil {
+			return fmt.Errorf("添加系统配置%s失败: %w", c.Key, err)
+		}
+	}
+
+	return nil
+}
+
 // seedData 初始化示例数据
 func seedData(db *sql.DB) error {
 	// 检查用户表是否为空
@@ -439,7 +1142,7 @@ func seedData(db *sql.DB) error {
 
 如果您没有请求重置密码，请忽略此邮件。
 
-此链接将在24小时后失效。
+此链接将在{{expire_minutes}}分钟后失效，且只能使用一次。
 
 {{site_name}} 团队
 {{current_date}}', 
@@ -0,0 +1,218 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// EmailRepository SQLite邮件配置和模板仓库实现
+type EmailRepository struct {
+	db *sql.DB
+}
+
+// NewEmailRepository 创建邮件仓库实例
+func NewEmailRepository(db *sql.DB) model.EmailRepository {
+	return &EmailRepository{db: db}
+}
+
+// GetConfig 获取当前邮件配置，未配置时返回nil
+func (r *EmailRepository) GetConfig() (*model.EmailConfig, error) {
+	config := &model.EmailConfig{}
+	var username, password, fromName, testEmail sql.NullString
+
+	err := r.db.QueryRow(`
+		SELECT id, smtp_host, smtp_port, username, password, from_email, from_name,
+		       enable_tls, enable_ssl, test_email, is_enabled, created_at, updated_at
+		FROM email_configs
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(
+		&config.ID, &config.SMTPHost, &config.SMTPPort, &username, &password,
+		&config.FromEmail, &fromName, &config.EnableTLS, &config.EnableSSL,
+		&testEmail, &config.IsEnabled, &config.CreatedAt, &config.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取邮件配置失败: %w", err)
+	}
+
+	config.Username = username.String
+	config.Password = password.String
+	config.FromName = fromName.String
+	config.TestEmail = testEmail.String
+	return config, nil
+}
+
+// SaveConfig 保存邮件配置，只保留一条配置记录
+func (r *EmailRepository) SaveConfig(config *model.EmailConfig) error {
+	existing, err := r.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		_, err = r.db.Exec(`
+			UPDATE email_configs
+			SET smtp_host = ?, smtp_port = ?, username = ?, password = ?, from_email = ?, from_name = ?,
+			    enable_tls = ?, enable_ssl = ?, test_email = ?, is_enabled = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, config.SMTPHost, config.SMTPPort, config.Username, config.Password, config.FromEmail, config.FromName,
+			config.EnableTLS, config.EnableSSL, config.TestEmail, config.IsEnabled, existing.ID)
+		if err != nil {
+			return fmt.Errorf("更新邮件配置失败: %w", err)
+		}
+		config.ID = existing.ID
+		return nil
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO email_configs (smtp_host, smtp_port, username, password, from_email, from_name,
+		                           enable_tls, enable_ssl, test_email, is_enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, config.SMTPHost, config.SMTPPort, config.Username, config.Password, config.FromEmail, config.FromName,
+		config.EnableTLS, config.EnableSSL, config.TestEmail, config.IsEnabled)
+	if err != nil {
+		return fmt.Errorf("创建邮件配置失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取邮件配置ID失败: %w", err)
+	}
+	config.ID = uint(id)
+	return nil
+}
+
+const emailTemplateColumns = `id, name, subject, body, html_body, type, is_default, created_at, updated_at`
+
+// scanEmailTemplate 扫描一行邮件模板
+func scanEmailTemplate(row rowScanner) (*model.EmailTemplate, error) {
+	template := &model.EmailTemplate{}
+	var htmlBody sql.NullString
+	err := row.Scan(
+		&template.ID, &template.Name, &template.Subject, &template.Body, &htmlBody,
+		&template.Type, &template.IsDefault, &template.CreatedAt, &template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	template.HTMLBody = htmlBody.String
+	return template, nil
+}
+
+// GetTemplates 获取全部邮件模板
+func (r *EmailRepository) GetTemplates() ([]*model.EmailTemplate, error) {
+	rows, err := r.db.Query(`SELECT ` + emailTemplateColumns + ` FROM email_templates ORDER BY type, is_default DESC, name`)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件模板失败: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]*model.EmailTemplate, 0)
+	for rows.Next() {
+		template, err := scanEmailTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描邮件模板失败: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// GetTemplateByID 根据ID获取邮件模板，不存在时返回nil
+func (r *EmailRepository) GetTemplateByID(id uint) (*model.EmailTemplate, error) {
+	template, err := scanEmailTemplate(r.db.QueryRow(`SELECT `+emailTemplateColumns+` FROM email_templates WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取邮件模板失败: %w", err)
+	}
+	return template, nil
+}
+
+// GetDefaultTemplate 获取指定类型的默认模板，不存在时返回nil
+func (r *EmailRepository) GetDefaultTemplate(templateType string) (*model.EmailTemplate, error) {
+	template, err := scanEmailTemplate(r.db.QueryRow(`
+		SELECT `+emailTemplateColumns+`
+		FROM email_templates
+		WHERE type = ?
+		ORDER BY is_default DESC, id
+		LIMIT 1
+	`, templateType))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取邮件模板失败: %w", err)
+	}
+	return template, nil
+}
+
+// CreateTemplate 创建邮件模板，设为默认时取消同类型其他模板的默认标记
+func (r *EmailRepository) CreateTemplate(template *model.EmailTemplate) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if template.IsDefault {
+		if _, err := tx.Exec("UPDATE email_templates SET is_default = 0 WHERE type = ?", template.Type); err != nil {
+			return fmt.Errorf("更新默认模板状态失败: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO email_templates (name, subject, body, html_body, type, is_default)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, template.Name, template.Subject, template.Body, template.HTMLBody, template.Type, template.IsDefault)
+	if err != nil {
+		return fmt.Errorf("创建邮件模板失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取邮件模板ID失败: %w", err)
+	}
+	template.ID = uint(id)
+
+	return tx.Commit()
+}
+
+// UpdateTemplate 更新邮件模板，设为默认时取消同类型其他模板的默认标记
+func (r *EmailRepository) UpdateTemplate(template *model.EmailTemplate) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if template.IsDefault {
+		if _, err := tx.Exec("UPDATE email_templates SET is_default = 0 WHERE type = ? AND id != ?", template.Type, template.ID); err != nil {
+			return fmt.Errorf("更新默认模板状态失败: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE email_templates
+		SET name = ?, subject = ?, body = ?, html_body = ?, type = ?, is_default = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, template.Name, template.Subject, template.Body, template.HTMLBody, template.Type, template.IsDefault, template.ID)
+	if err != nil {
+		return fmt.Errorf("更新邮件模板失败: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteTemplate 删除邮件模板
+func (r *EmailRepository) DeleteTemplate(id uint) error {
+	if _, err := r.db.Exec("DELETE FROM email_templates WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除邮件模板失败: %w", err)
+	}
+	return nil
+}
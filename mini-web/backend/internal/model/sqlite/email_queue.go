@@ -0,0 +1,187 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// EmailQueueRepository SQLite发信队列仓库实现
+type EmailQueueRepository struct {
+	db *sql.DB
+}
+
+// NewEmailQueueRepository 创建发信队列仓库实例
+func NewEmailQueueRepository(db *sql.DB) model.EmailQueueRepository {
+	return &EmailQueueRepository{db: db}
+}
+
+const emailQueueColumns = `id, recipient, subject, text_body, html_body, status, attempts, last_error, next_attempt_at, sent_at, created_at`
+
+// scanEmailMessage 扫描一行队列邮件
+func scanEmailMessage(row rowScanner) (*model.EmailMessage, error) {
+	message := &model.EmailMessage{}
+	var htmlBody, lastError sql.NullString
+	var sentAt sql.NullTime
+	err := row.Scan(
+		&message.ID, &message.To, &message.Subject, &message.TextBody, &htmlBody,
+		&message.Status, &message.Attempts, &lastError, &message.NextAttemptAt, &sentAt, &message.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	message.HTMLBody = htmlBody.String
+	message.LastError = lastError.String
+	if sentAt.Valid {
+		message.SentAt = &sentAt.Time
+	}
+	return message, nil
+}
+
+// Enqueue 将邮件加入队列
+func (r *EmailQueueRepository) Enqueue(message *model.EmailMessage) error {
+	if message.Status == "" {
+		message.Status = model.EmailStatusPending
+	}
+	if message.NextAttemptAt.IsZero() {
+		message.NextAttemptAt = time.Now()
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO email_queue (recipient, subject, text_body, html_body, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, message.To, message.Subject, message.TextBody, message.HTMLBody, message.Status, message.NextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("邮件加入发送队列失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取队列邮件ID失败: %w", err)
+	}
+	message.ID = uint(id)
+	return nil
+}
+
+// GetDue 获取到期待发送的邮件
+func (r *EmailQueueRepository) GetDue(now time.Time, limit int) ([]*model.EmailMessage, error) {
+	rows, err := r.db.Query(`
+		SELECT `+emailQueueColumns+`
+		FROM email_queue
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`, model.EmailStatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询待发送邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEmailMessages(rows)
+}
+
+// GetList 分页查询队列邮件，status为空时返回全部
+func (r *EmailQueueRepository) GetList(status string, limit, offset int) ([]*model.EmailMessage, int, error) {
+	where := ""
+	args := []interface{}{}
+	if status != "" {
+		where = " WHERE status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM email_queue"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计队列邮件失败: %w", err)
+	}
+
+	rows, err := r.db.Query("SELECT "+emailQueueColumns+" FROM email_queue"+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询队列邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	messages, err := scanEmailMessages(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// scanEmailMessages 扫描多行队列邮件
+func scanEmailMessages(rows *sql.Rows) ([]*model.EmailMessage, error) {
+	messages := make([]*model.EmailMessage, 0)
+	for rows.Next() {
+		message, err := scanEmailMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描队列邮件失败: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// MarkSent 标记邮件已发送
+func (r *EmailQueueRepository) MarkSent(id uint) error {
+	_, err := r.db.Exec(`
+		UPDATE email_queue
+		SET status = ?, attempts = attempts + 1, last_error = NULL, sent_at = ?
+		WHERE id = ?
+	`, model.EmailStatusSent, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("更新邮件发送状态失败: %w", err)
+	}
+	return nil
+}
+
+// MarkRetry 记录一次发送失败并安排下次重试
+func (r *EmailQueueRepository) MarkRetry(id uint, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE email_queue
+		SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`, lastError, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("更新邮件重试状态失败: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed 记录最后一次发送失败并放弃发送
+func (r *EmailQueueRepository) MarkFailed(id uint, lastError string) error {
+	_, err := r.db.Exec(`
+		UPDATE email_queue
+		SET status = ?, attempts = attempts + 1, last_error = ?
+		WHERE id = ?
+	`, model.EmailStatusFailed, lastError, id)
+	if err != nil {
+		return fmt.Errorf("更新邮件发送状态失败: %w", err)
+	}
+	return nil
+}
+
+// Requeue 将发送失败的邮件重新放回队列并清零重试次数，返回是否有邮件被放回
+func (r *EmailQueueRepository) Requeue(id uint) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE email_queue
+		SET status = ?, attempts = 0, next_attempt_at = ?
+		WHERE id = ? AND status = ?
+	`, model.EmailStatusPending, time.Now(), id, model.EmailStatusFailed)
+	if err != nil {
+		return false, fmt.Errorf("重新发送邮件失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("重新发送邮件失败: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// DeleteSentBefore 删除指定时间之前已发送的邮件
+func (r *EmailQueueRepository) DeleteSentBefore(before time.Time) error {
+	if _, err := r.db.Exec("DELETE FROM email_queue WHERE status = ? AND sent_at < ?", model.EmailStatusSent, before); err != nil {
+		return fmt.Errorf("清理已发送邮件失败: %w", err)
+	}
+	return nil
+}
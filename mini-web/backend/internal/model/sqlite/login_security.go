@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// LoginSecurityRepository SQLite登录安全仓库实现
+type LoginSecurityRepository struct {
+	db *sql.DB
+}
+
+// NewLoginSecurityRepository 创建登录安全仓库实例
+func NewLoginSecurityRepository(db *sql.DB) model.LoginSecurityRepository {
+	return &LoginSecurityRepository{db: db}
+}
+
+// RecordFailure 记录一次登录失败，返回连续失败次数
+func (r *LoginSecurityRepository) RecordFailure(userID uint) (int, error) {
+	var attempts int
+	err := r.db.QueryRow(`
+		INSERT INTO login_lockouts (user_id, failed_attempts, updated_at)
+		VALUES (?, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			failed_attempts = failed_attempts + 1,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING failed_attempts
+	`, userID).Scan(&attempts)
+	if err != nil {
+		return 0, fmt.Errorf("记录登录失败次数失败: %w", err)
+	}
+	return attempts, nil
+}
+
+// Lock 锁定账号到指定时间并清零失败次数
+func (r *LoginSecurityRepository) Lock(userID uint, until time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO login_lockouts (user_id, failed_attempts, locked_until, updated_at)
+		VALUES (?, 0, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			failed_attempts = 0,
+			locked_until = excluded.locked_until,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, until)
+	if err != nil {
+		return fmt.Errorf("锁定账号失败: %w", err)
+	}
+	return nil
+}
+
+// GetLockedUntil 获取账号锁定截止时间，未锁定时返回nil
+func (r *LoginSecurityRepository) GetLockedUntil(userID uint) (*time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRow("SELECT locked_until FROM login_lockouts WHERE user_id = ?", userID).Scan(&lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询账号锁定状态失败: %w", err)
+	}
+	if !lockedUntil.Valid {
+		return nil, nil
+	}
+	return &lockedUntil.Time, nil
+}
+
+// Reset 清零失败次数并解除锁定
+func (r *LoginSecurityRepository) Reset(userID uint) error {
+	if _, err := r.db.Exec("DELETE FROM login_lockouts WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("解除账号锁定失败: %w", err)
+	}
+	return nil
+}
+
+// RecordLoginIP 记录登录IP，返回该IP是否首次出现以及此前已知的IP数量
+func (r *LoginSecurityRepository) RecordLoginIP(userID uint, ip string) (bool, int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var known int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM user_login_ips WHERE user_id = ?", userID).Scan(&known); err != nil {
+		return false, 0, fmt.Errorf("查询登录IP失败: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO user_login_ips (user_id, ip_address, first_seen_at, last_seen_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, ip_address) DO NOTHING
+	`, userID, ip)
+	if err != nil {
+		return false, 0, fmt.Errorf("记录登录IP失败: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, fmt.Errorf("记录登录IP失败: %w", err)
+	}
+	if inserted == 0 {
+		_, err = tx.Exec("UPDATE user_login_ips SET last_seen_at = CURRENT_TIMESTAMP WHERE user_id = ? AND ip_address = ?", userID, ip)
+		if err != nil {
+			return false, 0, fmt.Errorf("更新登录IP失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return inserted > 0, known, nil
+}
+
+// DeleteByUserID 删除用户的全部登录安全记录
+func (r *LoginSecurityRepository) DeleteByUserID(userID uint) error {
+	if _, err := r.db.Exec("DELETE FROM login_lockouts WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除账号锁定记录失败: %w", err)
+	}
+	if _, err := r.db.Exec("DELETE FROM user_login_ips WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除登录IP记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// NotificationChannelRepository SQLite通知渠道仓库实现
+type NotificationChannelRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationChannelRepository 创建通知渠道仓库实例
+func NewNotificationChannelRepository(db *sql.DB) model.NotificationChannelRepository {
+	return &NotificationChannelRepository{db: db}
+}
+
+const notificationChannelColumns = `id, user_id, name, type, target, COALESCE(secret, ''), COALESCE(events, ''), is_enabled, created_at, updated_at`
+
+// scanNotificationChannel 扫描一行通知渠道
+func scanNotificationChannel(row rowScanner) (*model.NotificationChannel, error) {
+	channel := &model.NotificationChannel{}
+	err := row.Scan(
+		&channel.ID, &channel.UserID, &channel.Name, &channel.Type, &channel.Target,
+		&channel.Secret, &channel.Events, &channel.IsEnabled, &channel.CreatedAt, &channel.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	channel.HasSecret = channel.Secret != ""
+	return channel, nil
+}
+
+// queryNotificationChannels 查询通知渠道列表
+func (r *NotificationChannelRepository) queryNotificationChannels(query string, args ...interface{}) ([]*model.NotificationChannel, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询通知渠道失败: %w", err)
+	}
+	defer rows.Close()
+
+	channels := make([]*model.NotificationChannel, 0)
+	for rows.Next() {
+		channel, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描通知渠道失败: %w", err)
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+// Create 创建通知渠道
+func (r *NotificationChannelRepository) Create(channel *model.NotificationChannel) error {
+	result, err := r.db.Exec(`
+		INSERT INTO notification_channels (user_id, name, type, target, secret, events, is_enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, channel.UserID, channel.Name, channel.Type, channel.Target, channel.Secret, channel.Events, channel.IsEnabled)
+	if err != nil {
+		return fmt.Errorf("创建通知渠道失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取通知渠道ID失败: %w", err)
+	}
+	channel.ID = uint(id)
+	channel.CreatedAt = time.Now()
+	channel.UpdatedAt = channel.CreatedAt
+	return nil
+}
+
+// Update 更新通知渠道
+func (r *NotificationChannelRepository) Update(channel *model.NotificationChannel) error {
+	_, err := r.db.Exec(`
+		UPDATE notification_channels
+		SET name = ?, type = ?, target = ?, secret = ?, events = ?, is_enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, channel.Name, channel.Type, channel.Target, channel.Secret, channel.Events, channel.IsEnabled, channel.ID)
+	if err != nil {
+		return fmt.Errorf("更新通知渠道失败: %w", err)
+	}
+	channel.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete 删除通知渠道
+func (r *NotificationChannelRepository) Delete(id uint) error {
+	if _, err := r.db.Exec("DELETE FROM notification_channels WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除通知渠道失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取通知渠道，不存在时返回nil
+func (r *NotificationChannelRepository) GetByID(id uint) (*model.NotificationChannel, error) {
+	channel, err := scanNotificationChannel(r.db.QueryRow(`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取通知渠道失败: %w", err)
+	}
+	return channel, nil
+}
+
+// GetByUserID 获取用户的通知渠道，userID为0时返回系统渠道
+func (r *NotificationChannelRepository) GetByUserID(userID uint) ([]*model.NotificationChannel, error) {
+	return r.queryNotificationChannels(`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// GetEnabledForUser 获取应接收该用户相关事件的已启用渠道：系统渠道和该用户的个人渠道
+func (r *NotificationChannelRepository) GetEnabledForUser(userID uint) ([]*model.NotificationChannel, error) {
+	return r.queryNotificationChannels(`
+		SELECT `+notificationChannelColumns+`
+		FROM notification_channels
+		WHERE is_enabled = 1 AND (user_id = 0 OR user_id = ?)
+		ORDER BY id
+	`, userID)
+}
+
+// DeleteByUserID 删除用户的全部个人渠道及其投递记录
+func (r *NotificationChannelRepository) DeleteByUserID(userID uint) error {
+	if userID == 0 {
+		return nil
+	}
+	_, err := r.db.Exec(`
+		DELETE FROM notification_deliveries
+		WHERE channel_id IN (SELECT id FROM notification_channels WHERE user_id = ?)
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("删除通知投递记录失败: %w", err)
+	}
+	if _, err := r.db.Exec("DELETE FROM notification_channels WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除通知渠道失败: %w", err)
+	}
+	return nil
+}
+
+// NotificationDeliveryRepository SQLite通知投递仓库实现
+type NotificationDeliveryRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationDeliveryRepository 创建通知投递仓库实例
+func NewNotificationDeliveryRepository(db *sql.DB) model.NotificationDeliveryRepository {
+	return &NotificationDeliveryRepository{db: db}
+}
+
+const notificationDeliveryColumns = `id, channel_id, event_type, payload, status, attempts, last_error, next_attempt_at, delivered_at, created_at`
+
+// scanNotificationDelivery 扫描一行投递记录
+func scanNotificationDelivery(row rowScanner) (*model.NotificationDelivery, error) {
+	delivery := &model.NotificationDelivery{}
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+	err := row.Scan(
+		&delivery.ID, &delivery.ChannelID, &delivery.EventType, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &lastError, &delivery.NextAttemptAt, &deliveredAt, &delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, nil
+}
+
+// queryNotificationDeliveries 查询投递记录列表
+func (r *NotificationDeliveryRepository) queryNotificationDeliveries(query string, args ...interface{}) ([]*model.NotificationDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询通知投递记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*model.NotificationDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanNotificationDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描通知投递记录失败: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Create 创建投递记录
+func (r *NotificationDeliveryRepository) Create(delivery *model.NotificationDelivery) error {
+	if delivery.Status == "" {
+		delivery.Status = model.DeliveryStatusPending
+	}
+	if delivery.NextAttemptAt.IsZero() {
+		delivery.NextAttemptAt = time.Now()
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO notification_deliveries (channel_id, event_type, payload, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, delivery.ChannelID, delivery.EventType, delivery.Payload, delivery.Status, delivery.NextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("创建通知投递记录失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取通知投递记录ID失败: %w", err)
+	}
+	delivery.ID = uint(id)
+	return nil
+}
+
+// GetDue 获取到期待投递的记录
+func (r *NotificationDeliveryRepository) GetDue(now time.Time, limit int) ([]*model.NotificationDelivery, error) {
+	return r.queryNotificationDeliveries(`
+		SELECT `+notificationDeliveryColumns+`
+		FROM notification_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`, model.DeliveryStatusPending, now, limit)
+}
+
+// GetByChannelID 获取渠道最近的投递记录
+func (r *NotificationDeliveryRepository) GetByChannelID(channelID uint, limit int) ([]*model.NotificationDelivery, error) {
+	return r.queryNotificationDeliveries(`
+		SELECT `+notificationDeliveryColumns+`
+		FROM notification_deliveries
+		WHERE channel_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, channelID, limit)
+}
+
+// MarkDelivered 标记投递成功
+func (r *NotificationDeliveryRepository) MarkDelivered(id uint) error {
+	_, err := r.db.Exec(`
+		UPDATE notification_deliveries
+		SET status = ?, attempts = attempts + 1, last_error = NULL, delivered_at = ?
+		WHERE id = ?
+	`, model.DeliveryStatusDelivered, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("更新通知投递状态失败: %w", err)
+	}
+	return nil
+}
+
+// MarkRetry 记录一次投递失败并安排下次重试
+func (r *NotificationDeliveryRepository) MarkRetry(id uint, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE notification_deliveries
+		SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`, lastError, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("更新通知投递状态失败: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed 记录最后一次投递失败并放弃
+func (r *NotificationDeliveryRepository) MarkFailed(id uint, lastError string) error {
+	_, err := r.db.Exec(`
+		UPDATE notification_deliveries
+		SET status = ?, attempts = attempts + 1, last_error = ?
+		WHERE id = ?
+	`, model.DeliveryStatusFailed, lastError, id)
+	if err != nil {
+		return fmt.Errorf("更新通知投递状态失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteByChannelID 删除渠道的全部投递记录
+func (r *NotificationDeliveryRepository) DeleteByChannelID(channelID uint) error {
+	if _, err := r.db.Exec("DELETE FROM notification_deliveries WHERE channel_id = ?", channelID); err != nil {
+		return fmt.Errorf("删除通知投递记录失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteDeliveredBefore 删除指定时间之前已投递成功的记录
+func (r *NotificationDeliveryRepository) DeleteDeliveredBefore(before time.Time) error {
+	_, err := r.db.Exec("DELETE FROM notification_deliveries WHERE status = ? AND delivered_at < ?", model.DeliveryStatusDelivered, before)
+	if err != nil {
+		return fmt.Errorf("清理通知投递记录失败: %w", err)
+	}
+	return nil
+}
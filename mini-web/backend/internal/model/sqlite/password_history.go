@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// PasswordHistoryRepository SQLite密码历史仓库实现
+type PasswordHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordHistoryRepository 创建密码历史仓库实例
+func NewPasswordHistoryRepository(db *sql.DB) model.PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// Add 记录一个已使用的密码哈希
+func (r *PasswordHistoryRepository) Add(userID uint, passwordHash string) error {
+	_, err := r.db.Exec(
+		`INSERT INTO password_history (user_id, password_hash) VALUES (?, ?)`,
+		userID, passwordHash,
+	)
+	if err != nil {
+		return fmt.Errorf("保存密码历史失败: %w", err)
+	}
+	return nil
+}
+
+// GetRecent 获取用户最近使用过的密码哈希，按时间倒序
+func (r *PasswordHistoryRepository) GetRecent(userID uint, limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT password_hash FROM password_history
+		WHERE user_id = ?
+		ORDER BY id DESC
+		LIMIT ?`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询密码历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("扫描密码历史失败: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// Prune 只保留用户最近keep条密码历史
+func (r *PasswordHistoryRepository) Prune(userID uint, keep int) error {
+	_, err := r.db.Exec(`
+		DELETE FROM password_history
+		WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?
+		)`,
+		userID, userID, keep,
+	)
+	if err != nil {
+		return fmt.Errorf("清理密码历史失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteByUserID 删除用户的全部密码历史
+func (r *PasswordHistoryRepository) DeleteByUserID(userID uint) error {
+	_, err := r.db.Exec(`DELETE FROM password_history WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("删除密码历史失败: %w", err)
+	}
+	return nil
+}
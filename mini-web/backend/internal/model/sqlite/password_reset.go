@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// PasswordResetRepository SQLite找回密码令牌仓库实现
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository 创建找回密码令牌仓库实例
+func NewPasswordResetRepository(db *sql.DB) model.PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create 保存找回密码令牌
+func (r *PasswordResetRepository) Create(token *model.PasswordResetToken) error {
+	token.CreatedAt = time.Now()
+
+	result, err := r.db.Exec(`
+		INSERT INTO password_reset_tokens (user_id, token_hash, ip_address, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		token.UserID, token.TokenHash, token.IPAddress, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("保存找回密码令牌失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+	token.ID = uint(id)
+	return nil
+}
+
+// GetByHash 根据令牌哈希获取找回密码令牌，不存在时返回nil
+func (r *PasswordResetRepository) GetByHash(tokenHash string) (*model.PasswordResetToken, error) {
+	token := &model.PasswordResetToken{}
+	var ipAddress sql.NullString
+	var usedAt sql.NullTime
+
+	err := r.db.QueryRow(`
+		SELECT id, user_id, token_hash, ip_address, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = ?`, tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.TokenHash, &ipAddress, &token.ExpiresAt, &usedAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询找回密码令牌失败: %w", err)
+	}
+
+	token.IPAddress = ipAddress.String
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
+
+// MarkUsed 将令牌标记为已使用，返回false表示令牌已被使用
+func (r *PasswordResetRepository) MarkUsed(id uint) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE password_reset_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL`,
+		time.Now(), id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("更新找回密码令牌失败: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// InvalidateByUserID 作废用户所有未使用的令牌
+func (r *PasswordResetRepository) InvalidateByUserID(userID uint) error {
+	_, err := r.db.Exec(
+		`UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL`,
+		time.Now(), userID,
+	)
+	if err != nil {
+		return fmt.Errorf("作废找回密码令牌失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired 清理过期的找回密码令牌
+func (r *PasswordResetRepository) DeleteExpired(before time.Time) error {
+	if _, err := r.db.Exec("DELETE FROM password_reset_tokens WHERE expires_at < ?", before); err != nil {
+		return fmt.Errorf("清理过期找回密码令牌失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// RefreshTokenRepository SQLite刷新令牌仓库实现
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+// NewRefreshTokenRepository 创建刷新令牌仓库实例
+func NewRefreshTokenRepository(db *sql.DB) model.RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+const refreshTokenColumns = `id, user_id, family_id, token_hash, access_jti, user_agent,
+		   ip_address, expires_at, used_at, revoked_at, created_at`
+
+// Create 保存刷新令牌
+func (r *RefreshTokenRepository) Create(token *model.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (
+			user_id, family_id, token_hash, access_jti, user_agent,
+			ip_address, expires_at, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	token.CreatedAt = time.Now()
+
+	result, err := r.db.Exec(query,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.AccessJTI,
+		token.UserAgent,
+		token.IPAddress,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("保存刷新令牌失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+
+	token.ID = uint(id)
+	return nil
+}
+
+// GetByHash 根据令牌哈希获取刷新令牌
+func (r *RefreshTokenRepository) GetByHash(tokenHash string) (*model.RefreshToken, error) {
+	query := `SELECT ` + refreshTokenColumns + `
+		FROM refresh_tokens
+		WHERE token_hash = ?`
+
+	token, err := scanRefreshToken(r.db.QueryRow(query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询刷新令牌失败: %w", err)
+	}
+
+	return token, nil
+}
+
+// MarkUsed 将刷新令牌标记为已使用，返回false表示令牌已被使用或吊销
+func (r *RefreshTokenRepository) MarkUsed(id uint) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE refresh_tokens SET used_at = ?
+		WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`,
+		time.Now(), id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("更新刷新令牌失败: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// RevokeFamily 吊销整个令牌家族，返回家族中的全部令牌
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) ([]*model.RefreshToken, error) {
+	return r.revoke("family_id = ?", familyID)
+}
+
+// RevokeByUserID 吊销用户的全部刷新令牌，返回被吊销的令牌
+func (r *RefreshTokenRepository) RevokeByUserID(userID uint) ([]*model.RefreshToken, error) {
+	return r.revoke("user_id = ? AND revoked_at IS NULL", userID)
+}
+
+// GetActiveByUserID 获取用户当前有效的刷新令牌，即已登录的设备
+func (r *RefreshTokenRepository) GetActiveByUserID(userID uint) ([]*model.RefreshToken, error) {
+	return r.query(r.db, `SELECT `+refreshTokenColumns+`
+		FROM refresh_tokens
+		WHERE user_id = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY created_at DESC`, userID, time.Now())
+}
+
+// DeleteExpired 清理过期的刷新令牌
+func (r *RefreshTokenRepository) DeleteExpired(before time.Time) error {
+	if _, err := r.db.Exec("DELETE FROM refresh_tokens WHERE expires_at < ?", before); err != nil {
+		return fmt.Errorf("清理过期刷新令牌失败: %w", err)
+	}
+	return nil
+}
+
+// revoke 在事务中读取并吊销符合条件的令牌
+func (r *RefreshTokenRepository) revoke(where string, arg interface{}) ([]*model.RefreshToken, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	tokens, err := r.query(tx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE `+where, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = ?
+		WHERE revoked_at IS NULL AND `+where, time.Now(), arg); err != nil {
+		return nil, fmt.Errorf("吊销刷新令牌失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return tokens, nil
+}
+
+// query 查询刷新令牌列表
+func (r *RefreshTokenRepository) query(q queryer, query string, args ...interface{}) ([]*model.RefreshToken, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询刷新令牌失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*model.RefreshToken
+	for rows.Next() {
+		token, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描刷新令牌失败: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// queryer 数据库与事务共同的查询接口
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// scanRefreshToken 扫描一条刷新令牌记录
+func scanRefreshToken(scanner rowScanner) (*model.RefreshToken, error) {
+	token := &model.RefreshToken{}
+	var userAgent, ipAddress sql.NullString
+	var usedAt, revokedAt sql.NullTime
+
+	err := scanner.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.AccessJTI,
+		&userAgent,
+		&ipAddress,
+		&token.ExpiresAt,
+		&usedAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.UserAgent = userAgent.String
+	token.IPAddress = ipAddress.String
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+// RevokedTokenRepository SQLite访问令牌吊销名单仓库实现
+type RevokedTokenRepository struct {
+	db *sql.DB
+}
+
+// NewRevokedTokenRepository 创建访问令牌吊销名单仓库实例
+func NewRevokedTokenRepository(db *sql.DB) model.RevokedTokenRepository {
+	return &RevokedTokenRepository{db: db}
+}
+
+// Add 将访问令牌加入吊销名单
+func (r *RevokedTokenRepository) Add(token *model.RevokedToken) error {
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(jti) DO NOTHING`,
+		token.JTI, token.UserID, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("吊销访问令牌失败: %w", err)
+	}
+	return nil
+}
+
+// GetActive 获取尚未过期的吊销记录
+func (r *RevokedTokenRepository) GetActive(now time.Time) ([]*model.RevokedToken, error) {
+	rows, err := r.db.Query(`
+		SELECT jti, user_id, expires_at, created_at
+		FROM revoked_tokens
+		WHERE expires_at > ?`, now)
+	if err != nil {
+		return nil, fmt.Errorf("查询访问令牌吊销名单失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*model.RevokedToken
+	for rows.Next() {
+		token := &model.RevokedToken{}
+		if err := rows.Scan(&token.JTI, &token.UserID, &token.ExpiresAt, &token.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描访问令牌吊销记录失败: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// DeleteExpired 清理已过期的吊销记录
+func (r *RevokedTokenRepository) DeleteExpired(before time.Time) error {
+	if _, err := r.db.Exec("DELETE FROM revoked_tokens WHERE expires_at < ?", before); err != nil {
+		return fmt.Errorf("清理访问令牌吊销名单失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// RoleRepository SQLite角色仓库实现
+type RoleRepository struct {
+	db *sql.DB
+}
+
+// NewRoleRepository 创建角色仓库实例
+func NewRoleRepository(db *sql.DB) model.RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+const roleColumns = `r.id, r.name, COALESCE(r.description, ''), r.built_in, r.version,
+		   (SELECT COUNT(*) FROM users u WHERE u.role = r.name),
+		   r.created_at, r.updated_at`
+
+// Create 创建角色及其权限
+func (r *RoleRepository) Create(role *model.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	role.Version = 1
+	role.CreatedAt = now
+	role.UpdatedAt = now
+
+	result, err := tx.Exec(
+		"INSERT INTO roles (name, description, built_in, version, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		role.Name, role.Description, role.BuiltIn, role.Version, role.CreatedAt, role.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("创建角色失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+	role.ID = uint(id)
+
+	if err := saveRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update 更新角色描述和权限，并递增权限版本
+func (r *RoleRepository) Update(role *model.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	role.UpdatedAt = time.Now()
+	_, err = tx.Exec(
+		"UPDATE roles SET description = ?, version = version + 1, updated_at = ? WHERE id = ?",
+		role.Description, role.UpdatedAt, role.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新角色失败: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", role.ID); err != nil {
+		return fmt.Errorf("清除角色权限失败: %w", err)
+	}
+	if err := saveRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRow("SELECT version FROM roles WHERE id = ?", role.ID).Scan(&role.Version); err != nil {
+		return fmt.Errorf("查询角色版本失败: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Delete 删除角色及其权限
+func (r *RoleRepository) Delete(id uint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", id); err != nil {
+		return fmt.Errorf("删除角色权限失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM roles WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除角色失败: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetByID 根据ID获取角色
+func (r *RoleRepository) GetByID(id uint) (*model.Role, error) {
+	return r.getRole(`SELECT `+roleColumns+` FROM roles r WHERE r.id = ?`, id)
+}
+
+// GetByName 根据名称获取角色
+func (r *RoleRepository) GetByName(name string) (*model.Role, error) {
+	return r.getRole(`SELECT `+roleColumns+` FROM roles r WHERE r.name = ?`, name)
+}
+
+// GetAll 获取全部角色
+func (r *RoleRepository) GetAll() ([]*model.Role, error) {
+	rows, err := r.db.Query(`SELECT ` + roleColumns + ` FROM roles r ORDER BY r.built_in DESC, r.name`)
+	if err != nil {
+		return nil, fmt.Errorf("查询角色失败: %w", err)
+	}
+
+	var roles []*model.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("扫描角色失败: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		if role.Permissions, err = r.getPermissions(role.ID); err != nil {
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+// getRole 查询单个角色及其权限
+func (r *RoleRepository) getRole(query string, arg interface{}) (*model.Role, error) {
+	role, err := scanRole(r.db.QueryRow(query, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询角色失败: %w", err)
+	}
+
+	if role.Permissions, err = r.getPermissions(role.ID); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// getPermissions 查询角色的权限列表
+func (r *RoleRepository) getPermissions(roleID uint) ([]string, error) {
+	rows, err := r.db.Query("SELECT permission FROM role_permissions WHERE role_id = ? ORDER BY permission", roleID)
+	if err != nil {
+		return nil, fmt.Errorf("查询角色权限失败: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("扫描角色权限失败: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// saveRolePermissions 写入角色权限
+func saveRolePermissions(tx *sql.Tx, roleID uint, permissions []string) error {
+	for _, permission := range permissions {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO role_permissions (role_id, permission) VALUES (?, ?)", roleID, permission); err != nil {
+			return fmt.Errorf("保存角色权限失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// scanRole 扫描一条角色记录（不含权限列表）
+func scanRole(scanner rowScanner) (*model.Role, error) {
+	role := &model.Role{}
+	err := scanner.Scan(
+		&role.ID,
+		&role.Name,
+		&role.Description,
+		&role.BuiltIn,
+		&role.Version,
+		&role.UserCount,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// ScheduledJobRepository SQLite定时任务仓库实现
+type ScheduledJobRepository struct {
+	db *sql.DB
+}
+
+// NewScheduledJobRepository 创建定时任务仓库实例
+func NewScheduledJobRepository(db *sql.DB) model.ScheduledJobRepository {
+	return &ScheduledJobRepository{db: db}
+}
+
+const scheduledJobColumns = `id, user_id, name, cron_expr, COALESCE(connection_ids, ''), COALESCE(command, ''),
+		   COALESCE(script, ''), sudo, COALESCE(sudo_user, ''), credential_id, parallelism, timeout_seconds,
+		   max_retries, retry_delay_seconds, overlap_policy, retention_runs, notify_on_failure, is_enabled,
+		   last_run_at, COALESCE(last_status, ''), COALESCE(last_error, ''), COALESCE(last_batch_job_id, 0),
+		   created_at, updated_at`
+
+// scanScheduledJob 扫描一行定时任务
+func scanScheduledJob(row rowScanner) (*model.ScheduledJob, error) {
+	job := &model.ScheduledJob{}
+	var connectionIDs string
+	var lastRunAt sql.NullTime
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.Name, &job.CronExpr, &connectionIDs, &job.Command,
+		&job.Script, &job.Sudo, &job.SudoUser, &job.CredentialID, &job.Parallelism, &job.TimeoutSeconds,
+		&job.MaxRetries, &job.RetryDelaySeconds, &job.OverlapPolicy, &job.RetentionRuns, &job.NotifyOnFailure, &job.IsEnabled,
+		&lastRunAt, &job.LastStatus, &job.LastError, &job.LastBatchJobID,
+		&job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.ConnectionIDs = splitIDs(connectionIDs)
+	if lastRunAt.Valid {
+		job.LastRunAt = &lastRunAt.Time
+	}
+	return job, nil
+}
+
+// joinIDs 将ID列表保存为逗号分隔的字符串
+func joinIDs(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitIDs 解析逗号分隔的ID列表，忽略无效项
+func splitIDs(value string) []uint {
+	ids := make([]uint, 0)
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err == nil && id != 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// Create 创建定时任务
+func (r *ScheduledJobRepository) Create(job *model.ScheduledJob) error {
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	result, err := r.db.Exec(`
+		INSERT INTO scheduled_jobs (
+			user_id, name, cron_expr, connection_ids, command, script, sudo, sudo_user, credential_id,
+			parallelism, timeout_seconds, max_retries, retry_delay_seconds, overlap_policy, retention_runs,
+			notify_on_failure, is_enabled, created_at, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.UserID, job.Name, job.CronExpr, joinIDs(job.ConnectionIDs), job.Command, job.Script, job.Sudo, job.SudoUser, job.CredentialID,
+		job.Parallelism, job.TimeoutSeconds, job.MaxRetries, job.RetryDelaySeconds, job.OverlapPolicy, job.RetentionRuns,
+		job.NotifyOnFailure, job.IsEnabled, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("创建定时任务失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取定时任务ID失败: %w", err)
+	}
+	job.ID = uint(id)
+	return nil
+}
+
+// Update 更新定时任务配置
+func (r *ScheduledJobRepository) Update(job *model.ScheduledJob) error {
+	job.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		UPDATE scheduled_jobs
+		SET name = ?, cron_expr = ?, connection_ids = ?, command = ?, script = ?, sudo = ?, sudo_user = ?,
+			credential_id = ?, parallelism = ?, timeout_seconds = ?, max_retries = ?, retry_delay_seconds = ?,
+			overlap_policy = ?, retention_runs = ?, notify_on_failure = ?, is_enabled = ?, updated_at = ?
+		WHERE id = ?
+	`, job.Name, job.CronExpr, joinIDs(job.ConnectionIDs), job.Command, job.Script, job.Sudo, job.SudoUser,
+		job.CredentialID, job.Parallelism, job.TimeoutSeconds, job.MaxRetries, job.RetryDelaySeconds,
+		job.OverlapPolicy, job.RetentionRuns, job.NotifyOnFailure, job.IsEnabled, job.UpdatedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("更新定时任务失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除定时任务
+func (r *ScheduledJobRepository) Delete(id uint) error {
+	if _, err := r.db.Exec("DELETE FROM scheduled_jobs WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除定时任务失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取定时任务，不存在时返回nil
+func (r *ScheduledJobRepository) GetByID(id uint) (*model.ScheduledJob, error) {
+	job, err := scanScheduledJob(r.db.QueryRow(`SELECT `+scheduledJobColumns+` FROM scheduled_jobs WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取定时任务失败: %w", err)
+	}
+	return job, nil
+}
+
+// GetByUserID 获取用户创建的定时任务
+func (r *ScheduledJobRepository) GetByUserID(userID uint) ([]*model.ScheduledJob, error) {
+	return r.query(`SELECT `+scheduledJobColumns+` FROM scheduled_jobs WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// GetEnabled 获取全部已启用的定时任务
+func (r *ScheduledJobRepository) GetEnabled() ([]*model.ScheduledJob, error) {
+	return r.query(`SELECT ` + scheduledJobColumns + ` FROM scheduled_jobs WHERE is_enabled = 1 ORDER BY id`)
+}
+
+// UpdateLastRun 保存最近一次执行结果
+func (r *ScheduledJobRepository) UpdateLastRun(job *model.ScheduledJob) error {
+	_, err := r.db.Exec(`
+		UPDATE scheduled_jobs
+		SET last_run_at = ?, last_status = ?, last_error = ?, last_batch_job_id = ?
+		WHERE id = ?
+	`, job.LastRunAt, job.LastStatus, job.LastError, job.LastBatchJobID, job.ID)
+	if err != nil {
+		return fmt.Errorf("更新定时任务执行结果失败: %w", err)
+	}
+	return nil
+}
+
+// query 查询定时任务列表
+func (r *ScheduledJobRepository) query(query string, args ...interface{}) ([]*model.ScheduledJob, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询定时任务失败: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*model.ScheduledJob, 0)
+	for rows.Next() {
+		job, err := scanScheduledJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描定时任务失败: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,224 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// ftsMinTermLength trigram分词最短可匹配的关键词长度，更短的关键词改用LIKE匹配
+const ftsMinTermLength = 3
+
+// SnippetRepository SQLite命令片段仓库实现
+type SnippetRepository struct {
+	db *sql.DB
+}
+
+// NewSnippetRepository 创建命令片段仓库实例
+func NewSnippetRepository(db *sql.DB) model.SnippetRepository {
+	return &SnippetRepository{db: db}
+}
+
+// snippetColumns 查询时snippets表的别名为s，避免与全文索引表的同名列冲突
+const snippetColumns = `s.id, s.name, s.description, s.content, s.parameters, s.scope, s.owner_id,
+		   COALESCE(s.team_id, 0), s.tags, s.usage_count, s.last_used_at, s.created_at, s.updated_at`
+
+// scanSnippet 扫描一行命令片段
+func scanSnippet(row rowScanner) (*model.Snippet, error) {
+	snippet := &model.Snippet{}
+	var parameters string
+	var lastUsedAt sql.NullTime
+	err := row.Scan(
+		&snippet.ID, &snippet.Name, &snippet.Description, &snippet.Content, &parameters, &snippet.Scope, &snippet.OwnerID,
+		&snippet.TeamID, &snippet.Tags, &snippet.UsageCount, &lastUsedAt, &snippet.CreatedAt, &snippet.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(parameters), &snippet.Parameters); err != nil {
+		return nil, fmt.Errorf("解析命令片段参数失败: %w", err)
+	}
+	if snippet.Parameters == nil {
+		snippet.Parameters = []model.SnippetParameter{}
+	}
+	if lastUsedAt.Valid {
+		snippet.LastUsedAt = &lastUsedAt.Time
+	}
+	return snippet, nil
+}
+
+// Create 创建命令片段
+func (r *SnippetRepository) Create(snippet *model.Snippet) error {
+	parameters, err := json.Marshal(snippet.Parameters)
+	if err != nil {
+		return fmt.Errorf("序列化命令片段参数失败: %w", err)
+	}
+
+	now := time.Now()
+	snippet.CreatedAt = now
+	snippet.UpdatedAt = now
+
+	result, err := r.db.Exec(`
+		INSERT INTO snippets (name, description, content, parameters, scope, owner_id, team_id, tags, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, snippet.Name, snippet.Description, snippet.Content, string(parameters), snippet.Scope, snippet.OwnerID,
+		nullableID(snippet.TeamID), snippet.Tags, snippet.CreatedAt, snippet.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("创建命令片段失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取命令片段ID失败: %w", err)
+	}
+	snippet.ID = uint(id)
+	return nil
+}
+
+// Update 更新命令片段，不修改使用统计
+func (r *SnippetRepository) Update(snippet *model.Snippet) error {
+	parameters, err := json.Marshal(snippet.Parameters)
+	if err != nil {
+		return fmt.Errorf("序列化命令片段参数失败: %w", err)
+	}
+
+	snippet.UpdatedAt = time.Now()
+
+	_, err = r.db.Exec(`
+		UPDATE snippets
+		SET name = ?, description = ?, content = ?, parameters = ?, scope = ?, team_id = ?, tags = ?, updated_at = ?
+		WHERE id = ?
+	`, snippet.Name, snippet.Description, snippet.Content, string(parameters), snippet.Scope,
+		nullableID(snippet.TeamID), snippet.Tags, snippet.UpdatedAt, snippet.ID)
+	if err != nil {
+		return fmt.Errorf("更新命令片段失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除命令片段
+func (r *SnippetRepository) Delete(id uint) error {
+	if _, err := r.db.Exec("DELETE FROM snippets WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除命令片段失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取命令片段，不存在时返回nil
+func (r *SnippetRepository) GetByID(id uint) (*model.Snippet, error) {
+	snippet, err := scanSnippet(r.db.QueryRow(`SELECT `+snippetColumns+` FROM snippets s WHERE s.id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取命令片段失败: %w", err)
+	}
+	return snippet, nil
+}
+
+// GetVisible 获取用户可见的命令片段，常用的排在前面
+func (r *SnippetRepository) GetVisible(userID uint, teamIDs []uint) ([]*model.Snippet, error) {
+	visible, args := snippetVisibility(userID, teamIDs)
+	return r.query(`SELECT `+snippetColumns+` FROM snippets s
+		WHERE `+visible+`
+		ORDER BY s.usage_count DESC, s.name`, args...)
+}
+
+// Search 全文搜索用户可见的命令片段，多个关键词之间为且的关系。
+// 关键词都不短于3个字符时使用全文索引并按相关度排序，否则逐列LIKE匹配
+func (r *SnippetRepository) Search(userID uint, teamIDs []uint, query string, limit int) ([]*model.Snippet, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return []*model.Snippet{}, nil
+	}
+	visible, visibleArgs := snippetVisibility(userID, teamIDs)
+
+	useFTS := true
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < ftsMinTermLength {
+			useFTS = false
+			break
+		}
+	}
+
+	if useFTS {
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		}
+		args := append([]interface{}{strings.Join(quoted, " ")}, visibleArgs...)
+		args = append(args, limit)
+		return r.query(`SELECT `+snippetColumns+` FROM snippets_fts f
+			JOIN snippets s ON s.id = f.rowid
+			WHERE snippets_fts MATCH ? AND (`+visible+`)
+			ORDER BY bm25(snippets_fts), s.usage_count DESC
+			LIMIT ?`, args...)
+	}
+
+	conditions := make([]string, len(terms))
+	args := make([]interface{}, 0, len(terms)*4+len(visibleArgs)+1)
+	for i, term := range terms {
+		conditions[i] = `(s.name LIKE ? ESCAPE '\' OR s.description LIKE ? ESCAPE '\' OR s.content LIKE ? ESCAPE '\' OR s.tags LIKE ? ESCAPE '\')`
+		pattern := "%" + escapeLike(term) + "%"
+		args = append(args, pattern, pattern, pattern, pattern)
+	}
+	args = append(args, visibleArgs...)
+	args = append(args, limit)
+	return r.query(`SELECT `+snippetColumns+` FROM snippets s
+		WHERE `+strings.Join(conditions, " AND ")+` AND (`+visible+`)
+		ORDER BY s.usage_count DESC, s.name
+		LIMIT ?`, args...)
+}
+
+// IncrementUsage 使用次数加一并记录使用时间
+func (r *SnippetRepository) IncrementUsage(id uint) error {
+	_, err := r.db.Exec("UPDATE snippets SET usage_count = usage_count + 1, last_used_at = ? WHERE id = ?", time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("更新命令片段使用次数失败: %w", err)
+	}
+	return nil
+}
+
+// query 查询命令片段列表
+func (r *SnippetRepository) query(query string, args ...interface{}) ([]*model.Snippet, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询命令片段失败: %w", err)
+	}
+	defer rows.Close()
+
+	snippets := make([]*model.Snippet, 0)
+	for rows.Next() {
+		snippet, err := scanSnippet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描命令片段失败: %w", err)
+		}
+		snippets = append(snippets, snippet)
+	}
+	return snippets, rows.Err()
+}
+
+// snippetVisibility 生成用户可见片段的查询条件：自己创建的、全局的和所属团队的片段
+func snippetVisibility(userID uint, teamIDs []uint) (string, []interface{}) {
+	condition := `s.owner_id = ? OR s.scope = '` + model.SnippetScopeGlobal + `'`
+	args := []interface{}{userID}
+	if len(teamIDs) > 0 {
+		condition += ` OR (s.scope = '` + model.SnippetScopeTeam + `' AND s.team_id IN (` +
+			strings.TrimSuffix(strings.Repeat("?,", len(teamIDs)), ",") + `))`
+		for _, id := range teamIDs {
+			args = append(args, id)
+		}
+	}
+	return condition, args
+}
+
+// escapeLike 转义LIKE模式中的通配符，配合ESCAPE '\'使用
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
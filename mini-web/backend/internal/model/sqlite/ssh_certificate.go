@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// SSHCertificateRepository SQLite SSH证书签发记录仓库实现
+type SSHCertificateRepository struct {
+	db *sql.DB
+}
+
+// NewSSHCertificateRepository 创建SSH证书签发记录仓库实例
+func NewSSHCertificateRepository(db *sql.DB) model.SSHCertificateRepository {
+	return &SSHCertificateRepository{db: db}
+}
+
+const sshCertificateColumns = `id, serial, key_id, user_id, username, connection_id,
+		   principals, fingerprint, valid_after, valid_before, created_at`
+
+// Create 记录一次证书签发
+func (r *SSHCertificateRepository) Create(cert *model.SSHCertificate) error {
+	query := `
+		INSERT INTO ssh_certificates (
+			serial, key_id, user_id, username, connection_id,
+			principals, fingerprint, valid_after, valid_before, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	cert.CreatedAt = time.Now()
+
+	result, err := r.db.Exec(query,
+		int64(cert.Serial),
+		cert.KeyID,
+		cert.UserID,
+		cert.Username,
+		cert.ConnectionID,
+		cert.Principals,
+		cert.Fingerprint,
+		cert.ValidAfter,
+		cert.ValidBefore,
+		cert.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("记录SSH证书签发失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+
+	cert.ID = uint(id)
+	return nil
+}
+
+// GetAll 分页获取证书签发记录
+func (r *SSHCertificateRepository) GetAll(limit, offset int) ([]*model.SSHCertificate, error) {
+	query := `SELECT ` + sshCertificateColumns + `
+		FROM ssh_certificates
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("查询SSH证书签发记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var certs []*model.SSHCertificate
+	for rows.Next() {
+		cert, err := scanSSHCertificate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描SSH证书签发记录失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, rows.Err()
+}
+
+// GetBySerial 根据序列号获取证书签发记录
+func (r *SSHCertificateRepository) GetBySerial(serial uint64) (*model.SSHCertificate, error) {
+	query := `SELECT ` + sshCertificateColumns + `
+		FROM ssh_certificates
+		WHERE serial = ?`
+
+	cert, err := scanSSHCertificate(r.db.QueryRow(query, int64(serial)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询SSH证书签发记录失败: %w", err)
+	}
+
+	return cert, nil
+}
+
+// Count 获取证书签发记录总数
+func (r *SSHCertificateRepository) Count() (int, error) {
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM ssh_certificates").Scan(&total); err != nil {
+		return 0, fmt.Errorf("统计SSH证书签发记录失败: %w", err)
+	}
+	return total, nil
+}
+
+// scanSSHCertificate 扫描一条证书签发记录
+func scanSSHCertificate(scanner rowScanner) (*model.SSHCertificate, error) {
+	cert := &model.SSHCertificate{}
+	var serial int64
+
+	err := scanner.Scan(
+		&cert.ID,
+		&serial,
+		&cert.KeyID,
+		&cert.UserID,
+		&cert.Username,
+		&cert.ConnectionID,
+		&cert.Principals,
+		&cert.Fingerprint,
+		&cert.ValidAfter,
+		&cert.ValidBefore,
+		&cert.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cert.Serial = uint64(serial)
+	return cert, nil
+}
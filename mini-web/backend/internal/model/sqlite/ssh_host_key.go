@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// SSHHostKeyRepository SQLite SSH主机公钥仓库实现
+type SSHHostKeyRepository struct {
+	db *sql.DB
+}
+
+// NewSSHHostKeyRepository 创建SSH主机公钥仓库实例
+func NewSSHHostKeyRepository(db *sql.DB) model.SSHHostKeyRepository {
+	return &SSHHostKeyRepository{db: db}
+}
+
+const sshHostKeyColumns = `id, host, port, key_type, public_key, fingerprint, created_at, last_seen_at`
+
+// scanSSHHostKey 扫描一行主机公钥
+func scanSSHHostKey(row rowScanner) (*model.SSHHostKey, error) {
+	key := &model.SSHHostKey{}
+	err := row.Scan(&key.ID, &key.Host, &key.Port, &key.KeyType, &key.PublicKey, &key.Fingerprint, &key.CreatedAt, &key.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Get 获取主机记录的公钥，不存在时返回nil
+func (r *SSHHostKeyRepository) Get(host string, port int) (*model.SSHHostKey, error) {
+	key, err := scanSSHHostKey(r.db.QueryRow(`SELECT `+sshHostKeyColumns+` FROM ssh_host_keys WHERE host = ? AND port = ?`, host, port))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询SSH主机公钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// GetAll 获取全部主机公钥
+func (r *SSHHostKeyRepository) GetAll() ([]*model.SSHHostKey, error) {
+	rows, err := r.db.Query(`SELECT ` + sshHostKeyColumns + ` FROM ssh_host_keys ORDER BY host, port`)
+	if err != nil {
+		return nil, fmt.Errorf("查询SSH主机公钥失败: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*model.SSHHostKey, 0)
+	for rows.Next() {
+		key, err := scanSSHHostKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描SSH主机公钥失败: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Create 记录主机公钥，并发首次连接时以先写入的为准
+func (r *SSHHostKeyRepository) Create(key *model.SSHHostKey) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO ssh_host_keys (host, port, key_type, public_key, fingerprint, created_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(host, port) DO NOTHING
+	`, key.Host, key.Port, key.KeyType, key.PublicKey, key.Fingerprint, now, now)
+	if err != nil {
+		return fmt.Errorf("保存SSH主机公钥失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取SSH主机公钥ID失败: %w", err)
+	}
+	key.ID = uint(id)
+	key.CreatedAt = now
+	key.LastSeenAt = now
+	return nil
+}
+
+// Touch 更新主机公钥的最后确认时间
+func (r *SSHHostKeyRepository) Touch(id uint) error {
+	if _, err := r.db.Exec("UPDATE ssh_host_keys SET last_seen_at = ? WHERE id = ?", time.Now(), id); err != nil {
+		return fmt.Errorf("更新SSH主机公钥失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除主机公钥记录，返回是否存在
+func (r *SSHHostKeyRepository) Delete(id uint) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM ssh_host_keys WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("删除SSH主机公钥失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("删除SSH主机公钥失败: %w", err)
+	}
+	return affected > 0, nil
+}
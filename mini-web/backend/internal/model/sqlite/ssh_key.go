@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// SSHKeyRepository SQLite SSH密钥仓库实现
+type SSHKeyRepository struct {
+	db *sql.DB
+}
+
+// NewSSHKeyRepository 创建SSH密钥仓库实例
+func NewSSHKeyRepository(db *sql.DB) model.SSHKeyRepository {
+	return &SSHKeyRepository{db: db}
+}
+
+const sshKeyColumns = `id, name, key_type, bits, public_key, private_key, fingerprint,
+		   COALESCE(comment, ''), created_by, rotated_at, created_at, updated_at`
+
+// Create 保存SSH密钥，私钥需由调用方加密
+func (r *SSHKeyRepository) Create(key *model.SSHKey) error {
+	query := `
+		INSERT INTO ssh_keys (
+			name, key_type, bits, public_key, private_key, fingerprint,
+			comment, created_by, created_at, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	key.CreatedAt = now
+	key.UpdatedAt = now
+
+	result, err := r.db.Exec(query,
+		key.Name,
+		key.KeyType,
+		key.Bits,
+		key.PublicKey,
+		key.PrivateKey,
+		key.Fingerprint,
+		key.Comment,
+		key.CreatedBy,
+		key.CreatedAt,
+		key.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("创建SSH密钥失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+
+	key.ID = uint(id)
+	return nil
+}
+
+// Update 更新SSH密钥（名称、密钥对、轮换时间）
+func (r *SSHKeyRepository) Update(key *model.SSHKey) error {
+	query := `
+		UPDATE ssh_keys
+		SET name = ?, key_type = ?, bits = ?, public_key = ?, private_key = ?,
+			fingerprint = ?, comment = ?, rotated_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	key.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(query,
+		key.Name,
+		key.KeyType,
+		key.Bits,
+		key.PublicKey,
+		key.PrivateKey,
+		key.Fingerprint,
+		key.Comment,
+		key.RotatedAt,
+		key.UpdatedAt,
+		key.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新SSH密钥失败: %w", err)
+	}
+
+	return nil
+}
+
+// Delete 删除SSH密钥
+func (r *SSHKeyRepository) Delete(id uint) error {
+	if _, err := r.db.Exec("DELETE FROM ssh_keys WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除SSH密钥失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取SSH密钥
+func (r *SSHKeyRepository) GetByID(id uint) (*model.SSHKey, error) {
+	query := `SELECT ` + sshKeyColumns + `
+		FROM ssh_keys
+		WHERE id = ?`
+
+	key, err := scanSSHKey(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询SSH密钥失败: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetByUserID 获取用户的所有SSH密钥
+func (r *SSHKeyRepository) GetByUserID(userID uint) ([]*model.SSHKey, error) {
+	query := `SELECT ` + sshKeyColumns + `
+		FROM ssh_keys
+		WHERE created_by = ?
+		ORDER BY name`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询SSH密钥失败: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*model.SSHKey
+	for rows.Next() {
+		key, err := scanSSHKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描SSH密钥失败: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// scanSSHKey 扫描一条SSH密钥记录
+func scanSSHKey(scanner rowScanner) (*model.SSHKey, error) {
+	key := &model.SSHKey{}
+	var rotatedAt sql.NullTime
+
+	err := scanner.Scan(
+		&key.ID,
+		&key.Name,
+		&key.KeyType,
+		&key.Bits,
+		&key.PublicKey,
+		&key.PrivateKey,
+		&key.Fingerprint,
+		&key.Comment,
+		&key.CreatedBy,
+		&rotatedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotatedAt.Valid {
+		key.RotatedAt = &rotatedAt.Time
+	}
+	return key, nil
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SSLCSR 等待外部CA签发的证书请求，私钥只保存在本地
+type SSLCSR struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	CommonName string `json:"common_name"`
+	DNSNames   string `json:"dns_names"` // 逗号分隔
+	KeyType    string `json:"key_type"`
+	CSRContent string `json:"csr_content"`
+	KeyContent string `json:"-"`
+	CreatedAt  string `json:"created_at"`
+}
+
+const sslCSRColumns = `id, name, common_name, dns_names, key_type, csr_content, key_content, created_at`
+
+// scanSSLCSR 扫描一行证书请求
+func scanSSLCSR(row rowScanner) (*SSLCSR, error) {
+	csr := &SSLCSR{}
+	err := row.Scan(&csr.ID, &csr.Name, &csr.CommonName, &csr.DNSNames, &csr.KeyType, &csr.CSRContent, &csr.KeyContent, &csr.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return csr, nil
+}
+
+// CreateSSLCSR 保存证书请求
+func CreateSSLCSR(csr *SSLCSR) error {
+	result, err := DB.Exec(`
+		INSERT INTO ssl_csrs (name, common_name, dns_names, key_type, csr_content, key_content)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, csr.Name, csr.CommonName, csr.DNSNames, csr.KeyType, csr.CSRContent, csr.KeyContent)
+	if err != nil {
+		return fmt.Errorf("保存证书请求失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取证书请求ID失败: %w", err)
+	}
+	csr.ID = int(id)
+	return nil
+}
+
+// GetSSLCSRs 获取全部证书请求
+func GetSSLCSRs() ([]*SSLCSR, error) {
+	rows, err := DB.Query(`SELECT ` + sslCSRColumns + ` FROM ssl_csrs ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("查询证书请求失败: %w", err)
+	}
+	defer rows.Close()
+
+	csrs := make([]*SSLCSR, 0)
+	for rows.Next() {
+		csr, err := scanSSLCSR(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描证书请求失败: %w", err)
+		}
+		csrs = append(csrs, csr)
+	}
+	return csrs, rows.Err()
+}
+
+// GetSSLCSRByID 根据ID获取证书请求，不存在时返回nil
+func GetSSLCSRByID(id int) (*SSLCSR, error) {
+	csr, err := scanSSLCSR(DB.QueryRow(`SELECT `+sslCSRColumns+` FROM ssl_csrs WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取证书请求失败: %w", err)
+	}
+	return csr, nil
+}
+
+// DeleteSSLCSR 删除证书请求，返回是否存在
+func DeleteSSLCSR(id int) (bool, error) {
+	result, err := DB.Exec("DELETE FROM ssl_csrs WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("删除证书请求失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("删除证书请求失败: %w", err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,268 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// TeamRepository SQLite团队仓库实现
+type TeamRepository struct {
+	db *sql.DB
+}
+
+// NewTeamRepository 创建团队仓库实例
+func NewTeamRepository(db *sql.DB) model.TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+const teamColumns = `t.id, t.name, COALESCE(t.description, ''), t.created_by,
+		   (SELECT COUNT(*) FROM team_members m WHERE m.team_id = t.id),
+		   t.created_at, t.updated_at`
+
+// Create 创建团队
+func (r *TeamRepository) Create(team *model.Team) error {
+	now := time.Now()
+	team.CreatedAt = now
+	team.UpdatedAt = now
+
+	result, err := r.db.Exec(
+		"INSERT INTO teams (name, description, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		team.Name, team.Description, team.CreatedBy, team.CreatedAt, team.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("创建团队失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取插入ID失败: %w", err)
+	}
+
+	team.ID = uint(id)
+	return nil
+}
+
+// Update 更新团队名称和描述
+func (r *TeamRepository) Update(team *model.Team) error {
+	team.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(
+		"UPDATE teams SET name = ?, description = ?, updated_at = ? WHERE id = ?",
+		team.Name, team.Description, team.UpdatedAt, team.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新团队失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除团队及其成员和共享授权，团队凭据转为所有者的个人凭据
+func (r *TeamRepository) Delete(id uint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM team_members WHERE team_id = ?", []interface{}{id}},
+		{"DELETE FROM connection_shares WHERE subject_type = ? AND subject_id = ?", []interface{}{model.ShareSubjectTeam, id}},
+		{"UPDATE credentials SET team_id = NULL WHERE team_id = ?", []interface{}{id}},
+		{"DELETE FROM teams WHERE id = ?", []interface{}{id}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("删除团队失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByID 根据ID获取团队
+func (r *TeamRepository) GetByID(id uint) (*model.Team, error) {
+	query := `SELECT ` + teamColumns + `
+		FROM teams t
+		WHERE t.id = ?`
+
+	team, err := scanTeam(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询团队失败: %w", err)
+	}
+	return team, nil
+}
+
+// GetAll 获取全部团队
+func (r *TeamRepository) GetAll() ([]*model.Team, error) {
+	query := `SELECT ` + teamColumns + `
+		FROM teams t
+		ORDER BY t.name`
+
+	return r.queryTeams(query)
+}
+
+// GetByUserID 获取用户所属的团队
+func (r *TeamRepository) GetByUserID(userID uint) ([]*model.Team, error) {
+	query := `SELECT ` + teamColumns + `
+		FROM teams t
+		JOIN team_members tm ON tm.team_id = t.id
+		WHERE tm.user_id = ?
+		ORDER BY t.name`
+
+	return r.queryTeams(query, userID)
+}
+
+// SaveMember 添加团队成员，成员已存在时更新角色
+func (r *TeamRepository) SaveMember(member *model.TeamMember) error {
+	query := `
+		INSERT INTO team_members (team_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(team_id, user_id) DO UPDATE SET role = excluded.role
+	`
+
+	if member.CreatedAt.IsZero() {
+		member.CreatedAt = time.Now()
+	}
+
+	if _, err := r.db.Exec(query, member.TeamID, member.UserID, member.Role, member.CreatedAt); err != nil {
+		return fmt.Errorf("保存团队成员失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember 移除团队成员
+func (r *TeamRepository) RemoveMember(teamID uint, userID uint) error {
+	if _, err := r.db.Exec("DELETE FROM team_members WHERE team_id = ? AND user_id = ?", teamID, userID); err != nil {
+		return fmt.Errorf("移除团队成员失败: %w", err)
+	}
+	return nil
+}
+
+// GetMember 获取团队中的指定成员
+func (r *TeamRepository) GetMember(teamID uint, userID uint) (*model.TeamMember, error) {
+	query := `
+		SELECT tm.team_id, tm.user_id, COALESCE(u.username, ''), COALESCE(u.nickname, ''), tm.role, tm.created_at
+		FROM team_members tm
+		LEFT JOIN users u ON u.id = tm.user_id
+		WHERE tm.team_id = ? AND tm.user_id = ?`
+
+	member, err := scanTeamMember(r.db.QueryRow(query, teamID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询团队成员失败: %w", err)
+	}
+	return member, nil
+}
+
+// GetMembers 获取团队成员列表
+func (r *TeamRepository) GetMembers(teamID uint) ([]*model.TeamMember, error) {
+	query := `
+		SELECT tm.team_id, tm.user_id, COALESCE(u.username, ''), COALESCE(u.nickname, ''), tm.role, tm.created_at
+		FROM team_members tm
+		LEFT JOIN users u ON u.id = tm.user_id
+		WHERE tm.team_id = ?
+		ORDER BY tm.role DESC, u.username`
+
+	rows, err := r.db.Query(query, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("查询团队成员失败: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*model.TeamMember
+	for rows.Next() {
+		member, err := scanTeamMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描团队成员失败: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// GetTeamIDsByUserID 获取用户所属团队的ID列表
+func (r *TeamRepository) GetTeamIDsByUserID(userID uint) ([]uint, error) {
+	rows, err := r.db.Query("SELECT team_id FROM team_members WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户团队失败: %w", err)
+	}
+	defer rows.Close()
+
+	var teamIDs []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("扫描用户团队失败: %w", err)
+		}
+		teamIDs = append(teamIDs, id)
+	}
+
+	return teamIDs, rows.Err()
+}
+
+// queryTeams 查询团队列表辅助函数
+func (r *TeamRepository) queryTeams(query string, args ...interface{}) ([]*model.Team, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询团队失败: %w", err)
+	}
+	defer rows.Close()
+
+	var teams []*model.Team
+	for rows.Next() {
+		team, err := scanTeam(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描团队失败: %w", err)
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, rows.Err()
+}
+
+// scanTeam 扫描一条团队记录
+func scanTeam(scanner rowScanner) (*model.Team, error) {
+	team := &model.Team{}
+	err := scanner.Scan(
+		&team.ID,
+		&team.Name,
+		&team.Description,
+		&team.CreatedBy,
+		&team.MemberCount,
+		&team.CreatedAt,
+		&team.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// scanTeamMember 扫描一条团队成员记录
+func scanTeamMember(scanner rowScanner) (*model.TeamMember, error) {
+	member := &model.TeamMember{}
+	err := scanner.Scan(
+		&member.TeamID,
+		&member.UserID,
+		&member.Username,
+		&member.Nickname,
+		&member.Role,
+		&member.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
@@ -24,9 +24,11 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 	var user model.User
 	var createdAt, updatedAt string
+	var passwordChangedAt sql.NullTime
 
 	query := `
-	SELECT id, username, email, password, nickname, avatar, role, status, created_at, updated_at
+	SELECT id, username, email, password, nickname, avatar, role, status, auth_source,
+	       must_change_password, password_changed_at, created_at, updated_at
 	FROM users
 	WHERE username = ?
 	LIMIT 1
@@ -41,6 +43,9 @@ func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 		&user.Avatar,
 		&user.Role,
 		&user.Status,
+		&user.AuthSource,
+		&user.MustChangePassword,
+		&passwordChangedAt,
 		&createdAt,
 		&updatedAt,
 	)
@@ -57,6 +62,9 @@ func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 	// 解析时间
 	user.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	user.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	if passwordChangedAt.Valid {
+		user.PasswordChangedAt = &passwordChangedAt.Time
+	}
 
 	log.Printf("找到用户: ID=%d, 用户名=%s, 密码哈希=%s", user.ID, user.Username, user.Password)
 	return &user, nil
@@ -66,9 +74,11 @@ func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 	var user model.User
 	var createdAt, updatedAt string
+	var passwordChangedAt sql.NullTime
 
 	query := `
-	SELECT id, username, email, password, nickname, avatar, role, status, created_at, updated_at
+	SELECT id, username, email, password, nickname, avatar, role, status, auth_source,
+	       must_change_password, password_changed_at, created_at, updated_at
 	FROM users
 	WHERE email = ?
 	LIMIT 1
@@ -83,6 +93,9 @@ func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 		&user.Avatar,
 		&user.Role,
 		&user.Status,
+		&user.AuthSource,
+		&user.MustChangePassword,
+		&passwordChangedAt,
 		&createdAt,
 		&updatedAt,
 	)
@@ -97,6 +110,9 @@ func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 	// 解析时间
 	user.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	user.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	if passwordChangedAt.Valid {
+		user.PasswordChangedAt = &passwordChangedAt.Time
+	}
 
 	return &user, nil
 }
@@ -105,9 +121,11 @@ func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 func (r *UserRepository) GetByID(id uint) (*model.User, error) {
 	var user model.User
 	var createdAt, updatedAt string
+	var passwordChangedAt sql.NullTime
 
 	query := `
-	SELECT id, username, email, password, nickname, avatar, role, status, created_at, updated_at
+	SELECT id, username, email, password, nickname, avatar, role, status, auth_source,
+	       must_change_password, password_changed_at, created_at, updated_at
 	FROM users
 	WHERE id = ?
 	LIMIT 1
@@ -122,6 +140,9 @@ func (r *UserRepository) GetByID(id uint) (*model.User, error) {
 		&user.Avatar,
 		&user.Role,
 		&user.Status,
+		&user.AuthSource,
+		&user.MustChangePassword,
+		&passwordChangedAt,
 		&createdAt,
 		&updatedAt,
 	)
@@ -136,6 +157,9 @@ func (r *UserRepository) GetByID(id uint) (*model.User, error) {
 	// 解析时间
 	user.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	user.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	if passwordChangedAt.Valid {
+		user.PasswordChangedAt = &passwordChangedAt.Time
+	}
 
 	return &user, nil
 }
@@ -143,10 +167,15 @@ func (r *UserRepository) GetByID(id uint) (*model.User, error) {
 // Create 创建新用户
 func (r *UserRepository) Create(user *model.User) error {
 	query := `
-	INSERT INTO users (username, email, password, nickname, avatar, role, status)
-	VALUES (?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO users (username, email, password, nickname, avatar, role, status, auth_source,
+	                   must_change_password, password_changed_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	`
 
+	if user.AuthSource == "" {
+		user.AuthSource = model.AuthSourceLocal
+	}
+
 	// 对密码进行哈希处理
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -162,6 +191,8 @@ func (r *UserRepository) Create(user *model.User) error {
 		user.Avatar,
 		user.Role,
 		user.Status,
+		user.AuthSource,
+		user.MustChangePassword,
 	)
 	if err != nil {
 		return err
@@ -199,7 +230,8 @@ func (r *UserRepository) Update(user *model.User) error {
 func (r *UserRepository) UpdatePassword(userID uint, newPassword string) error {
 	query := `
 	UPDATE users
-	SET password = ?, updated_at = CURRENT_TIMESTAMP
+	SET password = ?, must_change_password = 0, password_changed_at = CURRENT_TIMESTAMP,
+	    updated_at = CURRENT_TIMESTAMP
 	WHERE id = ?
 	`
 
@@ -213,6 +245,15 @@ func (r *UserRepository) UpdatePassword(userID uint, newPassword string) error {
 	return err
 }
 
+// SetMustChangePassword 设置用户下次访问时是否必须修改密码
+func (r *UserRepository) SetMustChangePassword(userID uint, required bool) error {
+	_, err := r.db.Exec(
+		`UPDATE users SET must_change_password = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		required, userID,
+	)
+	return err
+}
+
 // VerifyPassword 验证用户密码
 func (r *UserRepository) VerifyPassword(username, password string) (bool, *model.User, error) {
 	log.Printf("开始验证密码: 用户名=%s", username)
@@ -260,7 +301,8 @@ func (r *UserRepository) VerifyPassword(username, password string) (bool, *model
 // GetAll 获取所有用户
 func (r *UserRepository) GetAll() ([]*model.User, error) {
 	query := `
-	SELECT id, username, email, password, nickname, avatar, role, status, created_at, updated_at
+	SELECT id, username, email, password, nickname, avatar, role, status, auth_source,
+	       must_change_password, password_changed_at, created_at, updated_at
 	FROM users
 	ORDER BY id
 	`
@@ -275,6 +317,7 @@ func (r *UserRepository) GetAll() ([]*model.User, error) {
 	for rows.Next() {
 		var user model.User
 		var createdAt, updatedAt string
+	var passwordChangedAt sql.NullTime
 
 		err := rows.Scan(
 			&user.ID,
@@ -285,6 +328,9 @@ func (r *UserRepository) GetAll() ([]*model.User, error) {
 			&user.Avatar,
 			&user.Role,
 			&user.Status,
+			&user.AuthSource,
+			&user.MustChangePassword,
+			&passwordChangedAt,
 			&createdAt,
 			&updatedAt,
 		)
@@ -295,6 +341,9 @@ func (r *UserRepository) GetAll() ([]*model.User, error) {
 		// 解析时间
 		user.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 		user.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		if passwordChangedAt.Valid {
+			user.PasswordChangedAt = &passwordChangedAt.Time
+		}
 
 		users = append(users, &user)
 	}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// SSHCertificate SSH CA签发的用户证书记录
+type SSHCertificate struct {
+	ID           uint      `json:"id"`
+	Serial       uint64    `json:"serial,string"` // 证书序列号，字符串形式避免前端精度丢失
+	KeyID        string    `json:"key_id"`        // 证书KeyId，会出现在sshd日志中
+	UserID       uint      `json:"user_id"`       // 申请证书的用户
+	Username     string    `json:"username"`      // 申请证书的用户名
+	ConnectionID uint      `json:"connection_id"` // 目标连接
+	Principals   string    `json:"principals"`    // 证书principals，逗号分隔
+	Fingerprint  string    `json:"fingerprint"`   // 临时公钥指纹(SHA256)
+	ValidAfter   time.Time `json:"valid_after"`   // 生效时间
+	ValidBefore  time.Time `json:"valid_before"`  // 失效时间
+	CreatedAt    time.Time `json:"created_at"`    // 签发时间
+}
+
+// SSHCertificateRepository SSH证书签发记录仓库接口
+type SSHCertificateRepository interface {
+	Create(cert *SSHCertificate) error
+	GetAll(limit int, offset int) ([]*SSHCertificate, error)
+	GetBySerial(serial uint64) (*SSHCertificate, error)
+	Count() (int, error)
+}
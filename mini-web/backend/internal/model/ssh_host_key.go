@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// SSHHostKey 首次连接时记录的SSH主机公钥，之后连接同一主机时必须一致
+type SSHHostKey struct {
+	ID          uint      `json:"id"`
+	Host        string    `json:"host"`
+	Port        int       `json:"port"`
+	KeyType     string    `json:"key_type"`
+	PublicKey   string    `json:"public_key"`  // authorized_keys格式
+	Fingerprint string    `json:"fingerprint"` // SHA256指纹
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// SSHHostKeyRepository SSH主机公钥仓库接口
+type SSHHostKeyRepository interface {
+	Get(host string, port int) (*SSHHostKey, error)
+	GetAll() ([]*SSHHostKey, error)
+	Create(key *SSHHostKey) error
+	Touch(id uint) error
+	Delete(id uint) (bool, error)
+}
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// SSH密钥类型
+const (
+	SSHKeyTypeED25519 = "ed25519"
+	SSHKeyTypeRSA     = "rsa"
+)
+
+// SSHKey 服务端生成并加密保存的SSH密钥对，可关联到多个连接
+type SSHKey struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`        // 密钥名称
+	KeyType     string     `json:"key_type"`    // 密钥类型：ed25519, rsa
+	Bits        int        `json:"bits"`        // RSA密钥长度，ed25519为256
+	PublicKey   string     `json:"public_key"`  // authorized_keys格式公钥
+	PrivateKey  string     `json:"-"`           // 私钥（数据库中加密保存），不在JSON中返回
+	Fingerprint string     `json:"fingerprint"` // 公钥指纹(SHA256)
+	Comment     string     `json:"comment"`     // 公钥注释
+	CreatedBy   uint       `json:"created_by"`  // 创建者ID
+	RotatedAt   *time.Time `json:"rotated_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// SSHKeyRequest 生成SSH密钥请求
+type SSHKeyRequest struct {
+	Name    string `json:"name"`
+	KeyType string `json:"key_type"`
+	Bits    int    `json:"bits"`
+	Comment string `json:"comment"`
+}
+
+// SSHKeyDeployRequest 部署公钥请求
+type SSHKeyDeployRequest struct {
+	ConnectionID uint   `json:"connection_id"`
+	Password     string `json:"password,omitempty"` // 为空时使用连接保存的密码
+	Attach       *bool  `json:"attach,omitempty"`   // 部署成功后是否关联到连接，默认关联
+}
+
+// SSHKeyDeployResult 单台主机的部署结果
+type SSHKeyDeployResult struct {
+	ConnectionID uint   `json:"connection_id"`
+	Name         string `json:"name"`
+	Host         string `json:"host"`
+	Added        bool   `json:"added"`       // false表示公钥已存在
+	OldRemoved   bool   `json:"old_removed"` // 轮换时旧公钥是否已移除
+	Error        string `json:"error,omitempty"`
+}
+
+// SSHKeyRepository SSH密钥仓库接口
+type SSHKeyRepository interface {
+	Create(key *SSHKey) error
+	Update(key *SSHKey) error
+	Delete(id uint) error
+	GetByID(id uint) (*SSHKey, error)
+	GetByUserID(userID uint) ([]*SSHKey, error)
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// 团队成员角色
+const (
+	TeamRoleOwner  = "owner"  // 团队管理者，可管理成员
+	TeamRoleMember = "member" // 普通成员
+)
+
+// Team 团队（用户组），用于共享连接、文件夹和凭据
+type Team struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`         // 团队名称
+	Description string    `json:"description"`  // 描述
+	CreatedBy   uint      `json:"created_by"`   // 创建者ID
+	MemberCount int       `json:"member_count"` // 成员数量
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TeamMember 团队成员
+type TeamMember struct {
+	TeamID    uint      `json:"team_id"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Nickname  string    `json:"nickname"`
+	Role      string    `json:"role"` // 成员角色：owner, member
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TeamRequest 创建/更新团队请求
+type TeamRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TeamMemberRequest 添加团队成员请求
+type TeamMemberRequest struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// IsValidTeamRole 检查团队成员角色是否有效
+func IsValidTeamRole(role string) bool {
+	return role == TeamRoleOwner || role == TeamRoleMember
+}
+
+// TeamRepository 团队仓库接口
+type TeamRepository interface {
+	Create(team *Team) error
+	Update(team *Team) error
+	Delete(id uint) error
+	GetByID(id uint) (*Team, error)
+	GetAll() ([]*Team, error)
+	GetByUserID(userID uint) ([]*Team, error)
+	SaveMember(member *TeamMember) error
+	RemoveMember(teamID uint, userID uint) error
+	GetMember(teamID uint, userID uint) (*TeamMember, error)
+	GetMembers(teamID uint) ([]*TeamMember, error)
+	GetTeamIDsByUserID(userID uint) ([]uint, error)
+}
@@ -2,20 +2,30 @@ package model
 
 import "time"
 
+// 用户账号来源
+const (
+	AuthSourceLocal = "local" // 本地账号，使用bcrypt密码登录
+	AuthSourceLDAP  = "ldap"  // LDAP/AD目录账号，首次登录时自动创建
+	AuthSourceOIDC  = "oidc"  // OpenID Connect单点登录账号，首次登录时自动创建
+)
+
 // User 用户模型
 type User struct {
-	ID           uint       `json:"id"`
-	Username     string     `json:"username"`
-	Email        string     `json:"email"`
-	Password     string     `json:"-"` // 不在JSON中返回密码
-	Nickname     string     `json:"nickname"`
-	Avatar       string     `json:"avatar"`
-	Role         string     `json:"role"`
-	Status       string     `json:"status"`
-	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
-	LoginCount   int        `json:"login_count"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID                 uint       `json:"id"`
+	Username           string     `json:"username"`
+	Email              string     `json:"email"`
+	Password           string     `json:"-"` // 不在JSON中返回密码
+	Nickname           string     `json:"nickname"`
+	Avatar             string     `json:"avatar"`
+	Role               string     `json:"role"`
+	Status             string     `json:"status"`
+	AuthSource         string     `json:"auth_source"`          // 账号来源：local、ldap、oidc
+	MustChangePassword bool       `json:"must_change_password"` // 必须修改密码后才能使用其他功能
+	PasswordChangedAt  *time.Time `json:"password_changed_at,omitempty"`
+	LastLoginAt        *time.Time `json:"last_login_at,omitempty"`
+	LoginCount         int        `json:"login_count"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 // UserLoginRequest 用户登录请求
@@ -34,12 +44,13 @@ type UserRegisterRequest struct {
 
 // UserCreateRequest 用户创建请求
 type UserCreateRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Nickname string `json:"nickname"`
-	Role     string `json:"role"`
-	Status   string `json:"status"`
+	Username           string `json:"username"`
+	Email              string `json:"email"`
+	Password           string `json:"password"`
+	Nickname           string `json:"nickname"`
+	Role               string `json:"role"`
+	Status             string `json:"status"`
+	MustChangePassword bool   `json:"must_change_password"` // 首次登录时必须修改密码
 }
 
 // UserUpdateRequest 用户信息更新请求
@@ -78,9 +89,11 @@ type UserPasswordUpdateRequest struct {
 
 // UserLoginResponse 用户登录响应
 type UserLoginResponse struct {
-	Token  string `json:"token"`
-	User   User   `json:"user"`
-	Expire int64  `json:"expire"`
+	Token         string `json:"token"`
+	User          User   `json:"user"`
+	Expire        int64  `json:"expire"`
+	RefreshToken  string `json:"refresh_token"`
+	RefreshExpire int64  `json:"refresh_expire"`
 }
 
 // PageInfo 分页信息
@@ -113,6 +126,7 @@ type UserRepository interface {
 	Update(user *User) error
 	Delete(id uint) error
 	UpdatePassword(userID uint, newPassword string) error
+	SetMustChangePassword(userID uint, required bool) error
 	VerifyPassword(username, password string) (bool, *User, error)
 	BatchUpdateStatus(userIDs []uint, status string) error
 	UpdateLoginInfo(userID uint) error
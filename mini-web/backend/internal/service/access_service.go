@@ -0,0 +1,383 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+var (
+	// ErrConnectionForbidden 对连接的权限不足
+	ErrConnectionForbidden = errors.New("对此连接的权限不足")
+
+	// ErrFolderNotFound 文件夹不存在
+	ErrFolderNotFound = errors.New("文件夹不存在")
+
+	// ErrFolderForbidden 对文件夹的权限不足
+	ErrFolderForbidden = errors.New("对此文件夹的权限不足")
+
+	// ErrShareNotFound 共享授权不存在
+	ErrShareNotFound = errors.New("共享授权不存在")
+
+	// ErrInvalidPermission 无效的权限
+	ErrInvalidPermission = errors.New("无效的权限，仅支持view、connect、edit、manage")
+
+	// ErrInvalidShareSubject 无效的共享对象
+	ErrInvalidShareSubject = errors.New("无效的共享对象，仅支持共享给用户(user)或团队(team)")
+)
+
+// AccessService 连接访问控制服务，负责文件夹、共享授权和统一的权限判定
+type AccessService struct {
+	connRepo   model.ConnectionRepository
+	folderRepo model.ConnectionFolderRepository
+	shareRepo  model.ConnectionShareRepository
+	teams      *TeamService
+}
+
+// NewAccessService 创建访问控制服务实例
+func NewAccessService(connRepo model.ConnectionRepository, folderRepo model.ConnectionFolderRepository, shareRepo model.ConnectionShareRepository, teams *TeamService) *AccessService {
+	return &AccessService{
+		connRepo:   connRepo,
+		folderRepo: folderRepo,
+		shareRepo:  shareRepo,
+		teams:      teams,
+	}
+}
+
+// accessContext 一次权限判定所需的用户授权信息
+type accessContext struct {
+	userID      uint
+	admin       bool
+	connections map[uint]string // 连接ID -> 直接授予的最高权限
+	folders     map[uint]string // 文件夹ID -> 授予的最高权限
+	folderOwner map[uint]uint   // 已加载的文件夹所有者
+}
+
+// loadAccessContext 加载用户本人及其所属团队获得的全部共享授权
+func (s *AccessService) loadAccessContext(userID uint) (*accessContext, error) {
+	admin, err := s.teams.hasPermission(userID, model.PermConnectionsManageAll)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := &accessContext{
+		userID:      userID,
+		admin:       admin,
+		connections: make(map[uint]string),
+		folders:     make(map[uint]string),
+		folderOwner: make(map[uint]uint),
+	}
+	if admin {
+		return ctx, nil
+	}
+
+	teamIDs, err := s.teams.GetUserTeamIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := s.shareRepo.GetBySubjects(userID, teamIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, share := range shares {
+		switch share.ResourceType {
+		case model.ShareResourceConnection:
+			ctx.connections[share.ResourceID] = model.MaxPermission(ctx.connections[share.ResourceID], share.Permission)
+		case model.ShareResourceFolder:
+			ctx.folders[share.ResourceID] = model.MaxPermission(ctx.folders[share.ResourceID], share.Permission)
+		}
+	}
+
+	return ctx, nil
+}
+
+// folderPermission 计算用户对文件夹的有效权限
+func (s *AccessService) folderPermission(ctx *accessContext, folderID uint) (string, error) {
+	if ctx.admin {
+		return model.PermissionManage, nil
+	}
+
+	ownerID, ok := ctx.folderOwner[folderID]
+	if !ok {
+		folder, err := s.folderRepo.GetByID(folderID)
+		if err != nil {
+			return "", err
+		}
+		if folder != nil {
+			ownerID = folder.OwnerID
+		}
+		ctx.folderOwner[folderID] = ownerID
+	}
+	if ownerID != 0 && ownerID == ctx.userID {
+		return model.PermissionManage, nil
+	}
+
+	return ctx.folders[folderID], nil
+}
+
+// authorize 所有连接访问的唯一判定入口：
+// 创建者和拥有connections.manage_all权限的用户拥有manage权限，其余权限取直接共享、团队共享和所在文件夹共享中的最高者。
+// 判定通过时将有效权限写入conn.Permission；完全不可见的连接按不存在处理，避免泄露其存在
+func (s *AccessService) authorize(ctx *accessContext, conn *model.Connection, required string) error {
+	permission := ""
+	if ctx.admin || conn.CreatedBy == ctx.userID {
+		permission = model.PermissionManage
+	} else {
+		permission = ctx.connections[conn.ID]
+		if conn.FolderID != 0 {
+			folderPermission, err := s.folderPermission(ctx, conn.FolderID)
+			if err != nil {
+				return err
+			}
+			permission = model.MaxPermission(permission, folderPermission)
+		}
+	}
+
+	if !model.HasPermission(permission, model.PermissionView) {
+		return ErrConnectionNotFound
+	}
+	if !model.HasPermission(permission, required) {
+		return ErrConnectionForbidden
+	}
+
+	conn.Permission = permission
+	return nil
+}
+
+// Authorize 检查用户对连接是否具有所需权限
+func (s *AccessService) Authorize(userID uint, conn *model.Connection, required string) error {
+	ctx, err := s.loadAccessContext(userID)
+	if err != nil {
+		return fmt.Errorf("加载访问权限失败: %w", err)
+	}
+	return s.authorize(ctx, conn, required)
+}
+
+// FilterConnections 过滤出用户具有所需权限的连接
+func (s *AccessService) FilterConnections(userID uint, connections []*model.Connection, required string) ([]*model.Connection, error) {
+	ctx, err := s.loadAccessContext(userID)
+	if err != nil {
+		return nil, fmt.Errorf("加载访问权限失败: %w", err)
+	}
+
+	var allowed []*model.Connection
+	for _, conn := range connections {
+		err := s.authorize(ctx, conn, required)
+		if err == nil {
+			allowed = append(allowed, conn)
+			continue
+		}
+		if !errors.Is(err, ErrConnectionNotFound) && !errors.Is(err, ErrConnectionForbidden) {
+			return nil, err
+		}
+	}
+
+	return allowed, nil
+}
+
+// AuthorizeFolder 检查用户对文件夹是否具有所需权限
+func (s *AccessService) AuthorizeFolder(userID uint, folderID uint, required string) (*model.ConnectionFolder, error) {
+	folder, err := s.folderRepo.GetByID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	ctx, err := s.loadAccessContext(userID)
+	if err != nil {
+		return nil, fmt.Errorf("加载访问权限失败: %w", err)
+	}
+	ctx.folderOwner[folder.ID] = folder.OwnerID
+
+	permission, err := s.folderPermission(ctx, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !model.HasPermission(permission, model.PermissionView) {
+		return nil, ErrFolderNotFound
+	}
+	if !model.HasPermission(permission, required) {
+		return nil, ErrFolderForbidden
+	}
+
+	folder.Permission = permission
+	return folder, nil
+}
+
+// GetUserFolders 获取用户可见的文件夹
+func (s *AccessService) GetUserFolders(userID uint) ([]*model.ConnectionFolder, error) {
+	folders, err := s.folderRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := s.loadAccessContext(userID)
+	if err != nil {
+		return nil, fmt.Errorf("加载访问权限失败: %w", err)
+	}
+
+	var visible []*model.ConnectionFolder
+	for _, folder := range folders {
+		ctx.folderOwner[folder.ID] = folder.OwnerID
+		permission, err := s.folderPermission(ctx, folder.ID)
+		if err != nil {
+			return nil, err
+		}
+		if model.HasPermission(permission, model.PermissionView) {
+			folder.Permission = permission
+			visible = append(visible, folder)
+		}
+	}
+
+	return visible, nil
+}
+
+// CreateFolder 创建文件夹
+func (s *AccessService) CreateFolder(userID uint, req *model.ConnectionFolderRequest) (*model.ConnectionFolder, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("文件夹名称不能为空")
+	}
+
+	folder := &model.ConnectionFolder{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		OwnerID:     userID,
+		Permission:  model.PermissionManage,
+	}
+	if err := s.folderRepo.Create(folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// UpdateFolder 更新文件夹，需要edit权限
+func (s *AccessService) UpdateFolder(userID uint, id uint, req *model.ConnectionFolderRequest) (*model.ConnectionFolder, error) {
+	folder, err := s.AuthorizeFolder(userID, id, model.PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("文件夹名称不能为空")
+	}
+
+	folder.Name = strings.TrimSpace(req.Name)
+	folder.Description = req.Description
+	if err := s.folderRepo.Update(folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// DeleteFolder 删除文件夹，需要manage权限，其中的连接移出文件夹
+func (s *AccessService) DeleteFolder(userID uint, id uint) error {
+	if _, err := s.AuthorizeFolder(userID, id, model.PermissionManage); err != nil {
+		return err
+	}
+	return s.folderRepo.Delete(id)
+}
+
+// GetShares 获取连接或文件夹的共享授权列表，需要manage权限
+func (s *AccessService) GetShares(userID uint, resourceType string, resourceID uint) ([]*model.ConnectionShare, error) {
+	if err := s.authorizeResource(userID, resourceType, resourceID, model.PermissionManage); err != nil {
+		return nil, err
+	}
+	return s.shareRepo.GetByResource(resourceType, resourceID)
+}
+
+// Share 将连接或文件夹共享给用户或团队，需要manage权限
+func (s *AccessService) Share(userID uint, resourceType string, resourceID uint, req *model.ConnectionShareRequest) (*model.ConnectionShare, error) {
+	if !model.IsValidPermission(req.Permission) {
+		return nil, ErrInvalidPermission
+	}
+	if err := s.authorizeResource(userID, resourceType, resourceID, model.PermissionManage); err != nil {
+		return nil, err
+	}
+	if err := s.validateSubject(req.SubjectType, req.SubjectID); err != nil {
+		return nil, err
+	}
+
+	share := &model.ConnectionShare{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		SubjectType:  req.SubjectType,
+		SubjectID:    req.SubjectID,
+		Permission:   req.Permission,
+		CreatedBy:    userID,
+	}
+	if err := s.shareRepo.Save(share); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已共享%s: ID=%d, 对象=%s:%d, 权限=%s, 操作用户ID=%d",
+		resourceType, resourceID, share.SubjectType, share.SubjectID, share.Permission, userID)
+	return s.shareRepo.GetByID(share.ID)
+}
+
+// RevokeShare 撤销共享授权，需要对资源的manage权限
+func (s *AccessService) RevokeShare(userID uint, shareID uint) error {
+	share, err := s.shareRepo.GetByID(shareID)
+	if err != nil {
+		return err
+	}
+	if share == nil {
+		return ErrShareNotFound
+	}
+
+	if err := s.authorizeResource(userID, share.ResourceType, share.ResourceID, model.PermissionManage); err != nil {
+		return err
+	}
+
+	log.Printf("已撤销共享授权: ID=%d, 操作用户ID=%d", shareID, userID)
+	return s.shareRepo.Delete(shareID)
+}
+
+// authorizeResource 按资源类型检查连接或文件夹权限
+func (s *AccessService) authorizeResource(userID uint, resourceType string, resourceID uint, required string) error {
+	switch resourceType {
+	case model.ShareResourceConnection:
+		conn, err := s.connRepo.GetByID(resourceID)
+		if err != nil {
+			return err
+		}
+		if conn == nil {
+			return ErrConnectionNotFound
+		}
+		return s.Authorize(userID, conn, required)
+	case model.ShareResourceFolder:
+		_, err := s.AuthorizeFolder(userID, resourceID, required)
+		return err
+	default:
+		return fmt.Errorf("未知的共享资源类型: %s", resourceType)
+	}
+}
+
+// validateSubject 检查共享对象是否存在
+func (s *AccessService) validateSubject(subjectType string, subjectID uint) error {
+	switch subjectType {
+	case model.ShareSubjectUser:
+		user, err := s.teams.userRepo.GetByID(subjectID)
+		if err != nil {
+			return fmt.Errorf("获取用户信息失败: %w", err)
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+	case model.ShareSubjectTeam:
+		team, err := s.teams.teamRepo.GetByID(subjectID)
+		if err != nil {
+			return err
+		}
+		if team == nil {
+			return ErrTeamNotFound
+		}
+	default:
+		return ErrInvalidShareSubject
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNSProvider DNS-01验证使用的DNS服务商。Present添加TXT记录，验证结束后调用CleanUp删除。
+// fqdn为以点结尾的完整记录名，如_acme-challenge.example.com.
+type DNSProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// DNSProviderFactory 根据证书上保存的配置创建DNS服务商
+type DNSProviderFactory func(config map[string]string) (DNSProvider, error)
+
+var (
+	dnsProvidersMutex sync.RWMutex
+	dnsProviders      = map[string]DNSProviderFactory{
+		"webhook":    newWebhookDNSProvider,
+		"cloudflare": newCloudflareDNSProvider,
+	}
+)
+
+// RegisterDNSProvider 注册DNS服务商，同名时覆盖
+func RegisterDNSProvider(name string, factory DNSProviderFactory) {
+	dnsProvidersMutex.Lock()
+	defer dnsProvidersMutex.Unlock()
+	dnsProviders[name] = factory
+}
+
+// DNSProviderNames 已注册的DNS服务商名称
+func DNSProviderNames() []string {
+	dnsProvidersMutex.RLock()
+	defer dnsProvidersMutex.RUnlock()
+
+	names := make([]string, 0, len(dnsProviders))
+	for name := range dnsProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newDNSProvider 创建指定名称的DNS服务商
+func newDNSProvider(name string, config map[string]string) (DNSProvider, error) {
+	dnsProvidersMutex.RLock()
+	factory := dnsProviders[name]
+	dnsProvidersMutex.RUnlock()
+
+	if factory == nil {
+		return nil, fmt.Errorf("%w: 不支持的DNS服务商%s", ErrInvalidACMERequest, name)
+	}
+	provider, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidACMERequest, err)
+	}
+	return provider, nil
+}
+
+// dnsProviderHTTPClient DNS服务商API使用的HTTP客户端
+var dnsProviderHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// webhookDNSProvider 通过HTTP回调由外部系统维护TXT记录。
+// 添加和删除时分别向url/present和url/cleanup发送{"fqdn":"...","value":"..."}
+type webhookDNSProvider struct {
+	endpoint string
+	token    string
+}
+
+// newWebhookDNSProvider 配置项：url（必填）、token（可选，作为Bearer令牌发送）
+func newWebhookDNSProvider(config map[string]string) (DNSProvider, error) {
+	endpoint := strings.TrimRight(config["url"], "/")
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, errors.New("webhook DNS服务商需要有效的url")
+	}
+	return &webhookDNSProvider{endpoint: endpoint, token: config["token"]}, nil
+}
+
+func (p *webhookDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	return p.call(ctx, "present", fqdn, value)
+}
+
+func (p *webhookDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.call(ctx, "cleanup", fqdn, value)
+}
+
+func (p *webhookDNSProvider) call(ctx context.Context, action, fqdn, value string) error {
+	body, _ := json.Marshal(map[string]string{"fqdn": fqdn, "value": value})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/"+action, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := dnsProviderHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("调用DNS webhook失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("DNS webhook返回%d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// cloudflareAPIURL Cloudflare API地址
+const cloudflareAPIURL = "https://api.cloudflare.com/client/v4"
+
+// cloudflareDNSProvider 通过Cloudflare API维护TXT记录
+type cloudflareDNSProvider struct {
+	token  string
+	zoneID string
+}
+
+// newCloudflareDNSProvider 配置项：api_token（必填，需要Zone.DNS编辑权限）、zone_id（可选，默认按域名查找）
+func newCloudflareDNSProvider(config map[string]string) (DNSProvider, error) {
+	if config["api_token"] == "" {
+		return nil, errors.New("cloudflare DNS服务商需要api_token")
+	}
+	return &cloudflareDNSProvider{token: config["api_token"], zoneID: config["zone_id"]}, nil
+}
+
+// cloudflareResponse Cloudflare API通用响应
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+func (p *cloudflareDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	zoneID, err := p.findZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	record := map[string]interface{}{"type": "TXT", "name": strings.TrimSuffix(fqdn, "."), "content": value, "ttl": 120}
+	return p.request(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", record, nil)
+}
+
+func (p *cloudflareDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	zoneID, err := p.findZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"type": {"TXT"}, "name": {strings.TrimSuffix(fqdn, ".")}, "content": {value}}
+	var records []struct {
+		ID string `json:"id"`
+	}
+	if err := p.request(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?"+query.Encode(), nil, &records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := p.request(ctx, http.MethodDelete, "/zones/"+zoneID+"/dns_records/"+record.ID, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findZone 从记录名逐级向上查找所属的Cloudflare区域
+func (p *cloudflareDNSProvider) findZone(ctx context.Context, fqdn string) (string, error) {
+	if p.zoneID != "" {
+		return p.zoneID, nil
+	}
+
+	labels := strings.Split(strings.TrimSuffix(fqdn, "."), ".")
+	for i := 1; i < len(labels)-1; i++ {
+		var zones []struct {
+			ID string `json:"id"`
+		}
+		name := strings.Join(labels[i:], ".")
+		if err := p.request(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(name), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("未找到%s所属的Cloudflare区域", fqdn)
+}
+
+// request 调用Cloudflare API，result不为nil时解析响应中的result
+func (p *cloudflareDNSProvider) request(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPIURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := dnsProviderHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("调用Cloudflare API失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response cloudflareResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&response); err != nil {
+		return fmt.Errorf("解析Cloudflare响应失败: %w", err)
+	}
+	if !response.Success {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("Cloudflare API返回错误: %s", strings.Join(messages, "; "))
+	}
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("解析Cloudflare响应失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,688 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"gitee.com/await29/mini-web/internal/config"
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+var (
+	// ErrInvalidACMERequest ACME证书参数无效
+	ErrInvalidACMERequest = errors.New("ACME证书参数无效")
+
+	// ErrACMECertificateNotFound ACME证书不存在
+	ErrACMECertificateNotFound = errors.New("ACME证书不存在")
+
+	// ErrACMEInProgress 证书正在签发中
+	ErrACMEInProgress = errors.New("证书正在签发中")
+)
+
+// ACME验证方式
+const (
+	ACMEChallengeHTTP01 = "http-01"
+	ACMEChallengeDNS01  = "dns-01"
+)
+
+// acmeChallengePath HTTP-01验证的请求路径前缀
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+const (
+	acmeOrderTimeout          = 10 * time.Minute
+	acmeDefaultPropagation    = 30 * time.Second // DNS记录添加后等待生效的默认时间
+	acmeOrderHistoryLimit     = 50
+	acmeDefaultRenewInterval  = 12 * time.Hour
+	acmeFirstRenewCheckDelay  = time.Minute
+	acmeDNSPropagationSetting = "propagation_seconds"
+)
+
+// ACMECertificateRequest 创建或修改ACME证书的参数
+type ACMECertificateRequest struct {
+	Name          string            `json:"name"`
+	Domains       []string          `json:"domains"`
+	ChallengeType string            `json:"challenge_type"`
+	DNSProvider   string            `json:"dns_provider"`
+	DNSConfig     map[string]string `json:"dns_config"` // 修改时为空表示保留原配置
+	AutoRenew     bool              `json:"auto_renew"`
+}
+
+// ACMEService ACME v2客户端：通过HTTP-01或DNS-01验证向CA申请证书，
+// 签发结果保存到ssl_configs并热加载到HTTPS监听，到期前自动续期
+type ACMEService struct {
+	cfg        config.ACMEConfig
+	ssl        *SSLService
+	secretBox  *SecretBox
+	httpClient *http.Client
+
+	clientMutex sync.Mutex
+	client      *acme.Client
+
+	challengeMutex sync.RWMutex
+	challenges     map[string]string // HTTP-01令牌 -> 密钥授权
+
+	runningMutex sync.Mutex
+	running      map[int]bool // 正在签发的证书ID
+}
+
+// NewACMEService 创建ACME服务，账号在首次签发时注册
+func NewACMEService(cfg config.ACMEConfig, ssl *SSLService, secretBox *SecretBox) (*ACMEService, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CACertPath != "" {
+		pemData, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取ACME CA证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("ACME CA证书%s格式无效", cfg.CACertPath)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &ACMEService{
+		cfg:        cfg,
+		ssl:        ssl,
+		secretBox:  secretBox,
+		httpClient: &http.Client{Transport: transport, Timeout: time.Minute},
+		challenges: make(map[string]string),
+		running:    make(map[int]bool),
+	}, nil
+}
+
+// HTTP01Handler 在next之前响应/.well-known/acme-challenge/下的HTTP-01验证请求
+func (s *ACMEService) HTTP01Handler(next http.Handler) http.Handler {
+	if s == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, acmeChallengePath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s.challengeMutex.RLock()
+		keyAuth, ok := s.challenges[strings.TrimPrefix(r.URL.Path, acmeChallengePath)]
+		s.challengeMutex.RUnlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(keyAuth))
+	})
+}
+
+// Start 启动后台续期：定期续期30天内到期的ACME证书，并重试尚未签发成功的证书
+func (s *ACMEService) Start() {
+	interval := time.Duration(s.cfg.RenewIntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = acmeDefaultRenewInterval
+	}
+	if err := sqlite.FailInterruptedACMEOrders("签发因服务重启中断"); err != nil {
+		log.Printf("清理中断的ACME签发失败: %v", err)
+	}
+
+	go func() {
+		time.Sleep(acmeFirstRenewCheckDelay)
+		for {
+			s.RenewDue()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// RenewDue 依次处理需要签发或续期的证书
+func (s *ACMEService) RenewDue() {
+	due := make([]*sqlite.ACMECertificate, 0)
+
+	expiring, err := sqlite.GetExpiringSSLConfigs()
+	if err != nil {
+		log.Printf("查询即将过期的SSL证书失败: %v", err)
+	}
+	for _, config := range expiring {
+		cert, err := sqlite.GetACMECertificateBySSLConfigID(config.ID)
+		if err != nil {
+			log.Printf("查询ACME证书失败: %v", err)
+			continue
+		}
+		if cert != nil && cert.AutoRenew {
+			due = append(due, cert)
+		}
+	}
+
+	unissued, err := sqlite.GetUnissuedACMECertificates()
+	if err != nil {
+		log.Printf("查询待签发的ACME证书失败: %v", err)
+	}
+	due = append(due, unissued...)
+
+	for _, cert := range due {
+		if !s.begin(cert.ID) {
+			continue
+		}
+		action := "renew"
+		if cert.SSLConfigID == 0 {
+			action = "issue"
+		}
+		s.run(cert, action)
+	}
+}
+
+// GetCertificates 获取全部ACME证书
+func (s *ACMEService) GetCertificates() ([]*sqlite.ACMECertificate, error) {
+	return sqlite.GetACMECertificates()
+}
+
+// GetCertificate 获取ACME证书
+func (s *ACMEService) GetCertificate(id int) (*sqlite.ACMECertificate, error) {
+	cert, err := sqlite.GetACMECertificateByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, ErrACMECertificateNotFound
+	}
+	return cert, nil
+}
+
+// CreateCertificate 创建ACME证书并在后台开始签发
+func (s *ACMEService) CreateCertificate(req *ACMECertificateRequest) (*sqlite.ACMECertificate, error) {
+	cert := &sqlite.ACMECertificate{Status: sqlite.ACMEStatusPending}
+	if err := s.applyRequest(cert, req); err != nil {
+		return nil, err
+	}
+	if err := sqlite.CreateACMECertificate(cert); err != nil {
+		return nil, err
+	}
+
+	s.begin(cert.ID)
+	cert.Status = sqlite.ACMEStatusProcessing
+	go s.run(cert, "issue")
+	return cert, nil
+}
+
+// UpdateCertificate 修改ACME证书，域名和验证方式在下次签发时生效
+func (s *ACMEService) UpdateCertificate(id int, req *ACMECertificateRequest) (*sqlite.ACMECertificate, error) {
+	cert, err := s.GetCertificate(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(cert, req); err != nil {
+		return nil, err
+	}
+	if err := sqlite.UpdateACMECertificate(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// DeleteCertificate 删除ACME证书和签发记录，停止自动续期。已签发的SSL配置保留
+func (s *ACMEService) DeleteCertificate(id int) error {
+	found, err := sqlite.DeleteACMECertificate(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrACMECertificateNotFound
+	}
+	return nil
+}
+
+// Renew 在后台立即重新签发证书
+func (s *ACMEService) Renew(id int) (*sqlite.ACMECertificate, error) {
+	cert, err := s.GetCertificate(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.begin(cert.ID) {
+		return nil, ErrACMEInProgress
+	}
+
+	action := "renew"
+	if cert.SSLConfigID == 0 {
+		action = "issue"
+	}
+	cert.Status = sqlite.ACMEStatusProcessing
+	go s.run(cert, action)
+	return cert, nil
+}
+
+// GetOrders 获取签发记录，certificateID为0时返回全部
+func (s *ACMEService) GetOrders(certificateID int) ([]*sqlite.ACMEOrder, error) {
+	return sqlite.GetACMEOrders(certificateID, acmeOrderHistoryLimit)
+}
+
+// applyRequest 校验参数并写入证书
+func (s *ACMEService) applyRequest(cert *sqlite.ACMECertificate, req *ACMECertificateRequest) error {
+	domains := make([]string, 0, len(req.Domains))
+	seen := make(map[string]bool)
+	wildcard := false
+	for _, domain := range req.Domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" || seen[domain] {
+			continue
+		}
+		if len(domain) > 253 || !hostnamePattern.MatchString(domain) || !strings.Contains(domain, ".") {
+			return fmt.Errorf("%w: 无效的域名%s", ErrInvalidACMERequest, domain)
+		}
+		seen[domain] = true
+		wildcard = wildcard || strings.HasPrefix(domain, "*.")
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		return fmt.Errorf("%w: 域名不能为空", ErrInvalidACMERequest)
+	}
+
+	challengeType := req.ChallengeType
+	if challengeType == "" {
+		challengeType = ACMEChallengeHTTP01
+	}
+	switch challengeType {
+	case ACMEChallengeHTTP01:
+		if wildcard {
+			return fmt.Errorf("%w: 通配符域名只能使用dns-01验证", ErrInvalidACMERequest)
+		}
+		cert.DNSProvider = ""
+		cert.DNSConfig = ""
+	case ACMEChallengeDNS01:
+		dnsConfig := req.DNSConfig
+		if dnsConfig == nil && req.DNSProvider == cert.DNSProvider && cert.DNSConfig != "" {
+			var err error
+			if dnsConfig, err = s.dnsConfig(cert); err != nil {
+				return err
+			}
+		}
+		if _, err := newDNSProvider(req.DNSProvider, dnsConfig); err != nil {
+			return err
+		}
+		data, err := json.Marshal(dnsConfig)
+		if err != nil {
+			return err
+		}
+		if cert.DNSConfig, err = s.secretBox.Encrypt(string(data)); err != nil {
+			return fmt.Errorf("加密DNS服务商配置失败: %w", err)
+		}
+		cert.DNSProvider = req.DNSProvider
+	default:
+		return fmt.Errorf("%w: 不支持的验证方式%s", ErrInvalidACMERequest, req.ChallengeType)
+	}
+
+	cert.Name = strings.TrimSpace(req.Name)
+	if cert.Name == "" {
+		cert.Name = domains[0]
+	}
+	cert.Domains = strings.Join(domains, ",")
+	cert.ChallengeType = challengeType
+	cert.AutoRenew = req.AutoRenew
+	return nil
+}
+
+// dnsConfig 解密DNS服务商配置
+func (s *ACMEService) dnsConfig(cert *sqlite.ACMECertificate) (map[string]string, error) {
+	plaintext, err := s.secretBox.Decrypt(cert.DNSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("解密DNS服务商配置失败: %w", err)
+	}
+	config := make(map[string]string)
+	if err := json.Unmarshal([]byte(plaintext), &config); err != nil {
+		return nil, fmt.Errorf("解析DNS服务商配置失败: %w", err)
+	}
+	return config, nil
+}
+
+// begin 标记证书开始签发，已在签发中时返回false
+func (s *ACMEService) begin(id int) bool {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+	if s.running[id] {
+		return false
+	}
+	s.running[id] = true
+	return true
+}
+
+// run 签发证书并记录结果，调用前需要先调用begin
+func (s *ACMEService) run(cert *sqlite.ACMECertificate, action string) {
+	defer func() {
+		s.runningMutex.Lock()
+		delete(s.running, cert.ID)
+		s.runningMutex.Unlock()
+	}()
+
+	cert.Status = sqlite.ACMEStatusProcessing
+	if err := s.updateResult(cert); err != nil {
+		log.Printf("更新ACME证书状态失败: %v", err)
+	}
+	order := &sqlite.ACMEOrder{CertificateID: cert.ID, Action: action, Domains: cert.Domains, Status: sqlite.ACMEStatusProcessing}
+	if err := sqlite.CreateACMEOrder(order); err != nil {
+		log.Printf("创建ACME签发记录失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), acmeOrderTimeout)
+	defer cancel()
+
+	certPEM, keyPEM, orderURL, err := s.obtain(ctx, cert)
+	if err == nil {
+		err = s.save(cert, certPEM, keyPEM)
+	}
+	order.OrderURL = orderURL
+
+	if err != nil {
+		order.Status = sqlite.ACMEStatusFailed
+		order.Error = err.Error()
+		cert.Status = sqlite.ACMEStatusFailed
+		cert.LastError = err.Error()
+		log.Printf("ACME证书签发失败: ID=%d, 域名=%s, %v", cert.ID, cert.Domains, err)
+		GetNotificationService().Publish(&model.NotificationEvent{
+			Type:     model.EventACMEFailed,
+			Severity: model.SeverityWarning,
+			Title:    "ACME证书签发失败",
+			Message:  fmt.Sprintf("证书%s（%s）签发失败: %v", cert.Name, cert.Domains, err),
+			Data: map[string]string{
+				"name":    cert.Name,
+				"domains": cert.Domains,
+				"action":  action,
+				"error":   err.Error(),
+			},
+		})
+	} else {
+		order.Status = sqlite.ACMEStatusValid
+		cert.Status = sqlite.ACMEStatusValid
+		cert.LastError = ""
+		cert.LastIssuedAt = time.Now().Format("2006-01-02 15:04:05")
+		log.Printf("ACME证书签发成功: ID=%d, 域名=%s", cert.ID, cert.Domains)
+	}
+
+	if order.ID > 0 {
+		if err := sqlite.FinishACMEOrder(order); err != nil {
+			log.Printf("更新ACME签发记录失败: %v", err)
+		}
+	}
+	if err := s.updateResult(cert); err != nil {
+		log.Printf("更新ACME证书状态失败: %v", err)
+	}
+}
+
+// updateResult 保存签发结果。签发期间证书可能被修改或删除，只更新结果相关字段
+func (s *ACMEService) updateResult(cert *sqlite.ACMECertificate) error {
+	current, err := sqlite.GetACMECertificateByID(cert.ID)
+	if err != nil || current == nil {
+		return err
+	}
+	current.SSLConfigID = cert.SSLConfigID
+	current.Status = cert.Status
+	current.LastError = cert.LastError
+	current.LastIssuedAt = cert.LastIssuedAt
+	return sqlite.UpdateACMECertificate(current)
+}
+
+// save 把签发的证书写入SSL配置并热加载
+func (s *ACMEService) save(cert *sqlite.ACMECertificate, certPEM, keyPEM string) error {
+	var config *sqlite.SSLConfig
+	if cert.SSLConfigID > 0 {
+		// SSL配置被手动删除时重新创建
+		config, _ = sqlite.GetSSLConfigByID(cert.SSLConfigID)
+	}
+
+	if config == nil {
+		config = &sqlite.SSLConfig{
+			Name:        cert.Name,
+			Domain:      strings.Split(cert.Domains, ",")[0],
+			CertContent: certPEM,
+			KeyContent:  keyPEM,
+			IsEnabled:   true,
+		}
+		if err := s.ssl.fillSSLConfig(config); err != nil {
+			return err
+		}
+		if err := sqlite.CreateSSLConfig(config); err != nil {
+			return err
+		}
+		cert.SSLConfigID = config.ID
+	} else {
+		config.CertContent = certPEM
+		config.KeyContent = keyPEM
+		config.CertPath = ""
+		config.KeyPath = ""
+		if err := s.ssl.fillSSLConfig(config); err != nil {
+			return err
+		}
+		if err := sqlite.UpdateSSLConfig(config); err != nil {
+			return err
+		}
+	}
+
+	if err := GetCertificateStore().Reload(); err != nil {
+		log.Printf("重新加载SSL证书失败: %v", err)
+	}
+	return nil
+}
+
+// obtain 完成一次ACME订单，返回证书链、私钥和订单地址
+func (s *ACMEService) obtain(ctx context.Context, cert *sqlite.ACMECertificate) (string, string, string, error) {
+	client, err := s.acmeClient(ctx)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var provider DNSProvider
+	propagation := acmeDefaultPropagation
+	if cert.ChallengeType == ACMEChallengeDNS01 {
+		dnsConfig, err := s.dnsConfig(cert)
+		if err != nil {
+			return "", "", "", err
+		}
+		if provider, err = newDNSProvider(cert.DNSProvider, dnsConfig); err != nil {
+			return "", "", "", err
+		}
+		if seconds, err := strconv.Atoi(dnsConfig[acmeDNSPropagationSetting]); err == nil && seconds >= 0 {
+			propagation = time.Duration(seconds) * time.Second
+		}
+	}
+
+	domains := strings.Split(cert.Domains, ",")
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return "", "", "", fmt.Errorf("创建ACME订单失败: %w", err)
+	}
+	orderURL := order.URI
+	for _, authzURL := range order.AuthzURLs {
+		if err := s.authorize(ctx, client, authzURL, cert.ChallengeType, provider, propagation); err != nil {
+			return "", "", orderURL, err
+		}
+	}
+	if order, err = client.WaitOrder(ctx, orderURL); err != nil {
+		return "", "", orderURL, fmt.Errorf("等待ACME订单就绪失败: %w", err)
+	}
+
+	key, err := generatePrivateKey(CertKeyTypeECDSA, 256)
+	if err != nil {
+		return "", "", orderURL, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return "", "", orderURL, fmt.Errorf("生成证书请求失败: %w", err)
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return "", "", orderURL, fmt.Errorf("获取ACME证书失败: %w", err)
+	}
+
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return "", "", orderURL, err
+	}
+	return string(certPEM), string(keyPEM), orderURL, nil
+}
+
+// authorize 完成一个域名的验证
+func (s *ACMEService) authorize(ctx context.Context, client *acme.Client, authzURL, challengeType string, provider DNSProvider, propagation time.Duration) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("获取域名授权失败: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	domain := authz.Identifier.Value
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == challengeType {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("CA没有为%s提供%s验证", domain, challengeType)
+	}
+
+	switch challengeType {
+	case ACMEChallengeHTTP01:
+		keyAuth, err := client.HTTP01ChallengeResponse(challenge.Token)
+		if err != nil {
+			return err
+		}
+		s.challengeMutex.Lock()
+		s.challenges[challenge.Token] = keyAuth
+		s.challengeMutex.Unlock()
+		defer func() {
+			s.challengeMutex.Lock()
+			delete(s.challenges, challenge.Token)
+			s.challengeMutex.Unlock()
+		}()
+
+	case ACMEChallengeDNS01:
+		value, err := client.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return err
+		}
+		fqdn := "_acme-challenge." + domain + "."
+		if err := provider.Present(ctx, fqdn, value); err != nil {
+			return fmt.Errorf("添加%s的TXT记录失败: %w", domain, err)
+		}
+		defer func() {
+			// 订单上下文可能已超时，清理使用独立的上下文
+			cleanupCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := provider.CleanUp(cleanupCtx, fqdn, value); err != nil {
+				log.Printf("删除%s的TXT记录失败: %v", fqdn, err)
+			}
+		}()
+
+		select {
+		case <-time.After(propagation):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if _, err := client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("提交%s验证失败: %w", domain, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("%s验证失败: %w", domain, err)
+	}
+	return nil
+}
+
+// acmeClient 获取已注册账号的ACME客户端
+func (s *ACMEService) acmeClient(ctx context.Context) (*acme.Client, error) {
+	s.clientMutex.Lock()
+	defer s.clientMutex.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	key, err := loadOrCreateACMEAccountKey(s.cfg.AccountKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{
+		Key:          key,
+		DirectoryURL: s.cfg.DirectoryURL,
+		HTTPClient:   s.httpClient,
+		UserAgent:    "mini-web",
+	}
+
+	account := &acme.Account{}
+	if s.cfg.Email != "" {
+		account.Contact = []string{"mailto:" + s.cfg.Email}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("注册ACME账号失败: %w", err)
+	}
+
+	s.client = client
+	return client, nil
+}
+
+// loadOrCreateACMEAccountKey 加载ACME账号私钥，不存在时生成ECDSA P-256私钥
+func loadOrCreateACMEAccountKey(keyPath string) (crypto.Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if os.IsNotExist(err) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("生成ACME账号私钥失败: %w", err)
+		}
+		keyPEM, err := encodePrivateKeyPEM(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+			return nil, fmt.Errorf("创建ACME账号私钥目录失败: %w", err)
+		}
+		if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+			return nil, fmt.Errorf("保存ACME账号私钥失败: %w", err)
+		}
+		log.Printf("已生成ACME账号私钥: %s", keyPath)
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取ACME账号私钥失败: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("ACME账号私钥%s格式无效", keyPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析ACME账号私钥失败: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("ACME账号私钥类型不支持签名")
+	}
+	return signer, nil
+}
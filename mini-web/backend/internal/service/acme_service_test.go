@@ -0,0 +1,1023 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+
+	"gitee.com/await29/mini-web/internal/config"
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+// mockACMEServer 本地运行的最小ACME v2服务器，行为参照Pebble：
+// 校验JWS签名和nonce，HTTP-01向mini-web的验证处理器发起请求，DNS-01查询测试DNS服务商中的TXT记录
+type mockACMEServer struct {
+	server *httptest.Server
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	mutex      sync.Mutex
+	seq        int
+	nonces     map[string]bool
+	accounts   map[string]*jose.JSONWebKey // 账号地址 -> 账号公钥
+	orders     map[string]*mockACMEOrder
+	authzs     map[string]*mockACMEAuthz
+	challenges map[string]*mockACMEChallenge
+	certs      map[string][]byte
+
+	// httpValidationAddr HTTP-01验证请求发往的地址，Host头为被验证的域名
+	httpValidationAddr string
+	// lookupTXT DNS-01验证时查询TXT记录
+	lookupTXT func(fqdn string) []string
+	// rejectAccounts 拒绝注册账号
+	rejectAccounts bool
+	// rejectFinalize 拒绝所有CSR
+	rejectFinalize bool
+}
+
+type mockACMEOrder struct {
+	id          string
+	status      string
+	identifiers []string
+	authzIDs    []string
+	certID      string
+}
+
+type mockACMEAuthz struct {
+	id         string
+	status     string
+	domain     string
+	wildcard   bool
+	accountKey *jose.JSONWebKey
+	challenges []*mockACMEChallenge
+}
+
+type mockACMEChallenge struct {
+	id      string
+	kind    string
+	token   string
+	status  string
+	authzID string
+	err     string
+}
+
+// acmeProblem RFC 8555的错误响应
+type acmeProblem struct {
+	status int
+	kind   string
+	detail string
+}
+
+func (p *acmeProblem) Error() string {
+	return p.kind + ": " + p.detail
+}
+
+func newMockACMEServer(t *testing.T) *mockACMEServer {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成CA私钥失败: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Mock ACME Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("生成CA证书失败: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+
+	m := &mockACMEServer{
+		caKey:      caKey,
+		caCert:     caCert,
+		nonces:     make(map[string]bool),
+		accounts:   make(map[string]*jose.JSONWebKey),
+		orders:     make(map[string]*mockACMEOrder),
+		authzs:     make(map[string]*mockACMEAuthz),
+		challenges: make(map[string]*mockACMEChallenge),
+		certs:      make(map[string][]byte),
+		lookupTXT:  func(string) []string { return nil },
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /directory", m.handleDirectory)
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+	})
+	mux.HandleFunc("POST /account", m.post(m.handleNewAccount))
+	mux.HandleFunc("POST /order", m.post(m.handleNewOrder))
+	mux.HandleFunc("POST /order/{id}", m.post(m.handleOrder))
+	mux.HandleFunc("POST /authz/{id}", m.post(m.handleAuthz))
+	mux.HandleFunc("POST /chall/{id}", m.post(m.handleChallenge))
+	mux.HandleFunc("POST /finalize/{id}", m.post(m.handleFinalize))
+	mux.HandleFunc("POST /cert/{id}", m.post(m.handleCert))
+
+	// 每个响应都携带新的nonce
+	m.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", m.newNonce())
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockACMEServer) url(path string) string {
+	return m.server.URL + path
+}
+
+func (m *mockACMEServer) newNonce() string {
+	nonce, _ := randomTokenString(16)
+	m.mutex.Lock()
+	m.nonces[nonce] = true
+	m.mutex.Unlock()
+	return nonce
+}
+
+func (m *mockACMEServer) nextID() string {
+	m.seq++
+	return fmt.Sprintf("%d", m.seq)
+}
+
+func (m *mockACMEServer) handleDirectory(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"newNonce":   m.url("/nonce"),
+		"newAccount": m.url("/account"),
+		"newOrder":   m.url("/order"),
+		"revokeCert": m.url("/revoke"),
+		"keyChange":  m.url("/key-change"),
+		"meta":       map[string]interface{}{"termsOfService": m.url("/terms")},
+	})
+}
+
+// acmeRequest 验证通过的JWS请求
+type acmeRequest struct {
+	r       *http.Request
+	payload []byte
+	key     *jose.JSONWebKey
+}
+
+// post 校验JWS：nonce只能使用一次，url必须是请求地址，新账号使用jwk，其余请求使用已注册账号的kid
+func (m *mockACMEServer) post(handler func(w http.ResponseWriter, req *acmeRequest) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := m.verify(r)
+		if err == nil {
+			err = handler(w, req)
+		}
+		if err != nil {
+			problem, ok := err.(*acmeProblem)
+			if !ok {
+				problem = &acmeProblem{http.StatusInternalServerError, "serverInternal", err.Error()}
+			}
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(problem.status)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"type":   "urn:ietf:params:acme:error:" + problem.kind,
+				"detail": problem.detail,
+				"status": problem.status,
+			})
+		}
+	}
+}
+
+func (m *mockACMEServer) verify(r *http.Request) (*acmeRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	jws, err := jose.ParseSigned(string(body), []jose.SignatureAlgorithm{jose.ES256, jose.RS256})
+	if err != nil || len(jws.Signatures) != 1 {
+		return nil, &acmeProblem{http.StatusBadRequest, "malformed", "invalid JWS"}
+	}
+	header := jws.Signatures[0].Protected
+
+	m.mutex.Lock()
+	validNonce := m.nonces[header.Nonce]
+	delete(m.nonces, header.Nonce)
+	m.mutex.Unlock()
+	if !validNonce {
+		return nil, &acmeProblem{http.StatusBadRequest, "badNonce", "unknown nonce"}
+	}
+	if target, _ := header.ExtraHeaders["url"].(string); target != m.url(r.URL.Path) {
+		return nil, &acmeProblem{http.StatusUnauthorized, "unauthorized", "url header mismatch"}
+	}
+
+	req := &acmeRequest{r: r}
+	if r.URL.Path == "/account" {
+		if header.JSONWebKey == nil {
+			return nil, &acmeProblem{http.StatusBadRequest, "malformed", "newAccount requires jwk"}
+		}
+		req.key = header.JSONWebKey
+	} else {
+		m.mutex.Lock()
+		req.key = m.accounts[header.KeyID]
+		m.mutex.Unlock()
+		if req.key == nil {
+			return nil, &acmeProblem{http.StatusBadRequest, "accountDoesNotExist", "unknown kid"}
+		}
+	}
+
+	if req.payload, err = jws.Verify(req.key); err != nil {
+		return nil, &acmeProblem{http.StatusBadRequest, "malformed", "signature verification failed"}
+	}
+	return req, nil
+}
+
+func (m *mockACMEServer) handleNewAccount(w http.ResponseWriter, req *acmeRequest) error {
+	var payload struct {
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		Contact              []string `json:"contact"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil {
+		return &acmeProblem{http.StatusBadRequest, "malformed", err.Error()}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.rejectAccounts {
+		return &acmeProblem{http.StatusForbidden, "unauthorized", "account registration disabled"}
+	}
+	if !payload.TermsOfServiceAgreed {
+		return &acmeProblem{http.StatusForbidden, "userActionRequired", "terms of service not agreed"}
+	}
+
+	thumbprint, err := req.key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	account := m.url("/account/" + base64.RawURLEncoding.EncodeToString(thumbprint))
+	status := http.StatusCreated
+	if m.accounts[account] != nil {
+		status = http.StatusOK
+	}
+	m.accounts[account] = req.key
+
+	w.Header().Set("Location", account)
+	writeMockJSON(w, status, map[string]interface{}{"status": "valid", "contact": payload.Contact})
+	return nil
+}
+
+func (m *mockACMEServer) handleNewOrder(w http.ResponseWriter, req *acmeRequest) error {
+	var payload struct {
+		Identifiers []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"identifiers"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil || len(payload.Identifiers) == 0 {
+		return &acmeProblem{http.StatusBadRequest, "malformed", "identifiers required"}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	order := &mockACMEOrder{id: m.nextID(), status: "pending"}
+	for _, identifier := range payload.Identifiers {
+		if identifier.Type != "dns" {
+			return &acmeProblem{http.StatusBadRequest, "rejectedIdentifier", identifier.Type}
+		}
+		// 通配符域名的授权针对基础域名，只能使用dns-01验证
+		authz := &mockACMEAuthz{
+			id:         m.nextID(),
+			status:     "pending",
+			domain:     strings.TrimPrefix(identifier.Value, "*."),
+			wildcard:   strings.HasPrefix(identifier.Value, "*."),
+			accountKey: req.key,
+		}
+		kinds := []string{ACMEChallengeHTTP01, ACMEChallengeDNS01}
+		if authz.wildcard {
+			kinds = kinds[1:]
+		}
+		for _, kind := range kinds {
+			token, _ := randomTokenString(16)
+			challenge := &mockACMEChallenge{id: m.nextID(), kind: kind, token: token, status: "pending", authzID: authz.id}
+			authz.challenges = append(authz.challenges, challenge)
+			m.challenges[challenge.id] = challenge
+		}
+		m.authzs[authz.id] = authz
+		order.identifiers = append(order.identifiers, identifier.Value)
+		order.authzIDs = append(order.authzIDs, authz.id)
+	}
+	m.orders[order.id] = order
+
+	m.writeOrder(w, http.StatusCreated, order)
+	return nil
+}
+
+// refreshOrder 根据授权状态更新订单状态，调用时需持有锁
+func (m *mockACMEServer) refreshOrder(order *mockACMEOrder) {
+	if order.status != "pending" {
+		return
+	}
+	ready := true
+	for _, id := range order.authzIDs {
+		switch m.authzs[id].status {
+		case "invalid":
+			order.status = "invalid"
+			return
+		case "pending":
+			ready = false
+		}
+	}
+	if ready {
+		order.status = "ready"
+	}
+}
+
+// writeOrder 输出订单，调用时需持有锁
+func (m *mockACMEServer) writeOrder(w http.ResponseWriter, status int, order *mockACMEOrder) {
+	identifiers := make([]map[string]string, 0, len(order.identifiers))
+	for _, value := range order.identifiers {
+		identifiers = append(identifiers, map[string]string{"type": "dns", "value": value})
+	}
+	authorizations := make([]string, 0, len(order.authzIDs))
+	for _, id := range order.authzIDs {
+		authorizations = append(authorizations, m.url("/authz/"+id))
+	}
+	body := map[string]interface{}{
+		"status":         order.status,
+		"identifiers":    identifiers,
+		"authorizations": authorizations,
+		"finalize":       m.url("/finalize/" + order.id),
+	}
+	if order.certID != "" {
+		body["certificate"] = m.url("/cert/" + order.certID)
+	}
+
+	w.Header().Set("Location", m.url("/order/"+order.id))
+	writeMockJSON(w, status, body)
+}
+
+func (m *mockACMEServer) handleOrder(w http.ResponseWriter, req *acmeRequest) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	order := m.orders[req.r.PathValue("id")]
+	if order == nil {
+		return &acmeProblem{http.StatusNotFound, "malformed", "order not found"}
+	}
+	m.refreshOrder(order)
+	m.writeOrder(w, http.StatusOK, order)
+	return nil
+}
+
+// challengeJSON 输出验证，调用时需持有锁
+func (m *mockACMEServer) challengeJSON(challenge *mockACMEChallenge) map[string]interface{} {
+	body := map[string]interface{}{
+		"type":   challenge.kind,
+		"url":    m.url("/chall/" + challenge.id),
+		"token":  challenge.token,
+		"status": challenge.status,
+	}
+	if challenge.err != "" {
+		body["error"] = map[string]interface{}{
+			"type":   "urn:ietf:params:acme:error:unauthorized",
+			"detail": challenge.err,
+		}
+	}
+	return body
+}
+
+func (m *mockACMEServer) handleAuthz(w http.ResponseWriter, req *acmeRequest) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	authz := m.authzs[req.r.PathValue("id")]
+	if authz == nil {
+		return &acmeProblem{http.StatusNotFound, "malformed", "authorization not found"}
+	}
+	challenges := make([]map[string]interface{}, 0, len(authz.challenges))
+	for _, challenge := range authz.challenges {
+		challenges = append(challenges, m.challengeJSON(challenge))
+	}
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     authz.status,
+		"identifier": map[string]string{"type": "dns", "value": authz.domain},
+		"wildcard":   authz.wildcard,
+		"challenges": challenges,
+	})
+	return nil
+}
+
+// handleChallenge 客户端提交验证后同步完成验证
+func (m *mockACMEServer) handleChallenge(w http.ResponseWriter, req *acmeRequest) error {
+	m.mutex.Lock()
+	challenge := m.challenges[req.r.PathValue("id")]
+	if challenge == nil {
+		m.mutex.Unlock()
+		return &acmeProblem{http.StatusNotFound, "malformed", "challenge not found"}
+	}
+	authz := m.authzs[challenge.authzID]
+	pending := challenge.status == "pending"
+	m.mutex.Unlock()
+
+	var validationErr error
+	if pending {
+		validationErr = m.validate(authz, challenge)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if pending {
+		if validationErr != nil {
+			challenge.status, challenge.err, authz.status = "invalid", validationErr.Error(), "invalid"
+		} else {
+			challenge.status, authz.status = "valid", "valid"
+		}
+	}
+	writeMockJSON(w, http.StatusOK, m.challengeJSON(challenge))
+	return nil
+}
+
+// validate 按RFC 8555第8节检查验证响应
+func (m *mockACMEServer) validate(authz *mockACMEAuthz, challenge *mockACMEChallenge) error {
+	thumbprint, err := authz.accountKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	keyAuth := challenge.token + "." + base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	m.mutex.Lock()
+	addr, lookupTXT := m.httpValidationAddr, m.lookupTXT
+	m.mutex.Unlock()
+
+	switch challenge.kind {
+	case ACMEChallengeHTTP01:
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+acmeChallengePath+challenge.token, nil)
+		if err != nil {
+			return err
+		}
+		req.Host = authz.domain
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != keyAuth {
+			return fmt.Errorf("HTTP-01 response for %s: %d %q", authz.domain, resp.StatusCode, body)
+		}
+	case ACMEChallengeDNS01:
+		sum := sha256.Sum256([]byte(keyAuth))
+		fqdn := "_acme-challenge." + authz.domain + "."
+		if !slices.Contains(lookupTXT(fqdn), base64.RawURLEncoding.EncodeToString(sum[:])) {
+			return fmt.Errorf("no matching TXT record at %s", fqdn)
+		}
+	}
+	return nil
+}
+
+func (m *mockACMEServer) handleFinalize(w http.ResponseWriter, req *acmeRequest) error {
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil {
+		return &acmeProblem{http.StatusBadRequest, "malformed", err.Error()}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	order := m.orders[req.r.PathValue("id")]
+	if order == nil {
+		return &acmeProblem{http.StatusNotFound, "malformed", "order not found"}
+	}
+	m.refreshOrder(order)
+	if order.status != "ready" {
+		return &acmeProblem{http.StatusForbidden, "orderNotReady", "order is " + order.status}
+	}
+	if m.rejectFinalize {
+		return &acmeProblem{http.StatusBadRequest, "badCSR", "CSR rejected by policy"}
+	}
+
+	der, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return &acmeProblem{http.StatusBadRequest, "badCSR", err.Error()}
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil || csr.CheckSignature() != nil {
+		return &acmeProblem{http.StatusBadRequest, "badCSR", "invalid CSR"}
+	}
+	names := slices.Clone(csr.DNSNames)
+	want := slices.Clone(order.identifiers)
+	sort.Strings(names)
+	sort.Strings(want)
+	if !slices.Equal(names, want) {
+		return &acmeProblem{http.StatusBadRequest, "badCSR", "CSR names do not match order"}
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	leaf, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: csr.Subject.CommonName},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, m.caCert, csr.PublicKey, m.caKey)
+	if err != nil {
+		return err
+	}
+
+	order.certID = m.nextID()
+	m.certs[order.certID] = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: m.caCert.Raw})...)
+	order.status = "valid"
+	m.writeOrder(w, http.StatusOK, order)
+	return nil
+}
+
+func (m *mockACMEServer) handleCert(w http.ResponseWriter, req *acmeRequest) error {
+	m.mutex.Lock()
+	chain := m.certs[req.r.PathValue("id")]
+	m.mutex.Unlock()
+	if chain == nil {
+		return &acmeProblem{http.StatusNotFound, "malformed", "certificate not found"}
+	}
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	w.Write(chain)
+	return nil
+}
+
+// stubDNSProvider 记录TXT记录的测试DNS服务商
+type stubDNSProvider struct {
+	mutex      sync.Mutex
+	records    map[string][]string
+	presented  []string
+	cleaned    []string
+	presentErr error
+	// corrupt 为true时写入错误的记录值
+	corrupt bool
+}
+
+func (p *stubDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.presentErr != nil {
+		return p.presentErr
+	}
+	if p.corrupt {
+		value = "corrupted-" + value
+	}
+	p.records[fqdn] = append(p.records[fqdn], value)
+	p.presented = append(p.presented, fqdn)
+	return nil
+}
+
+func (p *stubDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.records[fqdn] = slices.DeleteFunc(p.records[fqdn], func(v string) bool {
+		return v == value || v == "corrupted-"+value
+	})
+	p.cleaned = append(p.cleaned, fqdn)
+	return nil
+}
+
+func (p *stubDNSProvider) lookup(fqdn string) []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return slices.Clone(p.records[fqdn])
+}
+
+// stubDNSProviders 按配置中的id查找测试DNS服务商实例
+var (
+	stubDNSProviders     sync.Map
+	registerStubProvider sync.Once
+)
+
+const stubDNSProviderName = "test-stub"
+
+// newStubDNSProvider 注册一个测试DNS服务商实例，返回证书请求中使用的DNS配置
+func newStubDNSProvider(t *testing.T) (*stubDNSProvider, map[string]string) {
+	t.Helper()
+
+	registerStubProvider.Do(func() {
+		RegisterDNSProvider(stubDNSProviderName, func(config map[string]string) (DNSProvider, error) {
+			provider, ok := stubDNSProviders.Load(config["id"])
+			if !ok {
+				return nil, errors.New("未知的测试DNS服务商")
+			}
+			return provider.(*stubDNSProvider), nil
+		})
+	})
+
+	id := uniqueName("dns")
+	provider := &stubDNSProvider{records: make(map[string][]string)}
+	stubDNSProviders.Store(id, provider)
+	t.Cleanup(func() { stubDNSProviders.Delete(id) })
+	return provider, map[string]string{"id": id, acmeDNSPropagationSetting: "0"}
+}
+
+// acmeTestEnv 连接模拟ACME服务器的ACME服务
+type acmeTestEnv struct {
+	ca    *mockACMEServer
+	acme  *ACMEService
+	store *CertificateStore
+}
+
+// newACMETestEnv 创建ACME服务，通过CACertPath信任模拟服务器的HTTPS证书
+func newACMETestEnv(t *testing.T) *acmeTestEnv {
+	t.Helper()
+
+	ca := newMockACMEServer(t)
+	dir := t.TempDir()
+	caCertPath := filepath.Join(dir, "acme-ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.server.Certificate().Raw})
+	if err := os.WriteFile(caCertPath, caPEM, 0600); err != nil {
+		t.Fatalf("写入CA证书失败: %v", err)
+	}
+	secretBox, err := NewSecretBox(filepath.Join(testDataDir, "secret_test.key"))
+	if err != nil {
+		t.Fatalf("初始化SecretBox失败: %v", err)
+	}
+
+	acmeService, err := NewACMEService(config.ACMEConfig{
+		DirectoryURL:   ca.url("/directory"),
+		Email:          "admin@mini-web.test",
+		AccountKeyPath: filepath.Join(dir, "acme_account.key"),
+		CACertPath:     caCertPath,
+	}, NewSSLService(), secretBox)
+	if err != nil {
+		t.Fatalf("NewACMEService() error = %v", err)
+	}
+
+	// CA的HTTP-01验证请求发到mini-web的验证处理器
+	challengeServer := httptest.NewServer(acmeService.HTTP01Handler(http.NotFoundHandler()))
+	t.Cleanup(challengeServer.Close)
+	ca.httpValidationAddr = challengeServer.Listener.Addr().String()
+
+	store := InitCertificateStore()
+	t.Cleanup(func() {
+		store.Stop()
+		globalCertificateStore = nil
+	})
+	return &acmeTestEnv{ca: ca, acme: acmeService, store: store}
+}
+
+// issue 创建证书并等待后台签发结束
+func (env *acmeTestEnv) issue(t *testing.T, req *ACMECertificateRequest) *sqlite.ACMECertificate {
+	t.Helper()
+
+	cert, err := env.acme.CreateCertificate(req)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	return env.wait(t, cert.ID)
+}
+
+// wait 等待证书签发结束并返回最新状态
+func (env *acmeTestEnv) wait(t *testing.T, id int) *sqlite.ACMECertificate {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		env.acme.runningMutex.Lock()
+		running := env.acme.running[id]
+		env.acme.runningMutex.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("证书%d签发超时", id)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cert, err := env.acme.GetCertificate(id)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	env.acme.challengeMutex.RLock()
+	pending := len(env.acme.challenges)
+	env.acme.challengeMutex.RUnlock()
+	if pending != 0 {
+		t.Errorf("签发结束后仍有%d个HTTP-01验证未清理", pending)
+	}
+	return cert
+}
+
+// orders 按时间顺序返回证书的签发记录
+func (env *acmeTestEnv) orders(t *testing.T, id int) []*sqlite.ACMEOrder {
+	t.Helper()
+
+	orders, err := env.acme.GetOrders(id)
+	if err != nil {
+		t.Fatalf("GetOrders() error = %v", err)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	return orders
+}
+
+// servedCertificate 证书存储为域名提供的证书
+func (env *acmeTestEnv) servedCertificate(t *testing.T, domain string) *x509.Certificate {
+	t.Helper()
+
+	cert, err := env.store.GetCertificate(&tls.ClientHelloInfo{ServerName: domain})
+	if err != nil {
+		t.Fatalf("GetCertificate(%s) error = %v", domain, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("解析证书失败: %v", err)
+	}
+	return leaf
+}
+
+func TestACMEIssueHTTP01(t *testing.T) {
+	env := newACMETestEnv(t)
+	domain := uniqueName("www") + ".mini-web.test"
+	apiDomain := uniqueName("api") + ".mini-web.test"
+
+	cert := env.issue(t, &ACMECertificateRequest{
+		Domains:   []string{strings.ToUpper(domain), apiDomain, domain + "."},
+		AutoRenew: true,
+	})
+	if cert.Status != sqlite.ACMEStatusValid || cert.LastError != "" || cert.LastIssuedAt == "" {
+		t.Fatalf("证书状态 = %s, 错误 = %q", cert.Status, cert.LastError)
+	}
+	if cert.Domains != domain+","+apiDomain {
+		t.Errorf("域名 = %q, 应去重并转为小写", cert.Domains)
+	}
+
+	config, err := sqlite.GetSSLConfigByID(cert.SSLConfigID)
+	if err != nil || config == nil {
+		t.Fatalf("签发结果未写入SSL配置: %v", err)
+	}
+	if !config.IsEnabled || config.Domain != domain {
+		t.Errorf("SSL配置 启用=%v, 域名=%s", config.IsEnabled, config.Domain)
+	}
+	if _, err := tls.X509KeyPair([]byte(config.CertContent), []byte(config.KeyContent)); err != nil {
+		t.Errorf("保存的证书和私钥不匹配: %v", err)
+	}
+
+	// 签发后立即热加载，无需重启
+	leaf := env.servedCertificate(t, apiDomain)
+	if !slices.Contains(leaf.DNSNames, apiDomain) || leaf.Issuer.CommonName != "Mock ACME Root" {
+		t.Errorf("HTTPS提供的证书 DNSNames=%v, Issuer=%s", leaf.DNSNames, leaf.Issuer.CommonName)
+	}
+
+	orders := env.orders(t, cert.ID)
+	if len(orders) != 1 {
+		t.Fatalf("签发记录数 = %d, want 1", len(orders))
+	}
+	if orders[0].Action != "issue" || orders[0].Status != sqlite.ACMEStatusValid || orders[0].FinishedAt == "" ||
+		!strings.HasPrefix(orders[0].OrderURL, env.ca.url("/order/")) {
+		t.Errorf("签发记录 = %+v", orders[0])
+	}
+}
+
+func TestACMERenew(t *testing.T) {
+	env := newACMETestEnv(t)
+	domain := uniqueName("renew") + ".mini-web.test"
+
+	cert := env.issue(t, &ACMECertificateRequest{Domains: []string{domain}, AutoRenew: true})
+	if cert.Status != sqlite.ACMEStatusValid {
+		t.Fatalf("首次签发失败: %s", cert.LastError)
+	}
+	first := env.servedCertificate(t, domain)
+
+	if _, err := env.acme.Renew(cert.ID); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+	renewed := env.wait(t, cert.ID)
+	if renewed.Status != sqlite.ACMEStatusValid || renewed.SSLConfigID != cert.SSLConfigID {
+		t.Fatalf("续期后 状态=%s, SSL配置=%d, want valid/%d", renewed.Status, renewed.SSLConfigID, cert.SSLConfigID)
+	}
+	second := env.servedCertificate(t, domain)
+	if first.SerialNumber.Cmp(second.SerialNumber) == 0 {
+		t.Error("续期后HTTPS仍在使用旧证书")
+	}
+
+	// 续期失败时保留当前证书继续提供服务
+	env.ca.mutex.Lock()
+	env.ca.rejectFinalize = true
+	env.ca.mutex.Unlock()
+	if _, err := env.acme.Renew(cert.ID); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+	failed := env.wait(t, cert.ID)
+	if failed.Status != sqlite.ACMEStatusFailed || failed.SSLConfigID != cert.SSLConfigID {
+		t.Errorf("续期失败后 状态=%s, SSL配置=%d", failed.Status, failed.SSLConfigID)
+	}
+	if current := env.servedCertificate(t, domain); current.SerialNumber.Cmp(second.SerialNumber) != 0 {
+		t.Error("续期失败后不应替换正在使用的证书")
+	}
+
+	orders := env.orders(t, cert.ID)
+	var actions []string
+	for _, order := range orders {
+		actions = append(actions, order.Action+"/"+order.Status)
+	}
+	want := []string{"issue/valid", "renew/valid", "renew/failed"}
+	if !slices.Equal(actions, want) {
+		t.Errorf("签发记录 = %v, want %v", actions, want)
+	}
+}
+
+func TestACMEIssueDNS01Wildcard(t *testing.T) {
+	env := newACMETestEnv(t)
+	provider, dnsConfig := newStubDNSProvider(t)
+	env.ca.lookupTXT = provider.lookup
+	base := uniqueName("wild") + ".mini-web.test"
+
+	cert := env.issue(t, &ACMECertificateRequest{
+		Domains:       []string{"*." + base, base},
+		ChallengeType: ACMEChallengeDNS01,
+		DNSProvider:   stubDNSProviderName,
+		DNSConfig:     dnsConfig,
+	})
+	if cert.Status != sqlite.ACMEStatusValid {
+		t.Fatalf("证书状态 = %s, 错误 = %q", cert.Status, cert.LastError)
+	}
+
+	// 通配符的授权针对基础域名，TXT记录写在基础域名下
+	fqdn := "_acme-challenge." + base + "."
+	if len(provider.presented) != 2 || provider.presented[0] != fqdn || provider.presented[1] != fqdn {
+		t.Errorf("添加的TXT记录 = %v, want 2条%s", provider.presented, fqdn)
+	}
+	if len(provider.cleaned) != 2 || len(provider.lookup(fqdn)) != 0 {
+		t.Errorf("验证结束后TXT记录未清理: %v", provider.records)
+	}
+
+	leaf := env.servedCertificate(t, "api."+base)
+	if !slices.Contains(leaf.DNSNames, "*."+base) {
+		t.Errorf("通配符证书 DNSNames = %v", leaf.DNSNames)
+	}
+
+	// DNS服务商配置加密保存
+	stored, _ := sqlite.GetACMECertificateByID(cert.ID)
+	if strings.Contains(stored.DNSConfig, dnsConfig["id"]) {
+		t.Error("DNS服务商配置应加密保存")
+	}
+}
+
+func TestACMEIssueFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		dns     bool
+		setup   func(env *acmeTestEnv, provider *stubDNSProvider)
+		wantErr string
+		noOrder bool
+	}{
+		{
+			name:    "CA拒绝注册账号",
+			setup:   func(env *acmeTestEnv, _ *stubDNSProvider) { env.ca.rejectAccounts = true },
+			wantErr: "注册ACME账号失败",
+			noOrder: true,
+		},
+		{
+			name: "HTTP-01验证响应错误",
+			setup: func(env *acmeTestEnv, _ *stubDNSProvider) {
+				wrong := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("wrong-key-authorization"))
+				}))
+				env.ca.httpValidationAddr = wrong.Listener.Addr().String()
+				t.Cleanup(wrong.Close)
+			},
+			wantErr: "验证失败",
+		},
+		{
+			name:    "DNS记录添加失败",
+			dns:     true,
+			setup:   func(_ *acmeTestEnv, p *stubDNSProvider) { p.presentErr = errors.New("API token无权限") },
+			wantErr: "添加",
+		},
+		{
+			name:    "DNS记录值错误",
+			dns:     true,
+			setup:   func(_ *acmeTestEnv, p *stubDNSProvider) { p.corrupt = true },
+			wantErr: "验证失败",
+		},
+		{
+			name:    "CA拒绝CSR",
+			setup:   func(env *acmeTestEnv, _ *stubDNSProvider) { env.ca.rejectFinalize = true },
+			wantErr: "获取ACME证书失败",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newACMETestEnv(t)
+			provider, dnsConfig := newStubDNSProvider(t)
+			env.ca.lookupTXT = provider.lookup
+			tt.setup(env, provider)
+
+			req := &ACMECertificateRequest{Domains: []string{uniqueName("fail") + ".mini-web.test"}}
+			if tt.dns {
+				req.ChallengeType = ACMEChallengeDNS01
+				req.DNSProvider = stubDNSProviderName
+				req.DNSConfig = dnsConfig
+			}
+			cert := env.issue(t, req)
+
+			if cert.Status != sqlite.ACMEStatusFailed || !strings.Contains(cert.LastError, tt.wantErr) {
+				t.Errorf("证书 状态=%s, 错误=%q, want failed/%s", cert.Status, cert.LastError, tt.wantErr)
+			}
+			if cert.SSLConfigID != 0 {
+				t.Error("签发失败时不应创建SSL配置")
+			}
+			if tt.dns && len(provider.presented) != len(provider.cleaned) {
+				t.Errorf("验证失败后应删除已添加的TXT记录: 添加%v, 删除%v", provider.presented, provider.cleaned)
+			}
+
+			orders := env.orders(t, cert.ID)
+			if len(orders) != 1 {
+				t.Fatalf("签发记录数 = %d, want 1", len(orders))
+			}
+			order := orders[0]
+			if order.Status != sqlite.ACMEStatusFailed || order.Error != cert.LastError || order.FinishedAt == "" {
+				t.Errorf("签发记录 = %+v", order)
+			}
+			if (order.OrderURL == "") != tt.noOrder {
+				t.Errorf("签发记录的订单地址 = %q", order.OrderURL)
+			}
+		})
+	}
+}
+
+func TestACMEUntrustedServer(t *testing.T) {
+	env := newACMETestEnv(t)
+
+	// 不配置CACertPath时不信任测试CA的HTTPS证书
+	untrusted, err := NewACMEService(config.ACMEConfig{
+		DirectoryURL:   env.ca.url("/directory"),
+		AccountKeyPath: filepath.Join(t.TempDir(), "acme_account.key"),
+	}, NewSSLService(), env.acme.secretBox)
+	if err != nil {
+		t.Fatalf("NewACMEService() error = %v", err)
+	}
+	env.acme = untrusted
+
+	cert := env.issue(t, &ACMECertificateRequest{Domains: []string{uniqueName("tls") + ".mini-web.test"}})
+	if cert.Status != sqlite.ACMEStatusFailed || !strings.Contains(cert.LastError, "certificate") {
+		t.Errorf("证书 状态=%s, 错误=%q, want TLS证书校验失败", cert.Status, cert.LastError)
+	}
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.pem")
+	os.WriteFile(invalid, []byte("not a certificate"), 0600)
+	for _, path := range []string{filepath.Join(dir, "missing.pem"), invalid} {
+		if _, err := NewACMEService(config.ACMEConfig{CACertPath: path}, NewSSLService(), env.acme.secretBox); err == nil {
+			t.Errorf("NewACMEService(CACertPath=%s) 应返回错误", filepath.Base(path))
+		}
+	}
+}
+
+func TestACMERequestValidation(t *testing.T) {
+	env := newACMETestEnv(t)
+	_, dnsConfig := newStubDNSProvider(t)
+
+	tests := []struct {
+		name string
+		req  *ACMECertificateRequest
+	}{
+		{"域名为空", &ACMECertificateRequest{Domains: []string{" ", ""}}},
+		{"域名格式错误", &ACMECertificateRequest{Domains: []string{"bad domain.test"}}},
+		{"单标签域名", &ACMECertificateRequest{Domains: []string{"localhost"}}},
+		{"通配符使用HTTP-01", &ACMECertificateRequest{Domains: []string{"*.mini-web.test"}}},
+		{"不支持的验证方式", &ACMECertificateRequest{Domains: []string{"a.mini-web.test"}, ChallengeType: "tls-alpn-01"}},
+		{"未知DNS服务商", &ACMECertificateRequest{Domains: []string{"a.mini-web.test"}, ChallengeType: ACMEChallengeDNS01, DNSProvider: "unknown", DNSConfig: dnsConfig}},
+		{"DNS服务商配置无效", &ACMECertificateRequest{Domains: []string{"a.mini-web.test"}, ChallengeType: ACMEChallengeDNS01, DNSProvider: stubDNSProviderName, DNSConfig: map[string]string{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.acme.CreateCertificate(tt.req); !errors.Is(err, ErrInvalidACMERequest) {
+				t.Errorf("CreateCertificate() error = %v, want ErrInvalidACMERequest", err)
+			}
+		})
+	}
+}
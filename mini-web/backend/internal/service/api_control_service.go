@@ -125,24 +125,7 @@ func (s *APIControlService) CheckAccess(r *http.Request) error {
 
 // getClientIP 获取客户端IP地址
 func (s *APIControlService) getClientIP(r *http.Request) string {
-	// 检查X-Forwarded-For头
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		return strings.TrimSpace(ips[0])
-	}
-	
-	// 检查X-Real-IP头
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-	
-	// 使用RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	
-	return ip
+	return ClientIP(r)
 }
 
 // checkIPBlacklist 检查IP是否在黑名单中
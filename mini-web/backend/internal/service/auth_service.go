@@ -1,19 +1,19 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"time"
 	"log"
+	"time"
 
 	"gitee.com/await29/mini-web/internal/model"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
-	// JWTSecret JWT密钥
-	JWTSecret = []byte("mini-web-secret-key")
-
 	// ErrInvalidCredentials 无效的凭证错误
 	ErrInvalidCredentials = errors.New("无效的用户名或密码")
 
@@ -22,39 +22,82 @@ var (
 
 	// ErrUserNotFound 用户不存在错误
 	ErrUserNotFound = errors.New("用户不存在")
+
+	// ErrInvalidRefreshToken 刷新令牌无效或已过期
+	ErrInvalidRefreshToken = errors.New("刷新令牌无效或已过期")
+
+	// ErrRefreshTokenReused 刷新令牌被重复使用，所属登录会话已全部吊销
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，登录会话已吊销，请重新登录")
+
+	// ErrTokenRevoked 访问令牌已被吊销
+	ErrTokenRevoked = errors.New("令牌已被吊销")
+
+	// ErrOldPasswordIncorrect 旧密码不正确
+	ErrOldPasswordIncorrect = errors.New("旧密码不正确")
 )
 
 // TokenClaims JWT令牌声明
 type TokenClaims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID             uint   `json:"user_id"`
+	Role               string `json:"role"`
+	RoleVersion        int    `json:"role_version"`         // 签发时角色的权限版本
+	FamilyID           string `json:"sid,omitempty"`        // 所属登录会话（刷新令牌家族）
+	MustChangePassword bool   `json:"pwd_change,omitempty"` // 必须先修改密码，其他接口拒绝访问
 	jwt.RegisteredClaims
 }
 
 // AuthService 认证服务
 type AuthService struct {
-	userRepo model.UserRepository
+	userRepo    model.UserRepository
+	roles       *RoleService
+	refreshRepo model.RefreshTokenRepository
+	denylist    *TokenDenylist
+	keySet      *JWTKeySet
+	passwords   *PasswordService
+	guard       *LoginGuard
+	ldap        *LDAPProvider
+	provisioner *UserProvisioner
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+}
+
+// NewAuthService 创建认证服务实例。
+// accessTTL为访问令牌有效期，refreshTTL为刷新令牌（登录会话）有效期
+func NewAuthService(userRepo model.UserRepository, roles *RoleService, refreshRepo model.RefreshTokenRepository,
+	denylist *TokenDenylist, keySet *JWTKeySet, passwords *PasswordService, guard *LoginGuard, accessTTL, refreshTTL time.Duration) *AuthService {
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = 24 * time.Hour
+	}
+	return &AuthService{
+		userRepo:    userRepo,
+		roles:       roles,
+		refreshRepo: refreshRepo,
+		denylist:    denylist,
+		keySet:      keySet,
+		passwords:   passwords,
+		guard:       guard,
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
+	}
 }
 
-// NewAuthService 创建认证服务实例
-func NewAuthService(userRepo model.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+// EnableLDAP 启用LDAP认证，本地账号仍使用bcrypt密码登录
+func (s *AuthService) EnableLDAP(provider *LDAPProvider, provisioner *UserProvisioner) {
+	s.ldap = provider
+	s.provisioner = provisioner
 }
 
-// Login 用户登录
-func (s *AuthService) Login(username, password string) (*model.UserLoginResponse, error) {
+// Login 用户登录，userAgent和ipAddress记录登录设备
+func (s *AuthService) Login(username, password, userAgent, ipAddress string) (*model.UserLoginResponse, error) {
 	// 添加日志记录登录尝试
 	log.Printf("登录尝试: 用户名=%s, 密码长度=%d", username, len(password))
 
-	// 验证用户名和密码
-	ok, user, err := s.userRepo.VerifyPassword(username, password)
+	user, err := s.authenticate(username, password, ipAddress)
 	if err != nil {
-		log.Printf("验证密码时出错: %v", err)
-		return nil, fmt.Errorf("验证密码时出错: %w", err)
-	}
-	if !ok || user == nil {
-		log.Printf("登录失败: 用户名或密码错误")
-		return nil, ErrInvalidCredentials
+		return nil, err
 	}
 
 	// 检查用户状态
@@ -63,24 +106,109 @@ func (s *AuthService) Login(username, password string) (*model.UserLoginResponse
 		return nil, errors.New("用户账号已被禁用")
 	}
 
-	// 生成JWT令牌
-	token, expireTime, err := s.generateToken(user)
+	// 密码过期的本地账号需要先修改密码，令牌中带有标记
+	if _, err := s.passwords.CheckExpiry(user); err != nil {
+		log.Printf("检查密码有效期失败: %v", err)
+	}
+
+	// 签发访问令牌和刷新令牌
+	pair, err := s.IssueTokens(user, userAgent, ipAddress)
 	if err != nil {
 		log.Printf("生成令牌时出错: %v", err)
 		return nil, fmt.Errorf("生成令牌时出错: %w", err)
 	}
+	s.RecordLogin(user, userAgent, ipAddress)
 
 	// 构建响应
 	response := &model.UserLoginResponse{
-		Token:  token,
-		User:   *user,
-		Expire: expireTime.Unix(),
+		Token:         pair.Token,
+		User:          *user,
+		Expire:        pair.Expire,
+		RefreshToken:  pair.RefreshToken,
+		RefreshExpire: pair.RefreshExpire,
 	}
 
 	log.Printf("登录成功: 用户ID=%d, 用户名=%s", user.ID, user.Username)
 	return response, nil
 }
 
+// RecordLogin 记录一次成功登录：清零失败次数，首次使用的IP会发布通知
+func (s *AuthService) RecordLogin(user *model.User, userAgent, ipAddress string) {
+	s.guard.RecordSuccess(user, ipAddress, userAgent)
+}
+
+// UnlockUser 解除因连续登录失败导致的账号锁定
+func (s *AuthService) UnlockUser(userID uint) error {
+	return s.guard.Unlock(userID)
+}
+
+// authenticate 验证用户名和密码，已存在的账号先检查是否处于锁定期，密码错误时计入失败次数
+func (s *AuthService) authenticate(username, password, ipAddress string) (*model.User, error) {
+	existing, err := s.userRepo.GetByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户时出错: %w", err)
+	}
+	if existing != nil {
+		if err := s.guard.CheckLocked(existing); err != nil {
+			log.Printf("登录失败: 账号处于锁定期, 用户ID=%d", existing.ID)
+			return nil, err
+		}
+	}
+
+	user, err := s.verifyCredentials(existing, username, password)
+	if errors.Is(err, ErrInvalidCredentials) && existing != nil {
+		s.guard.RecordFailure(existing, ipAddress)
+	}
+	return user, err
+}
+
+// verifyCredentials 按账号来源验证用户名和密码：
+// 本地账号使用bcrypt密码，LDAP账号及尚不存在的用户交给目录服务验证
+func (s *AuthService) verifyCredentials(existing *model.User, username, password string) (*model.User, error) {
+	if s.ldap != nil && (existing == nil || existing.AuthSource == model.AuthSourceLDAP) {
+		return s.authenticateLDAP(username, password)
+	}
+	if existing != nil && existing.AuthSource != model.AuthSourceLocal {
+		log.Printf("登录失败: 账号来源%s未启用", existing.AuthSource)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 验证用户名和密码
+	ok, user, err := s.userRepo.VerifyPassword(username, password)
+	if err != nil {
+		log.Printf("验证密码时出错: %v", err)
+		return nil, fmt.Errorf("验证密码时出错: %w", err)
+	}
+	if !ok || user == nil {
+		log.Printf("登录失败: 用户名或密码错误")
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// authenticateLDAP 通过目录服务验证密码，并创建或同步对应的本地用户
+func (s *AuthService) authenticateLDAP(username, password string) (*model.User, error) {
+	identity, err := s.ldap.Authenticate(username, password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			log.Printf("LDAP登录失败: 用户名或密码错误")
+			return nil, ErrInvalidCredentials
+		}
+		log.Printf("LDAP认证出错: %v", err)
+		return nil, err
+	}
+
+	cfg := s.ldap.Config()
+	user, err := s.provisioner.Provision(identity, cfg.GroupRoleMap, cfg.GroupTeamMap, cfg.DefaultRole)
+	if err != nil {
+		if errors.Is(err, ErrAuthSourceMismatch) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("同步LDAP用户时出错: %w", err)
+	}
+	return user, nil
+}
+
 // Register 用户注册
 func (s *AuthService) Register(req *model.UserRegisterRequest) (*model.User, error) {
 	// 检查用户名是否已存在
@@ -101,14 +229,19 @@ func (s *AuthService) Register(req *model.UserRegisterRequest) (*model.User, err
 		return nil, ErrUserAlreadyExists
 	}
 
+	// 检查密码策略
+	if err := s.passwords.CheckNewPassword(req.Username, req.Password); err != nil {
+		return nil, err
+	}
+
 	// 创建新用户
 	user := &model.User{
 		Username: req.Username,
 		Email:    req.Email,
 		Password: req.Password, // 密码会在repository层进行哈希处理
 		Nickname: req.Nickname,
-		Role:     "user",      // 默认角色
-		Status:   "active",    // 默认状态
+		Role:     model.RoleUser,                                   // 默认角色
+		Status:   "active",                                         // 默认状态
 		Avatar:   "https://randomuser.me/api/portraits/lego/1.jpg", // 默认头像
 	}
 
@@ -158,40 +291,70 @@ func (s *AuthService) UpdateUserInfo(userID uint, req *model.UserUpdateRequest)
 	return user, nil
 }
 
-// UpdatePassword 更新用户密码
-func (s *AuthService) UpdatePassword(userID uint, req *model.UserPasswordUpdateRequest) error {
+// UpdatePassword 更新用户密码。
+// 修改成功后吊销该用户全部登录会话，并为当前设备签发新的令牌对
+func (s *AuthService) UpdatePassword(userID uint, req *model.UserPasswordUpdateRequest, userAgent, ipAddress string) (*model.TokenPair, error) {
 	// 获取用户
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return fmt.Errorf("获取用户信息时出错: %w", err)
+		return nil, fmt.Errorf("获取用户信息时出错: %w", err)
 	}
 	if user == nil {
-		return ErrUserNotFound
+		return nil, ErrUserNotFound
+	}
+	if user.AuthSource != model.AuthSourceLocal {
+		return nil, ErrExternalAccountPassword
 	}
 
 	// 验证旧密码
 	ok, _, err := s.userRepo.VerifyPassword(user.Username, req.OldPassword)
 	if err != nil {
-		return fmt.Errorf("验证旧密码时出错: %w", err)
+		return nil, fmt.Errorf("验证旧密码时出错: %w", err)
 	}
 	if !ok {
-		return errors.New("旧密码不正确")
+		return nil, ErrOldPasswordIncorrect
 	}
 
-	// 更新密码
-	if err := s.userRepo.UpdatePassword(userID, req.NewPassword); err != nil {
-		return fmt.Errorf("更新密码时出错: %w", err)
+	// 按密码策略更新密码
+	if err := s.passwords.ChangePassword(user, req.NewPassword); err != nil {
+		return nil, err
 	}
+	user.MustChangePassword = false
 
-	return nil
+	if err := s.RevokeUserTokens(userID); err != nil {
+		log.Printf("修改密码后吊销登录会话失败: %v", err)
+	}
+	return s.IssueTokens(user, userAgent, ipAddress)
+}
+
+// RequirePasswordChange 要求用户修改密码，并强制其重新登录
+func (s *AuthService) RequirePasswordChange(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("获取用户信息时出错: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.AuthSource != model.AuthSourceLocal {
+		return ErrExternalAccountPassword
+	}
+
+	if err := s.passwords.RequirePasswordChange(userID, true); err != nil {
+		return err
+	}
+	return s.RevokeUserTokens(userID)
+}
+
+// GetPasswordPolicy 获取当前生效的密码策略
+func (s *AuthService) GetPasswordPolicy() (*PasswordPolicy, error) {
+	return s.passwords.GetPolicy()
 }
 
 // VerifyToken 验证JWT令牌
 func (s *AuthService) VerifyToken(tokenString string) (*TokenClaims, error) {
 	// 解析令牌
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return JWTSecret, nil
-	})
+	token, err := s.keySet.ParseWithClaims(tokenString, &TokenClaims{})
 	if err != nil {
 		return nil, fmt.Errorf("解析令牌时出错: %w", err)
 	}
@@ -207,55 +370,235 @@ func (s *AuthService) VerifyToken(tokenString string) (*TokenClaims, error) {
 		return nil, errors.New("无效的令牌声明")
 	}
 
+	// 检查吊销名单
+	if s.denylist.IsRevoked(claims.ID) {
+		return nil, ErrTokenRevoked
+	}
+
 	return claims, nil
 }
 
-// RefreshToken 刷新令牌
-func (s *AuthService) RefreshToken(userID uint) (string, time.Time, error) {
-	// 获取用户
-	user, err := s.userRepo.GetByID(userID)
+// IssueTokens 为用户开启新的登录会话，签发访问令牌和刷新令牌
+func (s *AuthService) IssueTokens(user *model.User, userAgent, ipAddress string) (*model.TokenPair, error) {
+	familyID, err := randomTokenString(16)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("获取用户信息时出错: %w", err)
+		return nil, err
 	}
-	if user == nil {
-		return "", time.Time{}, ErrUserNotFound
+	return s.issueTokens(user, familyID, userAgent, ipAddress)
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对，旧刷新令牌随即失效。
+// 已使用过的刷新令牌再次出现说明令牌可能被盗，吊销其所属的整个登录会话
+func (s *AuthService) RefreshToken(refreshToken, userAgent, ipAddress string) (*model.TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.refreshRepo.GetByHash(hashSecretToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("查询刷新令牌时出错: %w", err)
+	}
+	if stored == nil || stored.RevokedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt != nil {
+		return nil, s.handleRefreshTokenReuse(stored)
+	}
+
+	// 原子地标记为已使用，并发请求中只有一个能成功
+	ok, err := s.refreshRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return nil, fmt.Errorf("更新刷新令牌时出错: %w", err)
+	}
+	if !ok {
+		return nil, s.handleRefreshTokenReuse(stored)
+	}
+
+	// 用户被删除或禁用后不再续期
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息时出错: %w", err)
+	}
+	if user == nil || user.Status != "active" {
+		if err := s.revokeFamily(stored.FamilyID); err != nil {
+			log.Printf("吊销登录会话失败: %v", err)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issueTokens(user, stored.FamilyID, userAgent, ipAddress)
+}
+
+// Logout 退出当前登录会话，吊销当前访问令牌及其刷新令牌家族
+func (s *AuthService) Logout(claims *TokenClaims) error {
+	if claims.FamilyID != "" {
+		if err := s.revokeFamily(claims.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	if claims.ExpiresAt != nil {
+		if err := s.denylist.Revoke(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			return fmt.Errorf("吊销访问令牌时出错: %w", err)
+		}
+	}
+	return nil
+}
+
+// RevokeUserTokens 吊销用户全部登录会话，用于退出所有设备和管理员强制下线
+func (s *AuthService) RevokeUserTokens(userID uint) error {
+	tokens, err := s.refreshRepo.RevokeByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("吊销刷新令牌时出错: %w", err)
+	}
+
+	if err := s.revokeAccessTokens(tokens); err != nil {
+		return err
+	}
+
+	log.Printf("已吊销用户全部登录会话: 用户ID=%d, 令牌数=%d", userID, len(tokens))
+	return nil
+}
+
+// GetLoginSessions 获取用户当前有效的登录会话
+func (s *AuthService) GetLoginSessions(userID uint) ([]*model.RefreshToken, error) {
+	tokens, err := s.refreshRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取登录会话时出错: %w", err)
+	}
+	return tokens, nil
+}
+
+// GetJWKS 获取用于验证访问令牌的公钥集合
+func (s *AuthService) GetJWKS() []JWK {
+	return s.keySet.JWKS()
+}
+
+// CleanupExpiredTokens 清理过期的刷新令牌
+func (s *AuthService) CleanupExpiredTokens() error {
+	return s.refreshRepo.DeleteExpired(time.Now())
+}
+
+// handleRefreshTokenReuse 处理刷新令牌重复使用，吊销整个令牌家族
+func (s *AuthService) handleRefreshTokenReuse(stored *model.RefreshToken) error {
+	log.Printf("检测到刷新令牌重复使用，吊销登录会话: 用户ID=%d, 会话=%s", stored.UserID, stored.FamilyID)
+
+	if err := s.revokeFamily(stored.FamilyID); err != nil {
+		return err
 	}
+	return ErrRefreshTokenReused
+}
 
-	// 生成新令牌
-	token, expireTime, err := s.generateToken(user)
+// revokeFamily 吊销令牌家族中的全部刷新令牌及其访问令牌
+func (s *AuthService) revokeFamily(familyID string) error {
+	tokens, err := s.refreshRepo.RevokeFamily(familyID)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("生成令牌时出错: %w", err)
+		return fmt.Errorf("吊销登录会话时出错: %w", err)
 	}
+	return s.revokeAccessTokens(tokens)
+}
 
-	return token, expireTime, nil
+// revokeAccessTokens 将刷新令牌对应的、尚未过期的访问令牌加入吊销名单
+func (s *AuthService) revokeAccessTokens(tokens []*model.RefreshToken) error {
+	for _, token := range tokens {
+		if err := s.denylist.Revoke(token.AccessJTI, token.UserID, token.CreatedAt.Add(s.accessTTL)); err != nil {
+			return fmt.Errorf("吊销访问令牌时出错: %w", err)
+		}
+	}
+	return nil
 }
 
-// generateToken 生成JWT令牌
-func (s *AuthService) generateToken(user *model.User) (string, time.Time, error) {
-	// 设置过期时间为24小时
-	expireTime := time.Now().Add(24 * time.Hour)
+// issueTokens 在指定令牌家族中签发访问令牌和刷新令牌
+func (s *AuthService) issueTokens(user *model.User, familyID, userAgent, ipAddress string) (*model.TokenPair, error) {
+	accessToken, jti, expireTime, err := s.generateToken(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomTokenString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &model.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashSecretToken(refreshToken),
+		AccessJTI: jti,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}
+	if err := s.refreshRepo.Create(stored); err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		Token:         accessToken,
+		Expire:        expireTime.Unix(),
+		RefreshToken:  refreshToken,
+		RefreshExpire: stored.ExpiresAt.Unix(),
+	}, nil
+}
+
+// generateToken 生成JWT访问令牌，返回令牌、令牌ID和过期时间
+func (s *AuthService) generateToken(user *model.User, familyID string) (string, string, time.Time, error) {
+	now := time.Now()
+	expireTime := now.Add(s.accessTTL)
+
+	// 记录角色的权限版本，权限检查据此发现其他实例上的角色修改
+	roleVersion := 0
+	if s.roles != nil {
+		version, err := s.roles.GetRoleVersion(user.Role)
+		if err != nil {
+			return "", "", time.Time{}, err
+		}
+		roleVersion = version
+	}
+
+	jti, err := randomTokenString(16)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
 
 	// 创建声明
 	claims := &TokenClaims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:             user.ID,
+		Role:               user.Role,
+		RoleVersion:        roleVersion,
+		FamilyID:           familyID,
+		MustChangePassword: user.MustChangePassword && user.AuthSource == model.AuthSourceLocal,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expireTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "mini-web",
 			Subject:   user.Username,
 		},
 	}
 
-	// 创建令牌
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// 签名令牌
-	tokenString, err := token.SignedString(JWTSecret)
+	// 使用当前密钥签名令牌
+	tokenString, err := s.keySet.Sign(claims)
 	if err != nil {
-		return "", time.Time{}, err
+		return "", "", time.Time{}, err
 	}
 
-	return tokenString, expireTime, nil
-}
\ No newline at end of file
+	return tokenString, jti, expireTime, nil
+}
+
+// randomTokenString 生成指定字节数的随机十六进制字符串
+func randomTokenString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机令牌失败: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashSecretToken 计算刷新令牌、找回密码令牌等一次性令牌的哈希，数据库只保存哈希值
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,814 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"golang.org/x/crypto/ssh"
+)
+
+// 批量任务：在多台主机上通过SSH执行同一命令或脚本，按并发数分批执行，
+// 每台主机的输出实时推送给订阅者，结束后保存到数据库，可只在失败的主机上重新执行
+const (
+	DefaultBatchParallelism = 10
+	MaxBatchParallelism     = 50
+	DefaultBatchTimeout     = 5 * time.Minute
+	MaxBatchTimeout         = time.Hour
+	MaxBatchHosts           = 500
+
+	// batchOutputLimit 每台主机每个输出流保存的最大字节数，超出部分不再保存和推送
+	batchOutputLimit = 256 * 1024
+	// batchSubscriberBuffer 订阅者的事件缓冲，消费过慢时断开订阅，客户端重新订阅可获得完整历史
+	batchSubscriberBuffer = 256
+	batchJobListLimit     = 100
+)
+
+var (
+	// ErrBatchJobNotFound 批量任务不存在
+	ErrBatchJobNotFound = errors.New("批量任务不存在")
+
+	// ErrBatchJobNoTargets 没有可执行的目标主机
+	ErrBatchJobNoTargets = errors.New("没有可执行的目标主机")
+
+	// ErrBatchJobTooManyHosts 目标主机过多
+	ErrBatchJobTooManyHosts = fmt.Errorf("目标主机不能超过%d台", MaxBatchHosts)
+
+	// ErrBatchJobCommandRequired 命令和脚本必须且只能指定一个
+	ErrBatchJobCommandRequired = errors.New("命令和脚本必须且只能指定一个")
+
+	// ErrBatchJobNotSSH 目标连接不是SSH连接
+	ErrBatchJobNotSSH = errors.New("批量任务仅支持SSH连接")
+
+	// ErrInvalidSudoUser sudo目标用户名无效
+	ErrInvalidSudoUser = errors.New("无效的sudo目标用户")
+
+	// ErrBatchJobRunning 任务仍在执行
+	ErrBatchJobRunning = errors.New("任务仍在执行")
+
+	// ErrBatchJobNotRunning 任务未在执行
+	ErrBatchJobNotRunning = errors.New("任务未在执行")
+)
+
+// sudoUserPattern sudo目标用户名格式
+var sudoUserPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,31}$`)
+
+// BatchJobService 批量命令执行服务
+type BatchJobService struct {
+	jobRepo     model.BatchJobRepository
+	connService *ConnectionService
+	access      *AccessService
+
+	mutex sync.Mutex
+	runs  map[uint]*batchJobRun // 正在执行的任务
+}
+
+// NewBatchJobService 创建批量命令执行服务，服务重启前未结束的任务标记为失败
+func NewBatchJobService(jobRepo model.BatchJobRepository, connService *ConnectionService, access *AccessService) *BatchJobService {
+	if err := jobRepo.FailUnfinished("服务重启，任务中断"); err != nil {
+		log.Printf("清理未完成的批量任务失败: %v", err)
+	}
+	return &BatchJobService{
+		jobRepo:     jobRepo,
+		connService: connService,
+		access:      access,
+		runs:        make(map[uint]*batchJobRun),
+	}
+}
+
+// CreateJob 创建批量任务并立即开始执行，目标主机需要connect权限
+func (s *BatchJobService) CreateJob(userID uint, req *model.BatchJobRequest) (*model.BatchJobDetail, error) {
+	job, err := newBatchJob(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	connections, err := s.resolveTargets(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.start(job, connections)
+}
+
+// RerunJob 使用原任务的命令和参数重新执行，failedOnly为true时只在未成功的主机上执行
+func (s *BatchJobService) RerunJob(userID uint, jobID uint, failedOnly bool) (*model.BatchJobDetail, error) {
+	source, err := s.getOwnedJob(userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if s.getRun(source.ID) != nil {
+		return nil, ErrBatchJobRunning
+	}
+
+	hosts, err := s.jobRepo.GetHosts(source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重新检查权限，原任务创建后连接的共享授权可能已被收回
+	var connections []*model.Connection
+	for _, host := range hosts {
+		if failedOnly && host.Succeeded() {
+			continue
+		}
+		conn, err := s.connService.AuthorizeConnection(userID, host.ConnectionID, model.PermissionConnect)
+		if err != nil {
+			return nil, fmt.Errorf("连接%s: %w", host.Name, err)
+		}
+		connections = append(connections, conn)
+	}
+	if len(connections) == 0 {
+		return nil, ErrBatchJobNoTargets
+	}
+	// 定时任务的执行记录继续使用其服务账号凭据，要求用户仍可使用该凭据
+	if err := s.connService.validateCredential(userID, source.CredentialID); err != nil {
+		return nil, err
+	}
+
+	job := &model.BatchJob{
+		UserID:         userID,
+		Name:           source.Name,
+		Command:        source.Command,
+		Script:         source.Script,
+		Sudo:           source.Sudo,
+		SudoUser:       source.SudoUser,
+		Parallelism:    source.Parallelism,
+		TimeoutSeconds: source.TimeoutSeconds,
+		SourceJobID:    source.ID,
+		CredentialID:   source.CredentialID,
+	}
+	return s.start(job, connections)
+}
+
+// CancelJob 取消正在执行的任务，正在执行的主机会被中断
+func (s *BatchJobService) CancelJob(userID uint, jobID uint) error {
+	job, err := s.getOwnedJob(userID, jobID)
+	if err != nil {
+		return err
+	}
+	run := s.getRun(job.ID)
+	if run == nil {
+		return ErrBatchJobNotRunning
+	}
+	log.Printf("取消批量任务: 任务ID=%d, 用户ID=%d", job.ID, userID)
+	run.cancel()
+	return nil
+}
+
+// GetJob 获取任务及各主机的执行结果
+func (s *BatchJobService) GetJob(userID uint, jobID uint) (*model.BatchJobDetail, error) {
+	job, err := s.getOwnedJob(userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := s.jobRepo.GetHosts(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.BatchJobDetail{BatchJob: job, Hosts: hosts}, nil
+}
+
+// ListJobs 获取用户最近的任务
+func (s *BatchJobService) ListJobs(userID uint) ([]*model.BatchJob, error) {
+	return s.jobRepo.GetByUserID(userID, batchJobListLimit)
+}
+
+// Subscribe 订阅任务的实时事件，返回已发生的事件和后续事件的通道，任务结束后通道关闭。
+// 任务已结束时根据保存的结果生成事件。调用方结束时必须调用返回的取消函数
+func (s *BatchJobService) Subscribe(userID uint, jobID uint) ([]*model.BatchJobEvent, <-chan *model.BatchJobEvent, func(), error) {
+	job, err := s.getOwnedJob(userID, jobID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if run := s.getRun(job.ID); run != nil {
+		history, events := run.subscribe()
+		return history, events, func() { run.unsubscribe(events) }, nil
+	}
+
+	// 任务结束时先保存结果再移除执行记录，此时读取到的一定是最终结果
+	job, err = s.getOwnedJob(userID, jobID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hosts, err := s.jobRepo.GetHosts(job.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	history := make([]*model.BatchJobEvent, 0, len(hosts)*3+1)
+	for _, host := range hosts {
+		if host.Stdout != "" {
+			history = append(history, &model.BatchJobEvent{Type: model.BatchEventOutput, JobID: job.ID, ConnectionID: host.ConnectionID, Stream: "stdout", Data: host.Stdout})
+		}
+		if host.Stderr != "" {
+			history = append(history, &model.BatchJobEvent{Type: model.BatchEventOutput, JobID: job.ID, ConnectionID: host.ConnectionID, Stream: "stderr", Data: host.Stderr})
+		}
+		history = append(history, newBatchHostDoneEvent(host))
+	}
+	history = append(history, &model.BatchJobEvent{Type: model.BatchEventJobDone, JobID: job.ID, Status: job.Status})
+
+	events := make(chan *model.BatchJobEvent)
+	close(events)
+	return history, events, func() {}, nil
+}
+
+// getOwnedJob 获取任务，只能访问自己创建的任务
+func (s *BatchJobService) getOwnedJob(userID uint, jobID uint) (*model.BatchJob, error) {
+	job, err := s.jobRepo.GetByID(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.UserID != userID {
+		return nil, ErrBatchJobNotFound
+	}
+	return job, nil
+}
+
+// getRun 获取正在执行的任务
+func (s *BatchJobService) getRun(jobID uint) *batchJobRun {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.runs[jobID]
+}
+
+// newBatchJob 校验请求并生成任务
+func newBatchJob(userID uint, req *model.BatchJobRequest) (*model.BatchJob, error) {
+	command := strings.TrimSpace(req.Command)
+	script := strings.TrimSpace(req.Script)
+	if (command == "") == (script == "") {
+		return nil, ErrBatchJobCommandRequired
+	}
+
+	sudoUser := strings.TrimSpace(req.SudoUser)
+	if sudoUser != "" && !sudoUserPattern.MatchString(sudoUser) {
+		return nil, ErrInvalidSudoUser
+	}
+
+	parallelism := req.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultBatchParallelism
+	}
+	if parallelism > MaxBatchParallelism {
+		parallelism = MaxBatchParallelism
+	}
+
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultBatchTimeout
+	}
+	if timeout > MaxBatchTimeout {
+		timeout = MaxBatchTimeout
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = command
+		if name == "" {
+			name = strings.SplitN(script, "\n", 2)[0]
+		}
+		if utf8.RuneCountInString(name) > 64 {
+			name = string([]rune(name)[:64]) + "..."
+		}
+	}
+
+	return &model.BatchJob{
+		UserID:         userID,
+		Name:           name,
+		Command:        command,
+		Script:         script,
+		Sudo:           req.Sudo,
+		SudoUser:       sudoUser,
+		Parallelism:    parallelism,
+		TimeoutSeconds: int(timeout / time.Second),
+	}, nil
+}
+
+// resolveTargets 解析目标主机：指定的连接、文件夹和标签中用户具有connect权限的SSH连接
+func (s *BatchJobService) resolveTargets(userID uint, req *model.BatchJobRequest) ([]*model.Connection, error) {
+	var connections []*model.Connection
+	seen := make(map[uint]bool)
+	add := func(conn *model.Connection) {
+		if !seen[conn.ID] {
+			seen[conn.ID] = true
+			connections = append(connections, conn)
+		}
+	}
+
+	// 明确指定的连接逐个检查权限和协议
+	for _, id := range req.ConnectionIDs {
+		conn, err := s.connService.AuthorizeConnection(userID, id, model.PermissionConnect)
+		if err != nil {
+			return nil, fmt.Errorf("连接%d: %w", id, err)
+		}
+		if conn.Protocol != model.ProtocolSSH {
+			return nil, fmt.Errorf("%w: %s", ErrBatchJobNotSSH, conn.Name)
+		}
+		add(conn)
+	}
+
+	// 文件夹和标签只选取其中可连接的SSH连接
+	tag := strings.TrimSpace(req.Tag)
+	if req.FolderID != 0 || tag != "" {
+		if req.FolderID != 0 {
+			if _, err := s.access.AuthorizeFolder(userID, req.FolderID, model.PermissionView); err != nil {
+				return nil, err
+			}
+		}
+
+		connectable, err := s.connService.GetConnectableConnections(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, conn := range connectable {
+			if conn.Protocol != model.ProtocolSSH {
+				continue
+			}
+			if (req.FolderID != 0 && conn.FolderID == req.FolderID) || (tag != "" && hasConnectionTag(conn, tag)) {
+				add(conn)
+			}
+		}
+	}
+
+	if len(connections) == 0 {
+		return nil, ErrBatchJobNoTargets
+	}
+	if len(connections) > MaxBatchHosts {
+		return nil, ErrBatchJobTooManyHosts
+	}
+	return connections, nil
+}
+
+// hasConnectionTag 连接是否带有指定标签，不区分大小写
+func hasConnectionTag(conn *model.Connection, tag string) bool {
+	for _, t := range conn.GetTags() {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// start 保存任务并在后台开始执行
+func (s *BatchJobService) start(job *model.BatchJob, connections []*model.Connection) (*model.BatchJobDetail, error) {
+	detail, _, err := s.startRun(job, connections)
+	return detail, err
+}
+
+// runAndWait 保存任务并执行，等待执行结束后返回任务的最终状态。ctx取消时任务被取消
+func (s *BatchJobService) runAndWait(ctx context.Context, job *model.BatchJob, connections []*model.Connection) (*model.BatchJob, error) {
+	detail, run, err := s.startRun(job, connections)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+		run.cancel()
+		<-run.done
+	}
+
+	finished, err := s.jobRepo.GetByID(detail.ID)
+	if err != nil {
+		return nil, err
+	}
+	if finished == nil {
+		return nil, ErrBatchJobNotFound
+	}
+	return finished, nil
+}
+
+// startRun 保存任务并在后台开始执行，返回任务副本和执行记录
+func (s *BatchJobService) startRun(job *model.BatchJob, connections []*model.Connection) (*model.BatchJobDetail, *batchJobRun, error) {
+	job.Status = model.BatchJobStatusPending
+	job.TotalHosts = len(connections)
+
+	hosts := make([]*model.BatchJobHost, len(connections))
+	for i, conn := range connections {
+		hosts[i] = &model.BatchJobHost{
+			ConnectionID: conn.ID,
+			Name:         conn.Name,
+			Host:         conn.Host,
+			Status:       model.BatchHostStatusPending,
+		}
+	}
+	if err := s.jobRepo.Create(job, hosts); err != nil {
+		return nil, nil, err
+	}
+
+	// 返回副本，执行协程会继续修改任务和主机状态
+	jobCopy := *job
+	hostCopies := make([]*model.BatchJobHost, len(hosts))
+	for i, host := range hosts {
+		hostCopy := *host
+		hostCopies[i] = &hostCopy
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := newBatchJobRun(cancel)
+	s.mutex.Lock()
+	s.runs[job.ID] = run
+	s.mutex.Unlock()
+
+	log.Printf("开始执行批量任务: 任务ID=%d, 用户ID=%d, 主机数=%d, 并发数=%d", job.ID, job.UserID, len(hosts), job.Parallelism)
+	go s.runJob(ctx, run, job, hosts, connections)
+
+	return &model.BatchJobDetail{BatchJob: &jobCopy, Hosts: hostCopies}, run, nil
+}
+
+// runJob 按并发数在各主机上执行任务
+func (s *BatchJobService) runJob(ctx context.Context, run *batchJobRun, job *model.BatchJob, hosts []*model.BatchJobHost, connections []*model.Connection) {
+	now := time.Now()
+	job.Status = model.BatchJobStatusRunning
+	job.StartedAt = &now
+	if err := s.jobRepo.Update(job); err != nil {
+		log.Printf("更新批量任务状态失败: %v", err)
+	}
+
+	slots := make(chan struct{}, job.Parallelism)
+	var wg sync.WaitGroup
+	for i := range hosts {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(host *model.BatchJobHost, conn *model.Connection) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s.runHost(ctx, run, job, host, conn)
+		}(hosts[i], connections[i])
+	}
+	wg.Wait()
+
+	// 任务取消后尚未开始的主机标记为已取消
+	for _, host := range hosts {
+		if host.Status == model.BatchHostStatusPending {
+			s.finishHost(run, host, model.BatchHostStatusCancelled, nil, "任务已取消")
+		}
+	}
+
+	job.SucceededHosts, job.FailedHosts = 0, 0
+	for _, host := range hosts {
+		if host.Succeeded() {
+			job.SucceededHosts++
+		} else {
+			job.FailedHosts++
+		}
+	}
+	switch {
+	case ctx.Err() != nil:
+		job.Status = model.BatchJobStatusCancelled
+	case job.FailedHosts > 0:
+		job.Status = model.BatchJobStatusFailed
+	default:
+		job.Status = model.BatchJobStatusCompleted
+	}
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if err := s.jobRepo.Update(job); err != nil {
+		log.Printf("更新批量任务状态失败: %v", err)
+	}
+
+	log.Printf("批量任务结束: 任务ID=%d, 状态=%s, 成功=%d, 失败=%d", job.ID, job.Status, job.SucceededHosts, job.FailedHosts)
+	run.publish(&model.BatchJobEvent{Type: model.BatchEventJobDone, JobID: job.ID, Status: job.Status})
+
+	s.mutex.Lock()
+	delete(s.runs, job.ID)
+	s.mutex.Unlock()
+	run.finish()
+}
+
+// runHost 在单台主机上执行任务并保存结果
+func (s *BatchJobService) runHost(ctx context.Context, run *batchJobRun, job *model.BatchJob, host *model.BatchJobHost, conn *model.Connection) {
+	started := time.Now()
+	host.Status = model.BatchHostStatusRunning
+	host.StartedAt = &started
+	if err := s.jobRepo.UpdateHost(host); err != nil {
+		log.Printf("更新批量任务主机状态失败: %v", err)
+	}
+	run.publish(&model.BatchJobEvent{Type: model.BatchEventHostStart, JobID: job.ID, ConnectionID: host.ConnectionID, Status: host.Status})
+
+	output := &batchOutput{run: run, jobID: job.ID, connectionID: host.ConnectionID}
+	exitCode, err := s.execute(ctx, job, conn, output)
+	host.Stdout, host.Stderr, host.Truncated = output.result()
+
+	switch {
+	case ctx.Err() != nil:
+		s.finishHost(run, host, model.BatchHostStatusCancelled, exitCode, "任务已取消")
+	case errors.Is(err, context.DeadlineExceeded):
+		s.finishHost(run, host, model.BatchHostStatusTimeout, exitCode, fmt.Sprintf("执行超过%d秒", job.TimeoutSeconds))
+	case err != nil:
+		s.finishHost(run, host, model.BatchHostStatusFailed, exitCode, err.Error())
+	case *exitCode == 0:
+		s.finishHost(run, host, model.BatchHostStatusSucceeded, exitCode, "")
+	default:
+		s.finishHost(run, host, model.BatchHostStatusFailed, exitCode, "")
+	}
+}
+
+// finishHost 保存主机的最终状态并推送结束事件
+func (s *BatchJobService) finishHost(run *batchJobRun, host *model.BatchJobHost, status string, exitCode *int, message string) {
+	finished := time.Now()
+	host.Status = status
+	host.ExitCode = exitCode
+	host.Error = message
+	host.FinishedAt = &finished
+	if err := s.jobRepo.UpdateHost(host); err != nil {
+		log.Printf("保存批量任务主机结果失败: %v", err)
+	}
+	run.publish(newBatchHostDoneEvent(host))
+}
+
+// newBatchHostDoneEvent 生成主机结束事件
+func newBatchHostDoneEvent(host *model.BatchJobHost) *model.BatchJobEvent {
+	return &model.BatchJobEvent{
+		Type:         model.BatchEventHostDone,
+		JobID:        host.JobID,
+		ConnectionID: host.ConnectionID,
+		Status:       host.Status,
+		ExitCode:     host.ExitCode,
+		Error:        host.Error,
+	}
+}
+
+// execute 以任务创建者的身份或指定的服务账号登录主机执行命令，返回远程命令的退出码
+func (s *BatchJobService) execute(ctx context.Context, job *model.BatchJob, conn *model.Connection, output *batchOutput) (*int, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(job.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	// 与交互式会话使用相同的凭据、密钥和证书，没有交互式认证提示；
+	// 指定了服务账号凭据时只使用该凭据
+	var authOptions *SSHAuthOptions
+	var err error
+	if job.CredentialID != 0 {
+		authOptions, err = s.connService.PrepareCredentialAuth(conn, job.CredentialID)
+	} else {
+		authOptions, err = s.connService.PrepareSessionAuth(job.UserID, conn, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client, err := dialSSHClient(conn, authOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("无法创建SSH会话: %w", err)
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("获取标准输出失败: %w", err)
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("获取标准错误失败: %w", err)
+	}
+
+	sudoPassword := job.Sudo && conn.Password != ""
+	if sudoPassword {
+		session.Stdin = strings.NewReader(conn.Password + "\n")
+	}
+	if err := session.Start(buildBatchCommand(job, sudoPassword)); err != nil {
+		return nil, fmt.Errorf("启动远程命令失败: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		var readers sync.WaitGroup
+		readers.Add(2)
+		go func() { defer readers.Done(); output.copy("stdout", stdout) }()
+		go func() { defer readers.Done(); output.copy("stderr", stderr) }()
+		readers.Wait()
+		done <- session.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// 多数SSH服务端不处理信号，关闭连接使远程命令收到SIGHUP
+		session.Signal(ssh.SIGKILL)
+		client.Close()
+		return nil, ctx.Err()
+	}
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		code := 0
+		return &code, nil
+	case errors.As(err, &exitErr):
+		code := exitErr.ExitStatus()
+		return &code, nil
+	default:
+		return nil, fmt.Errorf("远程命令异常结束: %w", err)
+	}
+}
+
+// buildBatchCommand 生成远程执行的命令行。使用sudo且有登录密码时，先用sudo -v从标准输入读取密码
+// 完成验证，再以sudo -n执行命令并把标准输入重定向到/dev/null，免密sudo时命令也读不到密码
+func buildBatchCommand(job *model.BatchJob, sudoPassword bool) string {
+	command := job.Command
+	if job.Script != "" {
+		command = "/bin/sh -c " + shellQuote(job.Script)
+	}
+	if !job.Sudo {
+		return command
+	}
+
+	sudo := "sudo -n "
+	if job.SudoUser != "" {
+		sudo += "-u " + shellQuote(job.SudoUser) + " "
+	}
+	body := job.Command
+	if job.Script != "" {
+		body = job.Script
+	}
+	command = sudo + "/bin/sh -c " + shellQuote(body)
+	if !sudoPassword {
+		return command
+	}
+	return "sudo -S -p '' -v && " + command + " </dev/null"
+}
+
+// batchOutput 收集单台主机的输出，保存不超过上限的部分并实时推送
+type batchOutput struct {
+	run          *batchJobRun
+	jobID        uint
+	connectionID uint
+
+	mutex     sync.Mutex
+	stdout    bytes.Buffer
+	stderr    bytes.Buffer
+	truncated bool
+}
+
+// copy 读取输出流直到结束，不完整的UTF-8字符留到下次读取后再推送
+func (o *batchOutput) copy(stream string, reader io.Reader) {
+	buf := make([]byte, 32*1024)
+	var pending []byte
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			pending = append(pending, buf[:n]...)
+			end := utf8Boundary(pending)
+			if err != nil {
+				end = len(pending)
+			}
+			if end > 0 {
+				o.append(stream, pending[:end])
+				pending = append(pending[:0], pending[end:]...)
+			}
+		}
+		if err != nil {
+			if len(pending) > 0 {
+				o.append(stream, pending)
+			}
+			return
+		}
+	}
+}
+
+// append 保存并推送一段输出，超出上限的部分丢弃
+func (o *batchOutput) append(stream string, data []byte) {
+	o.mutex.Lock()
+	buffer := &o.stdout
+	if stream == "stderr" {
+		buffer = &o.stderr
+	}
+	room := batchOutputLimit - buffer.Len()
+	if room <= 0 {
+		o.truncated = true
+		o.mutex.Unlock()
+		return
+	}
+	if len(data) > room {
+		data = data[:utf8Boundary(data[:room])]
+		o.truncated = true
+	}
+	buffer.Write(data)
+	o.mutex.Unlock()
+
+	if len(data) > 0 {
+		o.run.publish(&model.BatchJobEvent{
+			Type:         model.BatchEventOutput,
+			JobID:        o.jobID,
+			ConnectionID: o.connectionID,
+			Stream:       stream,
+			Data:         string(data),
+		})
+	}
+}
+
+// result 返回保存的标准输出、标准错误和是否截断
+func (o *batchOutput) result() (string, string, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.stdout.String(), o.stderr.String(), o.truncated
+}
+
+// utf8Boundary 返回data中完整UTF-8字符的长度，末尾不完整的字符不计入
+func utf8Boundary(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// batchJobRun 正在执行的任务，保存已发生的事件供后来的订阅者补发
+type batchJobRun struct {
+	cancel      context.CancelFunc
+	done        chan struct{} // 任务结束后关闭
+	mutex       sync.Mutex
+	events      []*model.BatchJobEvent
+	subscribers map[chan *model.BatchJobEvent]struct{}
+	finished    bool
+}
+
+// newBatchJobRun 创建执行记录
+func newBatchJobRun(cancel context.CancelFunc) *batchJobRun {
+	return &batchJobRun{
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		subscribers: make(map[chan *model.BatchJobEvent]struct{}),
+	}
+}
+
+// publish 记录事件并推送给订阅者，缓冲已满的订阅者被断开
+func (r *batchJobRun) publish(event *model.BatchJobEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.events = append(r.events, event)
+	for ch := range r.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(r.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe 返回已发生的事件和后续事件的通道
+func (r *batchJobRun) subscribe() ([]*model.BatchJobEvent, chan *model.BatchJobEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ch := make(chan *model.BatchJobEvent, batchSubscriberBuffer)
+	history := append([]*model.BatchJobEvent(nil), r.events...)
+	if r.finished {
+		close(ch)
+	} else {
+		r.subscribers[ch] = struct{}{}
+	}
+	return history, ch
+}
+
+// unsubscribe 取消订阅
+func (r *batchJobRun) unsubscribe(ch chan *model.BatchJobEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.subscribers[ch]; ok {
+		delete(r.subscribers, ch)
+		close(ch)
+	}
+}
+
+// finish 任务结束，关闭所有订阅
+func (r *batchJobRun) finish() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.finished = true
+	for ch := range r.subscribers {
+		delete(r.subscribers, ch)
+		close(ch)
+	}
+	r.cancel()
+	close(r.done)
+}
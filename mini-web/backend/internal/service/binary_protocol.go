@@ -8,10 +8,15 @@
 package service
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"sync"
+	"sync/atomic"
 )
 
 // 协议常量
@@ -25,13 +30,40 @@ const (
 	MessageTypeMixed               = 0x03
 	MessageTypeHeartbeat           = 0x04
 	MessageTypeProtocolNegotiation = 0x05
+	MessageTypeFlowControl         = 0x06 // 客户端确认已消费的终端输出，JSON为FlowControlAck
+	MessageTypeChannelOpen         = 0x07 // 打开多路复用通道，客户端请求为ChannelOpenRequest，服务端以同类型消息确认
+	MessageTypeChannelClose        = 0x08 // 关闭多路复用通道，任一方均可发送，JSON为ChannelClose
 
-	// 压缩类型
+	// FeatureFlowControl 协商时双方都声明该特性才启用终端输出流控
+	FeatureFlowControl = "flow-control"
+	// FeatureMultiplex 多路复用连接（/ws/mux）在协商响应中声明该特性
+	FeatureMultiplex = "multiplex"
+
+	// ControlChannel 控制通道，承载协议协商和心跳。非多路复用连接的所有消息都在该通道上
+	ControlChannel = 0
+
+	// 压缩类型，只压缩二进制部分，JSON元数据保持原样。
+	// LZ4的二进制部分为4字节大端原始长度加LZ4块，Gzip为标准gzip流
 	CompressionNone = 0x00
 	CompressionGzip = 0x01
 	CompressionLZ4  = 0x02
+
+	// 二进制部分小于该长度时不压缩
+	CompressionThreshold = 1024
 )
 
+// compressionPreference 服务端支持的压缩方式，按优先级排列。
+// LZ4压缩率略低但CPU开销小，更适合交互式终端
+var compressionPreference = []uint8{CompressionLZ4, CompressionGzip}
+
+// gzipWriterPool 复用gzip压缩器
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return w
+	},
+}
+
 // MessageHeader 消息头结构
 type MessageHeader struct {
 	MagicNumber     uint32 // 4字节 - 魔数
@@ -39,7 +71,7 @@ type MessageHeader struct {
 	CompressionFlag uint8  // 1字节 - 压缩标志
 	JSONLength      uint32 // 4字节 - JSON部分长度
 	BinaryLength    uint32 // 4字节 - 二进制部分长度
-	Reserved        uint16 // 2字节 - 保留字段
+	ChannelID       uint16 // 2字节 - 通道ID（原保留字段），0为控制通道
 }
 
 // ProtocolMessage 协议消息
@@ -55,6 +87,28 @@ type ProtocolNegotiation struct {
 	SupportedCompressions []uint8  `json:"supportedCompressions"`
 	MaxMessageSize        uint32   `json:"maxMessageSize"`
 	Features              []string `json:"features"`
+	Compression           uint8    `json:"compression"`                    // 服务端响应中为双方都支持的最佳压缩方式
+	CompressionThreshold  uint32   `json:"compressionThreshold,omitempty"` // 小于该长度的数据不压缩
+	FlowControlWindow     uint32   `json:"flowControlWindow,omitempty"`    // 客户端请求的窗口，服务端响应中为实际窗口，0表示不启用流控
+}
+
+// ChannelOpenRequest 打开通道请求。kind为terminal时打开sessionId对应的终端会话，
+// 为files时通过attach指定的终端通道执行文件管理请求
+type ChannelOpenRequest struct {
+	Kind              string `json:"kind"`
+	SessionID         uint   `json:"sessionId,omitempty"`
+	Attach            uint16 `json:"attach,omitempty"`
+	FlowControlWindow uint32 `json:"flowControlWindow,omitempty"` // 终端通道请求的流控窗口，0表示不启用
+}
+
+// ChannelClose 关闭通道消息
+type ChannelClose struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// FlowControlAck 流控确认消息
+type FlowControlAck struct {
+	Bytes uint32 `json:"bytes"` // 自上次确认后消费的终端输出字节数（解压后的二进制部分长度）
 }
 
 // BinaryProtocolHandler 二进制协议处理器
@@ -62,29 +116,41 @@ type BinaryProtocolHandler struct {
 	compressionSupported bool
 	maxMessageSize       uint32
 	protocolVersion      string
+
+	// 压缩统计
+	compressedMessages atomic.Int64
+	originalBytes      atomic.Int64
+	compressedBytes    atomic.Int64
 }
 
 // NewBinaryProtocolHandler 创建新的二进制协议处理器
 func NewBinaryProtocolHandler() *BinaryProtocolHandler {
 	return &BinaryProtocolHandler{
-		compressionSupported: false,            // 暂时不支持压缩
+		compressionSupported: true,
 		maxMessageSize:       10 * 1024 * 1024, // 10MB
 		protocolVersion:      "1.0",
 	}
 }
 
-// EncodeMessage 编码消息为二进制格式
+// EncodeMessage 编码消息为二进制格式。指定压缩方式时，二进制部分达到阈值且压缩后更小才压缩，
+// 否则按不压缩发送，实际使用的压缩方式见消息头
 func (h *BinaryProtocolHandler) EncodeMessage(jsonData interface{}, binaryData []byte, compression uint8) ([]byte, error) {
+	return h.EncodeChannelMessage(ControlChannel, 0, jsonData, binaryData, compression)
+}
+
+// EncodeChannelMessage 编码发往指定通道的消息，messageType为0时按内容确定消息类型
+func (h *BinaryProtocolHandler) EncodeChannelMessage(channelID uint16, messageType uint8, jsonData interface{}, binaryData []byte, compression uint8) ([]byte, error) {
 	// 确定消息类型
-	var messageType uint8
-	if jsonData != nil && binaryData != nil {
-		messageType = MessageTypeMixed
-	} else if jsonData != nil {
-		messageType = MessageTypeJSONOnly
-	} else if binaryData != nil {
-		messageType = MessageTypeBinaryOnly
-	} else {
-		return nil, fmt.Errorf("至少需要提供JSON数据或二进制数据")
+	if messageType == 0 {
+		if jsonData != nil && binaryData != nil {
+			messageType = MessageTypeMixed
+		} else if jsonData != nil {
+			messageType = MessageTypeJSONOnly
+		} else if binaryData != nil {
+			messageType = MessageTypeBinaryOnly
+		} else {
+			return nil, fmt.Errorf("至少需要提供JSON数据或二进制数据")
+		}
 	}
 
 	// 序列化JSON数据
@@ -101,6 +167,9 @@ func (h *BinaryProtocolHandler) EncodeMessage(jsonData interface{}, binaryData [
 	if binaryData == nil {
 		binaryData = []byte{}
 	}
+	if compression != CompressionNone {
+		binaryData, compression = h.compress(binaryData, compression)
+	}
 
 	// 创建消息头
 	header := MessageHeader{
@@ -109,7 +178,7 @@ func (h *BinaryProtocolHandler) EncodeMessage(jsonData interface{}, binaryData [
 		CompressionFlag: compression,
 		JSONLength:      uint32(len(jsonBytes)),
 		BinaryLength:    uint32(len(binaryData)),
-		Reserved:        0,
+		ChannelID:       channelID,
 	}
 
 	// 编码头部
@@ -178,8 +247,16 @@ func (h *BinaryProtocolHandler) DecodeMessage(data []byte) (*ProtocolMessage, er
 
 	// 解析二进制数据
 	if header.BinaryLength > 0 {
-		binaryData = make([]byte, header.BinaryLength)
-		copy(binaryData, data[offset:offset+int(header.BinaryLength)])
+		payload := data[offset : offset+int(header.BinaryLength)]
+		if header.CompressionFlag != CompressionNone {
+			binaryData, err = h.decompress(payload, header.CompressionFlag)
+			if err != nil {
+				return nil, fmt.Errorf("解压二进制数据失败: %w", err)
+			}
+		} else {
+			binaryData = make([]byte, header.BinaryLength)
+			copy(binaryData, payload)
+		}
 	}
 
 	return &ProtocolMessage{
@@ -189,6 +266,77 @@ func (h *BinaryProtocolHandler) DecodeMessage(data []byte) (*ProtocolMessage, er
 	}, nil
 }
 
+// compress 压缩二进制数据，不满足压缩条件时返回原数据和CompressionNone
+func (h *BinaryProtocolHandler) compress(data []byte, compression uint8) ([]byte, uint8) {
+	if len(data) < CompressionThreshold {
+		return data, CompressionNone
+	}
+
+	var compressed []byte
+	switch compression {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzipWriterPool.Get().(*gzip.Writer)
+		w.Reset(&buf)
+		_, err := w.Write(data)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		gzipWriterPool.Put(w)
+		if err != nil {
+			log.Printf("gzip压缩失败: %v", err)
+			return data, CompressionNone
+		}
+		compressed = buf.Bytes()
+	case CompressionLZ4:
+		compressed = make([]byte, 4, lz4CompressBound(len(data))+4)
+		binary.BigEndian.PutUint32(compressed, uint32(len(data)))
+		compressed = append(compressed, lz4CompressBlock(data)...)
+	default:
+		return data, CompressionNone
+	}
+
+	// 不可压缩的数据（已压缩的文件、加密数据等）按原样发送
+	if len(compressed) >= len(data) {
+		return data, CompressionNone
+	}
+	h.compressedMessages.Add(1)
+	h.originalBytes.Add(int64(len(data)))
+	h.compressedBytes.Add(int64(len(compressed)))
+	return compressed, compression
+}
+
+// decompress 解压二进制数据，解压后的长度不超过最大消息长度
+func (h *BinaryProtocolHandler) decompress(data []byte, compression uint8) ([]byte, error) {
+	switch compression {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		result, err := io.ReadAll(io.LimitReader(r, int64(h.maxMessageSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(result) > int(h.maxMessageSize) {
+			return nil, fmt.Errorf("解压后超过最大消息长度%d", h.maxMessageSize)
+		}
+		return result, nil
+	case CompressionLZ4:
+		if len(data) < 4 {
+			return nil, ErrLZ4Corrupted
+		}
+		size := binary.BigEndian.Uint32(data[:4])
+		if size > h.maxMessageSize {
+			return nil, fmt.Errorf("解压后超过最大消息长度%d", h.maxMessageSize)
+		}
+		return lz4DecompressBlock(data[4:], int(size))
+	default:
+		return nil, fmt.Errorf("不支持的压缩方式: %d", compression)
+	}
+}
+
 // encodeHeader 编码消息头
 func (h *BinaryProtocolHandler) encodeHeader(header MessageHeader) []byte {
 	headerBytes := make([]byte, HeaderSize)
@@ -198,7 +346,7 @@ func (h *BinaryProtocolHandler) encodeHeader(header MessageHeader) []byte {
 	headerBytes[5] = header.CompressionFlag
 	binary.BigEndian.PutUint32(headerBytes[6:10], header.JSONLength)
 	binary.BigEndian.PutUint32(headerBytes[10:14], header.BinaryLength)
-	binary.BigEndian.PutUint16(headerBytes[14:16], header.Reserved)
+	binary.BigEndian.PutUint16(headerBytes[14:16], header.ChannelID)
 
 	return headerBytes
 }
@@ -215,7 +363,7 @@ func (h *BinaryProtocolHandler) decodeHeader(data []byte) (*MessageHeader, error
 		CompressionFlag: data[5],
 		JSONLength:      binary.BigEndian.Uint32(data[6:10]),
 		BinaryLength:    binary.BigEndian.Uint32(data[10:14]),
-		Reserved:        binary.BigEndian.Uint16(data[14:16]),
+		ChannelID:       binary.BigEndian.Uint16(data[14:16]),
 	}
 
 	return header, nil
@@ -226,14 +374,15 @@ func (h *BinaryProtocolHandler) CreateNegotiationMessage() *ProtocolNegotiation
 	supportedCompressions := []uint8{CompressionNone}
 
 	if h.compressionSupported {
-		supportedCompressions = append(supportedCompressions, CompressionGzip)
+		supportedCompressions = append(supportedCompressions, compressionPreference...)
 	}
 
 	return &ProtocolNegotiation{
 		Version:               h.protocolVersion,
 		SupportedCompressions: supportedCompressions,
 		MaxMessageSize:        h.maxMessageSize,
-		Features:              []string{"file-transfer", "terminal-output", "command-execution"},
+		Features:              []string{"file-transfer", "terminal-output", "command-execution", FeatureFlowControl},
+		CompressionThreshold:  CompressionThreshold,
 	}
 }
 
@@ -245,7 +394,7 @@ func (h *BinaryProtocolHandler) CreateHeartbeatMessage() ([]byte, error) {
 		CompressionFlag: CompressionNone,
 		JSONLength:      0,
 		BinaryLength:    0,
-		Reserved:        0,
+		ChannelID:       ControlChannel,
 	}
 
 	return h.encodeHeader(header), nil
@@ -281,13 +430,25 @@ func (h *BinaryProtocolHandler) ConvertLegacyMessage(jsonBytes []byte) ([]byte,
 	return h.EncodeMessage(jsonData, nil, CompressionNone)
 }
 
-// GetStats 获取协议统计信息
+// GetStats 获取协议统计信息，compressionRatio为压缩后与压缩前的字节数之比
 func (h *BinaryProtocolHandler) GetStats() map[string]interface{} {
+	originalBytes := h.originalBytes.Load()
+	compressedBytes := h.compressedBytes.Load()
+	ratio := 1.0
+	if originalBytes > 0 {
+		ratio = float64(compressedBytes) / float64(originalBytes)
+	}
+
 	return map[string]interface{}{
 		"protocolVersion":      h.protocolVersion,
 		"compressionSupported": h.compressionSupported,
 		"maxMessageSize":       h.maxMessageSize,
 		"headerSize":           HeaderSize,
+		"compressionThreshold": CompressionThreshold,
+		"compressedMessages":   h.compressedMessages.Load(),
+		"originalBytes":        originalBytes,
+		"compressedBytes":      compressedBytes,
+		"compressionRatio":     ratio,
 	}
 }
 
@@ -296,14 +457,35 @@ func (h *BinaryProtocolHandler) HandleProtocolNegotiation(clientNegotiation *Pro
 	log.Printf("收到客户端协议协商: version=%s, features=%v",
 		clientNegotiation.Version, clientNegotiation.Features)
 
-	// 创建服务端协商响应
+	// 创建服务端协商响应，选择双方都支持的最佳压缩方式
 	serverNegotiation := h.CreateNegotiationMessage()
+	serverNegotiation.Compression = h.selectCompression(clientNegotiation.SupportedCompressions)
 
-	// 可以根据客户端支持的功能调整服务端响应
-	// 这里简单返回服务端支持的所有功能
+	// 客户端支持流控时确定窗口，否则不限制输出
+	for _, feature := range clientNegotiation.Features {
+		if feature == FeatureFlowControl {
+			serverNegotiation.FlowControlWindow = uint32(ClampFlowControlWindow(int(clientNegotiation.FlowControlWindow)))
+			break
+		}
+	}
 
-	log.Printf("发送服务端协议协商响应: version=%s, features=%v",
-		serverNegotiation.Version, serverNegotiation.Features)
+	log.Printf("发送服务端协议协商响应: version=%s, features=%v, compression=%d, flowControlWindow=%d",
+		serverNegotiation.Version, serverNegotiation.Features, serverNegotiation.Compression, serverNegotiation.FlowControlWindow)
 
 	return serverNegotiation, nil
 }
+
+// selectCompression 按服务端优先级选择客户端也支持的压缩方式
+func (h *BinaryProtocolHandler) selectCompression(clientCompressions []uint8) uint8 {
+	if !h.compressionSupported {
+		return CompressionNone
+	}
+	for _, preferred := range compressionPreference {
+		for _, supported := range clientCompressions {
+			if supported == preferred {
+				return preferred
+			}
+		}
+	}
+	return CompressionNone
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+// ErrNoCertificate 没有可用于HTTPS的证书
+var ErrNoCertificate = errors.New("没有已启用的SSL证书")
+
+// certificateReloadInterval 定期重新加载证书的间隔，
+// 用于感知证书文件在磁盘上被更新或其他实例修改了配置
+const certificateReloadInterval = 5 * time.Minute
+
+// CertificateStore HTTPS证书存储：从ssl_configs加载全部已启用的证书，
+// 握手时按SNI选择域名匹配的证书，没有匹配时使用默认证书。
+// 管理员修改证书配置后调用Reload即可生效，无需重启
+type CertificateStore struct {
+	mutex       sync.RWMutex
+	byDomain    map[string]*tls.Certificate // 小写域名，可以是*.example.com
+	defaultCert *tls.Certificate
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// 全局证书存储实例
+var globalCertificateStore *CertificateStore
+
+// InitCertificateStore 初始化全局证书存储，加载证书并启动定期重新加载
+func InitCertificateStore() *CertificateStore {
+	store := &CertificateStore{
+		byDomain: make(map[string]*tls.Certificate),
+		stop:     make(chan struct{}),
+	}
+	if err := store.Reload(); err != nil {
+		log.Printf("加载SSL证书失败: %v", err)
+	}
+	go store.reloadLoop()
+
+	globalCertificateStore = store
+	return store
+}
+
+// GetCertificateStore 获取全局证书存储，未初始化时返回nil
+func GetCertificateStore() *CertificateStore {
+	return globalCertificateStore
+}
+
+// Reload 从数据库重新加载已启用的证书。单个证书加载失败时跳过，不影响其他证书
+func (s *CertificateStore) Reload() error {
+	if s == nil {
+		return nil
+	}
+
+	configs, err := sqlite.GetSSLConfigs()
+	if err != nil {
+		return err
+	}
+
+	type loadedCertificate struct {
+		config *sqlite.SSLConfig
+		cert   *tls.Certificate
+	}
+	loaded := make([]loadedCertificate, 0, len(configs))
+	for i := range configs {
+		config := &configs[i]
+		if !config.IsEnabled {
+			continue
+		}
+		cert, err := loadSSLCertificate(config)
+		if err != nil {
+			log.Printf("加载SSL证书失败: ID=%d, 名称=%s, %v", config.ID, config.Name, err)
+			continue
+		}
+		loaded = append(loaded, loadedCertificate{config: config, cert: cert})
+	}
+
+	// 同一域名有多张证书时，优先默认证书，其次有效期最长的证书
+	sort.SliceStable(loaded, func(i, j int) bool {
+		if loaded[i].config.IsDefault != loaded[j].config.IsDefault {
+			return loaded[i].config.IsDefault
+		}
+		return loaded[i].cert.Leaf.NotAfter.After(loaded[j].cert.Leaf.NotAfter)
+	})
+
+	byDomain := make(map[string]*tls.Certificate)
+	var defaultCert *tls.Certificate
+	for _, item := range loaded {
+		if defaultCert == nil {
+			defaultCert = item.cert
+		}
+		for _, domain := range certificateDomains(item.config, item.cert) {
+			if byDomain[domain] == nil {
+				byDomain[domain] = item.cert
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	s.byDomain = byDomain
+	s.defaultCert = defaultCert
+	s.mutex.Unlock()
+
+	log.Printf("已加载SSL证书: %d个域名", len(byDomain))
+	return nil
+}
+
+// HasCertificate 是否存在可用证书
+func (s *CertificateStore) HasCertificate() bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.defaultCert != nil
+}
+
+// GetCertificate 供tls.Config使用，按SNI选择证书：精确匹配、通配符匹配、默认证书
+func (s *CertificateStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name != "" {
+		if cert := s.byDomain[name]; cert != nil {
+			return cert, nil
+		}
+		if i := strings.IndexByte(name, '.'); i > 0 {
+			if cert := s.byDomain["*"+name[i:]]; cert != nil {
+				return cert, nil
+			}
+		}
+	}
+	if s.defaultCert == nil {
+		return nil, ErrNoCertificate
+	}
+	return s.defaultCert, nil
+}
+
+// TLSConfig 创建使用本存储选择证书的TLS配置
+func (s *CertificateStore) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: s.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+}
+
+// Stop 停止定期重新加载
+func (s *CertificateStore) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// reloadLoop 定期重新加载证书
+func (s *CertificateStore) reloadLoop() {
+	ticker := time.NewTicker(certificateReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Reload(); err != nil {
+				log.Printf("重新加载SSL证书失败: %v", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// loadSSLCertificate 加载证书对，证书内容为空时从cert_path和key_path读取
+func loadSSLCertificate(config *sqlite.SSLConfig) (*tls.Certificate, error) {
+	certContent, keyContent := config.CertContent, config.KeyContent
+	if certContent == "" || keyContent == "" {
+		if config.CertPath == "" || config.KeyPath == "" {
+			return nil, errors.New("证书内容和证书文件路径均为空")
+		}
+		var err error
+		certContent, keyContent, err = NewSSLService().ReadCertificateFile(config.CertPath, config.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cert, err := tls.X509KeyPair([]byte(certContent), []byte(keyContent))
+	if err != nil {
+		return nil, fmt.Errorf("证书和私钥不匹配: %w", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+	}
+	if time.Now().After(cert.Leaf.NotAfter) {
+		return nil, fmt.Errorf("证书已于 %s 过期", cert.Leaf.NotAfter.Format("2006-01-02 15:04:05"))
+	}
+	return &cert, nil
+}
+
+// certificateDomains 证书服务的域名：配置的Domain（可逗号分隔）和证书中的DNS名称
+func certificateDomains(config *sqlite.SSLConfig, cert *tls.Certificate) []string {
+	seen := make(map[string]bool)
+	domains := make([]string, 0, len(cert.Leaf.DNSNames)+1)
+	add := func(domain string) {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	for _, domain := range strings.Split(config.Domain, ",") {
+		add(domain)
+	}
+	for _, domain := range cert.Leaf.DNSNames {
+		add(domain)
+	}
+	return domains
+}
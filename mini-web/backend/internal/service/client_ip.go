@@ -0,0 +1,88 @@
+package service
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	trustedProxiesMutex sync.RWMutex
+	trustedProxies      []*net.IPNet
+)
+
+// ConfigureTrustedProxies 设置可信反向代理的地址或网段，只有来自这些地址的请求才采信X-Forwarded-For
+func ConfigureTrustedProxies(proxies []string) {
+	var networks []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Printf("忽略无效的可信代理地址: %s", proxy)
+			continue
+		}
+		networks = append(networks, network)
+	}
+
+	trustedProxiesMutex.Lock()
+	trustedProxies = networks
+	trustedProxiesMutex.Unlock()
+}
+
+// isTrustedProxy 检查地址是否属于可信代理
+func isTrustedProxy(ip net.IP) bool {
+	trustedProxiesMutex.RLock()
+	defer trustedProxiesMutex.RUnlock()
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP 获取请求的客户端IP。
+// 直连地址不是可信代理时直接使用RemoteAddr；否则从X-Forwarded-For最右侧向左跳过可信代理，
+// 取第一个不可信的地址，客户端自行填写的靠左部分不会被采信
+func ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	remoteIP := net.ParseIP(remote)
+	if remoteIP == nil || !isTrustedProxy(remoteIP) {
+		return remote
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// 无法解析的跳数可能是伪造的，不再继续向左采信
+			break
+		}
+		if !isTrustedProxy(ip) || i == 0 {
+			return ip.String()
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil && len(hops) == 0 {
+		return ip.String()
+	}
+	return remote
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	ConfigureTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8"})
+	defer ConfigureTrustedProxies(nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{"直连客户端忽略转发头", "203.0.113.5:4000", "198.51.100.1", "198.51.100.2", "203.0.113.5"},
+		{"可信代理取最右侧不可信地址", "127.0.0.1:4000", "1.1.1.1, 203.0.113.5", "", "203.0.113.5"},
+		{"跳过多级可信代理", "127.0.0.1:4000", "1.1.1.1, 203.0.113.5, 10.1.2.3", "", "203.0.113.5"},
+		{"无法解析的跳数不被采信", "127.0.0.1:4000", "1.1.1.1, not-an-ip", "", "127.0.0.1"},
+		{"可信代理无转发头时使用X-Real-IP", "127.0.0.1:4000", "", "203.0.113.5", "203.0.113.5"},
+		{"可信代理无任何转发头", "127.0.0.1:4000", "", "", "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"net"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
 	"gitee.com/await29/mini-web/internal/model"
@@ -36,6 +37,9 @@ var (
 
 	// ErrTerminalFailed 终端创建失败错误
 	ErrTerminalFailed = errors.New("终端创建失败")
+
+	// ErrInvalidAuthMethod 无效的认证方式错误
+	ErrInvalidAuthMethod = errors.New("无效的SSH认证方式")
 )
 
 // TerminalSession 定义终端会话接口
@@ -77,13 +81,21 @@ type localTerminalSession struct {
 type ConnectionService struct {
 	connRepo    model.ConnectionRepository
 	sessionRepo model.SessionRepository
+	access      *AccessService
+	sshCA       *SSHCAService
+	sshKeys     *SSHKeyService
+	credentials *CredentialService
 }
 
-// NewConnectionService 创建连接服务实例
-func NewConnectionService(connRepo model.ConnectionRepository, sessionRepo model.SessionRepository) *ConnectionService {
+// NewConnectionService 创建连接服务实例，sshCA为nil时不支持证书认证
+func NewConnectionService(connRepo model.ConnectionRepository, sessionRepo model.SessionRepository, access *AccessService, sshCA *SSHCAService, sshKeys *SSHKeyService, credentials *CredentialService) *ConnectionService {
 	return &ConnectionService{
 		connRepo:    connRepo,
 		sessionRepo: sessionRepo,
+		access:      access,
+		sshCA:       sshCA,
+		sshKeys:     sshKeys,
+		credentials: credentials,
 	}
 }
 
@@ -94,20 +106,46 @@ func (s *ConnectionService) CreateConnection(userID uint, req *model.ConnectionR
 		return nil, ErrInvalidProtocol
 	}
 
+	// 验证认证方式
+	authMethods, err := normalizeAuthMethods(req.AuthMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证关联的SSH密钥和凭据
+	if err := s.validateSSHKey(userID, req.SSHKeyID); err != nil {
+		return nil, err
+	}
+	if err := s.validateCredential(userID, req.CredentialID); err != nil {
+		return nil, err
+	}
+
+	// 放入文件夹需要对文件夹的edit权限
+	if err := s.validateFolder(userID, req.FolderID); err != nil {
+		return nil, err
+	}
+
 	// 创建连接对象
 	conn := &model.Connection{
-		Name:        req.Name,
-		Protocol:    req.Protocol,
-		Host:        req.Host,
-		Port:        req.Port,
-		Username:    req.Username,
-		Password:    req.Password,
-		PrivateKey:  req.PrivateKey,
-		Group:       req.Group,
-		Description: req.Description,
-		CreatedBy:   userID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Name:           req.Name,
+		Protocol:       req.Protocol,
+		Host:           req.Host,
+		Port:           req.Port,
+		Username:       req.Username,
+		Password:       req.Password,
+		PrivateKey:     req.PrivateKey,
+		KeyPassphrase:  req.KeyPassphrase,
+		AuthMethods:    authMethods,
+		CertPrincipals: req.CertPrincipals,
+		SSHKeyID:       req.SSHKeyID,
+		CredentialID:   req.CredentialID,
+		FolderID:       req.FolderID,
+		Group:          req.Group,
+		Tags:           normalizeTags(req.Tags),
+		Description:    req.Description,
+		CreatedBy:      userID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
 	// 保存连接
@@ -115,32 +153,22 @@ func (s *ConnectionService) CreateConnection(userID uint, req *model.ConnectionR
 		return nil, fmt.Errorf("创建连接时出错: %w", err)
 	}
 
+	conn.Permission = model.PermissionManage
 	return conn, nil
 }
 
 // UpdateConnection 更新连接配置
 func (s *ConnectionService) UpdateConnection(userID uint, id uint, req *model.ConnectionRequest) (*model.Connection, error) {
-	var conn *model.Connection
-	var err error
-
-	// 使用修复后的方法获取连接，处理NULL值问题
-	if repo, ok := s.connRepo.(*sqlite.ConnectionRepository); ok {
-		conn, err = repo.GetByIDFixed(id)
-	} else {
-		// 回退到原始方法
-		conn, err = s.connRepo.GetByID(id)
-	}
-
+	// 修改连接需要edit权限
+	conn, err := s.AuthorizeConnection(userID, id, model.PermissionEdit)
 	if err != nil {
-		return nil, fmt.Errorf("获取连接信息时出错: %w", err)
-	}
-	if conn == nil {
-		return nil, ErrConnectionNotFound
+		return nil, err
 	}
 
-	// 检查是否是创建者
-	if conn.CreatedBy != userID {
-		return nil, errors.New("无权修改此连接")
+	// 已保存的密码、私钥或凭据会随连接发往新地址，仅manage权限可修改目标地址
+	if (req.Host != conn.Host || req.Port != conn.Port) && hasStoredSecrets(conn) &&
+		!model.HasPermission(conn.Permission, model.PermissionManage) {
+		return nil, fmt.Errorf("%w: 修改主机或端口需要manage权限", ErrConnectionForbidden)
 	}
 
 	// 验证协议类型
@@ -148,6 +176,37 @@ func (s *ConnectionService) UpdateConnection(userID uint, id uint, req *model.Co
 		return nil, ErrInvalidProtocol
 	}
 
+	// 验证认证方式
+	authMethods, err := normalizeAuthMethods(req.AuthMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	// 证书认证的登录名和principals决定会话在目标主机上的身份，仅manage权限可修改
+	certAuth := conn.HasAuthMethod(model.AuthMethodCertificate) ||
+		strings.Contains(authMethods, model.AuthMethodCertificate)
+	if certAuth && (req.Username != conn.Username || req.CertPrincipals != conn.CertPrincipals) &&
+		!model.HasPermission(conn.Permission, model.PermissionManage) {
+		return nil, fmt.Errorf("%w: 修改证书认证连接的用户名或principals需要manage权限", ErrConnectionForbidden)
+	}
+
+	// 验证新关联的SSH密钥、凭据和文件夹，未变化的引用保持不变
+	if req.SSHKeyID != conn.SSHKeyID {
+		if err := s.validateSSHKey(userID, req.SSHKeyID); err != nil {
+			return nil, err
+		}
+	}
+	if req.CredentialID != conn.CredentialID {
+		if err := s.validateCredential(userID, req.CredentialID); err != nil {
+			return nil, err
+		}
+	}
+	if req.FolderID != conn.FolderID {
+		if err := s.validateFolder(userID, req.FolderID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 更新连接信息
 	conn.Name = req.Name
 	conn.Protocol = req.Protocol
@@ -160,7 +219,16 @@ func (s *ConnectionService) UpdateConnection(userID uint, id uint, req *model.Co
 	if req.PrivateKey != "" {
 		conn.PrivateKey = req.PrivateKey
 	}
+	if req.KeyPassphrase != "" {
+		conn.KeyPassphrase = req.KeyPassphrase
+	}
+	conn.AuthMethods = authMethods
+	conn.CertPrincipals = req.CertPrincipals
+	conn.SSHKeyID = req.SSHKeyID
+	conn.CredentialID = req.CredentialID
+	conn.FolderID = req.FolderID
 	conn.Group = req.Group
+	conn.Tags = normalizeTags(req.Tags)
 	conn.Description = req.Description
 	conn.UpdatedAt = time.Now()
 
@@ -174,27 +242,9 @@ func (s *ConnectionService) UpdateConnection(userID uint, id uint, req *model.Co
 
 // DeleteConnection 删除连接
 func (s *ConnectionService) DeleteConnection(userID uint, id uint) error {
-	var conn *model.Connection
-	var err error
-
-	// 使用修复后的方法获取连接，处理NULL值问题
-	if repo, ok := s.connRepo.(*sqlite.ConnectionRepository); ok {
-		conn, err = repo.GetByIDFixed(id)
-	} else {
-		// 回退到原始方法
-		conn, err = s.connRepo.GetByID(id)
-	}
-
-	if err != nil {
-		return fmt.Errorf("获取连接信息时出错: %w", err)
-	}
-	if conn == nil {
-		return ErrConnectionNotFound
-	}
-
-	// 检查是否是创建者
-	if conn.CreatedBy != userID {
-		return errors.New("无权删除此连接")
+	// 删除连接需要manage权限
+	if _, err := s.AuthorizeConnection(userID, id, model.PermissionManage); err != nil {
+		return err
 	}
 
 	// 删除连接
@@ -205,8 +255,27 @@ func (s *ConnectionService) DeleteConnection(userID uint, id uint) error {
 	return nil
 }
 
-// GetConnection 获取连接详情
+// GetConnection 获取连接详情，需要view权限
 func (s *ConnectionService) GetConnection(userID uint, id uint) (*model.Connection, error) {
+	return s.AuthorizeConnection(userID, id, model.PermissionView)
+}
+
+// AuthorizeConnection 获取连接并检查用户是否具有所需权限，所有连接访问都经由此处
+func (s *ConnectionService) AuthorizeConnection(userID uint, id uint, required string) (*model.Connection, error) {
+	conn, err := s.getConnection(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.access.Authorize(userID, conn, required); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// getConnection 根据ID获取连接，不做权限检查
+func (s *ConnectionService) getConnection(id uint) (*model.Connection, error) {
 	var conn *model.Connection
 	var err error
 
@@ -225,39 +294,42 @@ func (s *ConnectionService) GetConnection(userID uint, id uint) (*model.Connecti
 		return nil, ErrConnectionNotFound
 	}
 
-	// 检查访问权限
-	if conn.CreatedBy != userID {
-		// 这里可以扩展为团队共享功能
-		return nil, errors.New("无权访问此连接")
-	}
-
 	return conn, nil
 }
 
-// GetUserConnections 获取用户的所有连接
+// GetUserConnections 获取用户可见的所有连接，包括自己创建的和共享给用户或其团队的
 func (s *ConnectionService) GetUserConnections(userID uint) ([]*model.Connection, error) {
+	return s.getPermittedConnections(userID, model.PermissionView)
+}
+
+// GetConnectableConnections 获取用户具有connect权限的所有连接
+func (s *ConnectionService) GetConnectableConnections(userID uint) ([]*model.Connection, error) {
+	return s.getPermittedConnections(userID, model.PermissionConnect)
+}
+
+// getPermittedConnections 获取用户具有所需权限的所有连接
+func (s *ConnectionService) getPermittedConnections(userID uint, required string) ([]*model.Connection, error) {
+	var connections []*model.Connection
+	var err error
+
 	// 使用修复后的方法获取连接，处理NULL值问题
 	if repo, ok := s.connRepo.(*sqlite.ConnectionRepository); ok {
-		connections, err := repo.GetByUserIDFixed(userID)
-		if err != nil {
-			return nil, fmt.Errorf("获取用户连接时出错: %w", err)
-		}
-		return connections, nil
+		connections, err = repo.GetAllFixed()
+	} else {
+		// 回退到原始方法
+		connections, err = s.connRepo.GetAll()
 	}
-
-	// 回退到原始方法
-	connections, err := s.connRepo.GetByUserID(userID)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户连接时出错: %w", err)
 	}
 
-	return connections, nil
+	return s.access.FilterConnections(userID, connections, required)
 }
 
 // CreateSession 创建新会话
 func (s *ConnectionService) CreateSession(userID uint, connectionID uint, clientIP string) (*model.Session, error) {
-	// 获取连接信息
-	conn, err := s.GetConnection(userID, connectionID)
+	// 发起会话需要connect权限
+	conn, err := s.AuthorizeConnection(userID, connectionID, model.PermissionConnect)
 	if err != nil {
 		return nil, err
 	}
@@ -282,6 +354,7 @@ func (s *ConnectionService) CreateSession(userID uint, connectionID uint, client
 	if err := s.sessionRepo.Create(session); err != nil {
 		return nil, fmt.Errorf("创建会话时出错: %w", err)
 	}
+	GetNotificationService().NotifySessionStarted(userID, conn, clientIP)
 
 	return session, nil
 }
@@ -307,6 +380,9 @@ func (s *ConnectionService) CloseSession(userID uint, sessionID uint) error {
 		return fmt.Errorf("关闭会话时出错: %w", err)
 	}
 
+	// 显式关闭的会话不再等待重连
+	CloseResumableTerminals(strconv.FormatUint(uint64(sessionID), 10))
+
 	return nil
 }
 
@@ -365,9 +441,14 @@ func (s *ConnectionService) GetSessionByID(userID uint, sessionID uint) (*model.
 
 // CreateTerminalSession 创建终端会话
 func (s *ConnectionService) CreateTerminalSession(protocol string, connection *model.Connection) (TerminalSession, error) {
+	return s.CreateTerminalSessionWithAuth(protocol, connection, nil)
+}
+
+// CreateTerminalSessionWithAuth 创建终端会话，authOptions由PrepareSessionAuth生成
+func (s *ConnectionService) CreateTerminalSessionWithAuth(protocol string, connection *model.Connection, authOptions *SSHAuthOptions) (TerminalSession, error) {
 	switch protocol {
 	case model.ProtocolSSH:
-		return s.createSSHSession(connection)
+		return s.createSSHSession(connection, authOptions)
 	case model.ProtocolRDP:
 		return s.createRDPSession(connection)
 	case model.ProtocolVNC:
@@ -380,12 +461,80 @@ func (s *ConnectionService) CreateTerminalSession(protocol string, connection *m
 	}
 }
 
+// PrepareSessionAuth 为用户发起的会话解析引用的凭据并准备SSH认证参数，启用证书认证时签发短期证书
+func (s *ConnectionService) PrepareSessionAuth(userID uint, connection *model.Connection, prompter SSHAuthPrompter) (*SSHAuthOptions, error) {
+	// 引用了凭据时使用凭据中的用户名、密码和私钥
+	if connection.CredentialID != 0 && s.credentials != nil {
+		if err := s.credentials.ResolveConnection(connection); err != nil {
+			return nil, fmt.Errorf("加载凭据失败: %w", err)
+		}
+	}
+
+	options := &SSHAuthOptions{Prompter: prompter}
+	if connection.Protocol != model.ProtocolSSH {
+		return options, nil
+	}
+
+	// 关联了服务端密钥时使用解密后的私钥
+	if connection.SSHKeyID != 0 && s.sshKeys != nil {
+		if err := s.sshKeys.ResolveConnectionKey(connection); err != nil {
+			return nil, fmt.Errorf("加载SSH密钥失败: %w", err)
+		}
+	}
+
+	if !connection.HasAuthMethod(model.AuthMethodCertificate) {
+		return options, nil
+	}
+
+	// 证书是唯一认证方式时签发失败直接返回错误，否则回退到其他认证方式
+	onlyCertificate := len(connection.GetAuthMethods()) == 1
+	if s.sshCA == nil {
+		if onlyCertificate {
+			return nil, ErrSSHCANotAvailable
+		}
+		log.Printf("SSH CA未配置，跳过证书认证: 连接ID=%d", connection.ID)
+		return options, nil
+	}
+
+	certificate, err := s.sshCA.IssueSessionCertificate(userID, connection)
+	if err != nil {
+		if onlyCertificate {
+			return nil, fmt.Errorf("签发SSH证书失败: %w", err)
+		}
+		log.Printf("签发SSH证书失败，跳过证书认证: 连接ID=%d, 错误: %v", connection.ID, err)
+		return options, nil
+	}
+
+	options.Certificate = certificate
+	return options, nil
+}
+
+// PrepareCredentialAuth 使用服务账号凭据代替连接自身的认证信息，供无人值守的定时任务使用。
+// 连接保存的密码、私钥和认证顺序都被忽略，不签发证书也没有交互式认证提示
+func (s *ConnectionService) PrepareCredentialAuth(connection *model.Connection, credentialID uint) (*SSHAuthOptions, error) {
+	if s.credentials == nil {
+		return nil, ErrCredentialNotFound
+	}
+
+	connection.Password = ""
+	connection.PrivateKey = ""
+	connection.KeyPassphrase = ""
+	connection.SSHKeyID = 0
+	connection.AuthMethods = ""
+	connection.CredentialID = credentialID
+	if err := s.credentials.ResolveConnection(connection); err != nil {
+		return nil, fmt.Errorf("加载服务账号凭据失败: %w", err)
+	}
+
+	return &SSHAuthOptions{}, nil
+}
+
 // createSSHSession 创建SSH会话
-func (s *ConnectionService) createSSHSession(connection *model.Connection) (TerminalSession, error) {
-	log.Printf("创建SSH终端会话: %s@%s:%d", connection.Username, connection.Host, connection.Port)
+func (s *ConnectionService) createSSHSession(connection *model.Connection, authOptions *SSHAuthOptions) (TerminalSession, error) {
+	log.Printf("创建SSH终端会话: %s@%s:%d, 认证顺序=%v", connection.Username, connection.Host, connection.Port, connection.GetAuthMethods())
 
 	// 使用我们实现的SSH终端
-	session, err := createSSHTerminalSession(connection)
+	session, err := createSSHTerminalSession(connection, authOptions)
 	if err != nil {
 		log.Printf("SSH终端创建失败: %v", err)
 		return nil, err
@@ -531,6 +680,82 @@ func isValidProtocol(protocol string) bool {
 
 // 辅助函数
 
+// validateSSHKey 验证连接关联的SSH密钥属于当前用户
+func (s *ConnectionService) validateSSHKey(userID uint, keyID uint) error {
+	if keyID == 0 {
+		return nil
+	}
+	if s.sshKeys == nil {
+		return ErrSSHKeyNotFound
+	}
+	_, err := s.sshKeys.GetKey(userID, keyID)
+	return err
+}
+
+// validateCredential 验证连接引用的凭据对当前用户可用
+func (s *ConnectionService) validateCredential(userID uint, credentialID uint) error {
+	if credentialID == 0 {
+		return nil
+	}
+	if s.credentials == nil {
+		return ErrCredentialNotFound
+	}
+	_, err := s.credentials.GetCredential(userID, credentialID)
+	return err
+}
+
+// validateFolder 验证用户可以将连接放入文件夹
+func (s *ConnectionService) validateFolder(userID uint, folderID uint) error {
+	if folderID == 0 {
+		return nil
+	}
+	_, err := s.access.AuthorizeFolder(userID, folderID, model.PermissionEdit)
+	return err
+}
+
+// hasStoredSecrets 检查连接是否保存或引用了认证信息
+func hasStoredSecrets(conn *model.Connection) bool {
+	return conn.Password != "" || conn.PrivateKey != "" || conn.SSHKeyID != 0 || conn.CredentialID != 0
+}
+
+// normalizeAuthMethods 校验并规范化认证方式列表，空值表示使用默认顺序
+func normalizeAuthMethods(authMethods string) (string, error) {
+	if strings.TrimSpace(authMethods) == "" {
+		return "", nil
+	}
+
+	seen := make(map[string]bool)
+	methods := make([]string, 0, 3)
+	for _, method := range strings.Split(authMethods, ",") {
+		method = strings.TrimSpace(method)
+		if method == "" || seen[method] {
+			continue
+		}
+		if !model.IsValidAuthMethod(method) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidAuthMethod, method)
+		}
+		seen[method] = true
+		methods = append(methods, method)
+	}
+
+	return strings.Join(methods, ","), nil
+}
+
+// normalizeTags 规范化逗号分隔的标签：去除空白和重复项，统一为小写
+func normalizeTags(tags string) string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, 4)
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return strings.Join(result, ",")
+}
+
 // testTCPConnection 测试TCP连接
 func testTCPConnection(host string, port int) error {
 	address := net.JoinHostPort(host, strconv.Itoa(port))
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrCredentialNotFound 凭据不存在
+	ErrCredentialNotFound = errors.New("凭据不存在")
+
+	// ErrCredentialInUse 凭据仍被连接引用
+	ErrCredentialInUse = errors.New("凭据仍被连接引用，请先解除引用")
+
+	// ErrInvalidCredentialType 无效的凭据类型
+	ErrInvalidCredentialType = errors.New("无效的凭据类型，仅支持password、key、key_passphrase")
+
+	// ErrCredentialSecretRequired 凭据缺少敏感信息
+	ErrCredentialSecretRequired = errors.New("凭据缺少密码、私钥或口令")
+
+	// ErrCredentialNotOwner 只有凭据所有者可以执行该操作
+	ErrCredentialNotOwner = errors.New("只有凭据所有者可以修改、轮换或删除凭据")
+)
+
+// CredentialService 凭据服务，管理可被多个连接引用的密码和私钥
+type CredentialService struct {
+	credRepo  model.CredentialRepository
+	connRepo  model.ConnectionRepository
+	access    *AccessService
+	secretBox *SecretBox
+	teams     *TeamService
+}
+
+// NewCredentialService 创建凭据服务实例
+func NewCredentialService(credRepo model.CredentialRepository, connRepo model.ConnectionRepository, access *AccessService, secretBox *SecretBox, teams *TeamService) *CredentialService {
+	return &CredentialService{
+		credRepo:  credRepo,
+		connRepo:  connRepo,
+		access:    access,
+		secretBox: secretBox,
+		teams:     teams,
+	}
+}
+
+// CreateCredential 创建凭据
+func (s *CredentialService) CreateCredential(userID uint, req *model.CredentialRequest) (*model.Credential, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("凭据名称不能为空")
+	}
+	if err := s.validateTeam(userID, req.TeamID); err != nil {
+		return nil, err
+	}
+
+	cred := &model.Credential{
+		Name:        req.Name,
+		Type:        req.Type,
+		Username:    req.Username,
+		Description: req.Description,
+		OwnerID:     userID,
+		TeamID:      req.TeamID,
+	}
+	secrets := &model.CredentialRotateRequest{
+		Password:   req.Password,
+		PrivateKey: req.PrivateKey,
+		Passphrase: req.Passphrase,
+	}
+	if err := s.applySecrets(cred, secrets); err != nil {
+		return nil, err
+	}
+
+	if err := s.credRepo.Create(cred); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已创建凭据: ID=%d, 类型=%s, 用户ID=%d", cred.ID, cred.Type, userID)
+	return cred, nil
+}
+
+// UpdateCredential 更新凭据，敏感字段为空时保持原值
+func (s *CredentialService) UpdateCredential(userID uint, id uint, req *model.CredentialRequest) (*model.Credential, error) {
+	cred, err := s.GetCredential(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if cred.OwnerID != userID {
+		return nil, ErrCredentialNotOwner
+	}
+	if req.TeamID != cred.TeamID {
+		if err := s.validateTeam(userID, req.TeamID); err != nil {
+			return nil, err
+		}
+	}
+
+	cred.Name = req.Name
+	cred.Username = req.Username
+	cred.Description = req.Description
+	cred.TeamID = req.TeamID
+
+	// 修改类型或提供了新的敏感信息时按轮换处理
+	if req.Type != cred.Type || req.Password != "" || req.PrivateKey != "" || req.Passphrase != "" {
+		cred.Type = req.Type
+		secrets := &model.CredentialRotateRequest{
+			Password:   req.Password,
+			PrivateKey: req.PrivateKey,
+			Passphrase: req.Passphrase,
+		}
+		if err := s.applySecrets(cred, secrets); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		cred.LastRotatedAt = &now
+	}
+
+	if err := s.credRepo.Update(cred); err != nil {
+		return nil, err
+	}
+
+	return cred, nil
+}
+
+// DeleteCredential 删除凭据，仍被连接引用时拒绝删除
+func (s *CredentialService) DeleteCredential(userID uint, id uint) error {
+	cred, err := s.GetCredential(userID, id)
+	if err != nil {
+		return err
+	}
+	if cred.OwnerID != userID {
+		return ErrCredentialNotOwner
+	}
+
+	connections, err := s.connRepo.GetByCredentialID(id)
+	if err != nil {
+		return fmt.Errorf("查询引用凭据的连接失败: %w", err)
+	}
+	if len(connections) > 0 {
+		return ErrCredentialInUse
+	}
+
+	return s.credRepo.Delete(id)
+}
+
+// GetCredential 获取用户可访问的凭据
+func (s *CredentialService) GetCredential(userID uint, id uint) (*model.Credential, error) {
+	cred, err := s.credRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if cred == nil || !s.canAccess(userID, cred) {
+		return nil, ErrCredentialNotFound
+	}
+	return cred, nil
+}
+
+// GetUserCredentials 获取用户可访问的凭据列表，包括个人凭据和所属团队的凭据
+func (s *CredentialService) GetUserCredentials(userID uint) ([]*model.Credential, error) {
+	creds, err := s.credRepo.GetByOwnerID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取凭据列表失败: %w", err)
+	}
+	if s.teams == nil {
+		return creds, nil
+	}
+
+	teamIDs, err := s.teams.GetUserTeamIDs(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户团队失败: %w", err)
+	}
+	teamCreds, err := s.credRepo.GetByTeamIDs(teamIDs)
+	if err != nil {
+		return nil, fmt.Errorf("获取团队凭据失败: %w", err)
+	}
+
+	for _, cred := range teamCreds {
+		if cred.OwnerID != userID {
+			creds = append(creds, cred)
+		}
+	}
+	return creds, nil
+}
+
+// GetCredentialConnections 获取引用了该凭据且用户有权查看的连接
+func (s *CredentialService) GetCredentialConnections(userID uint, id uint) ([]*model.Connection, error) {
+	if _, err := s.GetCredential(userID, id); err != nil {
+		return nil, err
+	}
+	return s.visibleConnections(userID, id)
+}
+
+// RotateCredential 轮换凭据中的密码或私钥，返回受影响的连接
+func (s *CredentialService) RotateCredential(userID uint, id uint, req *model.CredentialRotateRequest) (*model.Credential, []*model.Connection, error) {
+	cred, err := s.GetCredential(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cred.OwnerID != userID {
+		return nil, nil, ErrCredentialNotOwner
+	}
+
+	if err := s.applySecrets(cred, req); err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	cred.LastRotatedAt = &now
+
+	if err := s.credRepo.Update(cred); err != nil {
+		return nil, nil, err
+	}
+
+	connections, err := s.visibleConnections(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("凭据已轮换: ID=%d, 操作用户ID=%d, 受影响连接数=%d", cred.ID, userID, len(connections))
+	return cred, connections, nil
+}
+
+// ResolveConnection 连接引用了凭据时，解密凭据并填充到连接中供会话认证使用
+func (s *CredentialService) ResolveConnection(conn *model.Connection) error {
+	if conn.CredentialID == 0 {
+		return nil
+	}
+
+	cred, err := s.credRepo.GetByID(conn.CredentialID)
+	if err != nil {
+		return err
+	}
+	if cred == nil {
+		return ErrCredentialNotFound
+	}
+
+	password, err := s.secretBox.Decrypt(cred.Password)
+	if err != nil {
+		return err
+	}
+	privateKey, err := s.secretBox.Decrypt(cred.PrivateKey)
+	if err != nil {
+		return err
+	}
+	passphrase, err := s.secretBox.Decrypt(cred.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	if cred.Username != "" {
+		conn.Username = cred.Username
+	}
+	conn.Password = password
+	conn.PrivateKey = privateKey
+	conn.KeyPassphrase = passphrase
+	return nil
+}
+
+// visibleConnections 获取引用了凭据的连接，按访问控制过滤并填充用户对每个连接的权限
+func (s *CredentialService) visibleConnections(userID uint, id uint) ([]*model.Connection, error) {
+	connections, err := s.connRepo.GetByCredentialID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询引用凭据的连接失败: %w", err)
+	}
+	return s.access.FilterConnections(userID, connections, model.PermissionView)
+}
+
+// applySecrets 校验凭据类型对应的敏感信息并加密写入
+func (s *CredentialService) applySecrets(cred *model.Credential, secrets *model.CredentialRotateRequest) error {
+	var password, privateKey, passphrase string
+
+	switch cred.Type {
+	case model.CredentialTypePassword:
+		if secrets.Password == "" {
+			return ErrCredentialSecretRequired
+		}
+		password = secrets.Password
+	case model.CredentialTypeKey:
+		if secrets.PrivateKey == "" {
+			return ErrCredentialSecretRequired
+		}
+		if _, err := ssh.ParsePrivateKey([]byte(secrets.PrivateKey)); err != nil {
+			return fmt.Errorf("私钥无效（加密私钥请使用key_passphrase类型）: %w", err)
+		}
+		privateKey = secrets.PrivateKey
+	case model.CredentialTypeKeyPassphrase:
+		if secrets.PrivateKey == "" || secrets.Passphrase == "" {
+			return ErrCredentialSecretRequired
+		}
+		if _, err := ssh.ParsePrivateKeyWithPassphrase([]byte(secrets.PrivateKey), []byte(secrets.Passphrase)); err != nil {
+			return fmt.Errorf("私钥或口令无效: %w", err)
+		}
+		privateKey = secrets.PrivateKey
+		passphrase = secrets.Passphrase
+	default:
+		return ErrInvalidCredentialType
+	}
+
+	var err error
+	if cred.Password, err = s.secretBox.Encrypt(password); err != nil {
+		return err
+	}
+	if cred.PrivateKey, err = s.secretBox.Encrypt(privateKey); err != nil {
+		return err
+	}
+	if cred.Passphrase, err = s.secretBox.Encrypt(passphrase); err != nil {
+		return err
+	}
+	return nil
+}
+
+// canAccess 检查用户是否可以使用凭据，个人凭据仅所有者可访问，团队凭据对团队成员可见
+func (s *CredentialService) canAccess(userID uint, cred *model.Credential) bool {
+	if cred.OwnerID == userID {
+		return true
+	}
+	if cred.TeamID == 0 || s.teams == nil {
+		return false
+	}
+
+	member, err := s.teams.IsMember(userID, cred.TeamID)
+	if err != nil {
+		log.Printf("检查团队成员失败: 团队ID=%d, 用户ID=%d, 错误: %v", cred.TeamID, userID, err)
+		return false
+	}
+	return member
+}
+
+// validateTeam 验证用户属于凭据要归属的团队
+func (s *CredentialService) validateTeam(userID uint, teamID uint) error {
+	if teamID == 0 {
+		return nil
+	}
+	if s.teams == nil {
+		return ErrTeamNotFound
+	}
+
+	member, err := s.teams.IsMember(userID, teamID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrTeamNotFound
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+// credentialTestEnv 团队凭据被两个连接引用，其中只有一个连接共享给了团队成员
+type credentialTestEnv struct {
+	credentials *CredentialService
+	owner       *model.User
+	member      *model.User
+	cred        *model.Credential
+	private     *model.Connection
+	shared      *model.Connection
+}
+
+func newCredentialTestEnv(t *testing.T) *credentialTestEnv {
+	t.Helper()
+
+	s := newTestServices(t)
+	secretBox, err := NewSecretBox(filepath.Join(testDataDir, "secret_test.key"))
+	if err != nil {
+		t.Fatalf("初始化密钥失败: %v", err)
+	}
+	connRepo := sqlite.NewConnectionRepository(sqlite.DB)
+	teams := NewTeamService(s.teams, s.users, s.roles)
+	access := NewAccessService(connRepo, sqlite.NewConnectionFolderRepository(sqlite.DB),
+		sqlite.NewConnectionShareRepository(sqlite.DB), teams)
+
+	env := &credentialTestEnv{
+		credentials: NewCredentialService(sqlite.NewCredentialRepository(sqlite.DB), connRepo, access, secretBox, teams),
+	}
+	owner := uniqueName("credowner")
+	env.owner = s.createLocalUser(t, owner, owner+"@example.com", "Passw0rd!")
+	member := uniqueName("credmember")
+	env.member = s.createLocalUser(t, member, member+"@example.com", "Passw0rd!")
+
+	team, err := teams.CreateTeam(env.owner.ID, &model.TeamRequest{Name: uniqueName("credteam")})
+	if err != nil {
+		t.Fatalf("创建团队失败: %v", err)
+	}
+	if _, err := teams.AddMember(env.owner.ID, team.ID, &model.TeamMemberRequest{UserID: env.member.ID, Role: model.TeamRoleMember}); err != nil {
+		t.Fatalf("添加团队成员失败: %v", err)
+	}
+
+	env.cred, err = env.credentials.CreateCredential(env.owner.ID, &model.CredentialRequest{
+		Name:     uniqueName("cred"),
+		Type:     model.CredentialTypePassword,
+		Username: "root",
+		Password: "old-secret",
+		TeamID:   team.ID,
+	})
+	if err != nil {
+		t.Fatalf("创建凭据失败: %v", err)
+	}
+
+	for _, conn := range []**model.Connection{&env.private, &env.shared} {
+		*conn = &model.Connection{
+			Name:         uniqueName("credconn"),
+			Protocol:     "ssh",
+			Host:         "10.0.0.1",
+			Port:         22,
+			CredentialID: env.cred.ID,
+			CreatedBy:    env.owner.ID,
+		}
+		if err := connRepo.Create(*conn); err != nil {
+			t.Fatalf("创建连接失败: %v", err)
+		}
+	}
+	if _, err := access.Share(env.owner.ID, model.ShareResourceConnection, env.shared.ID, &model.ConnectionShareRequest{
+		SubjectType: model.ShareSubjectUser,
+		SubjectID:   env.member.ID,
+		Permission:  model.PermissionConnect,
+	}); err != nil {
+		t.Fatalf("共享连接失败: %v", err)
+	}
+	return env
+}
+
+func TestGetCredentialConnectionsFiltersByAccess(t *testing.T) {
+	env := newCredentialTestEnv(t)
+
+	connections, err := env.credentials.GetCredentialConnections(env.member.ID, env.cred.ID)
+	if err != nil {
+		t.Fatalf("获取凭据关联连接失败: %v", err)
+	}
+	if len(connections) != 1 || connections[0].ID != env.shared.ID {
+		t.Fatalf("团队成员只应看到共享给自己的连接, 实际: %+v", connections)
+	}
+	if connections[0].Permission != model.PermissionConnect {
+		t.Fatalf("连接权限应为%s, 实际: %q", model.PermissionConnect, connections[0].Permission)
+	}
+
+	connections, err = env.credentials.GetCredentialConnections(env.owner.ID, env.cred.ID)
+	if err != nil {
+		t.Fatalf("获取凭据关联连接失败: %v", err)
+	}
+	if len(connections) != 2 {
+		t.Fatalf("所有者应看到全部2个连接, 实际: %d", len(connections))
+	}
+	for _, conn := range connections {
+		if conn.Permission != model.PermissionManage {
+			t.Fatalf("所有者对连接%d的权限应为%s, 实际: %q", conn.ID, model.PermissionManage, conn.Permission)
+		}
+	}
+}
+
+func TestRotateCredentialRequiresOwner(t *testing.T) {
+	env := newCredentialTestEnv(t)
+	req := &model.CredentialRotateRequest{Password: "new-secret"}
+
+	if _, _, err := env.credentials.RotateCredential(env.member.ID, env.cred.ID, req); !errors.Is(err, ErrCredentialNotOwner) {
+		t.Fatalf("团队成员轮换凭据应返回ErrCredentialNotOwner, 实际: %v", err)
+	}
+
+	cred, connections, err := env.credentials.RotateCredential(env.owner.ID, env.cred.ID, req)
+	if err != nil {
+		t.Fatalf("所有者轮换凭据失败: %v", err)
+	}
+	if cred.LastRotatedAt == nil {
+		t.Fatal("轮换后应记录轮换时间")
+	}
+	if len(connections) != 2 {
+		t.Fatalf("轮换应返回2个受影响的连接, 实际: %d", len(connections))
+	}
+
+	conn := &model.Connection{CredentialID: env.cred.ID}
+	if err := env.credentials.ResolveConnection(conn); err != nil {
+		t.Fatalf("解析凭据失败: %v", err)
+	}
+	if conn.Password != "new-secret" {
+		t.Fatalf("轮换后连接应使用新密码, 实际: %q", conn.Password)
+	}
+}
@@ -0,0 +1,689 @@
+package service
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+var (
+	// ErrEmailDisabled 邮件服务未启用
+	ErrEmailDisabled = errors.New("邮件服务未启用")
+
+	// ErrEmailTemplateNotFound 邮件模板不存在
+	ErrEmailTemplateNotFound = errors.New("邮件模板不存在")
+
+	// ErrInvalidEmailConfig 邮件配置不完整或格式错误
+	ErrInvalidEmailConfig = errors.New("邮件配置无效")
+
+	// ErrInvalidEmailTemplate 邮件模板内容不合法
+	ErrInvalidEmailTemplate = errors.New("邮件模板无效")
+
+	// ErrEmailMessageNotFound 队列中不存在可重发的邮件
+	ErrEmailMessageNotFound = errors.New("邮件不存在或不是发送失败状态")
+)
+
+const (
+	// smtpTimeout 连接和收发SMTP命令的超时时间
+	smtpTimeout = 30 * time.Second
+
+	// emailQueueInterval 发信队列的轮询间隔
+	emailQueueInterval = 30 * time.Second
+
+	// emailQueueBatchSize 每轮最多处理的邮件数
+	emailQueueBatchSize = 20
+
+	// emailMaxAttempts 每封邮件的最大发送次数，超过后标记为失败
+	emailMaxAttempts = 8
+
+	// emailRetryBaseDelay 首次重试的等待时间，之后每次翻倍，最长emailRetryMaxDelay
+	emailRetryBaseDelay = time.Minute
+	emailRetryMaxDelay  = time.Hour
+
+	// emailSentRetention 已发送邮件在队列中的保留时间
+	emailSentRetention = 30 * 24 * time.Hour
+)
+
+// emailTemplateTypes 允许的邮件模板类型
+var emailTemplateTypes = map[string]bool{
+	model.EmailTemplateWelcome:              true,
+	model.EmailTemplateResetPassword:        true,
+	model.EmailTemplateSecurityNotification: true,
+	model.EmailTemplateSystemNotification:   true,
+}
+
+// emailVariableDescriptions 模板变量说明
+var emailVariableDescriptions = map[string]string{
+	"site_name":      "网站名称",
+	"support_email":  "支持邮箱",
+	"current_year":   "当前年份",
+	"current_date":   "当前日期",
+	"current_time":   "当前时间",
+	"user_name":      "用户名称",
+	"user_email":     "用户邮箱",
+	"reset_link":     "重置链接",
+	"expire_minutes": "链接有效分钟数",
+	"login_ip":       "登录IP",
+	"login_time":     "登录时间",
+
+	"notification_title":   "通知标题",
+	"notification_content": "通知内容",
+}
+
+// EmailService 邮件服务：管理SMTP配置和模板，邮件先写入持久化队列，由后台任务发送并按指数退避重试。
+// SMTP密码使用SecretBox加密后保存
+type EmailService struct {
+	emailRepo  model.EmailRepository
+	queueRepo  model.EmailQueueRepository
+	configRepo model.SystemConfigRepository
+	secretBox  *SecretBox
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewEmailService 创建邮件服务
+func NewEmailService(emailRepo model.EmailRepository, queueRepo model.EmailQueueRepository, configRepo model.SystemConfigRepository, secretBox *SecretBox) *EmailService {
+	return &EmailService{
+		emailRepo:  emailRepo,
+		queueRepo:  queueRepo,
+		configRepo: configRepo,
+		secretBox:  secretBox,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+}
+
+// GetConfig 获取邮件配置，不返回密码；未配置时返回默认值
+func (s *EmailService) GetConfig() (*model.EmailConfig, error) {
+	config, err := s.emailRepo.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return &model.EmailConfig{SMTPPort: 587, EnableTLS: true}, nil
+	}
+	config.Password = ""
+	return config, nil
+}
+
+// SaveConfig 保存邮件配置，密码为空时保留原密码。未启用的配置允许不完整
+func (s *EmailService) SaveConfig(config *model.EmailConfig) error {
+	if config.EnableSSL && config.EnableTLS {
+		return fmt.Errorf("%w: SSL和STARTTLS只能启用一个", ErrInvalidEmailConfig)
+	}
+	if config.IsEnabled {
+		if err := validateEmailConfig(config); err != nil {
+			return err
+		}
+	}
+	if config.TestEmail != "" {
+		if _, err := mail.ParseAddress(config.TestEmail); err != nil {
+			return fmt.Errorf("%w: 测试邮箱格式不正确", ErrInvalidEmailConfig)
+		}
+	}
+
+	stored := *config
+	if stored.Password == "" {
+		existing, err := s.emailRepo.GetConfig()
+		if err != nil {
+			return err
+		}
+		if existing != nil && sameSMTPAccount(existing, config) {
+			stored.Password = existing.Password
+		}
+	} else {
+		encrypted, err := s.secretBox.Encrypt(stored.Password)
+		if err != nil {
+			return err
+		}
+		stored.Password = encrypted
+	}
+
+	if err := s.emailRepo.SaveConfig(&stored); err != nil {
+		return err
+	}
+	config.ID = stored.ID
+	config.Password = ""
+	log.Printf("邮件配置已更新: %s:%d，启用=%v", config.SMTPHost, config.SMTPPort, config.IsEnabled)
+
+	// 配置可能刚刚启用，尽快处理积压的邮件
+	s.notifyQueue()
+	return nil
+}
+
+// TestConnection 使用给定配置连接SMTP服务器并认证，密码为空时使用已保存的密码
+func (s *EmailService) TestConnection(config *model.EmailConfig) error {
+	if err := s.fillSavedPassword(config); err != nil {
+		return err
+	}
+	if err := validateEmailConfig(config); err != nil {
+		return err
+	}
+
+	client, err := dialSMTP(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Quit()
+}
+
+// SendTest 使用给定配置直接发送测试邮件，不经过队列，密码为空时使用已保存的密码
+func (s *EmailService) SendTest(config *model.EmailConfig, to string) error {
+	if err := s.fillSavedPassword(config); err != nil {
+		return err
+	}
+
+	vars := s.CommonVariables()
+	subject := RenderEmailTemplate("{{site_name}} 测试邮件", vars)
+	textBody := RenderEmailTemplate("这是一封来自 {{site_name}} 的测试邮件。\n\n收到此邮件说明SMTP配置正确。\n\n发送时间：{{current_time}}", vars)
+	htmlBody := renderHTMLEmailTemplate("<p>这是一封来自 <strong>{{site_name}}</strong> 的测试邮件。</p><p>收到此邮件说明SMTP配置正确。</p><p>发送时间：{{current_time}}</p>", vars)
+
+	return sendWithConfig(config, &model.EmailMessage{To: to, Subject: subject, TextBody: textBody, HTMLBody: htmlBody})
+}
+
+// fillSavedPassword 表单中未填写密码时使用已保存的密码，
+// 服务器或用户名变更后必须重新填写，避免把已保存的密码发送到其他服务器
+func (s *EmailService) fillSavedPassword(config *model.EmailConfig) error {
+	if config.Password != "" {
+		return nil
+	}
+	saved, err := s.loadConfig()
+	if err != nil {
+		return err
+	}
+	if saved != nil && sameSMTPAccount(saved, config) {
+		config.Password = saved.Password
+	}
+	return nil
+}
+
+// sameSMTPAccount 检查两份配置是否指向同一SMTP服务器上的同一账号
+func sameSMTPAccount(a, b *model.EmailConfig) bool {
+	return a.SMTPHost == b.SMTPHost && a.SMTPPort == b.SMTPPort && a.Username == b.Username
+}
+
+// loadConfig 读取邮件配置并解密密码，未配置时返回nil
+func (s *EmailService) loadConfig() (*model.EmailConfig, error) {
+	config, err := s.emailRepo.GetConfig()
+	if err != nil || config == nil {
+		return nil, err
+	}
+	if config.Password, err = s.secretBox.Decrypt(config.Password); err != nil {
+		return nil, fmt.Errorf("解密SMTP密码失败: %w", err)
+	}
+	return config, nil
+}
+
+// Enqueue 将邮件写入发送队列，htmlBody为空时只发送纯文本
+func (s *EmailService) Enqueue(to, subject, textBody, htmlBody string) error {
+	config, err := s.emailRepo.GetConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil || !config.IsEnabled {
+		return ErrEmailDisabled
+	}
+
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效: %w", err)
+	}
+
+	message := &model.EmailMessage{
+		To:       recipient.Address,
+		Subject:  subject,
+		TextBody: textBody,
+		HTMLBody: htmlBody,
+	}
+	if err := s.queueRepo.Enqueue(message); err != nil {
+		return err
+	}
+
+	s.notifyQueue()
+	return nil
+}
+
+// SendTemplate 使用指定类型的默认模板生成邮件并加入发送队列，vars覆盖同名的通用变量
+func (s *EmailService) SendTemplate(templateType, to string, vars map[string]string) error {
+	template, err := s.emailRepo.GetDefaultTemplate(templateType)
+	if err != nil {
+		return err
+	}
+	if template == nil {
+		return fmt.Errorf("%w: %s", ErrEmailTemplateNotFound, templateType)
+	}
+
+	data := s.CommonVariables()
+	for key, value := range vars {
+		data[key] = value
+	}
+
+	htmlBody := ""
+	if template.HTMLBody != "" {
+		htmlBody = renderHTMLEmailTemplate(template.HTMLBody, data)
+	}
+	return s.Enqueue(to, RenderEmailTemplate(template.Subject, data), RenderEmailTemplate(template.Body, data), htmlBody)
+}
+
+// GetTemplates 获取全部邮件模板
+func (s *EmailService) GetTemplates() ([]*model.EmailTemplate, error) {
+	return s.emailRepo.GetTemplates()
+}
+
+// CreateTemplate 创建邮件模板
+func (s *EmailService) CreateTemplate(template *model.EmailTemplate) error {
+	if err := validateEmailTemplate(template); err != nil {
+		return err
+	}
+	return s.emailRepo.CreateTemplate(template)
+}
+
+// UpdateTemplate 更新邮件模板
+func (s *EmailService) UpdateTemplate(template *model.EmailTemplate) error {
+	existing, err := s.emailRepo.GetTemplateByID(template.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrEmailTemplateNotFound
+	}
+	if err := validateEmailTemplate(template); err != nil {
+		return err
+	}
+	if err := s.emailRepo.UpdateTemplate(template); err != nil {
+		return err
+	}
+	template.CreatedAt = existing.CreatedAt
+	template.UpdatedAt = time.Now()
+	return nil
+}
+
+// DeleteTemplate 删除邮件模板
+func (s *EmailService) DeleteTemplate(id uint) error {
+	existing, err := s.emailRepo.GetTemplateByID(id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrEmailTemplateNotFound
+	}
+	return s.emailRepo.DeleteTemplate(id)
+}
+
+// TemplateVariables 模板变量的当前值和说明
+func (s *EmailService) TemplateVariables() (map[string]string, map[string]string) {
+	return s.CommonVariables(), emailVariableDescriptions
+}
+
+// GetQueue 分页查询发信队列。正文可能包含重置密码链接等一次性凭据，不返回给前端
+func (s *EmailService) GetQueue(status string, limit, offset int) ([]*model.EmailMessage, int, error) {
+	messages, total, err := s.queueRepo.GetList(status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, message := range messages {
+		message.TextBody = ""
+		message.HTMLBody = ""
+	}
+	return messages, total, nil
+}
+
+// RetryMessage 重新发送已放弃的邮件
+func (s *EmailService) RetryMessage(id uint) error {
+	ok, err := s.queueRepo.Requeue(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrEmailMessageNotFound
+	}
+	s.notifyQueue()
+	return nil
+}
+
+// CleanupSentMessages 清理超过保留期的已发送邮件
+func (s *EmailService) CleanupSentMessages() error {
+	return s.queueRepo.DeleteSentBefore(time.Now().Add(-emailSentRetention))
+}
+
+// StartQueue 启动后台发信任务
+func (s *EmailService) StartQueue() {
+	go s.queueLoop()
+}
+
+// Stop 停止后台发信任务
+func (s *EmailService) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// notifyQueue 唤醒后台任务立即处理队列
+func (s *EmailService) notifyQueue() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// queueLoop 定期或被唤醒时处理到期的邮件
+func (s *EmailService) queueLoop() {
+	ticker := time.NewTicker(emailQueueInterval)
+	defer ticker.Stop()
+
+	for {
+		s.ProcessQueue()
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// ProcessQueue 发送一批到期的邮件，失败的邮件按指数退避安排重试。
+// 邮件服务未启用时邮件保留在队列中，启用后继续发送
+func (s *EmailService) ProcessQueue() {
+	config, err := s.loadConfig()
+	if err != nil {
+		log.Printf("读取邮件配置失败: %v", err)
+		return
+	}
+	if config == nil || !config.IsEnabled {
+		return
+	}
+
+	messages, err := s.queueRepo.GetDue(time.Now(), emailQueueBatchSize)
+	if err != nil {
+		log.Printf("读取发信队列失败: %v", err)
+		return
+	}
+
+	for _, message := range messages {
+		sendErr := sendWithConfig(config, message)
+		if sendErr == nil {
+			if err := s.queueRepo.MarkSent(message.ID); err != nil {
+				log.Printf("更新邮件状态失败: ID=%d, %v", message.ID, err)
+			}
+			continue
+		}
+
+		attempts := message.Attempts + 1
+		if attempts >= emailMaxAttempts {
+			log.Printf("邮件发送失败，已放弃: ID=%d, 收件人=%s, 尝试%d次, %v", message.ID, message.To, attempts, sendErr)
+			err = s.queueRepo.MarkFailed(message.ID, sendErr.Error())
+		} else {
+			delay := emailRetryDelay(attempts)
+			log.Printf("邮件发送失败，%v后重试: ID=%d, 收件人=%s, %v", delay, message.ID, message.To, sendErr)
+			err = s.queueRepo.MarkRetry(message.ID, sendErr.Error(), time.Now().Add(delay))
+		}
+		if err != nil {
+			log.Printf("更新邮件状态失败: ID=%d, %v", message.ID, err)
+		}
+	}
+}
+
+// emailRetryDelay 第attempts次失败后的重试等待时间
+func emailRetryDelay(attempts int) time.Duration {
+	delay := emailRetryBaseDelay
+	for i := 1; i < attempts && delay < emailRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, emailRetryMaxDelay)
+}
+
+// CommonVariables 所有模板可用的通用变量
+func (s *EmailService) CommonVariables() map[string]string {
+	now := time.Now()
+	vars := map[string]string{
+		"site_name":     "Mini Web",
+		"support_email": "",
+		"current_year":  strconv.Itoa(now.Year()),
+		"current_date":  now.Format("2006-01-02"),
+		"current_time":  now.Format("2006-01-02 15:04:05"),
+	}
+
+	if s.configRepo != nil {
+		if config, err := s.configRepo.GetByKey("site_name"); err == nil && config != nil && config.Value != "" {
+			vars["site_name"] = config.Value
+		}
+	}
+	if config, err := s.emailRepo.GetConfig(); err == nil && config != nil {
+		vars["support_email"] = config.FromEmail
+	}
+	return vars
+}
+
+// RenderEmailTemplate 替换内容中的{{变量}}，未提供的变量保持原样
+func RenderEmailTemplate(content string, data map[string]string) string {
+	pairs := make([]string, 0, len(data)*2)
+	for key, value := range data {
+		pairs = append(pairs, "{{"+key+"}}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(content)
+}
+
+// renderHTMLEmailTemplate 替换HTML内容中的{{变量}}，变量值做HTML转义
+func renderHTMLEmailTemplate(content string, data map[string]string) string {
+	escaped := make(map[string]string, len(data))
+	for key, value := range data {
+		escaped[key] = html.EscapeString(value)
+	}
+	return RenderEmailTemplate(content, escaped)
+}
+
+// sendWithConfig 按配置连接SMTP服务器并投递邮件
+func sendWithConfig(config *model.EmailConfig, message *model.EmailMessage) error {
+	if err := validateEmailConfig(config); err != nil {
+		return err
+	}
+
+	recipient, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效: %w", err)
+	}
+
+	data, err := buildMessage(config, recipient, message)
+	if err != nil {
+		return err
+	}
+
+	client, err := dialSMTP(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(config.FromEmail); err != nil {
+		return fmt.Errorf("设置发件人失败: %w", err)
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return fmt.Errorf("设置收件人失败: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return fmt.Errorf("写入邮件内容失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("服务器拒绝邮件: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dialSMTP 建立SMTP会话：enable_ssl直接使用TLS，enable_tls通过STARTTLS升级，之后按需认证
+func dialSMTP(config *model.EmailConfig) (*smtp.Client, error) {
+	address := net.JoinHostPort(config.SMTPHost, strconv.Itoa(config.SMTPPort))
+	tlsConfig := &tls.Config{ServerName: config.SMTPHost}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if config.EnableSSL {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, config.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("创建SMTP会话失败: %w", err)
+	}
+
+	if !config.EnableSSL && config.EnableTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("SMTP服务器不支持STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS失败: %w", err)
+		}
+	}
+
+	if config.Username != "" {
+		// net/smtp的PLAIN认证只允许在TLS连接或本机上发送密码
+		auth := smtp.PlainAuth("", config.Username, config.Password, config.SMTPHost)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("SMTP认证失败: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// buildMessage 构建UTF-8邮件：有HTML正文时使用multipart/alternative，否则为纯文本，正文均使用base64编码
+func buildMessage(config *model.EmailConfig, to *mail.Address, message *model.EmailMessage) ([]byte, error) {
+	from := &mail.Address{Name: config.FromName, Address: config.FromEmail}
+	messageID, err := randomTokenString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	writeHeader("From", from.String())
+	writeHeader("To", to.String())
+	writeHeader("Subject", mime.BEncoding.Encode("UTF-8", message.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", "<"+messageID+"@"+emailDomain(config.FromEmail)+">")
+	writeHeader("MIME-Version", "1.0")
+
+	if message.HTMLBody == "" {
+		writeHeader("Content-Type", "text/plain; charset=UTF-8")
+		writeHeader("Content-Transfer-Encoding", "base64")
+		buf.WriteString("\r\n")
+		writeBase64Lines(&buf, message.TextBody)
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	writeHeader("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+
+	// 按RFC 2046，客户端优先显示最后一个能识别的部分，HTML放在最后
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", message.TextBody},
+		{"text/html; charset=UTF-8", message.HTMLBody},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("构建邮件正文失败: %w", err)
+		}
+		var encoded bytes.Buffer
+		writeBase64Lines(&encoded, part.content)
+		writer.Write(encoded.Bytes())
+	}
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("构建邮件正文失败: %w", err)
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines 以每行76个字符写入base64编码的内容
+func writeBase64Lines(buf *bytes.Buffer, content string) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(content))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+// validateEmailConfig 检查发信配置是否完整
+func validateEmailConfig(config *model.EmailConfig) error {
+	if config.SMTPHost == "" {
+		return fmt.Errorf("%w: SMTP主机不能为空", ErrInvalidEmailConfig)
+	}
+	if config.SMTPPort <= 0 || config.SMTPPort > 65535 {
+		return fmt.Errorf("%w: SMTP端口必须在1-65535之间", ErrInvalidEmailConfig)
+	}
+	if _, err := mail.ParseAddress(config.FromEmail); err != nil {
+		return fmt.Errorf("%w: 发件人邮箱格式不正确", ErrInvalidEmailConfig)
+	}
+	return nil
+}
+
+// emailDomain 获取邮箱地址的域名部分
+func emailDomain(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
+
+// validateEmailTemplate 检查邮件模板内容
+func validateEmailTemplate(template *model.EmailTemplate) error {
+	template.Name = strings.TrimSpace(template.Name)
+	template.Subject = strings.TrimSpace(template.Subject)
+	if template.Name == "" {
+		return fmt.Errorf("%w: 模板名称不能为空", ErrInvalidEmailTemplate)
+	}
+	if template.Subject == "" {
+		return fmt.Errorf("%w: 邮件主题不能为空", ErrInvalidEmailTemplate)
+	}
+	if strings.TrimSpace(template.Body) == "" {
+		return fmt.Errorf("%w: 纯文本正文不能为空", ErrInvalidEmailTemplate)
+	}
+	if !emailTemplateTypes[template.Type] {
+		return fmt.Errorf("%w: 不支持的模板类型%s", ErrInvalidEmailTemplate, template.Type)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+func TestSavedSMTPPasswordOnlyForSameAccount(t *testing.T) {
+	s := newTestServices(t)
+	secretBox, err := NewSecretBox(filepath.Join(testDataDir, "secret_test.key"))
+	if err != nil {
+		t.Fatalf("初始化SecretBox失败: %v", err)
+	}
+	templates := sqlite.NewEmailRepository(sqlite.DB)
+	email := NewEmailService(templates, sqlite.NewEmailQueueRepository(sqlite.DB), s.configs, secretBox)
+
+	saved := model.EmailConfig{
+		SMTPHost:  "smtp.example.com",
+		SMTPPort:  587,
+		Username:  "mailer",
+		Password:  "smtp-secret",
+		FromEmail: "noreply@example.com",
+		EnableTLS: true,
+	}
+	config := saved
+	if err := email.SaveConfig(&config); err != nil {
+		t.Fatalf("保存邮件配置失败: %v", err)
+	}
+	t.Cleanup(func() {
+		email.SaveConfig(&model.EmailConfig{SMTPPort: 587, EnableTLS: true})
+	})
+
+	tests := []struct {
+		name   string
+		change func(c *model.EmailConfig)
+		want   string
+	}{
+		{"相同账号", func(c *model.EmailConfig) {}, "smtp-secret"},
+		{"修改服务器", func(c *model.EmailConfig) { c.SMTPHost = "attacker.example.net" }, ""},
+		{"修改端口", func(c *model.EmailConfig) { c.SMTPPort = 2525 }, ""},
+		{"修改用户名", func(c *model.EmailConfig) { c.Username = "other" }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := saved
+			config.Password = ""
+			tt.change(&config)
+			if err := email.fillSavedPassword(&config); err != nil {
+				t.Fatalf("fillSavedPassword() error = %v", err)
+			}
+			if config.Password != tt.want {
+				t.Errorf("fillSavedPassword() 密码 = %q, want %q", config.Password, tt.want)
+			}
+		})
+	}
+
+	// 修改服务器后保存且未填写密码时，不保留原密码
+	moved := saved
+	moved.SMTPHost = "smtp2.example.com"
+	moved.Password = ""
+	if err := email.SaveConfig(&moved); err != nil {
+		t.Fatalf("保存邮件配置失败: %v", err)
+	}
+	stored, err := email.loadConfig()
+	if err != nil {
+		t.Fatalf("读取邮件配置失败: %v", err)
+	}
+	if stored.Password != "" {
+		t.Errorf("修改服务器后已保存的密码 = %q, want 空", stored.Password)
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 终端输出流控：服务端最多发送window字节未确认的输出，客户端处理完输出后确认已消费的字节数。
+// 信用耗尽时停止读取终端，SSH通道窗口随之填满，远端暂停输出，浏览器不会被大量输出拖垮
+const (
+	DefaultFlowControlWindow = 256 * 1024
+	MinFlowControlWindow     = 16 * 1024
+	MaxFlowControlWindow     = 8 * 1024 * 1024
+
+	// 停滞超过该时长时记录警告
+	flowControlStallWarning = 30 * time.Second
+)
+
+// FlowControl 单个终端会话的输出信用窗口。未启用时不做限制，兼容不确认输出的旧客户端
+type FlowControl struct {
+	name         string
+	mutex        sync.Mutex
+	enabled      bool
+	window       int64
+	inFlight     int64 // 已发送未确认的字节数
+	stalledSince time.Time
+	signal       chan struct{}
+}
+
+// NewFlowControl 创建流控，name用于日志和统计
+func NewFlowControl(name string) *FlowControl {
+	return &FlowControl{name: name, signal: make(chan struct{}, 1)}
+}
+
+// ClampFlowControlWindow 把客户端请求的窗口限制在允许范围内，0表示使用默认窗口
+func ClampFlowControlWindow(window int) int {
+	switch {
+	case window <= 0:
+		return DefaultFlowControlWindow
+	case window < MinFlowControlWindow:
+		return MinFlowControlWindow
+	case window > MaxFlowControlWindow:
+		return MaxFlowControlWindow
+	}
+	return window
+}
+
+// Enable 启用流控或调整窗口，返回实际使用的窗口
+func (f *FlowControl) Enable(window int) int {
+	window = ClampFlowControlWindow(window)
+
+	f.mutex.Lock()
+	f.enabled = true
+	f.window = int64(window)
+	f.mutex.Unlock()
+	f.notify()
+
+	registerFlowControl(f)
+	return window
+}
+
+// Release 会话结束时从统计中移除
+func (f *FlowControl) Release() {
+	f.mutex.Lock()
+	if !f.stalledSince.IsZero() {
+		flowControlMetrics.endStall(time.Since(f.stalledSince))
+		f.stalledSince = time.Time{}
+	}
+	f.mutex.Unlock()
+
+	unregisterFlowControl(f)
+}
+
+// Acquire 等待可用信用，返回本次最多可以读取的字节数（不超过max）。
+// 信用耗尽时阻塞直到客户端确认或done关闭，done关闭时返回false
+func (f *FlowControl) Acquire(max int, done <-chan struct{}) (int, bool) {
+	var warning <-chan time.Time
+	for {
+		f.mutex.Lock()
+		if !f.enabled {
+			f.mutex.Unlock()
+			return max, true
+		}
+		if credit := f.window - f.inFlight; credit > 0 {
+			if !f.stalledSince.IsZero() {
+				flowControlMetrics.endStall(time.Since(f.stalledSince))
+				f.stalledSince = time.Time{}
+			}
+			f.mutex.Unlock()
+			if credit < int64(max) {
+				return int(credit), true
+			}
+			return max, true
+		}
+		if f.stalledSince.IsZero() {
+			f.stalledSince = time.Now()
+			flowControlMetrics.beginStall()
+			timer := time.NewTimer(flowControlStallWarning)
+			defer timer.Stop()
+			warning = timer.C
+		}
+		f.mutex.Unlock()
+
+		select {
+		case <-f.signal:
+		case <-warning:
+			log.Printf("终端输出流控停滞超过%v，客户端未确认输出: 会话=%s", flowControlStallWarning, f.name)
+		case <-done:
+			return 0, false
+		}
+	}
+}
+
+// Consume 记录已发送的字节数
+func (f *FlowControl) Consume(n int) {
+	f.mutex.Lock()
+	if f.enabled {
+		f.inFlight += int64(n)
+	}
+	f.mutex.Unlock()
+}
+
+// Ack 客户端确认已消费n字节，恢复相应的信用
+func (f *FlowControl) Ack(n int) {
+	if n <= 0 {
+		return
+	}
+	f.mutex.Lock()
+	f.inFlight -= int64(n)
+	if f.inFlight < 0 {
+		f.inFlight = 0
+	}
+	f.mutex.Unlock()
+	f.notify()
+}
+
+// Reset 清零未确认的字节数。客户端重连后不会再确认断开前的输出，由新连接重新计算
+func (f *FlowControl) Reset() {
+	f.mutex.Lock()
+	f.inFlight = 0
+	f.mutex.Unlock()
+	f.notify()
+}
+
+// notify 唤醒等待信用的读取协程
+func (f *FlowControl) notify() {
+	select {
+	case f.signal <- struct{}{}:
+	default:
+	}
+}
+
+// FlowControlSessionStats 单个会话的流控状态
+type FlowControlSessionStats struct {
+	Session      string  `json:"session"`
+	Window       int64   `json:"window"`
+	InFlight     int64   `json:"in_flight"`
+	Stalled      bool    `json:"stalled"`
+	StalledFor   float64 `json:"stalled_seconds"`
+	StalledSince string  `json:"stalled_since,omitempty"`
+}
+
+// stats 当前流控状态
+func (f *FlowControl) stats() FlowControlSessionStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	stats := FlowControlSessionStats{Session: f.name, Window: f.window, InFlight: f.inFlight}
+	if !f.stalledSince.IsZero() {
+		stats.Stalled = true
+		stats.StalledFor = time.Since(f.stalledSince).Seconds()
+		stats.StalledSince = f.stalledSince.Format(time.RFC3339)
+	}
+	return stats
+}
+
+// flowControlCounters 全局流控统计
+type flowControlCounters struct {
+	mutex         sync.Mutex
+	stalled       int
+	totalStalls   int64
+	totalStallDur time.Duration
+}
+
+func (c *flowControlCounters) beginStall() {
+	c.mutex.Lock()
+	c.stalled++
+	c.totalStalls++
+	c.mutex.Unlock()
+}
+
+func (c *flowControlCounters) endStall(d time.Duration) {
+	c.mutex.Lock()
+	c.stalled--
+	c.totalStallDur += d
+	c.mutex.Unlock()
+}
+
+var (
+	flowControlMetrics = &flowControlCounters{}
+
+	flowControlsMutex sync.Mutex
+	flowControls      = map[*FlowControl]struct{}{}
+)
+
+func registerFlowControl(f *FlowControl) {
+	flowControlsMutex.Lock()
+	flowControls[f] = struct{}{}
+	flowControlsMutex.Unlock()
+}
+
+func unregisterFlowControl(f *FlowControl) {
+	flowControlsMutex.Lock()
+	delete(flowControls, f)
+	flowControlsMutex.Unlock()
+}
+
+// GetFlowControlStats 获取终端输出流控统计，stalled_sessions列出当前停滞的会话，停滞最久的在前
+func GetFlowControlStats() map[string]interface{} {
+	flowControlsMutex.Lock()
+	sessions := make([]*FlowControl, 0, len(flowControls))
+	for f := range flowControls {
+		sessions = append(sessions, f)
+	}
+	flowControlsMutex.Unlock()
+
+	stalled := make([]FlowControlSessionStats, 0)
+	for _, f := range sessions {
+		if stats := f.stats(); stats.Stalled {
+			stalled = append(stalled, stats)
+		}
+	}
+	sort.Slice(stalled, func(i, j int) bool { return stalled[i].StalledFor > stalled[j].StalledFor })
+
+	flowControlMetrics.mutex.Lock()
+	defer flowControlMetrics.mutex.Unlock()
+	return map[string]interface{}{
+		"flow_controlled_sessions": len(sessions),
+		"stalled_count":            flowControlMetrics.stalled,
+		"total_stalls":             flowControlMetrics.totalStalls,
+		"total_stall_seconds":      flowControlMetrics.totalStallDur.Seconds(),
+		"stalled_sessions":         stalled,
+	}
+}
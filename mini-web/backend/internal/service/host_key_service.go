@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrHostKeyMismatch 主机公钥与首次连接时记录的不一致，可能遭到中间人攻击
+	ErrHostKeyMismatch = errors.New("SSH主机公钥与记录不一致，连接已拒绝")
+
+	// ErrHostKeyNotFound 主机公钥记录不存在
+	ErrHostKeyNotFound = errors.New("主机公钥记录不存在")
+)
+
+// HostKeyService SSH主机公钥校验：首次连接时记录主机公钥（TOFU），
+// 之后公钥变化时拒绝连接并发布通知。主机确实更换密钥时由管理员删除旧记录
+type HostKeyService struct {
+	repo model.SSHHostKeyRepository
+}
+
+// 全局主机公钥校验实例
+var globalHostKeyService *HostKeyService
+
+// InitHostKeyService 初始化全局主机公钥校验服务
+func InitHostKeyService(repo model.SSHHostKeyRepository) *HostKeyService {
+	globalHostKeyService = &HostKeyService{repo: repo}
+	return globalHostKeyService
+}
+
+// GetHostKeyService 获取全局主机公钥校验服务，未初始化时返回nil
+func GetHostKeyService() *HostKeyService {
+	return globalHostKeyService
+}
+
+// GetAll 获取全部已记录的主机公钥
+func (s *HostKeyService) GetAll() ([]*model.SSHHostKey, error) {
+	return s.repo.GetAll()
+}
+
+// Delete 删除主机公钥记录，下次连接时重新记录
+func (s *HostKeyService) Delete(id uint) error {
+	found, err := s.repo.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrHostKeyNotFound
+	}
+	return nil
+}
+
+// applyTo 为SSH客户端配置设置主机公钥校验，已有记录时只协商记录中的密钥类型，
+// 避免服务端提供另一种类型的公钥而误报不一致
+func (s *HostKeyService) applyTo(config *ssh.ClientConfig, conn *model.Connection) {
+	if s == nil {
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+		return
+	}
+
+	known, err := s.repo.Get(conn.Host, conn.Port)
+	if err != nil {
+		log.Printf("查询SSH主机公钥失败: %v", err)
+	}
+	if known != nil {
+		config.HostKeyAlgorithms = hostKeyAlgorithms(known.KeyType)
+	}
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return s.verify(conn, known, key)
+	}
+}
+
+// verify 校验服务端公钥，没有记录时保存
+func (s *HostKeyService) verify(conn *model.Connection, known *model.SSHHostKey, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+	if known == nil {
+		record := &model.SSHHostKey{
+			Host:        conn.Host,
+			Port:        conn.Port,
+			KeyType:     key.Type(),
+			PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+			Fingerprint: fingerprint,
+		}
+		if err := s.repo.Create(record); err != nil {
+			return err
+		}
+		// 并发首次连接时以先写入的记录为准
+		stored, err := s.repo.Get(conn.Host, conn.Port)
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.Fingerprint == fingerprint {
+			log.Printf("记录SSH主机公钥: %s:%d %s %s", conn.Host, conn.Port, key.Type(), fingerprint)
+			return nil
+		}
+		known = stored
+	}
+
+	if known.Fingerprint == fingerprint {
+		if err := s.repo.Touch(known.ID); err != nil {
+			log.Printf("更新SSH主机公钥失败: %v", err)
+		}
+		return nil
+	}
+
+	log.Printf("SSH主机公钥不一致: %s:%d 记录=%s 实际=%s", conn.Host, conn.Port, known.Fingerprint, fingerprint)
+	GetNotificationService().Publish(&model.NotificationEvent{
+		Type:     model.EventHostKeyMismatch,
+		Severity: model.SeverityCritical,
+		Title:    "SSH主机密钥不匹配",
+		Message:  fmt.Sprintf("连接%s（%s:%d）的主机公钥与记录不一致，连接已被拒绝", conn.Name, conn.Host, conn.Port),
+		Data: map[string]string{
+			"connection_id":        strconv.FormatUint(uint64(conn.ID), 10),
+			"connection_name":      conn.Name,
+			"host":                 conn.Host,
+			"port":                 strconv.Itoa(conn.Port),
+			"expected_fingerprint": known.Fingerprint,
+			"actual_fingerprint":   fingerprint,
+		},
+	})
+	return fmt.Errorf("%w: 记录的指纹为%s，服务器提供的为%s", ErrHostKeyMismatch, known.Fingerprint, fingerprint)
+}
+
+// hostKeyAlgorithms 记录的密钥类型对应的可协商算法
+func hostKeyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的JWT签名算法
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// minHMACSecretLength HS256密钥的最小长度，与SHA-256输出长度一致
+const minHMACSecretLength = 32
+
+var (
+	// ErrUnknownSigningKey 令牌的kid不在密钥集中
+	ErrUnknownSigningKey = errors.New("未知的令牌签名密钥")
+)
+
+// jwtKey 单个签名或验证密钥
+type jwtKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{} // 私钥或HMAC密钥，仅用于验证的历史公钥为nil
+	verifyKey interface{}
+}
+
+// JWTKeySet JWT密钥集。
+// 只有当前密钥用于签名，历史密钥在轮换期间继续用于验证，按令牌头的kid选择
+type JWTKeySet struct {
+	signing *jwtKey
+	keys    map[string]*jwtKey
+}
+
+// JWK JSON Web Key公钥表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// 全局JWT密钥集实例
+var globalJWTKeySet *JWTKeySet
+
+// GetJWTKeySet 获取全局JWT密钥集，未初始化时返回nil
+func GetJWTKeySet() *JWTKeySet {
+	return globalJWTKeySet
+}
+
+// InitJWTKeySet 加载签名密钥和历史验证密钥并设置为全局密钥集。
+// HS256优先使用secret，为空时从keyPath读取；非对称算法从keyPath读取PEM私钥。
+// keyPath不存在时自动生成。verifyKeyPaths为轮换前的旧密钥文件，可以是私钥、公钥或HMAC密钥
+func InitJWTKeySet(algorithm, secret, keyPath string, verifyKeyPaths []string) (*JWTKeySet, error) {
+	keySet, err := LoadJWTKeySet(algorithm, secret, keyPath, verifyKeyPaths)
+	if err != nil {
+		return nil, err
+	}
+	globalJWTKeySet = keySet
+	return keySet, nil
+}
+
+// LoadJWTKeySet 加载JWT密钥集
+func LoadJWTKeySet(algorithm, secret, keyPath string, verifyKeyPaths []string) (*JWTKeySet, error) {
+	var signing *jwtKey
+	var err error
+
+	switch algorithm {
+	case JWTAlgorithmHS256:
+		if secret != "" {
+			if len(secret) < minHMACSecretLength {
+				return nil, fmt.Errorf("JWT_SECRET长度为%d字节，HS256密钥至少需要%d字节", len(secret), minHMACSecretLength)
+			}
+			signing = newHMACKey([]byte(secret))
+		} else {
+			signing, err = loadOrCreateHMACKey(keyPath)
+		}
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+		signing, err = loadOrCreateAsymmetricKey(algorithm, keyPath)
+	default:
+		return nil, fmt.Errorf("不支持的JWT签名算法: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &JWTKeySet{
+		signing: signing,
+		keys:    map[string]*jwtKey{signing.kid: signing},
+	}
+
+	for _, path := range verifyKeyPaths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := loadVerifyKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := keySet.keys[key.kid]; !exists {
+			keySet.keys[key.kid] = key
+		}
+	}
+
+	log.Printf("JWT密钥集已加载: 算法=%s, 当前kid=%s, 可验证密钥数=%d", signing.method.Alg(), signing.kid, len(keySet.keys))
+	return keySet, nil
+}
+
+// Sign 使用当前密钥签名声明，令牌头写入kid
+func (k *JWTKeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.kid
+	return token.SignedString(k.signing.signKey)
+}
+
+// ParseWithClaims 解析并验证令牌，按kid选择密钥且要求算法与密钥一致
+func (k *JWTKeySet) ParseWithClaims(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, k.keyFunc,
+		jwt.WithValidMethods([]string{JWTAlgorithmHS256, JWTAlgorithmRS256, JWTAlgorithmEdDSA}))
+}
+
+// keyFunc 根据令牌头查找验证密钥
+func (k *JWTKeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("令牌算法%s与密钥%s不匹配", token.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS 返回全部非对称验证密钥的公钥，HMAC密钥不会公开
+func (k *JWTKeySet) JWKS() []JWK {
+	jwks := []JWK{}
+
+	// 当前签名密钥排在最前
+	if jwk, ok := k.signing.jwk(); ok {
+		jwks = append(jwks, jwk)
+	}
+	for kid, key := range k.keys {
+		if kid == k.signing.kid {
+			continue
+		}
+		if jwk, ok := key.jwk(); ok {
+			jwks = append(jwks, jwk)
+		}
+	}
+	return jwks
+}
+
+// jwk 将公钥转换为JWK
+func (key *jwtKey) jwk() (JWK, bool) {
+	switch pub := key.verifyKey.(type) {
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: key.kid,
+			Use: "sig",
+			Alg: JWTAlgorithmEdDSA,
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: key.kid,
+			Use: "sig",
+			Alg: JWTAlgorithmRS256,
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	}
+	return JWK{}, false
+}
+
+// newHMACKey 创建HMAC密钥，kid取密钥哈希前缀，不泄露密钥本身
+func newHMACKey(secret []byte) *jwtKey {
+	sum := sha256.Sum256(secret)
+	return &jwtKey{
+		kid:       "hs-" + hex.EncodeToString(sum[:6]),
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// newAsymmetricKey 根据私钥或公钥创建密钥，kid取公钥指纹
+func newAsymmetricKey(private crypto.Signer, public crypto.PublicKey) (*jwtKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("编码公钥失败: %w", err)
+	}
+	sum := sha256.Sum256(der)
+
+	key := &jwtKey{kid: base64.RawURLEncoding.EncodeToString(sum[:12]), verifyKey: public}
+	if private != nil {
+		key.signKey = private
+	}
+
+	switch public.(type) {
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	default:
+		return nil, errors.New("不支持的JWT密钥类型，仅支持Ed25519和RSA")
+	}
+	return key, nil
+}
+
+// loadOrCreateHMACKey 读取base64编码的HMAC密钥文件，不存在时生成
+func loadOrCreateHMACKey(keyPath string) (*jwtKey, error) {
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(secret) < minHMACSecretLength {
+			return nil, fmt.Errorf("JWT密钥文件%s格式无效，应为base64编码的至少%d字节密钥", keyPath, minHMACSecretLength)
+		}
+		return newHMACKey(secret), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取JWT密钥失败: %w", err)
+	}
+
+	secret := make([]byte, minHMACSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("生成JWT密钥失败: %w", err)
+	}
+	if err := writeJWTKeyFile(keyPath, []byte(base64.StdEncoding.EncodeToString(secret)+"\n")); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已生成新的JWT签名密钥: %s", keyPath)
+	return newHMACKey(secret), nil
+}
+
+// loadOrCreateAsymmetricKey 读取PEM私钥文件，不存在时按算法生成
+func loadOrCreateAsymmetricKey(algorithm, keyPath string) (*jwtKey, error) {
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		key, err := parsePEMKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析JWT密钥文件%s失败: %w", keyPath, err)
+		}
+		if key.signKey == nil {
+			return nil, fmt.Errorf("JWT密钥文件%s不包含私钥", keyPath)
+		}
+		if key.method.Alg() != algorithm {
+			return nil, fmt.Errorf("JWT密钥文件%s的算法为%s，与配置的%s不一致", keyPath, key.method.Alg(), algorithm)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取JWT密钥失败: %w", err)
+	}
+
+	var private crypto.Signer
+	if algorithm == JWTAlgorithmEdDSA {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成JWT密钥失败: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("编码JWT密钥失败: %w", err)
+	}
+	if err := writeJWTKeyFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已生成新的JWT签名密钥: %s", keyPath)
+	return newAsymmetricKey(private, private.Public())
+}
+
+// loadVerifyKey 读取轮换前的旧密钥，PEM格式按公私钥解析，否则视为base64编码的HMAC密钥
+func loadVerifyKey(path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取JWT验证密钥%s失败: %w", path, err)
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := parsePEMKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析JWT验证密钥%s失败: %w", path, err)
+		}
+		// 旧密钥只用于验证
+		key.signKey = nil
+		return key, nil
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(secret) < minHMACSecretLength {
+		return nil, fmt.Errorf("JWT验证密钥%s格式无效，HMAC密钥应为base64编码的至少%d字节密钥", path, minHMACSecretLength)
+	}
+	key := newHMACKey(secret)
+	key.signKey = nil
+	return key, nil
+}
+
+// parsePEMKey 解析PEM编码的私钥（PKCS8/PKCS1）或公钥（PKIX）
+func parsePEMKey(data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("不是PEM格式")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("不支持的私钥类型")
+		}
+		return newAsymmetricKey(signer, signer.Public())
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey(private, private.Public())
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey(nil, public)
+	}
+	return nil, fmt.Errorf("不支持的PEM类型: %s", block.Type)
+}
+
+// writeJWTKeyFile 以仅所有者可读写的权限保存密钥文件
+func writeJWTKeyFile(keyPath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return fmt.Errorf("创建JWT密钥目录失败: %w", err)
+	}
+	if err := os.WriteFile(keyPath, data, 0600); err != nil {
+		return fmt.Errorf("保存JWT密钥失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadJWTKeySetRejectsShortHMACSecret(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "jwt.key")
+
+	_, err := LoadJWTKeySet(JWTAlgorithmHS256, "too-short-secret", keyPath, nil)
+	if err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Fatalf("短密钥 LoadJWTKeySet() error = %v, want JWT_SECRET长度错误", err)
+	}
+
+	if _, err := LoadJWTKeySet(JWTAlgorithmHS256, strings.Repeat("s", minHMACSecretLength), keyPath, nil); err != nil {
+		t.Fatalf("%d字节密钥 LoadJWTKeySet() error = %v", minHMACSecretLength, err)
+	}
+}
+
+func TestLoadJWTKeySetRejectsShortHMACVerifyKey(t *testing.T) {
+	dir := t.TempDir()
+	verifyPath := filepath.Join(dir, "old.key")
+	if err := os.WriteFile(verifyPath, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadJWTKeySet(JWTAlgorithmEdDSA, "", filepath.Join(dir, "jwt.key"), []string{verifyPath})
+	if err == nil {
+		t.Fatal("过短的HMAC验证密钥应被拒绝")
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/config"
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	// ErrLDAPUnavailable 目录服务不可用
+	ErrLDAPUnavailable = errors.New("LDAP目录服务不可用")
+)
+
+// LDAPConn LDAPProvider用到的目录连接操作，可替换为进程内的桩实现
+type LDAPConn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	StartTLS(config *tls.Config) error
+	Close() error
+}
+
+// LDAPDialer 建立目录连接
+type LDAPDialer func(url string, tlsConfig *tls.Config, timeout time.Duration) (LDAPConn, error)
+
+// ExternalIdentity 外部身份源认证通过的用户身份
+type ExternalIdentity struct {
+	Source   string // 身份来源，如ldap
+	Subject  string // 身份源中的唯一标识，LDAP为用户DN
+	Username string // mini-web用户名
+	Email    string
+	Name     string
+	Groups   []string // 所属组，LDAP为组DN
+}
+
+// LDAPProvider LDAP/AD认证提供者：
+// 使用服务账号绑定并搜索用户，再以用户DN和密码绑定验证密码
+type LDAPProvider struct {
+	cfg       config.LDAPConfig
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	dial      LDAPDialer
+}
+
+// NewLDAPProvider 创建LDAP认证提供者
+func NewLDAPProvider(cfg config.LDAPConfig) (*LDAPProvider, error) {
+	return NewLDAPProviderWithDialer(cfg, dialLDAP)
+}
+
+// NewLDAPProviderWithDialer 使用指定的连接函数创建LDAP认证提供者
+func NewLDAPProviderWithDialer(cfg config.LDAPConfig, dial LDAPDialer) (*LDAPProvider, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("LDAP配置不完整，需要URL和BaseDN")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CACertPath != "" {
+		pemData, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取LDAP CA证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("LDAP CA证书%s格式无效", cfg.CACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if host := ldapURLHost(cfg.URL); host != "" {
+		tlsConfig.ServerName = host
+	}
+
+	timeout := time.Duration(cfg.TimeoutSecond) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &LDAPProvider{cfg: cfg, tlsConfig: tlsConfig, timeout: timeout, dial: dial}, nil
+}
+
+// Config 获取LDAP配置
+func (p *LDAPProvider) Config() config.LDAPConfig {
+	return p.cfg
+}
+
+// Authenticate 验证目录用户的密码并返回其身份和组
+func (p *LDAPProvider) Authenticate(username, password string) (*ExternalIdentity, error) {
+	// 空密码在LDAP中是匿名绑定，会被视为成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// 服务账号绑定
+	if p.cfg.BindDN != "" {
+		if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("%w: 服务账号绑定失败: %v", ErrLDAPUnavailable, err)
+		}
+	}
+
+	entry, err := p.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{
+		Source:   "ldap",
+		Subject:  entry.DN,
+		Username: username,
+		Email:    entry.GetAttributeValue(p.cfg.EmailAttribute),
+		Name:     entry.GetAttributeValue(p.cfg.NameAttribute),
+	}
+	if p.cfg.GroupAttribute != "" {
+		identity.Groups = append(identity.Groups, entry.GetAttributeValues(p.cfg.GroupAttribute)...)
+	}
+
+	// 以用户身份绑定验证密码
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: 用户绑定失败: %v", ErrLDAPUnavailable, err)
+	}
+
+	// 没有memberOf的目录通过搜索组获取成员关系，重新以服务账号绑定以获得搜索权限
+	if p.cfg.GroupBaseDN != "" {
+		if p.cfg.BindDN != "" {
+			if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+				return nil, fmt.Errorf("%w: 服务账号绑定失败: %v", ErrLDAPUnavailable, err)
+			}
+		}
+		groups, err := p.searchGroups(conn, entry.DN, username)
+		if err != nil {
+			return nil, err
+		}
+		identity.Groups = append(identity.Groups, groups...)
+	}
+
+	return identity, nil
+}
+
+// connect 建立连接，按配置升级StartTLS
+func (p *LDAPProvider) connect() (LDAPConn, error) {
+	conn, err := p.dial(p.cfg.URL, p.tlsConfig, p.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+
+	if p.cfg.StartTLS && !strings.HasPrefix(strings.ToLower(p.cfg.URL), "ldaps://") {
+		if err := conn.StartTLS(p.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: StartTLS失败: %v", ErrLDAPUnavailable, err)
+		}
+	}
+	return conn, nil
+}
+
+// findUser 按过滤器搜索唯一的用户条目
+func (p *LDAPProvider) findUser(conn LDAPConn, username string) (*ldap.Entry, error) {
+	attributes := []string{"dn", p.cfg.EmailAttribute, p.cfg.NameAttribute}
+	if p.cfg.GroupAttribute != "" {
+		attributes = append(attributes, p.cfg.GroupAttribute)
+	}
+
+	request := ldap.NewSearchRequest(
+		p.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(p.timeout.Seconds()), false,
+		strings.ReplaceAll(p.cfg.UserFilter, "{username}", ldap.EscapeFilter(username)),
+		attributes,
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 搜索用户失败: %v", ErrLDAPUnavailable, err)
+	}
+
+	// 用户不存在或匹配到多个条目都按凭证无效处理，不暴露账号是否存在
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+// searchGroups 搜索包含用户的组，返回组DN
+func (p *LDAPProvider) searchGroups(conn LDAPConn, userDN, username string) ([]string, error) {
+	filter := strings.NewReplacer(
+		"{dn}", ldap.EscapeFilter(userDN),
+		"{username}", ldap.EscapeFilter(username),
+	).Replace(p.cfg.GroupFilter)
+
+	request := ldap.NewSearchRequest(
+		p.cfg.GroupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(p.timeout.Seconds()), false,
+		filter,
+		[]string{"dn"},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 搜索用户组失败: %v", ErrLDAPUnavailable, err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+// dialLDAP 连接目录服务，ldaps://直接建立TLS连接
+func dialLDAP(url string, tlsConfig *tls.Config, timeout time.Duration) (LDAPConn, error) {
+	conn, err := ldap.DialURL(url,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	return conn, nil
+}
+
+// ldapURLHost 从LDAP URL中提取主机名，用于TLS证书校验
+func ldapURLHost(url string) string {
+	rest := url
+	if i := strings.Index(rest, "://"); i >= 0 {
+		rest = rest[i+3:]
+	}
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		rest = rest[:i]
+	}
+	host, _, err := net.SplitHostPort(rest)
+	if err != nil {
+		return rest
+	}
+	return host
+}
+
+// matchGroup 判断组是否与映射配置匹配，支持完整DN或CN，不区分大小写
+func matchGroup(group, pattern string) bool {
+	if strings.EqualFold(group, pattern) {
+		return true
+	}
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 {
+		return false
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") && strings.EqualFold(attr.Value, pattern) {
+			return true
+		}
+	}
+	return false
+}
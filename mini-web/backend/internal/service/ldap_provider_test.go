@@ -0,0 +1,510 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/config"
+	"gitee.com/await29/mini-web/internal/model"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	testLDAPBaseDN      = "dc=example,dc=com"
+	testLDAPServiceDN   = "cn=svc,ou=services,dc=example,dc=com"
+	testLDAPServicePass = "svc-secret"
+)
+
+// stubDirectory 进程内的LDAP目录桩：保存条目和密码，按真实的过滤器语义执行搜索
+type stubDirectory struct {
+	mutex       sync.Mutex
+	entries     []*ldap.Entry
+	passwords   map[string]string // DN -> 密码
+	dialErr     error
+	startTLSErr error
+
+	dials     int
+	startTLS  []*tls.Config
+	binds     []string
+	searches  []*ldap.SearchRequest
+	openConns int
+}
+
+func newStubDirectory() *stubDirectory {
+	return &stubDirectory{
+		passwords: map[string]string{testLDAPServiceDN: testLDAPServicePass},
+	}
+}
+
+// addUser 添加用户条目，memberOf为所属组DN
+func (d *stubDirectory) addUser(uid, password string, memberOf ...string) string {
+	dn := "uid=" + uid + ",ou=people," + testLDAPBaseDN
+	attributes := map[string][]string{
+		"objectClass": {"person"},
+		"uid":         {uid},
+		"mail":        {uid + "@example.com"},
+		"displayName": {strings.ToUpper(uid)},
+	}
+	if len(memberOf) > 0 {
+		attributes["memberOf"] = memberOf
+	}
+	d.entries = append(d.entries, ldap.NewEntry(dn, attributes))
+	d.passwords[dn] = password
+	return dn
+}
+
+// addGroup 添加groupOfNames组条目
+func (d *stubDirectory) addGroup(cn string, members ...string) string {
+	dn := "cn=" + cn + ",ou=groups," + testLDAPBaseDN
+	d.entries = append(d.entries, ldap.NewEntry(dn, map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {cn},
+		"member":      members,
+	}))
+	return dn
+}
+
+// dial 实现LDAPDialer
+func (d *stubDirectory) dial(url string, tlsConfig *tls.Config, timeout time.Duration) (LDAPConn, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.dials++
+	if d.dialErr != nil {
+		return nil, d.dialErr
+	}
+	d.openConns++
+	return &stubLDAPConn{dir: d}, nil
+}
+
+// stubLDAPConn 目录桩上的一个连接
+type stubLDAPConn struct {
+	dir     *stubDirectory
+	boundDN string
+	closed  bool
+}
+
+func (c *stubLDAPConn) Bind(username, password string) error {
+	c.dir.mutex.Lock()
+	defer c.dir.mutex.Unlock()
+
+	c.dir.binds = append(c.dir.binds, username)
+	expected, ok := c.dir.passwords[username]
+	if !ok || password == "" || expected != password {
+		c.boundDN = ""
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	c.boundDN = username
+	return nil
+}
+
+func (c *stubLDAPConn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	c.dir.mutex.Lock()
+	defer c.dir.mutex.Unlock()
+
+	c.dir.searches = append(c.dir.searches, request)
+	if c.boundDN != testLDAPServiceDN {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("search requires service account"))
+	}
+
+	filter, err := ldap.CompileFilter(request.Filter)
+	if err != nil {
+		return nil, ldap.NewError(ldap.LDAPResultFilterError, err)
+	}
+
+	result := &ldap.SearchResult{}
+	for _, entry := range c.dir.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), ","+strings.ToLower(request.BaseDN)) {
+			continue
+		}
+		if matchStubFilter(filter, entry) {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	if request.SizeLimit > 0 && len(result.Entries) > request.SizeLimit {
+		result.Entries = result.Entries[:request.SizeLimit]
+	}
+	return result, nil
+}
+
+func (c *stubLDAPConn) StartTLS(config *tls.Config) error {
+	c.dir.mutex.Lock()
+	defer c.dir.mutex.Unlock()
+
+	c.dir.startTLS = append(c.dir.startTLS, config)
+	return c.dir.startTLSErr
+}
+
+func (c *stubLDAPConn) Close() error {
+	c.dir.mutex.Lock()
+	defer c.dir.mutex.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		c.dir.openConns--
+	}
+	return nil
+}
+
+// matchStubFilter 在条目上执行已编译的过滤器，支持and、or、not、等值和存在性匹配
+func matchStubFilter(filter *ber.Packet, entry *ldap.Entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchStubFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchStubFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchStubFilter(filter.Children[0], entry)
+	case ldap.FilterEqualityMatch:
+		attribute, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+		for _, v := range entry.GetAttributeValues(attribute) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(entry.GetAttributeValues(filter.Data.String())) > 0
+	}
+	return false
+}
+
+// testLDAPConfig 测试使用的LDAP配置
+func testLDAPConfig() config.LDAPConfig {
+	return config.LDAPConfig{
+		Enabled:        true,
+		URL:            "ldap://ldap.example.com:389",
+		BindDN:         testLDAPServiceDN,
+		BindPassword:   testLDAPServicePass,
+		BaseDN:         testLDAPBaseDN,
+		UserFilter:     "(&(objectClass=person)(uid={username}))",
+		EmailAttribute: "mail",
+		NameAttribute:  "displayName",
+		GroupAttribute: "memberOf",
+		GroupFilter:    "(member={dn})",
+		DefaultRole:    model.RoleUser,
+		TimeoutSecond:  5,
+	}
+}
+
+func newTestLDAPProvider(t *testing.T, cfg config.LDAPConfig, dir *stubDirectory) *LDAPProvider {
+	t.Helper()
+	provider, err := NewLDAPProviderWithDialer(cfg, dir.dial)
+	if err != nil {
+		t.Fatalf("创建LDAP认证提供者失败: %v", err)
+	}
+	return provider
+}
+
+func TestLDAPAuthenticateWithMemberOf(t *testing.T) {
+	dir := newStubDirectory()
+	opsDN := "cn=ops,ou=groups," + testLDAPBaseDN
+	userDN := dir.addUser("alice", "alice-pass", opsDN)
+	provider := newTestLDAPProvider(t, testLDAPConfig(), dir)
+
+	identity, err := provider.Authenticate("alice", "alice-pass")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	if identity.Source != "ldap" || identity.Subject != userDN || identity.Username != "alice" {
+		t.Errorf("身份不正确: %+v", identity)
+	}
+	if identity.Email != "alice@example.com" || identity.Name != "ALICE" {
+		t.Errorf("属性映射不正确: email=%q, name=%q", identity.Email, identity.Name)
+	}
+	if len(identity.Groups) != 1 || identity.Groups[0] != opsDN {
+		t.Errorf("Groups = %v, want [%s]", identity.Groups, opsDN)
+	}
+
+	// 先以服务账号绑定搜索，再以用户DN绑定验证密码
+	if len(dir.binds) != 2 || dir.binds[0] != testLDAPServiceDN || dir.binds[1] != userDN {
+		t.Errorf("绑定顺序 = %v", dir.binds)
+	}
+	if len(dir.startTLS) != 0 {
+		t.Error("未配置StartTLS时不应升级连接")
+	}
+	if dir.openConns != 0 {
+		t.Errorf("认证结束后仍有%d个连接未关闭", dir.openConns)
+	}
+}
+
+func TestLDAPAuthenticateWithGroupSearch(t *testing.T) {
+	dir := newStubDirectory()
+	userDN := dir.addUser("bob", "bob-pass")
+	devDN := dir.addGroup("dev", userDN)
+	dir.addGroup("finance", "uid=carol,ou=people,"+testLDAPBaseDN)
+
+	cfg := testLDAPConfig()
+	cfg.GroupAttribute = ""
+	cfg.GroupBaseDN = "ou=groups," + testLDAPBaseDN
+	provider := newTestLDAPProvider(t, cfg, dir)
+
+	identity, err := provider.Authenticate("bob", "bob-pass")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if len(identity.Groups) != 1 || identity.Groups[0] != devDN {
+		t.Errorf("Groups = %v, want [%s]", identity.Groups, devDN)
+	}
+
+	// 用户绑定后需重新以服务账号绑定才能搜索组
+	if got := dir.binds[len(dir.binds)-1]; got != testLDAPServiceDN {
+		t.Errorf("搜索组前最后一次绑定 = %s, want %s", got, testLDAPServiceDN)
+	}
+}
+
+func TestLDAPAuthenticateStartTLS(t *testing.T) {
+	dir := newStubDirectory()
+	dir.addUser("alice", "alice-pass")
+
+	cfg := testLDAPConfig()
+	cfg.StartTLS = true
+	provider := newTestLDAPProvider(t, cfg, dir)
+
+	if _, err := provider.Authenticate("alice", "alice-pass"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if len(dir.startTLS) != 1 {
+		t.Fatalf("StartTLS调用次数 = %d, want 1", len(dir.startTLS))
+	}
+	if got := dir.startTLS[0].ServerName; got != "ldap.example.com" {
+		t.Errorf("TLS ServerName = %q, want ldap.example.com", got)
+	}
+
+	// ldaps://连接本身已是TLS，不再StartTLS
+	cfg.URL = "ldaps://ldap.example.com:636"
+	provider = newTestLDAPProvider(t, cfg, dir)
+	if _, err := provider.Authenticate("alice", "alice-pass"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if len(dir.startTLS) != 1 {
+		t.Errorf("ldaps://连接不应调用StartTLS")
+	}
+}
+
+func TestLDAPAuthenticateFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(dir *stubDirectory, cfg *config.LDAPConfig)
+		username string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "密码错误",
+			username: "alice",
+			password: "wrong",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:     "用户不存在",
+			username: "nobody",
+			password: "alice-pass",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:     "空密码不做匿名绑定",
+			username: "alice",
+			password: "",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:     "过滤器注入被转义",
+			username: "*",
+			password: "alice-pass",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name: "匹配到多个条目",
+			setup: func(dir *stubDirectory, cfg *config.LDAPConfig) {
+				dir.addUser("dup", "dup-pass")
+				dir.entries = append(dir.entries, ldap.NewEntry("uid=dup,ou=contractors,"+testLDAPBaseDN, map[string][]string{
+					"objectClass": {"person"},
+					"uid":         {"dup"},
+				}))
+			},
+			username: "dup",
+			password: "dup-pass",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name: "服务账号密码错误",
+			setup: func(dir *stubDirectory, cfg *config.LDAPConfig) {
+				cfg.BindPassword = "stale"
+			},
+			username: "alice",
+			password: "alice-pass",
+			wantErr:  ErrLDAPUnavailable,
+		},
+		{
+			name: "目录服务无法连接",
+			setup: func(dir *stubDirectory, cfg *config.LDAPConfig) {
+				dir.dialErr = errors.New("connection refused")
+			},
+			username: "alice",
+			password: "alice-pass",
+			wantErr:  ErrLDAPUnavailable,
+		},
+		{
+			name: "StartTLS失败",
+			setup: func(dir *stubDirectory, cfg *config.LDAPConfig) {
+				cfg.StartTLS = true
+				dir.startTLSErr = errors.New("tls: handshake failure")
+			},
+			username: "alice",
+			password: "alice-pass",
+			wantErr:  ErrLDAPUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newStubDirectory()
+			dir.addUser("alice", "alice-pass")
+			cfg := testLDAPConfig()
+			if tt.setup != nil {
+				tt.setup(dir, &cfg)
+			}
+			provider := newTestLDAPProvider(t, cfg, dir)
+
+			identity, err := provider.Authenticate(tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() = %+v, %v, want error %v", identity, err, tt.wantErr)
+			}
+			if dir.openConns != 0 {
+				t.Errorf("失败后仍有%d个连接未关闭", dir.openConns)
+			}
+		})
+	}
+}
+
+func TestLDAPEmptyPasswordSkipsDirectory(t *testing.T) {
+	dir := newStubDirectory()
+	provider := newTestLDAPProvider(t, testLDAPConfig(), dir)
+
+	if _, err := provider.Authenticate("alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want ErrInvalidCredentials", err)
+	}
+	if dir.dials != 0 {
+		t.Error("空密码不应连接目录服务")
+	}
+}
+
+func TestMatchGroup(t *testing.T) {
+	tests := []struct {
+		group, pattern string
+		want           bool
+	}{
+		{"cn=Admins,ou=groups,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com", true},
+		{"cn=Admins,ou=groups,dc=example,dc=com", "admins", true},
+		{"cn=admins-readonly,ou=groups,dc=example,dc=com", "admins", false},
+		{"ou=admins,dc=example,dc=com", "admins", false},
+		{"admins", "ADMINS", true},
+	}
+	for _, tt := range tests {
+		if got := matchGroup(tt.group, tt.pattern); got != tt.want {
+			t.Errorf("matchGroup(%q, %q) = %v, want %v", tt.group, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestLDAPLoginProvisionsUser(t *testing.T) {
+	s := newTestServices(t)
+
+	team := &model.Team{Name: uniqueName("ldap-team-")}
+	if err := s.teams.Create(team); err != nil {
+		t.Fatalf("创建团队失败: %v", err)
+	}
+
+	username := uniqueName("ldapuser")
+	adminsDN := "cn=mw-admins,ou=groups," + testLDAPBaseDN
+	dir := newStubDirectory()
+	dir.addUser(username, "dir-pass", adminsDN)
+
+	cfg := testLDAPConfig()
+	cfg.GroupRoleMap = []config.GroupMapping{{Group: "mw-admins", Target: model.RoleAdmin}}
+	cfg.GroupTeamMap = []config.GroupMapping{{Group: adminsDN, Target: team.Name}}
+	s.auth.EnableLDAP(newTestLDAPProvider(t, cfg, dir), s.provisioner)
+
+	response, err := s.auth.Login(username, "dir-pass", "test-agent", "192.0.2.10")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if response.Token == "" || response.RefreshToken == "" {
+		t.Error("LDAP登录应签发普通的mini-web令牌")
+	}
+
+	user, err := s.users.GetByUsername(username)
+	if err != nil || user == nil {
+		t.Fatalf("LDAP用户未被创建: %v", err)
+	}
+	if user.AuthSource != model.AuthSourceLDAP || user.Role != model.RoleAdmin {
+		t.Errorf("用户来源=%s, 角色=%s, want ldap/admin", user.AuthSource, user.Role)
+	}
+	if user.Email != username+"@example.com" {
+		t.Errorf("Email = %q", user.Email)
+	}
+	if member, _ := s.teams.GetMember(team.ID, user.ID); member == nil {
+		t.Error("按组映射应加入团队")
+	}
+
+	// 目录中移出组后再次登录，角色回落到默认角色并退出团队
+	dir.entries[0] = ldap.NewEntry(dir.entries[0].DN, map[string][]string{
+		"objectClass": {"person"},
+		"uid":         {username},
+	})
+	if _, err := s.auth.Login(username, "dir-pass", "test-agent", "192.0.2.10"); err != nil {
+		t.Fatalf("第二次Login() error = %v", err)
+	}
+	user, _ = s.users.GetByUsername(username)
+	if user.Role != model.RoleUser {
+		t.Errorf("移出组后角色 = %s, want %s", user.Role, model.RoleUser)
+	}
+	if member, _ := s.teams.GetMember(team.ID, user.ID); member != nil {
+		t.Error("移出组后应退出映射的团队")
+	}
+
+	// 目录密码错误时不会回退到本地随机密码
+	if _, err := s.auth.Login(username, "wrong", "test-agent", "192.0.2.10"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("错误密码 Login() error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLDAPLoginKeepsLocalAccounts(t *testing.T) {
+	s := newTestServices(t)
+
+	username := uniqueName("localonly")
+	s.createLocalUser(t, username, username+"@local.test", "Local-Pass-123")
+
+	dir := newStubDirectory()
+	dir.addUser(username, "dir-pass")
+	s.auth.EnableLDAP(newTestLDAPProvider(t, testLDAPConfig(), dir), s.provisioner)
+
+	// 同名的本地账号仍使用本地密码，目录密码不能接管本地账号
+	if _, err := s.auth.Login(username, "dir-pass", "test-agent", "192.0.2.11"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("目录密码 Login() error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.auth.Login(username, "Local-Pass-123", "test-agent", "192.0.2.11"); err != nil {
+		t.Errorf("本地密码 Login() error = %v", err)
+	}
+	if dir.dials != 0 {
+		t.Error("本地账号登录不应访问目录服务")
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// ErrAccountLocked 连续登录失败次数过多，账号被临时锁定
+var ErrAccountLocked = errors.New("登录失败次数过多，账号已被临时锁定")
+
+// 未配置时的默认值
+const (
+	defaultLoginAttempts       = 5
+	defaultLoginLockoutMinutes = 15
+)
+
+// LoginGuard 登录保护：连续失败达到login_attempts次后锁定账号，
+// 并识别从未使用过的登录IP，两者都会发布通知事件
+type LoginGuard struct {
+	repo       model.LoginSecurityRepository
+	configRepo model.SystemConfigRepository
+}
+
+// NewLoginGuard 创建登录保护服务
+func NewLoginGuard(repo model.LoginSecurityRepository, configRepo model.SystemConfigRepository) *LoginGuard {
+	return &LoginGuard{repo: repo, configRepo: configRepo}
+}
+
+// CheckLocked 账号处于锁定期时返回ErrAccountLocked
+func (g *LoginGuard) CheckLocked(user *model.User) error {
+	lockedUntil, err := g.repo.GetLockedUntil(user.ID)
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil && time.Now().Before(*lockedUntil) {
+		return fmt.Errorf("%w，请在%s后重试", ErrAccountLocked, lockedUntil.Format("15:04:05"))
+	}
+	return nil
+}
+
+// RecordFailure 记录一次密码错误，达到上限时锁定账号并发布通知
+func (g *LoginGuard) RecordFailure(user *model.User, ipAddress string) {
+	maxAttempts, lockoutMinutes := g.settings()
+	if maxAttempts <= 0 {
+		return
+	}
+
+	attempts, err := g.repo.RecordFailure(user.ID)
+	if err != nil {
+		log.Printf("记录登录失败次数失败: %v", err)
+		return
+	}
+	if attempts < maxAttempts {
+		return
+	}
+
+	until := time.Now().Add(time.Duration(lockoutMinutes) * time.Minute)
+	if err := g.repo.Lock(user.ID, until); err != nil {
+		log.Printf("锁定账号失败: %v", err)
+		return
+	}
+	log.Printf("账号因连续%d次登录失败被锁定: 用户ID=%d, IP=%s, 解锁时间=%s", attempts, user.ID, ipAddress, until.Format(time.RFC3339))
+
+	GetNotificationService().Publish(&model.NotificationEvent{
+		Type:     model.EventAccountLocked,
+		Severity: model.SeverityWarning,
+		Title:    "账号已被锁定",
+		Message:  fmt.Sprintf("账号%s连续%d次登录失败，已被锁定%d分钟", user.Username, attempts, lockoutMinutes),
+		UserID:   user.ID,
+		Data: map[string]string{
+			"username":     user.Username,
+			"ip_address":   ipAddress,
+			"attempts":     strconv.Itoa(attempts),
+			"locked_until": until.Format("2006-01-02 15:04:05"),
+		},
+	})
+}
+
+// RecordSuccess 登录成功后清零失败次数并记录登录IP，首次出现的IP发布通知。
+// 用户的第一次登录不视为新IP
+func (g *LoginGuard) RecordSuccess(user *model.User, ipAddress, userAgent string) {
+	if err := g.repo.Reset(user.ID); err != nil {
+		log.Printf("清零登录失败次数失败: %v", err)
+	}
+	if ipAddress == "" {
+		return
+	}
+
+	isNew, known, err := g.repo.RecordLoginIP(user.ID, ipAddress)
+	if err != nil {
+		log.Printf("记录登录IP失败: %v", err)
+		return
+	}
+	if !isNew || known == 0 {
+		return
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	GetNotificationService().Publish(&model.NotificationEvent{
+		Type:     model.EventLoginNewIP,
+		Severity: model.SeverityInfo,
+		Title:    "新IP登录",
+		Message:  fmt.Sprintf("账号%s从新的IP地址%s登录", user.Username, ipAddress),
+		UserID:   user.ID,
+		Data: map[string]string{
+			"username":   user.Username,
+			"login_ip":   ipAddress,
+			"login_time": now,
+			"user_agent": userAgent,
+		},
+	})
+}
+
+// Unlock 管理员解除账号锁定
+func (g *LoginGuard) Unlock(userID uint) error {
+	return g.repo.Reset(userID)
+}
+
+// DeleteUser 删除用户的登录保护记录
+func (g *LoginGuard) DeleteUser(userID uint) error {
+	return g.repo.DeleteByUserID(userID)
+}
+
+// settings 读取最大失败次数和锁定时长
+func (g *LoginGuard) settings() (int, int) {
+	maxAttempts, lockoutMinutes := defaultLoginAttempts, defaultLoginLockoutMinutes
+
+	configs, err := g.configRepo.GetByCategory("security")
+	if err != nil {
+		log.Printf("读取登录保护配置失败: %v", err)
+		return maxAttempts, lockoutMinutes
+	}
+	for _, c := range configs {
+		value, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || value < 0 {
+			continue
+		}
+		switch c.Key {
+		case "login_attempts":
+			maxAttempts = value
+		case "login_lockout_minutes":
+			if value > 0 {
+				lockoutMinutes = value
+			}
+		}
+	}
+	return maxAttempts, lockoutMinutes
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// LZ4块格式（https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md）的最小实现，
+// 用于二进制协议的LZ4压缩。只处理单个块，不包含帧头和校验
+
+// ErrLZ4Corrupted LZ4数据损坏
+var ErrLZ4Corrupted = errors.New("LZ4数据损坏")
+
+const (
+	lz4MinMatch     = 4
+	lz4HashLog      = 16
+	lz4LastLiterals = 5  // 块末尾至少保留5个字节的字面量
+	lz4MFLimit      = 12 // 最后一个匹配必须在块末尾12字节之前开始
+	lz4MaxOffset    = 65535
+)
+
+// lz4CompressBound 压缩结果的最大长度
+func lz4CompressBound(n int) int {
+	return n + n/255 + 16
+}
+
+// lz4CompressBlock 使用贪心哈希匹配压缩一个块
+func lz4CompressBlock(src []byte) []byte {
+	dst := make([]byte, 0, lz4CompressBound(len(src)))
+	if len(src) <= lz4MFLimit {
+		return lz4AppendSequence(dst, src, 0, 0)
+	}
+
+	var table [1 << lz4HashLog]int32 // 位置+1，0表示空
+	anchor := 0
+	matchLimit := len(src) - lz4LastLiterals
+	for i := 0; i <= len(src)-lz4MFLimit; {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		hash := (seq * 2654435761) >> (32 - lz4HashLog)
+		ref := int(table[hash]) - 1
+		table[hash] = int32(i + 1)
+		if ref < 0 || i-ref > lz4MaxOffset || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i++
+			continue
+		}
+
+		length := lz4MinMatch
+		for i+length < matchLimit && src[ref+length] == src[i+length] {
+			length++
+		}
+		dst = lz4AppendSequence(dst, src[anchor:i], i-ref, length)
+		i += length
+		anchor = i
+	}
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence 追加一个序列：字面量和匹配，matchLength为0表示最后一个只有字面量的序列
+func lz4AppendSequence(dst, literals []byte, offset, matchLength int) []byte {
+	token := byte(0)
+	if len(literals) >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(len(literals)) << 4
+	}
+	if matchLength > 0 {
+		if matchLength-lz4MinMatch >= 15 {
+			token |= 15
+		} else {
+			token |= byte(matchLength - lz4MinMatch)
+		}
+	}
+
+	dst = append(dst, token)
+	if len(literals) >= 15 {
+		dst = lz4AppendLength(dst, len(literals)-15)
+	}
+	dst = append(dst, literals...)
+	if matchLength > 0 {
+		dst = append(dst, byte(offset), byte(offset>>8))
+		if matchLength-lz4MinMatch >= 15 {
+			dst = lz4AppendLength(dst, matchLength-lz4MinMatch-15)
+		}
+	}
+	return dst
+}
+
+// lz4AppendLength 追加扩展长度：若干个255和一个余数
+func lz4AppendLength(dst []byte, n int) []byte {
+	for n >= 255 {
+		dst = append(dst, 255)
+		n -= 255
+	}
+	return append(dst, byte(n))
+}
+
+// lz4DecompressBlock 解压一个块，size为解压后的长度
+func lz4DecompressBlock(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		literalLength := int(token >> 4)
+		if literalLength == 15 {
+			n, next, err := lz4ReadLength(src, i)
+			if err != nil {
+				return nil, err
+			}
+			literalLength += n
+			i = next
+		}
+		if literalLength > len(src)-i || literalLength > size-len(dst) {
+			return nil, ErrLZ4Corrupted
+		}
+		dst = append(dst, src[i:i+literalLength]...)
+		i += literalLength
+		if i == len(src) {
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, ErrLZ4Corrupted
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, ErrLZ4Corrupted
+		}
+
+		matchLength := int(token & 15)
+		if matchLength == 15 {
+			n, next, err := lz4ReadLength(src, i)
+			if err != nil {
+				return nil, err
+			}
+			matchLength += n
+			i = next
+		}
+		matchLength += lz4MinMatch
+		if matchLength > size-len(dst) {
+			return nil, ErrLZ4Corrupted
+		}
+
+		// 匹配可以与输出重叠，逐字节复制
+		start := len(dst) - offset
+		for k := 0; k < matchLength; k++ {
+			dst = append(dst, dst[start+k])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, ErrLZ4Corrupted
+	}
+	return dst, nil
+}
+
+// lz4ReadLength 读取扩展长度，返回长度和下一个读取位置
+func lz4ReadLength(src []byte, i int) (int, int, error) {
+	n := 0
+	for {
+		if i >= len(src) {
+			return 0, 0, ErrLZ4Corrupted
+		}
+		b := src[i]
+		i++
+		n += int(b)
+		if b != 255 {
+			return n, i, nil
+		}
+	}
+}
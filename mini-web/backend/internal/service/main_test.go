@@ -0,0 +1,133 @@
+package service
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+// testDataDir 测试进程的数据目录，数据库和密钥文件都写在这里
+var testDataDir string
+
+// TestMain 在临时目录中初始化SQLite数据库，测试使用真实的仓库实现
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	dir, err := os.MkdirTemp("", "mini-web-service-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建临时目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "切换工作目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	testDataDir = filepath.Join(dir, "data")
+
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	if err := sqlite.InitDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化测试数据库失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	sqlite.CloseDB()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testServices 认证相关测试共用的服务
+type testServices struct {
+	users       model.UserRepository
+	configs     model.SystemConfigRepository
+	teams       model.TeamRepository
+	roles       *RoleService
+	passwords   *PasswordService
+	guard       *LoginGuard
+	auth        *AuthService
+	provisioner *UserProvisioner
+}
+
+// newTestServices 基于测试数据库创建认证相关服务
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+
+	keySet, err := InitJWTKeySet("EdDSA", "", filepath.Join(testDataDir, "jwt_test.key"), nil)
+	if err != nil {
+		t.Fatalf("初始化JWT密钥失败: %v", err)
+	}
+
+	s := &testServices{
+		users:   sqlite.NewUserRepository(sqlite.DB),
+		configs: sqlite.NewSystemConfigRepository(sqlite.DB),
+		teams:   sqlite.NewTeamRepository(sqlite.DB),
+		roles:   NewRoleService(sqlite.NewRoleRepository(sqlite.DB)),
+	}
+	s.passwords = NewPasswordService(s.configs, s.users, sqlite.NewPasswordHistoryRepository(sqlite.DB))
+	s.guard = NewLoginGuard(sqlite.NewLoginSecurityRepository(sqlite.DB), s.configs)
+	denylist := InitTokenDenylist(sqlite.NewRevokedTokenRepository(sqlite.DB))
+	s.auth = NewAuthService(s.users, s.roles, sqlite.NewRefreshTokenRepository(sqlite.DB), denylist, keySet,
+		s.passwords, s.guard, 15*time.Minute, time.Hour)
+	s.provisioner = NewUserProvisioner(s.users, s.teams, s.roles)
+	return s
+}
+
+// setConfig 修改一项系统配置，测试结束后恢复原值
+func (s *testServices) setConfig(t *testing.T, key, value string) {
+	t.Helper()
+
+	existing, err := s.configs.GetByKey(key)
+	if err != nil {
+		t.Fatalf("读取系统配置%s失败: %v", key, err)
+	}
+	if existing == nil {
+		t.Fatalf("系统配置%s不存在", key)
+	}
+
+	original := existing.Value
+	existing.Value = value
+	if err := s.configs.Update(existing); err != nil {
+		t.Fatalf("修改系统配置%s失败: %v", key, err)
+	}
+	t.Cleanup(func() {
+		existing.Value = original
+		s.configs.Update(existing)
+	})
+}
+
+var testNameSeq atomic.Int64
+
+// uniqueName 生成测试内唯一的用户名，测试共享同一个数据库
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, testNameSeq.Add(1))
+}
+
+// createLocalUser 创建一个本地账号
+func (s *testServices) createLocalUser(t *testing.T, username, email, password string) *model.User {
+	t.Helper()
+
+	user := &model.User{
+		Username:   username,
+		Email:      email,
+		Password:   password,
+		Nickname:   username,
+		Role:       model.RoleUser,
+		Status:     "active",
+		AuthSource: model.AuthSourceLocal,
+	}
+	if err := s.users.Create(user); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
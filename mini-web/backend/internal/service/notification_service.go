@@ -0,0 +1,710 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+var (
+	// ErrNotificationChannelNotFound 通知渠道不存在
+	ErrNotificationChannelNotFound = errors.New("通知渠道不存在")
+
+	// ErrInvalidNotificationChannel 通知渠道配置无效
+	ErrInvalidNotificationChannel = errors.New("通知渠道配置无效")
+
+	// errPrivateWebhookAddress Webhook地址指向内网，默认禁止
+	errPrivateWebhookAddress = errors.New("不允许向内网地址发送Webhook")
+)
+
+const (
+	notificationEventBuffer       = 256
+	notificationDeliveryInterval  = 30 * time.Second
+	notificationDeliveryBatch     = 50
+	notificationMaxAttempts       = 6
+	notificationRetryBaseDelay    = 30 * time.Second
+	notificationRetryMaxDelay     = time.Hour
+	notificationDeliveryRetention = 30 * 24 * time.Hour
+	notificationRequestTimeout    = 10 * time.Second
+	notificationSecretBytes       = 24
+)
+
+// notificationChannelTypes 支持的渠道类型
+var notificationChannelTypes = map[string]bool{
+	model.NotificationChannelEmail:    true,
+	model.NotificationChannelWebhook:  true,
+	model.NotificationChannelSlack:    true,
+	model.NotificationChannelDingTalk: true,
+}
+
+// NotificationService 通知服务：接收系统内部事件，按订阅关系分发到邮件或Webhook类渠道。
+// 邮件走邮件发送队列，Webhook类渠道写入投递记录，由后台任务发送并按指数退避重试
+type NotificationService struct {
+	channelRepo  model.NotificationChannelRepository
+	deliveryRepo model.NotificationDeliveryRepository
+	userRepo     model.UserRepository
+	configRepo   model.SystemConfigRepository
+	emailService *EmailService
+	secretBox    *SecretBox
+	client       *http.Client
+
+	events   chan *model.NotificationEvent
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// 全局通知服务实例
+var globalNotificationService *NotificationService
+
+// InitNotificationService 初始化全局通知服务并启动事件分发和投递任务
+func InitNotificationService(channelRepo model.NotificationChannelRepository, deliveryRepo model.NotificationDeliveryRepository,
+	userRepo model.UserRepository, configRepo model.SystemConfigRepository, emailService *EmailService, secretBox *SecretBox) *NotificationService {
+	s := &NotificationService{
+		channelRepo:  channelRepo,
+		deliveryRepo: deliveryRepo,
+		userRepo:     userRepo,
+		configRepo:   configRepo,
+		emailService: emailService,
+		secretBox:    secretBox,
+		events:       make(chan *model.NotificationEvent, notificationEventBuffer),
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+	}
+	s.client = s.newWebhookClient()
+
+	go s.dispatchLoop()
+	go s.deliveryLoop()
+
+	globalNotificationService = s
+	return s
+}
+
+// GetNotificationService 获取全局通知服务，未初始化时返回nil
+func GetNotificationService() *NotificationService {
+	return globalNotificationService
+}
+
+// Publish 发布事件，不阻塞调用方；队列已满时丢弃事件
+func (s *NotificationService) Publish(event *model.NotificationEvent) {
+	if s == nil || event == nil {
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.Severity == "" {
+		event.Severity = model.SeverityInfo
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		log.Printf("通知事件队列已满，丢弃事件: %s", event.Type)
+	}
+}
+
+// Stop 停止后台任务
+func (s *NotificationService) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// dispatchLoop 逐个处理发布的事件
+func (s *NotificationService) dispatchLoop() {
+	for {
+		select {
+		case event := <-s.events:
+			s.dispatch(event)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// dispatch 将事件分发到订阅了该事件的系统渠道和相关用户的个人渠道
+func (s *NotificationService) dispatch(event *model.NotificationEvent) {
+	channels, err := s.channelRepo.GetEnabledForUser(event.UserID)
+	if err != nil {
+		log.Printf("查询通知渠道失败: %v", err)
+		return
+	}
+
+	queued := false
+	for _, channel := range channels {
+		if !channel.Subscribes(event.Type) {
+			continue
+		}
+		if channel.Type == model.NotificationChannelEmail {
+			if err := s.sendEmail(channel, event); err != nil {
+				log.Printf("发送通知邮件失败: 渠道ID=%d, 事件=%s, %v", channel.ID, event.Type, err)
+			}
+			continue
+		}
+
+		payload, err := buildNotificationPayload(channel.Type, event)
+		if err != nil {
+			log.Printf("生成通知内容失败: 渠道ID=%d, %v", channel.ID, err)
+			continue
+		}
+		delivery := &model.NotificationDelivery{ChannelID: channel.ID, EventType: event.Type, Payload: payload}
+		if err := s.deliveryRepo.Create(delivery); err != nil {
+			log.Printf("创建通知投递记录失败: 渠道ID=%d, %v", channel.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		s.notifyDelivery()
+	}
+}
+
+// sendEmail 使用邮件模板发送通知：新IP登录使用安全通知模板，其他事件使用系统通知模板
+func (s *NotificationService) sendEmail(channel *model.NotificationChannel, event *model.NotificationEvent) error {
+	vars := map[string]string{
+		"user_name":            "管理员",
+		"notification_title":   event.Title,
+		"notification_content": formatNotificationText(event),
+	}
+	if event.UserID != 0 {
+		if user, err := s.userRepo.GetByID(event.UserID); err == nil && user != nil {
+			vars["user_name"] = user.Username
+			if user.Nickname != "" {
+				vars["user_name"] = user.Nickname
+			}
+		}
+	}
+
+	templateType := model.EmailTemplateSystemNotification
+	if event.Type == model.EventLoginNewIP {
+		templateType = model.EmailTemplateSecurityNotification
+		vars["login_ip"] = event.Data["login_ip"]
+		vars["login_time"] = event.Data["login_time"]
+	}
+	return s.emailService.SendTemplate(templateType, channel.Target, vars)
+}
+
+// notifyDelivery 唤醒投递任务立即处理
+func (s *NotificationService) notifyDelivery() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliveryLoop 定期或被唤醒时投递到期的Webhook通知
+func (s *NotificationService) deliveryLoop() {
+	ticker := time.NewTicker(notificationDeliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.ProcessDeliveries()
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// ProcessDeliveries 投递一批到期的通知，失败的按指数退避安排重试
+func (s *NotificationService) ProcessDeliveries() {
+	deliveries, err := s.deliveryRepo.GetDue(time.Now(), notificationDeliveryBatch)
+	if err != nil {
+		log.Printf("查询待投递通知失败: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		channel, err := s.channelRepo.GetByID(delivery.ChannelID)
+		if err != nil {
+			log.Printf("查询通知渠道失败: %v", err)
+			continue
+		}
+		if channel == nil || !channel.IsEnabled {
+			if err := s.deliveryRepo.MarkFailed(delivery.ID, "通知渠道已删除或已停用"); err != nil {
+				log.Printf("更新通知投递状态失败: ID=%d, %v", delivery.ID, err)
+			}
+			continue
+		}
+
+		sendErr := s.post(channel, delivery)
+		if sendErr == nil {
+			err = s.deliveryRepo.MarkDelivered(delivery.ID)
+		} else {
+			attempts := delivery.Attempts + 1
+			if attempts >= notificationMaxAttempts {
+				log.Printf("通知投递失败，已放弃: ID=%d, 渠道ID=%d, 尝试%d次, %v", delivery.ID, channel.ID, attempts, sendErr)
+				err = s.deliveryRepo.MarkFailed(delivery.ID, sendErr.Error())
+			} else {
+				delay := notificationRetryDelay(attempts)
+				log.Printf("通知投递失败，%v后重试: ID=%d, 渠道ID=%d, %v", delay, delivery.ID, channel.ID, sendErr)
+				err = s.deliveryRepo.MarkRetry(delivery.ID, sendErr.Error(), time.Now().Add(delay))
+			}
+		}
+		if err != nil {
+			log.Printf("更新通知投递状态失败: ID=%d, %v", delivery.ID, err)
+		}
+	}
+}
+
+// CleanupDeliveries 清理过期的投递成功记录
+func (s *NotificationService) CleanupDeliveries() error {
+	return s.deliveryRepo.DeleteDeliveredBefore(time.Now().Add(-notificationDeliveryRetention))
+}
+
+// notificationRetryDelay 第attempts次失败后的重试等待时间
+func notificationRetryDelay(attempts int) time.Duration {
+	delay := notificationRetryBaseDelay
+	for i := 1; i < attempts && delay < notificationRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, notificationRetryMaxDelay)
+}
+
+// post 向渠道地址发送一次投递。
+// 通用Webhook附带 X-MiniWeb-Signature: sha256=HMAC(secret, timestamp + "." + body)，
+// 钉钉按其加签规则把timestamp和sign附加到URL
+func (s *NotificationService) post(channel *model.NotificationChannel, delivery *model.NotificationDelivery) error {
+	secret, err := s.secretBox.Decrypt(channel.Secret)
+	if err != nil {
+		return fmt.Errorf("解密渠道密钥失败: %w", err)
+	}
+
+	target := channel.Target
+	timestamp := time.Now()
+	if channel.Type == model.NotificationChannelDingTalk && secret != "" {
+		target, err = signDingTalkURL(target, secret, timestamp)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MiniWeb-Notification/1.0")
+	if channel.Type == model.NotificationChannelWebhook {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req.Header.Set("X-MiniWeb-Event", delivery.EventType)
+		req.Header.Set("X-MiniWeb-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+		req.Header.Set("X-MiniWeb-Timestamp", ts)
+		if secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(ts + "." + delivery.Payload))
+			req.Header.Set("X-MiniWeb-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("服务器返回状态码%d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	// 钉钉以HTTP 200返回业务错误
+	if channel.Type == model.NotificationChannelDingTalk {
+		var result struct {
+			ErrCode int    `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}
+		if err := json.Unmarshal(body, &result); err == nil && result.ErrCode != 0 {
+			return fmt.Errorf("钉钉返回错误%d: %s", result.ErrCode, result.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// signDingTalkURL 按钉钉机器人加签规则附加timestamp和sign参数
+func signDingTalkURL(target, secret string, timestamp time.Time) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("Webhook地址无效: %w", err)
+	}
+	ts := strconv.FormatInt(timestamp.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", ts)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// newWebhookClient 创建Webhook客户端：不跟随重定向，
+// 未开启notification_allow_private_webhooks时拒绝连接内网和本机地址
+func (s *NotificationService) newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: notificationRequestTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if s.allowPrivateWebhooks() {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+				return fmt.Errorf("%w: %s", errPrivateWebhookAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   notificationRequestTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// allowPrivateWebhooks 是否允许向内网地址发送Webhook
+func (s *NotificationService) allowPrivateWebhooks() bool {
+	return s.configBool("notification_allow_private_webhooks")
+}
+
+// configBool 读取布尔型系统配置
+func (s *NotificationService) configBool(key string) bool {
+	config, err := s.configRepo.GetByKey(key)
+	if err != nil || config == nil {
+		return false
+	}
+	value, _ := strconv.ParseBool(strings.TrimSpace(config.Value))
+	return value
+}
+
+// configString 读取字符串型系统配置
+func (s *NotificationService) configString(key string) string {
+	config, err := s.configRepo.GetByKey(key)
+	if err != nil || config == nil {
+		return ""
+	}
+	return strings.TrimSpace(config.Value)
+}
+
+// buildNotificationPayload 按渠道类型生成请求体
+func buildNotificationPayload(channelType string, event *model.NotificationEvent) (string, error) {
+	var payload interface{}
+	switch channelType {
+	case model.NotificationChannelSlack:
+		payload = map[string]string{
+			"text": fmt.Sprintf("*[%s] %s*\n%s", strings.ToUpper(event.Severity), event.Title, formatNotificationText(event)),
+		}
+	case model.NotificationChannelDingTalk:
+		payload = map[string]interface{}{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"title": event.Title,
+				"text":  fmt.Sprintf("### %s\n\n%s", event.Title, strings.ReplaceAll(formatNotificationText(event), "\n", "\n\n")),
+			},
+		}
+	default:
+		payload = event
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// formatNotificationText 生成事件的纯文本描述，附带按键名排序的事件数据
+func formatNotificationText(event *model.NotificationEvent) string {
+	var buf bytes.Buffer
+	buf.WriteString(event.Message)
+
+	keys := make([]string, 0, len(event.Data))
+	for key := range event.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if event.Data[key] == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n%s: %s", key, event.Data[key])
+	}
+	fmt.Fprintf(&buf, "\n时间: %s", event.OccurredAt.Format("2006-01-02 15:04:05"))
+	return buf.String()
+}
+
+// ListChannels 获取渠道列表，ownerID为0时为系统渠道
+func (s *NotificationService) ListChannels(ownerID uint) ([]*model.NotificationChannel, error) {
+	channels, err := s.channelRepo.GetByUserID(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		channel.Secret = ""
+	}
+	return channels, nil
+}
+
+// getOwnedChannel 获取属于ownerID的渠道
+func (s *NotificationService) getOwnedChannel(ownerID, id uint) (*model.NotificationChannel, error) {
+	channel, err := s.channelRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil || channel.UserID != ownerID {
+		return nil, ErrNotificationChannelNotFound
+	}
+	return channel, nil
+}
+
+// CreateChannel 创建渠道。通用Webhook未指定密钥时自动生成，密钥只在创建结果中返回一次
+func (s *NotificationService) CreateChannel(ownerID uint, req *model.NotificationChannelRequest) (*model.NotificationChannel, error) {
+	channel := &model.NotificationChannel{UserID: ownerID}
+	if err := applyNotificationChannelRequest(channel, req); err != nil {
+		return nil, err
+	}
+
+	secret := strings.TrimSpace(req.Secret)
+	if secret == "" && channel.Type == model.NotificationChannelWebhook {
+		generated, err := randomTokenString(notificationSecretBytes)
+		if err != nil {
+			return nil, fmt.Errorf("生成签名密钥失败: %w", err)
+		}
+		secret = generated
+	}
+	if err := s.setChannelSecret(channel, secret); err != nil {
+		return nil, err
+	}
+
+	if err := s.channelRepo.Create(channel); err != nil {
+		return nil, err
+	}
+	channel.Secret = secret
+	return channel, nil
+}
+
+// UpdateChannel 更新渠道，请求中密钥为空时保留原密钥
+func (s *NotificationService) UpdateChannel(ownerID, id uint, req *model.NotificationChannelRequest) (*model.NotificationChannel, error) {
+	channel, err := s.getOwnedChannel(ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyNotificationChannelRequest(channel, req); err != nil {
+		return nil, err
+	}
+	if channel.Type == model.NotificationChannelEmail || channel.Type == model.NotificationChannelSlack {
+		channel.Secret = ""
+	} else if secret := strings.TrimSpace(req.Secret); secret != "" {
+		if err := s.setChannelSecret(channel, secret); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.channelRepo.Update(channel); err != nil {
+		return nil, err
+	}
+	channel.HasSecret = channel.Secret != ""
+	channel.Secret = ""
+	return channel, nil
+}
+
+// setChannelSecret 加密保存渠道密钥，邮件和Slack渠道不使用密钥
+func (s *NotificationService) setChannelSecret(channel *model.NotificationChannel, secret string) error {
+	if secret == "" || channel.Type == model.NotificationChannelEmail || channel.Type == model.NotificationChannelSlack {
+		channel.Secret = ""
+		channel.HasSecret = false
+		return nil
+	}
+	encrypted, err := s.secretBox.Encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("加密渠道密钥失败: %w", err)
+	}
+	channel.Secret = encrypted
+	channel.HasSecret = true
+	return nil
+}
+
+// DeleteChannel 删除渠道及其投递记录
+func (s *NotificationService) DeleteChannel(ownerID, id uint) error {
+	if _, err := s.getOwnedChannel(ownerID, id); err != nil {
+		return err
+	}
+	if err := s.deliveryRepo.DeleteByChannelID(id); err != nil {
+		return err
+	}
+	return s.channelRepo.Delete(id)
+}
+
+// DeleteUserChannels 删除用户的全部个人渠道
+func (s *NotificationService) DeleteUserChannels(userID uint) error {
+	if s == nil {
+		return nil
+	}
+	return s.channelRepo.DeleteByUserID(userID)
+}
+
+// TestChannel 向渠道发送一条测试通知。Webhook类渠道同步发送并返回结果，邮件渠道加入发送队列
+func (s *NotificationService) TestChannel(ownerID, id uint) error {
+	channel, err := s.getOwnedChannel(ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	event := &model.NotificationEvent{
+		Type:       "test",
+		Severity:   model.SeverityInfo,
+		Title:      "测试通知",
+		Message:    fmt.Sprintf("这是一条来自通知渠道“%s”的测试消息", channel.Name),
+		UserID:     ownerID,
+		OccurredAt: time.Now(),
+	}
+	if channel.Type == model.NotificationChannelEmail {
+		return s.sendEmail(channel, event)
+	}
+
+	payload, err := buildNotificationPayload(channel.Type, event)
+	if err != nil {
+		return err
+	}
+	return s.post(channel, &model.NotificationDelivery{ChannelID: channel.ID, EventType: event.Type, Payload: payload})
+}
+
+// GetDeliveries 获取渠道最近的投递记录
+func (s *NotificationService) GetDeliveries(ownerID, id uint, limit int) ([]*model.NotificationDelivery, error) {
+	if _, err := s.getOwnedChannel(ownerID, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.deliveryRepo.GetByChannelID(id, limit)
+}
+
+// EventTypes 可订阅的事件类型及说明
+func (s *NotificationService) EventTypes() map[string]string {
+	return model.NotificationEventTypes
+}
+
+// NotifySessionStarted 在带有生产标签的连接上发起会话时发布通知，
+// 生产标签由notification_production_tags配置，逗号分隔
+func (s *NotificationService) NotifySessionStarted(userID uint, conn *model.Connection, clientIP string) {
+	if s == nil || conn == nil || conn.Tags == "" {
+		return
+	}
+
+	productionTags := make(map[string]bool)
+	for _, tag := range strings.Split(strings.ToLower(s.configString("notification_production_tags")), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			productionTags[tag] = true
+		}
+	}
+
+	for _, tag := range conn.GetTags() {
+		if !productionTags[strings.ToLower(tag)] {
+			continue
+		}
+		username := strconv.FormatUint(uint64(userID), 10)
+		if user, err := s.userRepo.GetByID(userID); err == nil && user != nil {
+			username = user.Username
+		}
+		s.Publish(&model.NotificationEvent{
+			Type:     model.EventProductionSession,
+			Severity: model.SeverityWarning,
+			Title:    "生产环境会话",
+			Message:  fmt.Sprintf("用户%s连接了生产环境主机%s（%s:%d）", username, conn.Name, conn.Host, conn.Port),
+			UserID:   userID,
+			Data: map[string]string{
+				"username":        username,
+				"connection_id":   strconv.FormatUint(uint64(conn.ID), 10),
+				"connection_name": conn.Name,
+				"host":            conn.Host,
+				"protocol":        conn.Protocol,
+				"client_ip":       clientIP,
+				"tags":            conn.Tags,
+			},
+		})
+		return
+	}
+}
+
+// applyNotificationChannelRequest 校验请求并写入渠道字段
+func applyNotificationChannelRequest(channel *model.NotificationChannel, req *model.NotificationChannelRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return fmt.Errorf("%w: 名称不能为空且不超过100个字符", ErrInvalidNotificationChannel)
+	}
+	if !notificationChannelTypes[req.Type] {
+		return fmt.Errorf("%w: 不支持的渠道类型%s", ErrInvalidNotificationChannel, req.Type)
+	}
+
+	target := strings.TrimSpace(req.Target)
+	if req.Type == model.NotificationChannelEmail {
+		address, err := mail.ParseAddress(target)
+		if err != nil {
+			return fmt.Errorf("%w: 邮箱地址无效", ErrInvalidNotificationChannel)
+		}
+		target = address.Address
+	} else {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: Webhook地址必须是http或https地址", ErrInvalidNotificationChannel)
+		}
+	}
+
+	events := make([]string, 0, len(req.Events))
+	seen := make(map[string]bool)
+	for _, event := range req.Events {
+		event = strings.TrimSpace(event)
+		if event == "" || seen[event] {
+			continue
+		}
+		if _, ok := model.NotificationEventTypes[event]; !ok {
+			return fmt.Errorf("%w: 未知的事件类型%s", ErrInvalidNotificationChannel, event)
+		}
+		seen[event] = true
+		events = append(events, event)
+	}
+
+	channel.Name = name
+	channel.Type = req.Type
+	channel.Target = target
+	channel.Events = strings.Join(events, ",")
+	channel.IsEnabled = req.IsEnabled
+	return nil
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/config"
+	"gitee.com/await29/mini-web/internal/model"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateTTL 登录请求从跳转到回调的最长时间
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCDisabled 未启用OIDC登录
+	ErrOIDCDisabled = errors.New("未启用单点登录")
+
+	// ErrOIDCInvalidState 回调的state无效或已过期
+	ErrOIDCInvalidState = errors.New("单点登录请求无效或已过期，请重新登录")
+
+	// ErrOIDCClaimMissing ID令牌中缺少必需的claim
+	ErrOIDCClaimMissing = errors.New("身份提供者未返回用户名")
+)
+
+// OIDCSettings OIDC登录配置，保存在system_configs的oidc分类中
+type OIDCSettings struct {
+	Enabled       bool
+	ProviderName  string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	FrontendURL   string
+	Scopes        []string
+	UsernameClaim string
+	EmailClaim    string
+	NameClaim     string
+	GroupsClaim   string
+	GroupRoleMap  []config.GroupMapping
+	DefaultRole   string
+}
+
+// oidcLoginState 一次进行中的登录请求
+type oidcLoginState struct {
+	verifier  string // PKCE code_verifier
+	nonce     string
+	expiresAt time.Time
+}
+
+// OIDCService OpenID Connect授权码+PKCE登录。
+// 登录完成后按组映射创建或同步本地用户，并签发普通的mini-web令牌。
+// 进行中的登录状态保存在内存中，多实例部署时回调需路由到发起登录的实例
+type OIDCService struct {
+	configRepo  model.SystemConfigRepository
+	auth        *AuthService
+	provisioner *UserProvisioner
+	httpClient  *http.Client
+
+	mutex          sync.Mutex
+	provider       *oidc.Provider
+	providerIssuer string
+	states         map[string]*oidcLoginState
+}
+
+// NewOIDCService 创建OIDC登录服务
+func NewOIDCService(configRepo model.SystemConfigRepository, auth *AuthService, provisioner *UserProvisioner) *OIDCService {
+	return &OIDCService{
+		configRepo:  configRepo,
+		auth:        auth,
+		provisioner: provisioner,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		states:      make(map[string]*oidcLoginState),
+	}
+}
+
+// LoadSettings 从系统配置读取OIDC配置
+func (s *OIDCService) LoadSettings() (*OIDCSettings, error) {
+	configs, err := s.configRepo.GetByCategory("oidc")
+	if err != nil {
+		return nil, fmt.Errorf("读取单点登录配置失败: %w", err)
+	}
+
+	values := make(map[string]string, len(configs))
+	for _, c := range configs {
+		values[c.Key] = strings.TrimSpace(c.Value)
+	}
+	get := func(key, defaultValue string) string {
+		if value := values[key]; value != "" {
+			return value
+		}
+		return defaultValue
+	}
+
+	enabled, _ := strconv.ParseBool(values["oidc_enabled"])
+	return &OIDCSettings{
+		Enabled:       enabled,
+		ProviderName:  get("oidc_provider_name", "SSO"),
+		Issuer:        values["oidc_issuer"],
+		ClientID:      values["oidc_client_id"],
+		ClientSecret:  values["oidc_client_secret"],
+		RedirectURL:   values["oidc_redirect_url"],
+		FrontendURL:   get("oidc_frontend_url", "/login"),
+		Scopes:        strings.Fields(get("oidc_scopes", "openid profile email")),
+		UsernameClaim: get("oidc_username_claim", "preferred_username"),
+		EmailClaim:    get("oidc_email_claim", "email"),
+		NameClaim:     get("oidc_name_claim", "name"),
+		GroupsClaim:   values["oidc_groups_claim"],
+		GroupRoleMap:  config.ParseGroupMappings(values["oidc_group_role_map"]),
+		DefaultRole:   get("oidc_default_role", model.RoleUser),
+	}, nil
+}
+
+// BeginLogin 生成state、nonce和PKCE校验码，返回身份提供者的授权地址
+func (s *OIDCService) BeginLogin(ctx context.Context) (string, error) {
+	settings, err := s.enabledSettings()
+	if err != nil {
+		return "", err
+	}
+
+	provider, err := s.getProvider(ctx, settings.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomTokenString(16)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomTokenString(16)
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	s.mutex.Lock()
+	now := time.Now()
+	for key, pending := range s.states {
+		if now.After(pending.expiresAt) {
+			delete(s.states, key)
+		}
+	}
+	s.states[state] = &oidcLoginState{verifier: verifier, nonce: nonce, expiresAt: now.Add(oidcStateTTL)}
+	s.mutex.Unlock()
+
+	return s.oauth2Config(settings, provider).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(verifier),
+	), nil
+}
+
+// CompleteLogin 处理授权回调：用授权码换取令牌、验证ID令牌、同步用户并签发mini-web令牌
+func (s *OIDCService) CompleteLogin(ctx context.Context, state, code, userAgent, ipAddress string) (*model.UserLoginResponse, error) {
+	settings, err := s.enabledSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	// state只能使用一次
+	s.mutex.Lock()
+	pending, ok := s.states[state]
+	delete(s.states, state)
+	s.mutex.Unlock()
+	if !ok || time.Now().After(pending.expiresAt) {
+		return nil, ErrOIDCInvalidState
+	}
+
+	provider, err := s.getProvider(ctx, settings.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, s.httpClient)
+	token, err := s.oauth2Config(settings, provider).Exchange(ctx, code, oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		return nil, fmt.Errorf("授权码换取令牌失败: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("身份提供者未返回ID令牌")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: settings.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("验证ID令牌失败: %w", err)
+	}
+	if idToken.Nonce != pending.nonce {
+		return nil, errors.New("ID令牌nonce不匹配")
+	}
+
+	claims := make(map[string]interface{})
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("解析ID令牌失败: %w", err)
+	}
+
+	// ID令牌缺少用户名时从UserInfo端点补充
+	if claimString(claims, settings.UsernameClaim) == "" && provider.UserInfoEndpoint() != "" {
+		userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, fmt.Errorf("获取用户信息失败: %w", err)
+		}
+		extra := make(map[string]interface{})
+		if err := userInfo.Claims(&extra); err != nil {
+			return nil, fmt.Errorf("解析用户信息失败: %w", err)
+		}
+		for key, value := range extra {
+			if _, exists := claims[key]; !exists {
+				claims[key] = value
+			}
+		}
+	}
+
+	identity := &ExternalIdentity{
+		Source:   model.AuthSourceOIDC,
+		Subject:  idToken.Subject,
+		Username: claimString(claims, settings.UsernameClaim),
+		Email:    claimString(claims, settings.EmailClaim),
+		Name:     claimString(claims, settings.NameClaim),
+		Groups:   claimStrings(claims, settings.GroupsClaim),
+	}
+	if identity.Username == "" {
+		return nil, ErrOIDCClaimMissing
+	}
+
+	user, err := s.provisioner.Provision(identity, settings.GroupRoleMap, nil, settings.DefaultRole)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != "active" {
+		return nil, errors.New("用户账号已被禁用")
+	}
+
+	pair, err := s.auth.IssueTokens(user, userAgent, ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("生成令牌时出错: %w", err)
+	}
+	s.auth.RecordLogin(user, userAgent, ipAddress)
+
+	log.Printf("单点登录成功: 用户ID=%d, 用户名=%s, subject=%s", user.ID, user.Username, identity.Subject)
+	return &model.UserLoginResponse{
+		Token:         pair.Token,
+		User:          *user,
+		Expire:        pair.Expire,
+		RefreshToken:  pair.RefreshToken,
+		RefreshExpire: pair.RefreshExpire,
+	}, nil
+}
+
+// enabledSettings 读取配置并检查是否已启用且配置完整
+func (s *OIDCService) enabledSettings() (*OIDCSettings, error) {
+	settings, err := s.LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, ErrOIDCDisabled
+	}
+	if settings.Issuer == "" || settings.ClientID == "" || settings.RedirectURL == "" {
+		return nil, errors.New("单点登录配置不完整，需要issuer、client_id和redirect_url")
+	}
+	return settings, nil
+}
+
+// getProvider 获取身份提供者元数据，issuer未变化时复用已发现的配置
+func (s *OIDCService) getProvider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	s.mutex.Lock()
+	if s.provider != nil && s.providerIssuer == issuer {
+		provider := s.provider
+		s.mutex.Unlock()
+		return provider, nil
+	}
+	s.mutex.Unlock()
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, s.httpClient), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("获取身份提供者配置失败: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.provider = provider
+	s.providerIssuer = issuer
+	s.mutex.Unlock()
+	return provider, nil
+}
+
+// oauth2Config 构建OAuth2客户端配置
+func (s *OIDCService) oauth2Config(settings *OIDCSettings, provider *oidc.Provider) *oauth2.Config {
+	scopes := settings.Scopes
+	hasOpenID := false
+	for _, scope := range scopes {
+		if scope == oidc.ScopeOpenID {
+			hasOpenID = true
+		}
+	}
+	if !hasOpenID {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+
+	return &oauth2.Config{
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
+		RedirectURL:  settings.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+}
+
+// claimString 读取字符串claim
+func claimString(claims map[string]interface{}, name string) string {
+	if name == "" {
+		return ""
+	}
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// claimStrings 读取字符串数组claim，兼容单个字符串和逗号分隔的写法
+func claimStrings(claims map[string]interface{}, name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	var values []string
+	switch v := claims[name].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "" {
+				values = append(values, str)
+			}
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
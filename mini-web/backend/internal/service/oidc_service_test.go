@@ -0,0 +1,540 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	testOIDCClientID     = "mini-web"
+	testOIDCClientSecret = "client-secret"
+	testOIDCRedirectURL  = "https://mini-web.test/api/auth/oidc/callback"
+)
+
+// mockOIDCProvider 本地运行的最小OIDC身份提供者：
+// 提供discovery、JWKS、授权、令牌和UserInfo端点，令牌端点校验PKCE
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mutex sync.Mutex
+	// 下一次授权要签发的claims，由测试设置
+	claims map[string]interface{}
+	// userInfo UserInfo端点返回的claims
+	userInfo map[string]interface{}
+	// idTokenHook 在签名前修改ID令牌claims，用于构造异常令牌
+	idTokenHook func(claims map[string]interface{})
+	// signingKey 不为nil时用它代替JWKS中的密钥签名
+	signingKey *rsa.PrivateKey
+	codes      map[string]*mockAuthorization
+	tokens     map[string]map[string]interface{}
+}
+
+// mockAuthorization 一次授权码对应的请求参数
+type mockAuthorization struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]interface{}
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成RSA密钥失败: %v", err)
+	}
+
+	p := &mockOIDCProvider{
+		key:    key,
+		codes:  make(map[string]*mockAuthorization),
+		tokens: make(map[string]map[string]interface{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+	mux.HandleFunc("/userinfo", p.handleUserInfo)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockOIDCProvider) issuer() string {
+	return p.server.URL
+}
+
+func (p *mockOIDCProvider) setClaims(claims map[string]interface{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.claims = claims
+}
+
+func (p *mockOIDCProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer(),
+		"authorization_endpoint":                p.issuer() + "/authorize",
+		"token_endpoint":                        p.issuer() + "/token",
+		"jwks_uri":                              p.issuer() + "/jwks",
+		"userinfo_endpoint":                     p.issuer() + "/userinfo",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *mockOIDCProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       &p.key.PublicKey,
+		KeyID:     "test-key",
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}}})
+}
+
+// handleAuthorize 模拟用户已在身份提供者登录并同意授权，直接重定向回客户端
+func (p *mockOIDCProvider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "authorization code flow with S256 PKCE required", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomTokenString(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.mutex.Lock()
+	p.codes[code] = &mockAuthorization{
+		clientID:    query.Get("client_id"),
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		claims:      p.claims,
+	}
+	p.mutex.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *mockOIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testOIDCClientID || clientSecret != testOIDCClientSecret {
+		writeMockJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mutex.Lock()
+	auth, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mutex.Unlock()
+	if !ok || auth.clientID != clientID || auth.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	// PKCE: S256(code_verifier)必须等于授权请求中的code_challenge
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   p.issuer(),
+		"aud":   clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": auth.nonce,
+	}
+	for key, value := range auth.claims {
+		claims[key] = value
+	}
+	p.mutex.Lock()
+	hook, signingKey := p.idTokenHook, p.signingKey
+	p.mutex.Unlock()
+	if hook != nil {
+		hook(claims)
+	}
+	if signingKey == nil {
+		signingKey = p.key
+	}
+
+	idToken, err := signMockJWT(signingKey, claims)
+	if err != nil {
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	accessToken, _ := randomTokenString(16)
+	p.mutex.Lock()
+	p.tokens[accessToken] = p.userInfo
+	p.mutex.Unlock()
+
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (p *mockOIDCProvider) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	info, ok := p.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	p.mutex.Unlock()
+	if !ok {
+		writeMockJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeMockJSON(w, http.StatusOK, info)
+}
+
+// authorize 模拟浏览器访问授权地址，返回回调中的state和code
+func (p *mockOIDCProvider) authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("访问授权地址失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("授权请求被拒绝: %s", resp.Status)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("解析回调地址失败: %v", err)
+	}
+	return location.Query().Get("state"), location.Query().Get("code")
+}
+
+func signMockJWT(key *rsa.PrivateKey, claims map[string]interface{}) (string, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test-key"))
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	object, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return object.CompactSerialize()
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// newTestOIDC 创建指向模拟身份提供者的OIDC服务
+func newTestOIDC(t *testing.T) (*testServices, *OIDCService, *mockOIDCProvider) {
+	t.Helper()
+
+	s := newTestServices(t)
+	provider := newMockOIDCProvider(t)
+	s.setConfig(t, "oidc_enabled", "true")
+	s.setConfig(t, "oidc_issuer", provider.issuer())
+	s.setConfig(t, "oidc_client_id", testOIDCClientID)
+	s.setConfig(t, "oidc_client_secret", testOIDCClientSecret)
+	s.setConfig(t, "oidc_redirect_url", testOIDCRedirectURL)
+	s.setConfig(t, "oidc_groups_claim", "groups")
+	s.setConfig(t, "oidc_group_role_map", "mw-admins=>admin")
+	return s, NewOIDCService(s.configs, s.auth, s.provisioner), provider
+}
+
+// oidcLogin 走完一次授权码流程
+func oidcLogin(t *testing.T, oidcService *OIDCService, provider *mockOIDCProvider) (*model.UserLoginResponse, error) {
+	t.Helper()
+
+	authURL, err := oidcService.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	state, code := provider.authorize(t, authURL)
+	return oidcService.CompleteLogin(context.Background(), state, code, "test-agent", "192.0.2.20")
+}
+
+func TestOIDCLoginProvisionsUser(t *testing.T) {
+	s, oidcService, provider := newTestOIDC(t)
+
+	username := uniqueName("sso")
+	provider.setClaims(map[string]interface{}{
+		"sub":                "subject-" + username,
+		"preferred_username": username,
+		"email":              username + "@idp.test",
+		"name":               "SSO User",
+		"groups":             []string{"mw-admins", "staff"},
+	})
+
+	response, err := oidcLogin(t, oidcService, provider)
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if response.Token == "" || response.RefreshToken == "" {
+		t.Error("单点登录应签发普通的mini-web令牌")
+	}
+	if _, err := s.auth.VerifyToken(response.Token); err != nil {
+		t.Errorf("签发的访问令牌无法验证: %v", err)
+	}
+
+	user, err := s.users.GetByUsername(username)
+	if err != nil || user == nil {
+		t.Fatalf("OIDC用户未被创建: %v", err)
+	}
+	if user.AuthSource != model.AuthSourceOIDC || user.Role != model.RoleAdmin {
+		t.Errorf("用户来源=%s, 角色=%s, want oidc/admin", user.AuthSource, user.Role)
+	}
+	if user.Email != username+"@idp.test" || user.Nickname != "SSO User" {
+		t.Errorf("claims映射不正确: email=%q, nickname=%q", user.Email, user.Nickname)
+	}
+
+	// 再次登录时按组同步角色
+	provider.setClaims(map[string]interface{}{
+		"sub":                "subject-" + username,
+		"preferred_username": username,
+		"groups":             []string{"staff"},
+	})
+	if _, err := oidcLogin(t, oidcService, provider); err != nil {
+		t.Fatalf("第二次CompleteLogin() error = %v", err)
+	}
+	user, _ = s.users.GetByUsername(username)
+	if user.Role != model.RoleUser {
+		t.Errorf("移出组后角色 = %s, want %s", user.Role, model.RoleUser)
+	}
+}
+
+func TestOIDCUsernameFromUserInfo(t *testing.T) {
+	s, oidcService, provider := newTestOIDC(t)
+
+	username := uniqueName("ssoinfo")
+	provider.setClaims(map[string]interface{}{"sub": "subject-" + username})
+	provider.userInfo = map[string]interface{}{
+		"sub":                "subject-" + username,
+		"preferred_username": username,
+	}
+
+	if _, err := oidcLogin(t, oidcService, provider); err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if user, _ := s.users.GetByUsername(username); user == nil {
+		t.Error("应使用UserInfo端点返回的用户名创建用户")
+	}
+}
+
+func TestOIDCAuthorizationURLUsesPKCE(t *testing.T) {
+	_, oidcService, _ := newTestOIDC(t)
+
+	authURL, err := oidcService.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("解析授权地址失败: %v", err)
+	}
+
+	query := parsed.Query()
+	for _, key := range []string{"state", "nonce", "code_challenge"} {
+		if query.Get(key) == "" {
+			t.Errorf("授权地址缺少%s参数", key)
+		}
+	}
+	if query.Get("code_challenge_method") != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+	if !strings.Contains(" "+query.Get("scope")+" ", " openid ") {
+		t.Errorf("scope = %q, 必须包含openid", query.Get("scope"))
+	}
+}
+
+func TestOIDCLoginFailures(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成RSA密钥失败: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(provider *mockOIDCProvider)
+		claims  map[string]interface{}
+		wantErr error
+	}{
+		{
+			name:   "nonce不匹配",
+			setup:  func(p *mockOIDCProvider) { p.idTokenHook = func(c map[string]interface{}) { c["nonce"] = "replayed" } },
+			claims: map[string]interface{}{"sub": "s1", "preferred_username": "nonce-user"},
+		},
+		{
+			name: "audience不是本客户端",
+			setup: func(p *mockOIDCProvider) {
+				p.idTokenHook = func(c map[string]interface{}) { c["aud"] = "another-client" }
+			},
+			claims: map[string]interface{}{"sub": "s2", "preferred_username": "aud-user"},
+		},
+		{
+			name: "issuer不匹配",
+			setup: func(p *mockOIDCProvider) {
+				p.idTokenHook = func(c map[string]interface{}) { c["iss"] = "https://evil.test" }
+			},
+			claims: map[string]interface{}{"sub": "s3", "preferred_username": "iss-user"},
+		},
+		{
+			name: "ID令牌已过期",
+			setup: func(p *mockOIDCProvider) {
+				p.idTokenHook = func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }
+			},
+			claims: map[string]interface{}{"sub": "s4", "preferred_username": "exp-user"},
+		},
+		{
+			name:   "签名密钥不在JWKS中",
+			setup:  func(p *mockOIDCProvider) { p.signingKey = otherKey },
+			claims: map[string]interface{}{"sub": "s5", "preferred_username": "sig-user"},
+		},
+		{
+			name:    "缺少用户名claim",
+			claims:  map[string]interface{}{"sub": "s6"},
+			wantErr: ErrOIDCClaimMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, oidcService, provider := newTestOIDC(t)
+			provider.userInfo = map[string]interface{}{"sub": tt.claims["sub"]}
+			if tt.setup != nil {
+				tt.setup(provider)
+			}
+			provider.setClaims(tt.claims)
+
+			response, err := oidcLogin(t, oidcService, provider)
+			if err == nil {
+				t.Fatalf("CompleteLogin() = %+v, want error", response)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("CompleteLogin() error = %v, want %v", err, tt.wantErr)
+			}
+			if username, ok := tt.claims["preferred_username"].(string); ok {
+				if user, _ := s.users.GetByUsername(username); user != nil {
+					t.Error("验证失败时不应创建用户")
+				}
+			}
+		})
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	_, oidcService, provider := newTestOIDC(t)
+	provider.setClaims(map[string]interface{}{"sub": "state-sub", "preferred_username": uniqueName("state")})
+
+	authURL, err := oidcService.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	state, code := provider.authorize(t, authURL)
+
+	if _, err := oidcService.CompleteLogin(context.Background(), "forged-state", code, "", ""); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Errorf("伪造state CompleteLogin() error = %v, want ErrOIDCInvalidState", err)
+	}
+	if _, err := oidcService.CompleteLogin(context.Background(), state, code, "", ""); err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if _, err := oidcService.CompleteLogin(context.Background(), state, code, "", ""); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Errorf("重复使用state CompleteLogin() error = %v, want ErrOIDCInvalidState", err)
+	}
+}
+
+func TestOIDCRejectsCodeBoundToOtherVerifier(t *testing.T) {
+	_, oidcService, provider := newTestOIDC(t)
+	provider.setClaims(map[string]interface{}{"sub": "pkce-sub", "preferred_username": uniqueName("pkce")})
+
+	authURL, err := oidcService.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+
+	// 攻击者截获的授权码是用另一个code_challenge申请的，换取令牌时PKCE校验失败
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+	sum := sha256.Sum256([]byte("attacker-verifier"))
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	parsed.RawQuery = query.Encode()
+
+	state, code := provider.authorize(t, parsed.String())
+	if _, err := oidcService.CompleteLogin(context.Background(), state, code, "", ""); err == nil {
+		t.Fatal("PKCE校验失败时CompleteLogin()应返回错误")
+	}
+}
+
+func TestOIDCDoesNotTakeOverLocalAccount(t *testing.T) {
+	s, oidcService, provider := newTestOIDC(t)
+
+	username := uniqueName("breakglass")
+	s.createLocalUser(t, username, username+"@local.test", "Local-Pass-123")
+	provider.setClaims(map[string]interface{}{"sub": "bg-sub", "preferred_username": username})
+
+	if _, err := oidcLogin(t, oidcService, provider); !errors.Is(err, ErrAuthSourceMismatch) {
+		t.Errorf("CompleteLogin() error = %v, want ErrAuthSourceMismatch", err)
+	}
+
+	// 启用单点登录后本地账号仍可作为应急入口登录
+	if _, err := s.auth.Login(username, "Local-Pass-123", "test-agent", "192.0.2.21"); err != nil {
+		t.Errorf("本地账号 Login() error = %v", err)
+	}
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	s, oidcService, _ := newTestOIDC(t)
+	s.setConfig(t, "oidc_enabled", "false")
+
+	if _, err := oidcService.BeginLogin(context.Background()); !errors.Is(err, ErrOIDCDisabled) {
+		t.Errorf("BeginLogin() error = %v, want ErrOIDCDisabled", err)
+	}
+	if _, err := oidcService.CompleteLogin(context.Background(), "state", "code", "", ""); !errors.Is(err, ErrOIDCDisabled) {
+		t.Errorf("CompleteLogin() error = %v, want ErrOIDCDisabled", err)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+var (
+	// ErrInvalidResetToken 找回密码令牌无效、已使用或已过期
+	ErrInvalidResetToken = errors.New("重置链接无效或已过期，请重新申请")
+
+	// ErrTooManyResetRequests 找回密码请求过于频繁
+	ErrTooManyResetRequests = errors.New("请求过于频繁，请稍后再试")
+)
+
+// PasswordResetService 自助找回密码：
+// 通过邮件发送一次性的重置链接，数据库只保存令牌哈希
+type PasswordResetService struct {
+	userRepo   model.UserRepository
+	resetRepo  model.PasswordResetRepository
+	configRepo model.SystemConfigRepository
+	passwords  *PasswordService
+	auth       *AuthService
+	email      *EmailService
+
+	ipLimiter    *RateLimiter
+	emailLimiter *RateLimiter
+}
+
+// NewPasswordResetService 创建找回密码服务
+func NewPasswordResetService(userRepo model.UserRepository, resetRepo model.PasswordResetRepository, configRepo model.SystemConfigRepository,
+	passwords *PasswordService, auth *AuthService, email *EmailService) *PasswordResetService {
+	return &PasswordResetService{
+		userRepo:     userRepo,
+		resetRepo:    resetRepo,
+		configRepo:   configRepo,
+		passwords:    passwords,
+		auth:         auth,
+		email:        email,
+		ipLimiter:    NewRateLimiter(20, time.Hour),
+		emailLimiter: NewRateLimiter(3, time.Hour),
+	}
+}
+
+// RequestReset 申请找回密码。
+// 无论邮箱是否存在都返回相同结果，邮件在后台发送，避免通过响应内容或耗时判断账号是否存在
+func (s *PasswordResetService) RequestReset(email, ipAddress string) error {
+	email = strings.TrimSpace(email)
+	if !s.ipLimiter.Allow("ip:"+ipAddress) || !s.emailLimiter.Allow("email:"+strings.ToLower(email)) {
+		return ErrTooManyResetRequests
+	}
+	if email == "" {
+		return nil
+	}
+
+	go func() {
+		if err := s.sendResetEmail(email, ipAddress); err != nil {
+			log.Printf("发送找回密码邮件失败: %v", err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword 使用找回密码令牌设置新密码，成功后吊销该用户全部登录会话
+func (s *PasswordResetService) ResetPassword(token, newPassword, ipAddress string) error {
+	if !s.ipLimiter.Allow("ip:" + ipAddress) {
+		return ErrTooManyResetRequests
+	}
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+
+	stored, err := s.resetRepo.GetByHash(hashSecretToken(token))
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.UsedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return fmt.Errorf("获取用户信息时出错: %w", err)
+	}
+	if user == nil || user.Status != "active" || user.AuthSource != model.AuthSourceLocal {
+		return ErrInvalidResetToken
+	}
+
+	// 先校验新密码，不符合策略时令牌仍可继续使用
+	if err := s.passwords.ValidateChange(user, newPassword); err != nil {
+		return err
+	}
+
+	// 原子地标记为已使用，并发请求中只有一个能成功
+	ok, err := s.resetRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.passwords.ChangePassword(user, newPassword); err != nil {
+		return err
+	}
+	if err := s.resetRepo.InvalidateByUserID(user.ID); err != nil {
+		log.Printf("作废其余找回密码令牌失败: %v", err)
+	}
+	if err := s.auth.RevokeUserTokens(user.ID); err != nil {
+		log.Printf("重置密码后吊销登录会话失败: %v", err)
+	}
+
+	log.Printf("用户通过找回密码重置了密码: 用户ID=%d, IP=%s", user.ID, ipAddress)
+	return nil
+}
+
+// CleanupExpiredTokens 清理过期的找回密码令牌
+func (s *PasswordResetService) CleanupExpiredTokens() error {
+	return s.resetRepo.DeleteExpired(time.Now())
+}
+
+// sendResetEmail 为本地账号生成令牌并发送重置邮件，邮箱不存在或非本地账号时静默忽略
+func (s *PasswordResetService) sendResetEmail(email, ipAddress string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+	if user == nil || user.Status != "active" || user.AuthSource != model.AuthSourceLocal {
+		log.Printf("找回密码: 邮箱未对应可重置的本地账号，已忽略")
+		return nil
+	}
+
+	resetURL, ttl := s.settings()
+
+	token, err := randomTokenString(32)
+	if err != nil {
+		return err
+	}
+
+	// 同一用户只保留最新的一个有效令牌
+	if err := s.resetRepo.InvalidateByUserID(user.ID); err != nil {
+		return err
+	}
+	if err := s.resetRepo.Create(&model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashSecretToken(token),
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return err
+	}
+
+	link, err := appendQueryParam(resetURL, "token", token)
+	if err != nil {
+		return fmt.Errorf("找回密码地址配置无效: %w", err)
+	}
+
+	name := user.Nickname
+	if name == "" {
+		name = user.Username
+	}
+	return s.email.SendTemplate(model.EmailTemplateResetPassword, user.Email, map[string]string{
+		"user_name":      name,
+		"username":       user.Username,
+		"reset_link":     link,
+		"expire_minutes": strconv.Itoa(int(ttl.Minutes())),
+		"request_ip":     ipAddress,
+	})
+}
+
+// settings 读取重置页面地址和令牌有效期
+func (s *PasswordResetService) settings() (string, time.Duration) {
+	resetURL := "http://localhost:5173/reset-password"
+	ttl := 30 * time.Minute
+
+	if config, err := s.configRepo.GetByKey("password_reset_url"); err == nil && config != nil && config.Value != "" {
+		resetURL = config.Value
+	}
+	if config, err := s.configRepo.GetByKey("password_reset_ttl_minutes"); err == nil && config != nil {
+		if minutes, err := strconv.Atoi(config.Value); err == nil && minutes > 0 {
+			ttl = time.Duration(minutes) * time.Minute
+		}
+	}
+	return resetURL, ttl
+}
+
+// appendQueryParam 在地址上追加查询参数
+func appendQueryParam(rawURL, key, value string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	query.Set(key, value)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
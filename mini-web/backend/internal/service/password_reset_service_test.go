@@ -0,0 +1,542 @@
+package service
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+const testResetURL = "https://mini-web.test/reset-password?lang=zh"
+
+// smtpSink 本地运行的最小SMTP服务器，记录收到的邮件
+type smtpSink struct {
+	listener net.Listener
+	messages chan *sinkMessage
+
+	mutex sync.Mutex
+	// rejectRcpt 为true时拒绝所有收件人
+	rejectRcpt bool
+}
+
+// sinkMessage 收到的一封已解码邮件
+type sinkMessage struct {
+	to      []string
+	subject string
+	text    string
+	html    string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动SMTP测试服务器失败: %v", err)
+	}
+	sink := &smtpSink{listener: listener, messages: make(chan *sinkMessage, 16)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(t, conn)
+		}
+	}()
+	return sink
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) setRejectRcpt(reject bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rejectRcpt = reject
+}
+
+// serve 处理一个SMTP会话，只实现发信客户端用到的命令
+func (s *smtpSink) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 sink.test ESMTP")
+
+	var recipients []string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250-sink.test")
+			text.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			recipients = nil
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			s.mutex.Lock()
+			reject := s.rejectRcpt
+			s.mutex.Unlock()
+			if reject {
+				text.PrintfLine("550 mailbox unavailable")
+				continue
+			}
+			address := strings.TrimPrefix(arg, "TO:")
+			recipients = append(recipients, strings.Trim(address, "<>"))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 end with <CRLF>.<CRLF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message, err := parseSinkMessage(data)
+			if err != nil {
+				t.Errorf("解析收到的邮件失败: %v", err)
+				text.PrintfLine("554 malformed message")
+				continue
+			}
+			message.to = recipients
+			s.messages <- message
+			text.PrintfLine("250 OK")
+		case "RSET", "NOOP":
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// parseSinkMessage 解码邮件主题和base64编码的纯文本、HTML正文
+func parseSinkMessage(data []byte) (*sinkMessage, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(data))))
+	if err != nil {
+		return nil, err
+	}
+
+	var decoder mime.WordDecoder
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return nil, err
+	}
+	result := &sinkMessage{subject: subject}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		result.text, err = decodeBase64Body(msg.Body)
+		return result, err
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, err := decodeBase64Body(part)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") {
+			result.html = body
+		} else {
+			result.text = body
+		}
+	}
+}
+
+func decodeBase64Body(r io.Reader) (string, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(raw)))
+	return string(decoded), err
+}
+
+// resetTestEnv 找回密码测试使用的服务
+type resetTestEnv struct {
+	*testServices
+	email     *EmailService
+	queue     model.EmailQueueRepository
+	resets    model.PasswordResetRepository
+	reset     *PasswordResetService
+	sink      *smtpSink
+	templates model.EmailRepository
+}
+
+// newResetTestEnv 创建发信到本地SMTP测试服务器的找回密码服务
+func newResetTestEnv(t *testing.T) *resetTestEnv {
+	t.Helper()
+
+	s := newTestServices(t)
+	secretBox, err := NewSecretBox(filepath.Join(testDataDir, "secret_test.key"))
+	if err != nil {
+		t.Fatalf("初始化SecretBox失败: %v", err)
+	}
+
+	env := &resetTestEnv{
+		testServices: s,
+		queue:        sqlite.NewEmailQueueRepository(sqlite.DB),
+		resets:       sqlite.NewPasswordResetRepository(sqlite.DB),
+		templates:    sqlite.NewEmailRepository(sqlite.DB),
+		sink:         newSMTPSink(t),
+	}
+	env.email = NewEmailService(env.templates, env.queue, s.configs, secretBox)
+	env.reset = NewPasswordResetService(s.users, env.resets, s.configs, s.passwords, s.auth, env.email)
+
+	if err := env.email.SaveConfig(&model.EmailConfig{
+		SMTPHost:  "127.0.0.1",
+		SMTPPort:  env.sink.port(),
+		FromEmail: "noreply@mini-web.test",
+		FromName:  "Mini Web",
+		IsEnabled: true,
+	}); err != nil {
+		t.Fatalf("保存邮件配置失败: %v", err)
+	}
+	t.Cleanup(func() {
+		env.email.SaveConfig(&model.EmailConfig{SMTPPort: 587, EnableTLS: true})
+	})
+
+	s.setConfig(t, "password_reset_url", testResetURL)
+	return env
+}
+
+// deliver 处理发信队列，直到测试服务器收到发给to的邮件
+func (env *resetTestEnv) deliver(t *testing.T, to string) *sinkMessage {
+	t.Helper()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		env.email.ProcessQueue()
+		select {
+		case message := <-env.sink.messages:
+			if len(message.to) == 1 && message.to[0] == to {
+				return message
+			}
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("没有收到发给%s的邮件", to)
+		}
+	}
+}
+
+// resetToken 从邮件正文的重置链接中取出令牌
+func resetToken(t *testing.T, message *sinkMessage) string {
+	t.Helper()
+
+	for _, line := range strings.Split(message.text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "https://mini-web.test/reset-password") {
+			continue
+		}
+		link, err := url.Parse(line)
+		if err != nil {
+			t.Fatalf("解析重置链接失败: %v", err)
+		}
+		if link.Query().Get("lang") != "zh" {
+			t.Errorf("重置链接丢失了配置中的查询参数: %s", line)
+		}
+		return link.Query().Get("token")
+	}
+	t.Fatalf("邮件中没有重置链接:\n%s", message.text)
+	return ""
+}
+
+// queueSize 发信队列中的邮件总数
+func (env *resetTestEnv) queueSize(t *testing.T) int {
+	t.Helper()
+
+	_, total, err := env.queue.GetList("", 1, 0)
+	if err != nil {
+		t.Fatalf("查询发信队列失败: %v", err)
+	}
+	return total
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	env := newResetTestEnv(t)
+	env.setConfig(t, "password_reset_ttl_minutes", "45")
+
+	username := uniqueName("reset")
+	address := username + "@mail.test"
+	user := env.createLocalUser(t, username, address, "Old-Pass-123")
+	session, err := env.auth.Login(username, "Old-Pass-123", "test-agent", "192.0.2.30")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if err := env.reset.RequestReset(" "+address+" ", "192.0.2.30"); err != nil {
+		t.Fatalf("RequestReset() error = %v", err)
+	}
+	message := env.deliver(t, address)
+
+	if !strings.Contains(message.subject, "密码重置") || strings.Contains(message.subject, "{{") {
+		t.Errorf("邮件主题 = %q", message.subject)
+	}
+	for _, want := range []string{username, "45分钟"} {
+		if !strings.Contains(message.text, want) {
+			t.Errorf("邮件正文缺少%q:\n%s", want, message.text)
+		}
+	}
+	if strings.Contains(message.text, "{{") {
+		t.Errorf("邮件正文中有未替换的变量:\n%s", message.text)
+	}
+
+	// 数据库只保存令牌哈希
+	token := resetToken(t, message)
+	if stored, _ := env.resets.GetByHash(token); stored != nil {
+		t.Error("数据库中不应保存明文令牌")
+	}
+	stored, err := env.resets.GetByHash(hashSecretToken(token))
+	if err != nil || stored == nil || stored.UserID != user.ID {
+		t.Fatalf("找不到令牌哈希记录: %v", err)
+	}
+	if ttl := time.Until(stored.ExpiresAt); ttl < 44*time.Minute || ttl > 45*time.Minute {
+		t.Errorf("令牌有效期 = %v, want 45分钟", ttl)
+	}
+
+	if err := env.reset.ResetPassword(token, "New-Pass-456", "192.0.2.30"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if _, err := env.auth.Login(username, "New-Pass-456", "test-agent", "192.0.2.30"); err != nil {
+		t.Errorf("新密码 Login() error = %v", err)
+	}
+	if _, err := env.auth.Login(username, "Old-Pass-123", "test-agent", "192.0.2.30"); err == nil {
+		t.Error("旧密码不应再能登录")
+	}
+	if _, err := env.auth.RefreshToken(session.RefreshToken, "test-agent", "192.0.2.30"); err == nil {
+		t.Error("重置密码后原有登录会话应被吊销")
+	}
+
+	// 令牌只能使用一次
+	if err := env.reset.ResetPassword(token, "Other-Pass-789", "192.0.2.30"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("重复使用令牌 ResetPassword() error = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestPasswordResetNewRequestInvalidatesOldToken(t *testing.T) {
+	env := newResetTestEnv(t)
+
+	username := uniqueName("resetagain")
+	address := username + "@mail.test"
+	env.createLocalUser(t, username, address, "Old-Pass-123")
+
+	if err := env.reset.sendResetEmail(address, "192.0.2.31"); err != nil {
+		t.Fatalf("sendResetEmail() error = %v", err)
+	}
+	first := resetToken(t, env.deliver(t, address))
+	if err := env.reset.sendResetEmail(address, "192.0.2.31"); err != nil {
+		t.Fatalf("sendResetEmail() error = %v", err)
+	}
+	second := resetToken(t, env.deliver(t, address))
+
+	if err := env.reset.ResetPassword(first, "New-Pass-456", "192.0.2.31"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("旧令牌 ResetPassword() error = %v, want ErrInvalidResetToken", err)
+	}
+	if err := env.reset.ResetPassword(second, "New-Pass-456", "192.0.2.31"); err != nil {
+		t.Errorf("新令牌 ResetPassword() error = %v", err)
+	}
+}
+
+func TestPasswordResetExpiredToken(t *testing.T) {
+	env := newResetTestEnv(t)
+
+	user := env.createLocalUser(t, uniqueName("expired"), uniqueName("expired")+"@mail.test", "Old-Pass-123")
+	token, _ := randomTokenString(32)
+	if err := env.resets.Create(&model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashSecretToken(token),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+
+	if err := env.reset.ResetPassword(token, "New-Pass-456", "192.0.2.32"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("过期令牌 ResetPassword() error = %v, want ErrInvalidResetToken", err)
+	}
+	for _, forged := range []string{"", "not-a-token", hashSecretToken(token)} {
+		if err := env.reset.ResetPassword(forged, "New-Pass-456", "192.0.2.32"); !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("ResetPassword(%q) error = %v, want ErrInvalidResetToken", forged, err)
+		}
+	}
+}
+
+func TestPasswordResetRejectedPasswordKeepsToken(t *testing.T) {
+	env := newResetTestEnv(t)
+
+	username := uniqueName("weak")
+	address := username + "@mail.test"
+	env.createLocalUser(t, username, address, "Old-Pass-123")
+	if err := env.reset.sendResetEmail(address, "192.0.2.33"); err != nil {
+		t.Fatalf("sendResetEmail() error = %v", err)
+	}
+	token := resetToken(t, env.deliver(t, address))
+
+	if err := env.reset.ResetPassword(token, "a", "192.0.2.33"); err == nil || errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("弱密码 ResetPassword() error = %v, want 密码策略错误", err)
+	}
+	if err := env.reset.ResetPassword(token, "New-Pass-456", "192.0.2.33"); err != nil {
+		t.Errorf("密码不符合策略时令牌应仍然有效: %v", err)
+	}
+}
+
+func TestPasswordResetDoesNotRevealAccounts(t *testing.T) {
+	env := newResetTestEnv(t)
+
+	ssoName := uniqueName("ssoreset")
+	ssoUser := &model.User{
+		Username:   ssoName,
+		Email:      ssoName + "@mail.test",
+		Nickname:   ssoName,
+		Role:       model.RoleUser,
+		Status:     "active",
+		AuthSource: model.AuthSourceOIDC,
+	}
+	if err := env.users.Create(ssoUser); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	disabledName := uniqueName("disabledreset")
+	disabled := env.createLocalUser(t, disabledName, disabledName+"@mail.test", "Old-Pass-123")
+	disabled.Status = "disabled"
+	if err := env.users.Update(disabled); err != nil {
+		t.Fatalf("禁用用户失败: %v", err)
+	}
+
+	before := env.queueSize(t)
+	for _, address := range []string{"nobody-" + uniqueName("x") + "@mail.test", ssoUser.Email, disabled.Email} {
+		// 对外的结果与存在的账号完全相同
+		if err := env.reset.RequestReset(address, "192.0.2.34"); err != nil {
+			t.Errorf("RequestReset(%q) error = %v", address, err)
+		}
+		if err := env.reset.sendResetEmail(address, "192.0.2.34"); err != nil {
+			t.Errorf("sendResetEmail(%q) error = %v", address, err)
+		}
+	}
+	if after := env.queueSize(t); after != before {
+		t.Errorf("不可重置的邮箱不应产生邮件: 队列 %d -> %d", before, after)
+	}
+}
+
+func TestPasswordResetRateLimit(t *testing.T) {
+	env := newResetTestEnv(t)
+
+	address := "limited-" + uniqueName("x") + "@mail.test"
+	for i := 0; i < 3; i++ {
+		if err := env.reset.RequestReset(address, "192.0.2.35"); err != nil {
+			t.Fatalf("第%d次 RequestReset() error = %v", i+1, err)
+		}
+	}
+	// 同一邮箱按小写计数，换IP也不能绕过
+	if err := env.reset.RequestReset(strings.ToUpper(address), "192.0.2.36"); !errors.Is(err, ErrTooManyResetRequests) {
+		t.Errorf("超过邮箱频率限制 RequestReset() error = %v, want ErrTooManyResetRequests", err)
+	}
+
+	// 同一IP的申请和重置共用限额
+	var err error
+	for i := 0; i < 25 && err == nil; i++ {
+		err = env.reset.RequestReset(uniqueName("spray")+"@mail.test", "192.0.2.37")
+	}
+	if !errors.Is(err, ErrTooManyResetRequests) {
+		t.Errorf("超过IP频率限制 RequestReset() error = %v, want ErrTooManyResetRequests", err)
+	}
+	if err := env.reset.ResetPassword("guess", "New-Pass-456", "192.0.2.37"); !errors.Is(err, ErrTooManyResetRequests) {
+		t.Errorf("超过IP频率限制 ResetPassword() error = %v, want ErrTooManyResetRequests", err)
+	}
+}
+
+func TestPasswordResetTemplateEscapesHTML(t *testing.T) {
+	env := newResetTestEnv(t)
+
+	template, err := env.templates.GetDefaultTemplate(model.EmailTemplateResetPassword)
+	if err != nil || template == nil {
+		t.Fatalf("读取找回密码模板失败: %v", err)
+	}
+	original := *template
+	template.HTMLBody = `<p>您好 {{user_name}}</p><p><a href="{{reset_link}}">重置密码</a>，{{expire_minutes}}分钟内有效</p>`
+	if err := env.email.UpdateTemplate(template); err != nil {
+		t.Fatalf("UpdateTemplate() error = %v", err)
+	}
+	t.Cleanup(func() { env.email.UpdateTemplate(&original) })
+
+	username := uniqueName("html")
+	address := username + "@mail.test"
+	user := env.createLocalUser(t, username, address, "Old-Pass-123")
+	user.Nickname = `<script>alert(1)</script>`
+	if err := env.users.Update(user); err != nil {
+		t.Fatalf("更新昵称失败: %v", err)
+	}
+
+	if err := env.reset.sendResetEmail(address, "192.0.2.38"); err != nil {
+		t.Fatalf("sendResetEmail() error = %v", err)
+	}
+	message := env.deliver(t, address)
+
+	if !strings.Contains(message.text, user.Nickname) {
+		t.Errorf("纯文本正文应包含原始昵称:\n%s", message.text)
+	}
+	if strings.Contains(message.html, "<script>") || !strings.Contains(message.html, "&lt;script&gt;") {
+		t.Errorf("HTML正文中的变量未转义:\n%s", message.html)
+	}
+	if !strings.Contains(message.html, `href="https://mini-web.test/reset-password?`) {
+		t.Errorf("HTML正文缺少重置链接:\n%s", message.html)
+	}
+}
+
+func TestEmailQueueRetriesOnSMTPFailure(t *testing.T) {
+	env := newResetTestEnv(t)
+	env.sink.setRejectRcpt(true)
+
+	address := uniqueName("bounce") + "@mail.test"
+	if err := env.email.Enqueue(address, "测试", "正文", ""); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	env.email.ProcessQueue()
+
+	messages, _, err := env.queue.GetList(model.EmailStatusPending, 100, 0)
+	if err != nil {
+		t.Fatalf("查询发信队列失败: %v", err)
+	}
+	for _, message := range messages {
+		if message.To != address {
+			continue
+		}
+		if message.Attempts != 1 || message.LastError == "" || !message.NextAttemptAt.After(time.Now()) {
+			t.Errorf("发送失败后应安排重试: attempts=%d, last_error=%q, next=%v",
+				message.Attempts, message.LastError, message.NextAttemptAt)
+		}
+		return
+	}
+	t.Fatal("发送失败的邮件应保留在队列中等待重试")
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxPasswordBytes bcrypt只使用密码的前72个字节
+const bcryptMaxPasswordBytes = 72
+
+var (
+	// ErrWeakPassword 密码不符合安全策略
+	ErrWeakPassword = errors.New("密码不符合安全策略")
+
+	// ErrPasswordReused 新密码与最近使用过的密码相同
+	ErrPasswordReused = errors.New("不能使用最近用过的密码")
+
+	// ErrPasswordChangeRequired 用户必须先修改密码
+	ErrPasswordChangeRequired = errors.New("密码已过期或需要重置，请先修改密码")
+
+	// ErrExternalAccountPassword 外部身份源账号的密码不由本系统管理
+	ErrExternalAccountPassword = errors.New("该账号由外部身份源管理，不能在此修改密码")
+)
+
+//go:embed password_blocklist.txt
+var passwordBlocklistData string
+
+// passwordBlocklist 内置常见弱密码集合
+var passwordBlocklist = parsePasswordBlocklist(passwordBlocklistData)
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	Level            string `json:"level"`             // 预设级别：low、medium、high
+	MinLength        int    `json:"min_length"`        // 最小长度
+	MinCharClasses   int    `json:"min_char_classes"`  // 小写字母、大写字母、数字、符号中至少包含几类
+	CheckBlocklist   bool   `json:"check_blocklist"`   // 禁止使用常见弱密码
+	DisallowUsername bool   `json:"disallow_username"` // 禁止包含用户名
+	HistoryCount     int    `json:"history_count"`     // 不能与最近几次的密码相同，0表示不限制
+	MaxAgeDays       int    `json:"max_age_days"`      // 密码有效天数，0表示永不过期
+}
+
+// passwordPolicyPresets password_policy配置对应的预设策略
+var passwordPolicyPresets = map[string]PasswordPolicy{
+	"low":    {Level: "low", MinLength: 6, MinCharClasses: 1, CheckBlocklist: true, DisallowUsername: true},
+	"medium": {Level: "medium", MinLength: 8, MinCharClasses: 3, CheckBlocklist: true, DisallowUsername: true, HistoryCount: 3},
+	"high":   {Level: "high", MinLength: 12, MinCharClasses: 4, CheckBlocklist: true, DisallowUsername: true, HistoryCount: 5, MaxAgeDays: 90},
+}
+
+// PasswordService 密码策略校验、密码历史和过期管理。
+// 策略以password_policy预设为基础，password_*配置项非空时覆盖对应规则
+type PasswordService struct {
+	configRepo  model.SystemConfigRepository
+	userRepo    model.UserRepository
+	historyRepo model.PasswordHistoryRepository
+}
+
+// NewPasswordService 创建密码服务
+func NewPasswordService(configRepo model.SystemConfigRepository, userRepo model.UserRepository, historyRepo model.PasswordHistoryRepository) *PasswordService {
+	return &PasswordService{configRepo: configRepo, userRepo: userRepo, historyRepo: historyRepo}
+}
+
+// GetPolicy 获取当前生效的密码策略
+func (s *PasswordService) GetPolicy() (*PasswordPolicy, error) {
+	configs, err := s.configRepo.GetByCategory("security")
+	if err != nil {
+		return nil, fmt.Errorf("读取密码策略失败: %w", err)
+	}
+
+	values := make(map[string]string, len(configs))
+	for _, c := range configs {
+		values[c.Key] = strings.TrimSpace(c.Value)
+	}
+
+	policy, ok := passwordPolicyPresets[strings.ToLower(values["password_policy"])]
+	if !ok {
+		policy = passwordPolicyPresets["medium"]
+	}
+
+	overrideInt := func(key string, target *int) {
+		if value, err := strconv.Atoi(values[key]); err == nil && value >= 0 {
+			*target = value
+		}
+	}
+	overrideBool := func(key string, target *bool) {
+		if value, err := strconv.ParseBool(values[key]); err == nil {
+			*target = value
+		}
+	}
+	overrideInt("password_min_length", &policy.MinLength)
+	overrideInt("password_min_char_classes", &policy.MinCharClasses)
+	overrideBool("password_check_blocklist", &policy.CheckBlocklist)
+	overrideBool("password_disallow_username", &policy.DisallowUsername)
+	overrideInt("password_history_count", &policy.HistoryCount)
+	overrideInt("password_max_age_days", &policy.MaxAgeDays)
+
+	if policy.MinCharClasses > 4 {
+		policy.MinCharClasses = 4
+	}
+	return &policy, nil
+}
+
+// Validate 按策略检查密码强度
+func (s *PasswordService) Validate(policy *PasswordPolicy, username, password string) error {
+	if len(password) > bcryptMaxPasswordBytes {
+		return fmt.Errorf("%w: 长度不能超过%d个字节", ErrWeakPassword, bcryptMaxPasswordBytes)
+	}
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("%w: 长度至少为%d位", ErrWeakPassword, policy.MinLength)
+	}
+	if classes := countCharClasses(password); classes < policy.MinCharClasses {
+		return fmt.Errorf("%w: 需要包含小写字母、大写字母、数字、符号中的至少%d类", ErrWeakPassword, policy.MinCharClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if policy.DisallowUsername && len(username) >= 3 && strings.Contains(lower, strings.ToLower(username)) {
+		return fmt.Errorf("%w: 不能包含用户名", ErrWeakPassword)
+	}
+	if policy.CheckBlocklist && isBlocklistedPassword(lower) {
+		return fmt.Errorf("%w: 密码过于常见", ErrWeakPassword)
+	}
+	return nil
+}
+
+// CheckNewPassword 按当前策略检查新账号的密码
+func (s *PasswordService) CheckNewPassword(username, password string) error {
+	policy, err := s.GetPolicy()
+	if err != nil {
+		return err
+	}
+	return s.Validate(policy, username, password)
+}
+
+// ValidateChange 检查新密码是否符合策略且未与最近的密码重复，不修改密码
+func (s *PasswordService) ValidateChange(user *model.User, newPassword string) error {
+	_, err := s.validateChange(user, newPassword)
+	return err
+}
+
+// ChangePassword 校验策略和密码历史后更新密码，并清除必须修改密码标记
+func (s *PasswordService) ChangePassword(user *model.User, newPassword string) error {
+	policy, err := s.validateChange(user, newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, newPassword); err != nil {
+		return fmt.Errorf("更新密码时出错: %w", err)
+	}
+
+	if policy.HistoryCount > 1 {
+		if err := s.historyRepo.Add(user.ID, user.Password); err != nil {
+			return err
+		}
+	}
+	if err := s.historyRepo.Prune(user.ID, max(policy.HistoryCount-1, 0)); err != nil {
+		log.Printf("清理密码历史失败: %v", err)
+	}
+
+	log.Printf("用户已修改密码: 用户ID=%d", user.ID)
+	return nil
+}
+
+// validateChange 校验新密码，返回当前生效的策略
+func (s *PasswordService) validateChange(user *model.User, newPassword string) (*PasswordPolicy, error) {
+	if user.AuthSource != "" && user.AuthSource != model.AuthSourceLocal {
+		return nil, ErrExternalAccountPassword
+	}
+
+	policy, err := s.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Validate(policy, user.Username, newPassword); err != nil {
+		return nil, err
+	}
+
+	// 当前密码计为历史中的一次，再检查之前的HistoryCount-1次
+	if policy.HistoryCount > 0 {
+		hashes := []string{user.Password}
+		if policy.HistoryCount > 1 {
+			recent, err := s.historyRepo.GetRecent(user.ID, policy.HistoryCount-1)
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, recent...)
+		}
+		for _, hash := range hashes {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(newPassword)) == nil {
+				return nil, fmt.Errorf("%w: 不能与最近%d次的密码相同", ErrPasswordReused, policy.HistoryCount)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// IsExpired 判断本地账号的密码是否已超过有效期
+func (s *PasswordService) IsExpired(user *model.User, policy *PasswordPolicy) bool {
+	if policy.MaxAgeDays <= 0 || (user.AuthSource != "" && user.AuthSource != model.AuthSourceLocal) {
+		return false
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour
+}
+
+// CheckExpiry 密码过期时标记用户必须修改密码，返回是否需要修改
+func (s *PasswordService) CheckExpiry(user *model.User) (bool, error) {
+	if user.MustChangePassword {
+		return true, nil
+	}
+
+	policy, err := s.GetPolicy()
+	if err != nil {
+		return false, err
+	}
+	if !s.IsExpired(user, policy) {
+		return false, nil
+	}
+
+	if err := s.userRepo.SetMustChangePassword(user.ID, true); err != nil {
+		return false, fmt.Errorf("标记密码过期失败: %w", err)
+	}
+	user.MustChangePassword = true
+	log.Printf("用户密码已过期，需要修改密码: 用户ID=%d", user.ID)
+	return true, nil
+}
+
+// RequirePasswordChange 设置用户下次登录时必须修改密码
+func (s *PasswordService) RequirePasswordChange(userID uint, required bool) error {
+	if err := s.userRepo.SetMustChangePassword(userID, required); err != nil {
+		return fmt.Errorf("设置修改密码标记失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteHistory 删除用户的密码历史
+func (s *PasswordService) DeleteHistory(userID uint) error {
+	return s.historyRepo.DeleteByUserID(userID)
+}
+
+// countCharClasses 统计密码包含的字符种类：小写字母、大写字母、数字、其他符号
+func countCharClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// isBlocklistedPassword 判断密码是否为常见弱密码，
+// 去掉末尾数字和符号后命中也算，如Password123!
+func isBlocklistedPassword(lower string) bool {
+	if passwordBlocklist[lower] {
+		return true
+	}
+	base := strings.TrimRightFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return len(base) >= 4 && base != lower && passwordBlocklist[base]
+}
+
+// parsePasswordBlocklist 解析内置弱密码列表，忽略空行和#注释
+func parsePasswordBlocklist(data string) map[string]bool {
+	blocklist := make(map[string]bool)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[line] = true
+	}
+	return blocklist
+}
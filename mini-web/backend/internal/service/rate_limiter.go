@@ -0,0 +1,58 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter 按键的滑动窗口限流器，记录保存在内存中
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mutex     sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// NewRateLimiter 创建限流器，每个键在window内最多允许limit次
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow 记录一次请求，超过限制时返回false
+func (l *RateLimiter) Allow(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+
+	// 定期清理过期的键，避免内存无限增长
+	if now.Sub(l.lastSweep) > l.window {
+		for k, times := range l.hits {
+			if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	times := l.hits[key]
+	start := 0
+	for start < len(times) && !times[start].After(cutoff) {
+		start++
+	}
+	times = times[start:]
+
+	if len(times) >= l.limit {
+		l.hits[key] = times
+		return false
+	}
+	l.hits[key] = append(times, now)
+	return true
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 终端输出续传：每个输出帧带递增序号，服务端在有界缓冲中保留最近发送的帧。
+// WebSocket断开后终端在宽限期内保持运行，客户端携带续传令牌和收到的最后序号重连，
+// 服务端先补发缺失的帧再转为实时输出
+const (
+	// DefaultReplayBufferSize 重放缓冲保留的输出字节数
+	DefaultReplayBufferSize = 1024 * 1024
+	// DefaultResumeGracePeriod 连接断开后保留终端的时长
+	DefaultResumeGracePeriod = 2 * time.Minute
+)
+
+var (
+	// ErrResumeTokenInvalid 续传令牌无效或已过期
+	ErrResumeTokenInvalid = errors.New("续传令牌无效或会话已过期")
+	// ErrTerminalDetached 连接已被新的连接取代或已断开
+	ErrTerminalDetached = errors.New("终端已与当前连接分离")
+)
+
+// ReplayFrame 重放缓冲中的一个输出帧
+type ReplayFrame struct {
+	Seq  uint64
+	Data []byte
+}
+
+// ReplayBuffer 按字节数限制大小的输出帧缓冲，超出时丢弃最旧的帧
+type ReplayBuffer struct {
+	mutex   sync.Mutex
+	frames  []ReplayFrame
+	size    int
+	limit   int
+	lastSeq uint64
+}
+
+// NewReplayBuffer 创建重放缓冲，limit为保留的最大字节数
+func NewReplayBuffer(limit int) *ReplayBuffer {
+	if limit <= 0 {
+		limit = DefaultReplayBufferSize
+	}
+	return &ReplayBuffer{limit: limit}
+}
+
+// Append 记录一帧输出并返回分配的序号
+func (b *ReplayBuffer) Append(data []byte) uint64 {
+	frame := ReplayFrame{Data: append([]byte(nil), data...)}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastSeq++
+	frame.Seq = b.lastSeq
+	b.frames = append(b.frames, frame)
+	b.size += len(frame.Data)
+
+	// 至少保留最新的一帧
+	drop := 0
+	for b.size > b.limit && drop < len(b.frames)-1 {
+		b.size -= len(b.frames[drop].Data)
+		drop++
+	}
+	if drop > 0 {
+		b.frames = append(b.frames[:0:0], b.frames[drop:]...)
+	}
+	return frame.Seq
+}
+
+// Since 返回序号大于seq的帧，complete为false表示部分帧已被丢弃，无法完整补发
+func (b *ReplayBuffer) Since(seq uint64) ([]ReplayFrame, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if seq >= b.lastSeq {
+		return nil, true
+	}
+	complete := len(b.frames) > 0 && b.frames[0].Seq <= seq+1
+	start := 0
+	for start < len(b.frames) && b.frames[start].Seq <= seq {
+		start++
+	}
+	return append([]ReplayFrame(nil), b.frames[start:]...), complete
+}
+
+// LastSeq 最后分配的序号
+func (b *ReplayBuffer) LastSeq() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.lastSeq
+}
+
+// ResumeInfo 连接建立或恢复时发给客户端的续传信息
+type ResumeInfo struct {
+	Type     string `json:"type"` // 固定为resume
+	Token    string `json:"token"`
+	Resumed  bool   `json:"resumed"`
+	LastSeq  uint64 `json:"lastSeq"`  // 补发完成后客户端应收到的最后序号
+	Replayed int    `json:"replayed"` // 补发的帧数
+	Gap      bool   `json:"gap"`      // 部分缺失的帧已超出重放缓冲，无法补发
+}
+
+// terminalReadResult 读取协程的一次读取结果
+type terminalReadResult struct {
+	data []byte
+	err  error
+}
+
+// ResumableTerminal 可续传的终端。终端读取由独立的协程完成，不随WebSocket连接结束，
+// 连接分离后在宽限期内保留终端，期间的输出留在终端中，重连后继续读取
+type ResumableTerminal struct {
+	Token     string
+	UserID    uint
+	SessionID string
+	Terminal  TerminalSession
+
+	replay *ReplayBuffer
+	grace  time.Duration
+
+	// 读取状态，readMutex保证同一时刻只有一个连接读取，读取结果与序号分配是原子的
+	readMutex sync.Mutex
+	requests  chan int
+	results   chan terminalReadResult
+	pending   bool
+	leftover  []byte
+	readErr   error
+	pumpOnce  sync.Once
+
+	mutex      sync.Mutex
+	generation uint64
+	attached   bool
+	kick       func()
+	timer      *time.Timer
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+var (
+	resumableTerminalsMutex sync.Mutex
+	resumableTerminals      = map[string]*ResumableTerminal{}
+)
+
+// NewResumableTerminal 包装终端并登记续传令牌，sessionID为终端所属的会话
+func NewResumableTerminal(userID uint, sessionID string, terminal TerminalSession) *ResumableTerminal {
+	rt := &ResumableTerminal{
+		Token:     uuid.New().String(),
+		UserID:    userID,
+		SessionID: sessionID,
+		Terminal:  terminal,
+		replay:    NewReplayBuffer(DefaultReplayBufferSize),
+		grace:     DefaultResumeGracePeriod,
+		requests:  make(chan int, 1),
+		results:   make(chan terminalReadResult, 1),
+		closed:    make(chan struct{}),
+	}
+
+	resumableTerminalsMutex.Lock()
+	resumableTerminals[rt.Token] = rt
+	resumableTerminalsMutex.Unlock()
+	return rt
+}
+
+// ResumeTerminal 按续传令牌查找终端，令牌必须属于该用户和会话
+func ResumeTerminal(token string, userID uint, sessionID string) (*ResumableTerminal, error) {
+	resumableTerminalsMutex.Lock()
+	rt, exists := resumableTerminals[token]
+	resumableTerminalsMutex.Unlock()
+
+	if !exists || rt.UserID != userID || rt.SessionID != sessionID {
+		return nil, ErrResumeTokenInvalid
+	}
+	return rt, nil
+}
+
+// CloseResumableTerminals 关闭会话的所有可续传终端，会话被显式关闭时调用
+func CloseResumableTerminals(sessionID string) {
+	resumableTerminalsMutex.Lock()
+	targets := make([]*ResumableTerminal, 0)
+	for _, rt := range resumableTerminals {
+		if rt.SessionID == sessionID {
+			targets = append(targets, rt)
+		}
+	}
+	resumableTerminalsMutex.Unlock()
+
+	for _, rt := range targets {
+		rt.Close()
+	}
+}
+
+// Attach 把终端关联到新连接并返回连接代号。已有连接时调用其kick使旧连接退出
+func (rt *ResumableTerminal) Attach(kick func()) uint64 {
+	rt.mutex.Lock()
+	if rt.timer != nil {
+		rt.timer.Stop()
+		rt.timer = nil
+	}
+	previous := rt.kick
+	rt.generation++
+	rt.attached = true
+	rt.kick = kick
+	generation := rt.generation
+	rt.mutex.Unlock()
+
+	if previous != nil {
+		log.Printf("终端被新连接接管，关闭旧连接: 会话=%s", rt.SessionID)
+		previous()
+	}
+	return generation
+}
+
+// Detach 连接结束时调用。终端已结束时立即关闭，否则在宽限期后关闭
+func (rt *ResumableTerminal) Detach(generation uint64) {
+	rt.mutex.Lock()
+	if generation != rt.generation || !rt.attached {
+		// 已被新连接接管
+		rt.mutex.Unlock()
+		return
+	}
+	rt.attached = false
+	rt.kick = nil
+	rt.mutex.Unlock()
+
+	rt.readMutex.Lock()
+	ended := rt.readErr != nil
+	rt.readMutex.Unlock()
+	if ended {
+		rt.Close()
+		return
+	}
+
+	rt.mutex.Lock()
+	if rt.generation == generation {
+		rt.timer = time.AfterFunc(rt.grace, func() {
+			log.Printf("续传宽限期已过，关闭终端: 会话=%s", rt.SessionID)
+			rt.Close()
+		})
+	}
+	rt.mutex.Unlock()
+	log.Printf("终端连接断开，保留%v等待重连: 会话=%s", rt.grace, rt.SessionID)
+}
+
+// Close 关闭终端并注销续传令牌
+func (rt *ResumableTerminal) Close() {
+	rt.closeOnce.Do(func() {
+		resumableTerminalsMutex.Lock()
+		delete(resumableTerminals, rt.Token)
+		resumableTerminalsMutex.Unlock()
+
+		rt.mutex.Lock()
+		if rt.timer != nil {
+			rt.timer.Stop()
+			rt.timer = nil
+		}
+		rt.mutex.Unlock()
+
+		close(rt.closed)
+		rt.Terminal.Close()
+	})
+}
+
+// Read 读取终端输出并分配序号。连接被接管或done关闭时返回ErrTerminalDetached，
+// 此前已读出的数据仍记录在重放缓冲中，由新连接补发
+func (rt *ResumableTerminal) Read(generation uint64, p []byte, done <-chan struct{}) (int, uint64, error) {
+	rt.readMutex.Lock()
+	defer rt.readMutex.Unlock()
+
+	if !rt.current(generation) {
+		return 0, 0, ErrTerminalDetached
+	}
+
+	if len(rt.leftover) == 0 {
+		if rt.readErr != nil {
+			return 0, 0, rt.readErr
+		}
+		rt.pumpOnce.Do(func() { go rt.pump() })
+		if !rt.pending {
+			rt.requests <- len(p)
+			rt.pending = true
+		}
+
+		select {
+		case result := <-rt.results:
+			rt.pending = false
+			rt.leftover = result.data
+			if result.err != nil {
+				rt.readErr = result.err
+			}
+			if len(rt.leftover) == 0 {
+				return 0, 0, rt.readErr
+			}
+		case <-done:
+			return 0, 0, ErrTerminalDetached
+		}
+	}
+
+	n := copy(p, rt.leftover)
+	rt.leftover = rt.leftover[n:]
+	return n, rt.replay.Append(p[:n]), nil
+}
+
+// Replay 返回序号大于lastSeq的帧。与Read互斥，补发的内容和之后读取的输出不会重叠或遗漏
+func (rt *ResumableTerminal) Replay(generation uint64, lastSeq uint64) ([]ReplayFrame, bool, error) {
+	rt.readMutex.Lock()
+	defer rt.readMutex.Unlock()
+
+	if !rt.current(generation) {
+		return nil, false, ErrTerminalDetached
+	}
+	frames, complete := rt.replay.Since(lastSeq)
+	return frames, complete, nil
+}
+
+// LastSeq 已分配的最后序号
+func (rt *ResumableTerminal) LastSeq() uint64 {
+	return rt.replay.LastSeq()
+}
+
+// current 检查连接代号是否仍是当前连接
+func (rt *ResumableTerminal) current(generation uint64) bool {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	return rt.generation == generation
+}
+
+// pump 按请求读取终端，每次读取的结果由Read取走后才开始下一次读取
+func (rt *ResumableTerminal) pump() {
+	var buf []byte
+	for {
+		var limit int
+		select {
+		case limit = <-rt.requests:
+		case <-rt.closed:
+			return
+		}
+
+		if cap(buf) < limit {
+			buf = make([]byte, limit)
+		}
+		n, err := rt.Terminal.Read(buf[:limit])
+		rt.results <- terminalReadResult{data: buf[:n], err: err}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+var (
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("角色不存在")
+
+	// ErrRoleExists 角色已存在
+	ErrRoleExists = errors.New("角色名称已存在")
+
+	// ErrRoleBuiltIn 内置角色不可删除或修改权限
+	ErrRoleBuiltIn = errors.New("内置管理员角色不可删除或修改权限")
+
+	// ErrRoleInUse 角色仍被用户使用
+	ErrRoleInUse = errors.New("角色仍被用户使用，请先调整这些用户的角色")
+
+	// ErrInvalidSystemPermission 无效的系统权限
+	ErrInvalidSystemPermission = errors.New("无效的系统权限")
+)
+
+// 角色名称格式：字母开头，可包含字母、数字、下划线和短横线
+var roleNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{1,31}$`)
+
+// 角色权限缓存有效期，多实例部署时其他实例的修改最迟在此时间后生效
+const roleCacheTTL = 30 * time.Second
+
+// cachedRole 缓存的角色权限
+type cachedRole struct {
+	version     int
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+// RoleService 角色与权限服务
+type RoleService struct {
+	roleRepo model.RoleRepository
+
+	cache map[string]*cachedRole
+	mutex sync.RWMutex
+}
+
+// NewRoleService 创建角色服务实例
+func NewRoleService(roleRepo model.RoleRepository) *RoleService {
+	return &RoleService{
+		roleRepo: roleRepo,
+		cache:    make(map[string]*cachedRole),
+	}
+}
+
+// GetPermissionCatalog 获取全部可分配的权限
+func (s *RoleService) GetPermissionCatalog() []model.PermissionInfo {
+	return model.PermissionCatalog
+}
+
+// GetRoles 获取全部角色
+func (s *RoleService) GetRoles() ([]*model.Role, error) {
+	return s.roleRepo.GetAll()
+}
+
+// GetRole 获取角色详情
+func (s *RoleService) GetRole(id uint) (*model.Role, error) {
+	role, err := s.roleRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// CreateRole 创建自定义角色
+func (s *RoleService) CreateRole(req *model.RoleRequest) (*model.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, errors.New("角色名称需以字母开头，由2-32位字母、数字、下划线或短横线组成")
+	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.roleRepo.GetByName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrRoleExists
+	}
+
+	role := &model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: uniquePermissions(req.Permissions),
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已创建角色: %s, 权限=%v", role.Name, role.Permissions)
+	return role, nil
+}
+
+// UpdateRole 更新角色描述和权限，权限版本递增后已签发的令牌立即按新权限生效
+func (s *RoleService) UpdateRole(id uint, req *model.RoleRequest) (*model.Role, error) {
+	role, err := s.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == model.RoleAdmin {
+		return nil, ErrRoleBuiltIn
+	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		return nil, err
+	}
+
+	role.Description = req.Description
+	role.Permissions = uniquePermissions(req.Permissions)
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+	s.invalidate(role.Name)
+
+	log.Printf("已更新角色: %s, 版本=%d, 权限=%v", role.Name, role.Version, role.Permissions)
+	return role, nil
+}
+
+// DeleteRole 删除自定义角色
+func (s *RoleService) DeleteRole(id uint) error {
+	role, err := s.GetRole(id)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrRoleBuiltIn
+	}
+	if role.UserCount > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.roleRepo.Delete(id); err != nil {
+		return err
+	}
+	s.invalidate(role.Name)
+	return nil
+}
+
+// RoleExists 检查角色是否存在
+func (s *RoleService) RoleExists(name string) (bool, error) {
+	role, err := s.roleRepo.GetByName(name)
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+// GetRoleVersion 获取角色当前的权限版本，用于写入令牌
+func (s *RoleService) GetRoleVersion(name string) (int, error) {
+	cached, err := s.load(name, 0)
+	if err != nil {
+		return 0, err
+	}
+	return cached.version, nil
+}
+
+// HasPermission 检查角色是否拥有权限。tokenVersion为令牌中的角色版本，
+// 大于缓存版本时说明角色已在其他实例被修改，会立即重新加载
+func (s *RoleService) HasPermission(roleName string, tokenVersion int, permission string) (bool, error) {
+	cached, err := s.load(roleName, tokenVersion)
+	if err != nil {
+		return false, err
+	}
+	return cached.permissions[model.PermAll] || cached.permissions[permission], nil
+}
+
+// load 从缓存或数据库加载角色权限
+func (s *RoleService) load(roleName string, minVersion int) (*cachedRole, error) {
+	s.mutex.RLock()
+	cached, ok := s.cache[roleName]
+	s.mutex.RUnlock()
+	if ok && cached.version >= minVersion && time.Since(cached.loadedAt) < roleCacheTTL {
+		return cached, nil
+	}
+
+	role, err := s.roleRepo.GetByName(roleName)
+	if err != nil {
+		return nil, fmt.Errorf("加载角色权限失败: %w", err)
+	}
+
+	// 不存在的角色没有任何权限
+	cached = &cachedRole{permissions: make(map[string]bool), loadedAt: time.Now()}
+	if role != nil {
+		cached.version = role.Version
+		for _, permission := range role.Permissions {
+			cached.permissions[permission] = true
+		}
+	}
+
+	s.mutex.Lock()
+	s.cache[roleName] = cached
+	s.mutex.Unlock()
+	return cached, nil
+}
+
+// invalidate 清除角色权限缓存
+func (s *RoleService) invalidate(roleName string) {
+	s.mutex.Lock()
+	delete(s.cache, roleName)
+	s.mutex.Unlock()
+}
+
+// validatePermissions 检查权限是否都在权限目录中
+func validatePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		if !model.IsValidSystemPermission(permission) {
+			return fmt.Errorf("%w: %s", ErrInvalidSystemPermission, permission)
+		}
+	}
+	return nil
+}
+
+// uniquePermissions 去除重复的权限
+func uniquePermissions(permissions []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !seen[permission] {
+			seen[permission] = true
+			result = append(result, permission)
+		}
+	}
+	return result
+}
@@ -0,0 +1,587 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// 定时任务：按cron表达式在目标主机上执行命令或脚本，每次执行保存为关联的批量任务，
+// 可通过批量任务接口查看输出。执行时使用服务账号凭据登录，失败的主机按重试策略重新执行，
+// 重试后仍失败时发送通知。scheduled_jobs_paused配置为true时全部定时任务暂停
+const (
+	DefaultScheduledJobRetentionRuns = 20
+	MaxScheduledJobRetentionRuns     = 200
+	MaxScheduledJobRetries           = 5
+	DefaultScheduledJobRetryDelay    = time.Minute
+	MaxScheduledJobRetryDelay        = time.Hour
+
+	scheduledJobsPausedKey = "scheduled_jobs_paused"
+)
+
+var (
+	// ErrScheduledJobNotFound 定时任务不存在
+	ErrScheduledJobNotFound = errors.New("定时任务不存在")
+
+	// ErrScheduledJobCredentialRequired 未指定服务账号凭据
+	ErrScheduledJobCredentialRequired = errors.New("定时任务必须指定服务账号凭据")
+
+	// ErrInvalidOverlapPolicy 无效的重叠策略
+	ErrInvalidOverlapPolicy = errors.New("无效的重叠策略，仅支持skip、queue")
+
+	// ErrScheduledJobRunning 定时任务正在执行
+	ErrScheduledJobRunning = errors.New("定时任务正在执行")
+
+	// ErrScheduledJobsPaused 定时任务已全局暂停
+	ErrScheduledJobsPaused = errors.New("定时任务已全局暂停")
+)
+
+// ScheduledJobService 定时任务服务
+type ScheduledJobService struct {
+	jobRepo     model.ScheduledJobRepository
+	batchJobs   *BatchJobService
+	connService *ConnectionService
+	configRepo  model.SystemConfigRepository
+
+	mutex   sync.Mutex
+	running map[uint]bool // 正在执行的定时任务
+	queued  map[uint]bool // 执行期间又被触发、需要在结束后补执行的定时任务
+}
+
+// NewScheduledJobService 创建定时任务服务实例
+func NewScheduledJobService(jobRepo model.ScheduledJobRepository, batchJobs *BatchJobService, connService *ConnectionService, configRepo model.SystemConfigRepository) *ScheduledJobService {
+	return &ScheduledJobService{
+		jobRepo:     jobRepo,
+		batchJobs:   batchJobs,
+		connService: connService,
+		configRepo:  configRepo,
+		running:     make(map[uint]bool),
+		queued:      make(map[uint]bool),
+	}
+}
+
+// Start 启动调度，每分钟整点检查需要触发的定时任务。服务停止期间错过的触发不会补执行
+func (s *ScheduledJobService) Start() {
+	go func() {
+		for {
+			next := time.Now().Truncate(time.Minute).Add(time.Minute)
+			time.Sleep(time.Until(next))
+			s.RunDue(next)
+		}
+	}()
+}
+
+// RunDue 触发在指定分钟应执行的定时任务
+func (s *ScheduledJobService) RunDue(t time.Time) {
+	if s.IsPaused() {
+		return
+	}
+
+	jobs, err := s.jobRepo.GetEnabled()
+	if err != nil {
+		log.Printf("查询定时任务失败: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		schedule, err := ParseCronSchedule(job.CronExpr)
+		if err != nil {
+			log.Printf("定时任务的cron表达式无效: 任务ID=%d, 错误: %v", job.ID, err)
+			continue
+		}
+		if schedule.Matches(t) {
+			s.trigger(job)
+		}
+	}
+}
+
+// CreateJob 创建定时任务，目标主机需要connect权限，服务账号凭据需要对用户可用
+func (s *ScheduledJobService) CreateJob(userID uint, req *model.ScheduledJobRequest) (*model.ScheduledJob, error) {
+	job := &model.ScheduledJob{UserID: userID}
+	if err := s.applyRequest(userID, job, req); err != nil {
+		return nil, err
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	log.Printf("创建定时任务: 任务ID=%d, 用户ID=%d, 表达式=%s, 主机数=%d", job.ID, userID, job.CronExpr, len(job.ConnectionIDs))
+	s.setNextRun(job)
+	return job, nil
+}
+
+// UpdateJob 修改定时任务，正在进行的执行不受影响
+func (s *ScheduledJobService) UpdateJob(userID uint, id uint, req *model.ScheduledJobRequest) (*model.ScheduledJob, error) {
+	job, err := s.getOwnedJob(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(userID, job, req); err != nil {
+		return nil, err
+	}
+	if err := s.jobRepo.Update(job); err != nil {
+		return nil, err
+	}
+
+	s.setNextRun(job)
+	return job, nil
+}
+
+// DeleteJob 删除定时任务及其执行记录，正在执行时不能删除
+func (s *ScheduledJobService) DeleteJob(userID uint, id uint) error {
+	job, err := s.getOwnedJob(userID, id)
+	if err != nil {
+		return err
+	}
+	if s.isRunning(job.ID) {
+		return ErrScheduledJobRunning
+	}
+
+	if err := s.jobRepo.Delete(job.ID); err != nil {
+		return err
+	}
+	if err := s.batchJobs.jobRepo.DeleteByScheduledJobID(job.ID, 0); err != nil {
+		log.Printf("删除定时任务执行记录失败: 任务ID=%d, 错误: %v", job.ID, err)
+	}
+
+	log.Printf("删除定时任务: 任务ID=%d, 用户ID=%d", job.ID, userID)
+	return nil
+}
+
+// GetJob 获取定时任务
+func (s *ScheduledJobService) GetJob(userID uint, id uint) (*model.ScheduledJob, error) {
+	job, err := s.getOwnedJob(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.setNextRun(job)
+	return job, nil
+}
+
+// ListJobs 获取用户创建的定时任务
+func (s *ScheduledJobService) ListJobs(userID uint) ([]*model.ScheduledJob, error) {
+	jobs, err := s.jobRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		s.setNextRun(job)
+	}
+	return jobs, nil
+}
+
+// GetRuns 获取定时任务的执行记录，每次重试是一条单独的记录
+func (s *ScheduledJobService) GetRuns(userID uint, id uint) ([]*model.BatchJob, error) {
+	job, err := s.getOwnedJob(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.batchJobs.jobRepo.GetByScheduledJobID(job.ID, MaxScheduledJobRetentionRuns)
+}
+
+// RunJob 立即执行一次定时任务，未启用的任务也可以手动执行，仍遵循重叠策略和全局暂停
+func (s *ScheduledJobService) RunJob(userID uint, id uint) error {
+	job, err := s.getOwnedJob(userID, id)
+	if err != nil {
+		return err
+	}
+	if s.IsPaused() {
+		return ErrScheduledJobsPaused
+	}
+	if !s.trigger(job) {
+		return ErrScheduledJobRunning
+	}
+
+	log.Printf("手动执行定时任务: 任务ID=%d, 用户ID=%d", job.ID, userID)
+	return nil
+}
+
+// IsPaused 定时任务是否已全局暂停，读取配置失败时按暂停处理
+func (s *ScheduledJobService) IsPaused() bool {
+	config, err := s.configRepo.GetByKey(scheduledJobsPausedKey)
+	if err != nil {
+		log.Printf("读取定时任务暂停状态失败，按暂停处理: %v", err)
+		return true
+	}
+	if config == nil {
+		return false
+	}
+	paused, _ := strconv.ParseBool(strings.TrimSpace(config.Value))
+	return paused
+}
+
+// SetPaused 全局暂停或恢复定时任务。暂停后不再触发新的执行和重试，正在执行的批量任务继续完成
+func (s *ScheduledJobService) SetPaused(userID uint, paused bool) error {
+	config, err := s.configRepo.GetByKey(scheduledJobsPausedKey)
+	if err != nil {
+		return err
+	}
+
+	value := strconv.FormatBool(paused)
+	if config == nil {
+		err = s.configRepo.Create(&model.SystemConfig{
+			Key:         scheduledJobsPausedKey,
+			Value:       value,
+			Description: "暂停全部定时任务，暂停期间不触发新的执行",
+			Category:    "scheduled_jobs",
+			Type:        "boolean",
+		})
+	} else {
+		config.Value = value
+		err = s.configRepo.Update(config)
+	}
+	if err != nil {
+		return err
+	}
+
+	if paused {
+		s.mutex.Lock()
+		s.queued = make(map[uint]bool)
+		s.mutex.Unlock()
+		log.Printf("定时任务已全局暂停: 操作用户ID=%d", userID)
+	} else {
+		log.Printf("定时任务已恢复: 操作用户ID=%d", userID)
+	}
+	return nil
+}
+
+// getOwnedJob 获取定时任务，只能访问自己创建的任务
+func (s *ScheduledJobService) getOwnedJob(userID uint, id uint) (*model.ScheduledJob, error) {
+	job, err := s.jobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.UserID != userID {
+		return nil, ErrScheduledJobNotFound
+	}
+	return job, nil
+}
+
+// applyRequest 校验请求并写入定时任务
+func (s *ScheduledJobService) applyRequest(userID uint, job *model.ScheduledJob, req *model.ScheduledJobRequest) error {
+	schedule, err := ParseCronSchedule(req.CronExpr)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("%w: 表达式永远不会触发", ErrInvalidCronExpr)
+	}
+
+	// 命令、sudo、并发数和超时的校验与批量任务一致
+	batch, err := newBatchJob(userID, &model.BatchJobRequest{
+		Name:           req.Name,
+		Command:        req.Command,
+		Script:         req.Script,
+		Sudo:           req.Sudo,
+		SudoUser:       req.SudoUser,
+		Parallelism:    req.Parallelism,
+		TimeoutSeconds: req.TimeoutSeconds,
+	})
+	if err != nil {
+		return err
+	}
+
+	if req.CredentialID == 0 {
+		return ErrScheduledJobCredentialRequired
+	}
+	if err := s.connService.validateCredential(userID, req.CredentialID); err != nil {
+		return err
+	}
+
+	connectionIDs, err := s.validateTargets(userID, req.ConnectionIDs)
+	if err != nil {
+		return err
+	}
+
+	overlapPolicy := strings.TrimSpace(req.OverlapPolicy)
+	switch overlapPolicy {
+	case "":
+		overlapPolicy = model.OverlapPolicySkip
+	case model.OverlapPolicySkip, model.OverlapPolicyQueue:
+	default:
+		return ErrInvalidOverlapPolicy
+	}
+
+	maxRetries := req.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	if maxRetries > MaxScheduledJobRetries {
+		maxRetries = MaxScheduledJobRetries
+	}
+
+	retryDelay := time.Duration(req.RetryDelaySeconds) * time.Second
+	if retryDelay <= 0 {
+		retryDelay = DefaultScheduledJobRetryDelay
+	}
+	if retryDelay > MaxScheduledJobRetryDelay {
+		retryDelay = MaxScheduledJobRetryDelay
+	}
+
+	retentionRuns := req.RetentionRuns
+	if retentionRuns <= 0 {
+		retentionRuns = DefaultScheduledJobRetentionRuns
+	}
+	if retentionRuns > MaxScheduledJobRetentionRuns {
+		retentionRuns = MaxScheduledJobRetentionRuns
+	}
+
+	job.Name = batch.Name
+	job.CronExpr = strings.TrimSpace(req.CronExpr)
+	job.ConnectionIDs = connectionIDs
+	job.Command = batch.Command
+	job.Script = batch.Script
+	job.Sudo = batch.Sudo
+	job.SudoUser = batch.SudoUser
+	job.CredentialID = req.CredentialID
+	job.Parallelism = batch.Parallelism
+	job.TimeoutSeconds = batch.TimeoutSeconds
+	job.MaxRetries = maxRetries
+	job.RetryDelaySeconds = int(retryDelay / time.Second)
+	job.OverlapPolicy = overlapPolicy
+	job.RetentionRuns = retentionRuns
+	job.NotifyOnFailure = req.NotifyOnFailure == nil || *req.NotifyOnFailure
+	job.IsEnabled = req.IsEnabled == nil || *req.IsEnabled
+	return nil
+}
+
+// validateTargets 检查目标连接存在、用户具有connect权限且是SSH连接，返回去重后的ID
+func (s *ScheduledJobService) validateTargets(userID uint, ids []uint) ([]uint, error) {
+	connections, err := s.resolveTargets(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint, len(connections))
+	for i, conn := range connections {
+		result[i] = conn.ID
+	}
+	return result, nil
+}
+
+// resolveTargets 按创建者的权限获取目标连接，每次执行前都会重新检查，权限被收回的连接使本次执行失败
+func (s *ScheduledJobService) resolveTargets(userID uint, ids []uint) ([]*model.Connection, error) {
+	var connections []*model.Connection
+	seen := make(map[uint]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		conn, err := s.connService.AuthorizeConnection(userID, id, model.PermissionConnect)
+		if err != nil {
+			return nil, fmt.Errorf("连接%d: %w", id, err)
+		}
+		if conn.Protocol != model.ProtocolSSH {
+			return nil, fmt.Errorf("%w: %s", ErrBatchJobNotSSH, conn.Name)
+		}
+		connections = append(connections, conn)
+	}
+
+	if len(connections) == 0 {
+		return nil, ErrBatchJobNoTargets
+	}
+	if len(connections) > MaxBatchHosts {
+		return nil, ErrBatchJobTooManyHosts
+	}
+	return connections, nil
+}
+
+// setNextRun 计算已启用任务的下次执行时间
+func (s *ScheduledJobService) setNextRun(job *model.ScheduledJob) {
+	job.NextRunAt = nil
+	if !job.IsEnabled {
+		return
+	}
+	schedule, err := ParseCronSchedule(job.CronExpr)
+	if err != nil {
+		return
+	}
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		job.NextRunAt = &next
+	}
+}
+
+// isRunning 定时任务是否正在执行
+func (s *ScheduledJobService) isRunning(id uint) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.running[id]
+}
+
+// trigger 按重叠策略开始一次执行。上一次仍在执行时，skip策略跳过本次触发并返回false，
+// queue策略记录一次待执行，多次触发只补执行一次
+func (s *ScheduledJobService) trigger(job *model.ScheduledJob) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.running[job.ID] {
+		if job.OverlapPolicy != model.OverlapPolicyQueue {
+			log.Printf("定时任务上一次执行尚未结束，跳过本次触发: 任务ID=%d", job.ID)
+			return false
+		}
+		s.queued[job.ID] = true
+		log.Printf("定时任务上一次执行尚未结束，结束后补执行: 任务ID=%d", job.ID)
+		return true
+	}
+
+	s.running[job.ID] = true
+	go s.runLoop(job.ID)
+	return true
+}
+
+// runLoop 执行定时任务，执行期间有排队的触发时结束后再执行一次
+func (s *ScheduledJobService) runLoop(id uint) {
+	for {
+		s.runOnce(id)
+
+		s.mutex.Lock()
+		again := s.queued[id]
+		delete(s.queued, id)
+		if !again {
+			delete(s.running, id)
+		}
+		s.mutex.Unlock()
+		if !again {
+			return
+		}
+	}
+}
+
+// runOnce 读取最新配置执行一次并保存结果，最终失败时发送通知，然后清理超出保留数的执行记录
+func (s *ScheduledJobService) runOnce(id uint) {
+	job, err := s.jobRepo.GetByID(id)
+	if err != nil {
+		log.Printf("获取定时任务失败: 任务ID=%d, 错误: %v", id, err)
+		return
+	}
+	if job == nil {
+		return
+	}
+	if s.IsPaused() {
+		log.Printf("定时任务已全局暂停，跳过执行: 任务ID=%d", job.ID)
+		return
+	}
+
+	started := time.Now()
+	log.Printf("开始执行定时任务: 任务ID=%d, 名称=%s", job.ID, job.Name)
+	result, err := s.execute(job)
+
+	job.LastRunAt = &started
+	job.LastError = ""
+	if result != nil {
+		job.LastBatchJobID = result.ID
+		job.LastStatus = result.Status
+	}
+	if err != nil {
+		job.LastStatus = model.BatchJobStatusFailed
+		job.LastError = err.Error()
+	}
+	if err := s.jobRepo.UpdateLastRun(job); err != nil {
+		log.Printf("保存定时任务执行结果失败: %v", err)
+	}
+	log.Printf("定时任务执行结束: 任务ID=%d, 状态=%s", job.ID, job.LastStatus)
+
+	if job.LastStatus == model.BatchJobStatusFailed && job.NotifyOnFailure {
+		s.notifyFailure(job, result, err)
+	}
+
+	if err := s.batchJobs.jobRepo.DeleteByScheduledJobID(job.ID, job.RetentionRuns); err != nil {
+		log.Printf("清理定时任务执行记录失败: 任务ID=%d, 错误: %v", job.ID, err)
+	}
+}
+
+// execute 以服务账号在目标主机上执行，失败时等待后只在失败的主机上重试。
+// 返回最后一次尝试的批量任务，未能开始执行时为nil
+func (s *ScheduledJobService) execute(job *model.ScheduledJob) (*model.BatchJob, error) {
+	if err := s.connService.validateCredential(job.UserID, job.CredentialID); err != nil {
+		return nil, fmt.Errorf("服务账号凭据不可用: %w", err)
+	}
+	connections, err := s.resolveTargets(job.UserID, job.ConnectionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *model.BatchJob
+	for attempt := 1; ; attempt++ {
+		batch := &model.BatchJob{
+			UserID:         job.UserID,
+			Name:           job.Name,
+			Command:        job.Command,
+			Script:         job.Script,
+			Sudo:           job.Sudo,
+			SudoUser:       job.SudoUser,
+			Parallelism:    job.Parallelism,
+			TimeoutSeconds: job.TimeoutSeconds,
+			ScheduledJobID: job.ID,
+			CredentialID:   job.CredentialID,
+			Attempt:        attempt,
+		}
+		if result != nil {
+			batch.SourceJobID = result.ID
+		}
+
+		finished, err := s.batchJobs.runAndWait(context.Background(), batch, connections)
+		if err != nil {
+			return result, err
+		}
+		result = finished
+		if result.Status != model.BatchJobStatusFailed || attempt > job.MaxRetries {
+			return result, nil
+		}
+
+		log.Printf("定时任务执行失败，%d秒后重试: 任务ID=%d, 第%d次重试", job.RetryDelaySeconds, job.ID, attempt)
+		time.Sleep(time.Duration(job.RetryDelaySeconds) * time.Second)
+		if s.IsPaused() {
+			return result, nil
+		}
+
+		hosts, err := s.batchJobs.jobRepo.GetHosts(result.ID)
+		if err != nil {
+			return result, err
+		}
+		var failedIDs []uint
+		for _, host := range hosts {
+			if !host.Succeeded() {
+				failedIDs = append(failedIDs, host.ConnectionID)
+			}
+		}
+		if connections, err = s.resolveTargets(job.UserID, failedIDs); err != nil {
+			return result, err
+		}
+	}
+}
+
+// notifyFailure 向创建者的通知渠道和系统渠道发送失败通知
+func (s *ScheduledJobService) notifyFailure(job *model.ScheduledJob, result *model.BatchJob, err error) {
+	data := map[string]string{
+		"scheduled_job_id": strconv.FormatUint(uint64(job.ID), 10),
+		"name":             job.Name,
+	}
+
+	var message string
+	if result != nil {
+		data["batch_job_id"] = strconv.FormatUint(uint64(result.ID), 10)
+		data["failed_hosts"] = strconv.Itoa(result.FailedHosts)
+		data["total_hosts"] = strconv.Itoa(result.TotalHosts)
+		message = fmt.Sprintf("定时任务%s执行失败，共尝试%d次，最后一次在%d台主机中有%d台未成功", job.Name, result.Attempt, result.TotalHosts, result.FailedHosts)
+	}
+	if err != nil {
+		message = fmt.Sprintf("定时任务%s执行失败: %v", job.Name, err)
+	}
+
+	GetNotificationService().Publish(&model.NotificationEvent{
+		Type:     model.EventScheduledJobFailed,
+		Severity: model.SeverityWarning,
+		Title:    "定时任务执行失败",
+		Message:  message,
+		UserID:   job.UserID,
+		Data:     data,
+	})
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 加密值前缀，用于区分历史遗留的明文数据
+const secretBoxPrefix = "enc:v1:"
+
+// ErrSecretDecrypt 敏感数据解密失败
+var ErrSecretDecrypt = errors.New("敏感数据解密失败")
+
+// SecretBox 使用AES-256-GCM加密存储在数据库中的敏感数据（私钥、口令等）
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox 从keyPath加载主密钥创建加密器，文件不存在时自动生成
+func NewSecretBox(keyPath string) (*SecretBox, error) {
+	key, err := loadOrCreateSecretKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密器失败: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密器失败: %w", err)
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+// loadOrCreateSecretKey 读取base64编码的32字节主密钥
+func loadOrCreateSecretKey(keyPath string) ([]byte, error) {
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("主密钥文件%s格式无效，应为base64编码的32字节密钥", keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取主密钥失败: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("生成主密钥失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return nil, fmt.Errorf("创建主密钥目录失败: %w", err)
+	}
+	if err := os.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("保存主密钥失败: %w", err)
+	}
+
+	log.Printf("已生成新的数据加密主密钥: %s，请妥善备份", keyPath)
+	return key, nil
+}
+
+// Encrypt 加密字符串，空字符串原样返回
+func (b *SecretBox) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretBoxPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密字符串，未加密的历史数据原样返回
+func (b *SecretBox) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, secretBoxPrefix) {
+		return value, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretBoxPrefix))
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrSecretDecrypt
+	}
+
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrSecretDecrypt
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,495 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+const (
+	defaultSnippetSearchLimit = 20
+	maxSnippetSearchLimit     = 100
+	maxSnippetContentLength   = 64 * 1024
+)
+
+var (
+	// ErrInvalidSnippet 命令片段名称、内容或范围不符合要求
+	ErrInvalidSnippet = errors.New("命令片段无效")
+
+	// ErrSnippetNotFound 命令片段不存在
+	ErrSnippetNotFound = errors.New("命令片段不存在")
+
+	// ErrSnippetForbidden 无权修改命令片段
+	ErrSnippetForbidden = errors.New("无权修改此命令片段")
+
+	// ErrInvalidSnippetScope 无效的命令片段范围
+	ErrInvalidSnippetScope = errors.New("无效的命令片段范围，仅支持user、team、global")
+
+	// ErrInvalidSnippetParameter 命令片段参数定义无效
+	ErrInvalidSnippetParameter = errors.New("命令片段参数定义无效")
+
+	// ErrInvalidSnippetArgument 展开命令片段时传入的参数无效
+	ErrInvalidSnippetArgument = errors.New("命令片段参数值无效")
+)
+
+var (
+	// snippetPlaceholderPattern 内容中的{{name}}占位符，花括号内允许空格
+	snippetPlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	snippetParamNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// shellSafeArgPattern 只包含这些字符的参数值不需要引号，保留~开头路径的展开
+	shellSafeArgPattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./~-]+$`)
+)
+
+// SnippetService 命令片段服务，管理个人、团队和全局片段，并将片段展开为终端输入或批量任务
+type SnippetService struct {
+	snippetRepo model.SnippetRepository
+	userRepo    model.UserRepository
+	roles       *RoleService
+	teams       *TeamService
+	batchJobs   *BatchJobService
+}
+
+// NewSnippetService 创建命令片段服务实例
+func NewSnippetService(snippetRepo model.SnippetRepository, userRepo model.UserRepository, roles *RoleService, teams *TeamService, batchJobs *BatchJobService) *SnippetService {
+	return &SnippetService{
+		snippetRepo: snippetRepo,
+		userRepo:    userRepo,
+		roles:       roles,
+		teams:       teams,
+		batchJobs:   batchJobs,
+	}
+}
+
+// CreateSnippet 创建命令片段
+func (s *SnippetService) CreateSnippet(userID uint, req *model.SnippetRequest) (*model.Snippet, error) {
+	snippet := &model.Snippet{OwnerID: userID}
+	if err := s.applyRequest(userID, snippet, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.snippetRepo.Create(snippet); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已创建命令片段: ID=%d, 范围=%s, 用户ID=%d", snippet.ID, snippet.Scope, userID)
+	return snippet, nil
+}
+
+// UpdateSnippet 更新命令片段，不修改使用统计
+func (s *SnippetService) UpdateSnippet(userID uint, id uint, req *model.SnippetRequest) (*model.Snippet, error) {
+	snippet, err := s.getEditableSnippet(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(userID, snippet, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.snippetRepo.Update(snippet); err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// DeleteSnippet 删除命令片段
+func (s *SnippetService) DeleteSnippet(userID uint, id uint) error {
+	snippet, err := s.getEditableSnippet(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.snippetRepo.Delete(snippet.ID)
+}
+
+// GetSnippet 获取用户可见的命令片段
+func (s *SnippetService) GetSnippet(userID uint, id uint) (*model.Snippet, error) {
+	snippet, err := s.snippetRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if snippet == nil || !s.canAccess(userID, snippet) {
+		return nil, ErrSnippetNotFound
+	}
+	return snippet, nil
+}
+
+// ListSnippets 获取用户可见的命令片段，query不为空时全文搜索
+func (s *SnippetService) ListSnippets(userID uint, query string, limit int) ([]*model.Snippet, error) {
+	teamIDs, err := s.userTeamIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return s.snippetRepo.GetVisible(userID, teamIDs)
+	}
+
+	if limit <= 0 {
+		limit = defaultSnippetSearchLimit
+	}
+	if limit > maxSnippetSearchLimit {
+		limit = maxSnippetSearchLimit
+	}
+	return s.snippetRepo.Search(userID, teamIDs, query, limit)
+}
+
+// ExpandSnippet 按参数展开命令片段，用于预览，不计入使用次数
+func (s *SnippetService) ExpandSnippet(userID uint, id uint, args map[string]interface{}) (*model.SnippetExpansion, error) {
+	snippet, err := s.GetSnippet(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := expandSnippet(snippet, args)
+	if err != nil {
+		return nil, err
+	}
+	return &model.SnippetExpansion{SnippetID: snippet.ID, Command: command}, nil
+}
+
+// UseSnippet 展开命令片段并计入使用次数，供注入终端时调用
+func (s *SnippetService) UseSnippet(userID uint, id uint, args map[string]interface{}) (*model.SnippetExpansion, error) {
+	expansion, err := s.ExpandSnippet(userID, id, args)
+	if err != nil {
+		return nil, err
+	}
+	s.recordUsage(expansion.SnippetID)
+	return expansion, nil
+}
+
+// RunSnippet 展开命令片段并作为批量任务在目标主机上执行，多行片段按脚本执行
+func (s *SnippetService) RunSnippet(userID uint, id uint, req *model.SnippetBatchRequest) (*model.BatchJobDetail, error) {
+	snippet, err := s.GetSnippet(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := expandSnippet(snippet, req.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	jobReq := &model.BatchJobRequest{
+		Name:           req.Name,
+		ConnectionIDs:  req.ConnectionIDs,
+		FolderID:       req.FolderID,
+		Tag:            req.Tag,
+		Sudo:           req.Sudo,
+		SudoUser:       req.SudoUser,
+		Parallelism:    req.Parallelism,
+		TimeoutSeconds: req.TimeoutSeconds,
+	}
+	if jobReq.Name == "" {
+		jobReq.Name = snippet.Name
+	}
+	if strings.Contains(command, "\n") {
+		jobReq.Script = command
+	} else {
+		jobReq.Command = command
+	}
+
+	detail, err := s.batchJobs.CreateJob(userID, jobReq)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordUsage(snippet.ID)
+	log.Printf("命令片段已作为批量任务执行: 片段ID=%d, 任务ID=%d, 用户ID=%d", snippet.ID, detail.ID, userID)
+	return detail, nil
+}
+
+// applyRequest 校验请求并写入片段，包括范围权限、参数定义和占位符
+func (s *SnippetService) applyRequest(userID uint, snippet *model.Snippet, req *model.SnippetRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: 名称不能为空", ErrInvalidSnippet)
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return fmt.Errorf("%w: 内容不能为空", ErrInvalidSnippet)
+	}
+	if len(req.Content) > maxSnippetContentLength {
+		return fmt.Errorf("%w: 内容不能超过%dKB", ErrInvalidSnippet, maxSnippetContentLength/1024)
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = model.SnippetScopeUser
+	}
+	teamID, err := s.validateScope(userID, scope, req.TeamID)
+	if err != nil {
+		return err
+	}
+
+	parameters := req.Parameters
+	if parameters == nil {
+		parameters = []model.SnippetParameter{}
+	}
+	if err := validateSnippetParameters(req.Content, parameters); err != nil {
+		return err
+	}
+
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(req.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	snippet.Name = name
+	snippet.Description = strings.TrimSpace(req.Description)
+	snippet.Content = strings.ReplaceAll(req.Content, "\r\n", "\n")
+	snippet.Parameters = parameters
+	snippet.Scope = scope
+	snippet.TeamID = teamID
+	snippet.Tags = strings.Join(tags, ",")
+	return nil
+}
+
+// validateScope 校验用户可以在该范围创建片段，返回片段所属团队ID
+func (s *SnippetService) validateScope(userID uint, scope string, teamID uint) (uint, error) {
+	switch scope {
+	case model.SnippetScopeUser:
+		return 0, nil
+	case model.SnippetScopeTeam:
+		if teamID == 0 {
+			return 0, fmt.Errorf("%w: 团队片段必须指定团队", ErrInvalidSnippet)
+		}
+		if s.teams == nil {
+			return 0, ErrTeamNotFound
+		}
+		member, err := s.teams.IsMember(userID, teamID)
+		if err != nil {
+			return 0, err
+		}
+		if !member {
+			return 0, ErrTeamNotFound
+		}
+		return teamID, nil
+	case model.SnippetScopeGlobal:
+		allowed, err := s.hasPermission(userID, model.PermSnippetsManageGlobal)
+		if err != nil {
+			return 0, err
+		}
+		if !allowed {
+			return 0, ErrSnippetForbidden
+		}
+		return 0, nil
+	default:
+		return 0, ErrInvalidSnippetScope
+	}
+}
+
+// getEditableSnippet 获取用户可以修改的片段：个人和团队片段只有创建者可以修改，
+// 全局片段由拥有snippets.manage_global权限的用户维护
+func (s *SnippetService) getEditableSnippet(userID uint, id uint) (*model.Snippet, error) {
+	snippet, err := s.GetSnippet(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if snippet.Scope == model.SnippetScopeGlobal {
+		allowed, err := s.hasPermission(userID, model.PermSnippetsManageGlobal)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			return snippet, nil
+		}
+	}
+	if snippet.OwnerID != userID {
+		return nil, ErrSnippetForbidden
+	}
+	return snippet, nil
+}
+
+// canAccess 检查用户是否可以使用片段，全局片段对所有用户可见，团队片段对团队成员可见
+func (s *SnippetService) canAccess(userID uint, snippet *model.Snippet) bool {
+	if snippet.OwnerID == userID || snippet.Scope == model.SnippetScopeGlobal {
+		return true
+	}
+	if snippet.Scope != model.SnippetScopeTeam || snippet.TeamID == 0 || s.teams == nil {
+		return false
+	}
+
+	member, err := s.teams.IsMember(userID, snippet.TeamID)
+	if err != nil {
+		log.Printf("检查团队成员失败: 团队ID=%d, 用户ID=%d, 错误: %v", snippet.TeamID, userID, err)
+		return false
+	}
+	return member
+}
+
+// userTeamIDs 获取用户所属团队，用于筛选可见的团队片段
+func (s *SnippetService) userTeamIDs(userID uint) ([]uint, error) {
+	if s.teams == nil {
+		return nil, nil
+	}
+	teamIDs, err := s.teams.GetUserTeamIDs(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户团队失败: %w", err)
+	}
+	return teamIDs, nil
+}
+
+// hasPermission 按用户当前角色检查系统权限
+func (s *SnippetService) hasPermission(userID uint, permission string) (bool, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return false, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user == nil {
+		return false, nil
+	}
+	return s.roles.HasPermission(user.Role, 0, permission)
+}
+
+// recordUsage 记录片段使用次数，失败不影响本次使用
+func (s *SnippetService) recordUsage(id uint) {
+	if err := s.snippetRepo.IncrementUsage(id); err != nil {
+		log.Printf("记录命令片段使用次数失败: ID=%d, 错误: %v", id, err)
+	}
+}
+
+// validateSnippetParameters 校验参数定义，内容中的每个占位符都必须有对应的参数
+func validateSnippetParameters(content string, parameters []model.SnippetParameter) error {
+	defined := make(map[string]bool, len(parameters))
+	for i := range parameters {
+		param := &parameters[i]
+		if !snippetParamNamePattern.MatchString(param.Name) {
+			return fmt.Errorf("%w: 参数名%q只能包含字母、数字和下划线，且不能以数字开头", ErrInvalidSnippetParameter, param.Name)
+		}
+		if defined[param.Name] {
+			return fmt.Errorf("%w: 参数%s重复定义", ErrInvalidSnippetParameter, param.Name)
+		}
+		defined[param.Name] = true
+
+		if param.Type == "" {
+			param.Type = model.SnippetParamString
+		}
+		switch param.Type {
+		case model.SnippetParamString, model.SnippetParamNumber, model.SnippetParamBoolean:
+			param.Options = nil
+		case model.SnippetParamEnum:
+			if len(param.Options) == 0 {
+				return fmt.Errorf("%w: 枚举参数%s缺少可选值", ErrInvalidSnippetParameter, param.Name)
+			}
+		default:
+			return fmt.Errorf("%w: 参数%s的类型%q无效，仅支持string、number、boolean、enum", ErrInvalidSnippetParameter, param.Name, param.Type)
+		}
+
+		if param.Default != "" {
+			value, err := normalizeSnippetArgument(param, param.Default)
+			if err != nil {
+				return fmt.Errorf("%w: 参数%s的默认值: %v", ErrInvalidSnippetParameter, param.Name, err)
+			}
+			param.Default = value
+		}
+	}
+
+	for _, match := range snippetPlaceholderPattern.FindAllStringSubmatch(content, -1) {
+		if !defined[match[1]] {
+			return fmt.Errorf("%w: 占位符{{%s}}没有对应的参数定义", ErrInvalidSnippetParameter, match[1])
+		}
+	}
+	return nil
+}
+
+// expandSnippet 用参数值替换片段中的占位符。字符串和枚举值中含有shell特殊字符时使用单引号包裹，
+// 防止参数值被当作命令执行
+func expandSnippet(snippet *model.Snippet, args map[string]interface{}) (string, error) {
+	params := make(map[string]*model.SnippetParameter, len(snippet.Parameters))
+	for i := range snippet.Parameters {
+		params[snippet.Parameters[i].Name] = &snippet.Parameters[i]
+	}
+	for name := range args {
+		if params[name] == nil {
+			return "", fmt.Errorf("%w: 未定义的参数%s", ErrInvalidSnippetArgument, name)
+		}
+	}
+
+	values := make(map[string]string, len(params))
+	for name, param := range params {
+		raw, err := snippetArgumentString(args[name])
+		if err != nil {
+			return "", fmt.Errorf("%w: 参数%s%v", ErrInvalidSnippetArgument, name, err)
+		}
+		if raw == "" {
+			raw = param.Default
+		}
+		if raw == "" {
+			if param.Required {
+				return "", fmt.Errorf("%w: 缺少必填参数%s", ErrInvalidSnippetArgument, name)
+			}
+			values[name] = ""
+			continue
+		}
+
+		value, err := normalizeSnippetArgument(param, raw)
+		if err != nil {
+			return "", fmt.Errorf("%w: 参数%s%v", ErrInvalidSnippetArgument, name, err)
+		}
+		if !shellSafeArgPattern.MatchString(value) {
+			value = shellQuote(value)
+		}
+		values[name] = value
+	}
+
+	return snippetPlaceholderPattern.ReplaceAllStringFunc(snippet.Content, func(placeholder string) string {
+		return values[snippetPlaceholderPattern.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// snippetArgumentString 把JSON中的参数值转换为字符串，未传入时返回空字符串
+func snippetArgumentString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", errors.New("只能是字符串、数字或布尔值")
+	}
+}
+
+// normalizeSnippetArgument 按参数类型校验并规范化参数值
+func normalizeSnippetArgument(param *model.SnippetParameter, value string) (string, error) {
+	switch param.Type {
+	case model.SnippetParamNumber:
+		value = strings.TrimSpace(value)
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return "", fmt.Errorf("不是有效的数字: %q", value)
+		}
+		return value, nil
+	case model.SnippetParamBoolean:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "1", "yes":
+			return "true", nil
+		case "false", "0", "no":
+			return "false", nil
+		}
+		return "", fmt.Errorf("不是有效的布尔值: %q", value)
+	case model.SnippetParamEnum:
+		for _, option := range param.Options {
+			if value == option {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("必须是%s之一", strings.Join(param.Options, "、"))
+	default:
+		if strings.ContainsAny(value, "\r\n") {
+			return "", errors.New("不能包含换行")
+		}
+		return value, nil
+	}
+}
@@ -214,6 +214,28 @@ func (d *SpecialCommandDetector) extractOptions(output string, commandType Speci
 	return options
 }
 
+// DetectAuthPrompt 将SSH认证阶段的提示（keyboard-interactive、私钥口令等）转换为特殊命令信息
+func (d *SpecialCommandDetector) DetectAuthPrompt(instruction, question string, echo bool) *SpecialCommandInfo {
+	info := d.DetectSpecialCommand(question)
+	if info.Type == SpecialCommandNormal {
+		info = &SpecialCommandInfo{
+			Type:        SpecialCommandLogin,
+			Prompt:      strings.TrimSpace(question),
+			Description: "SSH交互式认证",
+		}
+	}
+
+	// 认证提示总是期待输入，是否掩码由服务端的echo标志决定
+	info.Context = strings.TrimSpace(instruction)
+	info.Masked = !echo
+	info.ExpectInput = true
+	if info.Timeout < 60 {
+		info.Timeout = 60
+	}
+
+	return info
+}
+
 // IsPasswordPrompt 快速检查是否是密码提示
 func (d *SpecialCommandDetector) IsPasswordPrompt(output string) bool {
 	info := d.DetectSpecialCommand(output)
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrAuthPromptUnavailable 无法向用户转发认证提示
+	ErrAuthPromptUnavailable = errors.New("当前连接不支持交互式认证提示")
+
+	// ErrPassphraseRequired 私钥已加密但未提供口令
+	ErrPassphraseRequired = errors.New("SSH私钥已加密，需要提供口令")
+)
+
+// SSHAuthPrompter SSH认证提示转发接口，由WebSocket层实现
+type SSHAuthPrompter interface {
+	// Prompt 向用户展示认证提示并返回用户输入
+	Prompt(info *SpecialCommandInfo) (string, error)
+}
+
+// SSHAuthOptions SSH会话认证的附加参数
+type SSHAuthOptions struct {
+	Prompter    SSHAuthPrompter // 交互式认证提示转发，可以为nil
+	Certificate ssh.Signer      // SSH CA签发的短期证书，可以为nil
+}
+
+// dialSSHClient 按连接配置的认证顺序建立SSH客户端连接
+func dialSSHClient(conn *model.Connection, authOptions *SSHAuthOptions) (*ssh.Client, error) {
+	// 按连接配置的顺序准备认证方式
+	authMethods, err := buildSSHAuthMethods(conn, authOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// 准备SSH配置
+	config := &ssh.ClientConfig{
+		User:    conn.Username,
+		Auth:    authMethods,
+		Timeout: time.Second * 10,
+	}
+	GetHostKeyService().applyTo(config, conn)
+
+	addr := net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port))
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, fmt.Errorf("SSH连接失败: %w", err)
+	}
+
+	return client, nil
+}
+
+// sshAuthBuilder 根据连接配置构建有序的SSH认证方式
+type sshAuthBuilder struct {
+	conn     *model.Connection
+	prompter SSHAuthPrompter
+	detector *SpecialCommandDetector
+	// 存储的密码只自动应答一次，避免服务端反复询问时死循环
+	passwordUsed bool
+}
+
+// buildSSHAuthMethods 按连接配置的顺序构建SSH认证方式，options可以为nil
+func buildSSHAuthMethods(conn *model.Connection, options *SSHAuthOptions) ([]ssh.AuthMethod, error) {
+	if options == nil {
+		options = &SSHAuthOptions{}
+	}
+	prompter := options.Prompter
+
+	builder := &sshAuthBuilder{
+		conn:     conn,
+		prompter: prompter,
+		detector: NewSpecialCommandDetector(),
+	}
+
+	var authMethods []ssh.AuthMethod
+	for _, method := range conn.GetAuthMethods() {
+		switch method {
+		case model.AuthMethodCertificate:
+			if options.Certificate != nil {
+				authMethods = append(authMethods, ssh.PublicKeys(options.Certificate))
+			}
+		case model.AuthMethodPublicKey:
+			if conn.PrivateKey == "" {
+				continue
+			}
+			signer, err := builder.parsePrivateKey()
+			if err != nil {
+				return nil, err
+			}
+			authMethods = append(authMethods, ssh.PublicKeys(signer))
+		case model.AuthMethodPassword:
+			if conn.Password != "" {
+				authMethods = append(authMethods, ssh.Password(conn.Password))
+			} else if prompter != nil {
+				authMethods = append(authMethods, ssh.PasswordCallback(builder.promptPassword))
+			}
+		case model.AuthMethodKeyboardInteractive:
+			if conn.Password != "" || prompter != nil {
+				authMethods = append(authMethods, ssh.KeyboardInteractive(builder.keyboardInteractive))
+			}
+		}
+	}
+
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf("未提供SSH认证方式（密码、私钥或证书）")
+	}
+
+	return authMethods, nil
+}
+
+// parsePrivateKey 解析私钥，加密私钥优先使用存储的口令，否则向用户询问
+func (b *sshAuthBuilder) parsePrivateKey() (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey([]byte(b.conn.PrivateKey))
+	if err == nil {
+		return signer, nil
+	}
+
+	var missingErr *ssh.PassphraseMissingError
+	if !errors.As(err, &missingErr) {
+		return nil, fmt.Errorf("解析SSH私钥失败: %w", err)
+	}
+
+	passphrase := b.conn.KeyPassphrase
+	if passphrase == "" {
+		if b.prompter == nil {
+			return nil, ErrPassphraseRequired
+		}
+		log.Printf("SSH私钥已加密，向用户请求口令: 连接ID=%d", b.conn.ID)
+		info := b.detector.DetectAuthPrompt("", "Enter passphrase for key: ", false)
+		info.Description = "SSH私钥口令"
+		passphrase, err = b.prompter.Prompt(info)
+		if err != nil {
+			return nil, fmt.Errorf("获取私钥口令失败: %w", err)
+		}
+	}
+
+	signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(b.conn.PrivateKey), []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("解析SSH私钥失败: %w", err)
+	}
+	return signer, nil
+}
+
+// promptPassword 未存储密码时向用户询问密码
+func (b *sshAuthBuilder) promptPassword() (string, error) {
+	question := fmt.Sprintf("%s@%s's password: ", b.conn.Username, b.conn.Host)
+	return b.prompter.Prompt(b.detector.DetectAuthPrompt("", question, false))
+}
+
+// keyboardInteractive 处理keyboard-interactive认证挑战（如PAM+OTP）
+func (b *sshAuthBuilder) keyboardInteractive(name, instruction string, questions []string, echos []bool) ([]string, error) {
+	answers := make([]string, len(questions))
+	for i, question := range questions {
+		echo := i < len(echos) && echos[i]
+
+		// 对于无回显的密码类提示，优先使用存储的密码
+		if !echo && b.conn.Password != "" && !b.passwordUsed && b.detector.IsPasswordPrompt(question) {
+			answers[i] = b.conn.Password
+			b.passwordUsed = true
+			continue
+		}
+
+		if b.prompter == nil {
+			return nil, ErrAuthPromptUnavailable
+		}
+
+		info := b.detector.DetectAuthPrompt(strings.TrimSpace(name+"\n"+instruction), question, echo)
+		answer, err := b.prompter.Prompt(info)
+		if err != nil {
+			return nil, fmt.Errorf("获取认证输入失败: %w", err)
+		}
+		answers[i] = answer
+	}
+
+	return answers, nil
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrSSHCANotAvailable SSH CA未初始化
+	ErrSSHCANotAvailable = errors.New("SSH证书颁发机构不可用")
+
+	// ErrNoCertPrincipals 无法确定证书principals
+	ErrNoCertPrincipals = errors.New("无法确定SSH证书principals")
+)
+
+// 证书签发前后允许的时钟偏差
+const sshCertClockSkew = 30 * time.Second
+
+// SSHCAService SSH证书颁发机构服务，为会话签发短期用户证书
+type SSHCAService struct {
+	certRepo   model.SSHCertificateRepository
+	userRepo   model.UserRepository
+	keyPath    string
+	certTTL    time.Duration
+	rolePrefix string
+	// rolePrincipals 管理员配置的角色到登录名映射
+	rolePrincipals map[string][]string
+
+	signer ssh.Signer
+	mutex  sync.Mutex
+}
+
+// NewSSHCAService 创建SSH CA服务实例，keyPath处的CA私钥不存在时会在首次使用时生成。
+// rolePrincipals为角色到目标主机登录名的映射，是证书中登录名principals的唯一来源
+func NewSSHCAService(certRepo model.SSHCertificateRepository, userRepo model.UserRepository, keyPath string, certTTL time.Duration, rolePrefix string, rolePrincipals map[string][]string) *SSHCAService {
+	if certTTL <= 0 {
+		certTTL = 5 * time.Minute
+	}
+	return &SSHCAService{
+		certRepo:       certRepo,
+		userRepo:       userRepo,
+		keyPath:        keyPath,
+		certTTL:        certTTL,
+		rolePrefix:     rolePrefix,
+		rolePrincipals: rolePrincipals,
+	}
+}
+
+// getSigner 加载或生成CA私钥
+func (s *SSHCAService) getSigner() (ssh.Signer, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.signer != nil {
+		return s.signer, nil
+	}
+
+	keyData, err := os.ReadFile(s.keyPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取SSH CA私钥失败: %w", err)
+		}
+		keyData, err = s.generateKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("解析SSH CA私钥失败: %w", err)
+	}
+
+	s.signer = signer
+	log.Printf("SSH CA已加载: 算法=%s, 指纹=%s", signer.PublicKey().Type(), ssh.FingerprintSHA256(signer.PublicKey()))
+	return signer, nil
+}
+
+// generateKey 生成新的ed25519 CA私钥并写入文件
+func (s *SSHCAService) generateKey() ([]byte, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成SSH CA私钥失败: %w", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, "mini-web ssh ca")
+	if err != nil {
+		return nil, fmt.Errorf("编码SSH CA私钥失败: %w", err)
+	}
+	keyData := pem.EncodeToMemory(block)
+
+	if err := os.MkdirAll(filepath.Dir(s.keyPath), 0700); err != nil {
+		return nil, fmt.Errorf("创建SSH CA私钥目录失败: %w", err)
+	}
+	if err := os.WriteFile(s.keyPath, keyData, 0600); err != nil {
+		return nil, fmt.Errorf("保存SSH CA私钥失败: %w", err)
+	}
+
+	log.Printf("已生成新的SSH CA私钥: %s", s.keyPath)
+	return keyData, nil
+}
+
+// GetPublicKey 获取CA公钥（authorized_keys格式），用于配置sshd的TrustedUserCAKeys
+func (s *SSHCAService) GetPublicKey() (string, string, error) {
+	signer, err := s.getSigner()
+	if err != nil {
+		return "", "", err
+	}
+
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	return publicKey + " mini-web-ca", ssh.FingerprintSHA256(signer.PublicKey()), nil
+}
+
+// CertPrincipals 根据用户角色确定证书principals。
+// 登录名只来自管理员配置的角色映射，连接配置的principals只能在其中进一步收窄，不能扩大
+func (s *SSHCAService) CertPrincipals(user *model.User, conn *model.Connection) []string {
+	var principals []string
+	seen := make(map[string]bool)
+	add := func(principal string) {
+		if principal != "" && !seen[principal] {
+			seen[principal] = true
+			principals = append(principals, principal)
+		}
+	}
+
+	if user.Role == "" {
+		return nil
+	}
+
+	// 角色principal，可在sshd的AuthorizedPrincipalsFile中按角色授权
+	add(s.rolePrefix + user.Role)
+
+	// 连接限定的principals，未配置时使用角色映射的全部登录名
+	requested := make(map[string]bool)
+	for _, principal := range conn.GetCertPrincipals() {
+		requested[principal] = true
+	}
+	for _, principal := range s.rolePrincipals[user.Role] {
+		if len(requested) == 0 || requested[principal] {
+			add(principal)
+		}
+	}
+
+	return principals
+}
+
+// IssueSessionCertificate 为一次会话生成临时密钥对并签发短期用户证书
+func (s *SSHCAService) IssueSessionCertificate(userID uint, conn *model.Connection) (ssh.Signer, error) {
+	caSigner, err := s.getSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	principals := s.CertPrincipals(user, conn)
+	if len(principals) == 0 {
+		return nil, ErrNoCertPrincipals
+	}
+
+	// 临时密钥对只存在于内存中，会话结束即丢弃
+	_, ephemeralKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成临时密钥对失败: %w", err)
+	}
+	ephemeralSigner, err := ssh.NewSignerFromKey(ephemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("创建临时密钥签名器失败: %w", err)
+	}
+
+	serial, err := newCertSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	validAfter := now.Add(-sshCertClockSkew)
+	validBefore := now.Add(s.certTTL)
+	keyID := fmt.Sprintf("mini-web:%s:conn-%d:%d", user.Username, conn.ID, serial)
+
+	cert := &ssh.Certificate{
+		Key:             ephemeralSigner.PublicKey(),
+		Serial:          serial,
+		CertType:        ssh.UserCert,
+		KeyId:           keyID,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-pty":     "",
+				"permit-user-rc": "",
+			},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		return nil, fmt.Errorf("签发SSH证书失败: %w", err)
+	}
+
+	certSigner, err := ssh.NewCertSigner(cert, ephemeralSigner)
+	if err != nil {
+		return nil, fmt.Errorf("创建证书签名器失败: %w", err)
+	}
+
+	record := &model.SSHCertificate{
+		Serial:       serial,
+		KeyID:        keyID,
+		UserID:       user.ID,
+		Username:     user.Username,
+		ConnectionID: conn.ID,
+		Principals:   strings.Join(principals, ","),
+		Fingerprint:  ssh.FingerprintSHA256(ephemeralSigner.PublicKey()),
+		ValidAfter:   validAfter,
+		ValidBefore:  validBefore,
+	}
+	if err := s.certRepo.Create(record); err != nil {
+		// 无法留存签发记录时不使用该证书
+		return nil, err
+	}
+
+	log.Printf("已签发SSH用户证书: 序列号=%d, 用户=%s, 连接ID=%d, principals=%v, 有效期至=%s",
+		serial, user.Username, conn.ID, principals, validBefore.Format(time.RFC3339))
+
+	return certSigner, nil
+}
+
+// GetIssuanceLog 分页获取证书签发记录
+func (s *SSHCAService) GetIssuanceLog(limit, offset int) ([]*model.SSHCertificate, int, error) {
+	certs, err := s.certRepo.GetAll(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.certRepo.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return certs, total, nil
+}
+
+// GetCertificateBySerial 根据序列号查询签发记录
+func (s *SSHCAService) GetCertificateBySerial(serial uint64) (*model.SSHCertificate, error) {
+	return s.certRepo.GetBySerial(serial)
+}
+
+// newCertSerial 生成随机证书序列号，保持在int64范围内以便存入SQLite
+func newCertSerial() (uint64, error) {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return 0, fmt.Errorf("生成证书序列号失败: %w", err)
+	}
+	serial := binary.BigEndian.Uint64(buf[:]) &^ (1 << 63)
+	if serial == 0 {
+		serial = 1
+	}
+	return serial, nil
+}
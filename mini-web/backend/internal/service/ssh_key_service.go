@@ -0,0 +1,504 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrSSHKeyNotFound SSH密钥不存在
+	ErrSSHKeyNotFound = errors.New("SSH密钥不存在")
+
+	// ErrSSHKeyInUse SSH密钥仍被连接使用
+	ErrSSHKeyInUse = errors.New("SSH密钥仍被连接使用，请先解除关联")
+
+	// ErrInvalidSSHKeyType 无效的SSH密钥类型
+	ErrInvalidSSHKeyType = errors.New("无效的SSH密钥类型，仅支持ed25519和rsa")
+
+	// ErrInvalidSSHKeyBits 无效的RSA密钥长度
+	ErrInvalidSSHKeyBits = errors.New("无效的RSA密钥长度，仅支持2048、3072、4096")
+
+	// ErrSSHKeyRotationFailed 轮换时部分主机部署失败
+	ErrSSHKeyRotationFailed = errors.New("部分主机部署新公钥失败，已回滚本次轮换")
+)
+
+// authorized_keys文件路径，使用相对登录用户主目录的路径
+const authorizedKeysPath = "~/.ssh/authorized_keys"
+
+// SSHKeyService SSH密钥管理服务
+type SSHKeyService struct {
+	keyRepo     model.SSHKeyRepository
+	connRepo    model.ConnectionRepository
+	access      *AccessService
+	secretBox   *SecretBox
+	credentials *CredentialService
+	// 同一时间只允许一个轮换操作，避免并发修改authorized_keys
+	rotateMutex sync.Mutex
+}
+
+// NewSSHKeyService 创建SSH密钥管理服务实例
+func NewSSHKeyService(keyRepo model.SSHKeyRepository, connRepo model.ConnectionRepository, access *AccessService, secretBox *SecretBox, credentials *CredentialService) *SSHKeyService {
+	return &SSHKeyService{
+		keyRepo:     keyRepo,
+		connRepo:    connRepo,
+		access:      access,
+		secretBox:   secretBox,
+		credentials: credentials,
+	}
+}
+
+// GenerateKey 在服务端生成密钥对并加密保存私钥
+func (s *SSHKeyService) GenerateKey(userID uint, req *model.SSHKeyRequest) (*model.SSHKey, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("密钥名称不能为空")
+	}
+
+	keyType := req.KeyType
+	if keyType == "" {
+		keyType = model.SSHKeyTypeED25519
+	}
+
+	comment := sanitizeKeyComment(req.Comment)
+	if comment == "" {
+		comment = "mini-web:" + sanitizeKeyComment(req.Name)
+	}
+
+	key := &model.SSHKey{
+		Name:      req.Name,
+		KeyType:   keyType,
+		Bits:      req.Bits,
+		Comment:   comment,
+		CreatedBy: userID,
+	}
+	if err := s.fillKeyPair(key); err != nil {
+		return nil, err
+	}
+
+	if err := s.keyRepo.Create(key); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已生成SSH密钥: ID=%d, 类型=%s, 指纹=%s, 用户ID=%d", key.ID, key.KeyType, key.Fingerprint, userID)
+	return key, nil
+}
+
+// fillKeyPair 按key的类型生成新的密钥对，私钥加密后写入key
+func (s *SSHKeyService) fillKeyPair(key *model.SSHKey) error {
+	var privateKey interface{}
+
+	switch key.KeyType {
+	case model.SSHKeyTypeED25519:
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("生成ed25519密钥失败: %w", err)
+		}
+		privateKey = edKey
+		key.Bits = 256
+	case model.SSHKeyTypeRSA:
+		if key.Bits == 0 {
+			key.Bits = 4096
+		}
+		if key.Bits != 2048 && key.Bits != 3072 && key.Bits != 4096 {
+			return ErrInvalidSSHKeyBits
+		}
+		rsaKey, err := rsa.GenerateKey(rand.Reader, key.Bits)
+		if err != nil {
+			return fmt.Errorf("生成RSA密钥失败: %w", err)
+		}
+		privateKey = rsaKey
+	default:
+		return ErrInvalidSSHKeyType
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, key.Comment)
+	if err != nil {
+		return fmt.Errorf("编码私钥失败: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("解析私钥失败: %w", err)
+	}
+
+	encrypted, err := s.secretBox.Encrypt(string(pem.EncodeToMemory(block)))
+	if err != nil {
+		return fmt.Errorf("加密私钥失败: %w", err)
+	}
+
+	key.PrivateKey = encrypted
+	key.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " " + key.Comment
+	key.Fingerprint = ssh.FingerprintSHA256(signer.PublicKey())
+	return nil
+}
+
+// GetUserKeys 获取用户的所有SSH密钥
+func (s *SSHKeyService) GetUserKeys(userID uint) ([]*model.SSHKey, error) {
+	keys, err := s.keyRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取SSH密钥列表失败: %w", err)
+	}
+	return keys, nil
+}
+
+// GetKey 获取用户的SSH密钥
+func (s *SSHKeyService) GetKey(userID uint, id uint) (*model.SSHKey, error) {
+	key, err := s.keyRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.CreatedBy != userID {
+		return nil, ErrSSHKeyNotFound
+	}
+	return key, nil
+}
+
+// DeleteKey 删除SSH密钥，仍被连接使用时拒绝删除
+func (s *SSHKeyService) DeleteKey(userID uint, id uint) error {
+	if _, err := s.GetKey(userID, id); err != nil {
+		return err
+	}
+
+	connections, err := s.connRepo.GetBySSHKeyID(id)
+	if err != nil {
+		return fmt.Errorf("查询关联连接失败: %w", err)
+	}
+	if len(connections) > 0 {
+		return ErrSSHKeyInUse
+	}
+
+	return s.keyRepo.Delete(id)
+}
+
+// GetLinkedConnections 获取关联了该密钥的连接
+func (s *SSHKeyService) GetLinkedConnections(userID uint, id uint) ([]*model.Connection, error) {
+	if _, err := s.GetKey(userID, id); err != nil {
+		return nil, err
+	}
+
+	connections, err := s.connRepo.GetBySSHKeyID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询关联连接失败: %w", err)
+	}
+	return connections, nil
+}
+
+// resolveConnection 解析连接引用的凭据和关联的密钥，用于登录关联主机
+func (s *SSHKeyService) resolveConnection(conn *model.Connection) error {
+	if s.credentials != nil {
+		if err := s.credentials.ResolveConnection(conn); err != nil {
+			return err
+		}
+	}
+	return s.ResolveConnectionKey(conn)
+}
+
+// ResolveConnectionKey 连接关联了服务端密钥时，解密私钥填充到连接中供认证使用
+func (s *SSHKeyService) ResolveConnectionKey(conn *model.Connection) error {
+	if conn.SSHKeyID == 0 {
+		return nil
+	}
+
+	key, err := s.keyRepo.GetByID(conn.SSHKeyID)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return ErrSSHKeyNotFound
+	}
+
+	privateKey, err := s.secretBox.Decrypt(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	conn.PrivateKey = privateKey
+	conn.KeyPassphrase = ""
+	return nil
+}
+
+// DeployKey 通过密码认证登录目标主机，将公钥写入authorized_keys（幂等）
+func (s *SSHKeyService) DeployKey(userID uint, keyID uint, req *model.SSHKeyDeployRequest) (*model.SSHKeyDeployResult, error) {
+	key, err := s.GetKey(userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.connRepo.GetByID(req.ConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取连接信息失败: %w", err)
+	}
+	if conn == nil {
+		return nil, ErrConnectionNotFound
+	}
+	// 部署会登录目标主机并修改连接配置，需要对连接的manage权限
+	if err := s.access.Authorize(userID, conn, model.PermissionManage); err != nil {
+		return nil, err
+	}
+	if conn.Protocol != model.ProtocolSSH {
+		return nil, ErrInvalidProtocol
+	}
+
+	// 只使用密码类认证建立连接，与ssh-copy-id的使用场景一致
+	passwordConn := *conn
+	if s.credentials != nil {
+		if err := s.credentials.ResolveConnection(&passwordConn); err != nil {
+			return nil, fmt.Errorf("加载凭据失败: %w", err)
+		}
+	}
+	passwordConn.PrivateKey = ""
+	passwordConn.KeyPassphrase = ""
+	passwordConn.AuthMethods = model.AuthMethodPassword + "," + model.AuthMethodKeyboardInteractive
+	if req.Password != "" {
+		passwordConn.Password = req.Password
+	}
+	if passwordConn.Password == "" {
+		return nil, errors.New("未提供目标主机的登录密码")
+	}
+
+	client, err := dialSSHClient(&passwordConn, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	result := &model.SSHKeyDeployResult{
+		ConnectionID: conn.ID,
+		Name:         conn.Name,
+		Host:         conn.Host,
+	}
+	result.Added, err = DeployPublicKey(client, key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 部署成功后关联到连接，并确保公钥认证在认证顺序中
+	if req.Attach == nil || *req.Attach {
+		conn.SSHKeyID = key.ID
+		if !conn.HasAuthMethod(model.AuthMethodPublicKey) {
+			conn.AuthMethods = strings.Join(append([]string{model.AuthMethodPublicKey}, conn.GetAuthMethods()...), ",")
+		}
+		// 仓库的Update对空值的敏感字段保持原值
+		conn.Password = ""
+		conn.PrivateKey = ""
+		conn.KeyPassphrase = ""
+		if err := s.connRepo.Update(conn); err != nil {
+			return nil, fmt.Errorf("关联SSH密钥失败: %w", err)
+		}
+	}
+
+	log.Printf("已部署SSH公钥: 密钥ID=%d, 连接ID=%d, 新增=%v", key.ID, conn.ID, result.Added)
+	return result, nil
+}
+
+// RotateKey 轮换密钥：生成新密钥对并部署到所有关联主机，全部成功后保存新密钥并移除旧公钥
+func (s *SSHKeyService) RotateKey(userID uint, keyID uint) (*model.SSHKey, []*model.SSHKeyDeployResult, error) {
+	s.rotateMutex.Lock()
+	defer s.rotateMutex.Unlock()
+
+	key, err := s.GetKey(userID, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	connections, err := s.connRepo.GetBySSHKeyID(key.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询关联连接失败: %w", err)
+	}
+
+	oldPublicKey := key.PublicKey
+	newKey := *key
+	if err := s.fillKeyPair(&newKey); err != nil {
+		return nil, nil, err
+	}
+
+	// 第一阶段：使用当前凭据登录所有主机并追加新公钥
+	results := make([]*model.SSHKeyDeployResult, len(connections))
+	clients := make([]*ssh.Client, len(connections))
+	defer func() {
+		for _, client := range clients {
+			if client != nil {
+				client.Close()
+			}
+		}
+	}()
+
+	failed := false
+	for i, conn := range connections {
+		results[i] = &model.SSHKeyDeployResult{ConnectionID: conn.ID, Name: conn.Name, Host: conn.Host}
+
+		if err := s.resolveConnection(conn); err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		client, err := dialSSHClient(conn, nil)
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		clients[i] = client
+
+		results[i].Added, err = DeployPublicKey(client, newKey.PublicKey)
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+		}
+	}
+
+	// 任一主机失败则回滚已追加的新公钥，旧密钥继续可用
+	if failed {
+		for i, client := range clients {
+			if client != nil && results[i].Added {
+				if _, err := RemovePublicKey(client, newKey.PublicKey); err != nil {
+					log.Printf("回滚新公钥失败: 连接ID=%d, 错误: %v", results[i].ConnectionID, err)
+				}
+				results[i].Added = false
+			}
+		}
+		log.Printf("SSH密钥轮换失败，已回滚: 密钥ID=%d", key.ID)
+		return nil, results, ErrSSHKeyRotationFailed
+	}
+
+	// 第二阶段：保存新密钥
+	now := time.Now()
+	newKey.RotatedAt = &now
+	if err := s.keyRepo.Update(&newKey); err != nil {
+		return nil, results, err
+	}
+
+	// 第三阶段：使用新密钥登录验证后移除旧公钥，失败时仅记录结果
+	for i, conn := range connections {
+		if clients[i] != nil {
+			clients[i].Close()
+			clients[i] = nil
+		}
+
+		if err := s.resolveConnection(conn); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		client, err := dialSSHClient(conn, nil)
+		if err != nil {
+			results[i].Error = "新密钥登录验证失败，保留旧公钥: " + err.Error()
+			continue
+		}
+		results[i].OldRemoved, err = RemovePublicKey(client, oldPublicKey)
+		client.Close()
+		if err != nil {
+			results[i].Error = "移除旧公钥失败: " + err.Error()
+		}
+	}
+
+	log.Printf("SSH密钥轮换完成: 密钥ID=%d, 主机数=%d, 新指纹=%s", key.ID, len(connections), newKey.Fingerprint)
+	return &newKey, results, nil
+}
+
+// DeployPublicKey 将公钥追加到authorized_keys，已存在时不重复添加，返回是否新增
+func DeployPublicKey(client *ssh.Client, publicKey string) (bool, error) {
+	keyMaterial, err := authorizedKeyMaterial(publicKey)
+	if err != nil {
+		return false, err
+	}
+
+	cmd := fmt.Sprintf(`umask 077; mkdir -p ~/.ssh && touch %[1]s && chmod 700 ~/.ssh && chmod 600 %[1]s && `+
+		`if grep -qF %[2]s %[1]s; then echo EXISTS; else `+
+		`if [ -s %[1]s ] && [ -n "$(tail -c1 %[1]s)" ]; then echo >> %[1]s; fi; `+
+		`echo %[3]s >> %[1]s && echo ADDED; fi`,
+		authorizedKeysPath, shellQuote(keyMaterial), shellQuote(strings.TrimSpace(publicKey)))
+
+	output, err := runRemoteCommand(client, cmd)
+	if err != nil {
+		return false, fmt.Errorf("部署公钥失败: %w", err)
+	}
+
+	switch {
+	case strings.Contains(output, "ADDED"):
+		return true, nil
+	case strings.Contains(output, "EXISTS"):
+		return false, nil
+	default:
+		return false, fmt.Errorf("部署公钥失败: %s", output)
+	}
+}
+
+// RemovePublicKey 从authorized_keys中移除公钥，返回是否存在并已移除
+func RemovePublicKey(client *ssh.Client, publicKey string) (bool, error) {
+	keyMaterial, err := authorizedKeyMaterial(publicKey)
+	if err != nil {
+		return false, err
+	}
+
+	// 通过cat回写保留原文件的权限和属主
+	cmd := fmt.Sprintf(`if [ -f %[1]s ] && grep -qF %[2]s %[1]s; then `+
+		`grep -vF %[2]s %[1]s > %[1]s.mini-web.tmp; cat %[1]s.mini-web.tmp > %[1]s && rm -f %[1]s.mini-web.tmp && echo REMOVED; `+
+		`else echo ABSENT; fi`,
+		authorizedKeysPath, shellQuote(keyMaterial))
+
+	output, err := runRemoteCommand(client, cmd)
+	if err != nil {
+		return false, fmt.Errorf("移除公钥失败: %w", err)
+	}
+
+	switch {
+	case strings.Contains(output, "REMOVED"):
+		return true, nil
+	case strings.Contains(output, "ABSENT"):
+		return false, nil
+	default:
+		return false, fmt.Errorf("移除公钥失败: %s", output)
+	}
+}
+
+// authorizedKeyMaterial 提取公钥的"类型 base64"部分，用于忽略注释进行匹配
+func authorizedKeyMaterial(publicKey string) (string, error) {
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return "", fmt.Errorf("解析公钥失败: %w", err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(parsed))), nil
+}
+
+// runRemoteCommand 在一次性会话中执行命令并返回输出
+func runRemoteCommand(client *ssh.Client, cmd string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("无法创建SSH会话: %w", err)
+	}
+	defer session.Close()
+
+	output, err := session.CombinedOutput(cmd)
+	if err != nil {
+		return string(output), fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// shellQuote 使用单引号包裹字符串，供远程shell安全使用
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sanitizeKeyComment 公钥注释只保留单行可见字符
+func sanitizeKeyComment(comment string) string {
+	comment = strings.TrimSpace(comment)
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		if r == ' ' {
+			return '_'
+		}
+		return r
+	}, comment)
+}
@@ -11,10 +11,7 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
-	"strconv"
 	"sync"
-	"time"
 
 	"gitee.com/await29/mini-web/internal/model"
 	"golang.org/x/crypto/ssh"
@@ -39,39 +36,12 @@ type SSHTerminalSession struct {
 	formatter *TerminalFormatter
 }
 
-// 创建SSH终端会话
-func createSSHTerminalSession(conn *model.Connection) (*SSHTerminalSession, error) {
-	// 准备SSH配置
-	config := &ssh.ClientConfig{
-		User:            conn.Username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 注意：生产环境应验证主机密钥
-		Timeout:         time.Second * 10,
-	}
-
-	// 添加认证方式（密码或密钥）
-	if conn.Password != "" {
-		config.Auth = append(config.Auth, ssh.Password(conn.Password))
-	}
-
-	if conn.PrivateKey != "" {
-		signer, err := ssh.ParsePrivateKey([]byte(conn.PrivateKey))
-		if err != nil {
-			return nil, fmt.Errorf("解析SSH私钥失败: %w", err)
-		}
-		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
-	}
-
-	// 如果没有提供认证方式，返回错误
-	if len(config.Auth) == 0 {
-		return nil, fmt.Errorf("未提供SSH认证方式（密码或私钥）")
-	}
-
+// 创建SSH终端会话，authOptions携带交互式认证提示转发和短期证书，可以为nil
+func createSSHTerminalSession(conn *model.Connection, authOptions *SSHAuthOptions) (*SSHTerminalSession, error) {
 	// 连接到SSH服务器
-	addr := net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port))
-	client, err := ssh.Dial("tcp", addr, config)
+	client, err := dialSSHClient(conn, authOptions)
 	if err != nil {
-		return nil, fmt.Errorf("SSH连接失败: %w", err)
+		return nil, err
 	}
 
 	// 创建会话
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrInternalCAUnavailable 内部CA未初始化
+var ErrInternalCAUnavailable = errors.New("内部证书颁发机构未启用")
+
+// internalCAValidity 自动生成的CA证书有效期
+const internalCAValidity = 10 * 365 * 24 * time.Hour
+
+// InternalCA 内部证书颁发机构，为内网主机签发HTTPS叶子证书。
+// CA证书和私钥保存在文件中，两者都不存在时在首次使用时自动生成
+type InternalCA struct {
+	certPath   string
+	keyPath    string
+	commonName string
+
+	mutex  sync.Mutex
+	cert   *x509.Certificate
+	signer crypto.Signer
+}
+
+// 全局内部CA实例
+var globalInternalCA *InternalCA
+
+// InitInternalCA 初始化全局内部CA，证书和私钥延迟到首次签发时加载
+func InitInternalCA(certPath, keyPath, commonName string) *InternalCA {
+	globalInternalCA = &InternalCA{certPath: certPath, keyPath: keyPath, commonName: commonName}
+	return globalInternalCA
+}
+
+// GetInternalCA 获取全局内部CA，未初始化时返回nil
+func GetInternalCA() *InternalCA {
+	return globalInternalCA
+}
+
+// load 加载或生成CA证书和私钥
+func (ca *InternalCA) load() (*x509.Certificate, crypto.Signer, error) {
+	if ca == nil {
+		return nil, nil, ErrInternalCAUnavailable
+	}
+
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+
+	if ca.cert != nil {
+		return ca.cert, ca.signer, nil
+	}
+
+	_, certErr := os.Stat(ca.certPath)
+	_, keyErr := os.Stat(ca.keyPath)
+	if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+		if err := ca.generate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pair, err := tls.LoadX509KeyPair(ca.certPath, ca.keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载内部CA失败: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析内部CA证书失败: %w", err)
+	}
+	if !cert.IsCA {
+		return nil, nil, fmt.Errorf("%s不是CA证书", ca.certPath)
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("内部CA私钥类型不支持签名")
+	}
+
+	ca.cert = cert
+	ca.signer = signer
+	log.Printf("内部CA已加载: %s, 有效期至%s", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+	return cert, signer, nil
+}
+
+// generate 生成ECDSA P-256 CA私钥和自签名CA证书
+func (ca *InternalCA) generate() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("生成内部CA私钥失败: %w", err)
+	}
+	serial, err := randomSerialNumber()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: ca.commonName, Organization: []string{"Mini-Web"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(internalCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("生成内部CA证书失败: %w", err)
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{ca.certPath, ca.keyPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("创建内部CA目录失败: %w", err)
+		}
+	}
+	if err := os.WriteFile(ca.keyPath, keyPEM, 0600); err != nil {
+		return fmt.Errorf("保存内部CA私钥失败: %w", err)
+	}
+	if err := os.WriteFile(ca.certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("保存内部CA证书失败: %w", err)
+	}
+
+	log.Printf("已生成新的内部CA: %s，请将%s分发给客户端信任", ca.certPath, ca.certPath)
+	return nil
+}
+
+// CertificatePEM 获取CA证书，客户端导入后信任内部签发的证书
+func (ca *InternalCA) CertificatePEM() (string, error) {
+	cert, _, err := ca.load()
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})), nil
+}
+
+// Issued 判断证书是否由本CA签发
+func (ca *InternalCA) Issued(cert *x509.Certificate) bool {
+	caCert, _, err := ca.load()
+	if err != nil {
+		return false
+	}
+	return cert.CheckSignatureFrom(caCert) == nil
+}
+
+// Sign 为公钥签发叶子证书，有效期不超过CA证书本身。返回叶子证书和CA证书组成的证书链
+func (ca *InternalCA) Sign(template *x509.Certificate, publicKey crypto.PublicKey) (string, error) {
+	caCert, signer, err := ca.load()
+	if err != nil {
+		return "", err
+	}
+	if template.NotAfter.After(caCert.NotAfter) {
+		template.NotAfter = caCert.NotAfter
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, publicKey, signer)
+	if err != nil {
+		return "", fmt.Errorf("签发证书失败: %w", err)
+	}
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})...)
+	return string(chain), nil
+}
@@ -0,0 +1,547 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model/sqlite"
+)
+
+var (
+	// ErrInvalidCertificateRequest 证书生成参数无效
+	ErrInvalidCertificateRequest = errors.New("证书参数无效")
+
+	// ErrNotInternalCertificate 证书不是由内部CA签发，不能自动续期
+	ErrNotInternalCertificate = errors.New("该证书不是由内部CA签发")
+
+	// ErrCSRNotFound 证书请求不存在
+	ErrCSRNotFound = errors.New("证书请求不存在")
+
+	// ErrCertificateKeyMismatch 签发的证书与证书请求的私钥不匹配
+	ErrCertificateKeyMismatch = errors.New("证书与证书请求的私钥不匹配")
+)
+
+// 证书私钥类型
+const (
+	CertKeyTypeECDSA = "ecdsa"
+	CertKeyTypeRSA   = "rsa"
+)
+
+const (
+	defaultCertValidDays     = 365
+	maxSelfSignedValidDays   = 3650
+	maxInternalCertValidDays = 825 // 与主流客户端对内部CA证书的有效期上限一致
+	internalRenewBefore      = 30 * 24 * time.Hour
+	certBackdate             = time.Hour // 生效时间提前，容忍客户端时钟偏差
+)
+
+// hostnamePattern 主机名，允许最左侧使用通配符
+var hostnamePattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// CertificateRequest 生成证书或证书请求的参数
+type CertificateRequest struct {
+	Name         string   `json:"name"`
+	CommonName   string   `json:"common_name"`
+	DNSNames     []string `json:"dns_names"`
+	IPAddresses  []string `json:"ip_addresses"`
+	Organization string   `json:"organization"`
+	KeyType      string   `json:"key_type"` // ecdsa（默认）或rsa
+	KeySize      int      `json:"key_size"` // ECDSA为256或384，RSA为2048、3072或4096
+	ValidDays    int      `json:"valid_days"`
+	IsEnabled    bool     `json:"is_enabled"`
+}
+
+// certificateSubject 校验后的证书主体信息
+type certificateSubject struct {
+	name        pkix.Name
+	dnsNames    []string
+	ipAddresses []net.IP
+	keyType     string
+	keySize     int
+	validity    time.Duration
+}
+
+// normalize 校验参数并补全默认值，通用名称不是IP时自动加入SAN
+func (req *CertificateRequest) normalize(maxValidDays int) (*certificateSubject, error) {
+	subject := &certificateSubject{keyType: strings.ToLower(strings.TrimSpace(req.KeyType)), keySize: req.KeySize}
+	if subject.keyType == "" {
+		subject.keyType = CertKeyTypeECDSA
+	}
+	switch subject.keyType {
+	case CertKeyTypeECDSA:
+		if subject.keySize == 0 {
+			subject.keySize = 256
+		}
+		if subject.keySize != 256 && subject.keySize != 384 {
+			return nil, fmt.Errorf("%w: ECDSA密钥长度只支持256或384", ErrInvalidCertificateRequest)
+		}
+	case CertKeyTypeRSA:
+		if subject.keySize == 0 {
+			subject.keySize = 2048
+		}
+		if subject.keySize != 2048 && subject.keySize != 3072 && subject.keySize != 4096 {
+			return nil, fmt.Errorf("%w: RSA密钥长度只支持2048、3072或4096", ErrInvalidCertificateRequest)
+		}
+	default:
+		return nil, fmt.Errorf("%w: 不支持的密钥类型%s", ErrInvalidCertificateRequest, req.KeyType)
+	}
+
+	validDays := req.ValidDays
+	if validDays == 0 {
+		validDays = defaultCertValidDays
+	}
+	if validDays < 1 || validDays > maxValidDays {
+		return nil, fmt.Errorf("%w: 有效期应为1到%d天", ErrInvalidCertificateRequest, maxValidDays)
+	}
+	subject.validity = time.Duration(validDays) * 24 * time.Hour
+
+	commonName := strings.ToLower(strings.TrimSpace(req.CommonName))
+	seen := make(map[string]bool)
+	addName := func(value string) error {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			return nil
+		}
+		seen[value] = true
+		if ip := net.ParseIP(value); ip != nil {
+			subject.ipAddresses = append(subject.ipAddresses, ip)
+			return nil
+		}
+		if len(value) > 253 || !hostnamePattern.MatchString(value) {
+			return fmt.Errorf("%w: 无效的域名%s", ErrInvalidCertificateRequest, value)
+		}
+		subject.dnsNames = append(subject.dnsNames, value)
+		return nil
+	}
+	for _, value := range append(append([]string{commonName}, req.DNSNames...), req.IPAddresses...) {
+		if err := addName(value); err != nil {
+			return nil, err
+		}
+	}
+	if len(subject.dnsNames) == 0 && len(subject.ipAddresses) == 0 {
+		return nil, fmt.Errorf("%w: 通用名称和备用名称不能都为空", ErrInvalidCertificateRequest)
+	}
+
+	if commonName == "" {
+		if len(subject.dnsNames) > 0 {
+			commonName = subject.dnsNames[0]
+		} else {
+			commonName = subject.ipAddresses[0].String()
+		}
+	}
+	subject.name = pkix.Name{CommonName: commonName}
+	if org := strings.TrimSpace(req.Organization); org != "" {
+		subject.name.Organization = []string{org}
+	}
+	return subject, nil
+}
+
+// generatePrivateKey 生成指定类型和长度的私钥
+func generatePrivateKey(keyType string, keySize int) (crypto.Signer, error) {
+	var key crypto.Signer
+	var err error
+	if keyType == CertKeyTypeRSA {
+		key, err = rsa.GenerateKey(rand.Reader, keySize)
+	} else if keySize == 384 {
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	} else {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成私钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// encodePrivateKeyPEM 以PKCS#8格式编码私钥
+func encodePrivateKeyPEM(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("编码私钥失败: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// randomSerialNumber 生成128位随机证书序列号
+func randomSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("生成证书序列号失败: %w", err)
+	}
+	return serial, nil
+}
+
+// leafTemplate 生成服务器叶子证书模板
+func (subject *certificateSubject) leafTemplate() (*x509.Certificate, error) {
+	serial, err := randomSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if subject.keyType == CertKeyTypeRSA {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject.name,
+		DNSNames:              subject.dnsNames,
+		IPAddresses:           subject.ipAddresses,
+		NotBefore:             now.Add(-certBackdate),
+		NotAfter:              now.Add(subject.validity),
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}, nil
+}
+
+// generateSelfSigned 生成自签名证书，返回证书和私钥PEM
+func generateSelfSigned(subject *certificateSubject) (string, string, error) {
+	key, err := generatePrivateKey(subject.keyType, subject.keySize)
+	if err != nil {
+		return "", "", err
+	}
+	template, err := subject.leafTemplate()
+	if err != nil {
+		return "", "", err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return "", "", fmt.Errorf("生成自签名证书失败: %w", err)
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return "", "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), string(keyPEM), nil
+}
+
+// issueInternal 由内部CA签发证书，返回证书链和私钥PEM
+func issueInternal(subject *certificateSubject) (string, string, error) {
+	key, err := generatePrivateKey(subject.keyType, subject.keySize)
+	if err != nil {
+		return "", "", err
+	}
+	template, err := subject.leafTemplate()
+	if err != nil {
+		return "", "", err
+	}
+
+	chain, err := GetInternalCA().Sign(template, key.Public())
+	if err != nil {
+		return "", "", err
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return "", "", err
+	}
+	return chain, string(keyPEM), nil
+}
+
+// fillSSLConfig 校验证书和私钥并写入解析出的证书信息
+func (s *SSLService) fillSSLConfig(config *sqlite.SSLConfig) error {
+	if err := s.ValidateCertificateAndKey(config.CertContent, config.KeyContent); err != nil {
+		return err
+	}
+	info, err := s.ParseCertificate(config.CertContent)
+	if err != nil {
+		return err
+	}
+
+	config.Issuer = info.Issuer
+	config.Subject = info.Subject
+	config.NotBefore = info.NotBefore.Format("2006-01-02 15:04:05")
+	config.NotAfter = info.NotAfter.Format("2006-01-02 15:04:05")
+	if config.Domain == "" {
+		domains, _ := s.GetDomainFromCertificate(config.CertContent)
+		if len(domains) > 0 {
+			config.Domain = domains[0]
+		}
+	}
+	return nil
+}
+
+// saveGeneratedCertificate 把生成的证书保存为SSL配置
+func (s *SSLService) saveGeneratedCertificate(name string, subject *certificateSubject, certPEM, keyPEM string, enabled bool) (*sqlite.SSLConfig, error) {
+	if name = strings.TrimSpace(name); name == "" {
+		name = subject.name.CommonName
+	}
+	config := &sqlite.SSLConfig{
+		Name:        name,
+		Domain:      subject.name.CommonName,
+		CertContent: certPEM,
+		KeyContent:  keyPEM,
+		IsEnabled:   enabled,
+	}
+	if err := s.fillSSLConfig(config); err != nil {
+		return nil, err
+	}
+	if err := sqlite.CreateSSLConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// CreateSelfSignedCertificate 生成自签名证书并保存为SSL配置
+func (s *SSLService) CreateSelfSignedCertificate(req *CertificateRequest) (*sqlite.SSLConfig, error) {
+	subject, err := req.normalize(maxSelfSignedValidDays)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, keyPEM, err := generateSelfSigned(subject)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveGeneratedCertificate(req.Name, subject, certPEM, keyPEM, req.IsEnabled)
+}
+
+// IssueInternalCertificate 由内部CA签发证书并保存为SSL配置
+func (s *SSLService) IssueInternalCertificate(req *CertificateRequest) (*sqlite.SSLConfig, error) {
+	subject, err := req.normalize(maxInternalCertValidDays)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, keyPEM, err := issueInternal(subject)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveGeneratedCertificate(req.Name, subject, certPEM, keyPEM, req.IsEnabled)
+}
+
+// GetInternalCACertificate 获取内部CA证书
+func (s *SSLService) GetInternalCACertificate() (string, error) {
+	return GetInternalCA().CertificatePEM()
+}
+
+// RenewInternalCertificate 使用新私钥重新签发内部CA证书，主体、备用名称、密钥类型和有效期长度保持不变
+func (s *SSLService) RenewInternalCertificate(id int) (*sqlite.SSLConfig, error) {
+	config, err := sqlite.GetSSLConfigByID(id)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := parseLeafCertificate(config.CertContent)
+	if err != nil {
+		return nil, err
+	}
+	if !GetInternalCA().Issued(leaf) {
+		return nil, ErrNotInternalCertificate
+	}
+
+	subject := &certificateSubject{
+		name:        pkix.Name{CommonName: leaf.Subject.CommonName, Organization: leaf.Subject.Organization},
+		dnsNames:    leaf.DNSNames,
+		ipAddresses: leaf.IPAddresses,
+		validity:    min(leaf.NotAfter.Sub(leaf.NotBefore)-certBackdate, maxInternalCertValidDays*24*time.Hour),
+	}
+	switch key := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		subject.keyType, subject.keySize = CertKeyTypeRSA, key.N.BitLen()
+	case *ecdsa.PublicKey:
+		subject.keyType, subject.keySize = CertKeyTypeECDSA, key.Curve.Params().BitSize
+	default:
+		subject.keyType, subject.keySize = CertKeyTypeECDSA, 256
+	}
+
+	certPEM, keyPEM, err := issueInternal(subject)
+	if err != nil {
+		return nil, err
+	}
+	config.CertContent = certPEM
+	config.KeyContent = keyPEM
+	config.CertPath = ""
+	config.KeyPath = ""
+	if err := s.fillSSLConfig(config); err != nil {
+		return nil, err
+	}
+	if err := sqlite.UpdateSSLConfig(config); err != nil {
+		return nil, err
+	}
+
+	log.Printf("内部CA证书已续期: ID=%d, 域名=%s, 有效期至%s", config.ID, config.Domain, config.NotAfter)
+	return config, nil
+}
+
+// RenewExpiringInternalCertificates 续期30天内到期的已启用内部CA证书，返回续期数量
+func (s *SSLService) RenewExpiringInternalCertificates() (int, error) {
+	if GetInternalCA() == nil {
+		return 0, nil
+	}
+	configs, err := sqlite.GetSSLConfigs()
+	if err != nil {
+		return 0, err
+	}
+
+	renewed := 0
+	for _, config := range configs {
+		if !config.IsEnabled || config.CertContent == "" {
+			continue
+		}
+		leaf, err := parseLeafCertificate(config.CertContent)
+		if err != nil || time.Until(leaf.NotAfter) > internalRenewBefore || !GetInternalCA().Issued(leaf) {
+			continue
+		}
+		if _, err := s.RenewInternalCertificate(config.ID); err != nil {
+			log.Printf("续期内部CA证书失败: ID=%d, %v", config.ID, err)
+			continue
+		}
+		renewed++
+	}
+	return renewed, nil
+}
+
+// GenerateCSR 生成私钥和证书请求，私钥保存在本地，证书请求交给外部CA签发
+func (s *SSLService) GenerateCSR(req *CertificateRequest) (*sqlite.SSLCSR, error) {
+	subject, err := req.normalize(maxSelfSignedValidDays)
+	if err != nil {
+		return nil, err
+	}
+	key, err := generatePrivateKey(subject.keyType, subject.keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     subject.name,
+		DNSNames:    subject.dnsNames,
+		IPAddresses: subject.ipAddresses,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("生成证书请求失败: %w", err)
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(subject.dnsNames)+len(subject.ipAddresses))
+	names = append(names, subject.dnsNames...)
+	for _, ip := range subject.ipAddresses {
+		names = append(names, ip.String())
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = subject.name.CommonName
+	}
+	csr := &sqlite.SSLCSR{
+		Name:       name,
+		CommonName: subject.name.CommonName,
+		DNSNames:   strings.Join(names, ","),
+		KeyType:    fmt.Sprintf("%s-%d", subject.keyType, subject.keySize),
+		CSRContent: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})),
+		KeyContent: string(keyPEM),
+	}
+	if err := sqlite.CreateSSLCSR(csr); err != nil {
+		return nil, err
+	}
+	return csr, nil
+}
+
+// GetCSRs 获取等待签发的证书请求
+func (s *SSLService) GetCSRs() ([]*sqlite.SSLCSR, error) {
+	return sqlite.GetSSLCSRs()
+}
+
+// GetCSR 获取证书请求
+func (s *SSLService) GetCSR(id int) (*sqlite.SSLCSR, error) {
+	csr, err := sqlite.GetSSLCSRByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if csr == nil {
+		return nil, ErrCSRNotFound
+	}
+	return csr, nil
+}
+
+// DeleteCSR 删除证书请求及其私钥
+func (s *SSLService) DeleteCSR(id int) error {
+	found, err := sqlite.DeleteSSLCSR(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrCSRNotFound
+	}
+	return nil
+}
+
+// CompleteCSR 导入外部CA签发的证书（可包含中间证书），与本地私钥组合保存为SSL配置，
+// 完成后删除证书请求
+func (s *SSLService) CompleteCSR(id int, certContent string, enabled bool) (*sqlite.SSLConfig, error) {
+	csr, err := s.GetCSR(id)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := parseLeafCertificate(certContent)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificateRequest, err)
+	}
+	request, err := parseCSR(csr.CSRContent)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(request.PublicKey) {
+		return nil, ErrCertificateKeyMismatch
+	}
+
+	config := &sqlite.SSLConfig{
+		Name:        csr.Name,
+		Domain:      csr.CommonName,
+		CertContent: strings.TrimSpace(certContent) + "\n",
+		KeyContent:  csr.KeyContent,
+		IsEnabled:   enabled,
+	}
+	if err := s.fillSSLConfig(config); err != nil {
+		return nil, err
+	}
+	if err := sqlite.CreateSSLConfig(config); err != nil {
+		return nil, err
+	}
+	if _, err := sqlite.DeleteSSLCSR(id); err != nil {
+		log.Printf("删除已完成的证书请求失败: ID=%d, %v", id, err)
+	}
+	return config, nil
+}
+
+// parseLeafCertificate 解析PEM中的第一张证书
+func parseLeafCertificate(certContent string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certContent))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("无法解码PEM格式证书")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析X.509证书失败: %w", err)
+	}
+	return cert, nil
+}
+
+// parseCSR 解析PEM格式的证书请求
+func parseCSR(csrContent string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(csrContent))
+	if block == nil {
+		return nil, errors.New("无法解码证书请求")
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书请求失败: %w", err)
+	}
+	return request, nil
+}
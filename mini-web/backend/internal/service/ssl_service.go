@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
+	"gitee.com/await29/mini-web/internal/model"
 	"gitee.com/await29/mini-web/internal/model/sqlite"
 )
 
@@ -146,9 +148,11 @@ func (s *SSLService) TestSSLConnection(host string, port int, certContent, keyCo
 
 // GenerateSelfSignedCertificate 生成自签名证书
 func (s *SSLService) GenerateSelfSignedCertificate(domain string, validDays int) (string, string, error) {
-	// 这里可以实现自签名证书生成逻辑
-	// 为了简化，这里返回示例
-	return "", "", fmt.Errorf("自签名证书生成功能暂未实现")
+	subject, err := (&CertificateRequest{CommonName: domain, ValidDays: validDays}).normalize(maxSelfSignedValidDays)
+	if err != nil {
+		return "", "", err
+	}
+	return generateSelfSigned(subject)
 }
 
 // CheckCertificateExpiry 检查证书过期状态
@@ -162,6 +166,46 @@ func (s *SSLService) CheckCertificateExpiry() ([]sqlite.SSLConfig, error) {
 	return expiring, nil
 }
 
+// sslExpiryNotifyDays 证书剩余天数等于这些值时发布通知，每天检查一次
+var sslExpiryNotifyDays = map[int]bool{30: true, 14: true, 7: true, 3: true, 1: true}
+
+// NotifyExpiringCertificates 检查即将过期的证书，在剩余30、14、7、3、1天时发布通知
+func (s *SSLService) NotifyExpiringCertificates() error {
+	expiring, err := s.CheckCertificateExpiry()
+	if err != nil {
+		return err
+	}
+
+	for _, config := range expiring {
+		notAfter, err := time.Parse("2006-01-02 15:04:05", config.NotAfter)
+		if err != nil {
+			continue
+		}
+		daysLeft := int(time.Until(notAfter).Hours() / 24)
+		if !sslExpiryNotifyDays[daysLeft] {
+			continue
+		}
+
+		severity := model.SeverityWarning
+		if daysLeft <= 3 {
+			severity = model.SeverityCritical
+		}
+		GetNotificationService().Publish(&model.NotificationEvent{
+			Type:     model.EventSSLExpiring,
+			Severity: severity,
+			Title:    "SSL证书即将过期",
+			Message:  fmt.Sprintf("证书%s（%s）将在%d天后过期", config.Name, config.Domain, daysLeft),
+			Data: map[string]string{
+				"name":      config.Name,
+				"domain":    config.Domain,
+				"not_after": config.NotAfter,
+				"days_left": strconv.Itoa(daysLeft),
+			},
+		})
+	}
+	return nil
+}
+
 // GetDomainFromCertificate 从证书中提取域名
 func (s *SSLService) GetDomainFromCertificate(certContent string) ([]string, error) {
 	// 解析证书
@@ -6,6 +6,9 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,15 +22,21 @@ var (
 
 // SystemService 系统服务
 type SystemService struct {
-	configRepo model.SystemConfigRepository
-	logRepo    model.SystemLogRepository
+	configRepo   model.SystemConfigRepository
+	logRepo      model.SystemLogRepository
+	emailService *EmailService
+
+	alertMutex sync.Mutex
+	alerting   map[string]bool // 正处于超限状态的指标，恢复正常前不重复告警
 }
 
 // NewSystemService 创建系统服务实例
-func NewSystemService(configRepo model.SystemConfigRepository, logRepo model.SystemLogRepository) *SystemService {
+func NewSystemService(configRepo model.SystemConfigRepository, logRepo model.SystemLogRepository, emailService *EmailService) *SystemService {
 	return &SystemService{
-		configRepo: configRepo,
-		logRepo:    logRepo,
+		configRepo:   configRepo,
+		logRepo:      logRepo,
+		emailService: emailService,
+		alerting:     make(map[string]bool),
 	}
 }
 
@@ -346,21 +355,98 @@ func (s *SystemService) GetSystemInfo() (map[string]interface{}, error) {
 	return systemInfo, nil
 }
 
-// TestEmailConfig 测试邮件配置
+// TestEmailConfig 使用给定的SMTP服务器和账号发送测试邮件，
+// 发件人和加密方式沿用已保存的邮件配置，密码为空时使用已保存的密码
 func (s *SystemService) TestEmailConfig(host string, port int, username, password, to string, userID uint, ipAddress string) error {
-	// 这里实现邮件发送测试逻辑
-	// 使用Go的net/smtp包发送测试邮件
-	
+	config, err := s.emailService.GetConfig()
+	if err != nil {
+		return err
+	}
+	if config.ID == 0 {
+		// 没有保存过配置时按端口推断加密方式，发件人使用登录账号
+		config.EnableSSL = port == 465
+		config.EnableTLS = port != 465
+		config.FromEmail = username
+	}
+	config.SMTPHost = host
+	config.SMTPPort = port
+	config.Username = username
+	config.Password = password
+
+	if err := s.emailService.SendTest(config, to); err != nil {
+		s.LogWarn("system", "邮件配置测试失败",
+			fmt.Sprintf("测试邮件发送到: %s, 错误: %v", to, err),
+			&userID, ipAddress)
+		return err
+	}
+
 	s.LogInfo("system", "邮件配置测试",
 		fmt.Sprintf("测试邮件发送到: %s", to),
 		&userID, ipAddress)
-	
-	// 模拟邮件发送成功
 	return nil
 }
 
 // 以下是性能数据收集的辅助方法
 
+// CheckPerformanceAlerts 检查内存和磁盘使用率，超过阈值时发布性能告警。
+// 阈值由notification_memory_threshold和notification_disk_threshold配置，为0时不检查；
+// 同一指标只在从正常变为超限时告警一次
+func (s *SystemService) CheckPerformanceAlerts() {
+	metrics := []struct {
+		key     string
+		name    string
+		usage   map[string]interface{}
+		setting string
+	}{
+		{"memory", "内存", s.getMemoryUsage(), "notification_memory_threshold"},
+		{"disk", "磁盘", s.getDiskUsage(), "notification_disk_threshold"},
+	}
+
+	for _, metric := range metrics {
+		threshold := s.alertThreshold(metric.setting)
+		percent, ok := metric.usage["percent"].(float64)
+		if threshold <= 0 || !ok {
+			continue
+		}
+
+		exceeded := percent >= threshold
+		s.alertMutex.Lock()
+		wasExceeded := s.alerting[metric.key]
+		s.alerting[metric.key] = exceeded
+		s.alertMutex.Unlock()
+		if !exceeded || wasExceeded {
+			continue
+		}
+
+		message := fmt.Sprintf("%s使用率%.1f%%，超过阈值%.0f%%", metric.name, percent, threshold)
+		s.LogWarn("performance", "性能告警", message, nil, "")
+		GetNotificationService().Publish(&model.NotificationEvent{
+			Type:     model.EventPerformanceAlert,
+			Severity: model.SeverityWarning,
+			Title:    "性能告警",
+			Message:  message,
+			Data: map[string]string{
+				"metric":    metric.key,
+				"percent":   strconv.FormatFloat(percent, 'f', 1, 64),
+				"threshold": strconv.FormatFloat(threshold, 'f', 0, 64),
+			},
+		})
+	}
+}
+
+// alertThreshold 读取告警阈值百分比，未配置或无效时返回0
+func (s *SystemService) alertThreshold(key string) float64 {
+	config, err := s.configRepo.GetByKey(key)
+	if err != nil || config == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(config.Value), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
 // getCPUUsage 获取CPU使用率
 func (s *SystemService) getCPUUsage() map[string]interface{} {
 	// 简化实现，实际应该通过系统调用获取真实数据
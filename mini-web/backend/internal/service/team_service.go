@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+var (
+	// ErrTeamNotFound 团队不存在
+	ErrTeamNotFound = errors.New("团队不存在")
+
+	// ErrTeamForbidden 无权管理团队
+	ErrTeamForbidden = errors.New("无权管理此团队")
+
+	// ErrInvalidTeamRole 无效的团队成员角色
+	ErrInvalidTeamRole = errors.New("无效的团队成员角色，仅支持owner、member")
+
+	// ErrLastTeamOwner 不能移除最后一名团队管理者
+	ErrLastTeamOwner = errors.New("团队至少需要保留一名管理者")
+)
+
+// TeamService 团队服务，管理团队及成员关系
+type TeamService struct {
+	teamRepo model.TeamRepository
+	userRepo model.UserRepository
+	roles    *RoleService
+}
+
+// NewTeamService 创建团队服务实例
+func NewTeamService(teamRepo model.TeamRepository, userRepo model.UserRepository, roles *RoleService) *TeamService {
+	return &TeamService{
+		teamRepo: teamRepo,
+		userRepo: userRepo,
+		roles:    roles,
+	}
+}
+
+// CreateTeam 创建团队，创建者自动成为团队管理者
+func (s *TeamService) CreateTeam(userID uint, req *model.TeamRequest) (*model.Team, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("团队名称不能为空")
+	}
+
+	team := &model.Team{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		CreatedBy:   userID,
+	}
+	if err := s.teamRepo.Create(team); err != nil {
+		return nil, err
+	}
+
+	owner := &model.TeamMember{TeamID: team.ID, UserID: userID, Role: model.TeamRoleOwner}
+	if err := s.teamRepo.SaveMember(owner); err != nil {
+		return nil, err
+	}
+	team.MemberCount = 1
+
+	log.Printf("已创建团队: ID=%d, 名称=%s, 创建者ID=%d", team.ID, team.Name, userID)
+	return team, nil
+}
+
+// UpdateTeam 更新团队信息
+func (s *TeamService) UpdateTeam(userID uint, id uint, req *model.TeamRequest) (*model.Team, error) {
+	team, err := s.requireManager(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("团队名称不能为空")
+	}
+
+	team.Name = strings.TrimSpace(req.Name)
+	team.Description = req.Description
+	if err := s.teamRepo.Update(team); err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// DeleteTeam 删除团队，团队的共享授权一并删除
+func (s *TeamService) DeleteTeam(userID uint, id uint) error {
+	if _, err := s.requireManager(userID, id); err != nil {
+		return err
+	}
+
+	if err := s.teamRepo.Delete(id); err != nil {
+		return err
+	}
+
+	log.Printf("已删除团队: ID=%d, 操作用户ID=%d", id, userID)
+	return nil
+}
+
+// GetTeams 获取用户可见的团队，拥有teams.manage_all权限时可见全部团队
+func (s *TeamService) GetTeams(userID uint) ([]*model.Team, error) {
+	admin, err := s.hasPermission(userID, model.PermTeamsManageAll)
+	if err != nil {
+		return nil, err
+	}
+	if admin {
+		return s.teamRepo.GetAll()
+	}
+	return s.teamRepo.GetByUserID(userID)
+}
+
+// GetTeam 获取团队详情，仅团队成员和管理员可见
+func (s *TeamService) GetTeam(userID uint, id uint) (*model.Team, error) {
+	team, err := s.teamRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if team == nil {
+		return nil, ErrTeamNotFound
+	}
+
+	member, err := s.IsMember(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		admin, err := s.hasPermission(userID, model.PermTeamsManageAll)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, ErrTeamNotFound
+		}
+	}
+
+	return team, nil
+}
+
+// GetMembers 获取团队成员列表
+func (s *TeamService) GetMembers(userID uint, teamID uint) ([]*model.TeamMember, error) {
+	if _, err := s.GetTeam(userID, teamID); err != nil {
+		return nil, err
+	}
+	return s.teamRepo.GetMembers(teamID)
+}
+
+// AddMember 添加团队成员或修改成员角色
+func (s *TeamService) AddMember(userID uint, teamID uint, req *model.TeamMemberRequest) (*model.TeamMember, error) {
+	if _, err := s.requireManager(userID, teamID); err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = model.TeamRoleMember
+	}
+	if !model.IsValidTeamRole(role) {
+		return nil, ErrInvalidTeamRole
+	}
+
+	user, err := s.userRepo.GetByID(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// 降级管理者时确保团队仍有其他管理者
+	if role != model.TeamRoleOwner {
+		if err := s.ensureOtherOwner(teamID, req.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	member := &model.TeamMember{TeamID: teamID, UserID: user.ID, Role: role}
+	if err := s.teamRepo.SaveMember(member); err != nil {
+		return nil, err
+	}
+
+	return s.teamRepo.GetMember(teamID, user.ID)
+}
+
+// RemoveMember 移除团队成员，成员可以自行退出团队
+func (s *TeamService) RemoveMember(userID uint, teamID uint, memberID uint) error {
+	if userID != memberID {
+		if _, err := s.requireManager(userID, teamID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ensureOtherOwner(teamID, memberID); err != nil {
+		return err
+	}
+
+	return s.teamRepo.RemoveMember(teamID, memberID)
+}
+
+// GetUserTeamIDs 获取用户所属团队的ID列表
+func (s *TeamService) GetUserTeamIDs(userID uint) ([]uint, error) {
+	return s.teamRepo.GetTeamIDsByUserID(userID)
+}
+
+// IsMember 检查用户是否属于团队
+func (s *TeamService) IsMember(userID uint, teamID uint) (bool, error) {
+	member, err := s.teamRepo.GetMember(teamID, userID)
+	if err != nil {
+		return false, err
+	}
+	return member != nil, nil
+}
+
+// requireManager 检查用户是否为团队管理者或拥有teams.manage_all权限
+func (s *TeamService) requireManager(userID uint, teamID uint) (*model.Team, error) {
+	team, err := s.teamRepo.GetByID(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team == nil {
+		return nil, ErrTeamNotFound
+	}
+
+	member, err := s.teamRepo.GetMember(teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil && member.Role == model.TeamRoleOwner {
+		return team, nil
+	}
+
+	admin, err := s.hasPermission(userID, model.PermTeamsManageAll)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		return nil, ErrTeamForbidden
+	}
+	return team, nil
+}
+
+// ensureOtherOwner 当成员是团队管理者时，确保团队中还有其他管理者
+func (s *TeamService) ensureOtherOwner(teamID uint, memberID uint) error {
+	members, err := s.teamRepo.GetMembers(teamID)
+	if err != nil {
+		return err
+	}
+
+	isOwner := false
+	owners := 0
+	for _, m := range members {
+		if m.Role != model.TeamRoleOwner {
+			continue
+		}
+		owners++
+		if m.UserID == memberID {
+			isOwner = true
+		}
+	}
+
+	if isOwner && owners <= 1 {
+		return ErrLastTeamOwner
+	}
+	return nil
+}
+
+// hasPermission 按用户当前角色检查系统权限
+func (s *TeamService) hasPermission(userID uint, permission string) (bool, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return false, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user == nil {
+		return false, nil
+	}
+	return s.roles.HasPermission(user.Role, 0, permission)
+}
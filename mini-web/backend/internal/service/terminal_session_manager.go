@@ -17,6 +17,8 @@ type TerminalMessage struct {
 	ID        string    `json:"id"`
 	Type      string    `json:"type"`      // input/output/error/system
 	Content   string    `json:"content"`
+	Size      int       `json:"size,omitempty"` // 输出消息的原始字节数，启用流控时客户端按该值确认
+	Seq       uint64    `json:"seq,omitempty"`  // 会话内递增的消息序号，续传时按序号补发
 	Timestamp time.Time `json:"timestamp"`
 	UserID    uint      `json:"user_id,omitempty"`
 }
@@ -33,8 +35,14 @@ type PersistentTerminalSession struct {
 	ExpiresAt       time.Time                       `json:"expires_at"`
 	MessageHistory  []TerminalMessage               `json:"message_history"`
 	MaxHistorySize  int                             `json:"max_history_size"`
+	MaxHistoryBytes int                             `json:"max_history_bytes"`
+	
+	// 续传令牌，重连时与最后序号一起提交，只补发缺失的消息
+	ResumeToken     string                          `json:"-"`
 	
 	// 内部状态，不序列化
+	lastSeq         uint64                          `json:"-"`
+	historyBytes    int                             `json:"-"`
 	process         *os.Process                     `json:"-"`
 	wsConnections   map[string]*websocket.Conn      `json:"-"`
 	inputChannel    chan []byte                     `json:"-"`
@@ -42,6 +50,7 @@ type PersistentTerminalSession struct {
 	errorChannel    chan error                      `json:"-"`
 	closeChannel    chan struct{}                   `json:"-"`
 	mutex           sync.RWMutex                    `json:"-"`
+	writeMutex      sync.Mutex                      `json:"-"` // 串行化WebSocket写入，保证消息顺序
 	Ctx             context.Context                 `json:"-"`
 	Cancel          context.CancelFunc              `json:"-"`
 	
@@ -53,6 +62,7 @@ type PersistentTerminalSession struct {
 type SessionConfig struct {
 	MaxIdleTimeout    time.Duration // 最大空闲时间
 	MaxHistorySize    int           // 最大历史记录数
+	MaxHistoryBytes   int           // 历史记录保留的最大字节数
 	CleanupInterval   time.Duration // 清理间隔
 	HeartbeatInterval time.Duration // 心跳间隔
 }
@@ -61,6 +71,7 @@ type SessionConfig struct {
 var DefaultSessionConfig = SessionConfig{
 	MaxIdleTimeout:    30 * time.Minute,
 	MaxHistorySize:    1000,
+	MaxHistoryBytes:   DefaultReplayBufferSize,
 	CleanupInterval:   5 * time.Minute,
 	HeartbeatInterval: 30 * time.Second,
 }
@@ -118,6 +129,8 @@ func (m *TerminalSessionManager) CreateSession(userID uint, connectionID uint, p
 		ExpiresAt:       now.Add(m.config.MaxIdleTimeout),
 		MessageHistory:  make([]TerminalMessage, 0),
 		MaxHistorySize:  m.config.MaxHistorySize,
+		MaxHistoryBytes: m.config.MaxHistoryBytes,
+		ResumeToken:     uuid.New().String(),
 		wsConnections:   make(map[string]*websocket.Conn),
 		inputChannel:    make(chan []byte, 100),
 		outputChannel:   make(chan []byte, 100),
@@ -174,16 +187,28 @@ func (m *TerminalSessionManager) GetUserSessions(userID uint) ([]*PersistentTerm
 	return sessions, nil
 }
 
-// AddWebSocketConnection 添加WebSocket连接到会话
+// AddWebSocketConnection 添加WebSocket连接到会话并发送全部历史消息
 func (m *TerminalSessionManager) AddWebSocketConnection(sessionID string, conn *websocket.Conn) error {
+	return m.attachConnection(sessionID, conn, "", 0)
+}
+
+// ResumeWebSocketConnection 客户端续传时添加连接，令牌有效时只补发序号大于lastSeq的消息，
+// 令牌无效时与AddWebSocketConnection相同
+func (m *TerminalSessionManager) ResumeWebSocketConnection(sessionID string, conn *websocket.Conn, token string, lastSeq uint64) error {
+	return m.attachConnection(sessionID, conn, token, lastSeq)
+}
+
+// attachConnection 登记连接并发送历史消息。持有写锁直到历史发送完成，避免与广播的消息重复或遗漏
+func (m *TerminalSessionManager) attachConnection(sessionID string, conn *websocket.Conn, token string, lastSeq uint64) error {
 	session, err := m.GetSession(sessionID)
 	if err != nil {
 		return err
 	}
 	
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
 	
+	session.mutex.Lock()
 	// 生成连接ID
 	connID := uuid.New().String()
 	session.wsConnections[connID] = conn
@@ -192,11 +217,16 @@ func (m *TerminalSessionManager) AddWebSocketConnection(sessionID string, conn *
 	session.LastActiveAt = time.Now()
 	session.ExpiresAt = session.LastActiveAt.Add(m.config.MaxIdleTimeout)
 	session.Status = "active"
+	resumed := token != "" && token == session.ResumeToken
+	session.mutex.Unlock()
 	
-	log.Printf("添加WebSocket连接到会话: 会话ID=%s, 连接ID=%s", sessionID, connID)
+	log.Printf("添加WebSocket连接到会话: 会话ID=%s, 连接ID=%s, 续传=%v", sessionID, connID, resumed)
 	
-	// 发送历史消息
-	go m.sendHistoryMessages(session, conn)
+	if resumed {
+		m.sendMissedMessages(session, conn, lastSeq)
+	} else {
+		m.sendHistoryMessages(session, conn)
+	}
 	
 	return nil
 }
@@ -236,58 +266,97 @@ func (m *TerminalSessionManager) RemoveWebSocketConnection(sessionID string, con
 
 // AddMessage 添加消息到会话历史
 func (m *TerminalSessionManager) AddMessage(sessionID string, msgType, content string) error {
+	return m.addMessage(sessionID, TerminalMessage{Type: msgType, Content: content})
+}
+
+// AddOutput 添加终端输出到会话历史，广播完成后返回，调用方据此形成背压
+func (m *TerminalSessionManager) AddOutput(sessionID string, data []byte) error {
+	return m.addMessage(sessionID, TerminalMessage{Type: "output", Content: string(data), Size: len(data)})
+}
+
+// addMessage 记录消息并按顺序广播给所有连接
+func (m *TerminalSessionManager) addMessage(sessionID string, message TerminalMessage) error {
 	session, err := m.GetSession(sessionID)
 	if err != nil {
 		return err
 	}
 	
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
+	// 持有写锁直到广播完成，保证各连接收到的顺序与历史记录一致
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
 	
-	message := TerminalMessage{
-		ID:        uuid.New().String(),
-		Type:      msgType,
-		Content:   content,
-		Timestamp: time.Now(),
-		UserID:    session.UserID,
-	}
+	session.mutex.Lock()
+	session.lastSeq++
+	message.ID = uuid.New().String()
+	message.Seq = session.lastSeq
+	message.Timestamp = time.Now()
+	message.UserID = session.UserID
 	
 	// 添加消息到历史记录
 	session.MessageHistory = append(session.MessageHistory, message)
+	session.historyBytes += len(message.Content)
 	
-	// 限制历史记录大小
-	if len(session.MessageHistory) > session.MaxHistorySize {
-		// 移除最旧的消息
-		copy(session.MessageHistory, session.MessageHistory[1:])
-		session.MessageHistory = session.MessageHistory[:session.MaxHistorySize]
+	// 按条数和字节数限制历史记录大小，移除最旧的消息，至少保留最新的一条
+	drop := 0
+	for drop < len(session.MessageHistory)-1 &&
+		(len(session.MessageHistory)-drop > session.MaxHistorySize ||
+			(session.MaxHistoryBytes > 0 && session.historyBytes > session.MaxHistoryBytes)) {
+		session.historyBytes -= len(session.MessageHistory[drop].Content)
+		drop++
+	}
+	if drop > 0 {
+		session.MessageHistory = append(session.MessageHistory[:0:0], session.MessageHistory[drop:]...)
 	}
+	session.mutex.Unlock()
 	
 	// 广播消息给所有连接
-	go m.broadcastMessage(session, message)
+	m.broadcastMessage(session, message)
 	
 	return nil
 }
 
-// broadcastMessage 广播消息给会话的所有WebSocket连接
+// broadcastMessage 广播消息给会话的所有WebSocket连接，调用方需持有writeMutex
 func (m *TerminalSessionManager) broadcastMessage(session *PersistentTerminalSession, message TerminalMessage) {
 	session.mutex.RLock()
-	defer session.mutex.RUnlock()
-	
+	conns := make(map[string]*websocket.Conn, len(session.wsConnections))
 	for connID, conn := range session.wsConnections {
+		conns[connID] = conn
+	}
+	session.mutex.RUnlock()
+	
+	for connID, conn := range conns {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 		if err := conn.WriteJSON(message); err != nil {
 			log.Printf("发送消息到WebSocket连接失败: 会话ID=%s, 连接ID=%s, 错误=%v", 
 				session.ID, connID, err)
 			// 移除失败的连接
+			session.mutex.Lock()
 			delete(session.wsConnections, connID)
+			session.mutex.Unlock()
 		}
 	}
 }
 
-// sendHistoryMessages 发送历史消息到新连接
+// WriteMessage 向会话的一个连接写入消息，与广播共用写锁
+func (s *PersistentTerminalSession) WriteMessage(conn *websocket.Conn, messageType int, data []byte) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return conn.WriteMessage(messageType, data)
+}
+
+// sendHistoryMessages 发送历史消息到新连接，调用方需持有writeMutex
 func (m *TerminalSessionManager) sendHistoryMessages(session *PersistentTerminalSession, conn *websocket.Conn) {
 	session.mutex.RLock()
 	defer session.mutex.RUnlock()
 	
+	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if err := conn.WriteJSON(ResumeInfo{Type: "resume", Token: session.ResumeToken, LastSeq: session.lastSeq}); err != nil {
+		log.Printf("发送续传信息失败: %v", err)
+		return
+	}
+	
 	// 发送系统消息表示开始历史记录
 	systemMsg := TerminalMessage{
 		ID:        uuid.New().String(),
@@ -322,6 +391,43 @@ func (m *TerminalSessionManager) sendHistoryMessages(session *PersistentTerminal
 	}
 }
 
+// sendMissedMessages 续传时只补发序号大于lastSeq的消息，调用方需持有writeMutex
+func (m *TerminalSessionManager) sendMissedMessages(session *PersistentTerminalSession, conn *websocket.Conn, lastSeq uint64) {
+	session.mutex.RLock()
+	defer session.mutex.RUnlock()
+	
+	missed := make([]TerminalMessage, 0)
+	for _, message := range session.MessageHistory {
+		if message.Seq > lastSeq {
+			missed = append(missed, message)
+		}
+	}
+	
+	// 最旧的历史消息之前还有缺失的消息时，客户端的输出不完整
+	info := ResumeInfo{
+		Type:     "resume",
+		Token:    session.ResumeToken,
+		Resumed:  true,
+		LastSeq:  session.lastSeq,
+		Replayed: len(missed),
+		Gap:      lastSeq < session.lastSeq && (len(session.MessageHistory) == 0 || session.MessageHistory[0].Seq > lastSeq+1),
+	}
+	
+	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if err := conn.WriteJSON(info); err != nil {
+		log.Printf("发送续传信息失败: %v", err)
+		return
+	}
+	for _, message := range missed {
+		if err := conn.WriteJSON(message); err != nil {
+			log.Printf("补发消息失败: %v", err)
+			return
+		}
+	}
+	
+	log.Printf("会话续传: 会话ID=%s, 客户端最后序号=%d, 补发%d条消息, 缺失=%v", session.ID, lastSeq, len(missed), info.Gap)
+}
+
 // CloseSession 关闭会话
 func (m *TerminalSessionManager) CloseSession(sessionID string) error {
 	m.mutex.Lock()
@@ -16,29 +16,31 @@ type TerminalSessionProxy struct {
 	terminal        TerminalSession  // 底层终端实现
 	connectionInfo  *model.Connection
 	inputBuffer     chan []byte
-	outputBuffer    chan []byte
+	flow            *FlowControl // 输出流控，客户端声明支持后启用
 	isActive        bool
 	lastActivity    time.Time
 	mutex           sync.RWMutex
 	ctx             context.Context
 	cancel          context.CancelFunc
 	sessionManager  *TerminalSessionManager
+	authOptions     *SSHAuthOptions // SSH认证附加参数
 }
 
-// NewTerminalSessionProxy 创建终端会话代理
-func NewTerminalSessionProxy(session *PersistentTerminalSession, connectionInfo *model.Connection, sessionManager *TerminalSessionManager) (*TerminalSessionProxy, error) {
+// NewTerminalSessionProxy 创建终端会话代理，authOptions可以为nil
+func NewTerminalSessionProxy(session *PersistentTerminalSession, connectionInfo *model.Connection, sessionManager *TerminalSessionManager, authOptions *SSHAuthOptions) (*TerminalSessionProxy, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	proxy := &TerminalSessionProxy{
 		session:        session,
 		connectionInfo: connectionInfo,
 		inputBuffer:    make(chan []byte, 100),
-		outputBuffer:   make(chan []byte, 100),
+		flow:           NewFlowControl(session.ID),
 		isActive:       false,
 		lastActivity:   time.Now(),
 		ctx:            ctx,
 		cancel:         cancel,
 		sessionManager: sessionManager,
+		authOptions:    authOptions,
 	}
 	
 	// 创建底层终端会话
@@ -61,7 +63,7 @@ func (p *TerminalSessionProxy) createTerminal() error {
 	
 	switch p.session.Protocol {
 	case "ssh":
-		terminal, err = CreateSSHSession(p.connectionInfo)
+		terminal, err = CreateSSHSession(p.connectionInfo, p.authOptions)
 	case "rdp":
 		terminal, err = CreateRDPSession(p.connectionInfo)
 	case "telnet":
@@ -84,13 +86,15 @@ func (p *TerminalSessionProxy) createTerminal() error {
 	return nil
 }
 
-// monitorTerminal 监控终端输出
+// monitorTerminal 监控终端输出，输出通过会话管理器记录并广播给所有连接。
+// 启用流控后信用耗尽时停止读取终端，不会丢弃输出
 func (p *TerminalSessionProxy) monitorTerminal() {
 	defer func() {
 		p.mutex.Lock()
 		p.isActive = false
 		p.mutex.Unlock()
 		
+		p.flow.Release()
 		if p.terminal != nil {
 			p.terminal.Close()
 		}
@@ -98,7 +102,7 @@ func (p *TerminalSessionProxy) monitorTerminal() {
 		log.Printf("终端监控协程结束: 会话ID=%s", p.session.ID)
 	}()
 	
-	buf := make([]byte, 4096)
+	buf := make([]byte, 32*1024)
 	
 	for {
 		select {
@@ -110,13 +114,19 @@ func (p *TerminalSessionProxy) monitorTerminal() {
 				continue
 			}
 			
+			// 等待客户端确认已消费的输出
+			limit, ok := p.flow.Acquire(len(buf), p.ctx.Done())
+			if !ok {
+				return
+			}
+			
 			// 设置读取超时
 			deadline := time.Now().Add(1 * time.Second)
 			if deadlineTerminal, ok := p.terminal.(interface{ SetReadDeadline(time.Time) error }); ok {
 				deadlineTerminal.SetReadDeadline(deadline)
 			}
 			
-			n, err := p.terminal.Read(buf)
+			n, err := p.terminal.Read(buf[:limit])
 			if err != nil {
 				// 超时错误可以继续，其他错误需要处理
 				if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
@@ -130,26 +140,11 @@ func (p *TerminalSessionProxy) monitorTerminal() {
 			}
 			
 			if n > 0 {
-				output := string(buf[:n])
 				p.updateActivity()
 				
-				// 添加输出消息到会话历史
-				p.sessionManager.AddMessage(p.session.ID, "output", output)
-				
-				// 发送到输出缓冲区
-				select {
-				case p.outputBuffer <- buf[:n]:
-				default:
-					// 缓冲区满，丢弃最旧的数据
-					select {
-					case <-p.outputBuffer:
-					default:
-					}
-					select {
-					case p.outputBuffer <- buf[:n]:
-					default:
-					}
-				}
+				// 添加输出消息到会话历史并按顺序广播
+				p.sessionManager.AddOutput(p.session.ID, buf[:n])
+				p.flow.Consume(n)
 			}
 		}
 	}
@@ -182,35 +177,37 @@ func (p *TerminalSessionProxy) processInput() {
 	}
 }
 
-// Write 实现TerminalSession接口
+// Write 发送输入到终端
 func (p *TerminalSessionProxy) Write(data []byte) (int, error) {
 	select {
 	case p.inputBuffer <- data:
 		return len(data), nil
+	case <-p.ctx.Done():
+		return 0, fmt.Errorf("终端会话已关闭")
 	case <-time.After(5 * time.Second):
 		return 0, fmt.Errorf("写入超时")
 	}
 }
 
-// Read 实现TerminalSession接口
-func (p *TerminalSessionProxy) Read(buf []byte) (int, error) {
-	select {
-	case data := <-p.outputBuffer:
-		n := copy(buf, data)
-		return n, nil
-	case <-time.After(30 * time.Second):
-		return 0, fmt.Errorf("读取超时")
-	}
+// EnableFlowControl 启用输出流控，返回实际使用的窗口。会话的所有连接共享同一窗口
+func (p *TerminalSessionProxy) EnableFlowControl(window int) int {
+	return p.flow.Enable(window)
+}
+
+// ResetFlowControl 客户端续传时清零未确认的输出
+func (p *TerminalSessionProxy) ResetFlowControl() {
+	p.flow.Reset()
 }
 
-// Close 实现TerminalSession接口
+// AckOutput 客户端确认已消费n字节输出
+func (p *TerminalSessionProxy) AckOutput(n int) {
+	p.flow.Ack(n)
+}
+
+// Close 关闭终端会话代理，输入缓冲区不关闭，由上下文通知各协程退出
 func (p *TerminalSessionProxy) Close() error {
 	p.cancel()
 	
-	// 关闭缓冲区
-	close(p.inputBuffer)
-	close(p.outputBuffer)
-	
 	// 关闭底层终端
 	if p.terminal != nil {
 		return p.terminal.Close()
@@ -255,8 +252,8 @@ func (p *TerminalSessionProxy) SendInput(input string) error {
 // 工厂函数用于创建不同类型的终端会话
 
 // CreateSSHSession 创建SSH会话
-func CreateSSHSession(conn *model.Connection) (TerminalSession, error) {
-	terminal, err := createSSHTerminalSession(conn)
+func CreateSSHSession(conn *model.Connection, authOptions *SSHAuthOptions) (TerminalSession, error) {
+	terminal, err := createSSHTerminalSession(conn, authOptions)
 	
 	if err != nil {
 		return nil, fmt.Errorf("SSH连接失败: %w", err)
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+// tokenDenylistRefreshInterval 从数据库同步吊销名单的间隔，
+// 用于感知其他实例写入的吊销记录
+const tokenDenylistRefreshInterval = 30 * time.Second
+
+// TokenDenylist 访问令牌吊销名单。
+// 吊销记录持久化到数据库，校验令牌时只查询内存缓存
+type TokenDenylist struct {
+	repo    model.RevokedTokenRepository
+	mutex   sync.RWMutex
+	entries map[string]time.Time // jti -> 令牌过期时间
+	stop    chan struct{}
+}
+
+// 全局访问令牌吊销名单实例
+var globalTokenDenylist *TokenDenylist
+
+// InitTokenDenylist 初始化全局访问令牌吊销名单并启动后台同步
+func InitTokenDenylist(repo model.RevokedTokenRepository) *TokenDenylist {
+	denylist := &TokenDenylist{
+		repo:    repo,
+		entries: make(map[string]time.Time),
+		stop:    make(chan struct{}),
+	}
+	if err := denylist.reload(); err != nil {
+		log.Printf("加载访问令牌吊销名单失败: %v", err)
+	}
+	go denylist.refreshLoop()
+
+	globalTokenDenylist = denylist
+	return denylist
+}
+
+// GetTokenDenylist 获取全局访问令牌吊销名单，未初始化时返回nil
+func GetTokenDenylist() *TokenDenylist {
+	return globalTokenDenylist
+}
+
+// IsRevoked 判断访问令牌是否已被吊销
+func (d *TokenDenylist) IsRevoked(jti string) bool {
+	if d == nil || jti == "" {
+		return false
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	_, ok := d.entries[jti]
+	return ok
+}
+
+// Revoke 吊销访问令牌，expiresAt为令牌本身的过期时间，过期后记录可被清理
+func (d *TokenDenylist) Revoke(jti string, userID uint, expiresAt time.Time) error {
+	if d == nil || jti == "" || !time.Now().Before(expiresAt) {
+		return nil
+	}
+
+	if err := d.repo.Add(&model.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	d.entries[jti] = expiresAt
+	d.mutex.Unlock()
+	return nil
+}
+
+// Stop 停止后台同步
+func (d *TokenDenylist) Stop() {
+	close(d.stop)
+}
+
+// refreshLoop 定期从数据库同步吊销名单并清理过期记录
+func (d *TokenDenylist) refreshLoop() {
+	ticker := time.NewTicker(tokenDenylistRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.reload(); err != nil {
+				log.Printf("同步访问令牌吊销名单失败: %v", err)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// reload 清理数据库中的过期记录并重建内存缓存
+func (d *TokenDenylist) reload() error {
+	now := time.Now()
+	if err := d.repo.DeleteExpired(now); err != nil {
+		return err
+	}
+
+	tokens, err := d.repo.GetActive(now)
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]time.Time, len(tokens))
+	for _, token := range tokens {
+		entries[token.JTI] = token.ExpiresAt
+	}
+
+	// 吊销不可撤销，保留同步期间本实例新增且未过期的记录
+	d.mutex.Lock()
+	for jti, expiresAt := range d.entries {
+		if expiresAt.After(now) {
+			entries[jti] = expiresAt
+		}
+	}
+	d.entries = entries
+	d.mutex.Unlock()
+	return nil
+}
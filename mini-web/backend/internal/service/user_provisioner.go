@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gitee.com/await29/mini-web/internal/config"
+	"gitee.com/await29/mini-web/internal/model"
+)
+
+var (
+	// ErrAuthSourceMismatch 同名账号属于其他身份来源
+	ErrAuthSourceMismatch = errors.New("该用户名已被其他来源的账号占用")
+)
+
+// UserProvisioner 外部身份源用户的即时创建与同步。
+// 首次登录时创建users记录，之后每次登录按组映射同步角色和团队成员关系
+type UserProvisioner struct {
+	userRepo model.UserRepository
+	teamRepo model.TeamRepository
+	roles    *RoleService
+}
+
+// NewUserProvisioner 创建外部用户同步器
+func NewUserProvisioner(userRepo model.UserRepository, teamRepo model.TeamRepository, roles *RoleService) *UserProvisioner {
+	return &UserProvisioner{userRepo: userRepo, teamRepo: teamRepo, roles: roles}
+}
+
+// Provision 创建或更新外部用户。
+// roleMap非空时角色由组映射决定，未匹配任何组时使用defaultRole；
+// roleMap为空时只为新用户设置defaultRole，已有用户的角色由管理员维护
+func (p *UserProvisioner) Provision(identity *ExternalIdentity, roleMap, teamMap []config.GroupMapping, defaultRole string) (*model.User, error) {
+	user, err := p.userRepo.GetByUsername(identity.Username)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	role := p.resolveRole(identity.Groups, roleMap, defaultRole)
+
+	if user == nil {
+		user, err = p.createUser(identity, role)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if user.AuthSource != identity.Source {
+			return nil, ErrAuthSourceMismatch
+		}
+
+		if identity.Name != "" {
+			user.Nickname = identity.Name
+		}
+		if len(roleMap) > 0 && role != user.Role {
+			log.Printf("按目录组同步用户角色: 用户=%s, %s -> %s", user.Username, user.Role, role)
+			user.Role = role
+		}
+		if err := p.userRepo.Update(user); err != nil {
+			return nil, fmt.Errorf("更新用户失败: %w", err)
+		}
+	}
+
+	if len(teamMap) > 0 {
+		if err := p.syncTeams(user.ID, identity.Groups, teamMap); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// createUser 创建外部用户，本地密码为随机值，不能用于本地登录
+func (p *UserProvisioner) createUser(identity *ExternalIdentity, role string) (*model.User, error) {
+	password, err := randomTokenString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// 邮箱缺失或已被其他账号使用时使用占位地址，保证唯一约束
+	email := identity.Email
+	if email != "" {
+		existing, err := p.userRepo.GetByEmail(email)
+		if err != nil {
+			return nil, fmt.Errorf("检查邮箱失败: %w", err)
+		}
+		if existing != nil {
+			email = ""
+		}
+	}
+	if email == "" {
+		email = fmt.Sprintf("%s@%s.invalid", identity.Username, identity.Source)
+	}
+
+	nickname := identity.Name
+	if nickname == "" {
+		nickname = identity.Username
+	}
+
+	user := &model.User{
+		Username:   identity.Username,
+		Email:      email,
+		Password:   password,
+		Nickname:   nickname,
+		Avatar:     "https://randomuser.me/api/portraits/lego/1.jpg",
+		Role:       role,
+		Status:     "active",
+		AuthSource: identity.Source,
+	}
+	if err := p.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("创建用户失败: %w", err)
+	}
+
+	log.Printf("已自动创建%s用户: 用户名=%s, 角色=%s", identity.Source, user.Username, user.Role)
+	return user, nil
+}
+
+// resolveRole 按映射顺序取第一个匹配且存在的角色
+func (p *UserProvisioner) resolveRole(groups []string, roleMap []config.GroupMapping, defaultRole string) string {
+	for _, mapping := range roleMap {
+		if !containsGroup(groups, mapping.Group) {
+			continue
+		}
+		exists, err := p.roles.RoleExists(mapping.Target)
+		if err != nil || !exists {
+			log.Printf("组映射的角色不存在，已忽略: 组=%s, 角色=%s", mapping.Group, mapping.Target)
+			continue
+		}
+		return mapping.Target
+	}
+
+	if defaultRole == "" {
+		return model.RoleUser
+	}
+	return defaultRole
+}
+
+// syncTeams 同步映射团队的成员关系：加入匹配的团队，退出不再匹配的团队。
+// 不在映射中的团队不受影响，团队所有者不会被自动移除
+func (p *UserProvisioner) syncTeams(userID uint, groups []string, teamMap []config.GroupMapping) error {
+	teams, err := p.teamRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("获取团队列表失败: %w", err)
+	}
+	teamsByName := make(map[string]*model.Team, len(teams))
+	for _, team := range teams {
+		teamsByName[strings.ToLower(team.Name)] = team
+	}
+
+	desired := make(map[uint]bool)
+	for _, mapping := range teamMap {
+		team, ok := teamsByName[strings.ToLower(mapping.Target)]
+		if !ok {
+			log.Printf("组映射的团队不存在，已忽略: 组=%s, 团队=%s", mapping.Group, mapping.Target)
+			continue
+		}
+		if containsGroup(groups, mapping.Group) {
+			desired[team.ID] = true
+		} else if _, set := desired[team.ID]; !set {
+			desired[team.ID] = false
+		}
+	}
+
+	for teamID, join := range desired {
+		member, err := p.teamRepo.GetMember(teamID, userID)
+		if err != nil {
+			return fmt.Errorf("查询团队成员失败: %w", err)
+		}
+
+		switch {
+		case join && member == nil:
+			if err := p.teamRepo.SaveMember(&model.TeamMember{TeamID: teamID, UserID: userID, Role: model.TeamRoleMember}); err != nil {
+				return err
+			}
+		case !join && member != nil && member.Role != model.TeamRoleOwner:
+			if err := p.teamRepo.RemoveMember(teamID, userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// containsGroup 判断组列表中是否有与映射匹配的组
+func containsGroup(groups []string, pattern string) bool {
+	for _, group := range groups {
+		if matchGroup(group, pattern) {
+			return true
+		}
+	}
+	return false
+}
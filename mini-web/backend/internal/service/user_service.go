@@ -9,12 +9,16 @@ import (
 
 // UserService 用户服务
 type UserService struct {
-	userRepo model.UserRepository
+	userRepo  model.UserRepository
+	roles     *RoleService
+	passwords *PasswordService
+	guard     *LoginGuard
+	auth      *AuthService
 }
 
 // NewUserService 创建用户服务实例
-func NewUserService(userRepo model.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo model.UserRepository, roles *RoleService, passwords *PasswordService, guard *LoginGuard, auth *AuthService) *UserService {
+	return &UserService{userRepo: userRepo, roles: roles, passwords: passwords, guard: guard, auth: auth}
 }
 
 // GetUsers 获取所有用户
@@ -58,6 +62,16 @@ func (s *UserService) CreateUser(user *model.User) error {
 		return errors.New("邮箱已存在")
 	}
 	
+	// 检查角色是否存在
+	if err := s.validateRole(user.Role); err != nil {
+		return err
+	}
+	
+	// 检查密码策略
+	if err := s.passwords.CheckNewPassword(user.Username, user.Password); err != nil {
+		return err
+	}
+	
 	// 创建用户
 	return s.userRepo.Create(user)
 }
@@ -74,8 +88,38 @@ func (s *UserService) UpdateUser(user *model.User) error {
 		return errors.New("用户不存在")
 	}
 	
+	// 修改角色时检查角色是否存在
+	if user.Role != existingUser.Role {
+		if err := s.validateRole(user.Role); err != nil {
+			return err
+		}
+	}
+	
 	// 更新用户
-	return s.userRepo.Update(user)
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	// 已签发的令牌携带旧角色，角色变更或禁用后吊销全部登录会话，强制重新登录
+	if user.Role != existingUser.Role || user.Status != existingUser.Status {
+		return s.auth.RevokeUserTokens(user.ID)
+	}
+	return nil
+}
+
+// validateRole 检查角色是否存在
+func (s *UserService) validateRole(role string) error {
+	if s.roles == nil {
+		return nil
+	}
+	exists, err := s.roles.RoleExists(role)
+	if err != nil {
+		return fmt.Errorf("检查角色失败: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+	}
+	return nil
 }
 
 // DeleteUser 删除用户
@@ -96,7 +140,23 @@ func (s *UserService) DeleteUser(id uint) error {
 	}
 	
 	// 删除用户
-	return s.userRepo.Delete(id)
+	if err := s.userRepo.Delete(id); err != nil {
+		return err
+	}
+	
+	// 吊销已签发的令牌，已删除的用户不能继续使用未过期的访问令牌
+	if err := s.auth.RevokeUserTokens(id); err != nil {
+		return err
+	}
+
+	// 清理密码历史、登录保护记录和个人通知渠道
+	if err := s.passwords.DeleteHistory(id); err != nil {
+		return err
+	}
+	if err := s.guard.DeleteUser(id); err != nil {
+		return err
+	}
+	return GetNotificationService().DeleteUserChannels(id)
 }
 
 // BatchUpdateUserStatus 批量更新用户状态
@@ -110,7 +170,19 @@ func (s *UserService) BatchUpdateUserStatus(userIDs []uint, status string) error
 		return errors.New("无效的状态值")
 	}
 	
-	return s.userRepo.BatchUpdateStatus(userIDs, status)
+	if err := s.userRepo.BatchUpdateStatus(userIDs, status); err != nil {
+		return err
+	}
+
+	// 禁用的用户立即下线
+	if status != "active" {
+		for _, id := range userIDs {
+			if err := s.auth.RevokeUserTokens(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // BatchDeleteUsers 批量删除用户
@@ -119,25 +191,29 @@ func (s *UserService) BatchDeleteUsers(userIDs []uint) error {
 		return errors.New("用户ID列表不能为空")
 	}
 	
-	// 检查是否包含管理员用户
+	// 检查是否包含管理员用户，跳过已不存在的用户
+	var existingIDs []uint
 	for _, id := range userIDs {
 		user, err := s.userRepo.GetByID(id)
 		if err != nil {
 			return fmt.Errorf("检查用户ID %d 失败: %w", id, err)
 		}
-		if user != nil && user.Role == "admin" {
+		if user == nil {
+			continue
+		}
+		if user.Role == "admin" {
 			return errors.New("不能删除管理员用户")
 		}
+		existingIDs = append(existingIDs, id)
 	}
-	
-	// 逐个删除用户
-	for _, id := range userIDs {
-		err := s.userRepo.Delete(id)
-		if err != nil {
+
+	// 逐个删除用户，与单个删除一样吊销令牌并清理关联数据
+	for _, id := range existingIDs {
+		if err := s.DeleteUser(id); err != nil {
 			return fmt.Errorf("删除用户ID %d 失败: %w", id, err)
 		}
 	}
-	
+
 	return nil
 }
 
@@ -163,6 +239,11 @@ func (s *UserService) UpdatePassword(userID uint, oldPassword, newPassword strin
 		return errors.New("旧密码错误")
 	}
 	
-	// 更新密码
-	return s.userRepo.UpdatePassword(userID, newPassword)
+	// 按密码策略更新密码
+	if err := s.passwords.ChangePassword(user, newPassword); err != nil {
+		return err
+	}
+
+	// 与找回密码一致，修改密码后吊销全部登录会话
+	return s.auth.RevokeUserTokens(userID)
 }
\ No newline at end of file
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeleteUsersRevokeSessions(t *testing.T) {
+	s := newTestServices(t)
+	users := NewUserService(s.users, s.roles, s.passwords, s.guard, s.auth)
+
+	single := uniqueName("deluser")
+	s.createLocalUser(t, single, single+"@example.com", "Passw0rd!")
+	batch := uniqueName("deluser")
+	s.createLocalUser(t, batch, batch+"@example.com", "Passw0rd!")
+
+	for _, tc := range []struct {
+		username string
+		delete   func(id uint) error
+	}{
+		{single, users.DeleteUser},
+		{batch, func(id uint) error { return users.BatchDeleteUsers([]uint{id, 0}) }},
+	} {
+		session, err := s.auth.Login(tc.username, "Passw0rd!", "test-agent", "192.0.2.40")
+		if err != nil {
+			t.Fatalf("%s Login() error = %v", tc.username, err)
+		}
+		if err := tc.delete(session.User.ID); err != nil {
+			t.Fatalf("%s 删除用户 error = %v", tc.username, err)
+		}
+
+		if _, err := s.auth.VerifyToken(session.Token); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("%s 删除后 VerifyToken() error = %v, want ErrTokenRevoked", tc.username, err)
+		}
+		if _, err := s.auth.RefreshToken(session.RefreshToken, "test-agent", "192.0.2.40"); err == nil {
+			t.Errorf("%s 删除后刷新令牌应失效", tc.username)
+		}
+	}
+}
+
+func TestUpdatePasswordRevokesSessions(t *testing.T) {
+	s := newTestServices(t)
+	users := NewUserService(s.users, s.roles, s.passwords, s.guard, s.auth)
+
+	username := uniqueName("pwduser")
+	s.createLocalUser(t, username, username+"@example.com", "Old-Pass-123")
+	session, err := s.auth.Login(username, "Old-Pass-123", "test-agent", "192.0.2.41")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if err := users.UpdatePassword(session.User.ID, "Old-Pass-123", "New-Pass-456"); err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+	if _, err := s.auth.VerifyToken(session.Token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("修改密码后 VerifyToken() error = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := s.auth.RefreshToken(session.RefreshToken, "test-agent", "192.0.2.41"); err == nil {
+		t.Error("修改密码后原有登录会话应被吊销")
+	}
+	if _, err := s.auth.Login(username, "New-Pass-456", "test-agent", "192.0.2.41"); err != nil {
+		t.Errorf("新密码 Login() error = %v", err)
+	}
+}
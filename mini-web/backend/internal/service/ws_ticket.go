@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// WebSocket连接票据：前端先用访问令牌换取短期一次性票据，再在WebSocket地址中携带票据，
+// 访问令牌不再出现在查询字符串中，也就不会被代理和nginx写入访问日志。
+// 票据绑定用户、会话和客户端地址，使用一次后即失效
+const DefaultWSTicketTTL = 30 * time.Second
+
+var (
+	// ErrWSTicketInvalid 票据不存在、已使用或已过期
+	ErrWSTicketInvalid = errors.New("WebSocket票据无效或已过期")
+	// ErrWSTicketMismatch 票据与请求的会话或客户端地址不匹配
+	ErrWSTicketMismatch = errors.New("WebSocket票据与会话或客户端地址不匹配")
+)
+
+// wsTicket 已签发的票据
+type wsTicket struct {
+	userID    uint
+	sessionID string
+	clientIP  string
+	expiresAt time.Time
+}
+
+// WSTicketStore 内存中的WebSocket票据，只保存票据的哈希
+type WSTicketStore struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	tickets map[string]*wsTicket
+}
+
+// NewWSTicketStore 创建票据存储，ttl为票据有效期
+func NewWSTicketStore(ttl time.Duration) *WSTicketStore {
+	if ttl <= 0 {
+		ttl = DefaultWSTicketTTL
+	}
+	return &WSTicketStore{ttl: ttl, tickets: make(map[string]*wsTicket)}
+}
+
+// 全局WebSocket票据存储
+var globalWSTicketStore = NewWSTicketStore(DefaultWSTicketTTL)
+
+// InitWSTicketStore 按配置的有效期初始化全局票据存储
+func InitWSTicketStore(ttl time.Duration) *WSTicketStore {
+	globalWSTicketStore = NewWSTicketStore(ttl)
+	return globalWSTicketStore
+}
+
+// GetWSTicketStore 获取全局票据存储
+func GetWSTicketStore() *WSTicketStore {
+	return globalWSTicketStore
+}
+
+// Issue 为用户签发连接指定会话的票据，返回票据和过期时间
+func (s *WSTicketStore) Issue(userID uint, sessionID, clientIP string) (string, time.Time, error) {
+	ticket, err := randomTokenString(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(s.ttl)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// 签发时顺便清理过期票据，票据数量与并发连接数相当，无需后台清理
+	now := time.Now()
+	for key, t := range s.tickets {
+		if now.After(t.expiresAt) {
+			delete(s.tickets, key)
+		}
+	}
+
+	s.tickets[hashSecretToken(ticket)] = &wsTicket{
+		userID:    userID,
+		sessionID: sessionID,
+		clientIP:  clientIP,
+		expiresAt: expiresAt,
+	}
+	return ticket, expiresAt, nil
+}
+
+// Redeem 使用票据，返回票据所属的用户。票据无论是否匹配都会被作废
+func (s *WSTicketStore) Redeem(ticket, sessionID, clientIP string) (uint, error) {
+	if ticket == "" {
+		return 0, ErrWSTicketInvalid
+	}
+	key := hashSecretToken(ticket)
+
+	s.mutex.Lock()
+	t, exists := s.tickets[key]
+	delete(s.tickets, key)
+	s.mutex.Unlock()
+
+	if !exists || time.Now().After(t.expiresAt) {
+		return 0, ErrWSTicketInvalid
+	}
+	if t.sessionID != sessionID || t.clientIP != clientIP {
+		return 0, ErrWSTicketMismatch
+	}
+	return t.userID, nil
+}
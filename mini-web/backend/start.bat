@@ -34,6 +34,9 @@ if exist "%FREERDP_PATH%" (
 )
 echo.
 
+rem 开发环境前端运行在Vite端口，需允许其发起WebSocket连接
+if not defined WS_ALLOWED_ORIGINS set "WS_ALLOWED_ORIGINS=http://localhost:5173,http://127.0.0.1:5173"
+
 rem 下载所有依赖
 echo 下载依赖中...
 go mod download github.com/gorilla/mux
@@ -39,6 +39,9 @@ echo
 export RDP_SCREENSHOT_INTERVAL=1000  # 屏幕截图间隔（毫秒）
 export RDP_JPEG_QUALITY=80           # JPEG压缩质量
 
+# 开发环境前端运行在Vite端口，需允许其发起WebSocket连接
+export WS_ALLOWED_ORIGINS=${WS_ALLOWED_ORIGINS:-http://localhost:5173,http://127.0.0.1:5173}
+
 # 下载所有依赖
 echo "下载依赖中..."
 go mod download github.com/gorilla/mux
@@ -1,5 +1,6 @@
 import { useCallback } from 'react';
 import type { RefObject } from 'react';
+import { createTicketWebSocket } from '../services/ticketWebSocket';
 
 // Tab类型定义
 interface Connection {
@@ -44,16 +45,13 @@ export const useWebSocketManager = () => {
             const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
             const host = window.location.host;
 
-            // 获取认证令牌
-            const token = localStorage.getItem('token');
-
             // 修改WebSocket URL以匹配后端路由格式 /ws/{protocol}/{sessionId}
             // 确保使用正确的协议和会话ID
             const wsProtocol = tab.connection.protocol || 'ssh'; // 默认使用ssh
             const sessionId = tab.sessionId || 0;
 
-            // 添加token参数到URL以解决认证问题
-            const wsUrl = `${protocol}//${host}/ws/${wsProtocol}/${sessionId}?token=${token}`;
+            // 认证使用连接前申请的一次性票据，票据在创建连接时附加到URL
+            const wsUrl = `${protocol}//${host}/ws/${wsProtocol}/${sessionId}`;
 
             console.log(`【WebSocket】准备创建连接，协议类型: ${wsProtocol}, 是否RDP: ${wsProtocol === 'rdp'}`);
             console.log(`【WebSocket】RDP连接URL: ${wsUrl}`);
@@ -64,7 +62,7 @@ export const useWebSocketManager = () => {
             }
 
             // 创建新连接
-            const ws = new WebSocket(wsUrl);
+            const ws = createTicketWebSocket(wsUrl, sessionId);
             tab.webSocketRef.current = ws;
 
             // 打开连接
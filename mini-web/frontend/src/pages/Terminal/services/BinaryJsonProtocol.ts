@@ -15,8 +15,12 @@ export const PROTOCOL_CONSTANTS = {
         BINARY_ONLY: 0x02,
         MIXED: 0x03,
         HEARTBEAT: 0x04,
-        PROTOCOL_NEGOTIATION: 0x05
+        PROTOCOL_NEGOTIATION: 0x05,
+        FLOW_CONTROL: 0x06,
+        CHANNEL_OPEN: 0x07,
+        CHANNEL_CLOSE: 0x08
     },
+    CONTROL_CHANNEL: 0,
     COMPRESSION_TYPES: {
         NONE: 0x00,
         GZIP: 0x01,
@@ -31,7 +35,7 @@ export interface MessageHeader {
     compressionFlag: number; // 1字节 - 压缩标志
     jsonLength: number;     // 4字节 - JSON部分长度
     binaryLength: number;   // 4字节 - 二进制部分长度
-    reserved: number;       // 2字节 - 保留字段
+    channelId: number;      // 2字节 - 多路复用通道ID，0为控制通道
 }
 
 // 协议消息接口
@@ -47,6 +51,9 @@ export interface ProtocolNegotiation {
     supportedCompressions: number[];
     maxMessageSize: number;
     features: string[];
+    compression?: number;           // 服务端响应中为选定的压缩方式
+    compressionThreshold?: number;
+    flowControlWindow?: number;     // 请求的流控窗口，服务端响应中为实际窗口，0表示不启用
 }
 
 /**
@@ -66,12 +73,12 @@ export class BinaryJsonProtocol {
      */
     private initializeCompression(): void {
         try {
-            // 检测浏览器是否支持压缩
-            if (typeof CompressionStream !== 'undefined') {
+            // 检测浏览器是否支持gzip解压，LZ4由本地实现解压，始终支持
+            if (typeof DecompressionStream !== 'undefined') {
                 this.compressionSupported = true;
                 console.log('BinaryJsonProtocol: 压缩支持已启用');
             } else {
-                console.log('BinaryJsonProtocol: 浏览器不支持压缩，使用无压缩模式');
+                console.log('BinaryJsonProtocol: 浏览器不支持gzip解压，仅使用LZ4压缩');
             }
         } catch (error) {
             console.warn('BinaryJsonProtocol: 压缩初始化失败:', error);
@@ -84,7 +91,8 @@ export class BinaryJsonProtocol {
     async encodeMessage(
         jsonData?: any,
         binaryData?: ArrayBuffer,
-        compression: number = PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE
+        compression: number = PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE,
+        channelId: number = PROTOCOL_CONSTANTS.CONTROL_CHANNEL
     ): Promise<ArrayBuffer> {
         // 确定消息类型
         let messageType: number;
@@ -120,7 +128,7 @@ export class BinaryJsonProtocol {
             compressionFlag: compression,
             jsonLength: jsonBuffer.byteLength,
             binaryLength: binaryBuffer.byteLength,
-            reserved: 0
+            channelId
         };
 
         // 编码头部
@@ -190,6 +198,9 @@ export class BinaryJsonProtocol {
         // 解析二进制数据
         if (header.binaryLength > 0) {
             binaryData = data.slice(offset, offset + header.binaryLength);
+            if (header.compressionFlag !== PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE) {
+                binaryData = await this.decompress(binaryData, header.compressionFlag);
+            }
         }
 
         return {
@@ -199,6 +210,99 @@ export class BinaryJsonProtocol {
         };
     }
 
+    /**
+     * 解压二进制数据
+     */
+    private async decompress(data: ArrayBuffer, compression: number): Promise<ArrayBuffer> {
+        switch (compression) {
+            case PROTOCOL_CONSTANTS.COMPRESSION_TYPES.GZIP: {
+                const stream = new Blob([data]).stream().pipeThrough(new DecompressionStream('gzip'));
+                return await new Response(stream).arrayBuffer();
+            }
+            case PROTOCOL_CONSTANTS.COMPRESSION_TYPES.LZ4: {
+                // 4字节大端原始长度 + LZ4块
+                if (data.byteLength < 4) {
+                    throw new Error('LZ4数据损坏');
+                }
+                const size = new DataView(data).getUint32(0, false);
+                if (size > this.maxMessageSize) {
+                    throw new Error(`解压后超过最大消息长度${this.maxMessageSize}`);
+                }
+                return this.decompressLZ4Block(new Uint8Array(data, 4), size);
+            }
+            default:
+                throw new Error(`不支持的压缩方式: ${compression}`);
+        }
+    }
+
+    /**
+     * 解压LZ4块，size为解压后的长度
+     */
+    private decompressLZ4Block(src: Uint8Array, size: number): ArrayBuffer {
+        const dst = new Uint8Array(size);
+        let i = 0;
+        let o = 0;
+
+        const readLength = (): number => {
+            let n = 0;
+            let b: number;
+            do {
+                if (i >= src.length) {
+                    throw new Error('LZ4数据损坏');
+                }
+                b = src[i++];
+                n += b;
+            } while (b === 255);
+            return n;
+        };
+
+        while (i < src.length) {
+            const token = src[i++];
+
+            let literalLength = token >> 4;
+            if (literalLength === 15) {
+                literalLength += readLength();
+            }
+            if (literalLength > src.length - i || literalLength > size - o) {
+                throw new Error('LZ4数据损坏');
+            }
+            dst.set(src.subarray(i, i + literalLength), o);
+            i += literalLength;
+            o += literalLength;
+            if (i === src.length) {
+                break;
+            }
+
+            if (i + 2 > src.length) {
+                throw new Error('LZ4数据损坏');
+            }
+            const offset = src[i] | (src[i + 1] << 8);
+            i += 2;
+            if (offset === 0 || offset > o) {
+                throw new Error('LZ4数据损坏');
+            }
+
+            let matchLength = token & 15;
+            if (matchLength === 15) {
+                matchLength += readLength();
+            }
+            matchLength += 4;
+            if (matchLength > size - o) {
+                throw new Error('LZ4数据损坏');
+            }
+
+            // 匹配可以与输出重叠，逐字节复制
+            for (let k = 0; k < matchLength; k++, o++) {
+                dst[o] = dst[o - offset];
+            }
+        }
+
+        if (o !== size) {
+            throw new Error('LZ4数据损坏');
+        }
+        return dst.buffer as ArrayBuffer;
+    }
+
     /**
      * 编码消息头
      */
@@ -211,7 +315,7 @@ export class BinaryJsonProtocol {
         view.setUint8(5, header.compressionFlag);
         view.setUint32(6, header.jsonLength, false);
         view.setUint32(10, header.binaryLength, false);
-        view.setUint16(14, header.reserved, false);
+        view.setUint16(14, header.channelId, false);
 
         return buffer;
     }
@@ -228,7 +332,7 @@ export class BinaryJsonProtocol {
             compressionFlag: view.getUint8(5),
             jsonLength: view.getUint32(6, false),
             binaryLength: view.getUint32(10, false),
-            reserved: view.getUint16(14, false)
+            channelId: view.getUint16(14, false)
         };
     }
 
@@ -236,7 +340,10 @@ export class BinaryJsonProtocol {
      * 创建协议协商消息
      */
     createNegotiationMessage(): ProtocolNegotiation {
-        const supportedCompressions: number[] = [PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE];
+        const supportedCompressions: number[] = [
+            PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE,
+            PROTOCOL_CONSTANTS.COMPRESSION_TYPES.LZ4
+        ];
 
         if (this.compressionSupported) {
             supportedCompressions.push(PROTOCOL_CONSTANTS.COMPRESSION_TYPES.GZIP);
@@ -246,7 +353,8 @@ export class BinaryJsonProtocol {
             version: this.protocolVersion,
             supportedCompressions,
             maxMessageSize: this.maxMessageSize,
-            features: ['file-transfer', 'terminal-output', 'command-execution']
+            features: ['file-transfer', 'terminal-output', 'command-execution', 'flow-control'],
+            flowControlWindow: 256 * 1024
         };
     }
 
@@ -267,6 +375,24 @@ export class BinaryJsonProtocol {
         return buffer;
     }
 
+    /**
+     * 创建流控确认消息，bytes为自上次确认后处理的终端输出字节数
+     */
+    async createFlowControlAck(bytes: number, channelId: number = PROTOCOL_CONSTANTS.CONTROL_CHANNEL): Promise<ArrayBuffer> {
+        const message = await this.encodeMessage({ bytes }, undefined, PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE, channelId);
+        new DataView(message).setUint8(4, PROTOCOL_CONSTANTS.MESSAGE_TYPES.FLOW_CONTROL);
+        return message;
+    }
+
+    /**
+     * 创建多路复用通道的控制消息（打开或关闭通道）
+     */
+    async createChannelMessage(messageType: number, channelId: number, jsonData: any): Promise<ArrayBuffer> {
+        const message = await this.encodeMessage(jsonData, undefined, PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE, channelId);
+        new DataView(message).setUint8(4, messageType);
+        return message;
+    }
+
     /**
      * 生成客户端ID
      */
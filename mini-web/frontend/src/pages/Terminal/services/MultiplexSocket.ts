@@ -0,0 +1,343 @@
+/*
+ * @Author: Await
+ * @Date: 2025-06-08 10:00:00
+ * @LastEditors: Await
+ * @LastEditTime: 2025-06-08 10:00:00
+ * @Description: 多路复用WebSocket客户端，一个连接承载多个终端和文件管理通道
+ */
+
+import { API_BASE_URL } from '../../../services/api';
+import { appendTicket, fetchWebSocketTicket } from '../../../services/ticketWebSocket';
+import binaryJsonProtocol, {
+    PROTOCOL_CONSTANTS,
+    type ProtocolMessage,
+    type ProtocolNegotiation
+} from './BinaryJsonProtocol';
+
+const { MESSAGE_TYPES, CONTROL_CHANNEL } = PROTOCOL_CONSTANTS;
+
+// 单个连接允许的最大通道ID，与服务端上限无关，仅用于分配
+const MAX_CHANNEL_ID = 0xFFFF;
+const HEARTBEAT_INTERVAL = 30000;
+const DEFAULT_FLOW_CONTROL_WINDOW = 256 * 1024;
+const MUX_TICKET_SESSION_ID = 'mux';
+
+export type ChannelKind = 'terminal' | 'files';
+
+// 打开通道的参数
+export interface ChannelOpenOptions {
+    kind: ChannelKind;
+    sessionId?: number;         // 终端通道对应的会话
+    attach?: number;            // 文件管理通道关联的终端通道
+    flowControlWindow?: number; // 终端通道的流控窗口，0表示不启用
+}
+
+// 通道事件回调
+export interface ChannelHandlers {
+    onOpen?: (info: any) => void;
+    onMessage?: (jsonData: any, binaryData?: ArrayBuffer) => void;
+    onClose?: (reason: string) => void;
+}
+
+/**
+ * 多路复用通道
+ */
+export class MultiplexChannel {
+    private opened = false;
+    private closed = false;
+    private pendingAck = 0;
+    private ackScheduled = false;
+    private flowControlWindow = 0;
+
+    constructor(
+        readonly id: number,
+        readonly kind: ChannelKind,
+        private readonly socket: MultiplexSocket,
+        private readonly handlers: ChannelHandlers
+    ) { }
+
+    get isOpen(): boolean {
+        return this.opened && !this.closed;
+    }
+
+    /**
+     * 发送终端输入
+     */
+    sendInput(data: string): void {
+        this.send({ type: 'command', content: data });
+    }
+
+    /**
+     * 调整终端大小
+     */
+    resize(cols: number, rows: number): void {
+        this.send({ type: 'resize', cols, rows });
+    }
+
+    /**
+     * 发送JSON和可选的二进制数据，如文件管理请求和上传分片
+     */
+    send(jsonData: any, binaryData?: ArrayBuffer): void {
+        if (this.closed) {
+            return;
+        }
+        this.socket.sendOnChannel(this.id, jsonData, binaryData);
+    }
+
+    /**
+     * 关闭通道
+     */
+    close(): void {
+        if (this.closed) {
+            return;
+        }
+        this.socket.closeChannel(this.id);
+    }
+
+    /** @internal */
+    handleOpen(info: any): void {
+        this.opened = true;
+        this.flowControlWindow = info?.flowControlWindow || 0;
+        this.handlers.onOpen?.(info);
+    }
+
+    /** @internal */
+    handleMessage(message: ProtocolMessage): void {
+        this.handlers.onMessage?.(message.jsonData, message.binaryData);
+
+        if (this.flowControlWindow > 0 && message.jsonData?.type === 'terminal-output' && !message.jsonData?.auth) {
+            this.acknowledge(message.binaryData?.byteLength || 0);
+        }
+    }
+
+    /** @internal */
+    handleClose(reason: string): void {
+        if (this.closed) {
+            return;
+        }
+        this.closed = true;
+        this.handlers.onClose?.(reason);
+    }
+
+    /**
+     * 在下一帧渲染后确认已处理的输出，合并同一帧内的确认
+     */
+    private acknowledge(bytes: number): void {
+        if (bytes <= 0) {
+            return;
+        }
+        this.pendingAck += bytes;
+        if (this.ackScheduled) {
+            return;
+        }
+        this.ackScheduled = true;
+
+        const flush = () => {
+            this.ackScheduled = false;
+            const ackBytes = this.pendingAck;
+            this.pendingAck = 0;
+            if (ackBytes > 0 && !this.closed) {
+                this.socket.sendAck(this.id, ackBytes);
+            }
+        };
+
+        if (typeof requestAnimationFrame === 'function') {
+            requestAnimationFrame(flush);
+        } else {
+            setTimeout(flush, 16);
+        }
+    }
+}
+
+/**
+ * 多路复用WebSocket连接
+ */
+export class MultiplexSocket {
+    private ws: WebSocket | null = null;
+    private channels = new Map<number, MultiplexChannel>();
+    private nextChannelId = 1;
+    private heartbeatTimer: ReturnType<typeof setInterval> | null = null;
+    private messageQueue: Promise<void> = Promise.resolve();
+    private compression: number = PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE;
+    private ready: Promise<void> | null = null;
+
+    /**
+     * 建立连接并完成协议协商
+     */
+    connect(): Promise<void> {
+        if (this.ready) {
+            return this.ready;
+        }
+
+        // 多路复用连接不属于单个会话，票据签发给固定的会话ID mux
+        const ready = fetchWebSocketTicket(MUX_TICKET_SESSION_ID).then(ticket => new Promise<void>((resolve, reject) => {
+            const apiUrl = new URL(API_BASE_URL);
+            const wsProtocol = apiUrl.protocol === 'https:' ? 'wss:' : 'ws:';
+            const ws = new WebSocket(appendTicket(`${wsProtocol}//${apiUrl.host}/ws/mux`, ticket));
+            ws.binaryType = 'arraybuffer';
+            this.ws = ws;
+
+            let negotiated = false;
+            ws.onopen = async () => {
+                const negotiation = binaryJsonProtocol.createNegotiationMessage();
+                negotiation.features = [...negotiation.features, 'multiplex'];
+                // 流控窗口在打开终端通道时分别指定
+                delete negotiation.flowControlWindow;
+                const message = await binaryJsonProtocol.encodeMessage(negotiation);
+                new DataView(message).setUint8(4, MESSAGE_TYPES.PROTOCOL_NEGOTIATION);
+                ws.send(message);
+            };
+
+            ws.onmessage = (event: MessageEvent) => {
+                // 解压是异步的，按到达顺序串行处理
+                this.messageQueue = this.messageQueue
+                    .then(() => this.handleMessage(event.data as ArrayBuffer, () => {
+                        negotiated = true;
+                        this.startHeartbeat();
+                        resolve();
+                    }))
+                    .catch(error => console.error('MultiplexSocket: 处理消息失败', error));
+            };
+
+            ws.onclose = () => {
+                this.stopHeartbeat();
+                this.channels.forEach(channel => channel.handleClose('连接已断开'));
+                this.channels.clear();
+                this.ws = null;
+                this.ready = null;
+                if (!negotiated) {
+                    reject(new Error('多路复用连接失败'));
+                }
+            };
+        }));
+        // 申请票据或连接失败后允许重新连接
+        ready.catch(() => {
+            if (this.ready === ready) {
+                this.ready = null;
+            }
+        });
+
+        this.ready = ready;
+        return ready;
+    }
+
+    /**
+     * 打开通道，服务端确认后触发onOpen，拒绝时触发onClose
+     */
+    async openChannel(options: ChannelOpenOptions, handlers: ChannelHandlers): Promise<MultiplexChannel> {
+        await this.connect();
+
+        const id = this.allocateChannelId();
+        const channel = new MultiplexChannel(id, options.kind, this, handlers);
+        this.channels.set(id, channel);
+
+        const request: ChannelOpenOptions = { ...options };
+        if (options.kind === 'terminal' && request.flowControlWindow === undefined) {
+            request.flowControlWindow = DEFAULT_FLOW_CONTROL_WINDOW;
+        }
+        const message = await binaryJsonProtocol.createChannelMessage(MESSAGE_TYPES.CHANNEL_OPEN, id, request);
+        this.ws?.send(message);
+        return channel;
+    }
+
+    /**
+     * 关闭所有通道并断开连接
+     */
+    disconnect(): void {
+        this.ws?.close(1000, '客户端断开');
+    }
+
+    /** @internal */
+    async sendOnChannel(channelId: number, jsonData: any, binaryData?: ArrayBuffer): Promise<void> {
+        if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+            return;
+        }
+        const message = await binaryJsonProtocol.encodeMessage(
+            jsonData, binaryData, PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE, channelId);
+        this.ws.send(message);
+    }
+
+    /** @internal */
+    async sendAck(channelId: number, bytes: number): Promise<void> {
+        if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+            return;
+        }
+        this.ws.send(await binaryJsonProtocol.createFlowControlAck(bytes, channelId));
+    }
+
+    /** @internal */
+    async closeChannel(channelId: number): Promise<void> {
+        const channel = this.channels.get(channelId);
+        if (!channel) {
+            return;
+        }
+        this.channels.delete(channelId);
+        channel.handleClose('');
+
+        if (this.ws && this.ws.readyState === WebSocket.OPEN) {
+            this.ws.send(await binaryJsonProtocol.createChannelMessage(MESSAGE_TYPES.CHANNEL_CLOSE, channelId, {}));
+        }
+    }
+
+    private async handleMessage(data: ArrayBuffer, onNegotiated: () => void): Promise<void> {
+        if (!(data instanceof ArrayBuffer) || !binaryJsonProtocol.isProtocolMessage(data)) {
+            return;
+        }
+        const message = await binaryJsonProtocol.decodeMessage(data);
+        const channelId = message.header.channelId;
+
+        if (channelId === CONTROL_CHANNEL) {
+            if (message.header.messageType === MESSAGE_TYPES.PROTOCOL_NEGOTIATION) {
+                const negotiation = message.jsonData as ProtocolNegotiation;
+                this.compression = negotiation.compression ?? PROTOCOL_CONSTANTS.COMPRESSION_TYPES.NONE;
+                console.log(`MultiplexSocket: 协商完成，压缩方式=${this.compression}`);
+                onNegotiated();
+            }
+            return;
+        }
+
+        const channel = this.channels.get(channelId);
+        switch (message.header.messageType) {
+            case MESSAGE_TYPES.CHANNEL_OPEN:
+                channel?.handleOpen(message.jsonData);
+                break;
+            case MESSAGE_TYPES.CHANNEL_CLOSE:
+                this.channels.delete(channelId);
+                channel?.handleClose(message.jsonData?.reason || '');
+                break;
+            default:
+                channel?.handleMessage(message);
+        }
+    }
+
+    private allocateChannelId(): number {
+        for (let i = 0; i < MAX_CHANNEL_ID; i++) {
+            const id = this.nextChannelId;
+            this.nextChannelId = id >= MAX_CHANNEL_ID ? 1 : id + 1;
+            if (!this.channels.has(id)) {
+                return id;
+            }
+        }
+        throw new Error('没有可用的通道ID');
+    }
+
+    private startHeartbeat(): void {
+        this.stopHeartbeat();
+        this.heartbeatTimer = setInterval(async () => {
+            if (this.ws && this.ws.readyState === WebSocket.OPEN) {
+                this.ws.send(await binaryJsonProtocol.createHeartbeatMessage());
+            }
+        }, HEARTBEAT_INTERVAL);
+    }
+
+    private stopHeartbeat(): void {
+        if (this.heartbeatTimer) {
+            clearInterval(this.heartbeatTimer);
+            this.heartbeatTimer = null;
+        }
+    }
+}
+
+// 页面内共享一个多路复用连接
+const multiplexSocket = new MultiplexSocket();
+export default multiplexSocket;
@@ -15,6 +15,7 @@
 
 import type { TerminalTab } from '../../../contexts/TerminalContext';
 import { API_BASE_URL } from '../../../services/api';
+import { createTicketWebSocket } from '../../../services/ticketWebSocket';
 import binaryJsonProtocol, {
     BinaryJsonProtocol,
     PROTOCOL_CONSTANTS
@@ -145,6 +146,13 @@ export class WebSocketService {
     private heartbeatTimestamps: Map<string, number> = new Map();
     // 网络延迟数据
     private networkLatencies: Map<string, number> = new Map();
+    // 协商得到的输出流控窗口，未启用流控的连接没有记录
+    private flowControlWindows: Map<string, number> = new Map();
+    // 已处理但尚未确认的终端输出字节数
+    private pendingAcks: Map<string, number> = new Map();
+    // 断线续传：服务端下发的续传令牌和已收到的最后输出序号
+    private resumeTokens: Map<string, string> = new Map();
+    private lastOutputSeqs: Map<string, number> = new Map();
 
     /**
      * 创建并管理WebSocket连接
@@ -272,15 +280,21 @@ export class WebSocketService {
             const wsProtocol = apiUrl.protocol === 'https:' ? 'wss:' : 'ws:';
             const host = apiUrl.host; // 包含端口号
 
-            // 构建WebSocket URL，不包含/api路径
-            const wsUrl = `${wsProtocol}//${host}/ws/${connProtocol}/${tab.sessionId}?token=${encodeURIComponent(token)}`;
+            // 构建WebSocket URL，不包含/api路径，认证使用连接前申请的一次性票据
+            let wsUrl = `${wsProtocol}//${host}/ws/${connProtocol}/${tab.sessionId}`;
+
+            // 重连时携带续传令牌，服务端接管断开前的终端并补发缺失的输出
+            const resumeToken = this.resumeTokens.get(tab.key);
+            if (resumeToken) {
+                wsUrl += `?resume_token=${encodeURIComponent(resumeToken)}&last_seq=${this.lastOutputSeqs.get(tab.key) || 0}`;
+            }
 
             console.log(`创建WebSocket连接: ${wsUrl}`);
 
             // 设置连接状态为connecting
             this.connectionStates.set(tab.key, 'connecting');
 
-            const ws = new WebSocket(wsUrl);
+            const ws = createTicketWebSocket(wsUrl, tab.sessionId);
 
             // 设置初始活动时间
             (ws as any).lastActivity = Date.now();
@@ -371,7 +385,15 @@ export class WebSocketService {
         };
 
         // 消息事件处理
-        ws.onmessage = async (event) => {
+        // 消息解码是异步的（Blob转换、gzip解压），按接收顺序串行处理，避免终端输出乱序
+        let messageQueue: Promise<void> = Promise.resolve();
+        ws.onmessage = (event) => {
+            messageQueue = messageQueue
+                .then(() => handleMessage(event))
+                .catch(error => console.error(`处理WebSocket消息失败: ${tab.key}`, error));
+        };
+
+        const handleMessage = async (event: MessageEvent) => {
             // 立即检查是否为心跳消息，在任何其他处理之前标记
 
             // 对于Blob类型，检查其大小是否为16字节（心跳包的典型大小）
@@ -418,6 +440,8 @@ export class WebSocketService {
 
             // 检查是否为二进制协议消息
             let processedEvent = event;
+            // 本条消息中需要流控确认的终端输出字节数
+            let consumedBytes = 0;
 
             // 处理二进制数据（ArrayBuffer或Blob）
             if (event.data instanceof ArrayBuffer || event.data instanceof Blob) {
@@ -485,6 +509,12 @@ export class WebSocketService {
                             return;
                         }
 
+                        // 续传信息不传递给处理函数
+                        if (protocolMessage.jsonData && protocolMessage.jsonData.type === 'resume') {
+                            this.handleResumeInfo(tab.key, protocolMessage.jsonData);
+                            return;
+                        }
+
                         // 根据消息类型提取实际数据
                         let actualData = protocolMessage.jsonData;
 
@@ -494,6 +524,17 @@ export class WebSocketService {
                             protocolMessage.binaryData) {
                             // 统计终端数据消息
                             this.stats.messageTypeStats.terminalData++;
+                            consumedBytes = protocolMessage.binaryData.byteLength;
+
+                            // 丢弃重连补发中已经收到过的输出，仍需确认以归还流控信用
+                            const seq = protocolMessage.jsonData.seq;
+                            if (typeof seq === 'number') {
+                                if (seq <= (this.lastOutputSeqs.get(tab.key) || 0)) {
+                                    this.acknowledgeOutput(tab.key, ws, consumedBytes);
+                                    return;
+                                }
+                                this.lastOutputSeqs.set(tab.key, seq);
+                            }
                             // 将二进制数据转换为字符串（终端输出）
                             const decoder = new TextDecoder();
                             actualData = decoder.decode(protocolMessage.binaryData);
@@ -594,6 +635,10 @@ export class WebSocketService {
             if (tabHandlers?.onMessage) {
                 tabHandlers.onMessage(processedEvent);
             }
+
+            if (consumedBytes > 0) {
+                this.acknowledgeOutput(tab.key, ws, consumedBytes);
+            }
         };
 
         // 关闭事件处理
@@ -865,10 +910,14 @@ export class WebSocketService {
 
             // 清理协议支持记录
             this.protocolSupport.delete(tabKey);
+            this.flowControlWindows.delete(tabKey);
+            this.pendingAcks.delete(tabKey);
 
-            // 根据参数决定是否移除处理函数
+            // 根据参数决定是否移除处理函数，重连时保留续传状态
             if (!preserveHandlers) {
                 this.handlers.delete(tabKey);
+                this.resumeTokens.delete(tabKey);
+                this.lastOutputSeqs.delete(tabKey);
             }
         }
     }
@@ -1167,12 +1216,69 @@ export class WebSocketService {
         if (negotiationData && typeof negotiationData === 'object') {
             // 记录服务端支持的协议
             this.protocolSupport.set(tabKey, true);
+            // 服务端返回窗口表示已启用输出流控，此后需要确认已处理的输出
+            if (negotiationData.flowControlWindow > 0) {
+                this.flowControlWindows.set(tabKey, negotiationData.flowControlWindow);
+            }
         } else {
             // 服务端不支持或协商失败
             this.protocolSupport.set(tabKey, false);
         }
     }
 
+    /**
+     * 处理服务端的续传信息。令牌失效时服务端新建了终端，序号从头开始
+     */
+    private handleResumeInfo(tabKey: string, info: any): void {
+        if (info.resumed) {
+            console.log(`终端续传成功: ${tabKey}, 补发${info.replayed}帧${info.gap ? '，部分输出已丢失' : ''}`);
+        } else {
+            this.lastOutputSeqs.delete(tabKey);
+        }
+        if (info.token) {
+            this.resumeTokens.set(tabKey, info.token);
+        }
+    }
+
+    /**
+     * 确认已处理的终端输出，恢复服务端的发送信用。
+     * 在下一帧渲染后再确认，终端来不及渲染时服务端自然暂停输出
+     */
+    private acknowledgeOutput(tabKey: string, ws: WebSocket, bytes: number): void {
+        const flowWindow = this.flowControlWindows.get(tabKey);
+        if (!flowWindow) {
+            return;
+        }
+
+        const pending = this.pendingAcks.get(tabKey);
+        this.pendingAcks.set(tabKey, (pending || 0) + bytes);
+        if (pending !== undefined) {
+            // 已安排确认，合并到同一条消息
+            return;
+        }
+
+        const flush = async () => {
+            const ackBytes = this.pendingAcks.get(tabKey) || 0;
+            this.pendingAcks.delete(tabKey);
+            if (ackBytes <= 0 || ws.readyState !== WebSocket.OPEN) {
+                return;
+            }
+            try {
+                const ackMessage = await binaryJsonProtocol.createFlowControlAck(ackBytes);
+                ws.send(ackMessage);
+                this.stats.totalDataSent += ackMessage.byteLength;
+            } catch (error) {
+                console.warn(`发送流控确认失败: ${tabKey}`, error);
+            }
+        };
+
+        if (typeof requestAnimationFrame === 'function') {
+            requestAnimationFrame(() => { flush(); });
+        } else {
+            setTimeout(flush, 16);
+        }
+    }
+
     /**
      * 检查是否为完整的协议消息
      * 避免将恰好以魔数开头的文本消息误判为二进制协议消息
@@ -1193,7 +1299,7 @@ export class WebSocketService {
                 compressionFlag: view.getUint8(5),
                 jsonLength: view.getUint32(6, false),
                 binaryLength: view.getUint32(10, false),
-                reserved: view.getUint16(14, false)
+                channelId: view.getUint16(14, false)
             };
 
             // 验证魔数
@@ -1708,6 +1814,10 @@ export class WebSocketService {
         // 清理连接相关数据
         this.stats.connectionDataStats.delete(tabKey);
         this.protocolSupport.delete(tabKey);
+        this.flowControlWindows.delete(tabKey);
+        this.pendingAcks.delete(tabKey);
+        this.resumeTokens.delete(tabKey);
+        this.lastOutputSeqs.delete(tabKey);
 
         console.log(`✅ 已强制停止重连: ${tabKey}`);
     }
@@ -1843,6 +1953,10 @@ export class WebSocketService {
         this.networkLatencies.clear();
         this.heartbeatTimestamps.clear();
         this.protocolSupport.clear();
+        this.flowControlWindows.clear();
+        this.pendingAcks.clear();
+        this.resumeTokens.clear();
+        this.lastOutputSeqs.clear();
 
         console.log('✅ 紧急清理完成');
     }
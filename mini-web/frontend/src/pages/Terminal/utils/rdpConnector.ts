@@ -4,6 +4,7 @@
  */
 
 import type { TerminalTab } from '../../../contexts/TerminalContext';
+import { createTicketWebSocket } from '../../../services/ticketWebSocket';
 
 /**
  * 创建RDP WebSocket连接
@@ -24,16 +25,13 @@ export const createRdpConnection = (sessionId: number, tab: TerminalTab): WebSoc
           ? window.location.host  // 生产环境使用当前host和port
           : 'localhost:8080';     // 开发环境使用localhost:8080
 
-        // 获取认证令牌
-        const token = localStorage.getItem('token');
-
-        // 添加token参数到URL以解决认证问题
-        const wsUrl = `${wsProtocol}//${wsHost}/ws/rdp/${sessionId}?token=${token}`;
+        // 认证使用连接前申请的一次性票据，票据在创建连接时附加到URL
+        const wsUrl = `${wsProtocol}//${wsHost}/ws/rdp/${sessionId}`;
 
         console.log(`[RDP] 创建连接: ${wsUrl}`);
 
         // 创建WebSocket
-        const ws = new WebSocket(wsUrl);
+        const ws = createTicketWebSocket(wsUrl, sessionId);
 
         // 设置事件处理函数
         ws.onopen = () => {
@@ -106,6 +106,10 @@ const UserProfile = () => {
       });
       
       if (response.data && response.data.code === 200) {
+        // 修改密码后旧令牌全部失效，使用服务端签发的新令牌
+        if (response.data.data?.token) {
+          localStorage.setItem('token', response.data.data.token);
+        }
         message.success('密码修改成功');
         passwordForm.resetFields();
       } else {
@@ -75,7 +75,22 @@ export const authAPI = {
     return api.put('/user/profile', userData);
   },
 
-  // 更新密码
+  // 获取密码策略
+  getPasswordPolicy: () => {
+    return api.get('/auth/password-policy');
+  },
+
+  // 申请找回密码，重置链接发送到注册邮箱
+  forgotPassword: (email: string) => {
+    return api.post('/auth/forgot-password', { email });
+  },
+
+  // 使用邮件中的令牌重置密码
+  resetPassword: (token: string, newPassword: string) => {
+    return api.post('/auth/reset-password', { token, new_password: newPassword });
+  },
+
+  // 更新密码，成功后返回新的令牌对
   updatePassword: (passwordData: {
     old_password: string;
     new_password: string;
@@ -83,9 +98,24 @@ export const authAPI = {
     return api.put('/user/password', passwordData);
   },
 
-  // 刷新Token
-  refreshToken: () => {
-    return api.post('/auth/refresh');
+  // 使用刷新令牌换取新的Token
+  refreshToken: (refreshToken: string) => {
+    return api.post('/auth/refresh', { refresh_token: refreshToken });
+  },
+
+  // 退出当前登录
+  logout: () => {
+    return api.post('/auth/logout');
+  },
+
+  // 退出所有设备
+  logoutAll: () => {
+    return api.post('/auth/logout-all');
+  },
+
+  // 获取单点登录信息，enabled为true时登录页跳转到login_url
+  getOIDCInfo: () => {
+    return api.get('/auth/oidc');
   },
 };
 
@@ -229,6 +259,7 @@ export interface Connection {
   port: number;
   username: string;
   group: string;
+  tags?: string;
   description: string;
   last_used: string;
   created_by: number;
@@ -246,6 +277,7 @@ export interface ConnectionRequest {
   password?: string;
   private_key?: string;
   group: string;
+  tags?: string;
   description: string;
 }
 
@@ -353,9 +385,25 @@ export const sessionAPI = {
       code: number;
       message: string;
     }>(`/sessions/${id}`);
+  },
+
+  // 申请WebSocket连接票据，票据一次有效，需在有效期内用于连接指定会话
+  createWebSocketTicket: (sessionId: string | number) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: WebSocketTicket;
+    }>('/ws-ticket', { session_id: String(sessionId) });
   }
 };
 
+// WebSocket连接票据
+export interface WebSocketTicket {
+  ticket: string;
+  expires_at: string;
+  expires_in: number;
+}
+
 // 系统配置类型
 export interface SystemConfig {
   id: number;
@@ -454,12 +502,26 @@ export interface EmailTemplate {
   name: string;
   subject: string;
   body: string;
+  html_body: string;
   type: string;
   is_default: boolean;
   created_at: string;
   updated_at: string;
 }
 
+// 发信队列中的邮件
+export interface EmailMessage {
+  id: number;
+  to: string;
+  subject: string;
+  status: 'pending' | 'sent' | 'failed';
+  attempts: number;
+  last_error?: string;
+  next_attempt_at: string;
+  sent_at?: string;
+  created_at: string;
+}
+
 // 邮件配置API
 export const emailAPI = {
   // 获取邮件配置
@@ -542,9 +604,134 @@ export const emailAPI = {
         usage: string;
       };
     }>('/admin/system/email/variables');
+  },
+
+  // 获取发信队列
+  getEmailQueue: (params?: { status?: string; limit?: number; offset?: number }) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: {
+        list: EmailMessage[];
+        total: number;
+        limit: number;
+        offset: number;
+      };
+    }>('/admin/system/email/queue', { params });
+  },
+
+  // 重新发送失败的邮件
+  retryEmail: (id: number) => {
+    return api.post<{
+      code: number;
+      message: string;
+    }>(`/admin/system/email/queue/${id}/retry`);
   }
 };
 
+// 通知渠道
+export interface NotificationChannel {
+  id: number;
+  user_id: number;
+  name: string;
+  type: 'email' | 'webhook' | 'slack' | 'dingtalk';
+  target: string;
+  secret?: string; // 仅创建时返回一次
+  has_secret: boolean;
+  events: string; // 逗号分隔，为空表示全部事件
+  is_enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface NotificationChannelRequest {
+  name: string;
+  type: NotificationChannel['type'];
+  target: string;
+  secret?: string;
+  events: string[];
+  is_enabled: boolean;
+}
+
+export interface NotificationDelivery {
+  id: number;
+  channel_id: number;
+  event_type: string;
+  payload: string;
+  status: 'pending' | 'delivered' | 'failed';
+  attempts: number;
+  last_error?: string;
+  next_attempt_at: string;
+  delivered_at?: string;
+  created_at: string;
+}
+
+// 通知渠道API，system为true时管理系统渠道
+const notificationBase = (system?: boolean) => (system ? '/admin/notifications' : '/notifications');
+
+export const notificationAPI = {
+  // 获取可订阅的事件类型
+  getEventTypes: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: Record<string, string>;
+    }>('/notifications/events');
+  },
+
+  // 获取通知渠道列表
+  getChannels: (system?: boolean) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: NotificationChannel[];
+    }>(`${notificationBase(system)}/channels`);
+  },
+
+  // 创建通知渠道
+  createChannel: (channel: NotificationChannelRequest, system?: boolean) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: NotificationChannel;
+    }>(`${notificationBase(system)}/channels`, channel);
+  },
+
+  // 更新通知渠道，secret留空保留原密钥
+  updateChannel: (id: number, channel: NotificationChannelRequest, system?: boolean) => {
+    return api.put<{
+      code: number;
+      message: string;
+      data: NotificationChannel;
+    }>(`${notificationBase(system)}/channels/${id}`, channel);
+  },
+
+  // 删除通知渠道
+  deleteChannel: (id: number, system?: boolean) => {
+    return api.delete<{
+      code: number;
+      message: string;
+    }>(`${notificationBase(system)}/channels/${id}`);
+  },
+
+  // 发送测试通知
+  testChannel: (id: number, system?: boolean) => {
+    return api.post<{
+      code: number;
+      message: string;
+    }>(`${notificationBase(system)}/channels/${id}/test`);
+  },
+
+  // 获取投递记录
+  getDeliveries: (id: number, system?: boolean, limit = 50) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: NotificationDelivery[];
+    }>(`${notificationBase(system)}/channels/${id}/deliveries`, { params: { limit } });
+  },
+};
+
 // SSL配置类型
 export interface SSLConfig {
   id: number;
@@ -576,6 +763,30 @@ export interface SSLCertInfo {
   days_until_expiry: number;
 }
 
+// 证书生成参数
+export interface CertificateGenerateRequest {
+  name?: string;
+  common_name: string;
+  dns_names?: string[];
+  ip_addresses?: string[];
+  organization?: string;
+  key_type?: 'ecdsa' | 'rsa';
+  key_size?: number;
+  valid_days?: number;
+  is_enabled?: boolean;
+}
+
+// 证书请求类型
+export interface SSLCSR {
+  id: number;
+  name: string;
+  common_name: string;
+  dns_names: string;
+  key_type: string;
+  csr_content: string;
+  created_at: string;
+}
+
 // SSL配置API
 export const sslAPI = {
   // 获取SSL配置列表
@@ -686,6 +897,582 @@ export const sslAPI = {
         default_config: string;
       };
     }>('/admin/system/ssl/status');
+  },
+
+  // 生成自签名证书
+  generateSelfSigned: (req: CertificateGenerateRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: SSLConfig;
+    }>('/admin/system/ssl/self-signed', req);
+  },
+
+  // 获取内部CA证书
+  getInternalCA: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: { cert_content: string; info: SSLCertInfo };
+    }>('/admin/system/ssl/ca');
+  },
+
+  // 由内部CA签发证书
+  issueInternalCertificate: (req: CertificateGenerateRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: SSLConfig;
+    }>('/admin/system/ssl/ca/issue', req);
+  },
+
+  // 续期内部CA签发的证书
+  renewSSLConfig: (id: number) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: SSLConfig;
+    }>(`/admin/system/ssl/configs/${id}/renew`);
+  },
+
+  // 获取证书请求列表
+  getCSRs: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: SSLCSR[];
+    }>('/admin/system/ssl/csrs');
+  },
+
+  // 生成证书请求
+  createCSR: (req: CertificateGenerateRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: SSLCSR;
+    }>('/admin/system/ssl/csrs', req);
+  },
+
+  // 删除证书请求
+  deleteCSR: (id: number) => {
+    return api.delete<{
+      code: number;
+      message: string;
+    }>(`/admin/system/ssl/csrs/${id}`);
+  },
+
+  // 导入外部CA签发的证书
+  completeCSR: (id: number, certContent: string, isEnabled: boolean) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: SSLConfig;
+    }>(`/admin/system/ssl/csrs/${id}/complete`, { cert_content: certContent, is_enabled: isEnabled });
+  }
+};
+
+// ACME证书类型
+export interface ACMECertificate {
+  id: number;
+  ssl_config_id: number;
+  name: string;
+  domains: string;
+  challenge_type: 'http-01' | 'dns-01';
+  dns_provider: string;
+  auto_renew: boolean;
+  status: 'pending' | 'processing' | 'valid' | 'failed';
+  last_error: string;
+  last_issued_at: string;
+  created_at: string;
+  updated_at: string;
+}
+
+// ACME证书请求
+export interface ACMECertificateRequest {
+  name?: string;
+  domains: string[];
+  challenge_type: 'http-01' | 'dns-01';
+  dns_provider?: string;
+  dns_config?: Record<string, string>;
+  auto_renew: boolean;
+}
+
+// ACME签发记录类型
+export interface ACMEOrder {
+  id: number;
+  certificate_id: number;
+  action: 'issue' | 'renew';
+  domains: string;
+  status: 'processing' | 'valid' | 'failed';
+  error: string;
+  order_url: string;
+  started_at: string;
+  finished_at: string;
+}
+
+// ACME自动证书API
+export const acmeAPI = {
+  // 获取支持的DNS服务商
+  getDNSProviders: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: string[];
+    }>('/admin/system/ssl/acme/dns-providers');
+  },
+
+  // 获取ACME证书列表
+  getCertificates: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: ACMECertificate[];
+    }>('/admin/system/ssl/acme/certificates');
+  },
+
+  // 创建ACME证书并开始签发
+  createCertificate: (req: ACMECertificateRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: ACMECertificate;
+    }>('/admin/system/ssl/acme/certificates', req);
+  },
+
+  // 修改ACME证书
+  updateCertificate: (id: number, req: ACMECertificateRequest) => {
+    return api.put<{
+      code: number;
+      message: string;
+      data: ACMECertificate;
+    }>(`/admin/system/ssl/acme/certificates/${id}`, req);
+  },
+
+  // 删除ACME证书
+  deleteCertificate: (id: number) => {
+    return api.delete<{
+      code: number;
+      message: string;
+    }>(`/admin/system/ssl/acme/certificates/${id}`);
+  },
+
+  // 立即续期
+  renewCertificate: (id: number) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: ACMECertificate;
+    }>(`/admin/system/ssl/acme/certificates/${id}/renew`);
+  },
+
+  // 获取签发记录
+  getOrders: (certificateId?: number) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: ACMEOrder[];
+    }>('/admin/system/ssl/acme/orders', { params: { certificate_id: certificateId } });
+  }
+};
+
+// 批量任务类型定义
+export interface BatchJob {
+  id: number;
+  user_id: number;
+  name: string;
+  command: string;
+  script: string;
+  sudo: boolean;
+  sudo_user: string;
+  parallelism: number;
+  timeout_seconds: number;
+  source_job_id: number;
+  scheduled_job_id: number;
+  credential_id: number;
+  attempt: number;
+  status: 'pending' | 'running' | 'completed' | 'failed' | 'cancelled';
+  total_hosts: number;
+  succeeded_hosts: number;
+  failed_hosts: number;
+  started_at?: string;
+  finished_at?: string;
+  created_at: string;
+}
+
+export interface BatchJobHost {
+  id: number;
+  job_id: number;
+  connection_id: number;
+  name: string;
+  host: string;
+  status: 'pending' | 'running' | 'succeeded' | 'failed' | 'timeout' | 'cancelled';
+  exit_code?: number;
+  stdout: string;
+  stderr: string;
+  truncated: boolean;
+  error?: string;
+  started_at?: string;
+  finished_at?: string;
+}
+
+export interface BatchJobDetail extends BatchJob {
+  hosts: BatchJobHost[];
+}
+
+export interface BatchJobRequest {
+  name?: string;
+  connection_ids?: number[];
+  folder_id?: number;
+  tag?: string;
+  command?: string;
+  script?: string;
+  sudo?: boolean;
+  sudo_user?: string;
+  parallelism?: number;
+  timeout_seconds?: number;
+}
+
+export interface BatchJobEvent {
+  type: 'host_start' | 'output' | 'host_done' | 'job_done';
+  job_id: number;
+  connection_id?: number;
+  stream?: 'stdout' | 'stderr';
+  data?: string;
+  status?: string;
+  exit_code?: number;
+  error?: string;
+}
+
+// 批量任务API
+export const batchJobAPI = {
+  // 创建批量任务并开始执行
+  createJob: (req: BatchJobRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: BatchJobDetail;
+    }>('/batch-jobs', req);
+  },
+
+  // 获取最近的批量任务
+  getJobs: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: BatchJob[];
+    }>('/batch-jobs');
+  },
+
+  // 获取批量任务及各主机的执行结果
+  getJob: (id: number) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: BatchJobDetail;
+    }>(`/batch-jobs/${id}`);
+  },
+
+  // 重新执行，默认只在失败的主机上执行
+  rerunJob: (id: number, failedOnly = true) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: BatchJobDetail;
+    }>(`/batch-jobs/${id}/rerun`, { failed_only: failedOnly });
+  },
+
+  // 取消正在执行的任务
+  cancelJob: (id: number) => {
+    return api.post<{
+      code: number;
+      message: string;
+    }>(`/batch-jobs/${id}/cancel`);
+  },
+
+  // 订阅任务的实时事件。EventSource无法携带Authorization头，这里用fetch读取事件流，
+  // 返回的函数用于取消订阅
+  streamEvents: (id: number, onEvent: (event: BatchJobEvent) => void, onError?: (error: unknown) => void) => {
+    const controller = new AbortController();
+    const token = localStorage.getItem('token');
+
+    (async () => {
+      const response = await fetch(`${API_BASE_URL}/batch-jobs/${id}/events`, {
+        headers: token ? { Authorization: `Bearer ${token}` } : {},
+        signal: controller.signal,
+      });
+      if (!response.ok || !response.body) {
+        throw new Error(`订阅任务事件失败: ${response.status}`);
+      }
+
+      const reader = response.body.getReader();
+      const decoder = new TextDecoder();
+      let buffer = '';
+      for (;;) {
+        const { done, value } = await reader.read();
+        if (done) {
+          break;
+        }
+        buffer += decoder.decode(value, { stream: true });
+
+        let index;
+        while ((index = buffer.indexOf('\n\n')) >= 0) {
+          const block = buffer.slice(0, index);
+          buffer = buffer.slice(index + 2);
+          const data = block
+            .split('\n')
+            .filter((line) => line.startsWith('data: '))
+            .map((line) => line.slice(6))
+            .join('\n');
+          if (data) {
+            onEvent(JSON.parse(data) as BatchJobEvent);
+          }
+        }
+      }
+    })().catch((error) => {
+      if (!controller.signal.aborted && onError) {
+        onError(error);
+      }
+    });
+
+    return () => controller.abort();
+  }
+};
+
+// 定时任务类型定义
+export interface ScheduledJob {
+  id: number;
+  user_id: number;
+  name: string;
+  cron_expr: string;
+  connection_ids: number[];
+  command: string;
+  script: string;
+  sudo: boolean;
+  sudo_user: string;
+  credential_id: number;
+  parallelism: number;
+  timeout_seconds: number;
+  max_retries: number;
+  retry_delay_seconds: number;
+  overlap_policy: 'skip' | 'queue';
+  retention_runs: number;
+  notify_on_failure: boolean;
+  is_enabled: boolean;
+  last_run_at?: string;
+  last_status: string;
+  last_error?: string;
+  last_batch_job_id: number;
+  next_run_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ScheduledJobRequest {
+  name?: string;
+  cron_expr: string;
+  connection_ids: number[];
+  command?: string;
+  script?: string;
+  sudo?: boolean;
+  sudo_user?: string;
+  credential_id: number;
+  parallelism?: number;
+  timeout_seconds?: number;
+  max_retries?: number;
+  retry_delay_seconds?: number;
+  overlap_policy?: 'skip' | 'queue';
+  retention_runs?: number;
+  notify_on_failure?: boolean;
+  is_enabled?: boolean;
+}
+
+// 定时任务API，每次执行的输出通过batchJobAPI查看
+export const scheduledJobAPI = {
+  // 获取定时任务列表
+  getJobs: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: ScheduledJob[];
+    }>('/scheduled-jobs');
+  },
+
+  // 获取定时任务
+  getJob: (id: number) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: ScheduledJob;
+    }>(`/scheduled-jobs/${id}`);
+  },
+
+  // 创建定时任务
+  createJob: (req: ScheduledJobRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: ScheduledJob;
+    }>('/scheduled-jobs', req);
+  },
+
+  // 修改定时任务
+  updateJob: (id: number, req: ScheduledJobRequest) => {
+    return api.put<{
+      code: number;
+      message: string;
+      data: ScheduledJob;
+    }>(`/scheduled-jobs/${id}`, req);
+  },
+
+  // 删除定时任务及其执行记录
+  deleteJob: (id: number) => {
+    return api.delete<{
+      code: number;
+      message: string;
+    }>(`/scheduled-jobs/${id}`);
+  },
+
+  // 立即执行一次
+  runJob: (id: number) => {
+    return api.post<{
+      code: number;
+      message: string;
+    }>(`/scheduled-jobs/${id}/run`);
+  },
+
+  // 获取执行记录
+  getRuns: (id: number) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: BatchJob[];
+    }>(`/scheduled-jobs/${id}/runs`);
+  },
+
+  // 获取全局暂停状态
+  getPauseState: () => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: { paused: boolean };
+    }>('/admin/scheduled-jobs/pause');
+  },
+
+  // 全局暂停或恢复定时任务
+  setPauseState: (paused: boolean) => {
+    return api.put<{
+      code: number;
+      message: string;
+      data: { paused: boolean };
+    }>('/admin/scheduled-jobs/pause', { paused });
+  }
+};
+
+// 命令片段类型定义
+export interface SnippetParameter {
+  name: string;
+  type: 'string' | 'number' | 'boolean' | 'enum';
+  description?: string;
+  default?: string;
+  required?: boolean;
+  options?: string[];
+}
+
+export interface Snippet {
+  id: number;
+  name: string;
+  description: string;
+  content: string;
+  parameters: SnippetParameter[];
+  scope: 'user' | 'team' | 'global';
+  owner_id: number;
+  team_id: number;
+  tags: string;
+  usage_count: number;
+  last_used_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface SnippetRequest {
+  name: string;
+  description?: string;
+  content: string;
+  parameters?: SnippetParameter[];
+  scope?: 'user' | 'team' | 'global';
+  team_id?: number;
+  tags?: string;
+}
+
+export type SnippetArgs = Record<string, string | number | boolean>;
+
+export interface SnippetBatchRequest extends Omit<BatchJobRequest, 'command' | 'script'> {
+  args?: SnippetArgs;
+}
+
+// 命令片段API。在终端中使用时通过终端WebSocket发送
+// {type: 'snippet', data: {id, args, execute, requestId}}，服务端回复snippet_response
+export const snippetAPI = {
+  // 获取可见的命令片段，传入关键词时全文搜索
+  getSnippets: (q?: string, limit?: number) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: Snippet[];
+    }>('/snippets', { params: { q, limit } });
+  },
+
+  // 获取命令片段
+  getSnippet: (id: number) => {
+    return api.get<{
+      code: number;
+      message: string;
+      data: Snippet;
+    }>(`/snippets/${id}`);
+  },
+
+  // 创建命令片段
+  createSnippet: (req: SnippetRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: Snippet;
+    }>('/snippets', req);
+  },
+
+  // 更新命令片段
+  updateSnippet: (id: number, req: SnippetRequest) => {
+    return api.put<{
+      code: number;
+      message: string;
+      data: Snippet;
+    }>(`/snippets/${id}`, req);
+  },
+
+  // 删除命令片段
+  deleteSnippet: (id: number) => {
+    return api.delete<{
+      code: number;
+      message: string;
+    }>(`/snippets/${id}`);
+  },
+
+  // 预览展开后的命令，不计入使用次数
+  expandSnippet: (id: number, args: SnippetArgs) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: { snippet_id: number; command: string };
+    }>(`/snippets/${id}/expand`, { args });
+  },
+
+  // 作为批量任务在多台主机上执行
+  runSnippet: (id: number, req: SnippetBatchRequest) => {
+    return api.post<{
+      code: number;
+      message: string;
+      data: BatchJobDetail;
+    }>(`/snippets/${id}/batch-jobs`, req);
   }
 };
 
@@ -1,5 +1,6 @@
 import axios from 'axios';
 import { API_BASE_URL } from './api';
+import { openTicketWebSocket } from './ticketWebSocket';
 
 // 创建专用的终端会话API实例
 const terminalApi = axios.create({
@@ -40,6 +41,23 @@ export interface TerminalMessage {
   content: string;
   timestamp: string;
   user_id?: number;
+  seq?: number; // 会话内递增的消息序号
+}
+
+// 服务端在连接建立时下发的续传信息
+export interface ResumeInfo {
+  type: 'resume';
+  token: string;
+  resumed: boolean;
+  lastSeq: number;
+  replayed: number;
+  gap: boolean; // 部分缺失的消息已超出服务端的历史缓冲
+}
+
+// 断线续传参数
+export interface ResumeOptions {
+  token: string;
+  lastSeq: number;
 }
 
 export interface CreateSessionRequest {
@@ -102,22 +120,23 @@ export const terminalSessionAPI = {
   },
 
   // 创建WebSocket连接URL
-  getWebSocketUrl: (sessionId: string, resume = false) => {
+  getWebSocketUrl: (sessionId: string, resume = false, resumeOptions?: ResumeOptions) => {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
     const host = process.env.NODE_ENV === 'production' 
       ? window.location.host  // 生产环境使用当前host和port
       : 'localhost:8080';     // 开发环境使用localhost:8080
-    const token = localStorage.getItem('token');
     
+    // 认证票据在连接前申请，见connectToSession
     let url = `${protocol}//${host}/ws/terminal/${sessionId}`;
     
     const params = new URLSearchParams();
-    if (token) {
-      params.append('token', token);
-    }
     if (resume) {
       params.append('resume', 'true');
     }
+    if (resumeOptions) {
+      params.append('resume_token', resumeOptions.token);
+      params.append('last_seq', String(resumeOptions.lastSeq));
+    }
     
     if (params.toString()) {
       url += `?${params.toString()}`;
@@ -133,6 +152,9 @@ export class TerminalSessionManager {
   private websockets: Map<string, WebSocket> = new Map();
   private messageHandlers: Map<string, (message: TerminalMessage) => void> = new Map();
   private statusHandlers: Map<string, (status: string) => void> = new Map();
+  // 断线续传：续传令牌和已收到的最后消息序号
+  private resumeTokens: Map<string, string> = new Map();
+  private lastSeqs: Map<string, number> = new Map();
 
   // 创建会话
   async createSession(connectionId: number, protocol: string): Promise<TerminalSession> {
@@ -157,8 +179,14 @@ export class TerminalSessionManager {
       this.disconnectFromSession(sessionId);
     }
 
-    const wsUrl = terminalSessionAPI.getWebSocketUrl(sessionId, resume);
-    const ws = new WebSocket(wsUrl);
+    // 有续传令牌时只需补发断开期间缺失的消息
+    const token = this.resumeTokens.get(sessionId);
+    const resumeOptions = token ? { token, lastSeq: this.lastSeqs.get(sessionId) || 0 } : undefined;
+    if (!resumeOptions) {
+      this.lastSeqs.delete(sessionId);
+    }
+    const wsUrl = terminalSessionAPI.getWebSocketUrl(sessionId, resume, resumeOptions);
+    const ws = await openTicketWebSocket(wsUrl, sessionId);
 
     // 设置事件处理器
     ws.onopen = () => {
@@ -179,8 +207,22 @@ export class TerminalSessionManager {
 
     ws.onmessage = (event) => {
       try {
-        const message: TerminalMessage = JSON.parse(event.data);
+        const data = JSON.parse(event.data);
+        if (data.type === 'resume') {
+          this.handleResumeInfo(sessionId, data as ResumeInfo);
+          return;
+        }
+
+        const message: TerminalMessage = data;
         console.log(`收到会话消息: ${sessionId}`, message);
+
+        // 跳过已经收到过的消息
+        if (message.seq) {
+          if (message.seq <= (this.lastSeqs.get(sessionId) || 0)) {
+            return;
+          }
+          this.lastSeqs.set(sessionId, message.seq);
+        }
         
         // 触发消息处理器
         const messageHandler = this.messageHandlers.get(sessionId);
@@ -275,6 +317,18 @@ export class TerminalSessionManager {
     this.sessions.delete(sessionId);
     this.messageHandlers.delete(sessionId);
     this.statusHandlers.delete(sessionId);
+    this.resumeTokens.delete(sessionId);
+    this.lastSeqs.delete(sessionId);
+  }
+
+  // 处理续传信息，未续传时服务端发送全部历史，序号重新计算
+  private handleResumeInfo(sessionId: string, info: ResumeInfo) {
+    if (info.resumed) {
+      console.log(`会话续传: ${sessionId}, 补发${info.replayed}条消息${info.gap ? '，部分输出已丢失' : ''}`);
+    } else {
+      this.lastSeqs.delete(sessionId);
+    }
+    this.resumeTokens.set(sessionId, info.token);
   }
 
   // 设置消息处理器
@@ -345,6 +399,8 @@ export class TerminalSessionManager {
     this.sessions.clear();
     this.messageHandlers.clear();
     this.statusHandlers.clear();
+    this.resumeTokens.clear();
+    this.lastSeqs.clear();
   }
 }
 
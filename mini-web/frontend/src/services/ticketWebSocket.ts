@@ -0,0 +1,163 @@
+/*
+ * @Author: Await
+ * @Date: 2025-06-10 10:00:00
+ * @LastEditors: Await
+ * @LastEditTime: 2025-06-10 10:00:00
+ * @Description: 使用一次性票据认证的WebSocket连接，访问令牌不再出现在WebSocket地址中
+ */
+
+import { sessionAPI } from './api';
+
+/**
+ * 申请连接指定会话的一次性票据
+ * @param sessionId 会话ID，多路复用连接使用mux
+ */
+export async function fetchWebSocketTicket(sessionId: string | number): Promise<string> {
+    const response = await sessionAPI.createWebSocketTicket(sessionId);
+    if (response.data.code !== 200 || !response.data.data?.ticket) {
+        throw new Error(response.data.message || '申请WebSocket票据失败');
+    }
+    return response.data.data.ticket;
+}
+
+/**
+ * 在URL上附加票据参数
+ */
+export function appendTicket(url: string, ticket: string): string {
+    const separator = url.includes('?') ? '&' : '?';
+    return `${url}${separator}ticket=${encodeURIComponent(ticket)}`;
+}
+
+/**
+ * 先申请票据再建立WebSocket连接
+ */
+export async function openTicketWebSocket(url: string, sessionId: string | number): Promise<WebSocket> {
+    const ticket = await fetchWebSocketTicket(sessionId);
+    return new WebSocket(appendTicket(url, ticket));
+}
+
+type SocketEventName = 'open' | 'message' | 'error' | 'close';
+
+/**
+ * 与WebSocket接口一致的连接对象，创建后先异步申请票据再连接。
+ * 用于需要同步返回连接对象的调用方，申请票据期间readyState为CONNECTING，
+ * 申请失败时依次触发error和close事件
+ */
+export class TicketWebSocket extends EventTarget {
+    static readonly CONNECTING = WebSocket.CONNECTING;
+    static readonly OPEN = WebSocket.OPEN;
+    static readonly CLOSING = WebSocket.CLOSING;
+    static readonly CLOSED = WebSocket.CLOSED;
+
+    readonly CONNECTING = WebSocket.CONNECTING;
+    readonly OPEN = WebSocket.OPEN;
+    readonly CLOSING = WebSocket.CLOSING;
+    readonly CLOSED = WebSocket.CLOSED;
+
+    private socket: WebSocket | null = null;
+    private closedBeforeOpen = false;
+    private handlers: Partial<Record<SocketEventName, ((event: any) => void) | null>> = {};
+    private type: BinaryType = 'blob';
+
+    constructor(readonly url: string, sessionId: string | number) {
+        super();
+        fetchWebSocketTicket(sessionId)
+            .then(ticket => {
+                if (this.closedBeforeOpen) {
+                    return;
+                }
+                this.attach(new WebSocket(appendTicket(url, ticket)));
+            })
+            .catch(error => {
+                console.error('申请WebSocket票据失败:', error);
+                this.closedBeforeOpen = true;
+                this.emit(new Event('error'));
+                this.emit(new CloseEvent('close', { code: 1006, reason: '申请连接票据失败' }));
+            });
+    }
+
+    get readyState(): number {
+        if (this.socket) {
+            return this.socket.readyState;
+        }
+        return this.closedBeforeOpen ? WebSocket.CLOSED : WebSocket.CONNECTING;
+    }
+
+    get binaryType(): BinaryType {
+        return this.type;
+    }
+
+    set binaryType(value: BinaryType) {
+        this.type = value;
+        if (this.socket) {
+            this.socket.binaryType = value;
+        }
+    }
+
+    get bufferedAmount(): number {
+        return this.socket?.bufferedAmount ?? 0;
+    }
+
+    get protocol(): string {
+        return this.socket?.protocol ?? '';
+    }
+
+    get extensions(): string {
+        return this.socket?.extensions ?? '';
+    }
+
+    get onopen() { return this.handlers.open ?? null; }
+    set onopen(handler) { this.handlers.open = handler; }
+    get onmessage() { return this.handlers.message ?? null; }
+    set onmessage(handler) { this.handlers.message = handler; }
+    get onerror() { return this.handlers.error ?? null; }
+    set onerror(handler) { this.handlers.error = handler; }
+    get onclose() { return this.handlers.close ?? null; }
+    set onclose(handler) { this.handlers.close = handler; }
+
+    send(data: string | ArrayBufferLike | Blob | ArrayBufferView): void {
+        if (!this.socket) {
+            throw new DOMException('WebSocket尚未连接', 'InvalidStateError');
+        }
+        this.socket.send(data);
+    }
+
+    close(code?: number, reason?: string): void {
+        if (this.socket) {
+            this.socket.close(code, reason);
+            return;
+        }
+        if (!this.closedBeforeOpen) {
+            this.closedBeforeOpen = true;
+            this.emit(new CloseEvent('close', { code: code ?? 1000, reason: reason ?? '', wasClean: true }));
+        }
+    }
+
+    private attach(socket: WebSocket): void {
+        this.socket = socket;
+        socket.binaryType = this.type;
+        socket.onopen = () => this.emit(new Event('open'));
+        socket.onmessage = (event) => this.emit(new MessageEvent('message', { data: event.data, origin: event.origin }));
+        socket.onerror = () => this.emit(new Event('error'));
+        socket.onclose = (event) => this.emit(new CloseEvent('close', {
+            code: event.code,
+            reason: event.reason,
+            wasClean: event.wasClean
+        }));
+    }
+
+    private emit(event: Event): void {
+        const handler = this.handlers[event.type as SocketEventName];
+        if (handler) {
+            handler.call(this, event);
+        }
+        this.dispatchEvent(event);
+    }
+}
+
+/**
+ * 创建使用票据认证的WebSocket，调用方按普通WebSocket使用
+ */
+export function createTicketWebSocket(url: string, sessionId: string | number): WebSocket {
+    return new TicketWebSocket(url, sessionId) as unknown as WebSocket;
+}